@@ -17,8 +17,20 @@ limitations under the License.
 package polardbx
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
 	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // BackupStorageProvider defines the configuration of storage for storing backup files.
@@ -28,9 +40,287 @@ type BackupStorageProvider struct {
 
 	// Sink defines the storage configuration choose to perform backup
 	Sink string `json:"sink,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// UploadConcurrency defines how many parts of a backup object are uploaded concurrently, only works for
+	// storages supporting multipart upload. Default is 1, which uploads the object as a single stream.
+	// +optional
+	UploadConcurrency int32 `json:"uploadConcurrency,omitempty"`
+
+	// UploadPartSize defines size of each part uploaded concurrently, e.g. 64Mi. Each part uploading is buffered
+	// in memory, so memory usage of upload is bounded by UploadConcurrency times UploadPartSize.
+	// +optional
+	UploadPartSize string `json:"uploadPartSize,omitempty"`
+
+	// MaxObjectSize defines the max size of each backup object, e.g. 5Gi, above which full backup stream is split
+	// into numbered objects along with a manifest, and reassembled on download. It's for storages rejecting
+	// objects above a size limit, and must not exceed the limit of storage. Default is no split.
+	// +optional
+	MaxObjectSize string `json:"maxObjectSize,omitempty"`
+
+	// ServerSideEncryption defines the server-side encryption requested on uploaded backup files, only works
+	// for storages oss and s3.
+	// +optional
+	ServerSideEncryption *ServerSideEncryption `json:"serverSideEncryption,omitempty"`
+
+	// +kubebuilder:validation:Enum=Internal;Public;Accelerate
+
+	// EndpointType selects the endpoint of sink to transfer backup files, Internal, Public, or Accelerate for
+	// transfer acceleration, only works for storage oss. The default endpoint of sink is used if not specified,
+	// not configured in sink, or transfer acceleration is unavailable for the bucket.
+	// +optional
+	EndpointType EndpointType `json:"endpointType,omitempty"`
 	// TODO: Add Nas Provider
 }
 
+// EndpointType defines the type of endpoint of sink
+type EndpointType string
+
+const (
+	// EndpointTypeInternal transfers via the internal endpoint within the region of storage.
+	EndpointTypeInternal EndpointType = "Internal"
+	// EndpointTypePublic transfers via the public endpoint.
+	EndpointTypePublic EndpointType = "Public"
+	// EndpointTypeAccelerate transfers via the endpoint of transfer acceleration.
+	EndpointTypeAccelerate EndpointType = "Accelerate"
+)
+
+// GetUploadPartSize parses the upload part size in bytes, 0 if not specified.
+func (p *BackupStorageProvider) GetUploadPartSize() (int64, error) {
+	if p.UploadPartSize == "" {
+		return 0, nil
+	}
+	quantity, err := resource.ParseQuantity(p.UploadPartSize)
+	if err != nil {
+		return 0, err
+	}
+	if quantity.Value() <= 0 {
+		return 0, errors.New("upload part size must be positive: " + p.UploadPartSize)
+	}
+	return quantity.Value(), nil
+}
+
+// GetMaxObjectSize parses the max object size in bytes and validates it against the limit of storage, 0 if not
+// specified.
+func (p *BackupStorageProvider) GetMaxObjectSize() (int64, error) {
+	if p.MaxObjectSize == "" {
+		return 0, nil
+	}
+	quantity, err := resource.ParseQuantity(p.MaxObjectSize)
+	if err != nil {
+		return 0, err
+	}
+	action, err := NewBackupStorageFilestreamAction(p.StorageName)
+	if err != nil {
+		return 0, err
+	}
+	if err := filestream.ValidateMaxObjectSize(action.Upload, quantity.Value()); err != nil {
+		return 0, err
+	}
+	return quantity.Value(), nil
+}
+
+// BackupArchive describes the archive which packs full backups of xstores into a single object.
+type BackupArchive struct {
+	// Path is the path of archive object relative to backup root path.
+	Path string `json:"path"`
+
+	// Entries records the byte range of full backup of each xstore in archive.
+	// +optional
+	Entries []BackupArchiveEntry `json:"entries,omitempty"`
+}
+
+// BackupArchiveEntry records the byte range of full backup of a xstore in archive.
+type BackupArchiveEntry struct {
+	// XStore is the name of xstore.
+	XStore string `json:"xstore"`
+
+	// Offset is the offset in bytes of full backup in archive.
+	Offset int64 `json:"offset"`
+
+	// Size is the size in bytes of full backup.
+	Size int64 `json:"size"`
+}
+
+// XStoreStorageProviderOverride overrides the storage provider of backups of xstores selected by role or name, e.g.
+// GMS goes to one sink and DN shards to another.
+type XStoreStorageProviderOverride struct {
+	// +kubebuilder:validation:Enum=gms;dn
+
+	// Role selects the xstores by role, gms or dn.
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// XStore selects the xstore by name, which takes precedence over override selecting by role.
+	// +optional
+	XStore string `json:"xstore,omitempty"`
+
+	// StorageProvider defines the backend storage to store the backup files of selected xstores.
+	StorageProvider BackupStorageProvider `json:"storageProvider"`
+}
+
+// ValidateStorageProviderOverrides checks that each override selects xstores by exactly one of role and name, and
+// that no xstore or role is selected twice.
+func ValidateStorageProviderOverrides(overrides []XStoreStorageProviderOverride) error {
+	selected := make(map[string]bool)
+	for _, override := range overrides {
+		var key string
+		switch {
+		case override.Role != "" && override.XStore != "":
+			return errors.New("override of storage provider selects by both role and xstore: " + override.XStore)
+		case override.Role != "":
+			key = "role/" + override.Role
+		case override.XStore != "":
+			key = "xstore/" + override.XStore
+		default:
+			return errors.New("override of storage provider selects neither role nor xstore")
+		}
+		if selected[key] {
+			return errors.New("storage provider is overridden twice for " + key)
+		}
+		selected[key] = true
+		if override.StorageProvider.StorageName == "" || override.StorageProvider.Sink == "" {
+			return errors.New("storage name and sink must be provided in override for " + key)
+		}
+	}
+	return nil
+}
+
+// BackupArchivePhase defines the phase of consolidating full backups into archive
+type BackupArchivePhase string
+
+// Valid phases of consolidating full backups into archive.
+const (
+	// BackupArchivePacking means the job packing full backups into archive is running
+	BackupArchivePacking BackupArchivePhase = "Packing"
+	// BackupArchivePacked means the archive is verified and recorded, while standalone full backups are not
+	// removed yet
+	BackupArchivePacked BackupArchivePhase = "Packed"
+	// BackupArchiveConsolidated means standalone full backups are removed
+	BackupArchiveConsolidated BackupArchivePhase = "Consolidated"
+	// BackupArchiveFailed means the job failed, full backups are left standalone
+	BackupArchiveFailed BackupArchivePhase = "Failed"
+)
+
+// GetEntry returns the entry of xstore in archive, nil if not found.
+func (a *BackupArchive) GetEntry(xstore string) *BackupArchiveEntry {
+	if a == nil {
+		return nil
+	}
+	for i := range a.Entries {
+		if a.Entries[i].XStore == xstore {
+			return &a.Entries[i]
+		}
+	}
+	return nil
+}
+
+// ForXStore returns the archive with only the entry of xstore, nil if xstore is not in archive.
+func (a *BackupArchive) ForXStore(xstore string) *BackupArchive {
+	entry := a.GetEntry(xstore)
+	if entry == nil {
+		return nil
+	}
+	return &BackupArchive{
+		Path:    a.Path,
+		Entries: []BackupArchiveEntry{*entry},
+	}
+}
+
+// BackupFailureReason defines the reason code of backup failure, which is stable for alerting unlike messages
+type BackupFailureReason string
+
+const (
+	// FailureReasonTargetPodNotFound means no pod is eligible to perform backup on.
+	FailureReasonTargetPodNotFound BackupFailureReason = "TargetPodNotFound"
+	// FailureReasonSinkUnreachable means backup files are unable to be uploaded to the sink.
+	FailureReasonSinkUnreachable BackupFailureReason = "SinkUnreachable"
+	// FailureReasonJobFailed means a job of backup failed.
+	FailureReasonJobFailed BackupFailureReason = "JobFailed"
+	// FailureReasonBinlogPurged means binlog required by backup has been purged.
+	FailureReasonBinlogPurged BackupFailureReason = "BinlogPurged"
+	// FailureReasonTimeout means backup or its job isn't finished within the timeout.
+	FailureReasonTimeout BackupFailureReason = "Timeout"
+	// FailureReasonChecksumMismatch means checksums of backed up data are unavailable or mismatched.
+	FailureReasonChecksumMismatch BackupFailureReason = "ChecksumMismatch"
+	// FailureReasonInsufficientDiskSpace means target pod has no enough disk space for backup.
+	FailureReasonInsufficientDiskSpace BackupFailureReason = "InsufficientDiskSpace"
+	// FailureReasonInvalidSpec means backup is unable to be performed as specified, e.g. on colliding root path.
+	FailureReasonInvalidSpec BackupFailureReason = "InvalidSpec"
+	// FailureReasonTerminated means backup is terminated by its parent.
+	FailureReasonTerminated BackupFailureReason = "Terminated"
+	// FailureReasonUnknown means backup failed for reasons not categorized.
+	FailureReasonUnknown BackupFailureReason = "Unknown"
+)
+
+// SSEAlgorithm defines the algorithm of server-side encryption
+type SSEAlgorithm string
+
+const (
+	// SSEAlgorithmAES256 encrypts objects with keys managed by storage.
+	SSEAlgorithmAES256 SSEAlgorithm = "AES256"
+	// SSEAlgorithmKMS encrypts objects with keys managed by KMS service.
+	SSEAlgorithmKMS SSEAlgorithm = "KMS"
+)
+
+// ServerSideEncryption defines the server-side encryption of objects performed by storage, which is
+// independent of the encryption of backup files by operator.
+type ServerSideEncryption struct {
+	// +kubebuilder:validation:Enum=AES256;KMS
+
+	// Algorithm defines the server-side encryption algorithm, AES256 for keys managed by storage and KMS
+	// for keys managed by KMS service.
+	Algorithm SSEAlgorithm `json:"algorithm,omitempty"`
+
+	// KMSKeyId defines the id of KMS key to encrypt objects, only works with algorithm KMS. The default
+	// KMS key of bucket is used if not specified.
+	// +optional
+	KMSKeyId string `json:"kmsKeyId,omitempty"`
+}
+
+// kmsKeyIdPatterns defines the formats of KMS key id accepted by storages supporting server-side encryption.
+var kmsKeyIdPatterns = map[BackupStorage]*regexp.Regexp{
+	// key id, e.g. key-hzz6xxx or uuid of legacy keys, or arn of key
+	OSS: regexp.MustCompile(`^(key-[0-9a-z]+|[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}|acs:kms:[a-z0-9-]+:[0-9]+:key/[0-9a-z-]+)$`),
+	// arn of key or alias, alias, multi-region key id or key name of minio KES
+	MINIO: regexp.MustCompile(`^(arn:aws:kms:[a-z0-9-]+:[0-9]{12}:(key|alias)/[A-Za-z0-9/_-]+|alias/[A-Za-z0-9/_-]+|[A-Za-z0-9][A-Za-z0-9_.-]*)$`),
+}
+
+// ValidateServerSideEncryption checks that the server-side encryption is supported by storage, and the KMS key id
+// matches the format of storage.
+func (p *BackupStorageProvider) ValidateServerSideEncryption() error {
+	sse := p.ServerSideEncryption
+	if sse == nil {
+		return nil
+	}
+	pattern, ok := kmsKeyIdPatterns[p.StorageName]
+	if !ok {
+		return errors.New("server-side encryption is not supported by storage: " + string(p.StorageName))
+	}
+	switch sse.Algorithm {
+	case SSEAlgorithmAES256:
+		if sse.KMSKeyId != "" {
+			return errors.New("kms key id only works with algorithm " + string(SSEAlgorithmKMS))
+		}
+	case SSEAlgorithmKMS:
+		if sse.KMSKeyId != "" && !pattern.MatchString(sse.KMSKeyId) {
+			return errors.New("invalid kms key id for storage " + string(p.StorageName) + ": " + sse.KMSKeyId)
+		}
+	default:
+		return errors.New("unsupported server-side encryption algorithm: " + string(sse.Algorithm))
+	}
+	return nil
+}
+
+// GetServerSideEncryption returns the algorithm and KMS key id of server-side encryption, empty if not required.
+func (p *BackupStorageProvider) GetServerSideEncryption() (algorithm string, kmsKeyId string) {
+	if p.ServerSideEncryption == nil {
+		return "", ""
+	}
+	return string(p.ServerSideEncryption.Algorithm), p.ServerSideEncryption.KMSKeyId
+}
+
 // BackupStorage defines the storage of backup
 type BackupStorage string
 
@@ -38,8 +328,13 @@ const (
 	OSS   BackupStorage = "oss"
 	SFTP  BackupStorage = "sftp"
 	MINIO BackupStorage = "s3"
+	AZURE BackupStorage = "azure"
+	GCS   BackupStorage = "gcs"
 )
 
+// SupportedBackupStorages lists all the storages that backup is able to perform on
+var SupportedBackupStorages = []BackupStorage{OSS, SFTP, MINIO, AZURE, GCS}
+
 // BackupStorageFilestreamAction records filestream actions related to specified backup storage
 type BackupStorageFilestreamAction struct {
 	Download filestream.Action
@@ -67,11 +362,361 @@ func NewBackupStorageFilestreamAction(storage BackupStorage) (*BackupStorageFile
 			Upload:   filestream.UploadMinio,
 			List:     filestream.ListMinio,
 		}, nil
+	case AZURE:
+		return &BackupStorageFilestreamAction{
+			Download: filestream.DownloadAzure,
+			Upload:   filestream.UploadAzure,
+			List:     filestream.ListAzure,
+		}, nil
+	case GCS:
+		return &BackupStorageFilestreamAction{
+			Download: filestream.DownloadGcs,
+			Upload:   filestream.UploadGcs,
+			List:     filestream.ListGcs,
+		}, nil
 	default:
 		return nil, errors.New("invalid storage: " + string(storage))
 	}
 }
 
+// BackupMode defines whether binlog is backed up along with the full backup
+type BackupMode string
+
+const (
+	// BackupModePITR backs up binlog along with the full backup, so that the backup set can be
+	// restored to any point in time covered by binlog.
+	BackupModePITR BackupMode = "pitr"
+	// BackupModeSnapshot takes only the full backup, the backup set can only be restored to the snapshot point.
+	BackupModeSnapshot BackupMode = "snapshot"
+)
+
+// MaxQuiesceLockDuration is the upper bound of lock duration of quiesce, regardless of the spec.
+const MaxQuiesceLockDuration = 30 * time.Second
+
+// DefaultQuiesceLockDuration is the lock duration of quiesce if not specified.
+const DefaultQuiesceLockDuration = 5 * time.Second
+
+// QuiesceSpec defines the coordinated quiesce of xstores before full backups begin, which flushes tables
+// with global read lock on leaders of all the xstores at once, records their binlog positions as a globally
+// consistent point and releases the lock. Writes are blocked during the quiesce.
+type QuiesceSpec struct {
+	// MaxLockDuration bounds the time that xstores are locked, including the time waiting for the lock. Quiesce
+	// is abandoned and retried if exceeded. Default is 5s, and no more than 30s.
+	// +optional
+	MaxLockDuration *metav1.Duration `json:"maxLockDuration,omitempty"`
+}
+
+// GetMaxLockDuration returns the lock duration of quiesce, defaulted and capped.
+func (q *QuiesceSpec) GetMaxLockDuration() time.Duration {
+	if q.MaxLockDuration == nil || q.MaxLockDuration.Duration <= 0 {
+		return DefaultQuiesceLockDuration
+	}
+	if q.MaxLockDuration.Duration > MaxQuiesceLockDuration {
+		return MaxQuiesceLockDuration
+	}
+	return q.MaxLockDuration.Duration
+}
+
+// BinlogCollectMargin extends the range of binlog collected by whole binlog files, so that the binlog collected
+// overlaps with the full backup beyond doubt. Lookback must not reach binlog files already purged.
+type BinlogCollectMargin struct {
+	// LookbackFiles moves the start of collecting back by the count of binlog files, to the beginning of the
+	// earlier file.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LookbackFiles int32 `json:"lookbackFiles,omitempty"`
+
+	// LookaheadFiles moves the end of collecting forward by the count of binlog files, to the end of the later
+	// file, or to the current offset if it's the latest one.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LookaheadFiles int32 `json:"lookaheadFiles,omitempty"`
+}
+
+// BackupJobCommandOverride overrides the container of backup jobs, e.g. for engine images with backup tools
+// installed elsewhere. Each argument of the commands is a go template, rendered with the paths of backup job
+// context (e.g. {{ .FullBackupPath }}), the mounted job context file {{ .BackupContext }}, {{ .JobName }} and
+// the default command {{ .Command }} for wrapping. Jobs are still created, watched and cleaned by the operator.
+type BackupJobCommandOverride struct {
+	// ContainerName overrides the name of container of backup jobs.
+	// +optional
+	ContainerName string `json:"containerName,omitempty"`
+
+	// FullBackup overrides the command of full backup job.
+	// +optional
+	FullBackup []string `json:"fullBackup,omitempty"`
+
+	// Collect overrides the command of binlog collect job, which renders {{ .HeartbeatName }} as well.
+	// +optional
+	Collect []string `json:"collect,omitempty"`
+
+	// BinlogBackup overrides the command of binlog backup job, which renders {{ .CommitIndex }},
+	// {{ .XStoreName }} and {{ .IsGMS }} as well.
+	// +optional
+	BinlogBackup []string `json:"binlogBackup,omitempty"`
+}
+
+// ValidateBinlogExcludePatterns checks that the patterns to exclude binlog files are valid shell patterns.
+func ValidateBinlogExcludePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return errors.New("empty binlog exclude pattern")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.New("invalid binlog exclude pattern: " + pattern)
+		}
+	}
+	return nil
+}
+
+// SystemDatabases are always backed up even if databases to back up are filtered, which are required by engine.
+var SystemDatabases = []string{"mysql", "sys"}
+
+// ValidateDatabaseFilter checks that the databases to include or exclude in backup are not empty, and that
+// system databases are not excluded.
+func ValidateDatabaseFilter(includeDatabases, excludeDatabases []string) error {
+	for _, db := range append(append([]string(nil), includeDatabases...), excludeDatabases...) {
+		if strings.TrimSpace(db) == "" || strings.ContainsAny(db, " ,") {
+			return errors.New("invalid database name: \"" + db + "\"")
+		}
+	}
+	for _, db := range excludeDatabases {
+		for _, systemDb := range SystemDatabases {
+			if db == systemDb {
+				return errors.New("system database can not be excluded: " + db)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateChecksumTables checks that the tables to checksum are in the form of "schema.table".
+func ValidateChecksumTables(tables []string) error {
+	for _, table := range tables {
+		schema, name, ok := strings.Cut(table, ".")
+		if !ok || schema == "" || name == "" || strings.Contains(name, ".") {
+			return errors.New("invalid checksum table, expect schema.table: " + table)
+		}
+	}
+	return nil
+}
+
+// TableChecksumSample records how rows are sampled when checksumming tables. Rows are selected by the hash of
+// primary key seeded by Seed, so that the same rows are sampled when verified after restore.
+type TableChecksumSample struct {
+	// Percent is the percentage of rows sampled.
+	Percent int32 `json:"percent"`
+
+	// Seed seeds the hash selecting rows.
+	Seed int64 `json:"seed"`
+
+	// SampledRows records the count of rows sampled of each table, keyed by "schema.table".
+	// +optional
+	SampledRows map[string]int64 `json:"sampledRows,omitempty"`
+}
+
+// KeyringReEncryption re-encrypts the keyring exported by backup with a target key, so that the keyring of restored
+// clusters is protected by the rotated key. Both the source key and the target key are read from the secret in the
+// namespace of backup, and backup fails if any of them is unavailable.
+type KeyringReEncryption struct {
+	// SecretName is the name of secret holding the keys.
+	SecretName string `json:"secretName"`
+
+	// SourceKey is the key in secret of the key currently in use, "source-key" by default.
+	// +optional
+	SourceKey string `json:"sourceKey,omitempty"`
+
+	// TargetKey is the key in secret of the key to rotate to, "target-key" by default.
+	// +optional
+	TargetKey string `json:"targetKey,omitempty"`
+}
+
+const (
+	DefaultKeyringSourceKey = "source-key"
+	DefaultKeyringTargetKey = "target-key"
+)
+
+func (k *KeyringReEncryption) GetSourceKey() string {
+	if k.SourceKey == "" {
+		return DefaultKeyringSourceKey
+	}
+	return k.SourceKey
+}
+
+func (k *KeyringReEncryption) GetTargetKey() string {
+	if k.TargetKey == "" {
+		return DefaultKeyringTargetKey
+	}
+	return k.TargetKey
+}
+
+// SourceKeySelector selects the source key in secret.
+func (k *KeyringReEncryption) SourceKeySelector() *corev1.SecretKeySelector {
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: k.SecretName},
+		Key:                  k.GetSourceKey(),
+	}
+}
+
+// TargetKeySelector selects the target key in secret, by which the re-encrypted keyring is decrypted.
+func (k *KeyringReEncryption) TargetKeySelector() *corev1.SecretKeySelector {
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: k.SecretName},
+		Key:                  k.GetTargetKey(),
+	}
+}
+
+// ValidateKeys checks that both keys are available in secret and differ from each other, and returns the
+// fingerprint of the target key.
+func (k *KeyringReEncryption) ValidateKeys(secret *corev1.Secret) (string, error) {
+	sourceKey, targetKey := secret.Data[k.GetSourceKey()], secret.Data[k.GetTargetKey()]
+	if len(sourceKey) == 0 {
+		return "", fmt.Errorf("source key %s not found in secret %s", k.GetSourceKey(), k.SecretName)
+	}
+	if len(targetKey) == 0 {
+		return "", fmt.Errorf("target key %s not found in secret %s", k.GetTargetKey(), k.SecretName)
+	}
+	if bytes.Equal(sourceKey, targetKey) {
+		return "", errors.New("target key is the same as source key in secret " + k.SecretName)
+	}
+	return KeyFingerprint(targetKey), nil
+}
+
+// KeyFingerprint returns the fingerprint of key, which is the hex encoded sha256 of it.
+func KeyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])
+}
+
+// BackupCompression defines how files of backup set are compressed by backup jobs before uploaded.
+type BackupCompression string
+
+const (
+	// BackupCompressionNone uploads files as they are.
+	BackupCompressionNone BackupCompression = "none"
+	// BackupCompressionGzip compresses files by gzip.
+	BackupCompressionGzip BackupCompression = "gzip"
+	// BackupCompressionQuickLZ is the compression of xtrabackup, which is applied on full backups only if
+	// compression is not specified. It's never specified by backups but recorded in codecs of full backups.
+	BackupCompressionQuickLZ BackupCompression = "quicklz"
+)
+
+// BackupEncryptionAlgorithm defines the algorithm which files of backup set are encrypted with by backup jobs.
+type BackupEncryptionAlgorithm string
+
+const (
+	// BackupEncryptionAES256 encrypts files by AES-256-CBC with key derived from the secret by PBKDF2.
+	BackupEncryptionAES256 BackupEncryptionAlgorithm = "AES256"
+)
+
+// BackupEncryption encrypts files of backup set by backup jobs before uploaded, which is independent of the
+// server-side encryption performed by storage. The key is read from the secret in the namespace of backup and
+// passed to jobs by env, it's never written to the backup set and required to restore.
+type BackupEncryption struct {
+	// Algorithm defines the encryption algorithm, AES256 by default.
+	// +kubebuilder:validation:Enum=AES256
+	// +optional
+	Algorithm BackupEncryptionAlgorithm `json:"algorithm,omitempty"`
+
+	// SecretName is the name of secret holding the key.
+	SecretName string `json:"secretName"`
+
+	// Key is the key in secret of the encryption key, "key" by default.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+const DefaultBackupEncryptionKey = "key"
+
+func (e *BackupEncryption) GetAlgorithm() BackupEncryptionAlgorithm {
+	if e.Algorithm == "" {
+		return BackupEncryptionAES256
+	}
+	return e.Algorithm
+}
+
+func (e *BackupEncryption) GetKey() string {
+	if e.Key == "" {
+		return DefaultBackupEncryptionKey
+	}
+	return e.Key
+}
+
+// KeySelector selects the encryption key in secret.
+func (e *BackupEncryption) KeySelector() *corev1.SecretKeySelector {
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: e.SecretName},
+		Key:                  e.GetKey(),
+	}
+}
+
+// ValidateKey checks that the encryption key is available in secret, and returns the fingerprint of it.
+func (e *BackupEncryption) ValidateKey(secret *corev1.Secret) (string, error) {
+	key := secret.Data[e.GetKey()]
+	if len(key) == 0 {
+		return "", fmt.Errorf("encryption key %s not found in secret %s", e.GetKey(), e.SecretName)
+	}
+	return KeyFingerprint(key), nil
+}
+
+// BackupArtifact defines the kind of files in backup set of xstore.
+type BackupArtifact string
+
+const (
+	BackupArtifactFullBackup BackupArtifact = "fullBackup"
+	BackupArtifactBinlog     BackupArtifact = "binlog"
+)
+
+// BackupCodec records how files of an artifact are processed by backup job before uploaded, restore reverses it
+// to read the files. Files are compressed before encrypted.
+type BackupCodec struct {
+	// Compression is the compression applied on files.
+	// +optional
+	Compression BackupCompression `json:"compression,omitempty"`
+
+	// Encryption is the encryption algorithm applied on files, empty if not encrypted.
+	// +optional
+	Encryption BackupEncryptionAlgorithm `json:"encryption,omitempty"`
+
+	// KeyFingerprint is the fingerprint of the encryption key, which restore checks the key against.
+	// +optional
+	KeyFingerprint string `json:"keyFingerprint,omitempty"`
+}
+
+// NewBackupCodec returns the codec applied on files of artifact by the compression and encryption of backup. If
+// compression is not specified, full backups are compressed by xtrabackup and binlogs are uploaded as they are,
+// which is what backups taken before codecs were introduced look like.
+func NewBackupCodec(artifact BackupArtifact, compression BackupCompression, encryption *BackupEncryption,
+	keyFingerprint string) BackupCodec {
+	codec := BackupCodec{Compression: compression}
+	if codec.Compression == "" {
+		codec.Compression = BackupCompressionNone
+		if artifact == BackupArtifactFullBackup {
+			codec.Compression = BackupCompressionQuickLZ
+		}
+	}
+	if encryption != nil {
+		codec.Encryption = encryption.GetAlgorithm()
+		codec.KeyFingerprint = keyFingerprint
+	}
+	return codec
+}
+
+// OrDefault returns the codec itself, or the default codec of artifact if not recorded, which is the codec of
+// backups taken before codecs were recorded.
+func (c *BackupCodec) OrDefault(artifact BackupArtifact) *BackupCodec {
+	if c != nil {
+		return c
+	}
+	codec := NewBackupCodec(artifact, "", nil, "")
+	return &codec
+}
+
+// IsEncrypted tells whether files are encrypted.
+func (c *BackupCodec) IsEncrypted() bool {
+	return c != nil && c.Encryption != ""
+}
+
 type CleanPolicyType string
 
 const (
@@ -87,3 +732,12 @@ const (
 	// when the backup object is deleted.
 	CleanPolicyOnFailure CleanPolicyType = "OnFailure"
 )
+
+// OverallTimeoutExceeded tells whether backup started at startTime runs beyond the overall timeout at now. It's
+// never exceeded if the timeout is not set or the backup is not started.
+func OverallTimeoutExceeded(startTime *metav1.Time, timeout *metav1.Duration, now time.Time) bool {
+	if startTime == nil || timeout == nil || timeout.Duration <= 0 {
+		return false
+	}
+	return now.Sub(startTime.Time) > timeout.Duration
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polardbx
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// +kubebuilder:validation:Enum=aggressive;default;relaxed;""
+
+// ProbeProfile is a named preset of timeout, period and failure threshold of liveness and readiness probes
+// of cn engine, cdc engine and their exporters. Values are listed in "timeout/period/failure threshold"
+// seconds, where 3 is the default failure threshold of kubernetes.
+type ProbeProfile string
+
+// Valid probe profiles.
+const (
+	// ProbeProfileAggressive detects failures fast.
+	// Engines: 5/5/3, exporters: 3/10/3.
+	ProbeProfileAggressive ProbeProfile = "aggressive"
+
+	// ProbeProfileDefault is used if no profile is specified.
+	// CN engine: 10/10/3, cdc engine: 10/10/5, exporters: 5/20/3.
+	ProbeProfileDefault ProbeProfile = "default"
+
+	// ProbeProfileRelaxed tolerates long pauses, e.g. on nodes with known GC pauses.
+	// CN engine: 30/20/6, cdc engine: 30/20/10, exporters: 10/30/6.
+	ProbeProfileRelaxed ProbeProfile = "relaxed"
+)
+
+// ProbeOverride overrides fields of liveness and readiness probes of a component. Fields not specified
+// are taken from the probe profile.
+type ProbeOverride struct {
+	// +kubebuilder:validation:Minimum=1
+
+	// TimeoutSeconds overrides the probe timeout.
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// PeriodSeconds overrides the probe period.
+	// +optional
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// FailureThreshold overrides the probe failure threshold.
+	// +optional
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=HTTP;TCP;Exec;""
+
+// ExporterReadinessType is the way how readiness of exporter is checked.
+type ExporterReadinessType string
+
+// Valid exporter readiness types.
+const (
+	// ExporterReadinessHTTP scrapes the metrics endpoint, which is expensive for large metric sets.
+	ExporterReadinessHTTP ExporterReadinessType = "HTTP"
+
+	// ExporterReadinessTCP checks the metrics port is listening.
+	ExporterReadinessTCP ExporterReadinessType = "TCP"
+
+	// ExporterReadinessExec executes the command in exporter container.
+	ExporterReadinessExec ExporterReadinessType = "Exec"
+)
+
+// ExporterReadiness defines the readiness check of exporter.
+type ExporterReadiness struct {
+	// Type represents the readiness check type. Default is HTTP.
+	// +optional
+	Type ExporterReadinessType `json:"type,omitempty"`
+
+	// Command represents the command executed for Exec readiness check, which falls back to TCP if
+	// the command is empty.
+	// +optional
+	Command []string `json:"command,omitempty"`
+}
+
+// ProbeConfig defines the probes of stateless nodes. Startup probes are not affected.
+type ProbeConfig struct {
+	// Profile represents the probe profile. Default is "default".
+	// +optional
+	Profile ProbeProfile `json:"profile,omitempty"`
+
+	// CNEngine overrides the probes of cn engine.
+	// +optional
+	CNEngine *ProbeOverride `json:"cnEngine,omitempty"`
+
+	// CNExporter overrides the probes of cn exporter.
+	// +optional
+	CNExporter *ProbeOverride `json:"cnExporter,omitempty"`
+
+	// CNExporterReadiness defines the readiness check of cn exporter. Default is HTTP.
+	// +optional
+	CNExporterReadiness *ExporterReadiness `json:"cnExporterReadiness,omitempty"`
+
+	// CNStartupTimeout defines how long cn engine is allowed to start before restarted, which is to be scaled
+	// with the metadata of cluster, e.g. longer for clusters of many tables. Default is 50m.
+	// +optional
+	CNStartupTimeout *metav1.Duration `json:"cnStartupTimeout,omitempty"`
+
+	// CDCEngine overrides the probes of cdc engine.
+	// +optional
+	CDCEngine *ProbeOverride `json:"cdcEngine,omitempty"`
+
+	// CDCExporter overrides the probes of cdc exporter.
+	// +optional
+	CDCExporter *ProbeOverride `json:"cdcExporter,omitempty"`
+}
@@ -49,8 +49,46 @@ type RestoreSpec struct {
 	// BinlogSource defines the binlog datasource
 	// +optional
 	BinlogSource *RestoreBinlogSource `json:"binlogSource,omitempty"`
+
+	// +kubebuilder:default=All
+	// +kubebuilder:validation:Enum=All;None;Selected
+
+	// AccountRestorePolicy defines which accounts are restored from backup. "All" restores all the accounts,
+	// "None" keeps accounts declared by the restored cluster only, and "Selected" restores only the accounts
+	// named in Accounts on top of the declared ones. Root account always exists regardless. Default is All.
+	// +optional
+	AccountRestorePolicy AccountRestorePolicy `json:"accountRestorePolicy,omitempty"`
+
+	// Accounts defines names of accounts to restore from backup, works only when AccountRestorePolicy is Selected.
+	// +optional
+	Accounts []string `json:"accounts,omitempty"`
+
+	// XStoreNameMapping maps names of xstores in the backup set to names of xstores of the restored cluster,
+	// which is required when restoring into a cluster of a different name or namespace. Names of the restored
+	// xstores can be either full or without the "<cluster name>-<rand>-" prefix generated on creation, i.e.
+	// "gms" and "dn-<index>". If specified, every xstore in the backup set must be mapped to a distinct xstore
+	// of the restored cluster, from which its data and accounts are restored. Otherwise, xstores are matched
+	// by name suffix.
+	// +optional
+	XStoreNameMapping map[string]string `json:"xstoreNameMapping,omitempty"`
+
+	// DryRun validates the restore without applying it. Metadata of the backup set is downloaded and validated,
+	// objects referenced are checked to exist with keyrings matching their checksums, and compatibility of
+	// versions and free space on nodes are checked. Readiness is reported in condition RestoreReady, while the
+	// cluster stays pending and nothing is created until DryRun is turned off.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
 }
 
+// AccountRestorePolicy defines which accounts are restored from backup.
+type AccountRestorePolicy string
+
+const (
+	AccountRestoreAll      AccountRestorePolicy = "All"
+	AccountRestoreNone     AccountRestorePolicy = "None"
+	AccountRestoreSelected AccountRestorePolicy = "Selected"
+)
+
 // PolarDBXRestoreFrom defines the source information of the restored cluster.
 type PolarDBXRestoreFrom struct {
 	// PolarBDXName defines the polardbx name that this polardbx is restored from. Optional.
@@ -76,3 +114,11 @@ type RestoreBinlogSource struct {
 	//SName defines the heartbeat sname in the cdc heartbeat table
 	HeartbeatSName string `json:"heartbeatSName,omitempty"`
 }
+
+// BinlogApplySpec defines the binlog backup applied onto a restored cluster to roll it forward.
+type BinlogApplySpec struct {
+	// BackupSet defines the backup set of the original cluster, whose binlog backup is applied onto DNs.
+	// Binlog of the backup set must be continuous with the data restored or applied before. Schema changes
+	// in between are not rolled forward.
+	BackupSet string `json:"backupset"`
+}
@@ -27,18 +27,19 @@ type Phase string
 
 // Valid phases.
 const (
-	PhaseNew        Phase = ""
-	PhasePending    Phase = "Pending"
-	PhaseCreating   Phase = "Creating"
-	PhaseRunning    Phase = "Running"
-	PhaseLocked     Phase = "Locked"
-	PhaseUpgrading  Phase = "Upgrading"
-	PhaseRestoring  Phase = "Restoring"
-	PhaseDeleting   Phase = "Deleting"
-	PhaseFailed     Phase = "Failed"
-	PhaseRestarting Phase = "Restarting"
-	PhaseUnknown    Phase = "Unknown"
-	PhaseTdeOpening Phase = "TdeOpening"
+	PhaseNew            Phase = ""
+	PhasePending        Phase = "Pending"
+	PhaseCreating       Phase = "Creating"
+	PhaseRunning        Phase = "Running"
+	PhaseLocked         Phase = "Locked"
+	PhaseUpgrading      Phase = "Upgrading"
+	PhaseRestoring      Phase = "Restoring"
+	PhaseDeleting       Phase = "Deleting"
+	PhaseFailed         Phase = "Failed"
+	PhaseRestarting     Phase = "Restarting"
+	PhaseUnknown        Phase = "Unknown"
+	PhaseTdeOpening     Phase = "TdeOpening"
+	PhaseApplyingBinlog Phase = "ApplyingBinlog"
 )
 
 // Stage defines the operating stage of the cluster.
@@ -63,6 +64,7 @@ const (
 	DnsReady     ConditionType = "DnsReady"
 	CdcReady     ConditionType = "CdcReady"
 	ClusterReady ConditionType = "ClusterReady"
+	RestoreReady ConditionType = "RestoreReady"
 )
 
 // Condition defines the condition and its status.
@@ -165,6 +167,37 @@ type PitrStatus struct {
 	Job                string `json:"job,omitempty"`
 }
 
+// BinlogApplyPhase defines the phase of applying binlog backup onto restored cluster.
+type BinlogApplyPhase string
+
+const (
+	BinlogApplyApplying BinlogApplyPhase = "Applying"
+	BinlogApplyFinished BinlogApplyPhase = "Finished"
+	BinlogApplyFailed   BinlogApplyPhase = "Failed"
+)
+
+// BinlogApplyStatus represents the status of applying binlog backup onto restored cluster.
+type BinlogApplyStatus struct {
+	// BackupSet is the backup set whose binlog is applied.
+	BackupSet string `json:"backupset,omitempty"`
+
+	// Phase is the phase of applying.
+	Phase BinlogApplyPhase `json:"phase,omitempty"`
+
+	// Message includes human-readable message related to current phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// AppliedIndexes records the commit index of original DN which binlog has been applied up to,
+	// keyed by name of DN. Binlog applied next time must continue from it.
+	// +optional
+	AppliedIndexes map[string]int64 `json:"appliedIndexes,omitempty"`
+
+	// AppliedTimestamp records the time of original cluster which data has been rolled forward to.
+	// +optional
+	AppliedTimestamp *metav1.Time `json:"appliedTimestamp,omitempty"`
+}
+
 type MonitorStatus string
 
 const (
@@ -135,6 +135,13 @@ type CNTemplate struct {
 
 	// Resources. Default is limits of 4 cpu and 8Gi memory.
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// +kubebuilder:validation:Enum=polardbx;columnar
+
+	// ProbeTarget is the target type of probes on the engine container. Columnar or mixed-engine
+	// CN should use columnar. Default is polardbx.
+	// +optional
+	ProbeTarget string `json:"probeTarget,omitempty"`
 }
 
 type CDCTemplate struct {
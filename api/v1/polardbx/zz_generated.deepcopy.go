@@ -22,9 +22,105 @@ package polardbx
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupArchive) DeepCopyInto(out *BackupArchive) {
+	*out = *in
+	if in.Entries != nil {
+		in, out := &in.Entries, &out.Entries
+		*out = make([]BackupArchiveEntry, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupArchive.
+func (in *BackupArchive) DeepCopy() *BackupArchive {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupArchive)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupArchiveEntry) DeepCopyInto(out *BackupArchiveEntry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupArchiveEntry.
+func (in *BackupArchiveEntry) DeepCopy() *BackupArchiveEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupArchiveEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupCodec) DeepCopyInto(out *BackupCodec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupCodec.
+func (in *BackupCodec) DeepCopy() *BackupCodec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupCodec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupEncryption) DeepCopyInto(out *BackupEncryption) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupEncryption.
+func (in *BackupEncryption) DeepCopy() *BackupEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupJobCommandOverride) DeepCopyInto(out *BackupJobCommandOverride) {
+	*out = *in
+	if in.FullBackup != nil {
+		in, out := &in.FullBackup, &out.FullBackup
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Collect != nil {
+		in, out := &in.Collect, &out.Collect
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BinlogBackup != nil {
+		in, out := &in.BinlogBackup, &out.BinlogBackup
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupJobCommandOverride.
+func (in *BackupJobCommandOverride) DeepCopy() *BackupJobCommandOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupJobCommandOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BackupStorageFilestreamAction) DeepCopyInto(out *BackupStorageFilestreamAction) {
 	*out = *in
@@ -43,6 +139,11 @@ func (in *BackupStorageFilestreamAction) DeepCopy() *BackupStorageFilestreamActi
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BackupStorageProvider) DeepCopyInto(out *BackupStorageProvider) {
 	*out = *in
+	if in.ServerSideEncryption != nil {
+		in, out := &in.ServerSideEncryption, &out.ServerSideEncryption
+		*out = new(ServerSideEncryption)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStorageProvider.
@@ -55,6 +156,62 @@ func (in *BackupStorageProvider) DeepCopy() *BackupStorageProvider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BinlogApplySpec) DeepCopyInto(out *BinlogApplySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BinlogApplySpec.
+func (in *BinlogApplySpec) DeepCopy() *BinlogApplySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BinlogApplySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BinlogApplyStatus) DeepCopyInto(out *BinlogApplyStatus) {
+	*out = *in
+	if in.AppliedIndexes != nil {
+		in, out := &in.AppliedIndexes, &out.AppliedIndexes
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AppliedTimestamp != nil {
+		in, out := &in.AppliedTimestamp, &out.AppliedTimestamp
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BinlogApplyStatus.
+func (in *BinlogApplyStatus) DeepCopy() *BinlogApplyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BinlogApplyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BinlogCollectMargin) DeepCopyInto(out *BinlogCollectMargin) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BinlogCollectMargin.
+func (in *BinlogCollectMargin) DeepCopy() *BinlogCollectMargin {
+	if in == nil {
+		return nil
+	}
+	out := new(BinlogCollectMargin)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CDCConfig) DeepCopyInto(out *CDCConfig) {
 	*out = *in
@@ -337,6 +494,26 @@ func (in *DNConfig) DeepCopy() *DNConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterReadiness) DeepCopyInto(out *ExporterReadiness) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterReadiness.
+func (in *ExporterReadiness) DeepCopy() *ExporterReadiness {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterReadiness)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FileStorageInfo) DeepCopyInto(out *FileStorageInfo) {
 	*out = *in
@@ -352,6 +529,21 @@ func (in *FileStorageInfo) DeepCopy() *FileStorageInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyringReEncryption) DeepCopyInto(out *KeyringReEncryption) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyringReEncryption.
+func (in *KeyringReEncryption) DeepCopy() *KeyringReEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyringReEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeSelectorItem) DeepCopyInto(out *NodeSelectorItem) {
 	*out = *in
@@ -455,6 +647,101 @@ func (in *PrivilegeItem) DeepCopy() *PrivilegeItem {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbeConfig) DeepCopyInto(out *ProbeConfig) {
+	*out = *in
+	if in.CNEngine != nil {
+		in, out := &in.CNEngine, &out.CNEngine
+		*out = new(ProbeOverride)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CNExporter != nil {
+		in, out := &in.CNExporter, &out.CNExporter
+		*out = new(ProbeOverride)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CNExporterReadiness != nil {
+		in, out := &in.CNExporterReadiness, &out.CNExporterReadiness
+		*out = new(ExporterReadiness)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CNStartupTimeout != nil {
+		in, out := &in.CNStartupTimeout, &out.CNStartupTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.CDCEngine != nil {
+		in, out := &in.CDCEngine, &out.CDCEngine
+		*out = new(ProbeOverride)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CDCExporter != nil {
+		in, out := &in.CDCExporter, &out.CDCExporter
+		*out = new(ProbeOverride)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeConfig.
+func (in *ProbeConfig) DeepCopy() *ProbeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbeOverride) DeepCopyInto(out *ProbeOverride) {
+	*out = *in
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PeriodSeconds != nil {
+		in, out := &in.PeriodSeconds, &out.PeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FailureThreshold != nil {
+		in, out := &in.FailureThreshold, &out.FailureThreshold
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeOverride.
+func (in *ProbeOverride) DeepCopy() *ProbeOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbeOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuiesceSpec) DeepCopyInto(out *QuiesceSpec) {
+	*out = *in
+	if in.MaxLockDuration != nil {
+		in, out := &in.MaxLockDuration, &out.MaxLockDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuiesceSpec.
+func (in *QuiesceSpec) DeepCopy() *QuiesceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(QuiesceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReadonlyParam) DeepCopyInto(out *ReadonlyParam) {
 	*out = *in
@@ -533,7 +820,7 @@ func (in *RestoreBinlogSource) DeepCopyInto(out *RestoreBinlogSource) {
 	if in.StorageProvider != nil {
 		in, out := &in.StorageProvider, &out.StorageProvider
 		*out = new(BackupStorageProvider)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -553,7 +840,7 @@ func (in *RestoreSpec) DeepCopyInto(out *RestoreSpec) {
 	if in.StorageProvider != nil {
 		in, out := &in.StorageProvider, &out.StorageProvider
 		*out = new(BackupStorageProvider)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	in.From.DeepCopyInto(&out.From)
 	if in.BinlogSource != nil {
@@ -561,6 +848,18 @@ func (in *RestoreSpec) DeepCopyInto(out *RestoreSpec) {
 		*out = new(RestoreBinlogSource)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Accounts != nil {
+		in, out := &in.Accounts, &out.Accounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.XStoreNameMapping != nil {
+		in, out := &in.XStoreNameMapping, &out.XStoreNameMapping
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreSpec.
@@ -598,6 +897,21 @@ func (in *Security) DeepCopy() *Security {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSideEncryption) DeepCopyInto(out *ServerSideEncryption) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSideEncryption.
+func (in *ServerSideEncryption) DeepCopy() *ServerSideEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSideEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SpecSnapshot) DeepCopyInto(out *SpecSnapshot) {
 	*out = *in
@@ -690,6 +1004,28 @@ func (in *TLS) DeepCopy() *TLS {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TableChecksumSample) DeepCopyInto(out *TableChecksumSample) {
+	*out = *in
+	if in.SampledRows != nil {
+		in, out := &in.SampledRows, &out.SampledRows
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TableChecksumSample.
+func (in *TableChecksumSample) DeepCopy() *TableChecksumSample {
+	if in == nil {
+		return nil
+	}
+	out := new(TableChecksumSample)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Topology) DeepCopyInto(out *Topology) {
 	*out = *in
@@ -905,6 +1241,22 @@ func (in *TopologyRules) DeepCopy() *TopologyRules {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *XStoreStorageProviderOverride) DeepCopyInto(out *XStoreStorageProviderOverride) {
+	*out = *in
+	in.StorageProvider.DeepCopyInto(&out.StorageProvider)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XStoreStorageProviderOverride.
+func (in *XStoreStorageProviderOverride) DeepCopy() *XStoreStorageProviderOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(XStoreStorageProviderOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *XStoreTemplate) DeepCopyInto(out *XStoreTemplate) {
 	*out = *in
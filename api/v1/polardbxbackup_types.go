@@ -47,12 +47,117 @@ type PolarDBXBackupSpec struct {
 	// StorageProvider defines the backend storage to store the backup files.
 	StorageProvider polardbx.BackupStorageProvider `json:"storageProvider,omitempty"`
 
+	// BackupClass refers to the backup class defined in config of operator, which bundles storage provider,
+	// retention time and schedule. Storage provider and retention time not specified are filled from the class
+	// when backup starts. Falls back to the class labeled on the cluster if not specified.
+	// +optional
+	BackupClass string `json:"backupClass,omitempty"`
+
 	// +kubebuilder:default=follower
 	// +kubebuilder:validation:Enum=leader;follower
 
 	// PreferredBackupRole defines the role of node on which backup will happen
 	// +optional
 	PreferredBackupRole string `json:"preferredBackupRole,omitempty"`
+
+	// ForbidBackupOnLeader forbids backup to happen on leader. If set, backup waits for a follower to be
+	// available as target pod, and fails if only leader is available. Otherwise backup on leader proceeds
+	// with a warning.
+	// +optional
+	ForbidBackupOnLeader bool `json:"forbidBackupOnLeader,omitempty"`
+
+	// +kubebuilder:default=pitr
+	// +kubebuilder:validation:Enum=pitr;snapshot
+
+	// BackupMode defines whether binlog is backed up along with the full backup. Backup in snapshot mode
+	// skips the binlog collection and backup, and can only be restored to the snapshot point. Default is pitr.
+	// +optional
+	BackupMode polardbx.BackupMode `json:"backupMode,omitempty"`
+
+	// XStoreSelector filters the xstores to be backed up by their labels. All the xstores
+	// of the cluster are backed up if not specified.
+	// +optional
+	XStoreSelector *metav1.LabelSelector `json:"xstoreSelector,omitempty"`
+
+	// BackupJobCommandOverride overrides the container name and commands of backup jobs of xstores.
+	// +optional
+	BackupJobCommandOverride *polardbx.BackupJobCommandOverride `json:"backupJobCommandOverride,omitempty"`
+
+	// BinlogExcludePatterns defines the shell patterns of binlog file names skipped by binlog backup of xstores.
+	// Files skipped leave gaps in the recoverable range.
+	// +optional
+	BinlogExcludePatterns []string `json:"binlogExcludePatterns,omitempty"`
+
+	// RefreshSecret refreshes the saved accounts of xstores from their live secrets until backups of xstores
+	// finish, so that passwords rotated during backup are restored. Otherwise, accounts are snapshotted once saved.
+	// +optional
+	RefreshSecret bool `json:"refreshSecret,omitempty"`
+
+	// UserMetadata defines custom key/value context attached to the backup set, e.g. ticket or reason, which is
+	// uploaded with metadata of backup set and recorded in status once uploaded.
+	// +optional
+	UserMetadata map[string]string `json:"userMetadata,omitempty"`
+
+	// Quiesce enables the coordinated quiesce of xstores before full backups begin, which records a globally
+	// consistent point of xstores. It's disabled by default since writes are blocked during the quiesce.
+	// +optional
+	Quiesce *polardbx.QuiesceSpec `json:"quiesce,omitempty"`
+
+	// ConsolidateFullBackups packs full backups of all the xstores into a single archive once they finish, which
+	// reduces the count of objects for small clusters. Full backup of each xstore is restored by its byte range in
+	// archive. It's incompatible with max object size of storage provider.
+	// +optional
+	ConsolidateFullBackups bool `json:"consolidateFullBackups,omitempty"`
+
+	// SkipQuickVerify skips the quick verification after backup finishes, which checks that the expected objects
+	// of backup set exist in the sink with non-zero size and records the result as condition Verified.
+	// +optional
+	SkipQuickVerify bool `json:"skipQuickVerify,omitempty"`
+
+	// OverallTimeout bounds the whole lifecycle of backup, measured from the start time. Backup still in progress
+	// beyond it fails wherever it is stuck, with the phase recorded, and its jobs are removed. It's propagated to
+	// xstore backups. Not bounded if not set.
+	// +optional
+	OverallTimeout *metav1.Duration `json:"overallTimeout,omitempty"`
+
+	// BinlogCollectMargin extends the range of binlog collected around the full backups beyond the offsets
+	// captured, to guarantee overlap. It's propagated to xstore backups.
+	// +optional
+	BinlogCollectMargin *polardbx.BinlogCollectMargin `json:"binlogCollectMargin,omitempty"`
+
+	// IncludeDatabases defines the databases to be backed up only by full backups of xstores, system databases
+	// are always backed up. Backup set of filtered databases can not be used to restore the whole cluster.
+	// It's propagated to xstore backups.
+	// +optional
+	IncludeDatabases []string `json:"includeDatabases,omitempty"`
+
+	// ExcludeDatabases defines the databases skipped by full backups of xstores, which must not be system
+	// databases. Backup set of filtered databases can not be used to restore the whole cluster. It's propagated
+	// to xstore backups.
+	// +optional
+	ExcludeDatabases []string `json:"excludeDatabases,omitempty"`
+
+	// StorageProviderOverrides overrides the storage provider of xstore backups by role or name of xstore, which
+	// falls back to StorageProvider if not overridden. It's incompatible with ConsolidateFullBackups.
+	// +optional
+	StorageProviderOverrides []polardbx.XStoreStorageProviderOverride `json:"storageProviderOverrides,omitempty"`
+
+	// KeyringReEncryption re-encrypts keyrings exported by backups of xstores with the target key for key
+	// rotation. It's propagated to xstore backups.
+	// +optional
+	KeyringReEncryption *polardbx.KeyringReEncryption `json:"keyringReEncryption,omitempty"`
+
+	// Compression defines the compression of full backups and binlogs, which are compressed alike. If not
+	// specified, full backups are compressed by xtrabackup and binlogs are uploaded as they are. It's propagated
+	// to xstore backups.
+	// +kubebuilder:validation:Enum=none;gzip
+	// +optional
+	Compression polardbx.BackupCompression `json:"compression,omitempty"`
+
+	// Encryption encrypts full backups and binlogs with the same key before uploaded. It's propagated to xstore
+	// backups.
+	// +optional
+	Encryption *polardbx.BackupEncryption `json:"encryption,omitempty"`
 }
 
 // PolarDBXBackupPhase defines the phase of backup
@@ -89,6 +194,12 @@ type PolarDBXBackupStatus struct {
 	// +optional
 	Reason string `json:"reason,omitempty"`
 
+	// +kubebuilder:validation:Enum=TargetPodNotFound;SinkUnreachable;JobFailed;BinlogPurged;Timeout;ChecksumMismatch;InsufficientDiskSpace;InvalidSpec;Terminated;Unknown
+
+	// FailureReason represents the reason code of failure, along with the human-readable Reason and Message.
+	// +optional
+	FailureReason polardbx.BackupFailureReason `json:"failureReason,omitempty"`
+
 	// Message includes human-readable message related to current status.
 	// +optional
 	Message string `json:"message,omitempty"`
@@ -102,6 +213,11 @@ type PolarDBXBackupStatus struct {
 	// +optional
 	XStores []string `json:"xstores,omitempty"`
 
+	// Partial indicates that only a subset of xstores selected by XStoreSelector is backed up,
+	// backup set of this kind can not be used to restore the whole cluster.
+	// +optional
+	Partial bool `json:"partial,omitempty"`
+
 	// ClusterSpecSnapshot records the snapshot of polardbx cluster spec
 	// +optional
 	ClusterSpecSnapshot *PolarDBXClusterSpec `json:"clusterSpecSnapshot,omitempty"`
@@ -126,6 +242,163 @@ type PolarDBXBackupStatus struct {
 
 	// CollectEndIndexMap records xstore name and the binlog index where collect should begin
 	CollectEndIndexMap map[string]string `json:"collectEndIndexMap,omitempty"`
+
+	// CdcState records state of CDC captured during backup
+	// +optional
+	CdcState *CdcBackupState `json:"cdcState,omitempty"`
+
+	// PolarDBXVersion records the detailed version of cluster when backup
+	// +optional
+	PolarDBXVersion string `json:"polarDBXVersion,omitempty"`
+
+	// Images records engine images of components of cluster when backup, keyed by component
+	// +optional
+	Images map[string]string `json:"images,omitempty"`
+
+	// GMSSchemaVersions records versions of GMS tables when backup, keyed by table name. Restore refuses
+	// backup sets with versions newer than supported by the operator.
+	// +optional
+	GMSSchemaVersions map[string]int32 `json:"gmsSchemaVersions,omitempty"`
+
+	// Metadata records the metadata uploaded with backup set, secrets excluded
+	// +optional
+	Metadata *BackupSetMetadata `json:"metadata,omitempty"`
+
+	// EarlyMetadataDigest records the digest of metadata uploaded before binlog backup finished, which
+	// excludes the recoverable timestamp fields finalized at the end of backup
+	// +optional
+	EarlyMetadataDigest string `json:"earlyMetadataDigest,omitempty"`
+
+	// QuiescePoint records the consistent point of xstores captured by quiesce before full backups
+	// +optional
+	QuiescePoint *QuiescePoint `json:"quiescePoint,omitempty"`
+
+	// Conditions represents the risky configurations found by lint before backup starts, which never fail
+	// the backup.
+	// +optional
+	Conditions []polardbx.Condition `json:"conditions,omitempty"`
+
+	// Archive records the archive packing full backups of xstores if consolidated
+	// +optional
+	Archive *polardbx.BackupArchive `json:"archive,omitempty"`
+
+	// ArchivePhase records the progress of consolidating full backups into archive
+	// +optional
+	ArchivePhase polardbx.BackupArchivePhase `json:"archivePhase,omitempty"`
+
+	// XStoreStorageProviders records the storage providers of xstore backups overridden, keyed by xstore. Backups of
+	// other xstores are stored by StorageProvider of spec.
+	// +optional
+	XStoreStorageProviders map[string]polardbx.BackupStorageProvider `json:"xstoreStorageProviders,omitempty"`
+
+	// KeyringKeyFingerprint records the fingerprint of the target key which keyrings are re-encrypted with
+	// +optional
+	KeyringKeyFingerprint string `json:"keyringKeyFingerprint,omitempty"`
+}
+
+// Condition types of lint, which are true if the risky configuration is found.
+const (
+	// PolarDBXBackupLintBackupOnLeader indicates that backup targets the leader without forbidding backup on leader,
+	// so that full backup competes with the online traffic on leader.
+	PolarDBXBackupLintBackupOnLeader polardbx.ConditionType = "LintBackupOnLeader"
+
+	// PolarDBXBackupLintNoRetention indicates that retention time is zero, so that the backup set is never purged.
+	PolarDBXBackupLintNoRetention polardbx.ConditionType = "LintNoRetention"
+
+	// PolarDBXBackupLintSingleSink indicates that no other backup set of the cluster is stored in another sink,
+	// so that all backup sets are lost along with the sink.
+	PolarDBXBackupLintSingleSink polardbx.ConditionType = "LintSingleSink"
+
+	// PolarDBXBackupLintUnencryptedTDE indicates that the cluster enables TDE while server-side encryption is not
+	// requested on backup files, so that keyring is stored along with the data in plain.
+	PolarDBXBackupLintUnencryptedTDE polardbx.ConditionType = "LintUnencryptedTDE"
+)
+
+// PolarDBXBackupVerified indicates the result of quick verification on objects of backup set, which is true if all
+// the expected objects are found in the sink with non-zero size.
+const PolarDBXBackupVerified polardbx.ConditionType = "Verified"
+
+// PolarDBXBackupAudited indicates that the audit record of backup in terminal state has been emitted to the
+// configured audit sink.
+const PolarDBXBackupAudited polardbx.ConditionType = "Audited"
+
+// QuiescePoint records binlog positions of xstores captured while all of them are under global read lock.
+type QuiescePoint struct {
+	// Timestamp records when the quiesce began
+	Timestamp *metav1.Time `json:"timestamp,omitempty"`
+
+	// LockDuration records the longest time that xstores were locked, including the time waiting for the lock
+	LockDuration metav1.Duration `json:"lockDuration,omitempty"`
+
+	// Positions records binlog positions of xstores under the lock, keyed by xstore name
+	Positions map[string]QuiescePosition `json:"positions,omitempty"`
+}
+
+// QuiescePosition records binlog position of a xstore under global read lock.
+type QuiescePosition struct {
+	// Pod records the leader pod locked
+	Pod string `json:"pod,omitempty"`
+
+	// BinlogOffset records the binlog position in format of <file>:<position>
+	BinlogOffset string `json:"binlogOffset,omitempty"`
+
+	// GtidExecuted records the executed gtid set
+	GtidExecuted string `json:"gtidExecuted,omitempty"`
+}
+
+// CdcBackupState records position and topology of CDC captured during backup.
+type CdcBackupState struct {
+	// Tso records the latest tso of CDC, from which CDC continues after restore
+	Tso string `json:"tso,omitempty"`
+
+	// Nodes records topology of CDC nodes, in format of <containerId>@<host>:<daemonPort>
+	Nodes []string `json:"nodes,omitempty"`
+}
+
+// BackupSetMetadata records the metadata uploaded with backup set, secrets excluded, which allows to
+// inspect backup set and choose restore point without access to the storage.
+type BackupSetMetadata struct {
+	// MetadataPath records the path of metadata file in storage
+	MetadataPath string `json:"metadataPath,omitempty"`
+
+	// BackupSetName records name of the backup set
+	BackupSetName string `json:"backupSetName,omitempty"`
+
+	// BackupRootPath records the root path of the backup set
+	BackupRootPath string `json:"backupRootPath,omitempty"`
+
+	// StartTime records start time of backup
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// EndTime records end time of backup
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+
+	// LatestRecoverableTimestamp records the latest timestamp that can recover from the backup set
+	LatestRecoverableTimestamp *metav1.Time `json:"latestRecoverableTimestamp,omitempty"`
+
+	// XStores records metadata of each xstore backed up
+	XStores []XStoreBackupSetMetadata `json:"xstores,omitempty"`
+
+	// UserMetadata records custom key/value context attached to the backup set
+	UserMetadata map[string]string `json:"userMetadata,omitempty"`
+
+	// QuiescePoint records the consistent point of xstores captured by quiesce before full backups
+	QuiescePoint *QuiescePoint `json:"quiescePoint,omitempty"`
+}
+
+// XStoreBackupSetMetadata records metadata of a xstore in backup set.
+type XStoreBackupSetMetadata struct {
+	// Name records name of the xstore
+	Name string `json:"name"`
+
+	// BackupName records name of the xstore backup
+	BackupName string `json:"backupName,omitempty"`
+
+	// LastCommitIndex records the last binlog index during full backup
+	LastCommitIndex int64 `json:"lastCommitIndex,omitempty"`
+
+	// TargetPod records the pod where backup was performed
+	TargetPod string `json:"targetPod,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -148,6 +421,43 @@ type PolarDBXBackup struct {
 	Status PolarDBXBackupStatus `json:"status,omitempty"`
 }
 
+// IsSnapshotOnly tells whether the backup takes only the full backup without binlog.
+func (b *PolarDBXBackup) IsSnapshotOnly() bool {
+	return b.Spec.BackupMode == polardbx.BackupModeSnapshot
+}
+
+// IsDatabaseFiltered tells whether only part of databases is backed up.
+func (b *PolarDBXBackup) IsDatabaseFiltered() bool {
+	return len(b.Spec.IncludeDatabases) > 0 || len(b.Spec.ExcludeDatabases) > 0
+}
+
+// ResolveXStoreStorageProvider returns the storage provider of backup of xstore with name and role, override
+// selecting the xstore by name takes precedence over the one by role. It tells whether it's overridden.
+func (s *PolarDBXBackupSpec) ResolveXStoreStorageProvider(xstoreName, role string) (polardbx.BackupStorageProvider, bool) {
+	var byRole *polardbx.XStoreStorageProviderOverride
+	for i := range s.StorageProviderOverrides {
+		override := &s.StorageProviderOverrides[i]
+		if override.XStore != "" && override.XStore == xstoreName {
+			return override.StorageProvider, true
+		}
+		if override.Role != "" && override.Role == role && byRole == nil {
+			byRole = override
+		}
+	}
+	if byRole != nil {
+		return byRole.StorageProvider, true
+	}
+	return s.StorageProvider, false
+}
+
+// XStoreStorageProvider returns the storage provider where backup of xstore is stored.
+func (b *PolarDBXBackup) XStoreStorageProvider(xstoreName string) polardbx.BackupStorageProvider {
+	if provider, ok := b.Status.XStoreStorageProviders[xstoreName]; ok {
+		return provider
+	}
+	return b.Spec.StorageProvider
+}
+
 // +kubebuilder:object:root=true
 
 // PolarDBXBackupList contains a list of PolarDBXBackup
@@ -11,6 +11,8 @@ type PolarDBXBackupBinlogSpec struct {
 	PxcName string `json:"pxcName"`
 	// +optional
 	PxcUid string `json:"pxcUid"`
+	// RemoteExpireLogHours defines the retention of remote binlog files in hours, independent of the retention of
+	// full backups. Binlog files after the latest finished full backup of the cluster are always kept.
 	// +kubebuilder:default=168
 	RemoteExpireLogHours intstr.IntOrString `json:"remoteExpireLogHours,omitempty"`
 	// +kubebuilder:default=7
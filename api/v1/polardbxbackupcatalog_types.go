@@ -0,0 +1,110 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+)
+
+type PolarDBXBackupCatalogSpec struct {
+	// Cluster represents the reference of polardbx cluster whose backup sets are listed.
+	Cluster PolarDBXClusterReference `json:"cluster,omitempty"`
+}
+
+// BackupSetSummary summarizes a backup set of the cluster.
+type BackupSetSummary struct {
+	// Name represents the name of PolarDBXBackup.
+	Name string `json:"name"`
+
+	// Phase represents the phase of the backup.
+	Phase PolarDBXBackupPhase `json:"phase,omitempty"`
+
+	// BackupMode represents whether binlog is backed up along with the full backup.
+	BackupMode polardbx.BackupMode `json:"backupMode,omitempty"`
+
+	// StartTime represents the backup start time.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// EndTime represents the backup end time.
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+
+	// EarliestRecoverableTimestamp records the earliest timestamp that can recover from the backup set,
+	// which is the latest consistent point of full backups of xstores.
+	EarliestRecoverableTimestamp *metav1.Time `json:"earliestRecoverableTimestamp,omitempty"`
+
+	// LatestRecoverableTimestamp records the latest timestamp that can recover from the backup set.
+	LatestRecoverableTimestamp *metav1.Time `json:"latestRecoverableTimestamp,omitempty"`
+
+	// Size records the estimated size of the backup set in bytes, summed from full backups of xstores.
+	Size int64 `json:"size,omitempty"`
+
+	// StorageName represents the kind of storage.
+	StorageName polardbx.BackupStorage `json:"storageName,omitempty"`
+
+	// Sink represents the sink of storage.
+	Sink string `json:"sink,omitempty"`
+
+	// Partial indicates that only a subset of xstores is backed up.
+	Partial bool `json:"partial,omitempty"`
+}
+
+type PolarDBXBackupCatalogStatus struct {
+	// BackupSets lists the backup sets of the cluster, sorted by creation time.
+	BackupSets []BackupSetSummary `json:"backupSets,omitempty"`
+
+	// BackupSetCount records the count of backup sets.
+	BackupSetCount int32 `json:"backupSetCount,omitempty"`
+
+	// TotalSize records the estimated size of all the backup sets in bytes.
+	TotalSize int64 `json:"totalSize,omitempty"`
+
+	// LastUpdateTime records the time when the catalog was last updated.
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=pxcbackupcatalog;pbc
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="CLUSTER",type=string,JSONPath=`.spec.cluster.name`
+// +kubebuilder:printcolumn:name="BACKUPS",type=integer,JSONPath=`.status.backupSetCount`
+// +kubebuilder:printcolumn:name="TOTAL_SIZE",type=integer,JSONPath=`.status.totalSize`
+// +kubebuilder:printcolumn:name="LAST_UPDATE",type=string,JSONPath=`.status.lastUpdateTime`
+
+// PolarDBXBackupCatalog summarizes all the backup sets of a polardbx cluster. It's maintained by the operator
+// and named after the cluster.
+type PolarDBXBackupCatalog struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PolarDBXBackupCatalogSpec   `json:"spec,omitempty"`
+	Status PolarDBXBackupCatalogStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PolarDBXBackupCatalogList contains a list of PolarDBXBackupCatalog.
+type PolarDBXBackupCatalogList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PolarDBXBackupCatalog `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PolarDBXBackupCatalog{}, &PolarDBXBackupCatalogList{})
+}
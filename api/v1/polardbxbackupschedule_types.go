@@ -17,11 +17,13 @@ limitations under the License.
 package v1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type PolarDBXBackupScheduleSpec struct {
-	// Schedule represents backup schedule in format of cron expression.
+	// Schedule represents backup schedule in format of cron expression. Falls back to the schedule of backup
+	// class referred by backup spec or labeled on the cluster if not specified.
 	Schedule string `json:"schedule,omitempty"`
 
 	// Suspend denotes whether current schedule is paused.
@@ -34,6 +36,20 @@ type PolarDBXBackupScheduleSpec struct {
 	// +optional
 	MaxBackupCount int `json:"maxBackupCount,omitempty"`
 
+	// MaxStorageUsage defines limit of storage used by backup sets of the schedule. If the usage exceeds the limit,
+	// the eldest unprotected backup sets will be purged until usage falls under it. Usage is estimated by summing
+	// sizes of full backups of xstores, since bucket usage is not exposed by storage providers. Default is empty,
+	// which means no limit.
+	// +optional
+	MaxStorageUsage *resource.Quantity `json:"maxStorageUsage,omitempty"`
+
+	// +kubebuilder:default=0
+
+	// MinRetainedBackups defines the count of backup sets that are always retained, neither MaxBackupCount nor
+	// MaxStorageUsage purges backup sets below it. Default is zero, which means no guarantee.
+	// +optional
+	MinRetainedBackups int `json:"minRetainedBackups,omitempty"`
+
 	// BackupSpec defines spec of each backup.
 	BackupSpec PolarDBXBackupSpec `json:"backupSpec,omitempty"`
 }
@@ -73,6 +73,11 @@ type PolarDBXClusterSpec struct {
 	// +optional
 	Restore *polardbx.RestoreSpec `json:"restore,omitempty"`
 
+	// BinlogApply defines the binlog backup applied onto the cluster restored from backup, which rolls
+	// the cluster forward without restoring again. It's applied once set or changed while running.
+	// +optional
+	BinlogApply *polardbx.BinlogApplySpec `json:"binlogApply,omitempty"`
+
 	// ParameterTemplate defines the template of parameters used by cn/dn/gms.
 	ParameterTemplate polardbx.ParameterTemplate `json:"parameterTemplate,omitempty"`
 
@@ -101,6 +106,10 @@ type PolarDBXClusterSpec struct {
 	// Tolerations specifies the tolerations of the Pods of the cluster.
 	// +optional
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Probes defines the probe profile of stateless nodes, along with per component overrides.
+	// +optional
+	Probes *polardbx.ProbeConfig `json:"probes,omitempty"`
 }
 
 type PolarDBXClusterStatus struct {
@@ -144,6 +153,10 @@ type PolarDBXClusterStatus struct {
 	//PitrStatus represents the status of the pitr restore
 	PitrStatus *polardbx.PitrStatus `json:"pitrStatus,omitempty"`
 
+	// BinlogApplyStatus represents the status of applying binlog backup onto the restored cluster
+	// +optional
+	BinlogApplyStatus *polardbx.BinlogApplyStatus `json:"binlogApplyStatus,omitempty"`
+
 	//LatestSyncReadonlyTs represents the lastest time sync readonly storage info to metadb
 	ReadonlyStorageInfoHash string `json:"readonlyStorageInfoHash,omitempty"`
 
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xstore
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RestorePhase represents the phase of restoring data from backup.
+type RestorePhase string
+
+// Valid restore phases.
+const (
+	RestorePhaseNew              RestorePhase = ""
+	RestorePhaseDownloading      RestorePhase = "Downloading"
+	RestorePhasePreparing        RestorePhase = "Preparing"
+	RestorePhaseApplying         RestorePhase = "Applying"
+	RestorePhaseRecoveringBinlog RestorePhase = "RecoveringBinlog"
+	RestorePhaseVerifying        RestorePhase = "Verifying"
+	RestorePhaseFinished         RestorePhase = "Finished"
+	RestorePhaseFailed           RestorePhase = "Failed"
+)
+
+// RestoreStatus represents the progress of restoring data from backup.
+type RestoreStatus struct {
+	// Phase is the current phase of restore.
+	Phase RestorePhase `json:"phase,omitempty"`
+
+	// Progress is the estimated percentage of restore, ranging from 0 to 100.
+	// +optional
+	Progress int32 `json:"progress,omitempty"`
+
+	// Message includes human-readable message related to current phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime is the last time that phase of restore transitioned.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// ExpectedCommitIndex is the commit index recorded by backup, which the restored data must reach.
+	// +optional
+	ExpectedCommitIndex int64 `json:"expectedCommitIndex,omitempty"`
+
+	// AppliedIndex is the applied index of leader observed after restore.
+	// +optional
+	AppliedIndex int64 `json:"appliedIndex,omitempty"`
+
+	// ChecksumCoverage is the percentage of rows sampled when table checksums are verified after restore, which is
+	// 100 if whole tables are verified.
+	// +optional
+	ChecksumCoverage int32 `json:"checksumCoverage,omitempty"`
+}
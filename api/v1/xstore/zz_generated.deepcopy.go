@@ -295,6 +295,22 @@ func (in *RestartingPods) DeepCopy() *RestartingPods {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreStatus) DeepCopyInto(out *RestoreStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreStatus.
+func (in *RestoreStatus) DeepCopy() *RestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Topology) DeepCopyInto(out *Topology) {
 	*out = *in
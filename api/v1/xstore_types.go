@@ -41,7 +41,7 @@ type XStoreRestoreFrom struct {
 
 // XStoreRestoreSpec defines the specification for restore a xstore with desired state.
 type XStoreRestoreSpec struct {
-	//BackupSet defines the source of backup set
+	//BackupSet defines the source of backup set, "latest" for the latest finished backup of source xstore
 	BackupSet string `json:"backupset,omitempty"`
 
 	// From defines the source information, either backup sets, snapshot or an running cluster.
@@ -231,6 +231,10 @@ type XStoreStatus struct {
 	//PitrStatus represents the status of the pitr restore
 	PitrStatus *xstore.PitrStatus `json:"pitrStatus,omitempty"`
 
+	// RestoreStatus represents the progress of restoring from backup
+	// +optional
+	RestoreStatus *xstore.RestoreStatus `json:"restoreStatus,omitempty"`
+
 	// +kubebuilder:default=false
 	// TdeStatus represents if tde open
 	TdeStatus bool `json:"tdeStatus,omitempty"`
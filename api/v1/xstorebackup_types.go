@@ -17,7 +17,10 @@ limitations under the License.
 package v1
 
 import (
+	"time"
+
 	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/api/v1/xstore"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -48,6 +51,11 @@ type XStoreBackupSpec struct {
 	// StorageProvider defines backup storage configuration
 	StorageProvider polardbx.BackupStorageProvider `json:"storageProvider,omitempty"`
 
+	// BackupClass refers to the backup class defined in config of operator, storage provider and retention time
+	// not specified are filled from the class when backup starts.
+	// +optional
+	BackupClass string `json:"backupClass,omitempty"`
+
 	// +kubebuilder:default=follower
 	// +kubebuilder:validation:Enum=leader;follower
 
@@ -55,6 +63,20 @@ type XStoreBackupSpec struct {
 	// +optional
 	PreferredBackupRole string `json:"preferredBackupRole,omitempty"`
 
+	// ForbidBackupOnLeader forbids backup to happen on leader. If set, backup waits for a follower to be
+	// available as target pod, and fails if only leader is available. Otherwise backup on leader proceeds
+	// with a warning.
+	// +optional
+	ForbidBackupOnLeader bool `json:"forbidBackupOnLeader,omitempty"`
+
+	// +kubebuilder:default=pitr
+	// +kubebuilder:validation:Enum=pitr;snapshot
+
+	// BackupMode defines whether binlog is backed up along with the full backup. Backup in snapshot mode
+	// skips the binlog collection and backup, and can only be restored to the snapshot point. Default is pitr.
+	// +optional
+	BackupMode polardbx.BackupMode `json:"backupMode,omitempty"`
+
 	// +kubebuilder:default=Retain
 	// +kubebuilder:validation:Enum=Retain;Delete;OnFailure
 
@@ -62,6 +84,78 @@ type XStoreBackupSpec struct {
 	// Default is Retain.
 	// +optional
 	CleanPolicy polardbx.CleanPolicyType `json:"cleanPolicy,omitempty"`
+
+	// BackupJobCommandOverride overrides the container name and commands of backup jobs.
+	// +optional
+	BackupJobCommandOverride *polardbx.BackupJobCommandOverride `json:"backupJobCommandOverride,omitempty"`
+
+	// FinalizeGracePeriod delays the transition to Finished after metadata uploaded, which lets consumers
+	// observe the backup set before retention is enforced. Not delayed if not set.
+	// +optional
+	FinalizeGracePeriod *metav1.Duration `json:"finalizeGracePeriod,omitempty"`
+
+	// OverallTimeout bounds the whole lifecycle of backup, measured from the start time. Backup still in progress
+	// beyond it fails wherever it is stuck, with the phase recorded, and its jobs are removed. Not bounded if not set.
+	// +optional
+	OverallTimeout *metav1.Duration `json:"overallTimeout,omitempty"`
+
+	// BinlogCollectMargin extends the range of binlog collected beyond the offsets captured by polardbx backup.
+	// +optional
+	BinlogCollectMargin *polardbx.BinlogCollectMargin `json:"binlogCollectMargin,omitempty"`
+
+	// BinlogExcludePatterns defines the shell patterns of binlog file names, e.g. "mysql_bin.00012*", binlog files
+	// matching any of them are skipped when collecting and backing up binlog. The last binlog file, which holds
+	// the end of backup set, is never skipped. Files skipped leave gaps in the recoverable range.
+	// +optional
+	BinlogExcludePatterns []string `json:"binlogExcludePatterns,omitempty"`
+
+	// RefreshSecret refreshes the saved accounts from the live secret of xstore until the backup reaches a
+	// terminal phase, so that passwords rotated during backup are restored. Metadata uploaded is uploaded again
+	// once accounts change. Otherwise, accounts are snapshotted once saved.
+	// +optional
+	RefreshSecret bool `json:"refreshSecret,omitempty"`
+
+	// UserMetadata defines custom key/value context attached to the backup, e.g. ticket or reason, which is
+	// uploaded with metadata of backup set and recorded in status once uploaded.
+	// +optional
+	UserMetadata map[string]string `json:"userMetadata,omitempty"`
+
+	// ChecksumTables defines the tables, in the form of "schema.table", whose checksums are captured after full
+	// backup and verified after the backup set is restored, restore fails if they diverge. Tables are expected
+	// not to be modified during backup.
+	// +optional
+	ChecksumTables []string `json:"checksumTables,omitempty"`
+
+	// ChecksumSamplePercent defines the percentage of rows sampled when checksumming tables, which trades the
+	// assurance of verification for its cost on large tables. Whole tables are checksummed if not specified or 100.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	ChecksumSamplePercent int32 `json:"checksumSamplePercent,omitempty"`
+
+	// IncludeDatabases defines the databases to be backed up only by full backup, which are passed to
+	// xtrabackup. System databases are always backed up.
+	// +optional
+	IncludeDatabases []string `json:"includeDatabases,omitempty"`
+
+	// ExcludeDatabases defines the databases skipped by full backup, which are passed to xtrabackup and must not
+	// be system databases.
+	// +optional
+	ExcludeDatabases []string `json:"excludeDatabases,omitempty"`
+
+	// KeyringReEncryption re-encrypts the keyring exported by backup with the target key for key rotation.
+	// +optional
+	KeyringReEncryption *polardbx.KeyringReEncryption `json:"keyringReEncryption,omitempty"`
+
+	// Compression defines the compression of full backup and binlogs, which are compressed alike. If not
+	// specified, full backup is compressed by xtrabackup and binlogs are uploaded as they are.
+	// +kubebuilder:validation:Enum=none;gzip
+	// +optional
+	Compression polardbx.BackupCompression `json:"compression,omitempty"`
+
+	// Encryption encrypts full backup and binlogs with the same key before uploaded.
+	// +optional
+	Encryption *polardbx.BackupEncryption `json:"encryption,omitempty"`
 }
 
 // XStoreBackupStatus defines the observed state of XStoreBackup
@@ -71,6 +165,12 @@ type XStoreBackupStatus struct {
 	EndTime     *metav1.Time      `json:"endTime,omitempty"`
 	TargetPod   string            `json:"targetPod,omitempty"`
 	CommitIndex int64             `json:"commitIndex,omitempty"`
+
+	// PhaseTimestamps records the time of the latest entry of each phase, which tells the duration of phases
+	// along with the time of entry of the next phase and the end time
+	// +optional
+	PhaseTimestamps map[XStoreBackupPhase]metav1.Time `json:"phaseTimestamps,omitempty"`
+
 	// StorageName represents the kind of Storage
 	StorageName polardbx.BackupStorage `json:"storageName,omitempty"`
 	// BackupRootPath stores the root path of backup set
@@ -78,15 +178,222 @@ type XStoreBackupStatus struct {
 	// BackupSetTimestamp records timestamp of last event included in tailored binlog
 	BackupSetTimestamp *metav1.Time `json:"backupSetTimestamp,omitempty"`
 
+	// Reason represents the reason of failure.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// +kubebuilder:validation:Enum=TargetPodNotFound;SinkUnreachable;JobFailed;BinlogPurged;Timeout;ChecksumMismatch;InsufficientDiskSpace;InvalidSpec;Terminated;Unknown
+
+	// FailureReason represents the reason code of failure, along with the human-readable Reason and Message.
+	// +optional
+	FailureReason polardbx.BackupFailureReason `json:"failureReason,omitempty"`
+
 	// Message includes human-readable message related to current status.
 	// +optional
 	Message string `json:"message,omitempty"`
 
+	// Conditions represents the fine-grained state of backup, set as corresponding steps finish.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []xstore.Condition `json:"conditions,omitempty"`
+
+	// ChunkSize records the block size at which full backup was chunked when uploading, 0 means not chunked.
+	// Chunking is a hint for dedup done by storage, full backup is always uploaded as a whole.
+	// +optional
+	ChunkSize int64 `json:"chunkSize,omitempty"`
+
+	// AvailableBinlogRange records the oldest and the latest binlog files available on target pod
+	// when collecting binlog, in the form of "oldest~latest"
+	// +optional
+	AvailableBinlogRange string `json:"availableBinlogRange,omitempty"`
+
+	// CollectRange records the effective range of binlog collected with margin applied, in the form of
+	// "start~end", each of which is "<binlog file>:<offset>"
+	// +optional
+	CollectRange string `json:"collectRange,omitempty"`
+
+	// MetadataUploadAttempts records the count of failed attempts to upload metadata, backup fails
+	// once it reaches the limit configured in operator
+	// +optional
+	MetadataUploadAttempts int32 `json:"metadataUploadAttempts,omitempty"`
+
+	// ClockSkewSeconds records the clock of target pod minus the clock of operator in seconds, measured when
+	// extracting the backup set timestamp from target pod
+	// +optional
+	ClockSkewSeconds int64 `json:"clockSkewSeconds,omitempty"`
+
+	// SecretName records the name of secret which saves accounts of xstore at the time of backup,
+	// the secret may be shared by backups with the same accounts. Name of backup is used if not set.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// SecretSnapshotTime records the time when accounts of xstore were last snapshotted into the backup secret,
+	// which are the accounts restored from the backup
+	// +optional
+	SecretSnapshotTime *metav1.Time `json:"secretSnapshotTime,omitempty"`
+
+	// StepDurations records the time spent on each step of backup, in the order steps are started
+	// +optional
+	StepDurations []StepDuration `json:"stepDurations,omitempty"`
+
 	// XStoreSpecSnapshot records the snapshot of xstore spec
 	// +optional
 	XStoreSpecSnapshot *XStoreSpec `json:"xstoreSpecSnapshot,omitempty"`
+
+	// Metadata records the metadata uploaded with backup set, secrets excluded
+	// +optional
+	Metadata *BackupSetMetadata `json:"metadata,omitempty"`
+
+	// ExcludedBinlogs records the binlog files skipped by binlog backup as they match the exclude patterns
+	// +optional
+	ExcludedBinlogs []string `json:"excludedBinlogs,omitempty"`
+
+	// FullBackupSize records the estimated size of full backup in bytes, which is the size of data directory
+	// on target pod after full backup finished
+	// +optional
+	FullBackupSize int64 `json:"fullBackupSize,omitempty"`
+
+	// TableChecksums records the checksums of tables defined in spec, keyed by "schema.table", which are
+	// captured on target pod after full backup finished
+	// +optional
+	TableChecksums map[string]string `json:"tableChecksums,omitempty"`
+
+	// TableChecksumSample records the sampling of rows by which table checksums are captured, including the count
+	// of rows sampled, nil if whole tables are checksummed
+	// +optional
+	TableChecksumSample *polardbx.TableChecksumSample `json:"tableChecksumSample,omitempty"`
+
+	// KeyringKeyFingerprint records the fingerprint of the target key which the keyring is re-encrypted with
+	// +optional
+	KeyringKeyFingerprint string `json:"keyringKeyFingerprint,omitempty"`
+
+	// ScheduledDeletionTime records when the backup is to be deleted by retention, which is end time plus
+	// retention time. It's empty for protected backups, which are retained forever
+	// +optional
+	ScheduledDeletionTime *metav1.Time `json:"scheduledDeletionTime,omitempty"`
+
+	// TargetPodLagSeconds records the replication lag of target pod in seconds when full backup started, not
+	// recorded if backup is performed on leader or the lag is unknown
+	// +optional
+	TargetPodLagSeconds *int64 `json:"targetPodLagSeconds,omitempty"`
+
+	// TargetPodHealthCheck records the latest health check of engine on target pod before full backup, another
+	// pod is preferred if the checked one is unhealthy
+	// +optional
+	TargetPodHealthCheck *TargetPodHealthCheck `json:"targetPodHealthCheck,omitempty"`
+
+	// Archive records the entry of full backup in archive if full backups of polardbx backup are consolidated,
+	// in which case the full backup is no longer a standalone object
+	// +optional
+	Archive *polardbx.BackupArchive `json:"archive,omitempty"`
+
+	// FullBackupAborts records the number of times the full backup job is aborted manually to re-attempt
+	// +optional
+	FullBackupAborts int32 `json:"fullBackupAborts,omitempty"`
+
+	// LastFullBackupAbortTime records when the full backup job is aborted manually for the last time
+	// +optional
+	LastFullBackupAbortTime *metav1.Time `json:"lastFullBackupAbortTime,omitempty"`
+
+	// FullBackupCodec records how full backup is processed before uploaded, nil for backups taken before codecs
+	// were recorded, which are compressed by xtrabackup
+	// +optional
+	FullBackupCodec *polardbx.BackupCodec `json:"fullBackupCodec,omitempty"`
+
+	// BinlogBackupCodec records how binlogs are processed before uploaded, nil for backups taken before codecs
+	// were recorded, in which binlogs are uploaded as they are
+	// +optional
+	BinlogBackupCodec *polardbx.BackupCodec `json:"binlogBackupCodec,omitempty"`
+}
+
+// TargetPodHealthCheck records the health check of engine on target pod, which is unhealthy if the engine is
+// unreachable or recovering from crash.
+type TargetPodHealthCheck struct {
+	// Pod is the name of pod checked
+	Pod string `json:"pod"`
+
+	// Healthy tells whether the engine is healthy to perform backup on
+	Healthy bool `json:"healthy"`
+
+	// Uptime is the uptime of engine in seconds
+	// +optional
+	Uptime int64 `json:"uptime,omitempty"`
+
+	// Message tells why the engine is unhealthy
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// CheckTime is the time of health check
+	CheckTime metav1.Time `json:"checkTime"`
+}
+
+// StepDuration records the time spent on a step of backup, from the first time the step is executed
+// to the time the step is passed, waiting across reconciliations included.
+type StepDuration struct {
+	// Name is the name of step
+	Name string `json:"name"`
+
+	// StartTime is the time the step is executed for the first time
+	StartTime metav1.Time `json:"startTime"`
+
+	// EndTime is the time the step is passed
+	// +optional
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+
+	// Duration is the time between start time and end time
+	// +optional
+	Duration metav1.Duration `json:"duration,omitempty"`
 }
 
+// IsSnapshotOnly tells whether the backup takes only the full backup without binlog.
+func (b *XStoreBackup) IsSnapshotOnly() bool {
+	return b.Spec.BackupMode == polardbx.BackupModeSnapshot
+}
+
+// GetSecretName returns the name of secret which saves accounts of xstore at the time of backup.
+func (b *XStoreBackup) GetSecretName() string {
+	if b.Status.SecretName != "" {
+		return b.Status.SecretName
+	}
+	return b.Name
+}
+
+// GetFinalizeGracePeriod returns the duration to wait before the backup turns to Finished, 0 if not set.
+func (b *XStoreBackup) GetFinalizeGracePeriod() time.Duration {
+	if b.Spec.FinalizeGracePeriod == nil || b.Spec.FinalizeGracePeriod.Duration < 0 {
+		return 0
+	}
+	return b.Spec.FinalizeGracePeriod.Duration
+}
+
+// Valid condition types of xstore backup.
+const (
+	// XStoreBackupFullBackupComplete indicates whether the full backup job has finished.
+	XStoreBackupFullBackupComplete xstore.ConditionType = "FullBackupComplete"
+
+	// XStoreBackupVerified indicates whether the full backup is verified to be valid by the full backup job.
+	XStoreBackupVerified xstore.ConditionType = "Verified"
+
+	// XStoreBackupBinlogBackupComplete indicates whether the binlog backup job has finished.
+	XStoreBackupBinlogBackupComplete xstore.ConditionType = "BinlogBackupComplete"
+
+	// XStoreBackupMetadataUploaded indicates whether metadata of the backup has been uploaded.
+	XStoreBackupMetadataUploaded xstore.ConditionType = "MetadataUploaded"
+
+	// XStoreBackupBinlogContinuous indicates whether binlog available is continuous from the commit index of
+	// full backup, recoverable window has a hole otherwise.
+	XStoreBackupBinlogContinuous xstore.ConditionType = "BinlogContinuous"
+
+	// XStoreBackupFilestreamConnected indicates whether the filestream server was reachable when last connected
+	// to upload metadata.
+	XStoreBackupFilestreamConnected xstore.ConditionType = "FilestreamConnected"
+
+	// XStoreBackupXStoreStable indicates whether the xstore is stable to start full backup on, i.e. not being
+	// reconfigured or restarted, during which the target pod may be replaced.
+	XStoreBackupXStoreStable xstore.ConditionType = "XStoreStable"
+)
+
 type XStoreBackupPhase string
 
 const (
@@ -96,6 +403,7 @@ const (
 	XStoreBinlogBackuping   XStoreBackupPhase = "Binloging"
 	XStoreBinlogWaiting     XStoreBackupPhase = "Waiting"
 	XStoreMetadataBackuping XStoreBackupPhase = "MetadataBackuping"
+	XStoreBackupFinalizing  XStoreBackupPhase = "Finalizing"
 	XStoreBackupFinished    XStoreBackupPhase = "Finished"
 	XStoreBackupDummy       XStoreBackupPhase = "Dummy"
 	XStoreBackupDeleting    XStoreBackupPhase = "Deleting"
@@ -110,6 +418,7 @@ const (
 // +kubebuilder:printcolumn:name="END",type=string,JSONPath=`.status.endTime`
 // +kubebuilder:printcolumn:name="PHASE",type=string,JSONPath=`.status.phase`
 // +kubebuilder:printcolumn:name="RETENTION",type=string,priority=1,JSONPath=`.spec.retentionTime`
+// +kubebuilder:printcolumn:name="EXPIRE",type=string,priority=1,JSONPath=`.status.scheduledDeletionTime`
 // +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // XStoreBackup is the Schema for the XStorebackups API
@@ -34,6 +34,8 @@ type XStoreBackupBinlogSpec struct {
 	// +optional
 	Engine string `json:"engine,omitempty"`
 
+	// RemoteExpireLogHours defines the retention of remote binlog files in hours, independent of the retention of
+	// full backups. Binlog files after the latest finished full backup of the xstore are always kept.
 	// +kubebuilder:default=168
 	RemoteExpireLogHours intstr.IntOrString `json:"remoteExpireLogHours,omitempty"`
 	// +kubebuilder:default=7
@@ -30,6 +30,101 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupSetMetadata) DeepCopyInto(out *BackupSetMetadata) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LatestRecoverableTimestamp != nil {
+		in, out := &in.LatestRecoverableTimestamp, &out.LatestRecoverableTimestamp
+		*out = (*in).DeepCopy()
+	}
+	if in.XStores != nil {
+		in, out := &in.XStores, &out.XStores
+		*out = make([]XStoreBackupSetMetadata, len(*in))
+		copy(*out, *in)
+	}
+	if in.UserMetadata != nil {
+		in, out := &in.UserMetadata, &out.UserMetadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.QuiescePoint != nil {
+		in, out := &in.QuiescePoint, &out.QuiescePoint
+		*out = new(QuiescePoint)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSetMetadata.
+func (in *BackupSetMetadata) DeepCopy() *BackupSetMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupSetMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupSetSummary) DeepCopyInto(out *BackupSetSummary) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EarliestRecoverableTimestamp != nil {
+		in, out := &in.EarliestRecoverableTimestamp, &out.EarliestRecoverableTimestamp
+		*out = (*in).DeepCopy()
+	}
+	if in.LatestRecoverableTimestamp != nil {
+		in, out := &in.LatestRecoverableTimestamp, &out.LatestRecoverableTimestamp
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSetSummary.
+func (in *BackupSetSummary) DeepCopy() *BackupSetSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupSetSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CdcBackupState) DeepCopyInto(out *CdcBackupState) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CdcBackupState.
+func (in *CdcBackupState) DeepCopy() *CdcBackupState {
+	if in == nil {
+		return nil
+	}
+	out := new(CdcBackupState)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FlowFlagType) DeepCopyInto(out *FlowFlagType) {
 	*out = *in
@@ -162,7 +257,7 @@ func (in *PolarDBXBackup) DeepCopyInto(out *PolarDBXBackup) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -189,7 +284,7 @@ func (in *PolarDBXBackupBinlog) DeepCopyInto(out *PolarDBXBackupBinlog) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -248,7 +343,7 @@ func (in *PolarDBXBackupBinlogSpec) DeepCopyInto(out *PolarDBXBackupBinlogSpec)
 	*out = *in
 	out.RemoteExpireLogHours = in.RemoteExpireLogHours
 	out.LocalExpireLogHours = in.LocalExpireLogHours
-	out.StorageProvider = in.StorageProvider
+	in.StorageProvider.DeepCopyInto(&out.StorageProvider)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolarDBXBackupBinlogSpec.
@@ -281,6 +376,107 @@ func (in *PolarDBXBackupBinlogStatus) DeepCopy() *PolarDBXBackupBinlogStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolarDBXBackupCatalog) DeepCopyInto(out *PolarDBXBackupCatalog) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolarDBXBackupCatalog.
+func (in *PolarDBXBackupCatalog) DeepCopy() *PolarDBXBackupCatalog {
+	if in == nil {
+		return nil
+	}
+	out := new(PolarDBXBackupCatalog)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PolarDBXBackupCatalog) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolarDBXBackupCatalogList) DeepCopyInto(out *PolarDBXBackupCatalogList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PolarDBXBackupCatalog, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolarDBXBackupCatalogList.
+func (in *PolarDBXBackupCatalogList) DeepCopy() *PolarDBXBackupCatalogList {
+	if in == nil {
+		return nil
+	}
+	out := new(PolarDBXBackupCatalogList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PolarDBXBackupCatalogList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolarDBXBackupCatalogSpec) DeepCopyInto(out *PolarDBXBackupCatalogSpec) {
+	*out = *in
+	out.Cluster = in.Cluster
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolarDBXBackupCatalogSpec.
+func (in *PolarDBXBackupCatalogSpec) DeepCopy() *PolarDBXBackupCatalogSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PolarDBXBackupCatalogSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolarDBXBackupCatalogStatus) DeepCopyInto(out *PolarDBXBackupCatalogStatus) {
+	*out = *in
+	if in.BackupSets != nil {
+		in, out := &in.BackupSets, &out.BackupSets
+		*out = make([]BackupSetSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolarDBXBackupCatalogStatus.
+func (in *PolarDBXBackupCatalogStatus) DeepCopy() *PolarDBXBackupCatalogStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PolarDBXBackupCatalogStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PolarDBXBackupList) DeepCopyInto(out *PolarDBXBackupList) {
 	*out = *in
@@ -318,7 +514,7 @@ func (in *PolarDBXBackupSchedule) DeepCopyInto(out *PolarDBXBackupSchedule) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -375,7 +571,12 @@ func (in *PolarDBXBackupScheduleList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PolarDBXBackupScheduleSpec) DeepCopyInto(out *PolarDBXBackupScheduleSpec) {
 	*out = *in
-	out.BackupSpec = in.BackupSpec
+	if in.MaxStorageUsage != nil {
+		in, out := &in.MaxStorageUsage, &out.MaxStorageUsage
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	in.BackupSpec.DeepCopyInto(&out.BackupSpec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolarDBXBackupScheduleSpec.
@@ -416,7 +617,71 @@ func (in *PolarDBXBackupSpec) DeepCopyInto(out *PolarDBXBackupSpec) {
 	*out = *in
 	out.Cluster = in.Cluster
 	out.RetentionTime = in.RetentionTime
-	out.StorageProvider = in.StorageProvider
+	in.StorageProvider.DeepCopyInto(&out.StorageProvider)
+	if in.XStoreSelector != nil {
+		in, out := &in.XStoreSelector, &out.XStoreSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BackupJobCommandOverride != nil {
+		in, out := &in.BackupJobCommandOverride, &out.BackupJobCommandOverride
+		*out = new(polardbx.BackupJobCommandOverride)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BinlogExcludePatterns != nil {
+		in, out := &in.BinlogExcludePatterns, &out.BinlogExcludePatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UserMetadata != nil {
+		in, out := &in.UserMetadata, &out.UserMetadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Quiesce != nil {
+		in, out := &in.Quiesce, &out.Quiesce
+		*out = new(polardbx.QuiesceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OverallTimeout != nil {
+		in, out := &in.OverallTimeout, &out.OverallTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BinlogCollectMargin != nil {
+		in, out := &in.BinlogCollectMargin, &out.BinlogCollectMargin
+		*out = new(polardbx.BinlogCollectMargin)
+		**out = **in
+	}
+	if in.IncludeDatabases != nil {
+		in, out := &in.IncludeDatabases, &out.IncludeDatabases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeDatabases != nil {
+		in, out := &in.ExcludeDatabases, &out.ExcludeDatabases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StorageProviderOverrides != nil {
+		in, out := &in.StorageProviderOverrides, &out.StorageProviderOverrides
+		*out = make([]polardbx.XStoreStorageProviderOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.KeyringReEncryption != nil {
+		in, out := &in.KeyringReEncryption, &out.KeyringReEncryption
+		*out = new(polardbx.KeyringReEncryption)
+		**out = **in
+	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(polardbx.BackupEncryption)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolarDBXBackupSpec.
@@ -482,6 +747,54 @@ func (in *PolarDBXBackupStatus) DeepCopyInto(out *PolarDBXBackupStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.CdcState != nil {
+		in, out := &in.CdcState, &out.CdcState
+		*out = new(CdcBackupState)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.GMSSchemaVersions != nil {
+		in, out := &in.GMSSchemaVersions, &out.GMSSchemaVersions
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(BackupSetMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.QuiescePoint != nil {
+		in, out := &in.QuiescePoint, &out.QuiescePoint
+		*out = new(QuiescePoint)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]polardbx.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Archive != nil {
+		in, out := &in.Archive, &out.Archive
+		*out = new(polardbx.BackupArchive)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.XStoreStorageProviders != nil {
+		in, out := &in.XStoreStorageProviders, &out.XStoreStorageProviders
+		*out = make(map[string]polardbx.BackupStorageProvider, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolarDBXBackupStatus.
@@ -686,6 +999,11 @@ func (in *PolarDBXClusterSpec) DeepCopyInto(out *PolarDBXClusterSpec) {
 		*out = new(polardbx.RestoreSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.BinlogApply != nil {
+		in, out := &in.BinlogApply, &out.BinlogApply
+		*out = new(polardbx.BinlogApplySpec)
+		**out = **in
+	}
 	out.ParameterTemplate = in.ParameterTemplate
 	if in.InitReadonly != nil {
 		in, out := &in.InitReadonly, &out.InitReadonly
@@ -706,6 +1024,11 @@ func (in *PolarDBXClusterSpec) DeepCopyInto(out *PolarDBXClusterSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Probes != nil {
+		in, out := &in.Probes, &out.Probes
+		*out = new(polardbx.ProbeConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolarDBXClusterSpec.
@@ -741,6 +1064,11 @@ func (in *PolarDBXClusterStatus) DeepCopyInto(out *PolarDBXClusterStatus) {
 		*out = new(polardbx.PitrStatus)
 		**out = **in
 	}
+	if in.BinlogApplyStatus != nil {
+		in, out := &in.BinlogApplyStatus, &out.BinlogApplyStatus
+		*out = new(polardbx.BinlogApplyStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolarDBXClusterStatus.
@@ -1113,13 +1441,55 @@ func (in *PolarDbXBinlogPodStatus) DeepCopy() *PolarDbXBinlogPodStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuiescePoint) DeepCopyInto(out *QuiescePoint) {
+	*out = *in
+	if in.Timestamp != nil {
+		in, out := &in.Timestamp, &out.Timestamp
+		*out = (*in).DeepCopy()
+	}
+	out.LockDuration = in.LockDuration
+	if in.Positions != nil {
+		in, out := &in.Positions, &out.Positions
+		*out = make(map[string]QuiescePosition, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuiescePoint.
+func (in *QuiescePoint) DeepCopy() *QuiescePoint {
+	if in == nil {
+		return nil
+	}
+	out := new(QuiescePoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuiescePosition) DeepCopyInto(out *QuiescePosition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuiescePosition.
+func (in *QuiescePosition) DeepCopy() *QuiescePosition {
+	if in == nil {
+		return nil
+	}
+	out := new(QuiescePosition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RestoreBinlogSource) DeepCopyInto(out *RestoreBinlogSource) {
 	*out = *in
 	if in.StorageProvider != nil {
 		in, out := &in.StorageProvider, &out.StorageProvider
 		*out = new(polardbx.BackupStorageProvider)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -1133,6 +1503,27 @@ func (in *RestoreBinlogSource) DeepCopy() *RestoreBinlogSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StepDuration) DeepCopyInto(out *StepDuration) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StepDuration.
+func (in *StepDuration) DeepCopy() *StepDuration {
+	if in == nil {
+		return nil
+	}
+	out := new(StepDuration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SystemTask) DeepCopyInto(out *SystemTask) {
 	*out = *in
@@ -1249,6 +1640,22 @@ func (in *TDE) DeepCopy() *TDE {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetPodHealthCheck) DeepCopyInto(out *TargetPodHealthCheck) {
+	*out = *in
+	in.CheckTime.DeepCopyInto(&out.CheckTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetPodHealthCheck.
+func (in *TargetPodHealthCheck) DeepCopy() *TargetPodHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetPodHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TemplateNode) DeepCopyInto(out *TemplateNode) {
 	*out = *in
@@ -1338,7 +1745,7 @@ func (in *XStoreBackup) DeepCopyInto(out *XStoreBackup) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -1365,7 +1772,7 @@ func (in *XStoreBackupBinlog) DeepCopyInto(out *XStoreBackupBinlog) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -1424,7 +1831,7 @@ func (in *XStoreBackupBinlogSpec) DeepCopyInto(out *XStoreBackupBinlogSpec) {
 	*out = *in
 	out.RemoteExpireLogHours = in.RemoteExpireLogHours
 	out.LocalExpireLogHours = in.LocalExpireLogHours
-	out.StorageProvider = in.StorageProvider
+	in.StorageProvider.DeepCopyInto(&out.StorageProvider)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XStoreBackupBinlogSpec.
@@ -1489,12 +1896,84 @@ func (in *XStoreBackupList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *XStoreBackupSetMetadata) DeepCopyInto(out *XStoreBackupSetMetadata) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XStoreBackupSetMetadata.
+func (in *XStoreBackupSetMetadata) DeepCopy() *XStoreBackupSetMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(XStoreBackupSetMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *XStoreBackupSpec) DeepCopyInto(out *XStoreBackupSpec) {
 	*out = *in
 	out.XStore = in.XStore
 	out.RetentionTime = in.RetentionTime
-	out.StorageProvider = in.StorageProvider
+	in.StorageProvider.DeepCopyInto(&out.StorageProvider)
+	if in.BackupJobCommandOverride != nil {
+		in, out := &in.BackupJobCommandOverride, &out.BackupJobCommandOverride
+		*out = new(polardbx.BackupJobCommandOverride)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FinalizeGracePeriod != nil {
+		in, out := &in.FinalizeGracePeriod, &out.FinalizeGracePeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.OverallTimeout != nil {
+		in, out := &in.OverallTimeout, &out.OverallTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BinlogCollectMargin != nil {
+		in, out := &in.BinlogCollectMargin, &out.BinlogCollectMargin
+		*out = new(polardbx.BinlogCollectMargin)
+		**out = **in
+	}
+	if in.BinlogExcludePatterns != nil {
+		in, out := &in.BinlogExcludePatterns, &out.BinlogExcludePatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UserMetadata != nil {
+		in, out := &in.UserMetadata, &out.UserMetadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ChecksumTables != nil {
+		in, out := &in.ChecksumTables, &out.ChecksumTables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeDatabases != nil {
+		in, out := &in.IncludeDatabases, &out.IncludeDatabases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeDatabases != nil {
+		in, out := &in.ExcludeDatabases, &out.ExcludeDatabases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeyringReEncryption != nil {
+		in, out := &in.KeyringReEncryption, &out.KeyringReEncryption
+		*out = new(polardbx.KeyringReEncryption)
+		**out = **in
+	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(polardbx.BackupEncryption)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XStoreBackupSpec.
@@ -1510,6 +1989,13 @@ func (in *XStoreBackupSpec) DeepCopy() *XStoreBackupSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *XStoreBackupStatus) DeepCopyInto(out *XStoreBackupStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]xstore.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.StartTime != nil {
 		in, out := &in.StartTime, &out.StartTime
 		*out = (*in).DeepCopy()
@@ -1518,15 +2004,88 @@ func (in *XStoreBackupStatus) DeepCopyInto(out *XStoreBackupStatus) {
 		in, out := &in.EndTime, &out.EndTime
 		*out = (*in).DeepCopy()
 	}
+	if in.PhaseTimestamps != nil {
+		in, out := &in.PhaseTimestamps, &out.PhaseTimestamps
+		*out = make(map[XStoreBackupPhase]metav1.Time, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	if in.BackupSetTimestamp != nil {
 		in, out := &in.BackupSetTimestamp, &out.BackupSetTimestamp
 		*out = (*in).DeepCopy()
 	}
+	if in.SecretSnapshotTime != nil {
+		in, out := &in.SecretSnapshotTime, &out.SecretSnapshotTime
+		*out = (*in).DeepCopy()
+	}
+	if in.StepDurations != nil {
+		in, out := &in.StepDurations, &out.StepDurations
+		*out = make([]StepDuration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.XStoreSpecSnapshot != nil {
 		in, out := &in.XStoreSpecSnapshot, &out.XStoreSpecSnapshot
 		*out = new(XStoreSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(BackupSetMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExcludedBinlogs != nil {
+		in, out := &in.ExcludedBinlogs, &out.ExcludedBinlogs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TableChecksums != nil {
+		in, out := &in.TableChecksums, &out.TableChecksums
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TableChecksumSample != nil {
+		in, out := &in.TableChecksumSample, &out.TableChecksumSample
+		*out = new(polardbx.TableChecksumSample)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScheduledDeletionTime != nil {
+		in, out := &in.ScheduledDeletionTime, &out.ScheduledDeletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.TargetPodLagSeconds != nil {
+		in, out := &in.TargetPodLagSeconds, &out.TargetPodLagSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.TargetPodHealthCheck != nil {
+		in, out := &in.TargetPodHealthCheck, &out.TargetPodHealthCheck
+		*out = new(TargetPodHealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Archive != nil {
+		in, out := &in.Archive, &out.Archive
+		*out = new(polardbx.BackupArchive)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastFullBackupAbortTime != nil {
+		in, out := &in.LastFullBackupAbortTime, &out.LastFullBackupAbortTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FullBackupCodec != nil {
+		in, out := &in.FullBackupCodec, &out.FullBackupCodec
+		*out = new(polardbx.BackupCodec)
+		**out = **in
+	}
+	if in.BinlogBackupCodec != nil {
+		in, out := &in.BinlogBackupCodec, &out.BinlogBackupCodec
+		*out = new(polardbx.BackupCodec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XStoreBackupStatus.
@@ -1714,7 +2273,7 @@ func (in *XStoreRestoreSpec) DeepCopyInto(out *XStoreRestoreSpec) {
 	if in.StorageProvider != nil {
 		in, out := &in.StorageProvider, &out.StorageProvider
 		*out = new(polardbx.BackupStorageProvider)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.BinlogSource != nil {
 		in, out := &in.BinlogSource, &out.BinlogSource
@@ -1844,6 +2403,11 @@ func (in *XStoreStatus) DeepCopyInto(out *XStoreStatus) {
 		*out = new(xstore.PitrStatus)
 		**out = **in
 	}
+	if in.RestoreStatus != nil {
+		in, out := &in.RestoreStatus, &out.RestoreStatus
+		*out = new(xstore.RestoreStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XStoreStatus.
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+	"github.com/alibaba/polardbx-operator/pkg/pitr/exportbackupset"
+)
+
+/**
+  export a backup set from storage into a local directory, or import an exported directory into storage:
+   - export: downloads metadata, full backups, binlogs, binlog offsets and keyrings into <dir>/objects
+             and writes <dir>/manifest.json along with its checksum <dir>/manifest.sha256
+   - import: verifies all the objects against the manifest and uploads them, metadata is uploaded last
+   - verify: only verifies all the objects against the manifest
+*/
+
+var (
+	mode        string // export, import or verify
+	host        string // filestream server host
+	port        int    // filestream server port
+	storageName string // storage name, e.g. oss, sftp, s3
+	sink        string // sink name
+	rootPath    string // backup root path in storage
+	dir         string // local directory of exported backup set
+)
+
+const AppName = "backupset-exporter"
+
+func init() {
+	flag.StringVar(&mode, "mode", "export", "export, import or verify")
+	flag.StringVar(&host, "host", "127.0.0.1", "Host of filestream server")
+	flag.IntVar(&port, "port", 6643, "Port of filestream server")
+	flag.StringVar(&storageName, "storage", "", "Storage name of backup set, e.g. oss, sftp, s3")
+	flag.StringVar(&sink, "sink", "", "Sink name of backup set")
+	flag.StringVar(&rootPath, "root", "", "Root path of backup set in storage")
+	flag.StringVar(&dir, "dir", "", "Local directory of exported backup set")
+	flag.Parse()
+}
+
+func main() {
+	logger := zap.New(zap.UseDevMode(true)).WithName(AppName)
+	logger.Info(fmt.Sprintf("mode is %s, dir is %s", mode, dir))
+	if dir == "" {
+		panic("dir must be provided")
+	}
+
+	if mode == "verify" {
+		manifest, err := exportbackupset.Verify(dir)
+		if err != nil {
+			logger.Error(err, "failed to verify exported backup set")
+			panic(err)
+		}
+		logger.Info("exported backup set verified", "backup-set", manifest.BackupSetName, "count", len(manifest.Objects))
+		return
+	}
+
+	if rootPath == "" {
+		panic("root path must be provided")
+	}
+	storage, err := exportbackupset.NewBackupSetStorage(filestream.NewFileClient(host, port, nil),
+		polardbx.BackupStorage(storageName), sink)
+	if err != nil {
+		logger.Error(err, "invalid storage", "storage", storageName)
+		panic(err)
+	}
+	switch mode {
+	case "export":
+		manifest, err := exportbackupset.Export(logger, storage, rootPath, dir)
+		if err != nil {
+			logger.Error(err, "failed to export backup set")
+			panic(err)
+		}
+		logger.Info("finish exporting", "backup-set", manifest.BackupSetName, "count", len(manifest.Objects))
+	case "import":
+		if err := exportbackupset.Import(logger, storage, dir, rootPath); err != nil {
+			logger.Error(err, "failed to import backup set")
+			panic(err)
+		}
+		logger.Info("finish importing", "root-path", rootPath)
+	default:
+		panic("invalid mode: " + mode)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -43,6 +44,20 @@ var (
 	sink             string
 	ossBufferSize    string
 	minioBufferSize  string
+	concurrency      string
+	partSize         string
+	tags             string
+	sseAlgorithm     string
+	sseKMSKeyId      string
+	endpointType     string
+	maxObjectSize    int64
+	rangeOffset      int64
+	rangeSize        int64
+	resumeFile       string
+	resumeAttempts   int
+	chunkManifest    string
+	archiveSources   string
+	downloadAction   string
 )
 
 var activeHosts map[string]discovery.HostInfo
@@ -59,6 +74,20 @@ func init() {
 	flag.StringVar(&sink, "meta.sink", "", "Sink name of metadata")
 	flag.StringVar(&ossBufferSize, "meta.ossBufferSize", "", "oss buffer size of metadata")
 	flag.StringVar(&minioBufferSize, "meta.minioBufferSize", "", "minio buffer size of metadata")
+	flag.StringVar(&concurrency, "meta.uploadConcurrency", "", "count of parts uploaded concurrently")
+	flag.StringVar(&partSize, "meta.uploadPartSize", "", "size of each part uploaded concurrently")
+	flag.StringVar(&tags, "meta.tags", "", "tags attached to uploaded object, encoded as url query, for example team=db&owner=dba")
+	flag.StringVar(&sseAlgorithm, "meta.sseAlgorithm", "", "server-side encryption algorithm of uploaded object, AES256 or KMS")
+	flag.StringVar(&sseKMSKeyId, "meta.sseKMSKeyId", "", "id of KMS key for server-side encryption of uploaded object")
+	flag.StringVar(&endpointType, "meta.endpointType", "", "type of endpoint of sink to transfer, Internal, Public or Accelerate")
+	flag.Int64Var(&maxObjectSize, "maxObjectSize", 0, "split uploaded stream into numbered objects no larger than it in bytes, 0 means no split")
+	flag.Int64Var(&rangeOffset, "rangeOffset", 0, "offset in bytes of the range to download, e.g. an entry of archive")
+	flag.Int64Var(&rangeSize, "rangeSize", -1, "size in bytes of the range to download, -1 means the whole object")
+	flag.StringVar(&resumeFile, "resumeFile", "", "local file to download into resumably, progress is persisted next to it so that a broken download resumes from it")
+	flag.IntVar(&resumeAttempts, "resumeAttempts", 5, "max attempts of resumable download")
+	flag.StringVar(&chunkManifest, "chunkManifest", "", "path of chunk manifest in sink to verify the file downloaded resumably against")
+	flag.StringVar(&archiveSources, "archiveSources", "", "objects packed into the uploaded archive, comma separated name=path pairs")
+	flag.StringVar(&downloadAction, "downloadAction", "", "action to download objects packed into archive and verify the archive, e.g. downloadOss")
 	flag.StringVar(&destNodeName, "destNodeName", "", "The name of the destination node name")
 	flag.StringVar(&hostInfoFilePath, "hostInfoFilePath", "/tools/xstore/hdfs-nodes.json", "The file path of the host info file")
 	flag.StringVar(&stream, "stream", "", "The file stream type such as tar, default: empty string")
@@ -114,18 +143,47 @@ func getHostInfo() map[string]discovery.HostInfo {
 func main() {
 	client := NewFileClient(host, port, nil)
 	metadata := ActionMetadata{
-		Action:          Action(action),
-		InstanceId:      instanceId,
-		Filename:        filename,
-		RedirectAddr:    redirectAddr,
-		Filepath:        filepath,
-		Stream:          stream,
-		RequestId:       uuid.New().String(),
-		Sink:            sink,
-		OssBufferSize:   ossBufferSize,
-		MinioBufferSize: minioBufferSize,
+		Action:            Action(action),
+		InstanceId:        instanceId,
+		Filename:          filename,
+		RedirectAddr:      redirectAddr,
+		Filepath:          filepath,
+		Stream:            stream,
+		RequestId:         uuid.New().String(),
+		Sink:              sink,
+		OssBufferSize:     ossBufferSize,
+		MinioBufferSize:   minioBufferSize,
+		UploadConcurrency: concurrency,
+		UploadPartSize:    partSize,
+		Tags:              tags,
+		SSEAlgorithm:      sseAlgorithm,
+		SSEKMSKeyId:       sseKMSKeyId,
+		EndpointType:      endpointType,
 	}
-	if strings.HasPrefix(strings.ToLower(action), "upload") {
+	if strings.HasPrefix(strings.ToLower(action), "upload") && archiveSources != "" {
+		if downloadAction == "" {
+			printErrAndExit(errors.New("download action is required to pack archive"), metadata)
+		}
+		names, paths, err := parseArchiveSources(archiveSources)
+		if err != nil {
+			printErrAndExit(err, metadata)
+		}
+		index, err := PackArchive(client, names, paths, metadata, Action(downloadAction))
+		if err != nil {
+			printErrAndExit(err, metadata)
+		}
+		indexBytes, _ := json.Marshal(index)
+		fmt.Print(string(indexBytes))
+	} else if strings.HasPrefix(strings.ToLower(action), "upload") && maxObjectSize > 0 {
+		if err := ValidateMaxObjectSize(metadata.Action, maxObjectSize); err != nil {
+			printErrAndExit(err, metadata)
+		}
+		len, err := UploadSplit(client, os.Stdin, metadata, maxObjectSize)
+		if err != nil {
+			printErrAndExit(err, metadata)
+		}
+		fmt.Print(len)
+	} else if strings.HasPrefix(strings.ToLower(action), "upload") {
 		len, err := client.Upload(os.Stdin, metadata)
 		if err != nil {
 			printErrAndExit(err, metadata)
@@ -135,8 +193,27 @@ func main() {
 			printErrAndExit(err, metadata)
 		}
 		fmt.Print(len)
+	} else if strings.HasPrefix(strings.ToLower(action), "download") && resumeFile != "" {
+		var manifest *ChunkManifest
+		if chunkManifest != "" {
+			var err error
+			manifest, err = downloadChunkManifest(client, metadata)
+			if err != nil {
+				printErrAndExit(err, metadata)
+			}
+		}
+		_, err := DownloadResumable(client, resumeFile, metadata, resumeAttempts, manifest)
+		if err != nil {
+			printErrAndExit(err, metadata)
+		}
+	} else if strings.HasPrefix(strings.ToLower(action), "download") && rangeSize >= 0 {
+		_, err := DownloadRange(client, os.Stdout, metadata, rangeOffset, rangeSize)
+		if err != nil {
+			printErrAndExit(err, metadata)
+		}
 	} else if strings.HasPrefix(strings.ToLower(action), "download") {
-		_, err := client.Download(os.Stdout, metadata)
+		// objects split on upload are reassembled
+		_, err := DownloadSplit(client, os.Stdout, metadata)
 		if err != nil {
 			printErrAndExit(err, metadata)
 		}
@@ -150,6 +227,37 @@ func main() {
 	}
 }
 
+// downloadChunkManifest downloads the chunk manifest from the same sink as the file.
+func downloadChunkManifest(client Client, metadata ActionMetadata) (*ChunkManifest, error) {
+	metadata.Filename = chunkManifest
+	metadata.Filepath = ""
+	var buf bytes.Buffer
+	if _, err := DownloadSplit(client, &buf, metadata); err != nil {
+		return nil, fmt.Errorf("failed to download chunk manifest: %w", err)
+	}
+	manifest := &ChunkManifest{}
+	if err := json.Unmarshal(buf.Bytes(), manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse chunk manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// parseArchiveSources parses the comma separated name=path pairs into names and paths in order.
+func parseArchiveSources(sources string) ([]string, []string, error) {
+	pairs := strings.Split(sources, ",")
+	names := make([]string, 0, len(pairs))
+	paths := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		name, path, ok := strings.Cut(pair, "=")
+		if !ok || name == "" || path == "" {
+			return nil, nil, errors.New("invalid archive source: " + pair)
+		}
+		names = append(names, name)
+		paths = append(paths, path)
+	}
+	return names, paths, nil
+}
+
 func printErrAndExit(err error, metadata ActionMetadata) {
 	metadataJsonBytes, _ := json.Marshal(metadata)
 	fmt.Fprintf(os.Stdout, "Failed,   error %v metadata %v host %s port %d", err, string(metadataJsonBytes), host, port)
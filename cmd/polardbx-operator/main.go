@@ -47,6 +47,12 @@ func init() {
 	flag.BoolVar(&operatorOptions.LeaderElection, "enable-leader-election", false, "Enable leader election for controller manager.")
 	flag.StringVar(&operatorOptions.LeaderElectionNamespace, "leader-election-namespace", "", "The namespace where leader election happens. "+
 		"If not specified, the namespace where this operator's running is used.")
+	flag.DurationVar(&operatorOptions.LeaseDuration, "leader-election-lease-duration", 0, "The duration that non-leader candidates "+
+		"will wait to force acquire leadership. If not specified, default of controller-runtime is used.")
+	flag.DurationVar(&operatorOptions.RenewDeadline, "leader-election-renew-deadline", 0, "The duration that the leader will retry "+
+		"refreshing leadership before giving up. If not specified, default of controller-runtime is used.")
+	flag.DurationVar(&operatorOptions.RetryPeriod, "leader-election-retry-period", 0, "The duration the candidates should wait "+
+		"between tries of actions. If not specified, default of controller-runtime is used.")
 	flag.StringVar(&operatorOptions.ConfigPath, "config-path", "/etc/operator/polardbx", "The path that contains configs of polardbx operator.")
 	flag.StringVar(&featureGates, "feature-gates", "", "Feature gates to enable.")
 
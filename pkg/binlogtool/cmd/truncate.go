@@ -19,6 +19,7 @@ limitations under the License.
 package cmd
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/alibaba/polardbx-operator/pkg/binlogtool/binlog"
@@ -38,13 +39,24 @@ var (
 	truncateEndOffset    string
 	truncateEndTimestamp uint32
 	truncateEndTSO       uint64
+	truncateStartIndex   uint64
+	truncateEndIndex     uint64
 	outputBinlogFile     string
 )
 
+// consensusIndexOf returns the index of a raw consensus log event, which is preceded by flag and term.
+func consensusIndexOf(ev event.LogEvent) (uint64, error) {
+	data, ok := ev.EventData().(event.RawLogEventData)
+	if !ok || len(data) < 20 {
+		return 0, errors.New("invalid consensus log event")
+	}
+	return binary.LittleEndian.Uint64(data[12:20]), nil
+}
+
 var truncateCmd = &cobra.Command{
 	Use:   "truncate",
-	Short: "Truncate binlog by end offset or timestamp",
-	Long:  "Truncate binlog by end offset or timestamp",
+	Short: "Truncate binlog by end offset, timestamp or consensus index range",
+	Long:  "Truncate binlog by end offset, timestamp or consensus index range",
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			return errors.New("please specify a binlog file")
@@ -61,8 +73,9 @@ var truncateCmd = &cobra.Command{
 			binlog.WithScanMode(binlog.ScanModeRaw),
 		}
 
-		if truncateEndOffset == "" && truncateEndTimestamp <= 0 && truncateEndTSO <= 0 {
-			return errors.New("end-offset or end-ts or end-tso must be specified")
+		if truncateEndOffset == "" && truncateEndTimestamp <= 0 && truncateEndTSO <= 0 &&
+			truncateStartIndex <= 0 && truncateEndIndex <= 0 {
+			return errors.New("end-offset or end-ts or end-tso or start-index or end-index must be specified")
 		}
 
 		if outputBinlogFile == "" {
@@ -104,6 +117,8 @@ var truncateCmd = &cobra.Command{
 
 		writer.WriteCommonHeader()
 
+		// events of transactions whose consensus index is not after start index are skipped
+		skipping := false
 		for {
 			_, ev, err := lazyScanner.Next()
 			if err != nil {
@@ -113,6 +128,20 @@ var truncateCmd = &cobra.Command{
 				return err
 			}
 			lastEvent = ev
+			if ev.EventHeader().EventTypeCode() == spec.CONSENSUS_LOG_EVENT &&
+				(truncateStartIndex > 0 || truncateEndIndex > 0) {
+				index, err := consensusIndexOf(ev)
+				if err != nil {
+					return err
+				}
+				if truncateEndIndex > 0 && index > truncateEndIndex {
+					return nil
+				}
+				skipping = index <= truncateStartIndex
+			}
+			if skipping {
+				continue
+			}
 			if truncateEndTimestamp > 0 && ev.EventHeader().EventTimestamp() > truncateEndTimestamp {
 				return nil
 			}
@@ -150,6 +179,8 @@ func init() {
 	truncateCmd.Flags().StringVar(&truncateEndOffset, "end-offset", "", "offset offset in bytes")
 	truncateCmd.Flags().Uint32Var(&truncateEndTimestamp, "end-ts", 0, "end timestamp in seconds (compared with event header)")
 	truncateCmd.Flags().Uint64Var(&truncateEndTSO, "end-tso", 0, "end tso (compared with event rows query info)")
+	truncateCmd.Flags().Uint64Var(&truncateStartIndex, "start-index", 0, "consensus index after which events are kept (exclusive)")
+	truncateCmd.Flags().Uint64Var(&truncateEndIndex, "end-index", 0, "consensus index up to which events are kept (inclusive)")
 
 	rootCmd.AddCommand(truncateCmd)
 }
@@ -0,0 +1,46 @@
+//go:build polardbx
+
+/*
+Copyright 2022 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/alibaba/polardbx-operator/pkg/binlogtool/binlog/event"
+	"github.com/alibaba/polardbx-operator/pkg/binlogtool/binlog/spec"
+)
+
+func TestConsensusIndexOf(t *testing.T) {
+	data := make([]byte, 36)
+	binary.LittleEndian.PutUint64(data[4:12], 3)
+	binary.LittleEndian.PutUint64(data[12:20], 1024)
+	header := &event.LogEventHeaderV4{}
+	header.TypeCode = spec.CONSENSUS_LOG_EVENT
+	index, err := consensusIndexOf(event.NewRawLogEvent(header, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index != 1024 {
+		t.Fatalf("expect index 1024, actual %d", index)
+	}
+
+	if _, err := consensusIndexOf(event.NewRawLogEvent(header, data[:8])); err == nil {
+		t.Fatal("expect truncated event rejected")
+	}
+}
@@ -181,6 +181,10 @@ func uploadBinlogFile(reader io.Reader, binlogFile *BinlogFile) error {
 		action = filestream.UploadSsh
 	} else if binlogFile.SinkType == config.SinkTypeMinio {
 		action = filestream.UploadMinio
+	} else if binlogFile.SinkType == config.SinkTypeAzure {
+		action = filestream.UploadAzure
+	} else if binlogFile.SinkType == config.SinkTypeGcs {
+		action = filestream.UploadGcs
 	}
 	//upload binlogfile
 	binlogFileMetadata := filestream.ActionMetadata{
@@ -209,6 +213,10 @@ func uploadBinlogFileMeta(binlogFile *BinlogFile) error {
 		action = filestream.UploadSsh
 	} else if binlogFile.SinkType == config.SinkTypeMinio {
 		action = filestream.UploadMinio
+	} else if binlogFile.SinkType == config.SinkTypeAzure {
+		action = filestream.UploadAzure
+	} else if binlogFile.SinkType == config.SinkTypeGcs {
+		action = filestream.UploadGcs
 	}
 	binlogMetaJsonBytes, _ := json.Marshal(binlogFile)
 	binlogMetaFileMetadata := filestream.ActionMetadata{
@@ -19,10 +19,13 @@ package config
 import (
 	"errors"
 	"fmt"
+	"golang.org/x/net/http/httpproxy"
 	"k8s.io/apimachinery/pkg/util/yaml"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"sync/atomic"
 	"time"
 )
@@ -33,6 +36,8 @@ const (
 	SinkTypeMinio                      = "s3"
 	SinkTypeOss                        = "oss"
 	SinkTypeSftp                       = "sftp"
+	SinkTypeAzure                      = "azure"
+	SinkTypeGcs                        = "gcs"
 	SinkTypeNone                       = "none"
 	DefaultLocalExpireLogHours float64 = 7
 	DefaultMaxLocalBinlogCount         = 50
@@ -45,11 +50,108 @@ type MinioSink struct {
 	UploadPartMaxSize int64  `json:"uploadPartMaxSize,omitempty"`
 }
 
+// Types of endpoint of oss sink, selected by clients per transfer.
+const (
+	EndpointTypeInternal   = "Internal"
+	EndpointTypePublic     = "Public"
+	EndpointTypeAccelerate = "Accelerate"
+)
+
 type OssSink struct {
 	Endpoint     string `json:"endpoint,omitempty"`
 	AccessKey    string `json:"accessKey,omitempty"`
 	AccessSecret string `json:"accessSecret,omitempty"`
 	Bucket       string `json:"bucket,omitempty"`
+	// InternalEndpoint, PublicEndpoint and AccelerateEndpoint are the endpoints selectable by clients, Endpoint is
+	// the default one. AccelerateEndpoint works only if transfer acceleration is enabled on the bucket.
+	InternalEndpoint   string `json:"internalEndpoint,omitempty"`
+	PublicEndpoint     string `json:"publicEndpoint,omitempty"`
+	AccelerateEndpoint string `json:"accelerateEndpoint,omitempty"`
+}
+
+// EndpointOf returns the endpoint of endpoint type, or the default endpoint with ok false if the endpoint type is
+// unknown or not configured.
+func (s *OssSink) EndpointOf(endpointType string) (endpoint string, ok bool) {
+	switch endpointType {
+	case EndpointTypeInternal:
+		endpoint = s.InternalEndpoint
+	case EndpointTypePublic:
+		endpoint = s.PublicEndpoint
+	case EndpointTypeAccelerate:
+		endpoint = s.AccelerateEndpoint
+	}
+	if endpoint == "" {
+		return s.Endpoint, false
+	}
+	return endpoint, true
+}
+
+// AzureSink configures azure blob storage, endpoint of OssSink is reused and defaults to the one of account.
+// AuthMode is either sharedKey (default) or workloadIdentity, which takes client and tenant injected into
+// the pod by azure workload identity webhook unless specified.
+type AzureSink struct {
+	Account    string `json:"account,omitempty"`
+	AccountKey string `json:"accountKey,omitempty"`
+	Container  string `json:"container,omitempty"`
+	AuthMode   string `json:"authMode,omitempty"`
+	ClientId   string `json:"clientId,omitempty"`
+	TenantId   string `json:"tenantId,omitempty"`
+	BlockSize  int64  `json:"blockSize,omitempty"`
+}
+
+// GcsSink configures google cloud storage, bucket and endpoint of OssSink are reused. CredentialsFile is the
+// path of service account key, usually mounted from a secret, workload identity is used if not specified.
+type GcsSink struct {
+	CredentialsFile string `json:"credentialsFile,omitempty"`
+	ChunkSize       int64  `json:"chunkSize,omitempty"`
+}
+
+// ProxySink configures the HTTP(S) proxy to reach the storage, proxy environment variables HTTPS_PROXY,
+// HTTP_PROXY and NO_PROXY of hpfs are used if ProxyURL is not specified. Credentials of proxy are read from
+// files username and password under ProxySecretPath, usually a mounted secret.
+type ProxySink struct {
+	ProxyURL        string `json:"proxyURL,omitempty"`
+	NoProxy         string `json:"noProxy,omitempty"`
+	ProxySecretPath string `json:"proxySecretPath,omitempty"`
+}
+
+// ProxyFor returns the proxy to reach endpoint, nil if the endpoint is not proxied, e.g. matched by NoProxy.
+// Endpoint without scheme is taken as https unless insecure.
+func (s *ProxySink) ProxyFor(endpoint string, insecure bool) (*url.URL, error) {
+	proxyConfig := httpproxy.FromEnvironment()
+	if s.ProxyURL != "" {
+		proxyConfig.HTTPProxy, proxyConfig.HTTPSProxy = s.ProxyURL, s.ProxyURL
+	}
+	if s.NoProxy != "" {
+		proxyConfig.NoProxy = s.NoProxy
+	}
+	if !strings.Contains(endpoint, "://") {
+		if insecure {
+			endpoint = "http://" + endpoint
+		} else {
+			endpoint = "https://" + endpoint
+		}
+	}
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %s: %w", endpoint, err)
+	}
+	proxyURL, err := proxyConfig.ProxyFunc()(target)
+	if err != nil || proxyURL == nil {
+		return nil, err
+	}
+	if s.ProxySecretPath != "" {
+		username, err := os.ReadFile(filepath.Join(s.ProxySecretPath, "username"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read username of proxy: %w", err)
+		}
+		password, err := os.ReadFile(filepath.Join(s.ProxySecretPath, "password"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password of proxy: %w", err)
+		}
+		proxyURL.User = url.UserPassword(strings.TrimSpace(string(username)), strings.TrimSpace(string(password)))
+	}
+	return proxyURL, nil
 }
 
 type SftpSink struct {
@@ -65,6 +167,30 @@ type Sink struct {
 	OssSink
 	SftpSink
 	MinioSink
+	AzureSink
+	GcsSink
+	ProxySink
+}
+
+// Proxy returns the proxy to reach storage of sink, nil if not proxied. Proxy is not supported by sftp.
+func (s *Sink) Proxy() (*url.URL, error) {
+	endpoint, insecure := s.Endpoint, false
+	switch s.Type {
+	case SinkTypeMinio:
+		insecure = !s.UseSSL
+	case SinkTypeAzure:
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", s.Account)
+		}
+	case SinkTypeGcs:
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+	case SinkTypeOss:
+	default:
+		return nil, nil
+	}
+	return s.ProxyFor(endpoint, insecure)
 }
 
 type BackupBinlogConfig struct {
@@ -18,6 +18,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -51,6 +52,67 @@ func TestConfig3(t *testing.T) {
 	g.Expect(sink.RootPath).Should(BeEquivalentTo("/xxx"))
 }
 
+func TestOssSinkEndpointOf(t *testing.T) {
+	g := NewGomegaWithT(t)
+	sink := OssSink{
+		Endpoint:           "oss-cn-hangzhou.aliyuncs.com",
+		InternalEndpoint:   "oss-cn-hangzhou-internal.aliyuncs.com",
+		AccelerateEndpoint: "oss-accelerate.aliyuncs.com",
+	}
+	endpoint, ok := sink.EndpointOf(EndpointTypeInternal)
+	g.Expect(ok).Should(BeTrue())
+	g.Expect(endpoint).Should(Equal("oss-cn-hangzhou-internal.aliyuncs.com"))
+	endpoint, ok = sink.EndpointOf(EndpointTypeAccelerate)
+	g.Expect(ok).Should(BeTrue())
+	g.Expect(endpoint).Should(Equal("oss-accelerate.aliyuncs.com"))
+	endpoint, ok = sink.EndpointOf(EndpointTypePublic)
+	g.Expect(ok).Should(BeFalse())
+	g.Expect(endpoint).Should(Equal("oss-cn-hangzhou.aliyuncs.com"))
+	endpoint, ok = sink.EndpointOf("")
+	g.Expect(ok).Should(BeFalse())
+	g.Expect(endpoint).Should(Equal("oss-cn-hangzhou.aliyuncs.com"))
+}
+
+func TestProxySinkProxyFor(t *testing.T) {
+	g := NewGomegaWithT(t)
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("NO_PROXY", "")
+	sink := ProxySink{}
+	proxyURL, err := sink.ProxyFor("oss-cn-hangzhou.aliyuncs.com", false)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(proxyURL).Should(BeNil())
+
+	// proxy from environment
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+	proxyURL, err = sink.ProxyFor("oss-cn-hangzhou.aliyuncs.com", false)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(proxyURL.String()).Should(Equal("http://env-proxy:3128"))
+
+	sink.ProxyURL = "http://proxy:8080"
+	sink.NoProxy = ".internal.example.com"
+	proxyURL, err = sink.ProxyFor("http://minio:9000", false)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(proxyURL.String()).Should(Equal("http://proxy:8080"))
+	proxyURL, err = sink.ProxyFor("minio.internal.example.com:9000", true)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(proxyURL).Should(BeNil())
+
+	secretPath := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(secretPath, "username"), []byte("user\n"), 0644)).Should(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(secretPath, "password"), []byte("p@ss"), 0644)).Should(Succeed())
+	sink.ProxySecretPath = secretPath
+	proxyURL, err = sink.ProxyFor("oss-cn-hangzhou.aliyuncs.com", false)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(proxyURL.User.Username()).Should(Equal("user"))
+	password, _ := proxyURL.User.Password()
+	g.Expect(password).Should(Equal("p@ss"))
+
+	sink.ProxySecretPath = filepath.Join(secretPath, "missing")
+	_, err = sink.ProxyFor("oss-cn-hangzhou.aliyuncs.com", false)
+	g.Expect(err).Should(HaveOccurred())
+}
+
 func TestConfig4(t *testing.T) {
 	g := NewGomegaWithT(t)
 	PrepareConfig()
@@ -0,0 +1,284 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filestream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// An archive packs several streams into a single object. Streams are concatenated in order, followed by the index
+// of them in json, the length of index in 8 bytes big endian and ArchiveTrailerMagic, so that the archive is self
+// described. Each stream is extracted by its byte range without reading the index.
+const (
+	ArchiveTrailerMagic = "PXARCH01"
+	archiveTrailerLen   = 8 + len(ArchiveTrailerMagic)
+
+	// ArchiveIndexSuffix is appended to the path of archive to store a copy of its index, so that the index is
+	// read without transferring the whole archive
+	ArchiveIndexSuffix = ".index"
+)
+
+// ArchiveEntry records the byte range of a stream in archive.
+type ArchiveEntry struct {
+	Name   string `json:"name"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+// ArchiveIndex lists the entries of archive in order.
+type ArchiveIndex struct {
+	Entries []ArchiveEntry `json:"entries"`
+}
+
+// ArchiveSource produces a stream to be packed into archive by writing it into writer.
+type ArchiveSource struct {
+	Name    string
+	WriteTo func(writer io.Writer) (int64, error)
+}
+
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// writeArchive writes streams of sources in order and then the index and trailer into writer.
+func writeArchive(writer io.Writer, sources []ArchiveSource) (*ArchiveIndex, error) {
+	index := &ArchiveIndex{Entries: make([]ArchiveEntry, 0, len(sources))}
+	counter := &countingWriter{writer: writer}
+	for _, source := range sources {
+		offset := counter.written
+		if _, err := source.WriteTo(counter); err != nil {
+			return nil, fmt.Errorf("failed to write %s into archive: %w", source.Name, err)
+		}
+		index.Entries = append(index.Entries, ArchiveEntry{
+			Name:   source.Name,
+			Offset: offset,
+			Size:   counter.written - offset,
+		})
+	}
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	trailer := make([]byte, archiveTrailerLen)
+	binary.BigEndian.PutUint64(trailer, uint64(len(indexBytes)))
+	copy(trailer[8:], ArchiveTrailerMagic)
+	if _, err := counter.Write(append(indexBytes, trailer...)); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// UploadArchive packs streams of sources into a single object and checks the upload. The index of archive is
+// returned.
+func UploadArchive(c Client, sources []ArchiveSource, actionMetadata ActionMetadata) (*ArchiveIndex, error) {
+	reader, writer := io.Pipe()
+	indexCh := make(chan *ArchiveIndex, 1)
+	go func() {
+		index, err := writeArchive(writer, sources)
+		indexCh <- index
+		writer.CloseWithError(err)
+	}()
+	_, err := c.Upload(reader, actionMetadata)
+	// unblock the writer if upload stops early
+	reader.CloseWithError(errors.New("upload of archive stopped"))
+	index := <-indexCh
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload archive: %w", err)
+	}
+	if index == nil {
+		return nil, errors.New("failed to upload archive: archive incomplete")
+	}
+	if err := c.Check(actionMetadata); err != nil {
+		return nil, fmt.Errorf("failed to upload archive: %w", err)
+	}
+	return index, nil
+}
+
+// tailWriter keeps the last bytes written no more than its capacity, along with the count of bytes written.
+type tailWriter struct {
+	tail    []byte
+	cap     int
+	written int64
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if len(p) >= w.cap {
+		w.tail = append(w.tail[:0], p[len(p)-w.cap:]...)
+		return len(p), nil
+	}
+	if overflow := len(w.tail) + len(p) - w.cap; overflow > 0 {
+		w.tail = append(w.tail[:0], w.tail[overflow:]...)
+	}
+	w.tail = append(w.tail, p...)
+	return len(p), nil
+}
+
+// VerifyArchive reads the archive back through and checks that it ends with index, and its size matches the
+// entries of index.
+func VerifyArchive(c Client, actionMetadata ActionMetadata, index *ArchiveIndex) error {
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	w := &tailWriter{cap: len(indexBytes) + archiveTrailerLen}
+	if _, err := c.Download(w, actionMetadata); err != nil {
+		return fmt.Errorf("failed to verify archive: %w", err)
+	}
+	parsed, err := ParseArchiveIndex(w.tail)
+	if err != nil {
+		return fmt.Errorf("failed to verify archive: %w", err)
+	}
+	if !reflect.DeepEqual(parsed, index) {
+		return errors.New("failed to verify archive: index mismatch")
+	}
+	var expectedSize int64
+	for _, entry := range index.Entries {
+		if entry.Offset != expectedSize {
+			return fmt.Errorf("failed to verify archive: entry %s at offset %d, expect %d", entry.Name,
+				entry.Offset, expectedSize)
+		}
+		expectedSize += entry.Size
+	}
+	expectedSize += int64(w.cap)
+	if w.written != expectedSize {
+		return fmt.Errorf("failed to verify archive: size is %d, expect %d", w.written, expectedSize)
+	}
+	return nil
+}
+
+// PackArchive packs the objects named by paths into a single archive, entries of which are named by names. Objects
+// are downloaded and the archive is verified with downloadAction, after which a copy of index is uploaded next to
+// the archive. The index is returned. Objects are left untouched, which are safe to remove once the index is found.
+func PackArchive(c Client, names, paths []string, actionMetadata ActionMetadata,
+	downloadAction Action) (*ArchiveIndex, error) {
+	if len(names) != len(paths) {
+		return nil, fmt.Errorf("count of names %d mismatches count of paths %d", len(names), len(paths))
+	}
+	downloadMetadata := actionMetadata
+	downloadMetadata.Action = downloadAction
+	sources := make([]ArchiveSource, 0, len(names))
+	for i := range names {
+		sourceMetadata := downloadMetadata
+		sourceMetadata.Filename = paths[i]
+		sources = append(sources, ArchiveSource{
+			Name: names[i],
+			WriteTo: func(writer io.Writer) (int64, error) {
+				return DownloadSplit(c, writer, sourceMetadata)
+			},
+		})
+	}
+	index, err := UploadArchive(c, sources, actionMetadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyArchive(c, downloadMetadata, index); err != nil {
+		return nil, err
+	}
+
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	indexMetadata := actionMetadata
+	indexMetadata.Filename = actionMetadata.Filename + ArchiveIndexSuffix
+	if _, err := c.Upload(bytes.NewReader(indexBytes), indexMetadata); err != nil {
+		return nil, fmt.Errorf("failed to upload archive index: %w", err)
+	}
+	if err := c.Check(indexMetadata); err != nil {
+		return nil, fmt.Errorf("failed to upload archive index: %w", err)
+	}
+	return index, nil
+}
+
+// ParseArchiveIndex parses the index from the tail of archive, which must be no shorter than the index and trailer.
+func ParseArchiveIndex(tail []byte) (*ArchiveIndex, error) {
+	if len(tail) < archiveTrailerLen || string(tail[len(tail)-len(ArchiveTrailerMagic):]) != ArchiveTrailerMagic {
+		return nil, errors.New("archive trailer not found")
+	}
+	indexLen := binary.BigEndian.Uint64(tail[len(tail)-archiveTrailerLen:])
+	if indexLen > uint64(len(tail)-archiveTrailerLen) {
+		return nil, errors.New("archive index truncated")
+	}
+	indexStart := len(tail) - archiveTrailerLen - int(indexLen)
+	index := &ArchiveIndex{}
+	if err := json.Unmarshal(tail[indexStart:len(tail)-archiveTrailerLen], index); err != nil {
+		return nil, fmt.Errorf("failed to parse archive index: %w", err)
+	}
+	return index, nil
+}
+
+// rangeWriter discards bytes before the range and passes the range through to writer.
+type rangeWriter struct {
+	writer  io.Writer
+	skip    int64
+	remain  int64
+	written int64
+}
+
+func (w *rangeWriter) Write(p []byte) (int, error) {
+	total := len(p)
+	if w.skip > 0 {
+		if int64(len(p)) <= w.skip {
+			w.skip -= int64(len(p))
+			return total, nil
+		}
+		p = p[w.skip:]
+		w.skip = 0
+	}
+	if int64(len(p)) > w.remain {
+		p = p[:w.remain]
+	}
+	n, err := w.writer.Write(p)
+	w.remain -= int64(n)
+	w.written += int64(n)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// DownloadRange downloads the byte range of object into writer, e.g. an entry of archive. Download stops at the
+// end of range, while bytes before the range are still transferred and discarded.
+func DownloadRange(c Client, writer io.Writer, actionMetadata ActionMetadata, offset, size int64) (int64, error) {
+	if offset < 0 || size < 0 {
+		return 0, fmt.Errorf("invalid range, offset %d, size %d", offset, size)
+	}
+	actionMetadata.LimitSize = strconv.FormatInt(offset+size, 10)
+	w := &rangeWriter{writer: writer, skip: offset, remain: size}
+	if _, err := c.Download(w, actionMetadata); err != nil {
+		return w.written, err
+	}
+	if w.written != size {
+		return w.written, fmt.Errorf("size of range is %d, expect %d", w.written, size)
+	}
+	return w.written, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filestream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestUploadArchiveAndDownloadRange(t *testing.T) {
+	g := NewGomegaWithT(t)
+	source := NewFakeFilestreamClient()
+	client := NewFakeFilestreamClient()
+	data := map[string]string{
+		"dn-0": strings.Repeat("a", 100),
+		"dn-1": "",
+		"gms":  strings.Repeat("c", 7),
+	}
+	sources := make([]ArchiveSource, 0)
+	for _, name := range []string{"dn-0", "dn-1", "gms"} {
+		source.PutFile("default", name+".xbstream", []byte(data[name]))
+		name := name
+		sources = append(sources, ArchiveSource{
+			Name: name,
+			WriteTo: func(writer io.Writer) (int64, error) {
+				return DownloadSplit(source, writer, ActionMetadata{Sink: "default", Filename: name + ".xbstream"})
+			},
+		})
+	}
+
+	metadata := ActionMetadata{Sink: "default", Filename: "fullbackup/archive"}
+	index, err := UploadArchive(client, sources, metadata)
+	g.Expect(err).To(BeNil())
+	g.Expect(index.Entries).To(Equal([]ArchiveEntry{
+		{Name: "dn-0", Offset: 0, Size: 100},
+		{Name: "dn-1", Offset: 100, Size: 0},
+		{Name: "gms", Offset: 100, Size: 7},
+	}))
+
+	archive, ok := client.GetFile("default", "fullbackup/archive")
+	g.Expect(ok).To(BeTrue())
+	parsed, err := ParseArchiveIndex(archive)
+	g.Expect(err).To(BeNil())
+	g.Expect(parsed).To(Equal(index))
+
+	for _, entry := range index.Entries {
+		var buf bytes.Buffer
+		size, err := DownloadRange(client, &buf, metadata, entry.Offset, entry.Size)
+		g.Expect(err).To(BeNil())
+		g.Expect(size).To(Equal(entry.Size))
+		g.Expect(buf.String()).To(Equal(data[entry.Name]))
+	}
+	// download stops at the end of range
+	g.Expect(client.Downloads[len(client.Downloads)-1].LimitSize).To(Equal("107"))
+
+	_, err = DownloadRange(client, &bytes.Buffer{}, metadata, int64(len(archive)), 1)
+	g.Expect(err).To(MatchError("size of range is 0, expect 1"))
+}
+
+func TestUploadArchiveSourceFailed(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := NewFakeFilestreamClient()
+	_, err := UploadArchive(client, []ArchiveSource{{
+		Name: "dn-0",
+		WriteTo: func(writer io.Writer) (int64, error) {
+			return 0, errors.New("not found")
+		},
+	}}, ActionMetadata{Sink: "default", Filename: "archive"})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("failed to write dn-0 into archive: not found"))
+}
+
+func TestPackArchive(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := NewFakeFilestreamClient()
+	client.PutFile("default", "fullbackup/dn-0.xbstream", []byte(strings.Repeat("a", 100)))
+	client.PutFile("default", "fullbackup/dn-1.xbstream", []byte(strings.Repeat("b", 3)))
+
+	metadata := ActionMetadata{Action: UploadOss, Sink: "default", Filename: "fullbackup/archive"}
+	index, err := PackArchive(client, []string{"dn-0", "dn-1"},
+		[]string{"fullbackup/dn-0.xbstream", "fullbackup/dn-1.xbstream"}, metadata, DownloadOss)
+	g.Expect(err).To(BeNil())
+	g.Expect(index.Entries).To(Equal([]ArchiveEntry{
+		{Name: "dn-0", Offset: 0, Size: 100},
+		{Name: "dn-1", Offset: 100, Size: 3},
+	}))
+
+	indexBytes, ok := client.GetFile("default", "fullbackup/archive"+ArchiveIndexSuffix)
+	g.Expect(ok).To(BeTrue())
+	copied := &ArchiveIndex{}
+	g.Expect(json.Unmarshal(indexBytes, copied)).To(Succeed())
+	g.Expect(copied).To(Equal(index))
+	// objects packed are left untouched
+	_, ok = client.GetFile("default", "fullbackup/dn-0.xbstream")
+	g.Expect(ok).To(BeTrue())
+
+	_, err = PackArchive(client, []string{"dn-0"}, nil, metadata, DownloadOss)
+	g.Expect(err).To(MatchError("count of names 1 mismatches count of paths 0"))
+}
+
+func TestVerifyArchive(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := NewFakeFilestreamClient()
+	index := &ArchiveIndex{Entries: []ArchiveEntry{{Name: "dn-0", Offset: 0, Size: 4}}}
+	var archive bytes.Buffer
+	_, err := writeArchive(&archive, []ArchiveSource{{
+		Name: "dn-0",
+		WriteTo: func(writer io.Writer) (int64, error) {
+			n, err := writer.Write([]byte("abcd"))
+			return int64(n), err
+		},
+	}})
+	g.Expect(err).To(BeNil())
+	metadata := ActionMetadata{Sink: "default", Filename: "archive"}
+
+	client.PutFile("default", "archive", archive.Bytes())
+	g.Expect(VerifyArchive(client, metadata, index)).To(Succeed())
+
+	// stream lost in the middle
+	client.PutFile("default", "archive", archive.Bytes()[1:])
+	g.Expect(VerifyArchive(client, metadata, index)).To(MatchError("failed to verify archive: size is " +
+		strconv.Itoa(archive.Len()-1) + ", expect " + strconv.Itoa(archive.Len())))
+
+	// archive of another index
+	client.PutFile("default", "archive", archive.Bytes())
+	other := &ArchiveIndex{Entries: []ArchiveEntry{{Name: "dn-1", Offset: 0, Size: 4}}}
+	g.Expect(VerifyArchive(client, metadata, other)).To(MatchError("failed to verify archive: index mismatch"))
+
+	client.PutFile("default", "archive", []byte("abcd"))
+	g.Expect(VerifyArchive(client, metadata, index)).To(MatchError(
+		"failed to verify archive: archive trailer not found"))
+}
+
+func TestParseArchiveIndexInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+	_, err := ParseArchiveIndex([]byte("short"))
+	g.Expect(err).To(MatchError("archive trailer not found"))
+	_, err = ParseArchiveIndex([]byte("\x00\x00\x00\x00\x00\x00\x00\x10" + ArchiveTrailerMagic))
+	g.Expect(err).To(MatchError("archive index truncated"))
+}
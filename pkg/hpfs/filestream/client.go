@@ -17,10 +17,13 @@ limitations under the License.
 package filestream
 
 import (
+	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	. "github.com/alibaba/polardbx-operator/pkg/hpfs/common"
+	"github.com/alibaba/polardbx-operator/pkg/util/path"
 	"go.uber.org/atomic"
 	"io"
 	net2 "k8s.io/apimachinery/pkg/util/net"
@@ -31,6 +34,24 @@ import (
 
 const ClientCopyBufferSize = (1 << 20) * 5
 
+// Client is the interface of filestream client, implemented by FileClient and FakeFilestreamClient.
+type Client interface {
+	Upload(reader io.Reader, actionMetadata ActionMetadata) (int64, error)
+	Download(writer io.Writer, actionMetadata ActionMetadata) (int64, error)
+	List(writer io.Writer, actionMetadata ActionMetadata) (int64, error)
+	Check(actionMetadata ActionMetadata) error
+	Exists(actionMetadata ActionMetadata) (bool, error)
+	InitWaitChan()
+	WaitForDownload() error
+	// Ping checks the filestream server is reachable.
+	Ping() error
+}
+
+var (
+	_ Client = &FileClient{}
+	_ Client = &FakeFilestreamClient{}
+)
+
 type FileClient struct {
 	host        string
 	port        int
@@ -101,6 +122,36 @@ func (f *FileClient) Upload(reader io.Reader, actionMetadata ActionMetadata) (in
 	return 0, nil
 }
 
+// PingTimeout is the timeout to connect filestream server when pinging.
+const PingTimeout = 3 * time.Second
+
+// ErrServerUnreachable is returned if the filestream server is unreachable after retries.
+var ErrServerUnreachable = errors.New("filestream server unreachable")
+
+func (f *FileClient) Ping() error {
+	conn, err := net.DialTimeout("tcp", f.addr(), PingTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// PingWithRetry pings the filestream server up to attempts times, sleeping for backoff before the first retry and
+// doubling it after each retry. The error of the last attempt is returned if all attempts fail.
+func PingWithRetry(client Client, attempts int, backoff time.Duration) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = client.Ping(); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w after %d attempts: %v", ErrServerUnreachable, attempts, err)
+}
+
 func (f *FileClient) Check(actionMetadata ActionMetadata) error {
 	conn, err := net.Dial("tcp", f.addr())
 	if err != nil {
@@ -184,6 +235,32 @@ func (f *FileClient) List(writer io.Writer, actionMetadata ActionMetadata) (int6
 	return f.Download(writer, actionMetadata)
 }
 
+// Exists lists the parent directory of actionMetadata.Filepath and checks whether the file is in it.
+// actionMetadata.Action must be a list action.
+func (f *FileClient) Exists(actionMetadata ActionMetadata) (bool, error) {
+	return exists(f, actionMetadata)
+}
+
+func exists(c Client, actionMetadata ActionMetadata) (bool, error) {
+	filePath := actionMetadata.Filepath
+	actionMetadata.Filepath = path.GetParentPathFromPath(filePath)
+	var buf bytes.Buffer
+	if _, err := c.List(&buf, actionMetadata); err != nil {
+		return false, err
+	}
+	var entryNames []string
+	if err := json.Unmarshal(buf.Bytes(), &entryNames); err != nil {
+		return false, err
+	}
+	baseName := path.GetBaseNameFromPath(filePath)
+	for _, entryName := range entryNames {
+		if entryName == baseName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (f *FileClient) writeMagicNumber(conn net.Conn) {
 	bytes := make([]byte, 4)
 	binary.BigEndian.PutUint32(bytes, MagicNumber)
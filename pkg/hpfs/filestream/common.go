@@ -15,6 +15,10 @@ func GetClientActionBySinkType(sinkType string) Action {
 		return DownloadSsh
 	case config.SinkTypeMinio:
 		return DownloadMinio
+	case config.SinkTypeAzure:
+		return DownloadAzure
+	case config.SinkTypeGcs:
+		return DownloadGcs
 	}
 	return InvalidAction
 }
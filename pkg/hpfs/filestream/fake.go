@@ -0,0 +1,221 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filestream
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alibaba/polardbx-operator/pkg/util/path"
+)
+
+// FakeFilestreamClient is an in-memory Client for tests. It keeps uploaded files by sink and
+// file name, records every action and returns injected errors if set. Server-side encryption of
+// uploaded files is kept as well, and downloads of them are rejected unless requesting the same one.
+type FakeFilestreamClient struct {
+	mu sync.Mutex
+
+	files map[string][]byte
+	sse   map[string]fakeServerSideEncryption
+
+	// Uploads, Downloads and Lists record the action metadata of each call.
+	Uploads   []ActionMetadata
+	Downloads []ActionMetadata
+	Lists     []ActionMetadata
+
+	// UploadErr, DownloadErr and ListErr are returned by corresponding calls when not nil.
+	UploadErr   error
+	DownloadErr error
+	ListErr     error
+	CheckErr    error
+
+	// DownloadInterrupts makes Download calls interrupted in order, each of which writes at most the first
+	// count of bytes and then fails. Negative count leaves the call not interrupted.
+	DownloadInterrupts []int64
+
+	// Pings records the count of Ping calls. PingErr is returned by Ping when not nil, only by the first
+	// PingFailures calls if PingFailures is positive.
+	Pings        int
+	PingErr      error
+	PingFailures int
+}
+
+func NewFakeFilestreamClient() *FakeFilestreamClient {
+	return &FakeFilestreamClient{
+		files: make(map[string][]byte),
+		sse:   make(map[string]fakeServerSideEncryption),
+	}
+}
+
+type fakeServerSideEncryption struct {
+	algorithm string
+	kmsKeyId  string
+}
+
+func fakeFileKey(sink, filename string) string {
+	return sink + ":" + path.JoinPath(filename)
+}
+
+// PutFile puts a file into the fake storage directly.
+func (f *FakeFilestreamClient) PutFile(sink, filename string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[fakeFileKey(sink, filename)] = append([]byte(nil), data...)
+	delete(f.sse, fakeFileKey(sink, filename))
+}
+
+// GetFileServerSideEncryption gets the server-side encryption algorithm and kms key id of a file uploaded,
+// empty if not encrypted.
+func (f *FakeFilestreamClient) GetFileServerSideEncryption(sink, filename string) (string, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sse := f.sse[fakeFileKey(sink, filename)]
+	return sse.algorithm, sse.kmsKeyId
+}
+
+// GetFile gets a file from the fake storage, false returned if not found.
+func (f *FakeFilestreamClient) GetFile(sink, filename string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.files[fakeFileKey(sink, filename)]
+	return data, ok
+}
+
+func (f *FakeFilestreamClient) Upload(reader io.Reader, actionMetadata ActionMetadata) (int64, error) {
+	f.mu.Lock()
+	f.Uploads = append(f.Uploads, actionMetadata)
+	uploadErr := f.UploadErr
+	f.mu.Unlock()
+	if uploadErr != nil {
+		return 0, uploadErr
+	}
+	// the reader is drained without lock like a separate connection, which may be fed by downloads of the client
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := fakeFileKey(actionMetadata.Sink, actionMetadata.Filename)
+	f.files[key] = data
+	if actionMetadata.SSEAlgorithm != "" {
+		f.sse[key] = fakeServerSideEncryption{algorithm: actionMetadata.SSEAlgorithm, kmsKeyId: actionMetadata.SSEKMSKeyId}
+	} else {
+		delete(f.sse, key)
+	}
+	return int64(len(data)), nil
+}
+
+func (f *FakeFilestreamClient) Download(writer io.Writer, actionMetadata ActionMetadata) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Downloads = append(f.Downloads, actionMetadata)
+	if f.DownloadErr != nil {
+		return 0, f.DownloadErr
+	}
+	key := fakeFileKey(actionMetadata.Sink, actionMetadata.Filename)
+	data, ok := f.files[key]
+	if !ok {
+		return 0, errors.New("file not found: " + actionMetadata.Filename)
+	}
+	if sse, ok := f.sse[key]; ok && (sse.algorithm != actionMetadata.SSEAlgorithm || sse.kmsKeyId != actionMetadata.SSEKMSKeyId) {
+		return 0, errors.New("server-side encryption mismatch: " + actionMetadata.Filename)
+	}
+	if actionMetadata.RangeOffset != "" {
+		offset, err := strconv.ParseInt(actionMetadata.RangeOffset, 10, 64)
+		if err != nil || offset < 0 || offset > int64(len(data)) {
+			return 0, errors.New("invalid range offset: " + actionMetadata.RangeOffset)
+		}
+		data = data[offset:]
+	}
+	if limitSize, err := strconv.ParseInt(actionMetadata.LimitSize, 10, 64); err == nil && limitSize < int64(len(data)) {
+		data = data[:limitSize]
+	}
+	if len(f.DownloadInterrupts) > 0 {
+		interrupt := f.DownloadInterrupts[0]
+		f.DownloadInterrupts = f.DownloadInterrupts[1:]
+		if interrupt >= 0 && interrupt < int64(len(data)) {
+			n, _ := writer.Write(data[:interrupt])
+			return int64(n), errors.New("download interrupted")
+		}
+	}
+	n, err := writer.Write(data)
+	return int64(n), err
+}
+
+// List writes base names of files and subdirectories directly under actionMetadata.Filepath as a json array.
+func (f *FakeFilestreamClient) List(writer io.Writer, actionMetadata ActionMetadata) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Lists = append(f.Lists, actionMetadata)
+	if f.ListErr != nil {
+		return 0, f.ListErr
+	}
+	prefix := actionMetadata.Sink + ":"
+	if dir := path.JoinPath(actionMetadata.Filepath); dir != "" {
+		prefix += dir + "/"
+	}
+	entrySet := make(map[string]struct{})
+	for key := range f.files {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		entryName := strings.SplitN(strings.TrimPrefix(key, prefix), "/", 2)[0]
+		entrySet[entryName] = struct{}{}
+	}
+	entryNames := make([]string, 0, len(entrySet))
+	for entryName := range entrySet {
+		entryNames = append(entryNames, entryName)
+	}
+	sort.Strings(entryNames)
+	data, err := json.Marshal(entryNames)
+	if err != nil {
+		return 0, err
+	}
+	n, err := writer.Write(data)
+	return int64(n), err
+}
+
+func (f *FakeFilestreamClient) Check(actionMetadata ActionMetadata) error {
+	return f.CheckErr
+}
+
+func (f *FakeFilestreamClient) Exists(actionMetadata ActionMetadata) (bool, error) {
+	return exists(f, actionMetadata)
+}
+
+func (f *FakeFilestreamClient) InitWaitChan() {
+}
+
+func (f *FakeFilestreamClient) WaitForDownload() error {
+	return nil
+}
+
+func (f *FakeFilestreamClient) Ping() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Pings++
+	if f.PingErr == nil || (f.PingFailures > 0 && f.Pings > f.PingFailures) {
+		return nil
+	}
+	return f.PingErr
+}
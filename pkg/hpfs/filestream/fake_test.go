@@ -0,0 +1,123 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filestream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestFakeFilestreamClientUploadAndDownload(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := NewFakeFilestreamClient()
+
+	sent, err := client.Upload(strings.NewReader("metadata"), ActionMetadata{Sink: "default", Filename: "backup/root/metadata"})
+	g.Expect(err).To(BeNil())
+	g.Expect(sent).To(BeEquivalentTo(len("metadata")))
+	g.Expect(client.Uploads).To(HaveLen(1))
+
+	var buf bytes.Buffer
+	_, err = client.Download(&buf, ActionMetadata{Sink: "default", Filename: "backup/root/metadata"})
+	g.Expect(err).To(BeNil())
+	g.Expect(buf.String()).To(Equal("metadata"))
+
+	_, err = client.Download(&buf, ActionMetadata{Sink: "other", Filename: "backup/root/metadata"})
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(client.Downloads).To(HaveLen(2))
+}
+
+func TestFakeFilestreamClientServerSideEncryption(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := NewFakeFilestreamClient()
+	encrypted := ActionMetadata{Sink: "default", Filename: "backup/root/metadata", SSEAlgorithm: "KMS", SSEKMSKeyId: "key"}
+
+	_, err := client.Upload(strings.NewReader("metadata"), encrypted)
+	g.Expect(err).To(BeNil())
+	algorithm, kmsKeyId := client.GetFileServerSideEncryption("default", "backup/root/metadata")
+	g.Expect(algorithm).To(Equal("KMS"))
+	g.Expect(kmsKeyId).To(Equal("key"))
+
+	var buf bytes.Buffer
+	_, err = client.Download(&buf, ActionMetadata{Sink: "default", Filename: "backup/root/metadata"})
+	g.Expect(err).To(MatchError(ContainSubstring("server-side encryption mismatch")))
+	_, err = client.Download(&buf, ActionMetadata{Sink: "default", Filename: "backup/root/metadata", SSEAlgorithm: "KMS", SSEKMSKeyId: "other"})
+	g.Expect(err).To(MatchError(ContainSubstring("server-side encryption mismatch")))
+	_, err = client.Download(&buf, encrypted)
+	g.Expect(err).To(BeNil())
+	g.Expect(buf.String()).To(Equal("metadata"))
+}
+
+func TestFakeFilestreamClientListAndExists(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := NewFakeFilestreamClient()
+	client.PutFile("default", "backup/root/fullbackup/xs-0.xbstream", []byte("0"))
+	client.PutFile("default", "backup/root/fullbackup/xs-1.xbstream", []byte("1"))
+	client.PutFile("default", "backup/root/metadata", []byte("m"))
+
+	var buf bytes.Buffer
+	_, err := client.List(&buf, ActionMetadata{Sink: "default", Filepath: "backup/root/fullbackup/"})
+	g.Expect(err).To(BeNil())
+	var entryNames []string
+	g.Expect(json.Unmarshal(buf.Bytes(), &entryNames)).To(Succeed())
+	g.Expect(entryNames).To(Equal([]string{"xs-0.xbstream", "xs-1.xbstream"}))
+
+	existed, err := client.Exists(ActionMetadata{Sink: "default", Filepath: "backup/root/fullbackup/xs-1.xbstream"})
+	g.Expect(err).To(BeNil())
+	g.Expect(existed).To(BeTrue())
+
+	existed, err = client.Exists(ActionMetadata{Sink: "default", Filepath: "backup/root/fullbackup/xs-2.xbstream"})
+	g.Expect(err).To(BeNil())
+	g.Expect(existed).To(BeFalse())
+}
+
+func TestFakeFilestreamClientInjectErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := NewFakeFilestreamClient()
+	client.UploadErr = errors.New("sink broken")
+	client.ListErr = errors.New("list broken")
+
+	_, err := client.Upload(strings.NewReader("metadata"), ActionMetadata{Sink: "default", Filename: "metadata"})
+	g.Expect(err).To(MatchError("sink broken"))
+	_, existed := client.GetFile("default", "metadata")
+	g.Expect(existed).To(BeFalse())
+
+	_, err = client.Exists(ActionMetadata{Sink: "default", Filepath: "metadata"})
+	g.Expect(err).To(MatchError("list broken"))
+}
+
+func TestPingWithRetry(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := NewFakeFilestreamClient()
+	client.PingErr = errors.New("connection refused")
+	client.PingFailures = 2
+
+	g.Expect(PingWithRetry(client, 3, time.Millisecond)).To(Succeed())
+	g.Expect(client.Pings).To(Equal(3))
+
+	// unreachable after all attempts
+	client.Pings, client.PingFailures = 0, 0
+	err := PingWithRetry(client, 3, time.Millisecond)
+	g.Expect(errors.Is(err, ErrServerUnreachable)).To(BeTrue())
+	g.Expect(err).To(MatchError(ContainSubstring("connection refused")))
+	g.Expect(client.Pings).To(Equal(3))
+}
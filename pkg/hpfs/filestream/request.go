@@ -16,7 +16,10 @@ limitations under the License.
 
 package filestream
 
-import "strings"
+import (
+	"net/url"
+	"strings"
+)
 
 type Action string
 
@@ -35,6 +38,12 @@ const (
 	UploadMinio    Action = "uploadMinio"
 	DownloadMinio  Action = "downloadMinio"
 	ListMinio      Action = "listMinio"
+	UploadAzure    Action = "uploadAzure"
+	DownloadAzure  Action = "downloadAzure"
+	ListAzure      Action = "listAzure"
+	UploadGcs      Action = "uploadGcs"
+	DownloadGcs    Action = "downloadGcs"
+	ListGcs        Action = "listGcs"
 	InvalidAction  Action = ""
 )
 
@@ -46,7 +55,12 @@ const (
 
 const (
 	MetaDataLenLen                = 4
-	MetaFiledLen                  = 12
+	MetaFiledLen                  = 19
+	LegacyMetaFiledLen            = 12
+	UntaggedMetaFiledLen          = 14 // metadata from clients not requiring object tags
+	UnencryptedMetaFiledLen       = 15 // metadata from clients not requiring server-side encryption
+	DefaultEndpointMetaFiledLen   = 17 // metadata from clients not selecting endpoint
+	UnrangedMetaFiledLen          = 18 // metadata from clients not requiring range download
 	MetadataActionOffset          = 0
 	MetadataInstanceIdOffset      = 1
 	MetadataFilenameOffset        = 2
@@ -59,6 +73,13 @@ const (
 	MetadataOssBufferSizeOffset   = 9
 	MetadataLimitSize             = 10
 	MetadataMinioBufferSizeOffset = 11
+	MetadataUploadConcurrency     = 12
+	MetadataUploadPartSize        = 13
+	MetadataTags                  = 14
+	MetadataSSEAlgorithm          = 15
+	MetadataSSEKMSKeyId           = 16
+	MetadataEndpointType          = 17
+	MetadataRangeOffset           = 18
 )
 
 var ActionLocal2Remote2 = map[Action]Action{
@@ -81,11 +102,54 @@ type ActionMetadata struct {
 	OssBufferSize   string `json:"ossBufferSize,omitempty"`
 	LimitSize       string `json:"limitSize,omitempty"`
 	MinioBufferSize string `json:"minioBufferSize,omitempty"`
-	redirect        bool
+	// UploadConcurrency and UploadPartSize make the object uploaded by parts concurrently
+	UploadConcurrency string `json:"uploadConcurrency,omitempty"`
+	UploadPartSize    string `json:"uploadPartSize,omitempty"`
+	// Tags are attached to uploaded objects by file services supporting tagging, encoded by EncodeObjectTags
+	Tags string `json:"tags,omitempty"`
+	// SSEAlgorithm and SSEKMSKeyId request server-side encryption on uploaded objects by file services supporting it
+	SSEAlgorithm string `json:"sseAlgorithm,omitempty"`
+	SSEKMSKeyId  string `json:"sseKMSKeyId,omitempty"`
+	// EndpointType selects the endpoint of sink by file services supporting it, e.g. Accelerate
+	EndpointType string `json:"endpointType,omitempty"`
+	// RangeOffset makes the download start from the offset of the file, so that a broken download is resumed
+	RangeOffset string `json:"rangeOffset,omitempty"`
+	redirect    bool
 }
 
 func (action *ActionMetadata) ToString() string {
-	return strings.Join([]string{string(action.Action), action.InstanceId, action.Filename, action.RedirectAddr, action.Filepath, action.RetentionTime, action.Stream, action.Sink, action.RequestId, action.OssBufferSize, action.LimitSize, action.MinioBufferSize}, ",")
+	fields := []string{string(action.Action), action.InstanceId, action.Filename, action.RedirectAddr, action.Filepath, action.RetentionTime, action.Stream, action.Sink, action.RequestId, action.OssBufferSize, action.LimitSize, action.MinioBufferSize}
+	// keep compatible with legacy server if upload concurrently, object tags, server-side encryption, endpoint
+	// selection and range download not required
+	rangeRequested := action.RangeOffset != ""
+	endpointSelected := action.EndpointType != "" || rangeRequested
+	encrypted := action.SSEAlgorithm != "" || endpointSelected
+	tagged := action.Tags != "" || encrypted
+	if action.UploadConcurrency != "" || action.UploadPartSize != "" || tagged {
+		fields = append(fields, action.UploadConcurrency, action.UploadPartSize)
+	}
+	if tagged {
+		fields = append(fields, action.Tags)
+	}
+	if encrypted {
+		fields = append(fields, action.SSEAlgorithm, action.SSEKMSKeyId)
+	}
+	if endpointSelected {
+		fields = append(fields, action.EndpointType)
+	}
+	if rangeRequested {
+		fields = append(fields, action.RangeOffset)
+	}
+	return strings.Join(fields, ",")
+}
+
+// EncodeObjectTags encodes tags as url query, which contains no comma and is able to be carried by metadata.
+func EncodeObjectTags(tags map[string]string) string {
+	values := url.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
 }
 
 const (
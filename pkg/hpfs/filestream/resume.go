@@ -0,0 +1,305 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filestream
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ResumeStateSuffix is appended to the local file of resumable download to name the file persisting its progress.
+const ResumeStateSuffix = ".resume"
+
+// resumeSyncInterval is the count of bytes downloaded between two persistences of progress.
+const resumeSyncInterval = 64 << 20 // 64MB
+
+// resumeBackoff is the interval before retrying a failed download, growing with attempts.
+var resumeBackoff = 5 * time.Second
+
+// ResumeState records the progress of resumable download, bytes before Offset have been synced to the local file.
+type ResumeState struct {
+	Sink     string `json:"sink,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Filepath string `json:"filepath,omitempty"`
+	Offset   int64  `json:"offset"`
+	// Split is the manifest if the object is found to be split on upload, and Offset is of the reassembled stream
+	Split *SplitManifest `json:"split,omitempty"`
+	// Completed means the whole object has been downloaded, and is to be verified
+	Completed bool `json:"completed,omitempty"`
+}
+
+// ChunkManifest lists offset, size and sha256 of each chunk of a stream, which is uploaded along with full backup.
+type ChunkManifest struct {
+	ChunkSize int64         `json:"chunkSize"`
+	Chunks    []ChunkDigest `json:"chunks"`
+}
+
+type ChunkDigest struct {
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// Validate checks that chunks are contiguous from the beginning.
+func (m *ChunkManifest) Validate() error {
+	var offset int64
+	for i, chunk := range m.Chunks {
+		if chunk.Offset != offset || chunk.Size < 0 {
+			return fmt.Errorf("invalid chunk %d, offset %d, size %d", i, chunk.Offset, chunk.Size)
+		}
+		offset += chunk.Size
+	}
+	return nil
+}
+
+func newResumeState(actionMetadata ActionMetadata) *ResumeState {
+	return &ResumeState{
+		Sink:     actionMetadata.Sink,
+		Filename: actionMetadata.Filename,
+		Filepath: actionMetadata.Filepath,
+	}
+}
+
+// loadResumeState loads the progress persisted at statePath, a new one is returned if not found, broken or
+// persisted for another object.
+func loadResumeState(statePath string, actionMetadata ActionMetadata) *ResumeState {
+	state := newResumeState(actionMetadata)
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return state
+	}
+	persisted := &ResumeState{}
+	if err := json.Unmarshal(data, persisted); err != nil || persisted.Offset < 0 || persisted.Sink != state.Sink ||
+		persisted.Filename != state.Filename || persisted.Filepath != state.Filepath {
+		return state
+	}
+	return persisted
+}
+
+// saveResumeState persists the progress by renaming, so that the persisted one is never half written.
+func saveResumeState(statePath string, state *ResumeState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmpPath := statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, statePath)
+}
+
+// resumeWriter writes downloaded bytes to the local file, and persists the progress after they are synced.
+type resumeWriter struct {
+	file      *os.File
+	statePath string
+	state     *ResumeState
+	unsynced  int64
+}
+
+func (w *resumeWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.state.Offset += int64(n)
+	w.unsynced += int64(n)
+	if err == nil && w.unsynced >= resumeSyncInterval {
+		err = w.sync()
+	}
+	return n, err
+}
+
+func (w *resumeWriter) sync() error {
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.unsynced = 0
+	return saveResumeState(w.statePath, w.state)
+}
+
+// truncate drops the bytes from offset, which are to be downloaded again.
+func (w *resumeWriter) truncate(offset int64) error {
+	if err := w.file.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	w.state.Offset = offset
+	w.state.Completed = false
+	return w.sync()
+}
+
+// DownloadResumable downloads the object into the local file, retrying at most attempts times on failure. The
+// progress is persisted next to the local file, so that a failed download, even by a restarted process, resumes
+// from the last synced offset by range download rather than from the beginning. Objects split on upload are
+// reassembled. Once downloaded, the local file is verified against chunkManifest, and chunks mismatched are dropped
+// to be downloaded again by the next call. Without chunkManifest there is nothing to tell bytes downloaded before
+// from the ones of an object overwritten since, so that the download is never resumed but started over on retry.
+// The size of the local file is returned.
+func DownloadResumable(c Client, localPath string, actionMetadata ActionMetadata, attempts int,
+	chunkManifest *ChunkManifest) (int64, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if chunkManifest != nil {
+		if err := chunkManifest.Validate(); err != nil {
+			return 0, err
+		}
+	}
+	statePath := localPath + ResumeStateSuffix
+	resumable := chunkManifest != nil
+	state := newResumeState(actionMetadata)
+	if resumable {
+		state = loadResumeState(statePath, actionMetadata)
+	}
+	f, err := os.OpenFile(localPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	// bytes after the persisted offset are not known to be synced
+	if fileInfo.Size() < state.Offset {
+		state = newResumeState(actionMetadata)
+	}
+	if err := f.Truncate(state.Offset); err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(state.Offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	w := &resumeWriter{file: f, statePath: statePath, state: state}
+
+	for i := 0; !state.Completed; i++ {
+		if i >= attempts {
+			return state.Offset, fmt.Errorf("failed to download after %d attempts at offset %d: %w", attempts,
+				state.Offset, err)
+		}
+		if i > 0 {
+			time.Sleep(resumeBackoff * time.Duration(i))
+			if !resumable {
+				if err := w.truncate(0); err != nil {
+					return state.Offset, err
+				}
+				state.Split = nil
+			}
+		}
+		err = resumeDownload(c, w, actionMetadata)
+		if err == nil {
+			state.Completed = true
+		}
+		if syncErr := w.sync(); syncErr != nil {
+			return state.Offset, syncErr
+		}
+	}
+
+	if resumable {
+		if offset, err := verifyChunks(f, state.Offset, chunkManifest); err != nil {
+			if truncateErr := w.truncate(offset); truncateErr != nil {
+				return state.Offset, truncateErr
+			}
+			return state.Offset, err
+		}
+	}
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		return state.Offset, err
+	}
+	return state.Offset, nil
+}
+
+// resumeDownload downloads the rest of object from the offset of progress.
+func resumeDownload(c Client, w *resumeWriter, actionMetadata ActionMetadata) error {
+	state := w.state
+	if state.Split == nil && state.Offset == 0 {
+		// not known whether object is the manifest of split stream
+		detector := &splitManifestDetector{writer: w}
+		if _, err := c.Download(detector, actionMetadata); err != nil {
+			return err
+		}
+		if !detector.decided {
+			// object shorter than the magic
+			return detector.flushHead()
+		}
+		if detector.manifest == nil {
+			return nil
+		}
+		manifest := &SplitManifest{}
+		if err := json.Unmarshal(detector.manifest.Bytes(), manifest); err != nil {
+			return fmt.Errorf("failed to parse split manifest: %w", err)
+		}
+		state.Split = manifest
+	}
+
+	if state.Split == nil {
+		return downloadFromOffset(c, w, actionMetadata, state.Offset)
+	}
+	var partOffset int64
+	for i, partSize := range state.Split.PartSizes {
+		partEnd := partOffset + partSize
+		if state.Offset < partEnd {
+			if err := downloadFromOffset(c, w, splitPartMetadata(actionMetadata, i), state.Offset-partOffset); err != nil {
+				return fmt.Errorf("failed to download part %d: %w", i, err)
+			}
+			if state.Offset != partEnd {
+				return fmt.Errorf("size of part %d is %d, expect %d", i, state.Offset-partOffset, partSize)
+			}
+		}
+		partOffset = partEnd
+	}
+	if state.Offset != state.Split.Size {
+		return fmt.Errorf("size of split object is %d, expect %d", state.Offset, state.Split.Size)
+	}
+	return nil
+}
+
+// downloadFromOffset downloads object from offset by range download.
+func downloadFromOffset(c Client, writer io.Writer, actionMetadata ActionMetadata, offset int64) error {
+	if offset > 0 {
+		actionMetadata.RangeOffset = strconv.FormatInt(offset, 10)
+	}
+	_, err := c.Download(writer, actionMetadata)
+	return err
+}
+
+// verifyChunks verifies sha256 of each chunk of the file of size against the manifest. The offset from which
+// the file is to be downloaded again is returned along with the error.
+func verifyChunks(f *os.File, size int64, chunkManifest *ChunkManifest) (int64, error) {
+	var offset int64
+	for i, chunk := range chunkManifest.Chunks {
+		h := sha256.New()
+		if _, err := io.Copy(h, io.NewSectionReader(f, chunk.Offset, chunk.Size)); err != nil {
+			return chunk.Offset, err
+		}
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != chunk.Sha256 {
+			return chunk.Offset, fmt.Errorf("sha256 of chunk %d at offset %d is %s, expect %s", i, chunk.Offset,
+				actual, chunk.Sha256)
+		}
+		offset += chunk.Size
+	}
+	if offset != size {
+		return 0, fmt.Errorf("size of file is %d, expect %d", size, offset)
+	}
+	return size, nil
+}
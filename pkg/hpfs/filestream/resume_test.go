@@ -0,0 +1,140 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filestream
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func newChunkManifest(data string, chunkSize int) *ChunkManifest {
+	manifest := &ChunkManifest{ChunkSize: int64(chunkSize)}
+	for offset := 0; offset < len(data); offset += chunkSize {
+		end := offset + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := sha256.Sum256([]byte(data[offset:end]))
+		manifest.Chunks = append(manifest.Chunks, ChunkDigest{
+			Offset: int64(offset),
+			Size:   int64(end - offset),
+			Sha256: hex.EncodeToString(sum[:]),
+		})
+	}
+	return manifest
+}
+
+func TestDownloadResumable(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resumeBackoff = 0
+	client := NewFakeFilestreamClient()
+	metadata := ActionMetadata{Sink: "default", Filename: "backup/fullbackup/dn-0.xbstream"}
+	data := strings.Repeat("0123456789", 100)
+	client.PutFile("default", metadata.Filename, []byte(data))
+	localPath := filepath.Join(t.TempDir(), "dn-0.xbstream")
+
+	// broken downloads are resumed by retries
+	client.DownloadInterrupts = []int64{100, 300}
+	size, err := DownloadResumable(client, localPath, metadata, 3, newChunkManifest(data, 256))
+	g.Expect(err).To(BeNil())
+	g.Expect(size).To(BeEquivalentTo(1000))
+	local, _ := os.ReadFile(localPath)
+	g.Expect(string(local)).To(Equal(data))
+	g.Expect(client.Downloads).To(HaveLen(3))
+	g.Expect(client.Downloads[1].RangeOffset).To(Equal("100"))
+	g.Expect(client.Downloads[2].RangeOffset).To(Equal("400"))
+	_, err = os.Stat(localPath + ResumeStateSuffix)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+
+	// progress persisted is resumed by another call, e.g. after restart
+	client.Downloads = nil
+	client.DownloadInterrupts = []int64{500}
+	_, err = DownloadResumable(client, localPath, metadata, 1, newChunkManifest(data, 256))
+	g.Expect(err).NotTo(BeNil())
+	_, err = os.Stat(localPath + ResumeStateSuffix)
+	g.Expect(err).To(BeNil())
+	size, err = DownloadResumable(client, localPath, metadata, 1, newChunkManifest(data, 256))
+	g.Expect(err).To(BeNil())
+	g.Expect(size).To(BeEquivalentTo(1000))
+	g.Expect(client.Downloads[1].RangeOffset).To(Equal("500"))
+	local, _ = os.ReadFile(localPath)
+	g.Expect(string(local)).To(Equal(data))
+
+	// without chunk manifest, nothing verifies the bytes downloaded before, so that downloads start over
+	client.Downloads = nil
+	client.DownloadInterrupts = []int64{500}
+	_, err = DownloadResumable(client, localPath, metadata, 1, nil)
+	g.Expect(err).NotTo(BeNil())
+	overwritten := strings.Repeat("9876543210", 100)
+	client.PutFile("default", metadata.Filename, []byte(overwritten))
+	client.DownloadInterrupts = []int64{300}
+	size, err = DownloadResumable(client, localPath, metadata, 2, nil)
+	g.Expect(err).To(BeNil())
+	g.Expect(size).To(BeEquivalentTo(1000))
+	g.Expect(client.Downloads).To(HaveLen(3))
+	g.Expect(client.Downloads[1].RangeOffset).To(Equal(""))
+	g.Expect(client.Downloads[2].RangeOffset).To(Equal(""))
+	local, _ = os.ReadFile(localPath)
+	g.Expect(string(local)).To(Equal(overwritten))
+	client.PutFile("default", metadata.Filename, []byte(data))
+
+	// mismatched chunks are dropped and downloaded again by the next call
+	client.Downloads = nil
+	corrupted := data[:600] + "x" + data[601:]
+	client.PutFile("default", metadata.Filename, []byte(corrupted))
+	_, err = DownloadResumable(client, localPath, metadata, 1, newChunkManifest(data, 256))
+	g.Expect(err).NotTo(BeNil())
+	local, _ = os.ReadFile(localPath)
+	g.Expect(string(local)).To(Equal(data[:512]))
+	client.PutFile("default", metadata.Filename, []byte(data))
+	_, err = DownloadResumable(client, localPath, metadata, 1, newChunkManifest(data, 256))
+	g.Expect(err).To(BeNil())
+	g.Expect(client.Downloads[1].RangeOffset).To(Equal("512"))
+	local, _ = os.ReadFile(localPath)
+	g.Expect(string(local)).To(Equal(data))
+}
+
+func TestDownloadResumableSplit(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resumeBackoff = 0
+	client := NewFakeFilestreamClient()
+	metadata := ActionMetadata{Sink: "default", Filename: "backup/fullbackup/dn-0.xbstream"}
+	data := strings.Repeat("0123456789", 25)
+	_, err := UploadSplit(client, strings.NewReader(data), metadata, 100)
+	g.Expect(err).To(BeNil())
+	localPath := filepath.Join(t.TempDir(), "dn-0.xbstream")
+
+	// manifest, part 0 broken, part 0 from 50, part 1 broken, part 1 from 130 and part 2
+	client.DownloadInterrupts = []int64{-1, 50, -1, 30}
+	size, err := DownloadResumable(client, localPath, metadata, 3, newChunkManifest(data, 64))
+	g.Expect(err).To(BeNil())
+	g.Expect(size).To(BeEquivalentTo(250))
+	local, _ := os.ReadFile(localPath)
+	g.Expect(string(local)).To(Equal(data))
+	g.Expect(client.Downloads).To(HaveLen(6))
+	g.Expect(client.Downloads[2].Filename).To(Equal(metadata.Filename + ".part-00000"))
+	g.Expect(client.Downloads[2].RangeOffset).To(Equal("50"))
+	g.Expect(client.Downloads[4].Filename).To(Equal(metadata.Filename + ".part-00001"))
+	g.Expect(client.Downloads[4].RangeOffset).To(Equal("30"))
+	g.Expect(client.Downloads[5].RangeOffset).To(Equal(""))
+}
@@ -37,6 +37,7 @@ import (
 	"golang.org/x/crypto/ssh"
 	"io"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -58,6 +59,8 @@ const (
 
 var OssParams = map[string]string{"write_len": "true"}
 var minioParams = map[string]string{"write_len": "true"}
+var azureParams = map[string]string{"write_len": "true"}
+var gcsParams = map[string]string{"write_len": "true"}
 
 var TaskMap = sync.Map{}
 
@@ -175,6 +178,22 @@ func (f *FileServer) handleRequest(conn net.Conn) error {
 		f.processDownloadMinio(logger, metadata, conn)
 	case strings.ToLower(string(ListMinio)):
 		f.processListMinio(logger, metadata, conn)
+	case strings.ToLower(string(UploadAzure)):
+		f.markTask(logger, metadata, TaskStateDoing)
+		err := f.processUploadAzure(logger, metadata, conn)
+		f.processTaskResult(logger, err, metadata)
+	case strings.ToLower(string(DownloadAzure)):
+		f.processDownloadAzure(logger, metadata, conn)
+	case strings.ToLower(string(ListAzure)):
+		f.processListAzure(logger, metadata, conn)
+	case strings.ToLower(string(UploadGcs)):
+		f.markTask(logger, metadata, TaskStateDoing)
+		err := f.processUploadGcs(logger, metadata, conn)
+		f.processTaskResult(logger, err, metadata)
+	case strings.ToLower(string(DownloadGcs)):
+		f.processDownloadGcs(logger, metadata, conn)
+	case strings.ToLower(string(ListGcs)):
+		f.processListGcs(logger, metadata, conn)
 	case strings.ToLower(string(CheckTask)):
 		f.processCheckTask(logger, metadata, conn)
 	default:
@@ -333,7 +352,11 @@ func (f *FileServer) processDownloadSsh(logger logr.Logger, metadata ActionMetad
 		logger.Error(err, "Failed to stat file")
 		return err
 	}
-	size := fileInfo.Size()
+	size, err := seekRangeOffset(fd, fileInfo.Size(), metadata)
+	if err != nil {
+		logger.Error(err, "Failed to seek file")
+		return err
+	}
 	sizeBytes := make([]byte, 8)
 	binary.BigEndian.PutUint64(sizeBytes, uint64(size))
 	writer.Write(sizeBytes[:])
@@ -418,21 +441,165 @@ func sftpRemove(client *sftp.Client, file string) error {
 	return nil
 }
 
-func getOssAuth(sink Sink) map[string]string {
-	return map[string]string{
+// ossAccelerateRecheckInterval is the interval to check again the transfer acceleration of bucket found
+// unavailable.
+const ossAccelerateRecheckInterval = 10 * time.Minute
+
+// ossAccelerateUnavailable records when transfer acceleration is found unavailable, keyed by endpoint and bucket.
+var ossAccelerateUnavailable sync.Map
+
+// checkOssAccelerate checks that transfer acceleration is available for bucket of sink via endpoint, the result
+// of available is cached while unavailable is checked again after ossAccelerateRecheckInterval.
+func checkOssAccelerate(sink Sink, endpoint string) error {
+	key := endpoint + "/" + sink.Bucket
+	if val, ok := ossAccelerateUnavailable.Load(key); ok {
+		if val == nil {
+			return nil
+		}
+		if unavailable := val.(time.Time); time.Since(unavailable) < ossAccelerateRecheckInterval {
+			return errors.New("transfer acceleration found unavailable at " + unavailable.Format(time.RFC3339))
+		}
+	}
+	sink.Endpoint = endpoint
+	auth, err := getOssAuth(sink)
+	if err != nil {
+		return err
+	}
+	if err := remote.CheckAliyunOssEndpoint(auth, sink.Bucket); err != nil {
+		ossAccelerateUnavailable.Store(key, time.Now())
+		return err
+	}
+	ossAccelerateUnavailable.Store(key, nil)
+	return nil
+}
+
+// getOssEndpoint returns the endpoint of sink of endpoint type selected by client. It falls back to the default
+// endpoint if the endpoint type is not configured, or transfer acceleration is unavailable for the bucket.
+func getOssEndpoint(logger logr.Logger, sink Sink, endpointType string) string {
+	if endpointType == "" {
+		return sink.Endpoint
+	}
+	endpoint, ok := sink.EndpointOf(endpointType)
+	if !ok {
+		logger.Info("endpoint not configured in sink, fall back to default endpoint", "sinkName", sink.Name,
+			"endpointType", endpointType)
+		return endpoint
+	}
+	if endpointType == EndpointTypeAccelerate {
+		if err := checkOssAccelerate(sink, endpoint); err != nil {
+			logger.Error(err, "transfer acceleration unavailable for bucket, fall back to default endpoint",
+				"sinkName", sink.Name, "bucket", sink.Bucket, "endpoint", endpoint)
+			return sink.Endpoint
+		}
+	}
+	return endpoint
+}
+
+// proxyDialTimeout is the timeout to connect to proxy when checking it is reachable.
+const proxyDialTimeout = 5 * time.Second
+
+// checkProxyReachable checks that the proxy accepts connections, so that failures of unreachable proxy are
+// told apart from the ones of storage.
+func checkProxyReachable(proxyURL *url.URL) error {
+	port := proxyURL.Port()
+	if port == "" {
+		port = "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(proxyURL.Hostname(), port), proxyDialTimeout)
+	if err != nil {
+		return fmt.Errorf("proxy %s unreachable: %w", proxyURL.Host, err)
+	}
+	return conn.Close()
+}
+
+// setProxyAuth passes the proxy to reach storage of sink to auth of file service, nothing is passed if the
+// storage is not proxied.
+func setProxyAuth(auth map[string]string, sink Sink) error {
+	proxyURL, err := sink.Proxy()
+	if err != nil {
+		return fmt.Errorf("failed to get proxy of sink %s: %w", sink.Name, err)
+	}
+	if proxyURL == nil {
+		return nil
+	}
+	if err := checkProxyReachable(proxyURL); err != nil {
+		return err
+	}
+	auth["proxy"] = proxyURL.String()
+	return nil
+}
+
+func getOssAuth(sink Sink) (map[string]string, error) {
+	auth := map[string]string{
 		"endpoint":      sink.Endpoint,
 		"access_key":    sink.AccessKey,
 		"access_secret": sink.AccessSecret,
 	}
+	return auth, setProxyAuth(auth, sink)
 }
 
-func getMinioAuth(sink Sink) map[string]string {
-	return map[string]string{
+func getMinioAuth(sink Sink) (map[string]string, error) {
+	auth := map[string]string{
 		"endpoint":   sink.Endpoint,
 		"access_key": sink.AccessKey,
 		"secret_key": sink.AccessSecret,
 		"useSSL":     strconv.FormatBool(sink.UseSSL),
 	}
+	return auth, setProxyAuth(auth, sink)
+}
+
+// setUploadConcurrencyParams passes the concurrency of upload required by client to params of file service,
+// which only works for file services supporting multipart upload.
+func setUploadConcurrencyParams(params map[string]string, metadata ActionMetadata) {
+	if metadata.UploadConcurrency != "" {
+		params["upload_concurrency"] = metadata.UploadConcurrency
+	}
+	if metadata.UploadPartSize != "" {
+		params["upload_part_size"] = metadata.UploadPartSize
+	}
+}
+
+// setRangeParams passes the offset of range download required by client to params of file service.
+func setRangeParams(params map[string]string, metadata ActionMetadata) {
+	if metadata.RangeOffset != "" {
+		params["range_offset"] = metadata.RangeOffset
+	}
+}
+
+// seekRangeOffset seeks the file of size to the offset of range download required by client, and returns the
+// size of the rest.
+func seekRangeOffset(fd io.Seeker, size int64, metadata ActionMetadata) (int64, error) {
+	if metadata.RangeOffset == "" {
+		return size, nil
+	}
+	offset, err := strconv.ParseInt(metadata.RangeOffset, 10, 64)
+	if err != nil || offset < 0 || offset > size {
+		return 0, fmt.Errorf("invalid range offset %s of file size %d", metadata.RangeOffset, size)
+	}
+	if _, err := fd.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size - offset, nil
+}
+
+// setObjectTagsParams passes the object tags required by client to params of file service, which only works
+// for file services supporting object tagging.
+func setObjectTagsParams(params map[string]string, metadata ActionMetadata) {
+	if metadata.Tags != "" {
+		params["tags"] = metadata.Tags
+	}
+}
+
+// setServerSideEncryptionParams passes the server-side encryption required by client to params of file service,
+// which only works for file services supporting server-side encryption.
+func setServerSideEncryptionParams(params map[string]string, metadata ActionMetadata) {
+	if metadata.SSEAlgorithm != "" {
+		params["sse_algorithm"] = metadata.SSEAlgorithm
+		params["sse_kms_key_id"] = metadata.SSEKMSKeyId
+	}
 }
 
 func (f *FileServer) processUploadOss(logger logr.Logger, metadata ActionMetadata, conn net.Conn) error {
@@ -476,7 +643,15 @@ func (f *FileServer) processUploadOss(logger logr.Logger, metadata ActionMetadat
 		nowOssParams["limit_reader_size"] = metadata.OssBufferSize
 	}
 	nowOssParams["bucket"] = sink.Bucket
-	ossAuth := getOssAuth(*sink)
+	setUploadConcurrencyParams(nowOssParams, metadata)
+	setObjectTagsParams(nowOssParams, metadata)
+	setServerSideEncryptionParams(nowOssParams, metadata)
+	sink.Endpoint = getOssEndpoint(logger, *sink, metadata.EndpointType)
+	ossAuth, err := getOssAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
 	ft, err := fileService.UploadFile(ctx, reader, metadata.Filepath, ossAuth, nowOssParams)
 	if err != nil {
 		logger.Error(err, "Failed to upload file to oss")
@@ -536,7 +711,13 @@ func (f *FileServer) processDownloadOss(logger logr.Logger, metadata ActionMetad
 	ctx := context.Background()
 	nowOssParams := polarxMap.MergeMap(map[string]string{}, OssParams, false).(map[string]string)
 	nowOssParams["bucket"] = sink.Bucket
-	ossAuth := getOssAuth(*sink)
+	setRangeParams(nowOssParams, metadata)
+	sink.Endpoint = getOssEndpoint(logger, *sink, metadata.EndpointType)
+	ossAuth, err := getOssAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
 	ft, err := fileService.DownloadFile(ctx, writer, metadata.Filepath, ossAuth, nowOssParams)
 	if err != nil {
 		logger.Error(err, "Failed to download file from oss ")
@@ -585,7 +766,12 @@ func (f *FileServer) processListOss(logger logr.Logger, metadata ActionMetadata,
 	ctx := context.Background()
 	nowOssParams := polarxMap.MergeMap(map[string]string{}, OssParams, false).(map[string]string)
 	nowOssParams["bucket"] = sink.Bucket
-	ossAuth := getOssAuth(*sink)
+	sink.Endpoint = getOssEndpoint(logger, *sink, metadata.EndpointType)
+	ossAuth, err := getOssAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
 	ft, err := fileService.ListFiles(ctx, writer, metadata.Filepath, ossAuth, nowOssParams)
 	if err != nil {
 		logger.Error(err, "Failed to list file from oss ")
@@ -642,8 +828,15 @@ func (f *FileServer) processUploadMinio(logger logr.Logger, metadata ActionMetad
 	newMinioParams["single_part_max_size"] = strconv.FormatInt(sink.UploadPartMaxSize, 10)
 	newMinioParams["bucket"] = sink.Bucket
 	newMinioParams["bucket_lookup_type"] = sink.BucketLookupType
+	setUploadConcurrencyParams(newMinioParams, metadata)
+	setObjectTagsParams(newMinioParams, metadata)
+	setServerSideEncryptionParams(newMinioParams, metadata)
 
-	minioAuth := getMinioAuth(*sink)
+	minioAuth, err := getMinioAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
 	ft, err := fileService.UploadFile(ctx, reader, metadata.Filepath, minioAuth, newMinioParams)
 	if err != nil {
 		logger.Error(err, "Failed to upload file to minio")
@@ -704,8 +897,13 @@ func (f *FileServer) processDownloadMinio(logger logr.Logger, metadata ActionMet
 	newMinioParams := polarxMap.MergeMap(map[string]string{}, minioParams, false).(map[string]string)
 	newMinioParams["bucket"] = sink.Bucket
 	newMinioParams["bucket_lookup_type"] = sink.BucketLookupType
+	setRangeParams(newMinioParams, metadata)
 
-	minioAuth := getMinioAuth(*sink)
+	minioAuth, err := getMinioAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
 	ft, err := fileService.DownloadFile(ctx, writer, metadata.Filepath, minioAuth, newMinioParams)
 	if err != nil {
 		logger.Error(err, "Failed to download file from minio ")
@@ -756,7 +954,11 @@ func (f *FileServer) processListMinio(logger logr.Logger, metadata ActionMetadat
 	minioParams["bucket"] = sink.Bucket
 	minioParams["bucket_lookup_type"] = sink.BucketLookupType
 
-	minioAuth := getMinioAuth(*sink)
+	minioAuth, err := getMinioAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
 	ft, err := fileService.ListFiles(ctx, writer, metadata.Filepath, minioAuth, minioParams)
 	if err != nil {
 		logger.Error(err, "Failed to list file from oss ")
@@ -772,6 +974,377 @@ func (f *FileServer) processListMinio(logger logr.Logger, metadata ActionMetadat
 	return nil
 }
 
+func getAzureAuth(sink Sink) (map[string]string, error) {
+	auth := map[string]string{
+		"endpoint":    sink.Endpoint,
+		"account":     sink.Account,
+		"account_key": sink.AccountKey,
+		"auth_mode":   sink.AuthMode,
+		"client_id":   sink.ClientId,
+		"tenant_id":   sink.TenantId,
+	}
+	return auth, setProxyAuth(auth, sink)
+}
+
+func (f *FileServer) processUploadAzure(logger logr.Logger, metadata ActionMetadata, conn net.Conn) error {
+	sink, err := GetSink(metadata.Sink, SinkTypeAzure)
+	if err != nil {
+		logger.Error(err, "fail to get sink", "sinkName", metadata.Sink)
+		return err
+	}
+	fileService, err := remote.GetFileService("azure")
+	if err != nil {
+		logger.Error(err, "Failed to get file service of azure blob")
+		return err
+	}
+	if metadata.Filepath == "" {
+		filepath := filepath.Join(metadata.InstanceId, metadata.Filename)
+		metadata.Filepath = filepath
+	}
+	reader, writer := io.Pipe()
+	defer func() {
+		reader.Close()
+		writer.Close()
+	}()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer func() {
+			writer.Close()
+			wg.Done()
+		}()
+		len, _ := f.flowControl.LimitFlow(conn, writer, conn)
+		logger.Info("limitFlow", "len", len)
+	}()
+	ctx := context.Background()
+	newAzureParams := polarxMap.MergeMap(map[string]string{}, azureParams, false).(map[string]string)
+	newAzureParams["container"] = sink.Container
+	newAzureParams["block_size"] = strconv.FormatInt(sink.BlockSize, 10)
+	setUploadConcurrencyParams(newAzureParams, metadata)
+
+	azureAuth, err := getAzureAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
+	ft, err := fileService.UploadFile(ctx, reader, metadata.Filepath, azureAuth, newAzureParams)
+	if err != nil {
+		logger.Error(err, "Failed to upload file to azure blob")
+		return err
+	}
+	err = ft.Wait()
+	reader.Close()
+	if err != nil {
+		logger.Error(err, "Failed to upload file to azure blob after wait")
+		return err
+	}
+	wg.Wait()
+	return nil
+}
+
+func (f *FileServer) processDownloadAzure(logger logr.Logger, metadata ActionMetadata, conn net.Conn) error {
+	sink, err := GetSink(metadata.Sink, SinkTypeAzure)
+	if err != nil {
+		logger.Error(err, "fail to get sink", "sinkName", metadata.Sink)
+		return err
+	}
+	fileService, err := remote.GetFileService("azure")
+	if err != nil {
+		logger.Error(err, "Failed to get file service of azure blob")
+		return err
+	}
+	if metadata.Filepath == "" {
+		filepath := filepath.Join(metadata.InstanceId, metadata.Filename)
+		metadata.Filepath = filepath
+	}
+	pReader, writer := io.Pipe()
+	defer func() {
+		writer.Close()
+		pReader.Close()
+	}()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer func() {
+			err := recover()
+			if err != nil {
+				logger.Info("panic", "err", err)
+			}
+		}()
+		defer func() {
+			pReader.Close()
+			wg.Done()
+		}()
+		var reader io.Reader = pReader
+		if metadata.LimitSize != "" {
+			limitSize, _ := strconv.ParseInt(metadata.LimitSize, 10, 64)
+			reader = io.LimitReader(reader, limitSize)
+		}
+		len, _ := f.flowControl.LimitFlow(reader, conn, nil)
+		logger.Info("limitFlow", "len", len)
+	}()
+	ctx := context.Background()
+	newAzureParams := polarxMap.MergeMap(map[string]string{}, azureParams, false).(map[string]string)
+	newAzureParams["container"] = sink.Container
+	setRangeParams(newAzureParams, metadata)
+
+	azureAuth, err := getAzureAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
+	ft, err := fileService.DownloadFile(ctx, writer, metadata.Filepath, azureAuth, newAzureParams)
+	if err != nil {
+		logger.Error(err, "Failed to download file from azure blob")
+		return err
+	}
+	err = ft.Wait()
+	writer.Close()
+	if err != nil {
+		logger.Error(err, "Failed to download file from azure blob")
+		return err
+	}
+	wg.Wait()
+	return nil
+}
+
+func (f *FileServer) processListAzure(logger logr.Logger, metadata ActionMetadata, conn net.Conn) error {
+	sink, err := GetSink(metadata.Sink, SinkTypeAzure)
+	if err != nil {
+		logger.Error(err, "fail to get sink", "sinkName", metadata.Sink)
+		return err
+	}
+	fileService, err := remote.GetFileService("azure")
+	if err != nil {
+		logger.Error(err, "Failed to get file service of azure blob")
+		return err
+	}
+	if metadata.Filepath != "" && metadata.Filepath[len(metadata.Filepath)-1] != '/' {
+		metadata.Filepath = polarxPath.NewPathFromStringSequence(metadata.Filepath, "")
+	}
+	logger.Info("filepath to be listed: " + metadata.Filepath)
+	reader, writer := io.Pipe()
+	defer func() {
+		writer.Close()
+		reader.Close()
+	}()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer func() {
+			reader.Close()
+			wg.Done()
+		}()
+		len, _ := f.flowControl.LimitFlow(reader, conn, nil)
+		logger.Info("limitFlow", "len", len)
+	}()
+	ctx := context.Background()
+	newAzureParams := polarxMap.MergeMap(map[string]string{}, azureParams, false).(map[string]string)
+	newAzureParams["container"] = sink.Container
+
+	azureAuth, err := getAzureAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
+	ft, err := fileService.ListFiles(ctx, writer, metadata.Filepath, azureAuth, newAzureParams)
+	if err != nil {
+		logger.Error(err, "Failed to list file from azure blob")
+		return err
+	}
+	err = ft.Wait()
+	writer.Close()
+	if err != nil {
+		logger.Error(err, "Failed to list file from azure blob")
+		return err
+	}
+	wg.Wait()
+	return nil
+}
+
+func getGcsAuth(sink Sink) (map[string]string, error) {
+	auth := map[string]string{
+		"endpoint":         sink.Endpoint,
+		"credentials_file": sink.CredentialsFile,
+	}
+	return auth, setProxyAuth(auth, sink)
+}
+
+func (f *FileServer) processUploadGcs(logger logr.Logger, metadata ActionMetadata, conn net.Conn) error {
+	sink, err := GetSink(metadata.Sink, SinkTypeGcs)
+	if err != nil {
+		logger.Error(err, "fail to get sink", "sinkName", metadata.Sink)
+		return err
+	}
+	fileService, err := remote.GetFileService("gcs")
+	if err != nil {
+		logger.Error(err, "Failed to get file service of gcs")
+		return err
+	}
+	if metadata.Filepath == "" {
+		filepath := filepath.Join(metadata.InstanceId, metadata.Filename)
+		metadata.Filepath = filepath
+	}
+	reader, writer := io.Pipe()
+	defer func() {
+		reader.Close()
+		writer.Close()
+	}()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer func() {
+			writer.Close()
+			wg.Done()
+		}()
+		len, _ := f.flowControl.LimitFlow(conn, writer, conn)
+		logger.Info("limitFlow", "len", len)
+	}()
+	ctx := context.Background()
+	newGcsParams := polarxMap.MergeMap(map[string]string{}, gcsParams, false).(map[string]string)
+	newGcsParams["bucket"] = sink.Bucket
+	newGcsParams["chunk_size"] = strconv.FormatInt(sink.ChunkSize, 10)
+
+	gcsAuth, err := getGcsAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
+	ft, err := fileService.UploadFile(ctx, reader, metadata.Filepath, gcsAuth, newGcsParams)
+	if err != nil {
+		logger.Error(err, "Failed to upload file to gcs")
+		return err
+	}
+	err = ft.Wait()
+	reader.Close()
+	if err != nil {
+		logger.Error(err, "Failed to upload file to gcs after wait")
+		return err
+	}
+	wg.Wait()
+	return nil
+}
+
+func (f *FileServer) processDownloadGcs(logger logr.Logger, metadata ActionMetadata, conn net.Conn) error {
+	sink, err := GetSink(metadata.Sink, SinkTypeGcs)
+	if err != nil {
+		logger.Error(err, "fail to get sink", "sinkName", metadata.Sink)
+		return err
+	}
+	fileService, err := remote.GetFileService("gcs")
+	if err != nil {
+		logger.Error(err, "Failed to get file service of gcs")
+		return err
+	}
+	if metadata.Filepath == "" {
+		filepath := filepath.Join(metadata.InstanceId, metadata.Filename)
+		metadata.Filepath = filepath
+	}
+	pReader, writer := io.Pipe()
+	defer func() {
+		writer.Close()
+		pReader.Close()
+	}()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer func() {
+			err := recover()
+			if err != nil {
+				logger.Info("panic", "err", err)
+			}
+		}()
+		defer func() {
+			pReader.Close()
+			wg.Done()
+		}()
+		var reader io.Reader = pReader
+		if metadata.LimitSize != "" {
+			limitSize, _ := strconv.ParseInt(metadata.LimitSize, 10, 64)
+			reader = io.LimitReader(reader, limitSize)
+		}
+		len, _ := f.flowControl.LimitFlow(reader, conn, nil)
+		logger.Info("limitFlow", "len", len)
+	}()
+	ctx := context.Background()
+	newGcsParams := polarxMap.MergeMap(map[string]string{}, gcsParams, false).(map[string]string)
+	newGcsParams["bucket"] = sink.Bucket
+	setRangeParams(newGcsParams, metadata)
+
+	gcsAuth, err := getGcsAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
+	ft, err := fileService.DownloadFile(ctx, writer, metadata.Filepath, gcsAuth, newGcsParams)
+	if err != nil {
+		logger.Error(err, "Failed to download file from gcs")
+		return err
+	}
+	err = ft.Wait()
+	writer.Close()
+	if err != nil {
+		logger.Error(err, "Failed to download file from gcs")
+		return err
+	}
+	wg.Wait()
+	return nil
+}
+
+func (f *FileServer) processListGcs(logger logr.Logger, metadata ActionMetadata, conn net.Conn) error {
+	sink, err := GetSink(metadata.Sink, SinkTypeGcs)
+	if err != nil {
+		logger.Error(err, "fail to get sink", "sinkName", metadata.Sink)
+		return err
+	}
+	fileService, err := remote.GetFileService("gcs")
+	if err != nil {
+		logger.Error(err, "Failed to get file service of gcs")
+		return err
+	}
+	if metadata.Filepath != "" && metadata.Filepath[len(metadata.Filepath)-1] != '/' {
+		metadata.Filepath = polarxPath.NewPathFromStringSequence(metadata.Filepath, "")
+	}
+	logger.Info("filepath to be listed: " + metadata.Filepath)
+	reader, writer := io.Pipe()
+	defer func() {
+		writer.Close()
+		reader.Close()
+	}()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer func() {
+			reader.Close()
+			wg.Done()
+		}()
+		len, _ := f.flowControl.LimitFlow(reader, conn, nil)
+		logger.Info("limitFlow", "len", len)
+	}()
+	ctx := context.Background()
+	newGcsParams := polarxMap.MergeMap(map[string]string{}, gcsParams, false).(map[string]string)
+	newGcsParams["bucket"] = sink.Bucket
+
+	gcsAuth, err := getGcsAuth(*sink)
+	if err != nil {
+		logger.Error(err, "Failed to get proxy of sink", "sinkName", sink.Name)
+		return err
+	}
+	ft, err := fileService.ListFiles(ctx, writer, metadata.Filepath, gcsAuth, newGcsParams)
+	if err != nil {
+		logger.Error(err, "Failed to list file from gcs")
+		return err
+	}
+	err = ft.Wait()
+	writer.Close()
+	if err != nil {
+		logger.Error(err, "Failed to list file from gcs")
+		return err
+	}
+	wg.Wait()
+	return nil
+}
+
 func (f *FileServer) processUploadRemote(logger logr.Logger, metadata ActionMetadata, conn net.Conn) error {
 	host, port := f.parseNetAddr(metadata.RedirectAddr)
 	fileClient := NewFileClient(host, port, f.flowControl)
@@ -992,7 +1565,11 @@ func (f *FileServer) processDownloadLocal(logger logr.Logger, metadata ActionMet
 		logger.Error(err, "Failed to stat file")
 		return err
 	}
-	size := fileInfo.Size()
+	size, err := seekRangeOffset(fd, fileInfo.Size(), metadata)
+	if err != nil {
+		logger.Error(err, "Failed to seek file")
+		return err
+	}
 	sizeBytes := make([]byte, 8)
 	binary.BigEndian.PutUint64(sizeBytes, uint64(size))
 	writer.Write(sizeBytes[:])
@@ -1065,23 +1642,37 @@ func (f *FileServer) readMetadata(reader io.Reader) (actionMeta ActionMetadata,
 		return
 	}
 	metadata := strings.Split(string(bytes), ",")
+	// metadata from legacy client has no fields of concurrent upload, object tags, server-side encryption,
+	// endpoint selection or range download
+	if len(metadata) == LegacyMetaFiledLen || len(metadata) == UntaggedMetaFiledLen ||
+		len(metadata) == UnencryptedMetaFiledLen || len(metadata) == DefaultEndpointMetaFiledLen ||
+		len(metadata) == UnrangedMetaFiledLen {
+		metadata = append(metadata, make([]string, MetaFiledLen-len(metadata))...)
+	}
 	if len(metadata) != MetaFiledLen {
 		err = errors.New("invalid metadata")
 		return
 	}
 	actionMeta = ActionMetadata{
-		Action:          Action(metadata[MetadataActionOffset]),
-		InstanceId:      metadata[MetadataInstanceIdOffset],
-		Filename:        metadata[MetadataFilenameOffset],
-		RedirectAddr:    metadata[MetadataRedirectOffset],
-		Filepath:        metadata[MetadataFilepathOffset],
-		RetentionTime:   metadata[MetadataRetentionTimeOffset],
-		Stream:          metadata[MetadataStreamOffset],
-		Sink:            metadata[MetadataSinkOffset],
-		RequestId:       metadata[MetadataRequestIdOffset],
-		OssBufferSize:   metadata[MetadataOssBufferSizeOffset],
-		LimitSize:       metadata[MetadataLimitSize],
-		MinioBufferSize: metadata[MetadataMinioBufferSizeOffset],
+		Action:            Action(metadata[MetadataActionOffset]),
+		InstanceId:        metadata[MetadataInstanceIdOffset],
+		Filename:          metadata[MetadataFilenameOffset],
+		RedirectAddr:      metadata[MetadataRedirectOffset],
+		Filepath:          metadata[MetadataFilepathOffset],
+		RetentionTime:     metadata[MetadataRetentionTimeOffset],
+		Stream:            metadata[MetadataStreamOffset],
+		Sink:              metadata[MetadataSinkOffset],
+		RequestId:         metadata[MetadataRequestIdOffset],
+		OssBufferSize:     metadata[MetadataOssBufferSizeOffset],
+		LimitSize:         metadata[MetadataLimitSize],
+		MinioBufferSize:   metadata[MetadataMinioBufferSizeOffset],
+		UploadConcurrency: metadata[MetadataUploadConcurrency],
+		UploadPartSize:    metadata[MetadataUploadPartSize],
+		Tags:              metadata[MetadataTags],
+		SSEAlgorithm:      metadata[MetadataSSEAlgorithm],
+		SSEKMSKeyId:       metadata[MetadataSSEKMSKeyId],
+		EndpointType:      metadata[MetadataEndpointType],
+		RangeOffset:       metadata[MetadataRangeOffset],
 	}
 	return
 }
@@ -0,0 +1,211 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filestream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A stream uploaded with max object size is split into numbered part objects named by appending SplitPartSuffix
+// to the object name, and the object itself is replaced by a manifest beginning with SplitManifestMagic. As
+// xbstream begins with its chunk magic, the manifest is told apart from ordinary objects on download.
+const (
+	SplitManifestMagic  = "PXSPLIT1\n"
+	SplitPartPrefix     = ".part-"
+	SplitPartSuffix     = SplitPartPrefix + "%05d"
+	MinMaxObjectSize    = 1 << 26 // 64MB
+	maxSplitManifestLen = 1 << 20
+)
+
+// MaxObjectSizeLimits records the max size of a single object of storages, max object size must not exceed it.
+var MaxObjectSizeLimits = map[Action]int64{
+	UploadOss:   48800 << 30,        // 48.8TB
+	UploadMinio: 5 << 40,            // 5TB
+	UploadAzure: 50000 * 4000 << 20, // 50000 blocks of 4000MB
+	UploadGcs:   5 << 40,            // 5TB
+}
+
+// ValidateMaxObjectSize checks that the max object size is neither too small to split into reasonable count of
+// parts, nor larger than the max object size of the storage of upload action.
+func ValidateMaxObjectSize(action Action, maxObjectSize int64) error {
+	if maxObjectSize < MinMaxObjectSize {
+		return fmt.Errorf("max object size %d is less than %d", maxObjectSize, MinMaxObjectSize)
+	}
+	if limit, ok := MaxObjectSizeLimits[action]; ok && maxObjectSize > limit {
+		return fmt.Errorf("max object size %d exceeds the limit %d of action %s", maxObjectSize, limit, action)
+	}
+	return nil
+}
+
+// SplitManifest lists the sizes of part objects of a split stream in order.
+type SplitManifest struct {
+	Size      int64   `json:"size"`
+	PartSizes []int64 `json:"partSizes"`
+}
+
+// splitPartMetadata returns the action metadata of the i-th part object.
+func splitPartMetadata(actionMetadata ActionMetadata, i int) ActionMetadata {
+	suffix := fmt.Sprintf(SplitPartSuffix, i)
+	if actionMetadata.Filename != "" {
+		actionMetadata.Filename += suffix
+	}
+	if actionMetadata.Filepath != "" {
+		actionMetadata.Filepath += suffix
+	}
+	if actionMetadata.RequestId != "" {
+		actionMetadata.RequestId += suffix
+	}
+	// size hints are of the whole stream
+	actionMetadata.OssBufferSize = ""
+	actionMetadata.MinioBufferSize = ""
+	return actionMetadata
+}
+
+// UploadSplit uploads the stream as part objects no larger than maxObjectSize, and then the manifest of them as
+// the object. Each upload is checked before the next begins. The size of the whole stream is returned.
+func UploadSplit(c Client, reader io.Reader, actionMetadata ActionMetadata, maxObjectSize int64) (int64, error) {
+	if maxObjectSize <= 0 {
+		return 0, errors.New("max object size must be positive")
+	}
+	bufReader := bufio.NewReader(reader)
+	manifest := SplitManifest{PartSizes: make([]int64, 0)}
+	for i := 0; ; i++ {
+		// upload an empty part for empty stream, so that the object always has parts
+		if _, err := bufReader.Peek(1); err == io.EOF && i > 0 {
+			break
+		} else if err != nil && err != io.EOF {
+			return manifest.Size, err
+		}
+		partMetadata := splitPartMetadata(actionMetadata, i)
+		written, err := c.Upload(io.LimitReader(bufReader, maxObjectSize), partMetadata)
+		if err != nil {
+			return manifest.Size, fmt.Errorf("failed to upload part %d: %w", i, err)
+		}
+		if err := c.Check(partMetadata); err != nil {
+			return manifest.Size, fmt.Errorf("failed to upload part %d: %w", i, err)
+		}
+		manifest.Size += written
+		manifest.PartSizes = append(manifest.PartSizes, written)
+	}
+
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return manifest.Size, err
+	}
+	manifestReader := io.MultiReader(bytes.NewReader([]byte(SplitManifestMagic)), bytes.NewReader(manifestBytes))
+	if _, err := c.Upload(manifestReader, actionMetadata); err != nil {
+		return manifest.Size, fmt.Errorf("failed to upload split manifest: %w", err)
+	}
+	if err := c.Check(actionMetadata); err != nil {
+		return manifest.Size, fmt.Errorf("failed to upload split manifest: %w", err)
+	}
+	return manifest.Size, nil
+}
+
+// splitManifestDetector passes the downloaded object through to writer, unless it begins with the manifest magic,
+// in which case the manifest is kept instead.
+type splitManifestDetector struct {
+	writer   io.Writer
+	head     []byte
+	decided  bool
+	manifest *bytes.Buffer
+	written  int64
+}
+
+func (d *splitManifestDetector) Write(p []byte) (int, error) {
+	if !d.decided {
+		n := len(SplitManifestMagic) - len(d.head)
+		if n > len(p) {
+			n = len(p)
+		}
+		d.head = append(d.head, p[:n]...)
+		if !bytes.HasPrefix([]byte(SplitManifestMagic), d.head) {
+			if err := d.flushHead(); err != nil {
+				return 0, err
+			}
+		} else if len(d.head) == len(SplitManifestMagic) {
+			d.decided = true
+			d.manifest = &bytes.Buffer{}
+		}
+		if !d.decided {
+			return len(p), nil
+		}
+		if _, err := d.Write(p[n:]); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if d.manifest != nil {
+		if d.manifest.Len()+len(p) > maxSplitManifestLen {
+			return 0, errors.New("split manifest too large")
+		}
+		return d.manifest.Write(p)
+	}
+	n, err := d.writer.Write(p)
+	d.written += int64(n)
+	return n, err
+}
+
+// flushHead passes the bytes held for detection through, and the rest of object is passed through as well.
+func (d *splitManifestDetector) flushHead() error {
+	d.decided = true
+	n, err := d.writer.Write(d.head)
+	d.written += int64(n)
+	return err
+}
+
+// DownloadSplit downloads the object into writer, reassembling the part objects in order if the object is
+// the manifest of split stream, otherwise the object is downloaded as is. Bytes written are returned.
+func DownloadSplit(c Client, writer io.Writer, actionMetadata ActionMetadata) (int64, error) {
+	detector := &splitManifestDetector{writer: writer}
+	if _, err := c.Download(detector, actionMetadata); err != nil {
+		return detector.written, err
+	}
+	if !detector.decided {
+		// object shorter than the magic
+		err := detector.flushHead()
+		return detector.written, err
+	}
+	if detector.manifest == nil {
+		return detector.written, nil
+	}
+
+	manifest := SplitManifest{}
+	if err := json.Unmarshal(detector.manifest.Bytes(), &manifest); err != nil {
+		return 0, fmt.Errorf("failed to parse split manifest: %w", err)
+	}
+	var written int64
+	for i, partSize := range manifest.PartSizes {
+		n, err := c.Download(writer, splitPartMetadata(actionMetadata, i))
+		written += n
+		if err != nil {
+			return written, fmt.Errorf("failed to download part %d: %w", i, err)
+		}
+		if n != partSize {
+			return written, fmt.Errorf("size of part %d is %d, expect %d", i, n, partSize)
+		}
+	}
+	if written != manifest.Size {
+		return written, fmt.Errorf("size of split object is %d, expect %d", written, manifest.Size)
+	}
+	return written, nil
+}
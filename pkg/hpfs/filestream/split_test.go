@@ -0,0 +1,85 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filestream
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestUploadAndDownloadSplit(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := NewFakeFilestreamClient()
+	metadata := ActionMetadata{Sink: "default", Filename: "backup/fullbackup/dn-0.xbstream", RequestId: "r"}
+
+	data := strings.Repeat("0123456789", 25)
+	size, err := UploadSplit(client, strings.NewReader(data), metadata, 100)
+	g.Expect(err).To(BeNil())
+	g.Expect(size).To(BeEquivalentTo(250))
+	part, ok := client.GetFile("default", "backup/fullbackup/dn-0.xbstream.part-00002")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(string(part)).To(Equal(data[200:]))
+	_, ok = client.GetFile("default", "backup/fullbackup/dn-0.xbstream.part-00003")
+	g.Expect(ok).To(BeFalse())
+	g.Expect(client.Uploads[0].RequestId).To(Equal("r.part-00000"))
+
+	var buf bytes.Buffer
+	size, err = DownloadSplit(client, &buf, metadata)
+	g.Expect(err).To(BeNil())
+	g.Expect(size).To(BeEquivalentTo(250))
+	g.Expect(buf.String()).To(Equal(data))
+
+	// stream of exactly one part
+	size, err = UploadSplit(client, strings.NewReader(data[:100]), metadata, 100)
+	g.Expect(err).To(BeNil())
+	g.Expect(size).To(BeEquivalentTo(100))
+	buf.Reset()
+	_, err = DownloadSplit(client, &buf, metadata)
+	g.Expect(err).To(BeNil())
+	g.Expect(buf.String()).To(Equal(data[:100]))
+
+	// missing part
+	_, err = UploadSplit(client, strings.NewReader(data), metadata, 100)
+	g.Expect(err).To(BeNil())
+	client.PutFile("default", "backup/fullbackup/dn-0.xbstream.part-00001", []byte("short"))
+	_, err = DownloadSplit(client, &bytes.Buffer{}, metadata)
+	g.Expect(err).To(MatchError("size of part 1 is 5, expect 100"))
+}
+
+func TestDownloadSplitOrdinaryObject(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := NewFakeFilestreamClient()
+	for _, data := range []string{"", "PX", "PXSPLIT", XbStreamChunkMagic + "payload"} {
+		client.PutFile("default", "object", []byte(data))
+		var buf bytes.Buffer
+		size, err := DownloadSplit(client, &buf, ActionMetadata{Sink: "default", Filename: "object"})
+		g.Expect(err).To(BeNil())
+		g.Expect(size).To(BeEquivalentTo(len(data)))
+		g.Expect(buf.String()).To(Equal(data))
+	}
+}
+
+func TestValidateMaxObjectSize(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect(ValidateMaxObjectSize(UploadOss, 1<<30)).To(Succeed())
+	g.Expect(ValidateMaxObjectSize(UploadSsh, 10<<40)).To(Succeed())
+	g.Expect(ValidateMaxObjectSize(UploadOss, 1<<20)).NotTo(Succeed())
+	g.Expect(ValidateMaxObjectSize(UploadMinio, 6<<40)).NotTo(Succeed())
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filestream
+
+import (
+	"io"
+	"time"
+)
+
+// ThroughputSample is the throughput measured over the last interval of transfer.
+type ThroughputSample struct {
+	Bytes          int64   // total bytes transferred so far
+	BytesPerSecond float64 // throughput over the last interval
+}
+
+// ThroughputSummary summarizes the throughput of the whole transfer.
+type ThroughputSummary struct {
+	Bytes              int64
+	Duration           time.Duration
+	AvgBytesPerSecond  float64
+	PeakBytesPerSecond float64
+}
+
+// ThroughputMeter wraps the source reader of transfer and measures the throughput, which is reported once
+// an interval passed on read. As the reader is drained by the transfer, slow reports mean either the source
+// or the sink is slow.
+type ThroughputMeter struct {
+	reader   io.Reader
+	interval time.Duration
+	report   func(ThroughputSample)
+	now      func() time.Time
+
+	start     time.Time
+	end       time.Time
+	bytes     int64
+	lastTime  time.Time
+	lastBytes int64
+	peak      float64
+}
+
+// NewThroughputMeter creates a throughput meter on reader, report is invoked with the throughput of every
+// interval, and can be nil.
+func NewThroughputMeter(reader io.Reader, interval time.Duration, report func(ThroughputSample)) *ThroughputMeter {
+	return newThroughputMeter(reader, interval, report, time.Now)
+}
+
+func newThroughputMeter(reader io.Reader, interval time.Duration, report func(ThroughputSample), now func() time.Time) *ThroughputMeter {
+	start := now()
+	return &ThroughputMeter{
+		reader:   reader,
+		interval: interval,
+		report:   report,
+		now:      now,
+		start:    start,
+		lastTime: start,
+	}
+}
+
+func bytesPerSecond(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) / d.Seconds()
+}
+
+func (m *ThroughputMeter) Read(p []byte) (int, error) {
+	n, err := m.reader.Read(p)
+	m.bytes += int64(n)
+	now := m.now()
+	m.end = now
+	if elapsed := now.Sub(m.lastTime); m.interval > 0 && elapsed >= m.interval {
+		sample := ThroughputSample{
+			Bytes:          m.bytes,
+			BytesPerSecond: bytesPerSecond(m.bytes-m.lastBytes, elapsed),
+		}
+		if sample.BytesPerSecond > m.peak {
+			m.peak = sample.BytesPerSecond
+		}
+		m.lastTime, m.lastBytes = now, m.bytes
+		if m.report != nil {
+			m.report(sample)
+		}
+	}
+	return n, err
+}
+
+// Summary summarizes the throughput till the last read. Peak is the average if the transfer completes within
+// an interval.
+func (m *ThroughputMeter) Summary() ThroughputSummary {
+	summary := ThroughputSummary{Bytes: m.bytes}
+	if !m.end.IsZero() {
+		summary.Duration = m.end.Sub(m.start)
+	}
+	summary.AvgBytesPerSecond = bytesPerSecond(summary.Bytes, summary.Duration)
+	summary.PeakBytesPerSecond = m.peak
+	if summary.PeakBytesPerSecond < summary.AvgBytesPerSecond {
+		summary.PeakBytesPerSecond = summary.AvgBytesPerSecond
+	}
+	return summary
+}
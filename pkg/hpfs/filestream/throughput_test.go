@@ -0,0 +1,69 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filestream
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+)
+
+func TestThroughputMeter(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// advances one second on each read
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		current := now
+		now = now.Add(time.Second)
+		return current
+	}
+
+	var samples []ThroughputSample
+	meter := newThroughputMeter(bytes.NewReader(make([]byte, 400)), 2*time.Second, func(sample ThroughputSample) {
+		samples = append(samples, sample)
+	}, clock)
+	buf := make([]byte, 100)
+	for i := 0; i < 4; i++ {
+		_, err := meter.Read(buf)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+	_, err := meter.Read(buf)
+	g.Expect(err).To(gomega.Equal(io.EOF))
+
+	// reported at 2s and 4s
+	g.Expect(samples).To(gomega.Equal([]ThroughputSample{
+		{Bytes: 200, BytesPerSecond: 100},
+		{Bytes: 400, BytesPerSecond: 100},
+	}))
+
+	summary := meter.Summary()
+	g.Expect(summary.Bytes).To(gomega.BeEquivalentTo(400))
+	g.Expect(summary.Duration).To(gomega.Equal(5 * time.Second))
+	g.Expect(summary.AvgBytesPerSecond).To(gomega.BeNumerically("==", 80))
+	g.Expect(summary.PeakBytesPerSecond).To(gomega.BeNumerically("==", 100))
+
+	// peak falls back to average if finished within an interval
+	meter = newThroughputMeter(bytes.NewReader(make([]byte, 100)), time.Minute, nil, clock)
+	_, _ = io.Copy(io.Discard, meter)
+	summary = meter.Summary()
+	g.Expect(summary.PeakBytesPerSecond).To(gomega.Equal(summary.AvgBytesPerSecond))
+	g.Expect(summary.Bytes).To(gomega.BeEquivalentTo(100))
+}
@@ -867,6 +867,28 @@ func GetFileServiceParam(sinkName string, sinkType string) (err error, params ma
 		params["bucket"] = sinkPtr.Bucket
 		fileServiceName = "s3"
 		params["bucket_lookup_type"] = sinkPtr.BucketLookupType
+	} else if sinkPtr.Type == config.SinkTypeAzure {
+		auth["endpoint"] = sinkPtr.Endpoint
+		auth["account"] = sinkPtr.Account
+		auth["account_key"] = sinkPtr.AccountKey
+		auth["auth_mode"] = sinkPtr.AuthMode
+		auth["client_id"] = sinkPtr.ClientId
+		auth["tenant_id"] = sinkPtr.TenantId
+		params["container"] = sinkPtr.Container
+		fileServiceName = "azure"
+	} else if sinkPtr.Type == config.SinkTypeGcs {
+		auth["endpoint"] = sinkPtr.Endpoint
+		auth["credentials_file"] = sinkPtr.CredentialsFile
+		params["bucket"] = sinkPtr.Bucket
+		fileServiceName = "gcs"
+	}
+	proxyURL, err := sinkPtr.Proxy()
+	if err != nil {
+		err = fmt.Errorf("failed to get proxy of sink %s: %w", sinkName, err)
+		return
+	}
+	if proxyURL != nil {
+		auth["proxy"] = proxyURL.String()
 	}
 	return
 }
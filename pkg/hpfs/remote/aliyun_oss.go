@@ -30,6 +30,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -46,7 +47,30 @@ func init() {
 type aliyunOssFs struct{}
 
 func (o *aliyunOssFs) newClient(ossCtx *aliyunOssContext) (*oss.Client, error) {
-	return oss.New(ossCtx.endpoint, ossCtx.accessKey, ossCtx.accessSecret, oss.Timeout(10, 3600*2))
+	options := append([]oss.ClientOption{oss.Timeout(10, 3600*2)}, ossCtx.proxyOptions...)
+	return oss.New(ossCtx.endpoint, ossCtx.accessKey, ossCtx.accessSecret, options...)
+}
+
+// CheckAliyunOssEndpoint checks that the bucket is accessible via the endpoint in auth, e.g. the endpoint of
+// transfer acceleration, which is rejected unless transfer acceleration is enabled on the bucket.
+func CheckAliyunOssEndpoint(auth map[string]string, bucketName string) error {
+	proxyOptions, err := ossProxyOptions(auth)
+	if err != nil {
+		return err
+	}
+	options := append([]oss.ClientOption{oss.Timeout(10, 30)}, proxyOptions...)
+	client, err := oss.New(auth["endpoint"], auth["access_key"], auth["access_secret"], options...)
+	if err != nil {
+		return fmt.Errorf("failed to create oss client: %w", err)
+	}
+	bucket, err := client.Bucket(bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to open oss bucket: %w", err)
+	}
+	if _, err := bucket.ListObjects(oss.MaxKeys(1)); err != nil {
+		return fmt.Errorf("failed to access oss bucket: %w", err)
+	}
+	return nil
 }
 
 func (o *aliyunOssFs) DeleteFile(ctx context.Context, path string, auth, params map[string]string) error {
@@ -179,6 +203,10 @@ func (o *aliyunOssFs) UploadFileNormally(ctx context.Context, reader io.Reader,
 		if ossCtx.retentionTime > 0 {
 			opts = append(opts, oss.Expires(time.Now().Add(ossCtx.retentionTime)))
 		}
+		if len(ossCtx.tags) > 0 {
+			opts = append(opts, oss.SetTagging(ossTagging(ossCtx.tags)))
+		}
+		opts = append(opts, ossServerSideEncryptionOptions(ossCtx.sse)...)
 
 		ft.complete(bucket.PutObject(path, reader, opts...))
 	}()
@@ -197,6 +225,11 @@ func (o *aliyunOssFs) UploadFile(ctx context.Context, reader io.Reader, path str
 		}
 		limitReaderSize = parsedVal
 	}
+	concurrency := uploadConcurrency(params)
+	if concurrency > 1 && (!ok || val == "") {
+		// part size bounds memory usage of concurrent upload
+		limitReaderSize = uploadPartSize(params, DefaultUploadPartSize)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -225,7 +258,7 @@ func (o *aliyunOssFs) UploadFile(ctx context.Context, reader io.Reader, path str
 		}
 
 		var partIndex int = 1
-		imur, err := bucket.InitiateMultipartUpload(path, opts...)
+		imur, err := bucket.InitiateMultipartUpload(path, append(opts, ossServerSideEncryptionOptions(ossCtx.sse)...)...)
 		if err != nil {
 			ft.complete(err)
 			return
@@ -267,20 +300,28 @@ func (o *aliyunOssFs) UploadFile(ctx context.Context, reader io.Reader, path str
 		}()
 		var uploadedLen int64
 		parts := make([]oss.UploadPart, 0)
-		emptyBytes := make([]byte, 0)
-		for {
-			_, err := pipeReader.Read(emptyBytes)
+		if concurrency > 1 {
+			parts, err = o.uploadPartsConcurrently(bucket, imur, pipeReader, limitReaderSize, concurrency, opts)
 			if err != nil {
-				break
+				ft.complete(err)
+				return
 			}
-			limitedReader := io.LimitReader(pipeReader, limitReaderSize)
-			uploadPart, err := bucket.UploadPart(imur, limitedReader, limitReaderSize, partIndex, opts...)
-			partIndex++
-			uploadedLen += limitReaderSize
-			if err != nil {
-				break
+		} else {
+			emptyBytes := make([]byte, 0)
+			for {
+				_, err := pipeReader.Read(emptyBytes)
+				if err != nil {
+					break
+				}
+				limitedReader := io.LimitReader(pipeReader, limitReaderSize)
+				uploadPart, err := bucket.UploadPart(imur, limitedReader, limitReaderSize, partIndex, opts...)
+				partIndex++
+				uploadedLen += limitReaderSize
+				if err != nil {
+					break
+				}
+				parts = append(parts, uploadPart)
 			}
-			parts = append(parts, uploadPart)
 		}
 
 		if len(parts) > 0 {
@@ -290,13 +331,13 @@ func (o *aliyunOssFs) UploadFile(ctx context.Context, reader io.Reader, path str
 				return
 			}
 			totalSize := atomic.LoadInt64(&actualSize)
-			SetTags(bucket, path, actualSize)
+			SetTags(bucket, path, actualSize, ossCtx.tags)
 			ft.complete(nil)
 			var copyPosition int64
 			pageNumber := 1
 			copiedParts := make([]oss.UploadPart, 0)
 			if totalSize%limitReaderSize != 0 {
-				imur, err = bucket.InitiateMultipartUpload(path, opts...)
+				imur, err = bucket.InitiateMultipartUpload(path, append(opts, ossServerSideEncryptionOptions(ossCtx.sse)...)...)
 				if err != nil {
 					return
 				}
@@ -317,7 +358,7 @@ func (o *aliyunOssFs) UploadFile(ctx context.Context, reader io.Reader, path str
 						if err != nil {
 							return
 						}
-						SetTags(bucket, path, actualSize)
+						SetTags(bucket, path, actualSize, ossCtx.tags)
 						break
 					}
 				}
@@ -329,21 +370,70 @@ func (o *aliyunOssFs) UploadFile(ctx context.Context, reader io.Reader, path str
 	return ft, nil
 }
 
-func SetTags(bucket *oss.Bucket, objKey string, actualSize int64) {
+// uploadPartsConcurrently uploads parts of the stream concurrently, and returns the uploaded parts in order. The
+// multipart upload is aborted on failure.
+func (o *aliyunOssFs) uploadPartsConcurrently(bucket *oss.Bucket, imur oss.InitiateMultipartUploadResult, reader io.Reader,
+	partSize int64, concurrency int, opts []oss.Option) ([]oss.UploadPart, error) {
+	var lock sync.Mutex
+	uploadedParts := make(map[int]oss.UploadPart)
+	partCount, err := concurrentPartUpload(reader, partSize, concurrency, func(partNumber int, data []byte) error {
+		uploadPart, err := bucket.UploadPart(imur, bytes.NewReader(data), int64(len(data)), partNumber, opts...)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		uploadedParts[partNumber] = uploadPart
+		return nil
+	}, func() error {
+		return bucket.AbortMultipartUpload(imur, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]oss.UploadPart, 0, partCount)
+	for i := 1; i <= partCount; i++ {
+		parts = append(parts, uploadedParts[i])
+	}
+	return parts, nil
+}
+
+func SetTags(bucket *oss.Bucket, objKey string, actualSize int64, objectTags map[string]string) {
 	uploaderTag := oss.Tag{
-		Key:   "uploader",
+		Key:   tagUploader,
 		Value: "hpfs",
 	}
 	sizeTag := oss.Tag{
-		Key:   "size",
+		Key:   tagSize,
 		Value: strconv.FormatInt(actualSize, 10),
 	}
-	tagging := oss.Tagging{
-		Tags: []oss.Tag{uploaderTag, sizeTag},
-	}
+	tagging := ossTagging(objectTags)
+	tagging.Tags = append([]oss.Tag{uploaderTag, sizeTag}, tagging.Tags...)
 	bucket.PutObjectTagging(objKey, tagging)
 }
 
+// ossTagging converts object tags to tagging of oss.
+func ossTagging(objectTags map[string]string) oss.Tagging {
+	tagging := oss.Tagging{}
+	for _, k := range sortedTagKeys(objectTags) {
+		tagging.Tags = append(tagging.Tags, oss.Tag{Key: k, Value: objectTags[k]})
+	}
+	return tagging
+}
+
+// ossServerSideEncryptionOptions converts server-side encryption to options of oss, which only apply to requests
+// creating objects, e.g. PutObject and InitiateMultipartUpload, but not UploadPart.
+func ossServerSideEncryptionOptions(sse *serverSideEncryption) []oss.Option {
+	if sse == nil {
+		return nil
+	}
+	opts := []oss.Option{oss.ServerSideEncryption(sse.algorithm)}
+	if sse.kmsKeyId != "" {
+		opts = append(opts, oss.ServerSideEncryptionKeyID(sse.kmsKeyId))
+	}
+	return opts
+}
+
 func GetActualSizeFromTags(bucket *oss.Bucket, objKey string) int64 {
 	hpfsUpload := false
 	var size int64 = -1
@@ -401,7 +491,7 @@ func (o *aliyunOssFs) UploadFileTmpFile(ctx context.Context, reader io.Reader, p
 		if ossCtx.retentionTime > 0 {
 			opts = append(opts, oss.Expires(time.Now().Add(ossCtx.retentionTime)))
 		}
-		imur, err := bucket.InitiateMultipartUpload(path, opts...)
+		imur, err := bucket.InitiateMultipartUpload(path, append(opts, ossServerSideEncryptionOptions(ossCtx.sse)...)...)
 		complete := false
 		if err != nil {
 			ft.complete(err)
@@ -487,6 +577,8 @@ func (o *aliyunOssFs) UploadFileTmpFile(ctx context.Context, reader io.Reader, p
 		_, err = bucket.CompleteMultipartUpload(imur, parts, opts...)
 		if err != nil {
 			complete = true
+		} else if len(ossCtx.tags) > 0 {
+			err = bucket.PutObjectTagging(path, ossTagging(ossCtx.tags))
 		}
 		ft.complete(err)
 	}()
@@ -509,6 +601,11 @@ func (o *aliyunOssFs) DownloadFile(ctx context.Context, writer io.Writer, path s
 		return nil, fmt.Errorf("failed to open oss bucket: %w", err)
 	}
 
+	offset, err := rangeOffset(params)
+	if err != nil {
+		return nil, err
+	}
+
 	ft := newFileTask(ctx)
 	go func() {
 		var bytesCount int64
@@ -524,10 +621,15 @@ func (o *aliyunOssFs) DownloadFile(ctx context.Context, writer io.Writer, path s
 			if actualSize != -1 {
 				bytesCount = actualSize
 			}
+			bytesCount -= offset
 			polarxIo.WriteUint64(writer, uint64(bytesCount))
 		}
 
-		r, err := bucket.GetObject(path)
+		var options []oss.Option
+		if offset > 0 {
+			options = append(options, oss.NormalizedRange(strconv.FormatInt(offset, 10)+"-"))
+		}
+		r, err := bucket.GetObject(path, options...)
 		if err != nil {
 			ft.complete(fmt.Errorf("failed to get object: %w", err))
 			return
@@ -688,6 +790,9 @@ type aliyunOssContext struct {
 	bufferSize    int64
 	useTmpFile    bool
 	deadline      int64
+	tags          map[string]string
+	sse           *serverSideEncryption
+	proxyOptions  []oss.ClientOption
 }
 
 func newAliyunOssContext(ctx context.Context, auth, params map[string]string) (*aliyunOssContext, error) {
@@ -723,6 +828,14 @@ func newAliyunOssContext(ctx context.Context, auth, params map[string]string) (*
 		}
 		deadline = parsedDeadline
 	}
+	sse, err := objectServerSideEncryption(params)
+	if err != nil {
+		return nil, err
+	}
+	proxyOptions, err := ossProxyOptions(auth)
+	if err != nil {
+		return nil, err
+	}
 	ossCtx := &aliyunOssContext{
 		ctx:          ctx,
 		endpoint:     auth["endpoint"],
@@ -733,6 +846,9 @@ func newAliyunOssContext(ctx context.Context, auth, params map[string]string) (*
 		bufferSize:   bufferSize,
 		useTmpFile:   useTmpFile,
 		deadline:     deadline,
+		tags:         objectTags(params),
+		sse:          sse,
+		proxyOptions: proxyOptions,
 	}
 
 	if t, ok := params["retention-time"]; ok {
@@ -0,0 +1,392 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/common"
+	polarxIo "github.com/alibaba/polardbx-operator/pkg/util/io"
+	polarxPath "github.com/alibaba/polardbx-operator/pkg/util/path"
+)
+
+const (
+	AzureBlobDefaultBlockSize  = 1 << 20 * 32 //32MB
+	AzureBlobAuthModeSharedKey = "sharedKey"
+	AzureBlobAuthModeWorkload  = "workloadIdentity"
+)
+
+func init() {
+	MustRegisterFileService("azure", &azureBlobFs{})
+}
+
+// ErrAzureBlobAuthFailed is returned when azure rejects the credential of sink, either the shared key
+// or the token of workload identity.
+var ErrAzureBlobAuthFailed = errors.New("azure blob authentication failed")
+
+type azureBlobFs struct{}
+
+type azureBlobContext struct {
+	ctx context.Context
+
+	account   string
+	container string
+	authMode  string
+	writeLen  bool
+	blockSize int64
+	deadline  int64
+
+	client *container.Client
+}
+
+// azureCredentialCache caches credentials of workload identity by tenant and client, so that the access
+// tokens are reused across requests.
+var azureCredentialCache sync.Map
+
+// workloadIdentityCredential returns the credential exchanging the federated token projected into the pod for
+// an access token of azure storage. Client and tenant default to the ones injected by azure workload identity webhook.
+func workloadIdentityCredential(clientId, tenantId string, options azcore.ClientOptions) (azcore.TokenCredential, error) {
+	cacheKey := tenantId + "/" + clientId
+	if val, ok := azureCredentialCache.Load(cacheKey); ok {
+		return val.(azcore.TokenCredential), nil
+	}
+	cred, err := azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+		ClientOptions: options,
+		ClientID:      clientId,
+		TenantID:      tenantId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: workload identity not configured: %v", ErrAzureBlobAuthFailed, err)
+	}
+	val, _ := azureCredentialCache.LoadOrStore(cacheKey, cred)
+	return val.(azcore.TokenCredential), nil
+}
+
+func newAzureBlobContext(ctx context.Context, auth, params map[string]string) (*azureBlobContext, error) {
+	var writeLen bool
+	if val, ok := params["write_len"]; ok {
+		toWriteLenVal, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, err
+		}
+		writeLen = toWriteLenVal
+	}
+	var blockSize int64 = AzureBlobDefaultBlockSize
+	if val, ok := params["block_size"]; ok && val != "" {
+		toBlockSize, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if toBlockSize > 0 {
+			blockSize = toBlockSize
+		}
+	}
+	var deadline int64 = 0
+	if val, ok := params["deadline"]; ok {
+		parsedDeadline, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		deadline = parsedDeadline
+	}
+
+	httpClient, err := newProxyHttpClient(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	azureCtx := &azureBlobContext{
+		ctx:       ctx,
+		account:   auth["account"],
+		container: params["container"],
+		authMode:  auth["auth_mode"],
+		writeLen:  writeLen,
+		blockSize: blockSize,
+		deadline:  deadline,
+	}
+	if azureCtx.account == "" {
+		return nil, errors.New("azure blob account not specified")
+	}
+	if azureCtx.container == "" {
+		return nil, errors.New("azure blob container not specified")
+	}
+	endpoint := strings.TrimSuffix(auth["endpoint"], "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", azureCtx.account)
+	}
+	if azureCtx.authMode == "" {
+		azureCtx.authMode = AzureBlobAuthModeSharedKey
+	}
+	clientOptions := azcore.ClientOptions{Transport: httpClient}
+	containerUrl := endpoint + "/" + azureCtx.container
+	switch azureCtx.authMode {
+	case AzureBlobAuthModeSharedKey:
+		cred, err := container.NewSharedKeyCredential(azureCtx.account, auth["account_key"])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid account key of account %s: %v", ErrAzureBlobAuthFailed, azureCtx.account, err)
+		}
+		azureCtx.client, err = container.NewClientWithSharedKeyCredential(containerUrl, cred,
+			&container.ClientOptions{ClientOptions: clientOptions})
+		if err != nil {
+			return nil, err
+		}
+	case AzureBlobAuthModeWorkload:
+		cred, err := workloadIdentityCredential(auth["client_id"], auth["tenant_id"], clientOptions)
+		if err != nil {
+			return nil, err
+		}
+		azureCtx.client, err = container.NewClient(containerUrl, cred, &container.ClientOptions{ClientOptions: clientOptions})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid azure blob auth mode: %s", azureCtx.authMode)
+	}
+	return azureCtx, nil
+}
+
+// wrapError wraps the authentication failures, either rejected by azure storage or by token exchange of
+// workload identity, with ErrAzureBlobAuthFailed.
+func (c *azureBlobContext) wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) &&
+		(respErr.StatusCode == http.StatusUnauthorized || respErr.StatusCode == http.StatusForbidden) {
+		return fmt.Errorf("%w: account %s, container %s, auth mode %s, status %d, code %s", ErrAzureBlobAuthFailed,
+			c.account, c.container, c.authMode, respErr.StatusCode, respErr.ErrorCode)
+	}
+	var authErr *azidentity.AuthenticationFailedError
+	if errors.As(err, &authErr) {
+		return fmt.Errorf("%w: %v", ErrAzureBlobAuthFailed, err)
+	}
+	return err
+}
+
+func (c *azureBlobContext) deleteBlob(blobPath string) error {
+	_, err := c.client.NewBlobClient(blobPath).Delete(c.ctx, nil)
+	return c.wrapError(err)
+}
+
+// walkBlobs lists all the blobs under prefix recursively and calls callback page by page.
+func (c *azureBlobContext) walkBlobs(prefix string, callback func([]*container.BlobItem) error) error {
+	pager := c.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(c.ctx)
+		if err != nil {
+			return c.wrapError(err)
+		}
+		if len(page.Segment.BlobItems) > 0 {
+			if err := callback(page.Segment.BlobItems); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *azureBlobFs) DeleteFile(ctx context.Context, path string, auth, params map[string]string) error {
+	azureCtx, err := newAzureBlobContext(ctx, auth, params)
+	if err != nil {
+		return err
+	}
+	recursive, err := strconv.ParseBool(params["recursive"])
+	if err != nil {
+		return fmt.Errorf("invalid value for param 'recursive': %w", err)
+	}
+	if !recursive {
+		return azureCtx.deleteBlob(path)
+	}
+	dirPrefix := strings.TrimSuffix(path, "/") + "/"
+	return azureCtx.walkBlobs(strings.TrimSuffix(path, "/"), func(blobs []*container.BlobItem) error {
+		for _, blob := range blobs {
+			name := *blob.Name
+			if name != path && !strings.HasPrefix(name, dirPrefix) {
+				continue
+			}
+			if err := azureCtx.deleteBlob(name); err != nil {
+				return fmt.Errorf("failed to delete blob '%s', error: '%w'", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (a *azureBlobFs) listBlobsBeforeDeadline(azureCtx *azureBlobContext, path string, callback func([]string) error) error {
+	return azureCtx.walkBlobs(strings.TrimSuffix(path, "/")+"/", func(blobs []*container.BlobItem) error {
+		objs := make([]string, 0)
+		for _, blob := range blobs {
+			if blob.Properties == nil || blob.Properties.LastModified == nil {
+				return fmt.Errorf("no last modified time of blob %s", *blob.Name)
+			}
+			if blob.Properties.LastModified.Unix() < azureCtx.deadline {
+				objs = append(objs, *blob.Name)
+			}
+		}
+		if len(objs) > 0 {
+			return callback(objs)
+		}
+		return nil
+	})
+}
+
+func (a *azureBlobFs) DeleteExpiredFile(ctx context.Context, path string, auth, params map[string]string) (FileTask, error) {
+	azureCtx, err := newAzureBlobContext(ctx, auth, params)
+	if err != nil {
+		return nil, err
+	}
+	ft := newFileTask(ctx)
+	go func() {
+		err := a.listBlobsBeforeDeadline(azureCtx, path, func(objs []string) error {
+			for _, obj := range objs {
+				if err := azureCtx.deleteBlob(obj); err != nil {
+					return err
+				}
+				if val, ok := ctx.Value(common.AffectedFiles).(*[]string); ok {
+					*val = append(*val, obj)
+				}
+			}
+			return nil
+		})
+		ft.complete(err)
+	}()
+	return ft, nil
+}
+
+// UploadFile uploads content as a block blob. The sdk puts content fits in a single block directly, otherwise
+// stages it block by block and commits the block list at last. Blocks are staged concurrently if upload
+// concurrency is specified. Blocks staged by a failed upload are left uncommitted and garbage collected by azure.
+func (a *azureBlobFs) UploadFile(ctx context.Context, reader io.Reader, path string, auth, params map[string]string) (FileTask, error) {
+	azureCtx, err := newAzureBlobContext(ctx, auth, params)
+	if err != nil {
+		return nil, err
+	}
+	ft := newFileTask(ctx)
+	go func() {
+		_, err := azureCtx.client.NewBlockBlobClient(path).UploadStream(ctx, reader, &blockblob.UploadStreamOptions{
+			BlockSize:   uploadPartSize(params, azureCtx.blockSize),
+			Concurrency: uploadConcurrency(params),
+		})
+		ft.complete(azureCtx.wrapError(err))
+	}()
+	return ft, nil
+}
+
+func (a *azureBlobFs) DownloadFile(ctx context.Context, writer io.Writer, path string, auth, params map[string]string) (FileTask, error) {
+	azureCtx, err := newAzureBlobContext(ctx, auth, params)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := rangeOffset(params)
+	if err != nil {
+		return nil, err
+	}
+	ft := newFileTask(ctx)
+	go func() {
+		resp, err := azureCtx.client.NewBlobClient(path).DownloadStream(ctx, &blob.DownloadStreamOptions{
+			Range: blob.HTTPRange{Offset: offset},
+		})
+		if err != nil {
+			ft.complete(fmt.Errorf("failed to get blob: %w", azureCtx.wrapError(err)))
+			return
+		}
+		defer resp.Body.Close()
+		if azureCtx.writeLen {
+			if resp.ContentLength == nil || *resp.ContentLength < 0 {
+				ft.complete(errors.New("failed to get blob size"))
+				return
+			}
+			bytesCount := *resp.ContentLength
+			polarxIo.WriteUint64(writer, uint64(bytesCount))
+			_, err = io.CopyN(writer, resp.Body, bytesCount)
+		} else {
+			_, err = io.Copy(writer, resp.Body)
+		}
+		if err != nil {
+			ft.complete(fmt.Errorf("failed to copy content: %w", err))
+			return
+		}
+		ft.complete(nil)
+	}()
+	return ft, nil
+}
+
+func (a *azureBlobFs) ListFiles(ctx context.Context, writer io.Writer, path string, auth, params map[string]string) (FileTask, error) {
+	azureCtx, err := newAzureBlobContext(ctx, auth, params)
+	if err != nil {
+		return nil, err
+	}
+	ft := newFileTask(ctx)
+	go func() {
+		entryNames := make([]string, 0)
+		pager := azureCtx.client.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{Prefix: &path})
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				ft.complete(fmt.Errorf("failed to list azure blobs in path %s: %w", path, azureCtx.wrapError(err)))
+				return
+			}
+			for _, blob := range page.Segment.BlobItems { // file
+				entryNames = append(entryNames, polarxPath.GetBaseNameFromPath(*blob.Name))
+			}
+			for _, dir := range page.Segment.BlobPrefixes { // subdirectory
+				entryNames = append(entryNames, polarxPath.GetBaseNameFromPath(*dir.Name))
+			}
+		}
+		// parse entry slice and send response
+		encodedEntryNames, err := json.Marshal(entryNames)
+		if err != nil {
+			ft.complete(fmt.Errorf("failed to encode entry name slice,: %w", err))
+			return
+		}
+		if azureCtx.writeLen {
+			bytesCount := int64(len(encodedEntryNames))
+			err := polarxIo.WriteUint64(writer, uint64(bytesCount))
+			if err != nil {
+				ft.complete(fmt.Errorf("failed to send content bytes count: %w", err))
+				return
+			}
+			_, err = io.CopyN(writer, bytes.NewReader(encodedEntryNames), bytesCount)
+		} else {
+			_, err = io.Copy(writer, bytes.NewReader(encodedEntryNames))
+		}
+		if err != nil {
+			ft.complete(fmt.Errorf("failed to copy content: %w", err))
+			return
+		}
+		ft.complete(nil)
+	}()
+	return ft, nil
+}
+
+func (a *azureBlobFs) ListAllFiles(ctx context.Context, path string, auth, params map[string]string) (FileTask, error) {
+	azureCtx, err := newAzureBlobContext(ctx, auth, params)
+	if err != nil {
+		return nil, err
+	}
+	ft := newFileTask(ctx)
+	go func() {
+		err := a.listBlobsBeforeDeadline(azureCtx, path, func(objs []string) error {
+			if val, ok := ctx.Value(common.AffectedFiles).(*[]string); ok {
+				*val = append(*val, objs...)
+			}
+			return nil
+		})
+		ft.complete(err)
+	}()
+	return ft, nil
+}
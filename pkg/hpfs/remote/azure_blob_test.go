@@ -0,0 +1,193 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// sharedKeySignature signs the request received according to the shared key authorization of azure storage.
+func sharedKeySignature(account string, accountKey []byte, req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+	headerNames := make([]string, 0)
+	for name := range req.Header {
+		lowerName := strings.ToLower(name)
+		if strings.HasPrefix(lowerName, "x-ms-") {
+			headerNames = append(headerNames, lowerName)
+		}
+	}
+	sort.Strings(headerNames)
+	var canonicalizedHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalizedHeaders.WriteString(name + ":" + strings.Join(req.Header.Values(name), ",") + "\n")
+	}
+
+	canonicalizedResource := "/" + account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	queryNames := make([]string, 0, len(query))
+	for name := range query {
+		queryNames = append(queryNames, name)
+	}
+	sort.Strings(queryNames)
+	for _, name := range queryNames {
+		values := query[name]
+		sort.Strings(values)
+		canonicalizedResource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, x-ms-date is used instead
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		canonicalizedHeaders.String() + canonicalizedResource,
+	}, "\n")
+	mac := hmac.New(sha256.New, accountKey)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// fakeAzureBlobServer serves block blobs in memory and rejects requests not signed by the shared key.
+func fakeAzureBlobServer(t *testing.T, accountKey string) *httptest.Server {
+	blobs := map[string][]byte{}
+	blocks := map[string][]byte{}
+	var lock sync.Mutex
+	key, _ := base64.StdEncoding.DecodeString(accountKey)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.Header.Get("Authorization") != "SharedKey test:"+sharedKeySignature("test", key, r) {
+			w.Header().Set("x-ms-error-code", "AuthenticationFailed")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPut && query.Get("comp") == "block":
+			blocks[r.URL.Path+"#"+query.Get("blockid")] = body
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+			var content []byte
+			for _, m := range regexp.MustCompile(`<Latest>([^<]*)</Latest>`).FindAllStringSubmatch(string(body), -1) {
+				content = append(content, blocks[r.URL.Path+"#"+m[1]]...)
+			}
+			blobs[r.URL.Path] = content
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut:
+			blobs[r.URL.Path] = body
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet:
+			content, ok := blobs[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(content)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+}
+
+func TestAzureBlobUploadAndDownload(t *testing.T) {
+	accountKey := base64.StdEncoding.EncodeToString([]byte("test-account-key"))
+	server := fakeAzureBlobServer(t, accountKey)
+	defer server.Close()
+
+	fileService, err := GetFileService("azure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := map[string]string{"endpoint": server.URL, "account": "test", "account_key": accountKey}
+	params := map[string]string{"container": "backup", "block_size": strconv.Itoa(1 << 20)}
+	for _, content := range []string{"abc", strings.Repeat("0123456789", 300000)} {
+		ft, err := fileService.UploadFile(context.Background(), strings.NewReader(content), "dir/file.xbstream", auth, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ft.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		ft, err = fileService.DownloadFile(context.Background(), &buf, "dir/file.xbstream", auth, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ft.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != content {
+			t.Fatalf("expect %d bytes, actual %d bytes", len(content), buf.Len())
+		}
+	}
+}
+
+func TestAzureBlobUploadConcurrently(t *testing.T) {
+	accountKey := base64.StdEncoding.EncodeToString([]byte("test-account-key"))
+	server := fakeAzureBlobServer(t, accountKey)
+	defer server.Close()
+
+	fileService, _ := GetFileService("azure")
+	auth := map[string]string{"endpoint": server.URL, "account": "test", "account_key": accountKey}
+	params := map[string]string{"container": "backup", "upload_concurrency": "3", "upload_part_size": strconv.Itoa(1 << 20)}
+	content := strings.Repeat("0123456789abcdefghij", 300000)
+	ft, err := fileService.UploadFile(context.Background(), strings.NewReader(content), "dir/file.xbstream", auth, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ft.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	ft, err = fileService.DownloadFile(context.Background(), &buf, "dir/file.xbstream", auth, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ft.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != content {
+		t.Fatalf("expect %d bytes, actual %d bytes", len(content), buf.Len())
+	}
+}
+
+func TestAzureBlobAuthFailed(t *testing.T) {
+	server := fakeAzureBlobServer(t, base64.StdEncoding.EncodeToString([]byte("test-account-key")))
+	defer server.Close()
+
+	fileService, _ := GetFileService("azure")
+	auth := map[string]string{"endpoint": server.URL, "account": "test",
+		"account_key": base64.StdEncoding.EncodeToString([]byte("wrong-account-key"))}
+	params := map[string]string{"container": "backup"}
+	ft, err := fileService.UploadFile(context.Background(), strings.NewReader("abc"), "file", auth, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ft.Wait(); !errors.Is(err, ErrAzureBlobAuthFailed) {
+		t.Fatalf("expect auth failure, actual %v", err)
+	}
+}
@@ -0,0 +1,420 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/common"
+	polarxIo "github.com/alibaba/polardbx-operator/pkg/util/io"
+	polarxPath "github.com/alibaba/polardbx-operator/pkg/util/path"
+)
+
+const (
+	GcsDefaultChunkSize = 1 << 20 * 16 //16MB
+	// GcsChunkSizeAlignment is the alignment of chunks of resumable upload except the last one
+	GcsChunkSizeAlignment = 1 << 10 * 256 //256KB
+)
+
+func init() {
+	MustRegisterFileService("gcs", &gcsFs{})
+}
+
+// ErrGcsAuthFailed is returned when google cloud storage rejects the credential of sink, either the service
+// account key or the token of workload identity.
+var ErrGcsAuthFailed = errors.New("gcs authentication failed")
+
+// ErrGcsChecksumMismatch is returned when the crc32c of transferred content differs from the one of object.
+var ErrGcsChecksumMismatch = errors.New("gcs object checksum mismatch")
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+type gcsFs struct{}
+
+type gcsContext struct {
+	ctx context.Context
+
+	bucket    string
+	writeLen  bool
+	chunkSize int
+	deadline  int64
+
+	client *storage.Client
+}
+
+// gcsTokenSourceCache caches token sources by credentials file, the empty key is for the application default
+// credentials, e.g. workload identity on GKE.
+var gcsTokenSourceCache sync.Map
+
+// gcsTokenSource returns the token source of the service account key if credentials file is specified, otherwise
+// of the application default credentials. Tokens are requested via httpClient.
+func gcsTokenSource(credentialsFile string, httpClient *http.Client) (oauth2.TokenSource, error) {
+	if val, ok := gcsTokenSourceCache.Load(credentialsFile); ok {
+		return val.(oauth2.TokenSource), nil
+	}
+	// token sources outlive the request, so that the context of them carries nothing but the http client
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+	var credentials *google.Credentials
+	if credentialsFile != "" {
+		data, err := os.ReadFile(credentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read service account key: %v", ErrGcsAuthFailed, err)
+		}
+		credentials, err = google.CredentialsFromJSON(ctx, data, storage.ScopeReadWrite)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to parse service account key: %v", ErrGcsAuthFailed, err)
+		}
+	} else {
+		var err error
+		credentials, err = google.FindDefaultCredentials(ctx, storage.ScopeReadWrite)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrGcsAuthFailed, err)
+		}
+	}
+	val, _ := gcsTokenSourceCache.LoadOrStore(credentialsFile, credentials.TokenSource)
+	return val.(oauth2.TokenSource), nil
+}
+
+func newGcsContext(ctx context.Context, auth, params map[string]string) (*gcsContext, error) {
+	var writeLen bool
+	if val, ok := params["write_len"]; ok {
+		toWriteLenVal, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, err
+		}
+		writeLen = toWriteLenVal
+	}
+	var chunkSize int64 = GcsDefaultChunkSize
+	if val, ok := params["chunk_size"]; ok && val != "" {
+		toChunkSize, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if toChunkSize > 0 {
+			chunkSize = toChunkSize / GcsChunkSizeAlignment * GcsChunkSizeAlignment
+			if chunkSize == 0 {
+				chunkSize = GcsChunkSizeAlignment
+			}
+		}
+	}
+	var deadline int64 = 0
+	if val, ok := params["deadline"]; ok {
+		parsedDeadline, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		deadline = parsedDeadline
+	}
+
+	gcsCtx := &gcsContext{
+		ctx:       ctx,
+		bucket:    params["bucket"],
+		writeLen:  writeLen,
+		chunkSize: int(chunkSize),
+		deadline:  deadline,
+	}
+	if gcsCtx.bucket == "" {
+		return nil, errors.New("gcs bucket not specified")
+	}
+
+	httpClient, err := newProxyHttpClient(auth)
+	if err != nil {
+		return nil, err
+	}
+	credentialsFile := auth["credentials_file"]
+	if credentialsFile == "" {
+		credentialsFile = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	tokenSource, err := gcsTokenSource(credentialsFile, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	options := []option.ClientOption{
+		option.WithHTTPClient(&http.Client{
+			Transport: &oauth2.Transport{Source: tokenSource, Base: httpClient.Transport},
+		}),
+	}
+	if endpoint := strings.TrimSuffix(auth["endpoint"], "/"); endpoint != "" {
+		options = append(options, option.WithEndpoint(endpoint+"/storage/v1/"))
+	}
+	gcsCtx.client, err = storage.NewClient(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	return gcsCtx, nil
+}
+
+// wrapError wraps the authentication failures, either rejected by gcs or by token exchange, with ErrGcsAuthFailed.
+func (c *gcsContext) wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && (apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden) {
+		return fmt.Errorf("%w: bucket %s, status %d: %s", ErrGcsAuthFailed, c.bucket, apiErr.Code, apiErr.Message)
+	}
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return fmt.Errorf("%w: %v", ErrGcsAuthFailed, err)
+	}
+	return err
+}
+
+func (c *gcsContext) object(objectPath string) *storage.ObjectHandle {
+	return c.client.Bucket(c.bucket).Object(objectPath)
+}
+
+func (c *gcsContext) deleteObject(objectPath string) error {
+	return c.wrapError(c.object(objectPath).Delete(c.ctx))
+}
+
+// walkObjects lists all the objects under prefix recursively and calls callback page by page.
+func (c *gcsContext) walkObjects(prefix string, callback func([]*storage.ObjectAttrs) error) error {
+	it := c.client.Bucket(c.bucket).Objects(c.ctx, &storage.Query{Prefix: prefix})
+	pager := iterator.NewPager(it, 1000, "")
+	for {
+		objects := make([]*storage.ObjectAttrs, 0)
+		pageToken, err := pager.NextPage(&objects)
+		if err != nil {
+			return c.wrapError(err)
+		}
+		if len(objects) > 0 {
+			if err := callback(objects); err != nil {
+				return err
+			}
+		}
+		if pageToken == "" {
+			return nil
+		}
+	}
+}
+
+func (g *gcsFs) DeleteFile(ctx context.Context, path string, auth, params map[string]string) error {
+	gcsCtx, err := newGcsContext(ctx, auth, params)
+	if err != nil {
+		return err
+	}
+	defer gcsCtx.client.Close()
+	recursive, err := strconv.ParseBool(params["recursive"])
+	if err != nil {
+		return fmt.Errorf("invalid value for param 'recursive': %w", err)
+	}
+	if !recursive {
+		return gcsCtx.deleteObject(path)
+	}
+	dirPrefix := strings.TrimSuffix(path, "/") + "/"
+	return gcsCtx.walkObjects(strings.TrimSuffix(path, "/"), func(objects []*storage.ObjectAttrs) error {
+		for _, object := range objects {
+			if object.Name != path && !strings.HasPrefix(object.Name, dirPrefix) {
+				continue
+			}
+			if err := gcsCtx.deleteObject(object.Name); err != nil {
+				return fmt.Errorf("failed to delete object '%s', error: '%w'", object.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (g *gcsFs) listObjectsBeforeDeadline(gcsCtx *gcsContext, path string, callback func([]string) error) error {
+	return gcsCtx.walkObjects(strings.TrimSuffix(path, "/")+"/", func(objects []*storage.ObjectAttrs) error {
+		objs := make([]string, 0)
+		for _, object := range objects {
+			if object.Updated.Unix() < gcsCtx.deadline {
+				objs = append(objs, object.Name)
+			}
+		}
+		if len(objs) > 0 {
+			return callback(objs)
+		}
+		return nil
+	})
+}
+
+func (g *gcsFs) DeleteExpiredFile(ctx context.Context, path string, auth, params map[string]string) (FileTask, error) {
+	gcsCtx, err := newGcsContext(ctx, auth, params)
+	if err != nil {
+		return nil, err
+	}
+	ft := newFileTask(ctx)
+	go func() {
+		defer gcsCtx.client.Close()
+		err := g.listObjectsBeforeDeadline(gcsCtx, path, func(objs []string) error {
+			for _, obj := range objs {
+				if err := gcsCtx.deleteObject(obj); err != nil {
+					return err
+				}
+				if val, ok := ctx.Value(common.AffectedFiles).(*[]string); ok {
+					*val = append(*val, obj)
+				}
+			}
+			return nil
+		})
+		ft.complete(err)
+	}()
+	return ft, nil
+}
+
+// UploadFile uploads content in a single request if it fits in a chunk, otherwise by a resumable upload session
+// chunk by chunk. Crc32c of content is checked against the one computed by gcs.
+func (g *gcsFs) UploadFile(ctx context.Context, reader io.Reader, path string, auth, params map[string]string) (FileTask, error) {
+	gcsCtx, err := newGcsContext(ctx, auth, params)
+	if err != nil {
+		return nil, err
+	}
+	ft := newFileTask(ctx)
+	go func() {
+		defer gcsCtx.client.Close()
+		ft.complete(gcsCtx.upload(reader, path))
+	}()
+	return ft, nil
+}
+
+func (c *gcsContext) upload(reader io.Reader, objectPath string) error {
+	// the writer is discarded without committing the object if upload is canceled
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	w := c.object(objectPath).NewWriter(ctx)
+	w.ChunkSize = c.chunkSize
+	w.ContentType = "application/octet-stream"
+	h := crc32.New(crc32cTable)
+	if _, err := io.Copy(io.MultiWriter(w, h), reader); err != nil {
+		cancel()
+		w.Close()
+		return fmt.Errorf("failed to upload content: %w", c.wrapError(err))
+	}
+	if err := w.Close(); err != nil {
+		return c.wrapError(err)
+	}
+	if actual := h.Sum32(); w.Attrs().CRC32C != actual {
+		return fmt.Errorf("%w: object %s, expected crc32c %d, actual %d", ErrGcsChecksumMismatch, objectPath,
+			w.Attrs().CRC32C, actual)
+	}
+	return nil
+}
+
+// DownloadFile downloads the object from offset. Crc32c of the whole object is verified by the sdk, and the one
+// of range download is verified by the client instead.
+func (g *gcsFs) DownloadFile(ctx context.Context, writer io.Writer, path string, auth, params map[string]string) (FileTask, error) {
+	gcsCtx, err := newGcsContext(ctx, auth, params)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := rangeOffset(params)
+	if err != nil {
+		return nil, err
+	}
+	ft := newFileTask(ctx)
+	go func() {
+		defer gcsCtx.client.Close()
+		r, err := gcsCtx.object(path).NewRangeReader(ctx, offset, -1)
+		if err != nil {
+			ft.complete(fmt.Errorf("failed to get object: %w", gcsCtx.wrapError(err)))
+			return
+		}
+		defer r.Close()
+		if gcsCtx.writeLen {
+			bytesCount := r.Remain()
+			if bytesCount < 0 {
+				ft.complete(errors.New("failed to get object size"))
+				return
+			}
+			polarxIo.WriteUint64(writer, uint64(bytesCount))
+			_, err = io.CopyN(writer, r, bytesCount)
+		} else {
+			_, err = io.Copy(writer, r)
+		}
+		if err != nil {
+			ft.complete(fmt.Errorf("failed to copy content: %w", err))
+			return
+		}
+		ft.complete(nil)
+	}()
+	return ft, nil
+}
+
+func (g *gcsFs) ListFiles(ctx context.Context, writer io.Writer, path string, auth, params map[string]string) (FileTask, error) {
+	gcsCtx, err := newGcsContext(ctx, auth, params)
+	if err != nil {
+		return nil, err
+	}
+	ft := newFileTask(ctx)
+	go func() {
+		defer gcsCtx.client.Close()
+		entryNames := make([]string, 0)
+		it := gcsCtx.client.Bucket(gcsCtx.bucket).Objects(ctx, &storage.Query{Prefix: path, Delimiter: "/"})
+		for {
+			object, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				ft.complete(fmt.Errorf("failed to list gcs objects in path %s: %w", path, gcsCtx.wrapError(err)))
+				return
+			}
+			if object.Prefix != "" { // subdirectory
+				entryNames = append(entryNames, polarxPath.GetBaseNameFromPath(object.Prefix))
+			} else { // file
+				entryNames = append(entryNames, polarxPath.GetBaseNameFromPath(object.Name))
+			}
+		}
+		// parse entry slice and send response
+		encodedEntryNames, err := json.Marshal(entryNames)
+		if err != nil {
+			ft.complete(fmt.Errorf("failed to encode entry name slice,: %w", err))
+			return
+		}
+		if gcsCtx.writeLen {
+			bytesCount := int64(len(encodedEntryNames))
+			err := polarxIo.WriteUint64(writer, uint64(bytesCount))
+			if err != nil {
+				ft.complete(fmt.Errorf("failed to send content bytes count: %w", err))
+				return
+			}
+			_, err = io.CopyN(writer, bytes.NewReader(encodedEntryNames), bytesCount)
+		} else {
+			_, err = io.Copy(writer, bytes.NewReader(encodedEntryNames))
+		}
+		if err != nil {
+			ft.complete(fmt.Errorf("failed to copy content: %w", err))
+			return
+		}
+		ft.complete(nil)
+	}()
+	return ft, nil
+}
+
+func (g *gcsFs) ListAllFiles(ctx context.Context, path string, auth, params map[string]string) (FileTask, error) {
+	gcsCtx, err := newGcsContext(ctx, auth, params)
+	if err != nil {
+		return nil, err
+	}
+	ft := newFileTask(ctx)
+	go func() {
+		defer gcsCtx.client.Close()
+		err := g.listObjectsBeforeDeadline(gcsCtx, path, func(objs []string) error {
+			if val, ok := ctx.Value(common.AffectedFiles).(*[]string); ok {
+				*val = append(*val, objs...)
+			}
+			return nil
+		})
+		ft.complete(err)
+	}()
+	return ft, nil
+}
@@ -11,10 +11,13 @@ import (
 	"github.com/eapache/queue"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
 	"github.com/minio/minio-go/v7/pkg/tags"
 	"io"
+	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -44,6 +47,21 @@ type minioContext struct {
 	useTmpFile       bool
 	deadline         int64
 	bucketLookupType minio.BucketLookupType
+	tags             map[string]string
+	sse              encrypt.ServerSide
+	transport        http.RoundTripper
+}
+
+// minioServerSideEncryption returns the server-side encryption of minio specified by params, or nil if not required.
+func minioServerSideEncryption(params map[string]string) (encrypt.ServerSide, error) {
+	sse, err := objectServerSideEncryption(params)
+	if err != nil || sse == nil {
+		return nil, err
+	}
+	if sse.algorithm == sseAlgorithmKMS {
+		return encrypt.NewSSEKMS(sse.kmsKeyId, nil)
+	}
+	return encrypt.NewSSE(), nil
 }
 
 func bucketLookupType2string(lookupType minio.BucketLookupType) string {
@@ -113,6 +131,20 @@ func newMinioContext(ctx context.Context, auth, params map[string]string) (*mini
 		deadline = parsedDeadline
 	}
 	var bucketLookupType minio.BucketLookupType = string2bucketLookupType(params["bucket_lookup_type"])
+	sse, err := minioServerSideEncryption(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// minio honors proxy environment variables with its default transport
+	var transport http.RoundTripper
+	proxyTransport, err := newProxyTransport(auth)
+	if err != nil {
+		return nil, err
+	}
+	if proxyTransport != nil {
+		transport = proxyTransport
+	}
 
 	minioCtx := &minioContext{
 		ctx:              ctx,
@@ -126,6 +158,9 @@ func newMinioContext(ctx context.Context, auth, params map[string]string) (*mini
 		useTmpFile:       useTmpFile,
 		deadline:         deadline,
 		bucketLookupType: bucketLookupType,
+		tags:             objectTags(params),
+		sse:              sse,
+		transport:        transport,
 	}
 
 	if t, ok := params["retention-time"]; ok {
@@ -146,6 +181,7 @@ func (m *minioFs) newClient(minioCtx *minioContext) (*minio.Client, error) {
 		Creds:        credentials.NewStaticV4(minioCtx.accessKey, minioCtx.secretKey, ""),
 		Secure:       minioCtx.useSSL,
 		BucketLookup: minioCtx.bucketLookupType,
+		Transport:    minioCtx.transport,
 	})
 }
 func (m *minioFs) newCore(minioCtx *minioContext) (*minio.Core, error) {
@@ -153,6 +189,7 @@ func (m *minioFs) newCore(minioCtx *minioContext) (*minio.Core, error) {
 		Creds:        credentials.NewStaticV4(minioCtx.accessKey, minioCtx.secretKey, ""),
 		Secure:       minioCtx.useSSL,
 		BucketLookup: minioCtx.bucketLookupType,
+		Transport:    minioCtx.transport,
 	})
 }
 
@@ -254,6 +291,14 @@ func (m *minioFs) UploadFile(ctx context.Context, reader io.Reader, path string,
 	if val > 0 && val < limitReaderSize {
 		limitReaderSize = val
 	}
+	concurrency := uploadConcurrency(params)
+	if concurrency > 1 {
+		// part size bounds memory usage of concurrent upload
+		val = uploadPartSize(params, DefaultUploadPartSize)
+		if val < limitReaderSize {
+			limitReaderSize = val
+		}
+	}
 	fmt.Printf("UploadFile: limitReaderSize = %d, path = %s\n", limitReaderSize, path)
 
 	client, err := m.newCore(minioCtx)
@@ -268,6 +313,8 @@ func (m *minioFs) UploadFile(ctx context.Context, reader io.Reader, path string,
 		if minioCtx.retentionTime > 0 {
 			opts = minio.PutObjectOptions{RetainUntilDate: time.Now().Add(minioCtx.retentionTime)}
 		}
+		opts.UserTags = minioCtx.tags
+		opts.ServerSideEncryption = minioCtx.sse
 		if err != nil {
 			ft.complete(err)
 			return
@@ -314,23 +361,48 @@ func (m *minioFs) UploadFile(ctx context.Context, reader io.Reader, path string,
 		}()
 		var uploadedLen int64
 		partsInfo := make(map[int]minio.ObjectPart)
-		emptyBytes := make([]byte, 0)
-		for {
-			_, err := pipeReader.Read(emptyBytes)
+		if concurrency > 1 {
+			var lock sync.Mutex
+			partCount, err := concurrentPartUpload(pipeReader, limitReaderSize, concurrency, func(partNumber int, data []byte) error {
+				uploadPart, err := client.PutObjectPart(ctx, minioCtx.bucket, path, uploadID, partNumber, bytes.NewReader(data), int64(len(data)), "", "", nil)
+				if err != nil {
+					if errResponse, ok := err.(minio.ErrorResponse); ok {
+						fmt.Printf("PutObjectPart Error: %s, bucketLookupType=%s, bucket=%s, path=%s, uploadID=%s, partIndex=%d, limitReaderSize=%d, remoteRequestID=%s\n",
+							err, bucketLookupType2string(minioCtx.bucketLookupType), minioCtx.bucket, path, uploadID, partNumber, limitReaderSize, errResponse.RequestID)
+					}
+					return err
+				}
+				lock.Lock()
+				defer lock.Unlock()
+				partsInfo[partNumber] = uploadPart
+				return nil
+			}, func() error {
+				return client.AbortMultipartUpload(ctx, minioCtx.bucket, path, uploadID)
+			})
 			if err != nil {
-				break
-			}
-			limitedReader := io.LimitReader(pipeReader, limitReaderSize)
-			uploadPart, err := client.PutObjectPart(ctx, minioCtx.bucket, path, uploadID, partIndex, limitedReader, limitReaderSize, "", "", nil)
-			if errResponse, ok := err.(minio.ErrorResponse); ok {
-				fmt.Printf("PutObjectPart Error: %s, bucketLookupType=%s, bucket=%s, path=%s, uploadID=%s, partIndex=%d, limitReaderSize=%d, remoteRequestID=%s\n",
-					err, bucketLookupType2string(minioCtx.bucketLookupType), minioCtx.bucket, path, uploadID, partIndex, limitReaderSize, errResponse.RequestID)
+				ft.complete(err)
+				return
 			}
-			partsInfo[partIndex] = uploadPart
-			partIndex++
-			uploadedLen += limitReaderSize
-			if err != nil {
-				break
+			partIndex = partCount + 1
+		} else {
+			emptyBytes := make([]byte, 0)
+			for {
+				_, err := pipeReader.Read(emptyBytes)
+				if err != nil {
+					break
+				}
+				limitedReader := io.LimitReader(pipeReader, limitReaderSize)
+				uploadPart, err := client.PutObjectPart(ctx, minioCtx.bucket, path, uploadID, partIndex, limitedReader, limitReaderSize, "", "", nil)
+				if errResponse, ok := err.(minio.ErrorResponse); ok {
+					fmt.Printf("PutObjectPart Error: %s, bucketLookupType=%s, bucket=%s, path=%s, uploadID=%s, partIndex=%d, limitReaderSize=%d, remoteRequestID=%s\n",
+						err, bucketLookupType2string(minioCtx.bucketLookupType), minioCtx.bucket, path, uploadID, partIndex, limitReaderSize, errResponse.RequestID)
+				}
+				partsInfo[partIndex] = uploadPart
+				partIndex++
+				uploadedLen += limitReaderSize
+				if err != nil {
+					break
+				}
 			}
 		}
 		if len(partsInfo) > 0 {
@@ -405,8 +477,11 @@ func (m *minioFs) UploadFile(ctx context.Context, reader io.Reader, path string,
 
 func SetMinioTags(client *minio.Core, ctx context.Context, minioCtx *minioContext, objectName string, actualSize int64) {
 	tagMap := make(map[string]string)
-	tagMap["uploader"] = "hpfs"
-	tagMap["size"] = strconv.FormatInt(actualSize, 10)
+	for k, v := range minioCtx.tags {
+		tagMap[k] = v
+	}
+	tagMap[tagUploader] = "hpfs"
+	tagMap[tagSize] = strconv.FormatInt(actualSize, 10)
 	tagging, _ := tags.NewTags(tagMap, true)
 	client.PutObjectTagging(ctx, minioCtx.bucket, objectName, tagging, minio.PutObjectTaggingOptions{})
 }
@@ -450,6 +525,10 @@ func (m *minioFs) DownloadFile(ctx context.Context, writer io.Writer, path strin
 	if err != nil {
 		return nil, fmt.Errorf("failed to create minio client: %w", err)
 	}
+	offset, err := rangeOffset(params)
+	if err != nil {
+		return nil, err
+	}
 	ft := newFileTask(ctx)
 	go func() {
 		var bytesCount int64
@@ -464,9 +543,17 @@ func (m *minioFs) DownloadFile(ctx context.Context, writer io.Writer, path strin
 			if actualSize != -1 {
 				bytesCount = actualSize
 			}
+			bytesCount -= offset
 			polarxIo.WriteUint64(writer, uint64(bytesCount))
 		}
-		r, err := client.GetObject(ctx, minioCtx.bucket, path, minio.GetObjectOptions{})
+		opts := minio.GetObjectOptions{}
+		if offset > 0 {
+			if err := opts.SetRange(offset, 0); err != nil {
+				ft.complete(fmt.Errorf("failed to set range: %w", err))
+				return
+			}
+		}
+		r, err := client.GetObject(ctx, minioCtx.bucket, path, opts)
 		if err != nil {
 			ft.complete(fmt.Errorf("failed to get object: %w", err))
 			return
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+const (
+	// DefaultUploadPartSize is the part size of concurrent upload if not specified
+	DefaultUploadPartSize = 1 << 20 * 64 //64MB
+)
+
+// uploadConcurrency returns the concurrency of part upload specified by param "upload_concurrency",
+// 1 if not specified or invalid.
+func uploadConcurrency(params map[string]string) int {
+	concurrency, err := strconv.Atoi(params["upload_concurrency"])
+	if err != nil || concurrency < 1 {
+		return 1
+	}
+	return concurrency
+}
+
+// uploadPartSize returns the part size specified by param "upload_part_size", or defaultSize if not specified
+// or invalid.
+func uploadPartSize(params map[string]string, defaultSize int64) int64 {
+	partSize, err := strconv.ParseInt(params["upload_part_size"], 10, 64)
+	if err != nil || partSize <= 0 {
+		return defaultSize
+	}
+	return partSize
+}
+
+// concurrentPartUpload reads parts of partSize from reader one after another, and uploads them by at most
+// concurrency goroutines. Parts are numbered from 1 in the order of the stream, so that they can be reassembled
+// in order when completing the upload. Each part in flight holds a buffer of partSize, which bounds memory usage
+// to concurrency * partSize. It returns the number of parts read, and stops reading on the first failure, in which
+// case abort is called once all the parts in flight are done, so that no part is left behind the aborted upload.
+func concurrentPartUpload(reader io.Reader, partSize int64, concurrency int,
+	uploadPart func(partNumber int, data []byte) error, abort func() error) (int, error) {
+	partCount, err := doConcurrentPartUpload(reader, partSize, concurrency, uploadPart)
+	if err != nil {
+		if abortErr := abort(); abortErr != nil {
+			return partCount, fmt.Errorf("%w, and failed to abort upload: %v", err, abortErr)
+		}
+	}
+	return partCount, err
+}
+
+func doConcurrentPartUpload(reader io.Reader, partSize int64, concurrency int,
+	uploadPart func(partNumber int, data []byte) error) (int, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	// buffers are allocated lazily and reused once part uploaded
+	buffers := make(chan []byte, concurrency)
+	for i := 0; i < concurrency; i++ {
+		buffers <- nil
+	}
+	var wg sync.WaitGroup
+	var once sync.Once
+	var uploadErr error
+	failed := make(chan struct{})
+
+	partNumber := 0
+	for {
+		var buf []byte
+		select {
+		case <-failed:
+			wg.Wait()
+			return partNumber, uploadErr
+		case buf = <-buffers:
+		}
+		if buf == nil {
+			buf = make([]byte, partSize)
+		}
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 {
+			partNumber++
+			wg.Add(1)
+			go func(partNumber int, data []byte) {
+				defer wg.Done()
+				if err := uploadPart(partNumber, data); err != nil {
+					once.Do(func() {
+						uploadErr = fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+						close(failed)
+					})
+				}
+				buffers <- data[:cap(data)]
+			}(partNumber, buf[:n])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			wg.Wait()
+			return partNumber, fmt.Errorf("failed to read part %d: %w", partNumber+1, err)
+		}
+	}
+	wg.Wait()
+	return partNumber, uploadErr
+}
@@ -0,0 +1,87 @@
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentPartUpload(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+	var lock sync.Mutex
+	parts := make(map[int][]byte)
+	var inFlight, maxInFlight int32
+	partCount, err := concurrentPartUpload(strings.NewReader(content), 7, 3, func(partNumber int, data []byte) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		lock.Lock()
+		if n > maxInFlight {
+			maxInFlight = n
+		}
+		parts[partNumber] = append([]byte{}, data...)
+		lock.Unlock()
+		time.Sleep(time.Millisecond)
+		return nil
+	}, func() error {
+		t.Fatal("upload aborted unexpectedly")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partCount != 15 || len(parts) != 15 {
+		t.Fatalf("expect 15 parts, actual %d parts uploaded of %d", len(parts), partCount)
+	}
+	if maxInFlight > 3 {
+		t.Fatalf("expect at most 3 parts in flight, actual %d", maxInFlight)
+	}
+	var buf bytes.Buffer
+	for i := 1; i <= partCount; i++ {
+		buf.Write(parts[i])
+	}
+	if buf.String() != content {
+		t.Fatalf("content mismatch after reassembly: %s", buf.String())
+	}
+}
+
+func TestConcurrentPartUploadFailed(t *testing.T) {
+	errUpload := errors.New("upload failed")
+	var inFlight, aborted int32
+	_, err := concurrentPartUpload(strings.NewReader(strings.Repeat("0", 100)), 10, 2, func(partNumber int, data []byte) error {
+		atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		time.Sleep(time.Millisecond)
+		if partNumber == 3 {
+			return errUpload
+		}
+		return nil
+	}, func() error {
+		if n := atomic.LoadInt32(&inFlight); n != 0 {
+			t.Errorf("expect no part in flight when aborting, actual %d", n)
+		}
+		atomic.AddInt32(&aborted, 1)
+		return nil
+	})
+	if !errors.Is(err, errUpload) {
+		t.Fatalf("expect upload failure, actual %v", err)
+	}
+	if aborted != 1 {
+		t.Fatalf("expect upload aborted once, actual %d", aborted)
+	}
+}
+
+func TestConcurrentPartUploadAbortFailed(t *testing.T) {
+	errUpload := errors.New("upload failed")
+	_, err := concurrentPartUpload(strings.NewReader(strings.Repeat("0", 100)), 10, 2, func(partNumber int, data []byte) error {
+		return errUpload
+	}, func() error {
+		return errors.New("abort failed")
+	})
+	if !errors.Is(err, errUpload) || !strings.Contains(err.Error(), "abort failed") {
+		t.Fatalf("expect upload and abort failure, actual %v", err)
+	}
+}
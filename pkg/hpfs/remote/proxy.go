@@ -0,0 +1,77 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// proxyURL returns the proxy specified by auth "proxy", nil if not specified.
+func proxyURL(auth map[string]string) (*url.URL, error) {
+	proxy := auth["proxy"]
+	if proxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	return u, nil
+}
+
+// newProxyTransport returns the transport reaching storage via proxy specified by auth, nil if not specified.
+func newProxyTransport(auth map[string]string) (*http.Transport, error) {
+	u, err := proxyURL(auth)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(u)
+	return transport, nil
+}
+
+// newProxyHttpClient returns the http client reaching storage via proxy specified by auth, the default client,
+// which honors proxy environment variables, is returned if not specified.
+func newProxyHttpClient(auth map[string]string) (*http.Client, error) {
+	transport, err := newProxyTransport(auth)
+	if err != nil {
+		return nil, err
+	}
+	if transport == nil {
+		return http.DefaultClient, nil
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// ossProxyOptions returns options of oss client reaching storage via proxy specified by auth.
+func ossProxyOptions(auth map[string]string) ([]oss.ClientOption, error) {
+	u, err := proxyURL(auth)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	if u.User == nil {
+		return []oss.ClientOption{oss.Proxy(u.String())}, nil
+	}
+	password, _ := u.User.Password()
+	host := *u
+	host.User = nil
+	return []oss.ClientOption{oss.AuthProxy(host.String(), u.User.Username(), password)}, nil
+}
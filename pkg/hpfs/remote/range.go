@@ -0,0 +1,40 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// rangeOffset returns the offset where download starts specified by param "range_offset", 0 if not specified.
+func rangeOffset(params map[string]string) (int64, error) {
+	val, ok := params["range_offset"]
+	if !ok || val == "" {
+		return 0, nil
+	}
+	offset, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid range offset: %s", val)
+	}
+	return offset, nil
+}
+
+// rangeHeader returns the value of http header Range requesting content from offset to the end.
+func rangeHeader(offset int64) string {
+	return "bytes=" + strconv.FormatInt(offset, 10) + "-"
+}
@@ -200,6 +200,11 @@ func (s *sftpFs) DownloadFile(ctx context.Context, writer io.Writer, path string
 		return nil, err
 	}
 
+	offset, err := rangeOffset(params)
+	if err != nil {
+		return nil, err
+	}
+
 	conn, err := s.newSshConn(sftpCtx)
 	if err != nil {
 		return nil, fmt.Errorf("ssh connection failure: %w", err)
@@ -221,6 +226,12 @@ func (s *sftpFs) DownloadFile(ctx context.Context, writer io.Writer, path string
 			ft.complete(fmt.Errorf("failed to open remote file: %w", err))
 			return
 		}
+		if offset > 0 {
+			if _, err := f.Seek(offset, io.SeekStart); err != nil {
+				ft.complete(fmt.Errorf("failed to seek remote file: %w", err))
+				return
+			}
+		}
 
 		if _, err := io.Copy(writer, f); err != nil {
 			ft.complete(fmt.Errorf("failed to copy file: %w", err))
@@ -0,0 +1,44 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import "fmt"
+
+// Algorithms of server-side encryption, specified by param "sse_algorithm".
+const (
+	sseAlgorithmAES256 = "AES256"
+	sseAlgorithmKMS    = "KMS"
+)
+
+// serverSideEncryption is the server-side encryption requested on uploaded objects.
+type serverSideEncryption struct {
+	algorithm string
+	kmsKeyId  string
+}
+
+// objectServerSideEncryption returns the server-side encryption specified by params "sse_algorithm" and
+// "sse_kms_key_id", or nil if not required.
+func objectServerSideEncryption(params map[string]string) (*serverSideEncryption, error) {
+	switch algorithm := params["sse_algorithm"]; algorithm {
+	case "":
+		return nil, nil
+	case sseAlgorithmAES256, sseAlgorithmKMS:
+		return &serverSideEncryption{algorithm: algorithm, kmsKeyId: params["sse_kms_key_id"]}, nil
+	default:
+		return nil, fmt.Errorf("unsupported server-side encryption algorithm: %s", algorithm)
+	}
+}
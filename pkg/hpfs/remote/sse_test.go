@@ -0,0 +1,23 @@
+package remote
+
+import "testing"
+
+func TestObjectServerSideEncryption(t *testing.T) {
+	sse, err := objectServerSideEncryption(map[string]string{"sse_algorithm": "KMS", "sse_kms_key_id": "key-123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *sse != (serverSideEncryption{algorithm: sseAlgorithmKMS, kmsKeyId: "key-123"}) {
+		t.Fatalf("unexpected server-side encryption: %+v", *sse)
+	}
+	if opts := ossServerSideEncryptionOptions(sse); len(opts) != 2 {
+		t.Fatalf("expect algorithm and key id options, actual %d", len(opts))
+	}
+
+	if sse, err := objectServerSideEncryption(map[string]string{}); err != nil || sse != nil {
+		t.Fatalf("expect no server-side encryption, actual %v, %v", sse, err)
+	}
+	if _, err := objectServerSideEncryption(map[string]string{"sse_algorithm": "DES"}); err == nil {
+		t.Fatal("expect unsupported algorithm rejected")
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"net/url"
+	"sort"
+)
+
+// Keys of tags set by hpfs on uploaded objects, which can not be overridden by object tags from clients.
+const (
+	tagUploader = "uploader"
+	tagSize     = "size"
+)
+
+// objectTags returns the tags to attach to uploaded objects specified by param "tags", which is encoded as
+// url query, e.g. "team=db&cost-center=cc01". Tags reserved by hpfs and unparsable tags are ignored.
+func objectTags(params map[string]string) map[string]string {
+	values, err := url.ParseQuery(params["tags"])
+	if err != nil || len(values) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(values))
+	for k, v := range values {
+		if k == "" || k == tagUploader || k == tagSize {
+			continue
+		}
+		tags[k] = v[0]
+	}
+	return tags
+}
+
+// sortedTagKeys returns keys of tags in order, so that tags are always sent in the same order.
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
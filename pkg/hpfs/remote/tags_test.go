@@ -0,0 +1,24 @@
+package remote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestObjectTags(t *testing.T) {
+	tags := objectTags(map[string]string{"tags": "cost-center=cc%2C01&size=1&team=db&uploader=me"})
+	expected := map[string]string{"cost-center": "cc,01", "team": "db"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("expect tags %v, actual %v", expected, tags)
+	}
+	if keys := sortedTagKeys(tags); !reflect.DeepEqual(keys, []string{"cost-center", "team"}) {
+		t.Fatalf("expect sorted keys, actual %v", keys)
+	}
+
+	if tags := objectTags(map[string]string{}); tags != nil {
+		t.Fatalf("expect no tags, actual %v", tags)
+	}
+	if tags := objectTags(map[string]string{"tags": "team=%zz"}); tags != nil {
+		t.Fatalf("expect unparsable tags ignored, actual %v", tags)
+	}
+}
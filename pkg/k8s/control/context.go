@@ -28,6 +28,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
@@ -35,6 +36,7 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
@@ -65,6 +67,9 @@ func (opts *ExecOptions) setDefaults() {
 	//}
 }
 
+// CommandExecutor executes the command in the container of pod.
+type CommandExecutor func(pod *corev1.Pod, container string, command []string, opts ExecOptions) error
+
 type ReconcileRemoteCommandHelper interface {
 	ExecuteCommandOn(pod *corev1.Pod, container string, command []string, opts ExecOptions) error
 }
@@ -126,6 +131,7 @@ type ReconcileContext interface {
 
 type BaseReconcileContext struct {
 	client     client.Client
+	apiReader  client.Reader
 	restConfig *rest.Config
 	clientSet  *kubernetes.Clientset
 	scheme     *runtime.Scheme
@@ -135,6 +141,8 @@ type BaseReconcileContext struct {
 
 	forceRequeueAfter time.Duration
 
+	commandExecutor CommandExecutor
+
 	customResourceDefinitions *apiextensionsv1.CustomResourceDefinitionList
 }
 
@@ -150,6 +158,10 @@ func (rc *BaseReconcileContext) ExecuteCommandOn(pod *corev1.Pod, container stri
 	// Set defaults.
 	opts.setDefaults()
 
+	if rc.commandExecutor != nil {
+		return rc.commandExecutor(pod, container, command, opts)
+	}
+
 	logger := opts.Logger
 	logger.Info("Executing command", "pod", pod.Name, "container", container, "command", command, "timeout", opts.Timeout)
 
@@ -190,6 +202,42 @@ func (rc *BaseReconcileContext) ExecuteCommandOn(pod *corev1.Pod, container stri
 	})
 }
 
+// SetCommandExecutor overrides the execution of commands on pods, e.g. with a fake one in tests.
+func (rc *BaseReconcileContext) SetCommandExecutor(executor CommandExecutor) {
+	rc.commandExecutor = executor
+}
+
+// RecordEvent creates an event for the object. Events are informative, so failures are ignored.
+func (rc *BaseReconcileContext) RecordEvent(obj client.Object, eventType, reason, message string) {
+	gvk, err := apiutil.GVKForObject(obj, rc.scheme)
+	if err != nil {
+		return
+	}
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: obj.GetName() + "-",
+			Namespace:    obj.GetNamespace(),
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion:      gvk.GroupVersion().String(),
+			Kind:            gvk.Kind,
+			Namespace:       obj.GetNamespace(),
+			Name:            obj.GetName(),
+			UID:             obj.GetUID(),
+			ResourceVersion: obj.GetResourceVersion(),
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: "polardbx-operator"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	_ = rc.client.Create(rc.context, event)
+}
+
 func (rc *BaseReconcileContext) Name() string {
 	return rc.request.Name
 }
@@ -243,6 +291,19 @@ func (rc *BaseReconcileContext) Client() client.Client {
 	return rc.client
 }
 
+// APIReader returns a reader querying the api server directly instead of the cache, or the client if not set.
+func (rc *BaseReconcileContext) APIReader() client.Reader {
+	if rc.apiReader == nil {
+		return rc.client
+	}
+	return rc.apiReader
+}
+
+// SetAPIReader sets the reader querying the api server directly, e.g. the one of manager.
+func (rc *BaseReconcileContext) SetAPIReader(reader client.Reader) {
+	rc.apiReader = reader
+}
+
 func (rc *BaseReconcileContext) RestConfig() *rest.Config {
 	return rc.restConfig
 }
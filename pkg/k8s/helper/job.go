@@ -19,6 +19,7 @@ package helper
 import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func IsJobCompleted(job *batchv1.Job) bool {
@@ -34,3 +35,45 @@ func IsJobCompleted(job *batchv1.Job) bool {
 
 	return false
 }
+
+func IsJobFailed(job *batchv1.Job) bool {
+	if job == nil {
+		return false
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsJobDeadlineExceeded returns true if the job is failed since it has been active longer than
+// its ActiveDeadlineSeconds.
+func IsJobDeadlineExceeded(job *batchv1.Job) bool {
+	if job == nil {
+		return false
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue &&
+			cond.Reason == "DeadlineExceeded" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JobDeletePropagationOrDefault parses the propagation policy to delete jobs, either "Background" or
+// "Foreground". The default policy is returned if value is empty or unsupported.
+func JobDeletePropagationOrDefault(value string, defaultPolicy metav1.DeletionPropagation) metav1.DeletionPropagation {
+	switch policy := metav1.DeletionPropagation(value); policy {
+	case metav1.DeletePropagationBackground, metav1.DeletePropagationForeground:
+		return policy
+	default:
+		return defaultPolicy
+	}
+}
@@ -18,7 +18,9 @@ package gms
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 
 	"k8s.io/apimachinery/pkg/types"
@@ -257,6 +259,34 @@ type CdcNodeInfo struct {
 	DaemonPort  int32  `json:"daemonPort"`
 }
 
+// CdcState records the position and topology of CDC in metadb.
+type CdcState struct {
+	// Tso is the latest tso of logic meta history of CDC.
+	Tso string `json:"tso,omitempty"`
+
+	// Nodes are the CDC nodes registered.
+	Nodes []CdcNodeInfo `json:"nodes,omitempty"`
+}
+
+// SupportedSchemaVersions are the latest versions of metadb tables which the operator reads and writes,
+// keyed by table name. Metadb of newer versions is not guaranteed to be compatible.
+var SupportedSchemaVersions = map[string]int32{
+	"user_priv": 10,
+}
+
+// UnsupportedSchemaVersions returns the tables of versions newer than supported, sorted and formatted
+// as "<table>: <version> > <supported version>".
+func UnsupportedSchemaVersions(versions map[string]int32) []string {
+	unsupported := make([]string, 0)
+	for table, version := range versions {
+		if supported, ok := SupportedSchemaVersions[table]; ok && version > supported {
+			unsupported = append(unsupported, fmt.Sprintf("%s: %d > %d", table, version, supported))
+		}
+	}
+	sort.Strings(unsupported)
+	return unsupported
+}
+
 // Manager defines a set of methods for manage the PolarDBX cluster.
 type Manager interface {
 	IsMetaDBExisted() (bool, error)
@@ -320,6 +350,12 @@ type Manager interface {
 	// DeleteCdcNodes deletes the specified CDC nodes by setting the metadb.
 	DeleteCdcNodes(cdcNodes ...CdcNodeInfo) error
 
+	// GetCdcState gets the current position and topology of CDC, nil if CDC is not enabled.
+	GetCdcState() (*CdcState, error)
+
+	// GetSchemaVersions gets the versions of metadb tables recorded in schema_change, keyed by table name.
+	GetSchemaVersions() (map[string]int32, error)
+
 	// ListDynamicParams list all dynamic parameters in the cluster.
 	ListDynamicParams() (map[string]string, error)
 
@@ -498,7 +498,8 @@ func (meta *manager) InitializeMetaDBSchema() error {
 	}
 
 	// Insert the version record
-	const schemaChangeInitDml = "insert ignore into `schema_change`(`table_name`, `version`) values('user_priv', 10)"
+	schemaChangeInitDml := fmt.Sprintf("insert ignore into `schema_change`(`table_name`, `version`) values('user_priv', %d)",
+		SupportedSchemaVersions["user_priv"])
 	if _, err := conn.ExecContext(ctx, schemaChangeInitDml); err != nil {
 		return fmt.Errorf("unable to insert schema change init record: %w", err)
 	}
@@ -1319,6 +1320,65 @@ func (meta *manager) DeleteCdcNodes(cdcNodes ...CdcNodeInfo) error {
 	return err
 }
 
+func (meta *manager) GetCdcState() (*CdcState, error) {
+	conn, err := meta.getConnectionForMetaDB(meta.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dbutil.DeferClose(conn)
+
+	var tso sql.NullString
+	//goland:noinspection SqlNoDataSourceInspection,SqlResolve
+	err = conn.QueryRowContext(meta.ctx, "SELECT MAX(tso) FROM binlog_logic_meta_history").Scan(&tso)
+	if err != nil {
+		if dbutil.IsMySQLErrTableNotExists(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	cdcNodes, err := meta.ListCdcNodes()
+	if err != nil {
+		return nil, err
+	}
+	if !tso.Valid && len(cdcNodes) == 0 {
+		return nil, nil
+	}
+	return &CdcState{
+		Tso:   tso.String,
+		Nodes: cdcNodes,
+	}, nil
+}
+
+func (meta *manager) GetSchemaVersions() (map[string]int32, error) {
+	conn, err := meta.getConnectionForMetaDB(meta.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dbutil.DeferClose(conn)
+
+	//goland:noinspection SqlNoDataSourceInspection,SqlResolve
+	rs, err := conn.QueryContext(meta.ctx, "SELECT table_name, version FROM schema_change")
+	if err != nil {
+		if dbutil.IsMySQLErrTableNotExists(err) {
+			return map[string]int32{}, nil
+		}
+		return nil, err
+	}
+	defer dbutil.DeferClose(rs)
+
+	versions := make(map[string]int32)
+	for rs.Next() {
+		var tableName string
+		var version int32
+		if err := rs.Scan(&tableName, &version); err != nil {
+			return nil, err
+		}
+		versions[tableName] = version
+	}
+	return versions, rs.Err()
+}
+
 func (meta *manager) Lock() error {
 	// Generate update statement
 	lockStmt := fmt.Sprintf(`INSERT IGNORE INTO  
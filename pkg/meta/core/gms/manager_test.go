@@ -0,0 +1,31 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gms
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestUnsupportedSchemaVersions(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(UnsupportedSchemaVersions(nil)).To(gomega.BeEmpty())
+	g.Expect(UnsupportedSchemaVersions(map[string]int32{"user_priv": 10, "unknown": 100})).To(gomega.BeEmpty())
+	g.Expect(UnsupportedSchemaVersions(map[string]int32{"user_priv": 9})).To(gomega.BeEmpty())
+	g.Expect(UnsupportedSchemaVersions(map[string]int32{"user_priv": 12})).To(gomega.Equal([]string{"user_priv: 12 > 10"}))
+}
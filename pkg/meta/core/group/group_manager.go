@@ -75,8 +75,9 @@ type DDLPlanStatus struct {
 
 // SlaveStatus describes slave status for xstore follower/logger
 type SlaveStatus struct {
-	SlaveSQLRunning string `json:"slave_sql_running,omitempty"` // Slave_SQL_Running
-	LastError       string `json:"last_error,omitempty"`        // Last_Error
+	SlaveSQLRunning     string `json:"slave_sql_running,omitempty"`     // Slave_SQL_Running
+	LastError           string `json:"last_error,omitempty"`            // Last_Error
+	SecondsBehindMaster *int64 `json:"seconds_behind_master,omitempty"` // Seconds_Behind_Master, nil if unknown
 }
 
 // ClusterStatus describes status of xstore cluster
@@ -95,6 +96,33 @@ type ClusterStatus struct {
 	SendApplied    string `json:"send_applied,omitempty"`    // SEND_APPLIED
 }
 
+// ConsensusLog describes a binlog file of xstore along with the index of its first consensus log entry
+type ConsensusLog struct {
+	LogName       string `json:"log_name,omitempty"`        // Log_name
+	FileSize      int64  `json:"file_size,omitempty"`       // File_size
+	StartLogIndex int64  `json:"start_log_index,omitempty"` // Start_log_index
+}
+
+// ReadLock is the global read lock held on a dedicated connection, along with the binlog position of server
+// captured under the lock
+type ReadLock struct {
+	ctx  context.Context
+	conn *sql.Conn
+
+	BinlogOffset string // File:Position
+	GtidExecuted string // Executed_Gtid_Set
+}
+
+// Release unlocks tables and closes the connection, which releases the lock as well even if unlock fails
+func (l *ReadLock) Release() error {
+	_, err := l.conn.ExecContext(l.ctx, "UNLOCK TABLES")
+	closeErr := l.conn.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
 func (s *DDLPlanStatus) IsSuccess() bool {
 	return strings.ToUpper(s.State) == "SUCCESS"
 }
@@ -126,6 +154,9 @@ type GroupManager interface {
 	DropFileStorage(fileStorageName string) error
 	ShowSlaveStatus() (*SlaveStatus, error)
 	ShowClusterStatus() ([]*ClusterStatus, error)
+	ShowBinaryLogs() ([]string, error)
+	ShowConsensusLogs() ([]*ConsensusLog, error)
+	FlushTablesWithReadLock(timeout time.Duration) (*ReadLock, error)
 }
 
 type groupManager struct {
@@ -933,14 +964,19 @@ func (m *groupManager) ShowSlaveStatus() (*SlaveStatus, error) {
 	}
 
 	status := &SlaveStatus{}
+	var secondsBehindMaster sql.NullInt64
 	dest := map[string]interface{}{
-		"Slave_SQL_Running": &status.SlaveSQLRunning,
-		"Last_Error":        &status.LastError,
+		"Slave_SQL_Running":     &status.SlaveSQLRunning,
+		"Last_Error":            &status.LastError,
+		"Seconds_Behind_Master": &secondsBehindMaster,
 	}
 	err = dbutil.Scan(rs, dest, dbutil.ScanOpt{CaseInsensitive: true})
 	if err != nil {
 		return nil, err
 	}
+	if secondsBehindMaster.Valid {
+		status.SecondsBehindMaster = &secondsBehindMaster.Int64
+	}
 	return status, nil
 }
 
@@ -984,6 +1020,67 @@ func (m *groupManager) ShowClusterStatus() ([]*ClusterStatus, error) {
 	return statusList, nil
 }
 
+// ShowBinaryLogs aims to list binlog files which are still available on the server, from the oldest to the latest
+func (m *groupManager) ShowBinaryLogs() ([]string, error) {
+	conn, err := m.getConn("")
+	if err != nil {
+		return nil, err
+	}
+	defer dbutil.DeferClose(conn)
+
+	rs, err := conn.QueryContext(m.ctx, "SHOW BINARY LOGS")
+	if err != nil {
+		return nil, err
+	}
+	defer dbutil.DeferClose(rs)
+
+	var binlogFiles []string
+	for rs.Next() {
+		var logName string
+		dest := map[string]interface{}{
+			"Log_name": &logName,
+		}
+		err = dbutil.Scan(rs, dest, dbutil.ScanOpt{CaseInsensitive: true})
+		if err != nil {
+			return nil, err
+		}
+		binlogFiles = append(binlogFiles, logName)
+	}
+	return binlogFiles, nil
+}
+
+// ShowConsensusLogs aims to list binlog files available on the server along with their start log indexes,
+// from the oldest to the latest
+func (m *groupManager) ShowConsensusLogs() ([]*ConsensusLog, error) {
+	conn, err := m.getConn("")
+	if err != nil {
+		return nil, err
+	}
+	defer dbutil.DeferClose(conn)
+
+	rs, err := conn.QueryContext(m.ctx, "SHOW CONSENSUS LOGS")
+	if err != nil {
+		return nil, err
+	}
+	defer dbutil.DeferClose(rs)
+
+	var logs []*ConsensusLog
+	for rs.Next() {
+		log := &ConsensusLog{}
+		dest := map[string]interface{}{
+			"Log_name":        &log.LogName,
+			"File_size":       &log.FileSize,
+			"Start_log_index": &log.StartLogIndex,
+		}
+		err = dbutil.Scan(rs, dest, dbutil.ScanOpt{CaseInsensitive: true})
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+	return logs, nil
+}
+
 func NewGroupManagerWithDB(ctx context.Context, db *sql.DB, caseInsensitive bool) GroupManager {
 	if ctx == nil {
 		ctx = context.Background()
@@ -1014,3 +1111,52 @@ func NewGroupManager(ctx context.Context, ds dbutil.MySQLDataSource, caseInsensi
 		caseInsensitive: caseInsensitive,
 	}
 }
+
+// FlushTablesWithReadLock acquires the global read lock on a dedicated connection and captures the binlog position
+// under the lock. Waiting for the lock is bounded by timeout, and the lock must be released by the caller.
+func (m *groupManager) FlushTablesWithReadLock(timeout time.Duration) (*ReadLock, error) {
+	conn, err := m.getConn("")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, timeout)
+	defer cancel()
+
+	lockWaitTimeout := int64(timeout.Seconds())
+	if lockWaitTimeout < 1 {
+		lockWaitTimeout = 1
+	}
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET SESSION lock_wait_timeout = %d", lockWaitTimeout)); err != nil {
+		dbutil.DeferClose(conn)
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "FLUSH TABLES WITH READ LOCK"); err != nil {
+		dbutil.DeferClose(conn)
+		return nil, err
+	}
+	lock := &ReadLock{ctx: m.ctx, conn: conn}
+
+	rs, err := conn.QueryContext(ctx, "SHOW MASTER STATUS")
+	if err != nil {
+		_ = lock.Release()
+		return nil, err
+	}
+	defer dbutil.DeferClose(rs)
+	if !rs.Next() {
+		_ = lock.Release()
+		return nil, errors.New("no rows returned")
+	}
+	var file, position string
+	dest := map[string]interface{}{
+		"File":              &file,
+		"Position":          &position,
+		"Executed_Gtid_Set": &lock.GtidExecuted,
+	}
+	if err := dbutil.Scan(rs, dest, dbutil.ScanOpt{CaseInsensitive: true}); err != nil {
+		_ = lock.Release()
+		return nil, err
+	}
+	lock.BinlogOffset = file + ":" + position
+	return lock, nil
+}
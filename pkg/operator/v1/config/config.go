@@ -22,7 +22,11 @@ import (
 	"time"
 
 	"github.com/distribution/distribution/reference"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
 	"github.com/alibaba/polardbx-operator/pkg/util/defaults"
 )
 
@@ -171,12 +175,13 @@ func (c *schedulerConfig) AllowScheduleToMasterNode() bool {
 }
 
 type clusterConfig struct {
-	OptionEnableExporters                   bool `json:"enable_exporters,omitempty"`
-	OptionEnableAliyunAckResourceController bool `json:"enable_aliyun_ack_resource_controller,omitempty"`
-	OptionEnableDebugModeForComputeNodes    bool `json:"enable_debug_mode_for_compute_nodes,omitempty"`
-	OptionEnableRunModeCheck                bool `json:"enable_run_mode_check,omitempty"`
-	OptionEnablePrivilegedContainer         bool `json:"enable_privileged_container,omitempty"`
-	OptionForceCGroup                       bool `json:"force_cgroup,omitempty"`
+	OptionEnableExporters                   bool   `json:"enable_exporters,omitempty"`
+	OptionEnableAliyunAckResourceController bool   `json:"enable_aliyun_ack_resource_controller,omitempty"`
+	OptionEnableDebugModeForComputeNodes    bool   `json:"enable_debug_mode_for_compute_nodes,omitempty"`
+	OptionEnableRunModeCheck                bool   `json:"enable_run_mode_check,omitempty"`
+	OptionEnablePrivilegedContainer         bool   `json:"enable_privileged_container,omitempty"`
+	OptionForceCGroup                       bool   `json:"force_cgroup,omitempty"`
+	OptionJobDeletePropagation              string `json:"job_delete_propagation,omitempty"`
 }
 
 func (c *clusterConfig) EnableExporters() bool {
@@ -203,12 +208,19 @@ func (c *clusterConfig) ForceCGroup() bool {
 	return c.OptionForceCGroup
 }
 
+func (c *clusterConfig) JobDeletePropagation() metav1.DeletionPropagation {
+	return k8shelper.JobDeletePropagationOrDefault(c.OptionJobDeletePropagation, metav1.DeletePropagationBackground)
+}
+
 type storeConfig struct {
 	EnablePrivilegedContainer bool              `json:"enable_privileged_container,omitempty"`
 	HostPaths                 map[string]string `json:"host_paths,omitempty"`
 	HpfsEndpoint              string            `json:"hpfs_endpoint,omitempty"`
 	FsEndpoint                string            `json:"fs_endpoint,omitempty"`
 	MaxAutoRebuildingCount    string            `json:"max_auto_rebuilding_count,omitempty"`
+	FsConnectAttempts         int               `json:"fs_connect_attempts,omitempty"`
+	FsConnectBackoff          string            `json:"fs_connect_backoff,omitempty"`
+	DataDir                   string            `json:"engine_data_dir,omitempty"`
 }
 
 func (c *storeConfig) GetMaxAutoRebuildingCount() int {
@@ -239,6 +251,10 @@ func (c *storeConfig) HostPathFilestreamVolumeRoot() string {
 	return defaults.NonEmptyStrOrDefault(c.HostPaths["volume_filestream"], "/filestream")
 }
 
+func (c *storeConfig) EngineDataDir() string {
+	return defaults.NonEmptyStrOrDefault(c.DataDir, "/data/mysql")
+}
+
 func (c *storeConfig) HostPathFileServiceEndpoint() string {
 	return c.HpfsEndpoint
 }
@@ -247,6 +263,18 @@ func (c *storeConfig) FilestreamServiceEndpoint() string {
 	return c.FsEndpoint
 }
 
+func (c *storeConfig) FilestreamConnectAttempts() int {
+	if c.FsConnectAttempts <= 0 {
+		return 3
+	}
+	return c.FsConnectAttempts
+}
+
+func (c *storeConfig) FilestreamConnectBackoff() (time.Duration, error) {
+	backoff := defaults.NonEmptyStrOrDefault(c.FsConnectBackoff, "500ms")
+	return time.ParseDuration(backoff)
+}
+
 type securityConfig struct {
 	EncodeKey string `json:"encode_key,omitempty"`
 }
@@ -291,13 +319,123 @@ func (c *nfsConfig) Server() string {
 	return c.NfsServer
 }
 
-type backupConfig struct {
-	CheckBinlogExpiredInterval string `json:"check_binlog_expired_interval,omitempty"`
-	HeartbeatJobNamePrefix     string `json:"heartbeat_job_name_prefix,omitempty"`
-	HeartbeatInterval          string `json:"heartbeat_interval,omitempty"`
-	RestorePodSuffix           string `json:"restore_pod_suffix,omitempty"`
+// backupSinkPolicy maps clusters to a default backup sink, selected by namespace and labels.
+type backupSinkPolicy struct {
+	Namespaces  []string          `json:"namespaces,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	StorageName string            `json:"storage_name,omitempty"`
+	Sink        string            `json:"sink,omitempty"`
+}
+
+func (p *backupSinkPolicy) matches(namespace string, labels map[string]string) bool {
+	if len(p.Namespaces) > 0 {
+		found := false
+		for _, ns := range p.Namespaces {
+			if ns == namespace {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for k, v := range p.Labels {
+		if lv, ok := labels[k]; !ok || lv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// BackupClass bundles storage, retention and schedule of backups, which is referenced by name from backups,
+// backup schedules and clusters instead of repeating them everywhere.
+type BackupClass struct {
+	Name          string `json:"name,omitempty"`
+	StorageName   string `json:"storage_name,omitempty"`
+	Sink          string `json:"sink,omitempty"`
+	RetentionTime string `json:"retention_time,omitempty"`
+	Schedule      string `json:"schedule,omitempty"`
+}
+
+// StorageProvider returns the storage provider of class, or nil if not specified.
+func (c *BackupClass) StorageProvider() *polardbx.BackupStorageProvider {
+	if c.StorageName == "" && c.Sink == "" {
+		return nil
+	}
+	return &polardbx.BackupStorageProvider{
+		StorageName: polardbx.BackupStorage(c.StorageName),
+		Sink:        c.Sink,
+	}
+}
+
+// GetRetentionTime returns the retention time of class, 0 if not specified.
+func (c *BackupClass) GetRetentionTime() (time.Duration, error) {
+	if c.RetentionTime == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(c.RetentionTime)
+}
+
+// FillBackup fills storage provider and retention time of backup from class if not specified.
+func (c *BackupClass) FillBackup(storageProvider *polardbx.BackupStorageProvider, retentionTime *metav1.Duration) error {
+	retention, err := c.GetRetentionTime()
+	if err != nil {
+		return err
+	}
+	if storageProvider.StorageName == "" && storageProvider.Sink == "" {
+		if classProvider := c.StorageProvider(); classProvider != nil {
+			*storageProvider = *classProvider
+		}
+	}
+	if retentionTime.Duration == 0 {
+		retentionTime.Duration = retention
+	}
+	return nil
 }
 
+type backupConfig struct {
+	CheckBinlogExpiredInterval string             `json:"check_binlog_expired_interval,omitempty"`
+	HeartbeatJobNamePrefix     string             `json:"heartbeat_job_name_prefix,omitempty"`
+	HeartbeatInterval          string             `json:"heartbeat_interval,omitempty"`
+	RestorePodSuffix           string             `json:"restore_pod_suffix,omitempty"`
+	SinkPolicies               []backupSinkPolicy `json:"sink_policies,omitempty"`
+	UploadChunkSize            string             `json:"upload_chunk_size,omitempty"`
+	ScheduleMaxJitter          string             `json:"schedule_max_jitter,omitempty"`
+	MetadataUploadMaxAttempts  int32              `json:"metadata_upload_max_attempts,omitempty"`
+	FullBackupJobTimeout       string             `json:"full_backup_job_timeout,omitempty"`
+	CollectJobTimeout          string             `json:"collect_job_timeout,omitempty"`
+	BinlogBackupJobTimeout     string             `json:"binlog_backup_job_timeout,omitempty"`
+	CollectJobParallelism      int32              `json:"collect_job_parallelism,omitempty"`
+	ClockSkewThreshold         string             `json:"clock_skew_threshold,omitempty"`
+	CorrectClockSkew           bool               `json:"correct_clock_skew,omitempty"`
+	ShareXStoreSecret          bool               `json:"share_xstore_secret,omitempty"`
+	PropagatedLabels           []string           `json:"propagated_labels,omitempty"`
+	DiskSpaceSafetyMargin      *int32             `json:"disk_space_safety_margin,omitempty"`
+	StableWaitTimeout          string             `json:"stable_wait_timeout,omitempty"`
+	TargetPodPolicy            string             `json:"target_pod_policy,omitempty"`
+	AuditSink                  string             `json:"audit_sink,omitempty"`
+	EarlyMetadataUpload        bool               `json:"early_metadata_upload,omitempty"`
+	Classes                    []BackupClass      `json:"classes,omitempty"`
+}
+
+// Policies to select the standby pod to perform backup on.
+const (
+	// TargetPodPolicyFirstAvailable selects the first standby pod with a consistent data view.
+	TargetPodPolicyFirstAvailable = "FirstAvailable"
+	// TargetPodPolicyLeastLag selects the standby pod with the least replication lag, which minimizes
+	// binlog to catch up on restore.
+	TargetPodPolicyLeastLag = "LeastLag"
+)
+
+// Sinks to emit audit records of backups to.
+const (
+	// BackupAuditSinkStdout emits audit records to stdout of operator as JSON lines.
+	BackupAuditSinkStdout = "Stdout"
+	// BackupAuditSinkStorage emits audit records as objects in the storage of backups.
+	BackupAuditSinkStorage = "Storage"
+)
+
 func (b *backupConfig) CheckBinlogExpiredFileInterval() (time.Duration, error) {
 	interval := defaults.NonEmptyStrOrDefault(b.CheckBinlogExpiredInterval, "3600s")
 	return time.ParseDuration(interval)
@@ -315,3 +453,120 @@ func (b *backupConfig) GetHeartbeatInterval() (time.Duration, error) {
 func (b *backupConfig) GetRestorePodSuffix() string {
 	return defaults.NonEmptyStrOrDefault(b.RestorePodSuffix, "-cand-0")
 }
+
+func (b *backupConfig) GetUploadChunkSize() (int64, error) {
+	if b.UploadChunkSize == "" {
+		return 0, nil
+	}
+	chunkSize, err := resource.ParseQuantity(b.UploadChunkSize)
+	if err != nil {
+		return 0, err
+	}
+	return chunkSize.Value(), nil
+}
+
+func (b *backupConfig) GetScheduleMaxJitter() (time.Duration, error) {
+	jitter := defaults.NonEmptyStrOrDefault(b.ScheduleMaxJitter, "0s")
+	return time.ParseDuration(jitter)
+}
+
+func (b *backupConfig) GetMetadataUploadMaxAttempts() int32 {
+	if b.MetadataUploadMaxAttempts <= 0 {
+		return 30
+	}
+	return b.MetadataUploadMaxAttempts
+}
+
+func (b *backupConfig) GetFullBackupJobTimeout() (time.Duration, error) {
+	timeout := defaults.NonEmptyStrOrDefault(b.FullBackupJobTimeout, "0s")
+	return time.ParseDuration(timeout)
+}
+
+func (b *backupConfig) GetCollectJobTimeout() (time.Duration, error) {
+	timeout := defaults.NonEmptyStrOrDefault(b.CollectJobTimeout, "2h")
+	return time.ParseDuration(timeout)
+}
+
+func (b *backupConfig) GetBinlogBackupJobTimeout() (time.Duration, error) {
+	timeout := defaults.NonEmptyStrOrDefault(b.BinlogBackupJobTimeout, "0s")
+	return time.ParseDuration(timeout)
+}
+
+func (b *backupConfig) GetCollectJobParallelism() int32 {
+	if b.CollectJobParallelism <= 0 {
+		return 1
+	}
+	return b.CollectJobParallelism
+}
+
+func (b *backupConfig) GetClockSkewThreshold() (time.Duration, error) {
+	threshold := defaults.NonEmptyStrOrDefault(b.ClockSkewThreshold, "5s")
+	return time.ParseDuration(threshold)
+}
+
+func (b *backupConfig) IsClockSkewCorrectionEnabled() bool {
+	return b.CorrectClockSkew
+}
+
+func (b *backupConfig) IsXStoreSecretSharingEnabled() bool {
+	return b.ShareXStoreSecret
+}
+
+func (b *backupConfig) GetPropagatedLabels() []string {
+	return b.PropagatedLabels
+}
+
+func (b *backupConfig) GetDiskSpaceSafetyMargin() int32 {
+	if b.DiskSpaceSafetyMargin == nil || *b.DiskSpaceSafetyMargin < 0 {
+		return 20
+	}
+	return *b.DiskSpaceSafetyMargin
+}
+
+func (b *backupConfig) GetStableWaitTimeout() (time.Duration, error) {
+	timeout := defaults.NonEmptyStrOrDefault(b.StableWaitTimeout, "30m")
+	return time.ParseDuration(timeout)
+}
+
+func (b *backupConfig) GetTargetPodPolicy() string {
+	if b.TargetPodPolicy == TargetPodPolicyLeastLag {
+		return TargetPodPolicyLeastLag
+	}
+	return TargetPodPolicyFirstAvailable
+}
+
+func (b *backupConfig) GetAuditSink() string {
+	if b.AuditSink == BackupAuditSinkStdout || b.AuditSink == BackupAuditSinkStorage {
+		return b.AuditSink
+	}
+	return ""
+}
+
+func (b *backupConfig) IsEarlyMetadataUploadEnabled() bool {
+	return b.EarlyMetadataUpload
+}
+
+func (b *backupConfig) GetBackupClass(name string) *BackupClass {
+	if name == "" {
+		return nil
+	}
+	for i := range b.Classes {
+		if b.Classes[i].Name == name {
+			return &b.Classes[i]
+		}
+	}
+	return nil
+}
+
+func (b *backupConfig) DefaultStorageProvider(namespace string, labels map[string]string) *polardbx.BackupStorageProvider {
+	for i := range b.SinkPolicies {
+		policy := &b.SinkPolicies[i]
+		if policy.matches(namespace, labels) {
+			return &polardbx.BackupStorageProvider{
+				StorageName: polardbx.BackupStorage(policy.StorageName),
+				Sink:        policy.Sink,
+			}
+		}
+	}
+	return nil
+}
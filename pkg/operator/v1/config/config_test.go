@@ -0,0 +1,85 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+)
+
+func TestStoreConfigEngineDataDir(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	var c config
+	g.Expect(yaml.NewYAMLOrJSONDecoder(strings.NewReader("store: {}\n"), 512).Decode(&c)).To(gomega.Succeed())
+	g.Expect(c.Store().EngineDataDir()).To(gomega.Equal("/data/mysql"))
+
+	c = config{}
+	g.Expect(yaml.NewYAMLOrJSONDecoder(strings.NewReader("store:\n  engine_data_dir: /var/lib/mysql\n"), 512).
+		Decode(&c)).To(gomega.Succeed())
+	g.Expect(c.Store().EngineDataDir()).To(gomega.Equal("/var/lib/mysql"))
+}
+
+func TestBackupConfigGetBackupClass(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	var c config
+	g.Expect(yaml.NewYAMLOrJSONDecoder(strings.NewReader(`backup:
+  classes:
+  - name: daily-cheap
+    storage_name: oss
+    sink: cold
+    retention_time: 168h
+    schedule: "0 2 * * *"
+  - name: hourly-replicated
+    schedule: "0 * * * *"
+`), 512).Decode(&c)).To(gomega.Succeed())
+
+	class := c.Backup().GetBackupClass("daily-cheap")
+	g.Expect(class).NotTo(gomega.BeNil())
+	g.Expect(class.StorageProvider()).To(gomega.Equal(&polardbx.BackupStorageProvider{
+		StorageName: polardbx.OSS,
+		Sink:        "cold",
+	}))
+	g.Expect(class.GetRetentionTime()).To(gomega.Equal(7 * 24 * time.Hour))
+	g.Expect(class.Schedule).To(gomega.Equal("0 2 * * *"))
+
+	// only fields not specified are filled
+	storageProvider, retentionTime := polardbx.BackupStorageProvider{}, metav1.Duration{Duration: time.Hour}
+	g.Expect(class.FillBackup(&storageProvider, &retentionTime)).To(gomega.Succeed())
+	g.Expect(storageProvider).To(gomega.Equal(*class.StorageProvider()))
+	g.Expect(retentionTime.Duration).To(gomega.Equal(time.Hour))
+	storageProvider, retentionTime = polardbx.BackupStorageProvider{StorageName: polardbx.SFTP, Sink: "hot"}, metav1.Duration{}
+	g.Expect(class.FillBackup(&storageProvider, &retentionTime)).To(gomega.Succeed())
+	g.Expect(storageProvider.Sink).To(gomega.Equal("hot"))
+	g.Expect(retentionTime.Duration).To(gomega.Equal(7 * 24 * time.Hour))
+
+	class = c.Backup().GetBackupClass("hourly-replicated")
+	g.Expect(class).NotTo(gomega.BeNil())
+	g.Expect(class.StorageProvider()).To(gomega.BeNil())
+	g.Expect(class.GetRetentionTime()).To(gomega.BeZero())
+
+	g.Expect(c.Backup().GetBackupClass("weekly")).To(gomega.BeNil())
+	g.Expect(c.Backup().GetBackupClass("")).To(gomega.BeNil())
+}
@@ -16,7 +16,13 @@ limitations under the License.
 
 package config
 
-import "time"
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+)
 
 type Config interface {
 	Images() ImagesConfig
@@ -51,6 +57,9 @@ type ClusterConfig interface {
 	EnableRunModeCheck() bool
 	ContainerPrivileged() bool
 	ForceCGroup() bool
+	// JobDeletePropagation returns the propagation policy to delete jobs, Background by default. With Foreground,
+	// reconcile waits until pods of jobs are deleted.
+	JobDeletePropagation() metav1.DeletionPropagation
 }
 
 type StoreConfig interface {
@@ -60,10 +69,18 @@ type StoreConfig interface {
 	HostPathDataVolumeRoot() string
 	HostPathLogVolumeRoot() string
 	HostPathFilestreamVolumeRoot() string
+	// EngineDataDir returns the mount path of data volume in engine container, under which data, logs and
+	// temporary files of engine reside.
+	EngineDataDir() string
 
 	HostPathFileServiceEndpoint() string
 	FilestreamServiceEndpoint() string
 	GetMaxAutoRebuildingCount() int
+	// FilestreamConnectAttempts returns the max attempts to check the filestream service is reachable
+	// before the client is returned, and FilestreamConnectBackoff returns the backoff before the first retry,
+	// which doubles after each retry.
+	FilestreamConnectAttempts() int
+	FilestreamConnectBackoff() (time.Duration, error)
 }
 
 type OssConfig interface {
@@ -83,4 +100,53 @@ type BackupConfig interface {
 	GetHeartbeatJobNamePrefix() string
 	GetHeartbeatInterval() (time.Duration, error)
 	GetRestorePodSuffix() string
+	// GetUploadChunkSize returns block size to chunk full backup uploads at, 0 means chunking disabled. Chunks only
+	// align uploads to fixed block boundaries and record hashes as hints for dedup done by storage, every chunk is
+	// still uploaded since the operator never skips chunks already stored.
+	GetUploadChunkSize() (int64, error)
+	// GetScheduleMaxJitter returns the max delay added to start time of scheduled backups, 0 means no jitter.
+	GetScheduleMaxJitter() (time.Duration, error)
+	// GetMetadataUploadMaxAttempts returns the max failed attempts to upload backup metadata before backup fails.
+	GetMetadataUploadMaxAttempts() int32
+	// GetFullBackupJobTimeout, GetCollectJobTimeout and GetBinlogBackupJobTimeout return the active deadline
+	// of corresponding backup jobs, 0 means no deadline.
+	GetFullBackupJobTimeout() (time.Duration, error)
+	GetCollectJobTimeout() (time.Duration, error)
+	GetBinlogBackupJobTimeout() (time.Duration, error)
+	// GetCollectJobParallelism returns the number of sub-ranges collected in parallel by collect job, 1 means
+	// the whole range is collected by a single pod.
+	GetCollectJobParallelism() int32
+	// GetClockSkewThreshold returns the clock skew between target pod and operator above which a warning
+	// is recorded on backup.
+	GetClockSkewThreshold() (time.Duration, error)
+	// IsClockSkewCorrectionEnabled tells whether backup set timestamp is corrected to the clock of operator
+	// when clock skew exceeds the threshold.
+	IsClockSkewCorrectionEnabled() bool
+	// IsXStoreSecretSharingEnabled tells whether backups of an xstore share the secret saving accounts when
+	// accounts don't change, instead of creating a secret for each backup.
+	IsXStoreSecretSharingEnabled() bool
+	// GetPropagatedLabels returns keys of cluster labels copied to xstore backups and attached to uploaded
+	// backup files as object tags, e.g. labels for cost attribution.
+	GetPropagatedLabels() []string
+	// GetDiskSpaceSafetyMargin returns the percentage added to the estimated local disk space of full backup,
+	// full backup is refused if the available space on target pod is less than that.
+	GetDiskSpaceSafetyMargin() int32
+	// GetStableWaitTimeout returns how long a backup waits for xstore to be stable before full backup starts,
+	// backup fails once exceeded. 0 means waiting without limit.
+	GetStableWaitTimeout() (time.Duration, error)
+	// GetTargetPodPolicy returns the policy to select the standby pod to perform backup on, either
+	// FirstAvailable or LeastLag. FirstAvailable by default.
+	GetTargetPodPolicy() string
+	// GetAuditSink returns the sink to emit audit records of backups to on terminal states, either Stdout or
+	// Storage. Audit records are not emitted if empty.
+	GetAuditSink() string
+	// IsEarlyMetadataUploadEnabled tells whether metadata of backup set is uploaded while binlog backup is still
+	// running, leaving only the recoverable timestamp fields to be finalized at the end of backup.
+	IsEarlyMetadataUploadEnabled() bool
+	// GetBackupClass returns the backup class of name, or nil if not found. Backups resolve the class when they
+	// start, so changes of class apply to backups started afterwards.
+	GetBackupClass(name string) *BackupClass
+	// DefaultStorageProvider returns the storage provider of the first sink policy matching
+	// the namespace and labels of a cluster, or nil if none matches.
+	DefaultStorageProvider(namespace string, labels map[string]string) *polardbx.BackupStorageProvider
 }
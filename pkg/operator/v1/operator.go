@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"os"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"time"
 
 	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -67,6 +68,9 @@ type Options struct {
 	WebhookListenPort       int
 	LeaderElection          bool
 	LeaderElectionNamespace string
+	LeaseDuration           time.Duration
+	RenewDeadline           time.Duration
+	RetryPeriod             time.Duration
 	MaxConcurrentReconciles int
 	CertDir                 string
 
@@ -210,6 +214,15 @@ func setupPolarDBXBackupControllers(opts controllerOptions) error {
 	if err := pxcBackupReconciler.SetupWithManager(opts.Manager); err != nil {
 		return err
 	}
+
+	backupCatalogReconciler := polardbxv1controllers.PolarDBXBackupCatalogReconciler{
+		Client:         opts.Manager.GetClient(),
+		Logger:         ctrl.Log.WithName("controller").WithName("polardbxbackupcatalog"),
+		MaxConcurrency: opts.opts.MaxConcurrentReconciles,
+	}
+	if err := backupCatalogReconciler.SetupWithManager(opts.Manager); err != nil {
+		return err
+	}
 	return nil
 }
 func setupXStoreBackupControllers(opts controllerOptions) error {
@@ -307,25 +320,33 @@ func Start(ctx context.Context, opts Options) {
 		LeaderElection:          opts.LeaderElection,
 		LeaderElectionNamespace: opts.LeaderElectionNamespace,
 		LeaderElectionID:        "polardbx.aliyun.com",
-		WebhookServer:           webhookSever,
+		// Step down as soon as the manager stops, so that the new leader resumes in-flight backups
+		// without waiting for the lease to expire.
+		LeaderElectionReleaseOnCancel: true,
+		LeaseDuration:                 durationOrNil(opts.LeaseDuration),
+		RenewDeadline:                 durationOrNil(opts.RenewDeadline),
+		RetryPeriod:                   durationOrNil(opts.RetryPeriod),
+		WebhookServer:                 webhookSever,
 	})
 	if err != nil {
 		setupLog.Error(err, "Unable to new manager.")
 		os.Exit(1)
 	}
 
+	baseReconcileContext := control.NewBaseReconcileContext(
+		mgr.GetClient(),
+		restConfig,
+		clientset,
+		scheme,
+		context.Background(),
+		reconcile.Request{},
+	)
+	baseReconcileContext.SetAPIReader(mgr.GetAPIReader())
 	ctrlOpts := controllerOptions{
-		BaseReconcileContext: control.NewBaseReconcileContext(
-			mgr.GetClient(),
-			restConfig,
-			clientset,
-			scheme,
-			context.Background(),
-			reconcile.Request{},
-		),
-		Manager:       mgr,
-		LoaderFactory: configLoaderFactory,
-		opts:          &opts,
+		BaseReconcileContext: baseReconcileContext,
+		Manager:              mgr,
+		LoaderFactory:        configLoaderFactory,
+		opts:                 &opts,
 	}
 
 	// Setup controllers.
@@ -398,6 +419,14 @@ func Start(ctx context.Context, opts Options) {
 	}
 }
 
+// durationOrNil returns nil for a non-positive duration, which makes the manager use its default.
+func durationOrNil(d time.Duration) *time.Duration {
+	if d <= 0 {
+		return nil
+	}
+	return &d
+}
+
 type nullWebhookServer struct {
 }
 
@@ -96,18 +96,29 @@ func (r *PolarDBXBackupReconciler) newReconcileTask(rc *polardbxreconcile.Contex
 		commonsteps.TransferPhaseTo(polardbxv1.BackupDeleting, true),
 	)(task)
 
+	commonsteps.FailBackupIfOverallTimeout(task)
+
 	switch backup.Status.Phase {
 	case polardbxv1.BackupNew:
 		commonsteps.AddFinalizer(task)
 		commonsteps.UpdateBackupStartInfo(task)
+		commonsteps.LintBackup(task)
+		commonsteps.ValidateKeyringReEncryption(task)
 		//locked binlog purge
 		commonsteps.LockXStoreBinlogPurge(task)
+		commonsteps.QuiesceXStores(task)
 		commonsteps.CreateBackupJobsForXStore(task)
 		commonsteps.TransferPhaseTo(polardbxv1.FullBackuping, false)(task)
 	case polardbxv1.FullBackuping:
 		commonsteps.WaitAllBackupJobsFinished(task)
+		commonsteps.CreateConsolidateJob(task)
+		commonsteps.WaitUntilConsolidateJobFinished(task)
+		commonsteps.RemoveConsolidatedFullBackups(task)
 		if backup.Status.Phase == polardbxv1.BackupFailed {
 			commonsteps.TransferPhaseTo(polardbxv1.BackupFailed, false)(task)
+		} else if backup.IsSnapshotOnly() {
+			// no binlog to collect, xstore backups record the snapshot point as their backup set timestamp
+			commonsteps.TransferPhaseTo(polardbxv1.BinlogBackuping, false)(task)
 		} else {
 			commonsteps.TransferPhaseTo(polardbxv1.BackupCollecting, false)(task)
 		}
@@ -119,6 +130,7 @@ func (r *PolarDBXBackupReconciler) newReconcileTask(rc *polardbxreconcile.Contex
 			commonsteps.DrainCommittingTrans,
 			commonsteps.SendHeartBeat,
 			commonsteps.WaitHeartbeatSentToFollower,
+			commonsteps.CollectCdcState,
 			commonsteps.CollectBinlogEndIndex,
 		)(task)
 		commonsteps.TransferPhaseTo(polardbxv1.BackupCalculating, false)(task)
@@ -129,8 +141,10 @@ func (r *PolarDBXBackupReconciler) newReconcileTask(rc *polardbxreconcile.Contex
 		commonsteps.WaitUntilSeekCpJobFinished(task)
 		commonsteps.TransferPhaseTo(polardbxv1.BinlogBackuping, false)(task)
 	case polardbxv1.BinlogBackuping:
-		commonsteps.WaitAllBinlogJobFinished(task)
+		// metadata doesn't depend on binlog, upload it early while waiting binlog backup if enabled
 		commonsteps.SavePXCSecrets(task)
+		commonsteps.UploadEarlyClusterMetadata(task)
+		commonsteps.WaitAllBinlogJobFinished(task)
 		commonsteps.TransferPhaseTo(polardbxv1.MetadataBackuping, false)(task)
 	case polardbxv1.MetadataBackuping:
 		// In order to mitigate effect of cache, avoiding duplicate uploads
@@ -141,7 +155,10 @@ func (r *PolarDBXBackupReconciler) newReconcileTask(rc *polardbxreconcile.Contex
 	case polardbxv1.BackupFinished:
 		commonsteps.UnLockXStoreBinlogPurge(task)
 		commonsteps.RemoveSeekCpJob(task)
+		commonsteps.RemoveConsolidateJob(task)
 		commonsteps.RemoveBackupOverRetention(task)
+		commonsteps.QuickVerifyBackupObjects(task)
+		commonsteps.EmitBackupAuditRecord(task)
 		log.Info("Finished phase.")
 	case polardbxv1.BackupDeleting:
 		commonsteps.UnLockXStoreBinlogPurge(task)
@@ -149,6 +166,8 @@ func (r *PolarDBXBackupReconciler) newReconcileTask(rc *polardbxreconcile.Contex
 		commonsteps.RemoveFinalizer(task)
 	case polardbxv1.BackupFailed:
 		commonsteps.UnLockXStoreBinlogPurge(task)
+		control.When(backup.Status.Reason == commonsteps.ReasonOverallTimeout, commonsteps.RemoveSeekCpJob)(task)
+		commonsteps.EmitBackupAuditRecord(task)
 		log.Info("Failed phase.")
 	default:
 		log.Info("Unrecognized phase for pxc backup")
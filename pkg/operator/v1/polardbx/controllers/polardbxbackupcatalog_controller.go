@@ -0,0 +1,171 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/pkg/operator/hint"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/steps/backup/catalog"
+)
+
+// PolarDBXBackupCatalogReconciler maintains one backup catalog per polardbx cluster, which is named after the
+// cluster and lists all the backup sets of it. Catalog is created once the cluster has backups, and removed once
+// all the backups are deleted.
+type PolarDBXBackupCatalogReconciler struct {
+	Client         client.Client
+	Logger         logr.Logger
+	MaxConcurrency int
+}
+
+func (r *PolarDBXBackupCatalogReconciler) listBackups(ctx context.Context, namespace, cluster string) ([]polardbxv1.PolarDBXBackup, error) {
+	var backupList polardbxv1.PolarDBXBackupList
+	if err := r.Client.List(ctx, &backupList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	backups := make([]polardbxv1.PolarDBXBackup, 0, len(backupList.Items))
+	for _, backup := range backupList.Items {
+		if backup.Spec.Cluster.Name == cluster {
+			backups = append(backups, backup)
+		}
+	}
+	return backups, nil
+}
+
+func (r *PolarDBXBackupCatalogReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	logger := r.Logger.WithValues("namespace", request.Namespace, "polardbxbackupcatalog", request.Name)
+
+	if hint.IsNamespacePaused(request.Namespace) {
+		logger.Info("Reconcile is paused, skip")
+		return reconcile.Result{}, nil
+	}
+
+	backups, err := r.listBackups(ctx, request.Namespace, request.Name)
+	if err != nil {
+		logger.Error(err, "Unable to list polardbx backups.")
+		return reconcile.Result{}, err
+	}
+	var xstoreBackupList polardbxv1.XStoreBackupList
+	if err := r.Client.List(ctx, &xstoreBackupList, client.InNamespace(request.Namespace),
+		client.MatchingLabels{polardbxmeta.LabelName: request.Name}); err != nil {
+		logger.Error(err, "Unable to list xstore backups.")
+		return reconcile.Result{}, err
+	}
+
+	var backupCatalog polardbxv1.PolarDBXBackupCatalog
+	if err := r.Client.Get(ctx, request.NamespacedName, &backupCatalog); err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Error(err, "Unable to get polardbx backup catalog.")
+			return reconcile.Result{}, err
+		}
+		if len(backups) == 0 {
+			return reconcile.Result{}, nil
+		}
+
+		// Create the catalog for the first backup of cluster.
+		backupCatalog = polardbxv1.PolarDBXBackupCatalog{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      request.Name,
+				Namespace: request.Namespace,
+				Labels: map[string]string{
+					polardbxmeta.LabelName: request.Name,
+				},
+			},
+			Spec: polardbxv1.PolarDBXBackupCatalogSpec{
+				Cluster: backups[len(backups)-1].Spec.Cluster,
+			},
+		}
+		if err := r.Client.Create(ctx, &backupCatalog); err != nil {
+			logger.Error(err, "Unable to create polardbx backup catalog.")
+			return reconcile.Result{}, err
+		}
+		logger.Info("Backup catalog created.")
+	} else if len(backups) == 0 {
+		if err := r.Client.Delete(ctx, &backupCatalog); client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "Unable to delete polardbx backup catalog.")
+			return reconcile.Result{}, err
+		}
+		logger.Info("No backup left, backup catalog deleted.")
+		return reconcile.Result{}, nil
+	}
+
+	status := catalog.NewCatalogStatus(backups, xstoreBackupList.Items)
+	if !catalog.IsCatalogStatusChanged(&backupCatalog.Status, &status) {
+		return reconcile.Result{}, nil
+	}
+	now := metav1.Now()
+	status.LastUpdateTime = &now
+	backupCatalog.Status = status
+	if err := r.Client.Status().Update(ctx, &backupCatalog); err != nil {
+		if apierrors.IsConflict(err) {
+			logger.Info("Update conflict, just retry.")
+			return reconcile.Result{Requeue: true}, nil
+		}
+		logger.Error(err, "Unable to update status of polardbx backup catalog.")
+		return reconcile.Result{}, err
+	}
+	logger.Info("Backup catalog updated.", "backup-sets", status.BackupSetCount, "total-size", status.TotalSize)
+	return reconcile.Result{}, nil
+}
+
+func mapRequestsForBackupCatalog(_ context.Context, object client.Object) []reconcile.Request {
+	var cluster string
+	switch o := object.(type) {
+	case *polardbxv1.PolarDBXBackup:
+		cluster = o.Spec.Cluster.Name
+	case *polardbxv1.XStoreBackup:
+		cluster = o.Labels[polardbxmeta.LabelName]
+	}
+	if cluster == "" {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: object.GetNamespace(), Name: cluster}},
+	}
+}
+
+func (r *PolarDBXBackupCatalogReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrency,
+			RateLimiter: workqueue.NewMaxOfRateLimiter(
+				workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, 300*time.Second),
+				// 60 qps, 10 bucket size.  This is only for retry speed. It's only the overall factor (not per item).
+				&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(60), 10)},
+			),
+		}).
+		For(&polardbxv1.PolarDBXBackupCatalog{}).
+		// Watches backups and xstore backups of the cluster.
+		Watches(&polardbxv1.PolarDBXBackup{}, handler.EnqueueRequestsFromMapFunc(mapRequestsForBackupCatalog)).
+		Watches(&polardbxv1.XStoreBackup{}, handler.EnqueueRequestsFromMapFunc(mapRequestsForBackupCatalog)).
+		Complete(r)
+}
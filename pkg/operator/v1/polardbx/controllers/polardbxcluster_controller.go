@@ -33,6 +33,7 @@ import (
 	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
 	polardbxreconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
 	instancesteps "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/steps/instance"
+	binlogapplysteps "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/steps/instance/binlogapply"
 	checksteps "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/steps/instance/check"
 	commonsteps "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/steps/instance/common"
 	finalizersteps "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/steps/instance/finalizer"
@@ -138,6 +139,11 @@ func (r *PolarDBXReconciler) newReconcileTask(rc *polardbxreconcile.Context, pol
 		commonsteps.TransferPhaseTo(polardbxv1polardbx.PhasePending, true)(task)
 
 	case polardbxv1polardbx.PhasePending:
+		// Stay pending with nothing created until restore dry-run is turned off.
+		if polardbx.Spec.Restore != nil && polardbx.Spec.Restore.DryRun {
+			commonsteps.DryRunRestore(task)
+			break
+		}
 		control.When(polardbx.Spec.Restore != nil,
 			commonsteps.CreateDummyBackupObject,
 			pitr.LoadLatestBackupSetByTime,
@@ -182,6 +188,9 @@ func (r *PolarDBXReconciler) newReconcileTask(rc *polardbxreconcile.Context, pol
 				),
 				control.When(!readonly,
 					gmssteps.CreateAccounts,
+					control.When(helper.IsPhaseIn(polardbx, polardbxv1polardbx.PhaseRestoring),
+						gmssteps.SyncRestoredAccounts,
+					),
 					gmssteps.SyncDynamicConfigs(true),
 				),
 			)(task)
@@ -266,6 +275,10 @@ func (r *PolarDBXReconciler) newReconcileTask(rc *polardbxreconcile.Context, pol
 		control.When(helper.IsTdeOpen(polardbx),
 			commonsteps.TransferPhaseTo(polardbxv1polardbx.PhaseTdeOpening, true),
 		)(task)
+
+		control.When(helper.IsPhaseIn(polardbx, polardbxv1polardbx.PhaseRunning) && helper.IsBinlogApplyRequested(polardbx),
+			commonsteps.TransferPhaseTo(polardbxv1polardbx.PhaseApplyingBinlog, true),
+		)(task)
 		// Always reconcile the stateless components (mainly for rebuilt).
 		instancesteps.CreateOrReconcileCNs(task)
 		instancesteps.CreateOrReconcileCDCs(task)
@@ -397,6 +410,12 @@ func (r *PolarDBXReconciler) newReconcileTask(rc *polardbxreconcile.Context, pol
 		)(task)
 		commonsteps.UpdateTdeStatus(task)
 		commonsteps.TransferPhaseTo(polardbxv1polardbx.PhaseRunning, true)(task)
+	case polardbxv1polardbx.PhaseApplyingBinlog:
+		binlogapplysteps.CheckBinlogApplySpec(task)
+		binlogapplysteps.StartBinlogApplyJobs(task)
+		binlogapplysteps.WaitBinlogApplyJobsFinished(task)
+		binlogapplysteps.RemoveBinlogApplyJobs(task)
+		commonsteps.TransferPhaseTo(polardbxv1polardbx.PhaseRunning, true)(task)
 	case polardbxv1polardbx.PhaseFailed:
 	case polardbxv1polardbx.PhaseUnknown:
 
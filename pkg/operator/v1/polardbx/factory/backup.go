@@ -1,15 +1,25 @@
 package factory
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
 	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/config"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/convention"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	xstoreconvention "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
+	"github.com/alibaba/polardbx-operator/pkg/util/defaults"
 	"github.com/alibaba/polardbx-operator/pkg/util/name"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"maps"
+	"sort"
+	"strings"
 )
 
 type PolarDBXClusterMetadata struct {
@@ -47,6 +57,39 @@ type XstoreMetadata struct {
 
 	// Spec records the topology from original xstore
 	Spec *polardbxv1.XStoreSpec `json:"spec,omitempty"`
+
+	// ChunkSize records the block size at which full backup was chunked, 0 means not chunked
+	ChunkSize int64 `json:"chunkSize,omitempty"`
+
+	// ChunkManifestPath records the path of manifest which lists offset, size and sha256 of each chunk,
+	// serving as dedup hints for storage
+	ChunkManifestPath string `json:"chunkManifestPath,omitempty"`
+
+	// TableChecksums records the checksums of tables captured after full backup, keyed by "schema.table"
+	TableChecksums map[string]string `json:"tableChecksums,omitempty"`
+
+	// TableChecksumSample records the sampling of rows by which table checksums are captured, nil if whole tables
+	// are checksummed
+	TableChecksumSample *polardbxv1polardbx.TableChecksumSample `json:"tableChecksumSample,omitempty"`
+
+	// StorageProvider records the storage provider of xstore backup if overridden, nil means the one of backup set
+	StorageProvider *polardbxv1polardbx.BackupStorageProvider `json:"storageProvider,omitempty"`
+
+	// KeyringReEncryption records the secret of keys if keyring is re-encrypted, the target key in which is
+	// required to decrypt the keyring on restore
+	KeyringReEncryption *polardbxv1polardbx.KeyringReEncryption `json:"keyringReEncryption,omitempty"`
+
+	// KeyringKeyFingerprint records the fingerprint of the target key which keyring is re-encrypted with
+	KeyringKeyFingerprint string `json:"keyringKeyFingerprint,omitempty"`
+
+	// Encryption records the secret of key if files are encrypted, the key in which is required on restore
+	Encryption *polardbxv1polardbx.BackupEncryption `json:"encryption,omitempty"`
+
+	// FullBackupCodec records how full backup is processed before uploaded, which restore reverses
+	FullBackupCodec *polardbxv1polardbx.BackupCodec `json:"fullBackupCodec,omitempty"`
+
+	// BinlogBackupCodec records how binlogs are processed before uploaded, which restore reverses
+	BinlogBackupCodec *polardbxv1polardbx.BackupCodec `json:"binlogBackupCodec,omitempty"`
 }
 
 // MetadataBackup defines metadata to be uploaded during backup
@@ -72,6 +115,86 @@ type MetadataBackup struct {
 
 	// LatestRecoverableTimestamp records the latest timestamp that can recover from current backup set
 	LatestRecoverableTimestamp *metav1.Time `json:"latestRecoverableTimestamp,omitempty"`
+
+	// CdcState records positions and topology of cdc captured during backup
+	CdcState *polardbxv1.CdcBackupState `json:"cdcState,omitempty"`
+
+	// BackupMode records whether binlog is backed up, backup set in snapshot mode can only be restored to
+	// the snapshot point
+	BackupMode polardbxv1polardbx.BackupMode `json:"backupMode,omitempty"`
+
+	// PolarDBXVersion records the detailed version of original pxc, used to check compatibility during restore
+	PolarDBXVersion string `json:"polarDBXVersion,omitempty"`
+
+	// Images records engine images of components of original pxc, keyed by component
+	Images map[string]string `json:"images,omitempty"`
+
+	// GMSSchemaVersions records versions of GMS tables of original pxc, keyed by table name
+	GMSSchemaVersions map[string]int32 `json:"gmsSchemaVersions,omitempty"`
+
+	// ServerSideEncryption records the server-side encryption requested on uploaded files, for audit
+	ServerSideEncryption *polardbxv1polardbx.ServerSideEncryption `json:"serverSideEncryption,omitempty"`
+
+	// UserMetadata records custom key/value context attached to the backup set by user
+	UserMetadata map[string]string `json:"userMetadata,omitempty"`
+	// QuiescePoint records the consistent point of xstores captured by quiesce before full backups
+	QuiescePoint *polardbxv1.QuiescePoint `json:"quiescePoint,omitempty"`
+
+	// Archive records the archive packing full backups of xstores, full backups are no longer standalone
+	// objects if set
+	Archive *polardbxv1polardbx.BackupArchive `json:"archive,omitempty"`
+
+	// IncludeDatabases and ExcludeDatabases record the databases filtered by full backups, backup set of
+	// filtered databases can not be used to restore the whole cluster
+	IncludeDatabases []string `json:"includeDatabases,omitempty"`
+	ExcludeDatabases []string `json:"excludeDatabases,omitempty"`
+}
+
+// IsDatabaseFiltered tells whether only part of databases is backed up.
+func (m *MetadataBackup) IsDatabaseFiltered() bool {
+	return len(m.IncludeDatabases) > 0 || len(m.ExcludeDatabases) > 0
+}
+
+// Sort orders the xstore metadata by name and uid, and the secrets by username, so that the marshaled metadata
+// is reproducible regardless of the order collected.
+func (m *MetadataBackup) Sort() {
+	sortSecrets(m.PolarDBXClusterMetadata.Secrets)
+	sort.SliceStable(m.XstoreMetadataList, func(i, j int) bool {
+		a, b := &m.XstoreMetadataList[i], &m.XstoreMetadataList[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.UID < b.UID
+	})
+	for i := range m.XstoreMetadataList {
+		sortSecrets(m.XstoreMetadataList[i].Secrets)
+	}
+}
+
+// WithoutRecoverableFields returns a copy of metadata with the fields only known after binlog backup finished
+// cleared, i.e. end time, latest recoverable timestamp and cdc state.
+func (m *MetadataBackup) WithoutRecoverableFields() *MetadataBackup {
+	early := *m
+	early.EndTime = nil
+	early.LatestRecoverableTimestamp = nil
+	early.CdcState = nil
+	return &early
+}
+
+// Digest returns the sha256 of marshaled metadata, which is reproducible once metadata sorted.
+func (m *MetadataBackup) Digest() (string, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func sortSecrets(secrets []polardbxv1polardbx.PrivilegeItem) {
+	sort.SliceStable(secrets, func(i, j int) bool {
+		return secrets[i].Username < secrets[j].Username
+	})
 }
 
 func (m *MetadataBackup) GetXstoreNameList() []string {
@@ -82,6 +205,47 @@ func (m *MetadataBackup) GetXstoreNameList() []string {
 	return xstoreNameList
 }
 
+// GetXStoreStorageProviders returns the storage providers of xstore backups overridden, keyed by xstore, nil if
+// none is overridden.
+func (m *MetadataBackup) GetXStoreStorageProviders() map[string]polardbxv1polardbx.BackupStorageProvider {
+	var providers map[string]polardbxv1polardbx.BackupStorageProvider
+	for _, xstoreMetadata := range m.XstoreMetadataList {
+		if xstoreMetadata.StorageProvider == nil {
+			continue
+		}
+		if providers == nil {
+			providers = make(map[string]polardbxv1polardbx.BackupStorageProvider)
+		}
+		providers[xstoreMetadata.Name] = *xstoreMetadata.StorageProvider.DeepCopy()
+	}
+	return providers
+}
+
+// Summary returns the metadata with secrets and specs excluded, which is recorded in status of backup
+// after metadata uploaded to metadataPath.
+func (m *MetadataBackup) Summary(metadataPath string) *polardbxv1.BackupSetMetadata {
+	summary := &polardbxv1.BackupSetMetadata{
+		MetadataPath:               metadataPath,
+		BackupSetName:              m.BackupSetName,
+		BackupRootPath:             m.BackupRootPath,
+		StartTime:                  m.StartTime.DeepCopy(),
+		EndTime:                    m.EndTime.DeepCopy(),
+		LatestRecoverableTimestamp: m.LatestRecoverableTimestamp.DeepCopy(),
+		XStores:                    make([]polardbxv1.XStoreBackupSetMetadata, 0, len(m.XstoreMetadataList)),
+		UserMetadata:               maps.Clone(m.UserMetadata),
+		QuiescePoint:               m.QuiescePoint.DeepCopy(),
+	}
+	for _, xstoreMetadata := range m.XstoreMetadataList {
+		summary.XStores = append(summary.XStores, polardbxv1.XStoreBackupSetMetadata{
+			Name:            xstoreMetadata.Name,
+			BackupName:      xstoreMetadata.BackupName,
+			LastCommitIndex: xstoreMetadata.LastCommitIndex,
+			TargetPod:       xstoreMetadata.TargetPod,
+		})
+	}
+	return summary
+}
+
 func (m *MetadataBackup) GetXstoreMetadataByName(xstoreName string) (*XstoreMetadata, error) {
 	for i := range m.XstoreMetadataList {
 		if m.XstoreMetadataList[i].Name == xstoreName {
@@ -91,6 +255,49 @@ func (m *MetadataBackup) GetXstoreMetadataByName(xstoreName string) (*XstoreMeta
 	return nil, errors.New("no such metadata related to xstore " + xstoreName)
 }
 
+// ClusterImages returns the engine images of components described by spec, default images are used
+// for components which do not specify image explicitly.
+func ClusterImages(spec *polardbxv1.PolarDBXClusterSpec, images config.ImagesConfig) map[string]string {
+	if spec == nil {
+		return nil
+	}
+	topology := spec.Topology
+	storeImage := func(template *polardbxv1polardbx.XStoreTemplate) string {
+		return defaults.NonEmptyStrOrDefault(template.Image,
+			images.DefaultImageForStore(template.Engine, xstoreconvention.ContainerEngine, topology.Version))
+	}
+	clusterImage := func(role, image string) string {
+		return defaults.NonEmptyStrOrDefault(image,
+			images.DefaultImageForCluster(role, convention.ContainerEngine, topology.Version))
+	}
+
+	result := map[string]string{
+		meta.RoleCN: clusterImage(meta.RoleCN, topology.Nodes.CN.Template.Image),
+		meta.RoleDN: storeImage(&topology.Nodes.DN.Template),
+	}
+	if topology.Nodes.GMS.Template != nil {
+		result[meta.RoleGMS] = storeImage(topology.Nodes.GMS.Template)
+	}
+	if topology.Nodes.CDC != nil {
+		result[meta.RoleCDC] = clusterImage(meta.RoleCDC, topology.Nodes.CDC.Template.Image)
+	}
+	if topology.Nodes.Columnar != nil {
+		result[meta.RoleColumnar] = clusterImage(meta.RoleColumnar, topology.Nodes.Columnar.Template.Image)
+	}
+	return result
+}
+
+// PropagatedLabels returns the labels with given keys, which are propagated from cluster to its backups.
+func PropagatedLabels(labels map[string]string, keys []string) map[string]string {
+	result := make(map[string]string)
+	for _, k := range keys {
+		if v, ok := labels[k]; ok {
+			result[k] = v
+		}
+	}
+	return result
+}
+
 func (f *objectFactory) NewPolarDBXBackupBySchedule() (*polardbxv1.PolarDBXBackup, error) {
 	backupSchedule := f.rc.MustGetPolarDBXBackupSchedule()
 	backupName := name.NewSplicedName(
@@ -114,6 +321,7 @@ func (f *objectFactory) NewPolarDBXBackupBySchedule() (*polardbxv1.PolarDBXBacku
 func (f *objectFactory) NewXStoreBackup(
 	xstore *polardbxv1.XStore) (*polardbxv1.XStoreBackup, error) {
 	backup := f.rc.MustGetPolarDBXBackup()
+	storageProvider, _ := backup.Spec.ResolveXStoreStorageProvider(xstore.Name, xstore.Labels[meta.LabelRole])
 	xstoreBackup := &polardbxv1.XStoreBackup{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: backup.Namespace,
@@ -133,12 +341,46 @@ func (f *objectFactory) NewXStoreBackup(
 				Name: xstore.Name,
 				UID:  xstore.UID,
 			},
-			RetentionTime:       backup.Spec.RetentionTime,
-			StorageProvider:     backup.Spec.StorageProvider,
-			Engine:              xstore.Spec.Engine,
-			PreferredBackupRole: backup.Spec.PreferredBackupRole,
+			RetentionTime:            backup.Spec.RetentionTime,
+			StorageProvider:          *storageProvider.DeepCopy(),
+			Engine:                   xstore.Spec.Engine,
+			PreferredBackupRole:      backup.Spec.PreferredBackupRole,
+			ForbidBackupOnLeader:     backup.Spec.ForbidBackupOnLeader,
+			BackupMode:               backup.Spec.BackupMode,
+			BackupJobCommandOverride: backup.Spec.BackupJobCommandOverride.DeepCopy(),
+			BinlogExcludePatterns:    append([]string(nil), backup.Spec.BinlogExcludePatterns...),
+			RefreshSecret:            backup.Spec.RefreshSecret,
+			UserMetadata:             maps.Clone(backup.Spec.UserMetadata),
+			BinlogCollectMargin:      backup.Spec.BinlogCollectMargin.DeepCopy(),
+			IncludeDatabases:         append([]string(nil), backup.Spec.IncludeDatabases...),
+			ExcludeDatabases:         append([]string(nil), backup.Spec.ExcludeDatabases...),
+			KeyringReEncryption:      backup.Spec.KeyringReEncryption.DeepCopy(),
+			Compression:              backup.Spec.Compression,
+			Encryption:               backup.Spec.Encryption.DeepCopy(),
 		},
 	}
+	if backup.Spec.OverallTimeout != nil {
+		overallTimeout := *backup.Spec.OverallTimeout
+		xstoreBackup.Spec.OverallTimeout = &overallTimeout
+	}
+
+	// Propagate labels of cluster, which are also attached to uploaded files as tags
+	polardbx := f.rc.MustGetPolarDBX()
+	propagated := PropagatedLabels(polardbx.Labels, f.rc.Config().Backup().GetPropagatedLabels())
+	if len(propagated) > 0 {
+		keys := make([]string, 0, len(propagated))
+		for k, v := range propagated {
+			if _, ok := xstoreBackup.Labels[k]; ok {
+				continue
+			}
+			xstoreBackup.Labels[k] = v
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		xstoreBackup.Annotations = map[string]string{
+			xstoremeta.AnnotationObjectTagLabels: strings.Join(keys, ","),
+		}
+	}
 
 	return xstoreBackup, nil
 }
@@ -168,7 +410,11 @@ func (f *objectFactory) NewDummyPolarDBXBackup(metadata *MetadataBackup) (*polar
 				Name: metadata.PolarDBXClusterMetadata.Name,
 				UID:  metadata.PolarDBXClusterMetadata.UID,
 			},
-			StorageProvider: *polardbx.Spec.Restore.StorageProvider,
+			StorageProvider:  *polardbx.Spec.Restore.StorageProvider,
+			BackupMode:       metadata.BackupMode,
+			UserMetadata:     metadata.UserMetadata,
+			IncludeDatabases: metadata.IncludeDatabases,
+			ExcludeDatabases: metadata.ExcludeDatabases,
 		},
 		Status: polardbxv1.PolarDBXBackupStatus{
 			Phase:                      polardbxv1.BackupDummy,
@@ -179,6 +425,12 @@ func (f *objectFactory) NewDummyPolarDBXBackup(metadata *MetadataBackup) (*polar
 			LatestRecoverableTimestamp: metadata.LatestRecoverableTimestamp,
 			StartTime:                  metadata.StartTime,
 			EndTime:                    metadata.EndTime,
+			CdcState:                   metadata.CdcState,
+			PolarDBXVersion:            metadata.PolarDBXVersion,
+			Images:                     metadata.Images,
+			GMSSchemaVersions:          metadata.GMSSchemaVersions,
+			Archive:                    metadata.Archive.DeepCopy(),
+			XStoreStorageProviders:     metadata.GetXStoreStorageProviders(),
 		},
 	}
 	return polardbxBackup, nil
@@ -213,13 +465,22 @@ func (f *objectFactory) NewDummyXstoreBackup(xstoreName string, polardbxBackup *
 				Name: xstoreName,
 				UID:  xstoreMetadata.UID,
 			},
-			StorageProvider: polardbxBackup.Spec.StorageProvider,
+			StorageProvider:     polardbxBackup.XStoreStorageProvider(xstoreName),
+			BackupMode:          polardbxBackup.Spec.BackupMode,
+			UserMetadata:        maps.Clone(polardbxBackup.Spec.UserMetadata),
+			KeyringReEncryption: xstoreMetadata.KeyringReEncryption.DeepCopy(),
+			Encryption:          xstoreMetadata.Encryption.DeepCopy(),
 		},
 		Status: polardbxv1.XStoreBackupStatus{
-			Phase:          polardbxv1.XStoreBackupDummy,
-			CommitIndex:    xstoreMetadata.LastCommitIndex,
-			BackupRootPath: metadata.BackupRootPath,
-			TargetPod:      xstoreMetadata.TargetPod,
+			Phase:                 polardbxv1.XStoreBackupDummy,
+			CommitIndex:           xstoreMetadata.LastCommitIndex,
+			BackupRootPath:        metadata.BackupRootPath,
+			TargetPod:             xstoreMetadata.TargetPod,
+			Archive:               metadata.Archive.ForXStore(xstoreName),
+			ChunkSize:             xstoreMetadata.ChunkSize,
+			KeyringKeyFingerprint: xstoreMetadata.KeyringKeyFingerprint,
+			FullBackupCodec:       xstoreMetadata.FullBackupCodec.DeepCopy(),
+			BinlogBackupCodec:     xstoreMetadata.BinlogBackupCodec.DeepCopy(),
 		},
 	}
 	return xstoreBackup, nil
@@ -0,0 +1,86 @@
+package factory
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+)
+
+func TestMetadataBackupSort(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	newMetadata := func(xstores ...XstoreMetadata) *MetadataBackup {
+		return &MetadataBackup{
+			BackupSetName:      "pxb",
+			XstoreMetadataList: xstores,
+		}
+	}
+	secrets := func(users ...string) []polardbxv1polardbx.PrivilegeItem {
+		items := make([]polardbxv1polardbx.PrivilegeItem, 0, len(users))
+		for _, user := range users {
+			items = append(items, polardbxv1polardbx.PrivilegeItem{Username: user, Password: user + "-pwd"})
+		}
+		return items
+	}
+
+	// collected in different orders
+	m1 := newMetadata(
+		XstoreMetadata{Name: "pxc-gms", UID: "u-gms", Secrets: secrets("root", "admin")},
+		XstoreMetadata{Name: "pxc-dn-1", UID: "u-dn-1"},
+		XstoreMetadata{Name: "pxc-dn-0", UID: "u-dn-0"},
+	)
+	m2 := newMetadata(
+		XstoreMetadata{Name: "pxc-dn-0", UID: "u-dn-0"},
+		XstoreMetadata{Name: "pxc-gms", UID: "u-gms", Secrets: secrets("admin", "root")},
+		XstoreMetadata{Name: "pxc-dn-1", UID: "u-dn-1"},
+	)
+	m1.Sort()
+	m2.Sort()
+	g.Expect(m1.GetXstoreNameList()).To(gomega.Equal([]string{"pxc-dn-0", "pxc-dn-1", "pxc-gms"}))
+
+	j1, err := json.Marshal(m1)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	j2, err := json.Marshal(m2)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(j1)).To(gomega.Equal(string(j2)))
+	g.Expect(string(j1)).To(gomega.Equal(`{"polarDBXClusterMetadata":{},"xstoreMetadataList":[` +
+		`{"name":"pxc-dn-0","uid":"u-dn-0"},{"name":"pxc-dn-1","uid":"u-dn-1"},` +
+		`{"name":"pxc-gms","uid":"u-gms","secrets":[{"username":"admin","password":"admin-pwd"},` +
+		`{"username":"root","password":"root-pwd"}]}],"backupSetName":"pxb"}`))
+}
+
+func TestMetadataBackupWithoutRecoverableFields(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	startTime, endTime := metav1.Unix(1700000000, 0), metav1.Unix(1700003600, 0)
+	early := &MetadataBackup{
+		BackupSetName:      "pxb",
+		StartTime:          &startTime,
+		XstoreMetadataList: []XstoreMetadata{{Name: "pxc-dn-0", LastCommitIndex: 100}},
+	}
+	final := *early
+	final.EndTime = &endTime
+	final.LatestRecoverableTimestamp = &endTime
+	final.CdcState = &polardbxv1.CdcBackupState{Tso: "tso"}
+
+	stripped := final.WithoutRecoverableFields()
+	g.Expect(stripped.EndTime).To(gomega.BeNil())
+	g.Expect(stripped.LatestRecoverableTimestamp).To(gomega.BeNil())
+	g.Expect(stripped.CdcState).To(gomega.BeNil())
+	g.Expect(final.EndTime).NotTo(gomega.BeNil())
+
+	earlyDigest, err := early.Digest()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	digest, err := stripped.Digest()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(digest).To(gomega.Equal(earlyDigest))
+
+	// superseded if anything else changed after early upload
+	final.XstoreMetadataList = []XstoreMetadata{{Name: "pxc-dn-0", LastCommitIndex: 101}}
+	digest, err = final.WithoutRecoverableFields().Digest()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(digest).NotTo(gomega.Equal(earlyDigest))
+}
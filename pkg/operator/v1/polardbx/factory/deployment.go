@@ -392,6 +392,9 @@ func (f *objectFactory) newDeployment4CN(group string, mr *matchingRule, mustSta
 
 	// Ports & Envs
 	ports := portsFactory.NewPortsForCNEngine(mustStaticPorts)
+	if err := validateProberPort(&ports); err != nil {
+		return nil, err
+	}
 	envVars := envFactory.NewEnvVarsForCNEngine(gmsConn, ports)
 
 	// Host network
@@ -460,7 +463,7 @@ func (f *objectFactory) newDeployment4CN(group string, mr *matchingRule, mustSta
 		dnsPolicy = corev1.DNSClusterFirstWithHostNet
 	}
 
-	probeConfigure.ConfigureForCNEngine(&engineContainer, ports)
+	probeConfigure.ConfigureForCNEngine(&engineContainer, ports, template.ProbeTarget)
 	proberContainer := corev1.Container{
 		Name:  convention.ContainerProber,
 		Image: imageConfig.DefaultImageForCluster(polardbxmeta.RoleCN, convention.ContainerProber, topology.Version),
@@ -646,6 +649,9 @@ func (f *objectFactory) newDeployment4CDC(group string, mr *matchingRule, mustSt
 
 	// Ports & Envs
 	ports := portsFactory.NewPortsForCDCEngine()
+	if err := validateProberPort(&ports); err != nil {
+		return nil, err
+	}
 	envVars := envFactory.NewEnvVarsForCDCEngine(gmsConn)
 
 	// Affinity
@@ -17,18 +17,23 @@ limitations under the License.
 package factory
 
 import (
+	"fmt"
+	"math"
 	"strconv"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
 	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
 	"github.com/alibaba/polardbx-operator/pkg/probe"
 )
 
 type ProbeConfigure interface {
-	ConfigureForCNEngine(container *corev1.Container, ports CNPorts)
+	ConfigureForCNEngine(container *corev1.Container, ports CNPorts, probeTarget string)
 	ConfigureForCNExporter(container *corev1.Container, ports CNPorts)
 	ConfigureForCDCEngine(container *corev1.Container, ports CDCPorts)
 	ConfigureForCDCExporter(container *corev1.Container, ports CDCPorts)
@@ -39,6 +44,106 @@ type probeConfigure struct {
 	polardbx *polardbxv1.PolarDBXCluster
 }
 
+// probeTiming is the timing of liveness and readiness probes, zero failure threshold leaves the kubernetes default.
+type probeTiming struct {
+	TimeoutSeconds   int32
+	PeriodSeconds    int32
+	FailureThreshold int32
+}
+
+type probeComponent int
+
+const (
+	probeComponentCNEngine probeComponent = iota
+	probeComponentCNExporter
+	probeComponentCDCEngine
+	probeComponentCDCExporter
+)
+
+// probeProfiles lists timings of components in each probe profile, see polardbx.ProbeProfile for details.
+var probeProfiles = map[polardbx.ProbeProfile]map[probeComponent]probeTiming{
+	polardbx.ProbeProfileAggressive: {
+		probeComponentCNEngine:    {TimeoutSeconds: 5, PeriodSeconds: 5, FailureThreshold: 3},
+		probeComponentCNExporter:  {TimeoutSeconds: 3, PeriodSeconds: 10, FailureThreshold: 3},
+		probeComponentCDCEngine:   {TimeoutSeconds: 5, PeriodSeconds: 5, FailureThreshold: 3},
+		probeComponentCDCExporter: {TimeoutSeconds: 3, PeriodSeconds: 10, FailureThreshold: 3},
+	},
+	polardbx.ProbeProfileDefault: {
+		probeComponentCNEngine:    {TimeoutSeconds: 10, PeriodSeconds: 10},
+		probeComponentCNExporter:  {TimeoutSeconds: 5, PeriodSeconds: 20},
+		probeComponentCDCEngine:   {TimeoutSeconds: 10, PeriodSeconds: 10, FailureThreshold: 5},
+		probeComponentCDCExporter: {TimeoutSeconds: 5, PeriodSeconds: 20},
+	},
+	polardbx.ProbeProfileRelaxed: {
+		probeComponentCNEngine:    {TimeoutSeconds: 30, PeriodSeconds: 20, FailureThreshold: 6},
+		probeComponentCNExporter:  {TimeoutSeconds: 10, PeriodSeconds: 30, FailureThreshold: 6},
+		probeComponentCDCEngine:   {TimeoutSeconds: 30, PeriodSeconds: 20, FailureThreshold: 10},
+		probeComponentCDCExporter: {TimeoutSeconds: 10, PeriodSeconds: 30, FailureThreshold: 6},
+	},
+}
+
+// newProbeTiming returns the timing of component from the probe profile, falling back to the default profile
+// if unknown, with the overrides specified applied.
+func newProbeTiming(config *polardbx.ProbeConfig, component probeComponent) probeTiming {
+	if config == nil {
+		return probeProfiles[polardbx.ProbeProfileDefault][component]
+	}
+	profile, ok := probeProfiles[config.Profile]
+	if !ok {
+		profile = probeProfiles[polardbx.ProbeProfileDefault]
+	}
+	timing := profile[component]
+
+	var override *polardbx.ProbeOverride
+	switch component {
+	case probeComponentCNEngine:
+		override = config.CNEngine
+	case probeComponentCNExporter:
+		override = config.CNExporter
+	case probeComponentCDCEngine:
+		override = config.CDCEngine
+	case probeComponentCDCExporter:
+		override = config.CDCExporter
+	}
+	if override != nil {
+		if override.TimeoutSeconds != nil {
+			timing.TimeoutSeconds = *override.TimeoutSeconds
+		}
+		if override.PeriodSeconds != nil {
+			timing.PeriodSeconds = *override.PeriodSeconds
+		}
+		if override.FailureThreshold != nil {
+			timing.FailureThreshold = *override.FailureThreshold
+		}
+	}
+	return timing
+}
+
+func (p *probeConfigure) probeTiming(component probeComponent) probeTiming {
+	if p.polardbx == nil {
+		return newProbeTiming(nil, component)
+	}
+	return newProbeTiming(p.polardbx.Spec.Probes, component)
+}
+
+func (t probeTiming) newProbe(handler corev1.ProbeHandler) *corev1.Probe {
+	return &corev1.Probe{
+		TimeoutSeconds:   t.TimeoutSeconds,
+		PeriodSeconds:    t.PeriodSeconds,
+		FailureThreshold: t.FailureThreshold,
+		ProbeHandler:     handler,
+	}
+}
+
+// validateProberPort checks the probe port is allocated, otherwise probes would silently point
+// at port 0 and the pods would never be ready.
+func validateProberPort(ports ProberPort) error {
+	if port := ports.GetProbePort(); port <= 0 || port > math.MaxUint16 {
+		return fmt.Errorf("invalid probe port: %d", port)
+	}
+	return nil
+}
+
 func (p *probeConfigure) newProbeWithProber(endpoint string, probeTarget string, ports ProberPort) corev1.ProbeHandler {
 	return corev1.ProbeHandler{
 		HTTPGet: &corev1.HTTPGetAction{
@@ -53,46 +158,118 @@ func (p *probeConfigure) newProbeWithProber(endpoint string, probeTarget string,
 	}
 }
 
-func (p *probeConfigure) ConfigureForCNEngine(container *corev1.Container, ports CNPorts) {
+const (
+	cnStartupProbePeriodSeconds           = 10
+	cnStartupProbeDefaultFailureThreshold = 300
+)
+
+// newCNStartupProbeFailureThreshold computes the failure threshold of startup probe of cn engine so that the
+// startup timeout is covered. The default threshold is used if timeout is not specified or not positive.
+func newCNStartupProbeFailureThreshold(config *polardbx.ProbeConfig) int32 {
+	if config == nil || config.CNStartupTimeout == nil || config.CNStartupTimeout.Duration <= 0 {
+		return cnStartupProbeDefaultFailureThreshold
+	}
+	threshold := math.Ceil(config.CNStartupTimeout.Seconds() / cnStartupProbePeriodSeconds)
+	if threshold > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(threshold)
+}
+
+// ConfigureForCNEngine configures probes of the cn engine container. The probe target defaults
+// to polardbx if not specified.
+func (p *probeConfigure) ConfigureForCNEngine(container *corev1.Container, ports CNPorts, probeTarget string) {
+	if probeTarget == "" {
+		probeTarget = probe.TypePolarDBX
+	}
+	var config *polardbx.ProbeConfig
+	if p.polardbx != nil {
+		config = p.polardbx.Spec.Probes
+	}
 	container.StartupProbe = &corev1.Probe{
 		InitialDelaySeconds: 10,
 		TimeoutSeconds:      10,
-		PeriodSeconds:       10,
-		FailureThreshold:    300,
-		ProbeHandler:        p.newProbeWithProber("/liveness", probe.TypePolarDBX, &ports),
+		PeriodSeconds:       cnStartupProbePeriodSeconds,
+		FailureThreshold:    newCNStartupProbeFailureThreshold(config),
+		ProbeHandler:        p.newProbeWithProber("/liveness", probeTarget, &ports),
 	}
-	container.LivenessProbe = &corev1.Probe{
-		TimeoutSeconds: 10,
-		PeriodSeconds:  10,
-		ProbeHandler:   p.newProbeWithProber("/liveness", probe.TypePolarDBX, &ports),
+	timing := p.probeTiming(probeComponentCNEngine)
+	container.LivenessProbe = timing.newProbe(p.newProbeWithProber("/liveness", probeTarget, &ports))
+	container.ReadinessProbe = timing.newProbe(p.newProbeWithProber("/readiness", probeTarget, &ports))
+}
+
+// newExporterReadinessHandler returns the readiness handler of exporter. Metrics endpoint is scraped by default,
+// while TCP and Exec checks avoid the cost of collecting metrics. Exec falls back to TCP if no command specified.
+func newExporterReadinessHandler(readiness *polardbx.ExporterReadiness, metricsPort int) corev1.ProbeHandler {
+	readinessType := polardbx.ExporterReadinessHTTP
+	if readiness != nil && readiness.Type != "" {
+		readinessType = readiness.Type
 	}
-	container.ReadinessProbe = &corev1.Probe{
-		TimeoutSeconds: 10,
-		PeriodSeconds:  10,
-		ProbeHandler:   p.newProbeWithProber("/readiness", probe.TypePolarDBX, &ports),
+	if readinessType == polardbx.ExporterReadinessExec && len(readiness.Command) == 0 {
+		readinessType = polardbx.ExporterReadinessTCP
 	}
-}
 
-func (p *probeConfigure) ConfigureForCNExporter(container *corev1.Container, ports CNPorts) {
-	container.LivenessProbe = &corev1.Probe{
-		TimeoutSeconds: 5,
-		PeriodSeconds:  20,
-		ProbeHandler: corev1.ProbeHandler{
+	switch readinessType {
+	case polardbx.ExporterReadinessTCP:
+		return corev1.ProbeHandler{
 			TCPSocket: &corev1.TCPSocketAction{
-				Port: intstr.FromInt(ports.MetricsPort),
+				Port: intstr.FromInt(metricsPort),
 			},
-		},
-	}
-	container.ReadinessProbe = &corev1.Probe{
-		TimeoutSeconds: 5,
-		PeriodSeconds:  20,
-		ProbeHandler: corev1.ProbeHandler{
+		}
+	case polardbx.ExporterReadinessExec:
+		return corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{
+				Command: append([]string(nil), readiness.Command...),
+			},
+		}
+	default:
+		return corev1.ProbeHandler{
 			HTTPGet: &corev1.HTTPGetAction{
 				Path: "/metrics",
-				Port: intstr.FromInt(ports.MetricsPort),
+				Port: intstr.FromInt(metricsPort),
 			},
+		}
+	}
+}
+
+func (p *probeConfigure) ConfigureForCNExporter(container *corev1.Container, ports CNPorts) {
+	timing := p.probeTiming(probeComponentCNExporter)
+	container.LivenessProbe = timing.newProbe(corev1.ProbeHandler{
+		TCPSocket: &corev1.TCPSocketAction{
+			Port: intstr.FromInt(ports.MetricsPort),
 		},
+	})
+	var readiness *polardbx.ExporterReadiness
+	if p.polardbx != nil && p.polardbx.Spec.Probes != nil {
+		readiness = p.polardbx.Spec.Probes.CNExporterReadiness
+	}
+	container.ReadinessProbe = timing.newProbe(newExporterReadinessHandler(readiness, ports.MetricsPort))
+}
+
+const (
+	cdcStartupProbePeriodSeconds           = 10
+	cdcStartupProbeDefaultFailureThreshold = 18
+)
+
+// newCDCStartupProbeFailureThreshold computes the failure threshold of startup probe of cdc engine so that
+// the expected catch-up time is covered. The default threshold is used if catch-up time is empty, invalid
+// or shorter than the default.
+func newCDCStartupProbeFailureThreshold(catchupTime string) int32 {
+	if catchupTime == "" {
+		return cdcStartupProbeDefaultFailureThreshold
+	}
+	d, err := time.ParseDuration(catchupTime)
+	if err != nil || d <= 0 {
+		return cdcStartupProbeDefaultFailureThreshold
+	}
+	threshold := math.Ceil(d.Seconds() / cdcStartupProbePeriodSeconds)
+	if threshold <= cdcStartupProbeDefaultFailureThreshold {
+		return cdcStartupProbeDefaultFailureThreshold
 	}
+	if threshold > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(threshold)
 }
 
 func (p *probeConfigure) ConfigureForCDCEngine(container *corev1.Container, ports CDCPorts) {
@@ -104,38 +281,35 @@ func (p *probeConfigure) ConfigureForCDCEngine(container *corev1.Container, port
 	container.StartupProbe = &corev1.Probe{
 		InitialDelaySeconds: 10,
 		TimeoutSeconds:      10,
-		PeriodSeconds:       10,
-		FailureThreshold:    18,
+		PeriodSeconds:       cdcStartupProbePeriodSeconds,
+		FailureThreshold:    newCDCStartupProbeFailureThreshold(p.polardbx.Annotations[polardbxmeta.AnnotationCDCStartupCatchupTime]),
 		ProbeHandler:        hanlder,
 	}
-	container.LivenessProbe = &corev1.Probe{
-		TimeoutSeconds:   10,
-		PeriodSeconds:    10,
-		FailureThreshold: 5,
-		ProbeHandler:     hanlder,
+	timing := p.probeTiming(probeComponentCDCEngine)
+	container.LivenessProbe = timing.newProbe(hanlder)
+
+	// Readiness is left to the startup probe, which checks tcp only, if no http endpoint is specified.
+	if httpPath := p.polardbx.Annotations[polardbxmeta.AnnotationCDCReadinessHttpPath]; httpPath != "" {
+		readinessHandler := p.newProbeWithProber("/readiness", probe.TypeCdc, &ports)
+		readinessHandler.HTTPGet.HTTPHeaders = append(readinessHandler.HTTPGet.HTTPHeaders,
+			corev1.HTTPHeader{Name: "Probe-Http-Path", Value: httpPath})
+		container.ReadinessProbe = timing.newProbe(readinessHandler)
 	}
 }
 
 func (p *probeConfigure) ConfigureForCDCExporter(container *corev1.Container, ports CDCPorts) {
-	container.LivenessProbe = &corev1.Probe{
-		TimeoutSeconds: 5,
-		PeriodSeconds:  20,
-		ProbeHandler: corev1.ProbeHandler{
-			TCPSocket: &corev1.TCPSocketAction{
-				Port: intstr.FromInt(ports.MetricsPort),
-			},
+	timing := p.probeTiming(probeComponentCDCExporter)
+	container.LivenessProbe = timing.newProbe(corev1.ProbeHandler{
+		TCPSocket: &corev1.TCPSocketAction{
+			Port: intstr.FromInt(ports.MetricsPort),
 		},
-	}
-	container.ReadinessProbe = &corev1.Probe{
-		TimeoutSeconds: 5,
-		PeriodSeconds:  20,
-		ProbeHandler: corev1.ProbeHandler{
-			HTTPGet: &corev1.HTTPGetAction{
-				Path: "/metrics",
-				Port: intstr.FromInt(ports.MetricsPort),
-			},
+	})
+	container.ReadinessProbe = timing.newProbe(corev1.ProbeHandler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Path: "/metrics",
+			Port: intstr.FromInt(ports.MetricsPort),
 		},
-	}
+	})
 }
 
 func NewProbeConfigure(rc *polardbxv1reconcile.Context, pxc *polardbxv1.PolarDBXCluster) ProbeConfigure {
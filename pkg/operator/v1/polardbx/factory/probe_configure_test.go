@@ -0,0 +1,151 @@
+package factory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/probe"
+)
+
+func TestNewCDCStartupProbeFailureThreshold(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(newCDCStartupProbeFailureThreshold("")).To(gomega.BeEquivalentTo(18))
+	g.Expect(newCDCStartupProbeFailureThreshold("invalid")).To(gomega.BeEquivalentTo(18))
+	g.Expect(newCDCStartupProbeFailureThreshold("-1h")).To(gomega.BeEquivalentTo(18))
+	g.Expect(newCDCStartupProbeFailureThreshold("1m")).To(gomega.BeEquivalentTo(18))
+	g.Expect(newCDCStartupProbeFailureThreshold("30m")).To(gomega.BeEquivalentTo(180))
+	g.Expect(newCDCStartupProbeFailureThreshold("301s")).To(gomega.BeEquivalentTo(31))
+}
+
+func TestConfigureForCNEngineStartupProbe(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ports := CNPorts{AccessPort: 3306, ProbePort: 9999}
+
+	for timeout, expected := range map[string]int32{
+		"":      300,
+		"0s":    300,
+		"2h":    720,
+		"10m":   60,
+		"1001s": 101,
+	} {
+		pxc := &polardbxv1.PolarDBXCluster{}
+		if timeout != "" {
+			d, err := time.ParseDuration(timeout)
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			pxc.Spec.Probes = &polardbx.ProbeConfig{CNStartupTimeout: &metav1.Duration{Duration: d}}
+		}
+		container := &corev1.Container{}
+		(&probeConfigure{polardbx: pxc}).ConfigureForCNEngine(container, ports, "")
+		g.Expect(container.StartupProbe.PeriodSeconds).To(gomega.BeEquivalentTo(10))
+		g.Expect(container.StartupProbe.FailureThreshold).To(gomega.Equal(expected), "timeout %s", timeout)
+	}
+}
+
+func TestConfigureForCNEngineProbeTarget(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	configure := &probeConfigure{}
+	ports := CNPorts{AccessPort: 3306, ProbePort: 9999}
+
+	for target, expected := range map[string]string{
+		"":                 probe.TypePolarDBX,
+		probe.TypePolarDBX: probe.TypePolarDBX,
+		probe.TypeColumnar: probe.TypeColumnar,
+	} {
+		container := &corev1.Container{}
+		configure.ConfigureForCNEngine(container, ports, target)
+		for _, p := range []*corev1.Probe{container.StartupProbe, container.LivenessProbe, container.ReadinessProbe} {
+			g.Expect(p.HTTPGet.HTTPHeaders).To(gomega.ContainElement(
+				corev1.HTTPHeader{Name: "Probe-Target", Value: expected}))
+		}
+	}
+}
+
+func TestValidateProberPort(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(validateProberPort(&CNPorts{AccessPort: 3306, ProbePort: 9090})).To(gomega.Succeed())
+	g.Expect(validateProberPort(&CDCPorts{DaemonPort: 3007, ProbePort: 9999})).To(gomega.Succeed())
+
+	// unallocated or out of range
+	g.Expect(validateProberPort(&CNPorts{AccessPort: 3306})).NotTo(gomega.Succeed())
+	g.Expect(validateProberPort(&CDCPorts{DaemonPort: 3007, ProbePort: 70000})).NotTo(gomega.Succeed())
+}
+
+func TestNewProbeTiming(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// default profile if not specified or unknown
+	defaultTiming := probeTiming{TimeoutSeconds: 10, PeriodSeconds: 10}
+	g.Expect(newProbeTiming(nil, probeComponentCNEngine)).To(gomega.Equal(defaultTiming))
+	g.Expect(newProbeTiming(&polardbx.ProbeConfig{}, probeComponentCNEngine)).To(gomega.Equal(defaultTiming))
+	g.Expect(newProbeTiming(&polardbx.ProbeConfig{Profile: "unknown"}, probeComponentCNEngine)).To(gomega.Equal(defaultTiming))
+
+	// overrides win over profile
+	config := &polardbx.ProbeConfig{
+		Profile: polardbx.ProbeProfileRelaxed,
+		CDCEngine: &polardbx.ProbeOverride{
+			FailureThreshold: pointer.Int32(20),
+		},
+	}
+	g.Expect(newProbeTiming(config, probeComponentCNEngine)).To(gomega.Equal(
+		probeTiming{TimeoutSeconds: 30, PeriodSeconds: 20, FailureThreshold: 6}))
+	g.Expect(newProbeTiming(config, probeComponentCDCEngine)).To(gomega.Equal(
+		probeTiming{TimeoutSeconds: 30, PeriodSeconds: 20, FailureThreshold: 20}))
+
+	// every component is defined in every profile
+	for profile, timings := range probeProfiles {
+		for _, component := range []probeComponent{probeComponentCNEngine, probeComponentCNExporter,
+			probeComponentCDCEngine, probeComponentCDCExporter} {
+			g.Expect(timings).To(gomega.HaveKey(component), "profile %s", profile)
+		}
+	}
+}
+
+func TestConfigureForCNExporterProbeProfile(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	configure := &probeConfigure{polardbx: &polardbxv1.PolarDBXCluster{
+		Spec: polardbxv1.PolarDBXClusterSpec{
+			Probes: &polardbx.ProbeConfig{Profile: polardbx.ProbeProfileAggressive},
+		},
+	}}
+
+	container := &corev1.Container{}
+	configure.ConfigureForCNExporter(container, CNPorts{MetricsPort: 8081})
+	for _, p := range []*corev1.Probe{container.LivenessProbe, container.ReadinessProbe} {
+		g.Expect(p.TimeoutSeconds).To(gomega.BeEquivalentTo(3))
+		g.Expect(p.PeriodSeconds).To(gomega.BeEquivalentTo(10))
+		g.Expect(p.FailureThreshold).To(gomega.BeEquivalentTo(3))
+	}
+}
+
+func TestNewExporterReadinessHandler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// http by default
+	handler := newExporterReadinessHandler(nil, 8081)
+	g.Expect(handler.HTTPGet).NotTo(gomega.BeNil())
+	g.Expect(handler.HTTPGet.Path).To(gomega.Equal("/metrics"))
+	handler = newExporterReadinessHandler(&polardbx.ExporterReadiness{}, 8081)
+	g.Expect(handler.HTTPGet).NotTo(gomega.BeNil())
+
+	handler = newExporterReadinessHandler(&polardbx.ExporterReadiness{Type: polardbx.ExporterReadinessTCP}, 8081)
+	g.Expect(handler.HTTPGet).To(gomega.BeNil())
+	g.Expect(handler.TCPSocket.Port.IntValue()).To(gomega.Equal(8081))
+
+	handler = newExporterReadinessHandler(&polardbx.ExporterReadiness{
+		Type:    polardbx.ExporterReadinessExec,
+		Command: []string{"/bin/true"},
+	}, 8081)
+	g.Expect(handler.Exec.Command).To(gomega.Equal([]string{"/bin/true"}))
+
+	// exec without command falls back to tcp
+	handler = newExporterReadinessHandler(&polardbx.ExporterReadiness{Type: polardbx.ExporterReadinessExec}, 8081)
+	g.Expect(handler.Exec).To(gomega.BeNil())
+	g.Expect(handler.TCPSocket).NotTo(gomega.BeNil())
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package factory
 
 import (
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/convention"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/helper"
 	"github.com/alibaba/polardbx-operator/pkg/util/defaults"
@@ -42,8 +43,32 @@ func (f *objectFactory) NewSecretForRestore() (*corev1.Secret, error) {
 	}
 
 	data := make(map[string][]byte)
-	for user, passwd := range originalSecret.Data {
-		data[user] = passwd
+	restore := polardbx.Spec.Restore
+	switch restore.AccountRestorePolicy {
+	case "", polardbxv1polardbx.AccountRestoreAll:
+		for user, passwd := range originalSecret.Data {
+			data[user] = passwd
+		}
+	default:
+		// accounts declared by the restored cluster first, then the selected ones from backup
+		declaredSecret, err := f.NewSecret()
+		if err != nil {
+			return nil, err
+		}
+		for user, passwd := range declaredSecret.StringData {
+			data[user] = []byte(passwd)
+		}
+		if restore.AccountRestorePolicy == polardbxv1polardbx.AccountRestoreSelected {
+			for _, user := range restore.Accounts {
+				if passwd, ok := originalSecret.Data[user]; ok {
+					data[user] = passwd
+				}
+			}
+		}
+	}
+	// root account is mandatory, passwords of accounts not from backup are synced into GMS after restore
+	if _, ok := data[convention.RootAccount]; !ok {
+		data[convention.RootAccount] = []byte(rand.String(8))
 	}
 	secret.Data = data
 	return secret, nil
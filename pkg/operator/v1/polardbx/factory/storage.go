@@ -24,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -518,6 +519,17 @@ func (f *objectFactory) GetOriginalXstoreNameForRestore(polardbx polardbxv1.Pola
 	if err != nil {
 		return "", err
 	}
+	if len(polardbx.Spec.Restore.XStoreNameMapping) > 0 {
+		sources, err := ResolveXStoreNameMapping(&polardbx, backup.Status.XStores)
+		if err != nil {
+			return "", err
+		}
+		source, ok := sources[name]
+		if !ok {
+			return "", fmt.Errorf("xstore %s is not mapped from any xstore of backup set", name)
+		}
+		return source, nil
+	}
 	for _, xstoreName := range backup.Status.XStores {
 		if xstoreName[len(xstoreName)-4:] == name[len(name)-4:] { // safe when quantity of dn less than 10000
 			return xstoreName, nil
@@ -539,6 +551,64 @@ func (f *objectFactory) GetOriginalXstoreNameForRestore(polardbx polardbxv1.Pola
 	return "", errors.New("failed to find matched xstore")
 }
 
+// ResolveXStoreNameMapping validates the xstore name mapping of restore against xstores of the backup set and
+// xstores to be created for the restored cluster, and returns the source xstore of each restored xstore.
+func ResolveXStoreNameMapping(polardbx *polardbxv1.PolarDBXCluster, backupXStores []string) (map[string]string, error) {
+	prefix := polardbx.Name + "-" + polardbx.Status.Rand + "-"
+	targetNames := make([]string, 0)
+	if !polardbx.Spec.ShareGMS {
+		targetNames = append(targetNames, convention.NewGMSName(polardbx))
+	}
+	for i := 0; i < int(polardbx.Spec.Topology.Nodes.DN.Replicas); i++ {
+		targetNames = append(targetNames, convention.NewDNName(polardbx, i))
+	}
+	targets := make(map[string]bool, len(targetNames))
+	for _, target := range targetNames {
+		targets[target] = true
+	}
+	backupXStoreSet := make(map[string]bool, len(backupXStores))
+	for _, xstoreName := range backupXStores {
+		backupXStoreSet[xstoreName] = true
+	}
+
+	mapping := polardbx.Spec.Restore.XStoreNameMapping
+	sourceNames := make([]string, 0, len(mapping))
+	for source := range mapping {
+		sourceNames = append(sourceNames, source)
+	}
+	sort.Strings(sourceNames)
+
+	sources := make(map[string]string, len(mapping))
+	for _, source := range sourceNames {
+		if !backupXStoreSet[source] {
+			return nil, fmt.Errorf("xstore %s in name mapping is not found in backup set, xstores of backup set are %s",
+				source, strings.Join(backupXStores, ","))
+		}
+		target := mapping[source]
+		if !strings.HasPrefix(target, prefix) {
+			target = prefix + target
+		}
+		if !targets[target] {
+			return nil, fmt.Errorf("xstore %s is mapped to %s, which is not a xstore of the restored cluster", source, mapping[source])
+		}
+		if conflict, ok := sources[target]; ok {
+			return nil, fmt.Errorf("xstores %s and %s are both mapped to %s", conflict, source, target)
+		}
+		sources[target] = source
+	}
+	for _, xstoreName := range backupXStores {
+		if _, ok := mapping[xstoreName]; !ok {
+			return nil, fmt.Errorf("xstore %s of backup set is not mapped", xstoreName)
+		}
+	}
+	for _, target := range targetNames {
+		if _, ok := sources[target]; !ok {
+			return nil, fmt.Errorf("xstore %s of the restored cluster is not mapped from any xstore of backup set", target)
+		}
+	}
+	return sources, nil
+}
+
 func (f *objectFactory) GetXStoreBackupName(backupName, xstoreName string) (string, error) {
 	backup, err := f.rc.GetPXCBackupByName(backupName)
 	if err != nil {
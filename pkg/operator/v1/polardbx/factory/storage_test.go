@@ -0,0 +1,78 @@
+package factory
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+)
+
+func TestResolveXStoreNameMapping(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	backupXStores := []string{"src-abcd-gms", "src-abcd-dn-0", "src-abcd-dn-1"}
+	newPolarDBX := func(mapping map[string]string) *polardbxv1.PolarDBXCluster {
+		polardbx := &polardbxv1.PolarDBXCluster{}
+		polardbx.Name = "dst"
+		polardbx.Status.Rand = "wxyz"
+		polardbx.Spec.Topology.Nodes.DN.Replicas = 2
+		polardbx.Spec.Restore = &polardbxv1polardbx.RestoreSpec{XStoreNameMapping: mapping}
+		return polardbx
+	}
+
+	// targets in full or short names
+	sources, err := ResolveXStoreNameMapping(newPolarDBX(map[string]string{
+		"src-abcd-gms":  "gms",
+		"src-abcd-dn-0": "dn-1",
+		"src-abcd-dn-1": "dst-wxyz-dn-0",
+	}), backupXStores)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(sources).To(gomega.Equal(map[string]string{
+		"dst-wxyz-gms":  "src-abcd-gms",
+		"dst-wxyz-dn-0": "src-abcd-dn-1",
+		"dst-wxyz-dn-1": "src-abcd-dn-0",
+	}))
+
+	// unmapped xstore of backup set
+	_, err = ResolveXStoreNameMapping(newPolarDBX(map[string]string{
+		"src-abcd-gms":  "gms",
+		"src-abcd-dn-0": "dn-0",
+	}), backupXStores)
+	g.Expect(err).To(gomega.MatchError("xstore src-abcd-dn-1 of backup set is not mapped"))
+
+	// unknown source
+	_, err = ResolveXStoreNameMapping(newPolarDBX(map[string]string{
+		"src-abcd-gms":  "gms",
+		"src-abcd-dn-0": "dn-0",
+		"src-abcd-dn-2": "dn-1",
+	}), backupXStores)
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("xstore src-abcd-dn-2 in name mapping is not found in backup set"))
+
+	// conflicting targets
+	_, err = ResolveXStoreNameMapping(newPolarDBX(map[string]string{
+		"src-abcd-gms":  "gms",
+		"src-abcd-dn-0": "dn-0",
+		"src-abcd-dn-1": "dst-wxyz-dn-0",
+	}), backupXStores)
+	g.Expect(err).To(gomega.MatchError("xstores src-abcd-dn-0 and src-abcd-dn-1 are both mapped to dst-wxyz-dn-0"))
+
+	// target not in restored cluster
+	_, err = ResolveXStoreNameMapping(newPolarDBX(map[string]string{
+		"src-abcd-gms":  "gms",
+		"src-abcd-dn-0": "dn-0",
+		"src-abcd-dn-1": "dn-2",
+	}), backupXStores)
+	g.Expect(err).To(gomega.MatchError("xstore src-abcd-dn-1 is mapped to dn-2, which is not a xstore of the restored cluster"))
+
+	// restored xstore left unmapped
+	polardbx := newPolarDBX(map[string]string{
+		"src-abcd-gms":  "gms",
+		"src-abcd-dn-0": "dn-0",
+		"src-abcd-dn-1": "dn-1",
+	})
+	polardbx.Spec.Topology.Nodes.DN.Replicas = 3
+	_, err = ResolveXStoreNameMapping(polardbx, backupXStores)
+	g.Expect(err).To(gomega.MatchError("xstore dst-wxyz-dn-2 of the restored cluster is not mapped from any xstore of backup set"))
+}
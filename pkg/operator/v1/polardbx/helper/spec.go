@@ -21,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 
 	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
 )
 
 func IsTopologyOrStaticConfigChanges(polardbx *polardbxv1.PolarDBXCluster) bool {
@@ -92,3 +93,14 @@ func IsTdeOpen(polardbx *polardbxv1.PolarDBXCluster) bool {
 	}
 	return false
 }
+
+// IsBinlogApplyRequested returns true if binlog of a backup set is requested to be applied, while not
+// applied or applying.
+func IsBinlogApplyRequested(polardbx *polardbxv1.PolarDBXCluster) bool {
+	if polardbx.Spec.BinlogApply == nil || polardbx.Spec.BinlogApply.BackupSet == "" {
+		return false
+	}
+	status := polardbx.Status.BinlogApplyStatus
+	return status == nil || status.BackupSet != polardbx.Spec.BinlogApply.BackupSet ||
+		status.Phase == polardbxv1polardbx.BinlogApplyApplying
+}
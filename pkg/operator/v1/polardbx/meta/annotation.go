@@ -21,12 +21,34 @@ const (
 	AnnotationControllerHints        = "polardbx/controller.hints"
 	AnnotationEnableRebalanceOnScale = "polardbx/scale.enable-rebalance"
 	AnnotationSchemaCaseInsensitive  = "polardbx/schema.case-insensitive"
+
+	// AnnotationJobDeletePropagation overrides the propagation policy to delete jobs of cluster, either
+	// "Background" or "Foreground". Operator config is used if absent.
+	AnnotationJobDeletePropagation = "polardbx/job-delete-propagation"
 )
 
 const (
 	HintForbidden = "forbidden"
 )
 
+// Pod annotations
+const (
+	// AnnotationDrain denotes the pod is draining ahead of a controlled shutdown if "true". Readiness of the pod
+	// fails, so that it's removed from the endpoints of services while in-flight requests complete.
+	AnnotationDrain = "polardbx/drain"
+)
+
+// CDC annotations
+const (
+	// AnnotationCDCStartupCatchupTime denotes the expected time for cdc engine to catch up backlogs after
+	// restart, e.g. "30m". Startup probe of cdc engine tolerates failures within the time.
+	AnnotationCDCStartupCatchupTime = "polardbx/cdc-startup-catchup-time"
+
+	// AnnotationCDCReadinessHttpPath denotes the http health endpoint of cdc engine, e.g. "/status". Readiness
+	// of cdc engine is checked by both tcp and http if specified, otherwise tcp only.
+	AnnotationCDCReadinessHttpPath = "polardbx/cdc-readiness-http-path"
+)
+
 // Guide annotations
 const (
 	AnnotationConfigGuide       = "polardbx/config-guide"
@@ -38,12 +60,21 @@ const (
 const (
 	AnnotationDummyBackup  = "polardbx/dummy-backup"
 	AnnotationBackupBinlog = "polardbx/backupbinlog"
+
+	// AnnotationProtectedBackup protects backup set from being purged by backup schedule if "true"
+	AnnotationProtectedBackup = "polardbx-backup/protected"
+
+	// AnnotationBackupCreatedBy records who created the backup, which is carried in the audit record of backup
+	AnnotationBackupCreatedBy = "polardbx-backup/created-by"
 )
 
 // Restore annotations
 const (
 	// AnnotationImmutableBackupSetPath denotes whether mutate webhook is enabled for RestoreSpec.From.BackupSetPath
 	AnnotationImmutableBackupSetPath = "polardbx/immutable-backup-set-path"
+
+	// AnnotationSkipGMSSchemaCheck denotes whether to restore GMS of schema versions newer than supported
+	AnnotationSkipGMSSchemaCheck = "polardbx/skip-gms-schema-check"
 )
 
 const (
@@ -36,6 +36,7 @@ const (
 	LabelType            = "polardbx/type"
 	LabelAuditLog        = "polardbx/enableAuditLog"
 	LabelBackupSchedule  = "polardbx/backup-schedule"
+	LabelBackupClass     = "polardbx/backup-class"
 	LabelBackupBinlog    = "polardbx/backupBinlog"
 	LabelJobType         = "polardbx/jobType"
 	LabelIsolateCpu      = "polardbx/isolate-cpu"
@@ -48,6 +49,12 @@ const (
 	SeekCpJobLabelPodName = "seekcp-job/pod"
 )
 
+const (
+	ConsolidateJobLabelBackupName = "consolidate-job/backup"
+	// ConsolidateJobLabelPodName denotes the pod on which consolidate job performed
+	ConsolidateJobLabelPodName = "consolidate-job/pod"
+)
+
 const (
 	RoleGMS      = "gms"
 	RoleCN       = "cn"
@@ -65,6 +72,18 @@ const (
 	SeekCpName        = "set.cp"
 	BinlogIndexesName = "indexes"
 	KeyringPath       = "keyring"
+
+	// ChunkManifestSuffix is appended to the path of full backup file to store its chunk manifest
+	ChunkManifestSuffix = ".chunks"
+	// KeyringChecksumSuffix is appended to the path of keyring file to store its sha256 checksum
+	KeyringChecksumSuffix = ".sha256"
+	// FullBackupArchiveName is the name of archive under full backup path, which packs full backups of xstores
+	FullBackupArchiveName = "archive"
+	// MetadataName is the name of metadata under root path of backup set, which is read by restore
+	MetadataName = "metadata"
+	// PartialMetadataName is the name of metadata uploaded before binlog backup finished, which lacks the
+	// recoverable timestamp fields and is never read by restore
+	PartialMetadataName = MetadataName + ".partial"
 )
 
 func AssertRoleIn(role string, candidates ...string) {
@@ -91,4 +110,5 @@ type PxcJobType string
 const (
 	HeartbeatJobType         PxcJobType = "PitrHeartbeat"
 	PitrPrepareBinlogJobType PxcJobType = "PitrPrepareBinlogs"
+	BinlogApplyJobType       PxcJobType = "BinlogApply"
 )
@@ -111,7 +111,7 @@ type Context struct {
 	taskConfigMap *corev1.ConfigMap
 
 	// Filestream client
-	filestreamClient *filestream.FileClient
+	filestreamClient filestream.Client
 
 	//hpfs client
 	hpfsConn   *grpc.ClientConn
@@ -134,6 +134,17 @@ func (rc *Context) Config() config.Config {
 	return rc.configLoader()
 }
 
+// JobDeletePropagation returns the propagation policy to delete jobs of cluster, annotation of cluster overrides
+// the operator config.
+func (rc *Context) JobDeletePropagation() metav1.DeletionPropagation {
+	defaultPolicy := rc.Config().Cluster().JobDeletePropagation()
+	polardbx, err := rc.GetPolarDBX()
+	if err != nil {
+		return defaultPolicy
+	}
+	return k8shelper.JobDeletePropagationOrDefault(polardbx.Annotations[polardbxmeta.AnnotationJobDeletePropagation], defaultPolicy)
+}
+
 func (rc *Context) GetNodesSortedByName() ([]corev1.Node, error) {
 	if rc.nodes == nil {
 		var nodeList corev1.NodeList
@@ -1425,6 +1436,18 @@ func (rc *Context) GetLastCompletedPXCBackup(matchLabels map[string]string, befo
 		if backup.Status.Phase != polardbxv1.BackupFinished {
 			continue
 		}
+		// partial backup set is not able to restore the whole cluster
+		if backup.Status.Partial {
+			continue
+		}
+		// backup set of filtered databases is not able to restore the whole cluster
+		if backup.IsDatabaseFiltered() {
+			continue
+		}
+		// snapshot-only backup set is not able to recover to a point in time
+		if backup.IsSnapshotOnly() {
+			continue
+		}
 		if backup.Status.LatestRecoverableTimestamp.After(beforeTime) {
 			continue
 		}
@@ -1739,7 +1762,12 @@ func (rc *Context) NewSecretFromPolarDBX(secret *corev1.Secret) (*corev1.Secret,
 	}, nil
 }
 
-func (rc *Context) GetFilestreamClient() (*filestream.FileClient, error) {
+// SetFilestreamClient overrides the filestream client, e.g. with a fake one in tests.
+func (rc *Context) SetFilestreamClient(filestreamClient filestream.Client) {
+	rc.filestreamClient = filestreamClient
+}
+
+func (rc *Context) GetFilestreamClient() (filestream.Client, error) {
 	if rc.filestreamClient == nil {
 		hostPort := strings.SplitN(rc.Config().Store().FilestreamServiceEndpoint(), ":", 2)
 		if len(hostPort) < 2 {
@@ -1749,7 +1777,18 @@ func (rc *Context) GetFilestreamClient() (*filestream.FileClient, error) {
 		if err != nil {
 			return nil, errors.New("invalid filestream port: " + hostPort[1])
 		}
-		rc.filestreamClient = filestream.NewFileClient(hostPort[0], port, nil)
+		backoff, err := rc.Config().Store().FilestreamConnectBackoff()
+		if err != nil {
+			return nil, errors.New("invalid filestream connect backoff: " + err.Error())
+		}
+
+		// Retry on transient unavailability of filestream server, e.g. hpfs restarting.
+		filestreamClient := filestream.NewFileClient(hostPort[0], port, nil)
+		err = filestream.PingWithRetry(filestreamClient, rc.Config().Store().FilestreamConnectAttempts(), backoff)
+		if err != nil {
+			return nil, err
+		}
+		rc.filestreamClient = filestreamClient
 	}
 	return rc.filestreamClient, nil
 }
@@ -1818,6 +1857,16 @@ func (rc *Context) GetPolarDBXBackupListByLabels(labels map[string]string) (*pol
 	return &backupList, nil
 }
 
+func (rc *Context) GetXStoreBackupListByPolarDBXName(polardbxName string) (*polardbxv1.XStoreBackupList, error) {
+	var xstoreBackupList polardbxv1.XStoreBackupList
+	err := rc.Client().List(rc.Context(), &xstoreBackupList, client.InNamespace(rc.Namespace()),
+		client.MatchingLabels{polardbxmeta.LabelName: polardbxName})
+	if err != nil {
+		return nil, err
+	}
+	return &xstoreBackupList, nil
+}
+
 func (rc *Context) SetBackupBinlogKey(key types.NamespacedName) {
 	rc.backupBinlogKey = key
 }
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+)
+
+// earliestRecoverableTimestamp returns the latest consistent point of xstores in the backup set, before which
+// the cluster can not be recovered to. End time of backup is used if no consistent point recorded.
+func earliestRecoverableTimestamp(backup *polardbxv1.PolarDBXBackup) *metav1.Time {
+	var earliest *metav1.Time
+	for _, timestamp := range backup.Status.BackupSetTimestamp {
+		if timestamp != nil && (earliest == nil || earliest.Before(timestamp)) {
+			earliest = timestamp
+		}
+	}
+	if earliest == nil {
+		earliest = backup.Status.EndTime
+	}
+	return earliest.DeepCopy()
+}
+
+// NewBackupSetSummary summarizes the backup set, size is summed from the xstore backups of the backup set,
+// which are keyed by name.
+func NewBackupSetSummary(backup *polardbxv1.PolarDBXBackup, xstoreBackups map[string]*polardbxv1.XStoreBackup) polardbxv1.BackupSetSummary {
+	summary := polardbxv1.BackupSetSummary{
+		Name:        backup.Name,
+		Phase:       backup.Status.Phase,
+		BackupMode:  backup.Spec.BackupMode,
+		StartTime:   backup.Status.StartTime.DeepCopy(),
+		EndTime:     backup.Status.EndTime.DeepCopy(),
+		StorageName: backup.Spec.StorageProvider.StorageName,
+		Sink:        backup.Spec.StorageProvider.Sink,
+		Partial:     backup.Status.Partial,
+	}
+	if summary.StorageName == "" {
+		summary.StorageName = backup.Status.StorageName
+	}
+	if backup.Status.Phase == polardbxv1.BackupFinished || backup.Status.Phase == polardbxv1.BackupDummy {
+		summary.EarliestRecoverableTimestamp = earliestRecoverableTimestamp(backup)
+		summary.LatestRecoverableTimestamp = backup.Status.LatestRecoverableTimestamp.DeepCopy()
+	}
+	for _, xstoreBackupName := range backup.Status.Backups {
+		if xstoreBackup, ok := xstoreBackups[xstoreBackupName]; ok {
+			summary.Size += xstoreBackup.Status.FullBackupSize
+		}
+	}
+	return summary
+}
+
+// NewCatalogStatus builds the catalog status from the backups of cluster and their xstore backups, backup sets
+// are sorted by creation time. Last update time is left empty.
+func NewCatalogStatus(backups []polardbxv1.PolarDBXBackup, xstoreBackups []polardbxv1.XStoreBackup) polardbxv1.PolarDBXBackupCatalogStatus {
+	xstoreBackupMap := make(map[string]*polardbxv1.XStoreBackup, len(xstoreBackups))
+	for i := range xstoreBackups {
+		xstoreBackupMap[xstoreBackups[i].Name] = &xstoreBackups[i]
+	}
+
+	sorted := make([]*polardbxv1.PolarDBXBackup, 0, len(backups))
+	for i := range backups {
+		sorted = append(sorted, &backups[i])
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].CreationTimestamp.Equal(&sorted[j].CreationTimestamp) {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].CreationTimestamp.Before(&sorted[j].CreationTimestamp)
+	})
+
+	status := polardbxv1.PolarDBXBackupCatalogStatus{}
+	for _, backup := range sorted {
+		summary := NewBackupSetSummary(backup, xstoreBackupMap)
+		status.BackupSets = append(status.BackupSets, summary)
+		status.TotalSize += summary.Size
+	}
+	status.BackupSetCount = int32(len(status.BackupSets))
+	return status
+}
+
+// IsCatalogStatusChanged tells whether the catalog status differs from the current one, last update time ignored.
+func IsCatalogStatusChanged(current, status *polardbxv1.PolarDBXBackupCatalogStatus) bool {
+	current, status = current.DeepCopy(), status.DeepCopy()
+	current.LastUpdateTime, status.LastUpdateTime = nil, nil
+	return !equality.Semantic.DeepEqual(current, status)
+}
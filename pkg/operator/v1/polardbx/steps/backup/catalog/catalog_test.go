@@ -0,0 +1,92 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+)
+
+func newTime(minute int) *metav1.Time {
+	t := metav1.NewTime(time.Date(2023, 1, 1, 0, minute, 0, 0, time.UTC))
+	return &t
+}
+
+func TestNewCatalogStatus(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	finished := polardbxv1.PolarDBXBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup-1", CreationTimestamp: *newTime(0)},
+		Spec: polardbxv1.PolarDBXBackupSpec{
+			StorageProvider: polardbx.BackupStorageProvider{StorageName: polardbx.OSS, Sink: "default"},
+		},
+		Status: polardbxv1.PolarDBXBackupStatus{
+			Phase:     polardbxv1.BackupFinished,
+			StartTime: newTime(0),
+			EndTime:   newTime(30),
+			Backups:   map[string]string{"dn-0": "backup-1-dn-0", "dn-1": "backup-1-dn-1"},
+			BackupSetTimestamp: map[string]*metav1.Time{
+				"dn-0": newTime(10),
+				"dn-1": newTime(12),
+			},
+			LatestRecoverableTimestamp: newTime(25),
+		},
+	}
+	running := polardbxv1.PolarDBXBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup-2", CreationTimestamp: *newTime(60)},
+		Status: polardbxv1.PolarDBXBackupStatus{
+			Phase:     polardbxv1.FullBackuping,
+			StartTime: newTime(60),
+			Backups:   map[string]string{"dn-0": "backup-2-dn-0"},
+		},
+	}
+	xstoreBackups := []polardbxv1.XStoreBackup{
+		{ObjectMeta: metav1.ObjectMeta{Name: "backup-1-dn-0"}, Status: polardbxv1.XStoreBackupStatus{FullBackupSize: 100}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "backup-1-dn-1"}, Status: polardbxv1.XStoreBackupStatus{FullBackupSize: 200}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "backup-2-dn-0"}},
+	}
+
+	status := NewCatalogStatus([]polardbxv1.PolarDBXBackup{running, finished}, xstoreBackups)
+	g.Expect(status.BackupSetCount).To(gomega.BeEquivalentTo(2))
+	g.Expect(status.TotalSize).To(gomega.BeEquivalentTo(300))
+
+	// sorted by creation time
+	g.Expect(status.BackupSets[0].Name).To(gomega.Equal("backup-1"))
+	g.Expect(status.BackupSets[0].Size).To(gomega.BeEquivalentTo(300))
+	g.Expect(status.BackupSets[0].StorageName).To(gomega.Equal(polardbx.OSS))
+	g.Expect(status.BackupSets[0].Sink).To(gomega.Equal("default"))
+	g.Expect(status.BackupSets[0].EarliestRecoverableTimestamp.Equal(newTime(12))).To(gomega.BeTrue())
+	g.Expect(status.BackupSets[0].LatestRecoverableTimestamp.Equal(newTime(25))).To(gomega.BeTrue())
+
+	// no recoverable window before finished
+	g.Expect(status.BackupSets[1].Name).To(gomega.Equal("backup-2"))
+	g.Expect(status.BackupSets[1].Phase).To(gomega.Equal(polardbxv1.FullBackuping))
+	g.Expect(status.BackupSets[1].EarliestRecoverableTimestamp).To(gomega.BeNil())
+	g.Expect(status.BackupSets[1].LatestRecoverableTimestamp).To(gomega.BeNil())
+
+	// unchanged regardless of last update time
+	current := status.DeepCopy()
+	current.LastUpdateTime = newTime(90)
+	g.Expect(IsCatalogStatusChanged(current, &status)).To(gomega.BeFalse())
+	current.BackupSets[1].Phase = polardbxv1.BackupFinished
+	g.Expect(IsCatalogStatusChanged(current, &status)).To(gomega.BeTrue())
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/config"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+	"github.com/alibaba/polardbx-operator/pkg/util/path"
+)
+
+// auditRecordWriter is where audit records go when sink is stdout.
+var auditRecordWriter io.Writer = os.Stdout
+
+// BackupAuditXStore records the full backup of a single xstore in audit record.
+type BackupAuditXStore struct {
+	XStore      string                           `json:"xstore"`
+	Backup      string                           `json:"backup"`
+	StorageName polardbxv1polardbx.BackupStorage `json:"storageName,omitempty"`
+	Sink        string                           `json:"sink,omitempty"`
+	Size        int64                            `json:"size"`
+}
+
+// BackupAuditRecord is the structured audit record of a backup in terminal state.
+type BackupAuditRecord struct {
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	UID       types.UID `json:"uid"`
+	Cluster   string    `json:"cluster"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+
+	StorageName    polardbxv1polardbx.BackupStorage `json:"storageName,omitempty"`
+	Sink           string                           `json:"sink,omitempty"`
+	BackupRootPath string                           `json:"backupRootPath,omitempty"`
+
+	Outcome       polardbxv1.PolarDBXBackupPhase         `json:"outcome"`
+	Reason        string                                 `json:"reason,omitempty"`
+	FailureReason polardbxv1polardbx.BackupFailureReason `json:"failureReason,omitempty"`
+	Message       string                                 `json:"message,omitempty"`
+	Verified      corev1.ConditionStatus                 `json:"verified,omitempty"`
+
+	XStores   []BackupAuditXStore `json:"xstores,omitempty"`
+	TotalSize int64               `json:"totalSize"`
+
+	CreationTime metav1.Time  `json:"creationTime"`
+	StartTime    *metav1.Time `json:"startTime,omitempty"`
+	EndTime      *metav1.Time `json:"endTime,omitempty"`
+	RecordTime   metav1.Time  `json:"recordTime"`
+}
+
+// backupCreatedBy tells who created the backup, by annotation if specified, otherwise by the schedule label.
+func backupCreatedBy(backup *polardbxv1.PolarDBXBackup) string {
+	if createdBy := backup.Annotations[polardbxmeta.AnnotationBackupCreatedBy]; createdBy != "" {
+		return createdBy
+	}
+	if schedule := backup.Labels[polardbxmeta.LabelBackupSchedule]; schedule != "" {
+		return "schedule/" + schedule
+	}
+	return ""
+}
+
+func newBackupAuditRecord(backup *polardbxv1.PolarDBXBackup, xstoreBackups []polardbxv1.XStoreBackup,
+	now metav1.Time) *BackupAuditRecord {
+	record := &BackupAuditRecord{
+		Kind:           "PolarDBXBackup",
+		Namespace:      backup.Namespace,
+		Name:           backup.Name,
+		UID:            backup.UID,
+		Cluster:        backup.Spec.Cluster.Name,
+		CreatedBy:      backupCreatedBy(backup),
+		StorageName:    backup.Spec.StorageProvider.StorageName,
+		Sink:           backup.Spec.StorageProvider.Sink,
+		BackupRootPath: backup.Status.BackupRootPath,
+		Outcome:        backup.Status.Phase,
+		Reason:         backup.Status.Reason,
+		FailureReason:  backup.Status.FailureReason,
+		Message:        backup.Status.Message,
+		CreationTime:   backup.CreationTimestamp,
+		StartTime:      backup.Status.StartTime,
+		EndTime:        backup.Status.EndTime,
+		RecordTime:     now,
+	}
+	for _, cond := range backup.Status.Conditions {
+		if cond.Type == polardbxv1.PolarDBXBackupVerified {
+			record.Verified = cond.Status
+		}
+	}
+	for _, xstoreBackup := range xstoreBackups {
+		storageProvider := backup.XStoreStorageProvider(xstoreBackup.Spec.XStore.Name)
+		record.XStores = append(record.XStores, BackupAuditXStore{
+			XStore:      xstoreBackup.Spec.XStore.Name,
+			Backup:      xstoreBackup.Name,
+			StorageName: storageProvider.StorageName,
+			Sink:        storageProvider.Sink,
+			Size:        xstoreBackup.Status.FullBackupSize,
+		})
+		record.TotalSize += xstoreBackup.Status.FullBackupSize
+	}
+	sort.Slice(record.XStores, func(i, j int) bool {
+		return record.XStores[i].XStore < record.XStores[j].XStore
+	})
+	return record
+}
+
+// EmitBackupAuditRecord emits the audit record of backup in terminal state to the configured sink, which is either
+// stdout of operator as a json line or an object under the audit directory of cluster in storage. It's emitted once
+// and recorded as condition Audited, and skipped if no audit sink is configured.
+var EmitBackupAuditRecord = polardbxv1reconcile.NewStepBinder("EmitBackupAuditRecord",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetPolarDBXBackup()
+		sink := rc.Config().Backup().GetAuditSink()
+		if sink == "" || hasBackupCondition(backup, polardbxv1.PolarDBXBackupAudited) {
+			return flow.Pass()
+		}
+
+		xstoreBackups, err := rc.GetXStoreBackups()
+		if err != nil {
+			return flow.RetryAfter(10*time.Second, "Failed to get xstore backups, error: "+err.Error())
+		}
+		record := newBackupAuditRecord(backup, xstoreBackups.Items, metav1.Now())
+		data, err := json.Marshal(record)
+		if err != nil {
+			return flow.Error(err, "Failed to marshal audit record.")
+		}
+
+		cond := polardbxv1polardbx.Condition{
+			Type:    polardbxv1.PolarDBXBackupAudited,
+			Status:  corev1.ConditionTrue,
+			Reason:  "AuditRecorded",
+			Message: "audit record emitted to " + sink,
+		}
+		switch sink {
+		case config.BackupAuditSinkStdout:
+			if _, err := auditRecordWriter.Write(append(data, '\n')); err != nil {
+				return flow.RetryAfter(10*time.Second, "Failed to write audit record, error: "+err.Error())
+			}
+		case config.BackupAuditSinkStorage:
+			filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(backup.Spec.StorageProvider.StorageName)
+			if err != nil {
+				// backup failed on unsupported storage can never be recorded there, keep the record in log instead
+				if _, err := auditRecordWriter.Write(append(data, '\n')); err != nil {
+					return flow.RetryAfter(10*time.Second, "Failed to write audit record, error: "+err.Error())
+				}
+				cond.Reason = "StorageUnavailable"
+				cond.Message = "unsupported storage, audit record emitted to " + config.BackupAuditSinkStdout
+				break
+			}
+			filestreamClient, err := rc.GetFilestreamClient()
+			if err != nil {
+				return flow.RetryAfter(10*time.Second, "Failed to get filestream client, error: "+err.Error())
+			}
+			auditPath := path.NewPathFromStringSequence(polardbxmeta.BackupPath, backup.Spec.Cluster.Name, "audit",
+				fmt.Sprintf("%s-%s-%s.json", backup.Name, backup.UID, backup.Status.Phase))
+			actionMetadata := filestream.ActionMetadata{
+				Action:    filestreamAction.Upload,
+				Sink:      backup.Spec.StorageProvider.Sink,
+				RequestId: uuid.New().String(),
+				Filename:  auditPath,
+			}
+			actionMetadata.SSEAlgorithm, actionMetadata.SSEKMSKeyId = backup.Spec.StorageProvider.GetServerSideEncryption()
+			actionMetadata.EndpointType = string(backup.Spec.StorageProvider.EndpointType)
+			if _, err := filestreamClient.Upload(bytes.NewReader(data), actionMetadata); err != nil {
+				return flow.RetryAfter(10*time.Second, "Failed to upload audit record, error: "+err.Error())
+			}
+			cond.Message = "audit record uploaded to " + auditPath
+		}
+		setBackupCondition(backup, cond)
+		return flow.Continue("Audit record emitted.", "sink", sink, "outcome", record.Outcome)
+	})
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+)
+
+func TestNewBackupAuditRecord(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	backup := &polardbxv1.PolarDBXBackup{}
+	backup.Name, backup.Namespace, backup.UID = "b", "ns", "uid"
+	backup.Labels = map[string]string{polardbxmeta.LabelBackupSchedule: "daily"}
+	backup.Spec.Cluster.Name = "pxc"
+	backup.Spec.StorageProvider = polardbxv1polardbx.BackupStorageProvider{StorageName: "oss", Sink: "default"}
+	backup.Status.Phase = polardbxv1.BackupFailed
+	backup.Status.FailureReason = polardbxv1polardbx.BackupFailureReason("Timeout")
+	backup.Status.XStoreStorageProviders = map[string]polardbxv1polardbx.BackupStorageProvider{
+		"pxc-dn-0": {StorageName: "s3", Sink: "archive"},
+	}
+	backup.Status.Conditions = []polardbxv1polardbx.Condition{
+		{Type: polardbxv1.PolarDBXBackupVerified, Status: corev1.ConditionFalse},
+	}
+
+	xstoreBackups := make([]polardbxv1.XStoreBackup, 2)
+	xstoreBackups[0].Name, xstoreBackups[0].Spec.XStore.Name = "b-gms", "pxc-gms"
+	xstoreBackups[0].Status.FullBackupSize = 100
+	xstoreBackups[1].Name, xstoreBackups[1].Spec.XStore.Name = "b-dn-0", "pxc-dn-0"
+	xstoreBackups[1].Status.FullBackupSize = 200
+
+	now := metav1.Now()
+	record := newBackupAuditRecord(backup, xstoreBackups, now)
+	g.Expect(record.CreatedBy).To(gomega.Equal("schedule/daily"))
+	g.Expect(record.Outcome).To(gomega.Equal(polardbxv1.BackupFailed))
+	g.Expect(record.FailureReason).To(gomega.BeEquivalentTo("Timeout"))
+	g.Expect(record.Verified).To(gomega.Equal(corev1.ConditionFalse))
+	g.Expect(record.TotalSize).To(gomega.BeEquivalentTo(300))
+	g.Expect(record.RecordTime).To(gomega.Equal(now))
+	g.Expect(record.XStores).To(gomega.Equal([]BackupAuditXStore{
+		{XStore: "pxc-dn-0", Backup: "b-dn-0", StorageName: "s3", Sink: "archive", Size: 200},
+		{XStore: "pxc-gms", Backup: "b-gms", StorageName: "oss", Sink: "default", Size: 100},
+	}))
+
+	// annotation takes precedence over schedule
+	backup.Annotations = map[string]string{polardbxmeta.AnnotationBackupCreatedBy: "alice"}
+	g.Expect(backupCreatedBy(backup)).To(gomega.Equal("alice"))
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+)
+
+// getXStoreBackup gets the xstore backup from api server directly, as the cache may not have seen the one
+// created by a previous reconcile. Nil is returned if not found, and error if it's not controlled by backup.
+func getXStoreBackup(rc *polardbxv1reconcile.Context, key client.ObjectKey) (*polardbxv1.XStoreBackup, error) {
+	backup := rc.MustGetPolarDBXBackup()
+	var xstoreBackup polardbxv1.XStoreBackup
+	err := rc.APIReader().Get(rc.Context(), key, &xstoreBackup)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !metav1.IsControlledBy(&xstoreBackup, backup) {
+		return nil, fmt.Errorf("xstore backup %s exists but is not controlled by backup %s", key.Name, backup.Name)
+	}
+	return &xstoreBackup, nil
+}
+
+// ensureXStoreBackup creates the xstore backup of xstore, so that exactly one child is created for each xstore
+// by concurrent reconciles or reconciles of successive leaders. Name of the xstore backup is determined by pxc
+// backup and xstore, so that the duplicate is rejected by api server. The existing one is returned if created
+// before.
+func ensureXStoreBackup(rc *polardbxv1reconcile.Context,
+	newXStoreBackup func() (*polardbxv1.XStoreBackup, error)) (*polardbxv1.XStoreBackup, error) {
+	xstoreBackup, err := newXStoreBackup()
+	if err != nil {
+		return nil, err
+	}
+	key := client.ObjectKeyFromObject(xstoreBackup)
+	existing, err := getXStoreBackup(rc, key)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	err = rc.SetControllerRefAndCreateToBackup(xstoreBackup)
+	// created by another reconcile in the meantime
+	if apierrors.IsAlreadyExists(err) {
+		existing, err = getXStoreBackup(rc, key)
+		if err == nil && existing == nil {
+			err = fmt.Errorf("xstore backup %s already exists but not found", key.Name)
+		}
+		return existing, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return xstoreBackup, nil
+}
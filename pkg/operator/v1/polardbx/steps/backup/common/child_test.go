@@ -0,0 +1,148 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+)
+
+func newXStoreBackupOfDN0() (*polardbxv1.XStoreBackup, error) {
+	return &polardbxv1.XStoreBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "pxb-dn-0",
+			Labels: map[string]string{
+				polardbxmeta.LabelTopBackup:    "pxb",
+				polardbxmeta.LabelBackupXStore: "dn-0",
+			},
+		},
+	}, nil
+}
+
+// newChildTestContext returns the reconcile context of backup, whose cached client never finds any xstore backup,
+// while the api reader sees the ones created.
+func newChildTestContext(scheme *runtime.Scheme, apiReader client.Client) *polardbxv1reconcile.Context {
+	staleCache := interceptor.NewClient(apiReader.(client.WithWatch), interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if _, ok := obj.(*polardbxv1.XStoreBackup); ok {
+				return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if _, ok := list.(*polardbxv1.XStoreBackupList); ok {
+				return nil
+			}
+			return c.List(ctx, list, opts...)
+		},
+	})
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "pxb"}}
+	base := control.NewBaseReconcileContext(staleCache, nil, nil, scheme, context.Background(), request)
+	base.SetAPIReader(apiReader)
+	return polardbxv1reconcile.NewContext(base, nil)
+}
+
+func TestEnsureXStoreBackupConcurrently(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(polardbxv1.AddToScheme(scheme)).To(gomega.Succeed())
+	backup := &polardbxv1.PolarDBXBackup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pxb", UID: "pxb-uid"},
+	}
+	var creates int32
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(backup).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				err := c.Create(ctx, obj, opts...)
+				if err == nil {
+					atomic.AddInt32(&creates, 1)
+				}
+				return err
+			},
+		}).Build()
+
+	var wg sync.WaitGroup
+	names := make(chan string, 8)
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			xstoreBackup, err := ensureXStoreBackup(newChildTestContext(scheme, c), newXStoreBackupOfDN0)
+			errs <- err
+			if xstoreBackup != nil {
+				names <- xstoreBackup.Name
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	close(names)
+	for err := range errs {
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+	for name := range names {
+		g.Expect(name).To(gomega.Equal("pxb-dn-0"))
+	}
+
+	var xstoreBackupList polardbxv1.XStoreBackupList
+	g.Expect(c.List(context.Background(), &xstoreBackupList)).To(gomega.Succeed())
+	g.Expect(xstoreBackupList.Items).To(gomega.HaveLen(1))
+	g.Expect(metav1.IsControlledBy(&xstoreBackupList.Items[0], backup)).To(gomega.BeTrue())
+	g.Expect(creates).To(gomega.Equal(int32(1)))
+
+	// created before but not seen by the cache, e.g. of a new leader
+	xstoreBackup, err := ensureXStoreBackup(newChildTestContext(scheme, c), newXStoreBackupOfDN0)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(xstoreBackup.Name).To(gomega.Equal("pxb-dn-0"))
+	g.Expect(creates).To(gomega.Equal(int32(1)))
+}
+
+func TestEnsureXStoreBackupNotControlled(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(polardbxv1.AddToScheme(scheme)).To(gomega.Succeed())
+	backup := &polardbxv1.PolarDBXBackup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pxb", UID: "pxb-uid"},
+	}
+	other, _ := newXStoreBackupOfDN0()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(backup, other).Build()
+
+	_, err := ensureXStoreBackup(newChildTestContext(scheme, c), newXStoreBackupOfDN0)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("not controlled by backup pxb")))
+}
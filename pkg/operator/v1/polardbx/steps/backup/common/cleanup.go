@@ -8,37 +8,88 @@ import (
 	hpfs "github.com/alibaba/polardbx-operator/pkg/hpfs/proto"
 	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
 	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 var CleanRemoteBackupFiles = polardbxv1reconcile.NewStepBinder("CleanRemoteBackupFile",
 	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		backup := rc.MustGetPolarDBXBackup()
+		protected, err := protectedXStoreBackups(rc)
+		if err != nil {
+			return flow.Error(err, "Unable to get xstore backups.")
+		}
+		if len(protected) > 0 {
+			// remote files of xstore backups are under root path of pxc backup
+			return flow.Continue("Xstore backups are protected, remote backup files retained.",
+				"xstore-backups", protected)
+		}
 		if backup.Spec.CleanPolicy == polardbx.CleanPolicyRetain ||
 			(backup.Spec.CleanPolicy == polardbx.CleanPolicyOnFailure && backup.Status.Phase != v1.BackupFailed) {
 			return flow.Continue("No need to clean remote backup files.")
 		}
+		if backup.Status.BackupRootPath == "" {
+			// root path is not assigned, or dropped due to collision with another backup set
+			return flow.Continue("No remote backup files to clean.")
+		}
 
 		client, err := rc.GetHpfsClient()
 		if err != nil {
 			return flow.Error(err, "Failed to get hpfs client.")
 		}
 
-		response, err := client.DeleteRemoteFile(rc.Context(), &hpfs.DeleteRemoteFileRequest{
-			SinkType: string(backup.Spec.StorageProvider.StorageName),
-			SinkName: backup.Spec.StorageProvider.Sink,
-			Target: &hpfs.RemoteFsEndpoint{
-				Path: backup.Status.BackupRootPath,
-				Other: map[string]string{
-					"recursive": "true",
+		// backup files of xstores overridden are stored in their own sinks under the same root path
+		sinks := []polardbx.BackupStorageProvider{backup.Spec.StorageProvider}
+		for _, provider := range backup.Status.XStoreStorageProviders {
+			if !containsSink(sinks, provider) {
+				sinks = append(sinks, provider)
+			}
+		}
+		for _, sink := range sinks {
+			response, err := client.DeleteRemoteFile(rc.Context(), &hpfs.DeleteRemoteFileRequest{
+				SinkType: string(sink.StorageName),
+				SinkName: sink.Sink,
+				Target: &hpfs.RemoteFsEndpoint{
+					Path: backup.Status.BackupRootPath,
+					Other: map[string]string{
+						"recursive": "true",
+					},
 				},
-			},
-		})
-		if response.GetStatus().Code != hpfs.Status_OK {
-			return flow.Error(errors.New("cleanup failure"),
-				fmt.Sprintf("reponse status code: %s, message: %s",
-					response.GetStatus().Code, response.GetStatus().Message))
+			})
+			if err != nil {
+				return flow.Error(err, "Failed to delete remote backup files.", "sink", sink.Sink)
+			}
+			if response.GetStatus().Code != hpfs.Status_OK {
+				return flow.Error(errors.New("cleanup failure"),
+					fmt.Sprintf("reponse status code: %s, message: %s",
+						response.GetStatus().Code, response.GetStatus().Message), "sink", sink.Sink)
+			}
 		}
 
 		return flow.Continue("Remote backup files cleaned.")
 	})
+
+// containsSink tells whether the sink of provider is in sinks.
+func containsSink(sinks []polardbx.BackupStorageProvider, provider polardbx.BackupStorageProvider) bool {
+	for _, sink := range sinks {
+		if sink.StorageName == provider.StorageName && sink.Sink == provider.Sink {
+			return true
+		}
+	}
+	return false
+}
+
+// protectedXStoreBackups returns names of xstore backups of current pxc backup, which are pinned by annotation.
+func protectedXStoreBackups(rc *polardbxv1reconcile.Context) ([]string, error) {
+	xstoreBackups, err := rc.GetXStoreBackups()
+	if err != nil {
+		return nil, err
+	}
+	protected := make([]string, 0)
+	for _, xstoreBackup := range xstoreBackups.Items {
+		if xstoreBackup.Annotations[xstoremeta.AnnotationProtectedBackup] == "true" {
+			protected = append(protected, xstoreBackup.Name)
+		}
+	}
+	return protected, nil
+}
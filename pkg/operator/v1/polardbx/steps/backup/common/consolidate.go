@@ -0,0 +1,320 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+	hpfs "github.com/alibaba/polardbx-operator/pkg/hpfs/proto"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+	"github.com/alibaba/polardbx-operator/pkg/util/path"
+)
+
+// fullBackupObjectPath returns the path of full backup object of xstore backup.
+func fullBackupObjectPath(xstoreBackup *polardbxv1.XStoreBackup) string {
+	return path.JoinPath(xstoreBackup.Status.BackupRootPath, polardbxmeta.FullBackupPath,
+		xstoreBackup.Spec.XStore.Name+".xbstream")
+}
+
+// newBackupArchive converts the index of archive uploaded to archivePath, entries of which are named by xstores.
+func newBackupArchive(archivePath string, index *filestream.ArchiveIndex) *polardbxv1polardbx.BackupArchive {
+	archive := &polardbxv1polardbx.BackupArchive{
+		Path:    archivePath,
+		Entries: make([]polardbxv1polardbx.BackupArchiveEntry, 0, len(index.Entries)),
+	}
+	for _, entry := range index.Entries {
+		archive.Entries = append(archive.Entries, polardbxv1polardbx.BackupArchiveEntry{
+			XStore: entry.Name,
+			Offset: entry.Offset,
+			Size:   entry.Size,
+		})
+	}
+	return archive
+}
+
+// archiveObjectPath returns the path of archive packing full backups of pxc backup.
+func archiveObjectPath(pxcBackup *polardbxv1.PolarDBXBackup) string {
+	return path.JoinPath(pxcBackup.Status.BackupRootPath, polardbxmeta.FullBackupPath,
+		polardbxmeta.FullBackupArchiveName)
+}
+
+// sortedXStoreBackups lists the xstore backups of pxc backup in order of xstore names, which is the order of
+// entries in archive.
+func sortedXStoreBackups(rc *polardbxv1reconcile.Context) ([]polardbxv1.XStoreBackup, error) {
+	xstoreBackupList, err := rc.GetXStoreBackups()
+	if err != nil {
+		return nil, err
+	}
+	xstoreBackups := xstoreBackupList.Items
+	sort.Slice(xstoreBackups, func(i, j int) bool {
+		return xstoreBackups[i].Spec.XStore.Name < xstoreBackups[j].Spec.XStore.Name
+	})
+	return xstoreBackups, nil
+}
+
+// getConsolidateJob gets the consolidate job of pxc backup, nil if not found.
+func getConsolidateJob(rc *polardbxv1reconcile.Context) (*batchv1.Job, error) {
+	pxcBackup := rc.MustGetPolarDBXBackup()
+	var job batchv1.Job
+	err := rc.Client().Get(rc.Context(), types.NamespacedName{
+		Namespace: pxcBackup.Namespace,
+		Name:      consolidateJobName(pxcBackup),
+	}, &job)
+	if err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if err := k8shelper.CheckControllerReference(&job, pxcBackup); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// downloadArchiveIndex downloads the copy of index uploaded next to the archive by consolidate job.
+func downloadArchiveIndex(rc *polardbxv1reconcile.Context) (*filestream.ArchiveIndex, error) {
+	pxcBackup := rc.MustGetPolarDBXBackup()
+	filestreamClient, err := rc.GetFilestreamClient()
+	if err != nil {
+		return nil, err
+	}
+	filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(pxcBackup.Spec.StorageProvider.StorageName)
+	if err != nil {
+		return nil, err
+	}
+	actionMetadata := filestream.ActionMetadata{
+		Action:       filestreamAction.Download,
+		Sink:         pxcBackup.Spec.StorageProvider.Sink,
+		RequestId:    uuid.New().String(),
+		Filename:     archiveObjectPath(pxcBackup) + filestream.ArchiveIndexSuffix,
+		EndpointType: string(pxcBackup.Spec.StorageProvider.EndpointType),
+	}
+	actionMetadata.SSEAlgorithm, actionMetadata.SSEKMSKeyId = pxcBackup.Spec.StorageProvider.GetServerSideEncryption()
+	var buf bytes.Buffer
+	if _, err := filestreamClient.Download(&buf, actionMetadata); err != nil {
+		return nil, err
+	}
+	index := &filestream.ArchiveIndex{}
+	if err := json.Unmarshal(buf.Bytes(), index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// checkArchiveIndex checks that the index has exactly the entries of xstore backups in order.
+func checkArchiveIndex(index *filestream.ArchiveIndex, xstoreBackups []polardbxv1.XStoreBackup) error {
+	if len(index.Entries) != len(xstoreBackups) {
+		return fmt.Errorf("count of entries in archive is %d, expect %d", len(index.Entries), len(xstoreBackups))
+	}
+	for i := range xstoreBackups {
+		if index.Entries[i].Name != xstoreBackups[i].Spec.XStore.Name {
+			return fmt.Errorf("entry %d in archive is %s, expect %s", i, index.Entries[i].Name,
+				xstoreBackups[i].Spec.XStore.Name)
+		}
+	}
+	return nil
+}
+
+// CreateConsolidateJob creates the job packing full backups of xstores into a single archive if required by spec,
+// so that objects are transferred out of the reconcile loop. Progress is recorded as archive phase of pxc backup.
+var CreateConsolidateJob = polardbxv1reconcile.NewStepBinder("CreateConsolidateJob",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetPolarDBXBackup()
+		if !backup.Spec.ConsolidateFullBackups || backup.Status.Phase == polardbxv1.BackupFailed ||
+			backup.Status.ArchivePhase != "" {
+			return flow.Pass()
+		}
+		if len(backup.Status.XStoreStorageProviders) > 0 {
+			// full backups in different sinks can not be packed together
+			return flow.Continue("Full backups not consolidated as storage providers of xstores are overridden.")
+		}
+		xstoreBackups, err := sortedXStoreBackups(rc)
+		if err != nil {
+			return flow.Error(err, "Unable to list xstore backups.")
+		}
+
+		var targetPod *corev1.Pod
+		for i := range xstoreBackups {
+			if xstoreBackups[i].Status.TargetPod == "" {
+				continue
+			}
+			var pod corev1.Pod
+			err := rc.Client().Get(rc.Context(),
+				types.NamespacedName{Namespace: rc.Namespace(), Name: xstoreBackups[i].Status.TargetPod}, &pod)
+			if err == nil {
+				targetPod = &pod
+				break
+			}
+		}
+		if targetPod == nil {
+			return flow.RetryAfter(10*time.Second, "No target pod found to run consolidate job.")
+		}
+
+		tags := ""
+		if polardbx, err := rc.GetPolarDBX(); err == nil {
+			tags = clusterObjectTags(rc, polardbx)
+		}
+		args, err := consolidateJobArgs(backup, xstoreBackups, tags)
+		if err != nil {
+			return flow.Error(err, "Unsupported storage provided.")
+		}
+		job := newConsolidateJob(backup, targetPod, args)
+		if err := rc.SetControllerRefAndCreateToBackup(job); client.IgnoreAlreadyExists(err) != nil {
+			return flow.Error(err, "Unable to create consolidate job.")
+		}
+		backup.Status.ArchivePhase = polardbxv1polardbx.BackupArchivePacking
+		return flow.Retry("Consolidate job created.", "job-name", job.Name, "target-pod", targetPod.Name)
+	})
+
+// WaitUntilConsolidateJobFinished waits until the consolidate job finishes. The archive verified by the job is
+// recorded in status of pxc backup once its index is checked, before standalone objects are removed. If the job
+// fails, full backups are left standalone, which are still valid.
+var WaitUntilConsolidateJobFinished = polardbxv1reconcile.NewStepBinder("WaitUntilConsolidateJobFinished",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetPolarDBXBackup()
+		if backup.Status.ArchivePhase != polardbxv1polardbx.BackupArchivePacking {
+			return flow.Pass()
+		}
+		job, err := getConsolidateJob(rc)
+		if err != nil {
+			return flow.Error(err, "Unable to get consolidate job.")
+		}
+		if job == nil {
+			return flow.Wait("Consolidate job may not have been created yet, wait a while.")
+		}
+		if k8shelper.IsJobFailed(job) {
+			backup.Status.ArchivePhase = polardbxv1polardbx.BackupArchiveFailed
+			rc.RecordEvent(backup, corev1.EventTypeWarning, "ConsolidationFailed",
+				"consolidate job "+job.Name+" failed, full backups are left standalone")
+			return flow.Continue("Consolidate job failed.", "job-name", job.Name)
+		}
+		if !k8shelper.IsJobCompleted(job) {
+			return flow.Wait("Consolidate job is still running.", "job-name", job.Name)
+		}
+
+		index, err := downloadArchiveIndex(rc)
+		if err != nil {
+			return flow.RetryAfter(10*time.Second, "Failed to download archive index, error: "+err.Error())
+		}
+		xstoreBackups, err := sortedXStoreBackups(rc)
+		if err != nil {
+			return flow.Error(err, "Unable to list xstore backups.")
+		}
+		if err := checkArchiveIndex(index, xstoreBackups); err != nil {
+			backup.Status.ArchivePhase = polardbxv1polardbx.BackupArchiveFailed
+			rc.RecordEvent(backup, corev1.EventTypeWarning, "ConsolidationFailed",
+				"archive mismatches xstore backups, full backups are left standalone: "+err.Error())
+			return flow.Continue("Archive mismatches xstore backups.", "error", err.Error())
+		}
+		archivePath := path.JoinPath(polardbxmeta.FullBackupPath, polardbxmeta.FullBackupArchiveName)
+		backup.Status.Archive = newBackupArchive(archivePath, index)
+		backup.Status.ArchivePhase = polardbxv1polardbx.BackupArchivePacked
+		// persist the archive before standalone objects are removed
+		return flow.Retry("Full backups consolidated.", "archive", archivePath)
+	})
+
+// RemoveConsolidatedFullBackups records the archive in status of each xstore backup, after which the standalone
+// full backup objects are removed. Chunk manifests are kept, offsets of which are relative to the entry.
+var RemoveConsolidatedFullBackups = polardbxv1reconcile.NewStepBinder("RemoveConsolidatedFullBackups",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetPolarDBXBackup()
+		if backup.Status.ArchivePhase != polardbxv1polardbx.BackupArchivePacked {
+			return flow.Pass()
+		}
+		xstoreBackups, err := sortedXStoreBackups(rc)
+		if err != nil {
+			return flow.Error(err, "Unable to list xstore backups.")
+		}
+
+		hpfsClient, err := rc.GetHpfsClient()
+		if err != nil {
+			return flow.Error(err, "Failed to get hpfs client.")
+		}
+		for i := range xstoreBackups {
+			xstoreBackup := &xstoreBackups[i]
+			if xstoreBackup.Status.Archive != nil {
+				continue
+			}
+			archive := backup.Status.Archive.ForXStore(xstoreBackup.Spec.XStore.Name)
+			if archive == nil {
+				return flow.Error(errors.New("xstore not found in archive"), "Unable to consolidate full backup.",
+					"xstore", xstoreBackup.Spec.XStore.Name)
+			}
+			// object left behind if removal fails is cleaned along with the backup set
+			xstoreBackup.Status.Archive = archive
+			if err := rc.Client().Status().Update(rc.Context(), xstoreBackup); err != nil {
+				return flow.Error(err, "Unable to update xstore backup.", "xstore-backup", xstoreBackup.Name)
+			}
+			response, err := hpfsClient.DeleteRemoteFile(rc.Context(), &hpfs.DeleteRemoteFileRequest{
+				SinkType: string(backup.Spec.StorageProvider.StorageName),
+				SinkName: backup.Spec.StorageProvider.Sink,
+				Target: &hpfs.RemoteFsEndpoint{
+					Path: fullBackupObjectPath(xstoreBackup),
+					Other: map[string]string{
+						"recursive": "false",
+					},
+				},
+			})
+			if err != nil || response.GetStatus().Code != hpfs.Status_OK {
+				flow.Logger().Info("Unable to remove full backup object.", "xstore-backup", xstoreBackup.Name,
+					"error", err, "status", response.GetStatus().String())
+			}
+		}
+		backup.Status.ArchivePhase = polardbxv1polardbx.BackupArchiveConsolidated
+		return flow.Continue("Standalone full backup objects removed.", "archive", backup.Status.Archive.Path)
+	})
+
+// RemoveConsolidateJob removes the consolidate job once it's no longer needed.
+var RemoveConsolidateJob = polardbxv1reconcile.NewStepBinder("RemoveConsolidateJob",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		job, err := getConsolidateJob(rc)
+		if err != nil {
+			return flow.Error(err, "Unable to get consolidate job.")
+		}
+		if job == nil {
+			return flow.Continue("Consolidate job already removed.")
+		}
+
+		propagation := rc.JobDeletePropagation()
+		if job.DeletionTimestamp.IsZero() {
+			err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(propagation))
+			if client.IgnoreNotFound(err) != nil {
+				return flow.Error(err, "Unable to remove consolidate job.", "job-name", job.Name)
+			}
+		}
+		if propagation == metav1.DeletePropagationForeground {
+			return flow.RetryAfter(5*time.Second, "Wait until consolidate job removed.", "job-name", job.Name)
+		}
+		return flow.Continue("Consolidate job removed.", "job-name", job.Name)
+	})
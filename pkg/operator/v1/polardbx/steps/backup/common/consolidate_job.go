@@ -0,0 +1,116 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	"github.com/alibaba/polardbx-operator/pkg/util/name"
+)
+
+const consolidateJobCommand = "/tools/xstore/current/bin/polardbx-filestream-client"
+
+// consolidateJobName returns the name of job packing full backups of pxc backup into archive.
+func consolidateJobName(pxcBackup *polardbxv1.PolarDBXBackup) string {
+	return name.NewSplicedName(
+		name.WithTokens("consolidate", "job", pxcBackup.Name),
+		name.WithPrefix("consolidate-job"),
+	)
+}
+
+// consolidateJobArgs returns the arguments of filestream client to pack full backups of xstore backups into archive,
+// which is verified and indexed by the client as well. Xstore backups are packed in order.
+func consolidateJobArgs(pxcBackup *polardbxv1.PolarDBXBackup, xstoreBackups []polardbxv1.XStoreBackup,
+	tags string) ([]string, error) {
+	storageProvider := pxcBackup.Spec.StorageProvider
+	filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(storageProvider.StorageName)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]string, 0, len(xstoreBackups))
+	for i := range xstoreBackups {
+		sources = append(sources, xstoreBackups[i].Spec.XStore.Name+"="+fullBackupObjectPath(&xstoreBackups[i]))
+	}
+	sseAlgorithm, sseKMSKeyId := storageProvider.GetServerSideEncryption()
+	return []string{
+		"--meta.action=" + string(filestreamAction.Upload),
+		"--meta.sink=" + storageProvider.Sink,
+		"--meta.filename=" + archiveObjectPath(pxcBackup),
+		"--meta.tags=" + tags,
+		"--meta.sseAlgorithm=" + sseAlgorithm,
+		"--meta.sseKMSKeyId=" + sseKMSKeyId,
+		"--meta.endpointType=" + string(storageProvider.EndpointType),
+		"--downloadAction=" + string(filestreamAction.Download),
+		"--archiveSources=" + strings.Join(sources, ","),
+	}, nil
+}
+
+// newConsolidateJob creates the job running filestream client with args on the node of target pod, which reuses the
+// engine container of target pod for the filestream client and host info of hpfs mounted.
+func newConsolidateJob(pxcBackup *polardbxv1.PolarDBXBackup, targetPod *corev1.Pod, args []string) *batchv1.Job {
+	podSpec := targetPod.Spec.DeepCopy()
+	podSpec.InitContainers = nil
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+	podSpec.HostNetwork = false
+
+	podSpec.Containers = []corev1.Container{
+		*k8shelper.GetContainerFromPodSpec(podSpec, "engine"),
+	}
+	podSpec.Containers[0].Name = "consolidate"
+	podSpec.Containers[0].Command = []string{consolidateJobCommand}
+	podSpec.Containers[0].Args = args
+	podSpec.Containers[0].Resources.Limits = nil
+	podSpec.Containers[0].Resources.Requests = nil
+	podSpec.Containers[0].Ports = nil
+	podSpec.Containers[0].ReadinessProbe = nil
+	podSpec.Containers[0].LivenessProbe = nil
+	podSpec.Containers[0].StartupProbe = nil
+	podSpec.Containers[0].Lifecycle = nil
+
+	// Replace system envs
+	replaceSystemEnvs(podSpec, targetPod)
+
+	labels := map[string]string{
+		meta.ConsolidateJobLabelBackupName: pxcBackup.Name,
+		meta.ConsolidateJobLabelPodName:    targetPod.Name,
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consolidateJobName(pxcBackup),
+			Namespace: pxcBackup.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: pointer.Int32(0),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: *podSpec,
+			},
+		},
+	}
+}
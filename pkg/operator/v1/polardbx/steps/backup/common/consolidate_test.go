@@ -0,0 +1,104 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+)
+
+func newConsolidateTestBackups() (*polardbxv1.PolarDBXBackup, []polardbxv1.XStoreBackup) {
+	backup := &polardbxv1.PolarDBXBackup{}
+	backup.Name = "pxb"
+	backup.Namespace = "default"
+	backup.Spec.StorageProvider = polardbxv1polardbx.BackupStorageProvider{
+		StorageName: polardbxv1polardbx.OSS,
+		Sink:        "default",
+	}
+	backup.Status.BackupRootPath = "polardbx-backup/pxc/pxb"
+
+	xstoreBackups := make([]polardbxv1.XStoreBackup, 0)
+	for _, xstoreName := range []string{"pxc-dn-0", "pxc-gms"} {
+		xstoreBackup := polardbxv1.XStoreBackup{}
+		xstoreBackup.Spec.XStore.Name = xstoreName
+		xstoreBackup.Status.BackupRootPath = backup.Status.BackupRootPath
+		xstoreBackups = append(xstoreBackups, xstoreBackup)
+	}
+	return backup, xstoreBackups
+}
+
+func TestNewConsolidateJob(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	backup, xstoreBackups := newConsolidateTestBackups()
+	args, err := consolidateJobArgs(backup, xstoreBackups, "team=db")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(args).To(gomega.Equal([]string{
+		"--meta.action=uploadOss",
+		"--meta.sink=default",
+		"--meta.filename=polardbx-backup/pxc/pxb/fullbackup/archive",
+		"--meta.tags=team=db",
+		"--meta.sseAlgorithm=",
+		"--meta.sseKMSKeyId=",
+		"--meta.endpointType=",
+		"--downloadAction=downloadOss",
+		"--archiveSources=pxc-dn-0=polardbx-backup/pxc/pxb/fullbackup/pxc-dn-0.xbstream," +
+			"pxc-gms=polardbx-backup/pxc/pxb/fullbackup/pxc-gms.xbstream",
+	}))
+
+	targetPod := &corev1.Pod{}
+	targetPod.Name = "pxc-dn-0-cand-0"
+	targetPod.Spec.NodeName = "node-0"
+	targetPod.Spec.Containers = []corev1.Container{
+		{Name: "prober"},
+		{Name: "engine", Image: "xstore-tools", Ports: []corev1.ContainerPort{{ContainerPort: 3306}}},
+	}
+	job := newConsolidateJob(backup, targetPod, args)
+	g.Expect(job.Name).To(gomega.Equal(consolidateJobName(backup)))
+	podSpec := job.Spec.Template.Spec
+	g.Expect(podSpec.NodeName).To(gomega.Equal("node-0"))
+	g.Expect(podSpec.RestartPolicy).To(gomega.Equal(corev1.RestartPolicyNever))
+	g.Expect(podSpec.Containers).To(gomega.HaveLen(1))
+	g.Expect(podSpec.Containers[0].Image).To(gomega.Equal("xstore-tools"))
+	g.Expect(podSpec.Containers[0].Command).To(gomega.Equal([]string{consolidateJobCommand}))
+	g.Expect(podSpec.Containers[0].Args).To(gomega.Equal(args))
+	g.Expect(podSpec.Containers[0].Ports).To(gomega.BeEmpty())
+
+	backup.Spec.StorageProvider.StorageName = "unknown"
+	_, err = consolidateJobArgs(backup, xstoreBackups, "")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestCheckArchiveIndex(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	_, xstoreBackups := newConsolidateTestBackups()
+	index := &filestream.ArchiveIndex{Entries: []filestream.ArchiveEntry{
+		{Name: "pxc-dn-0", Offset: 0, Size: 1024},
+		{Name: "pxc-gms", Offset: 1024, Size: 1024},
+	}}
+	g.Expect(checkArchiveIndex(index, xstoreBackups)).To(gomega.Succeed())
+	g.Expect(checkArchiveIndex(index, xstoreBackups[:1])).To(gomega.MatchError(
+		"count of entries in archive is 2, expect 1"))
+	index.Entries[0].Name = "pxc-dn-1"
+	g.Expect(checkArchiveIndex(index, xstoreBackups)).To(gomega.MatchError(
+		"entry 0 in archive is pxc-dn-1, expect pxc-dn-0"))
+}
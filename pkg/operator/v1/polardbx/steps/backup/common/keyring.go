@@ -0,0 +1,59 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+)
+
+// ValidateKeyringReEncryption checks that both the source key and the target key of keyring re-encryption are
+// available before any xstore backup is started, and records the fingerprint of target key. Backup fails if
+// any key is unavailable, since keyrings can not be rotated as requested.
+var ValidateKeyringReEncryption = polardbxv1reconcile.NewStepBinder("ValidateKeyringReEncryption",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetPolarDBXBackup()
+		reEncryption := backup.Spec.KeyringReEncryption
+		if reEncryption == nil || backup.Status.KeyringKeyFingerprint != "" {
+			return flow.Pass()
+		}
+
+		secret, err := rc.GetSecret(reEncryption.SecretName)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return flow.Error(err, "Unable to get secret of keyring keys.", "secret", reEncryption.SecretName)
+		}
+		fingerprint := ""
+		if err == nil {
+			fingerprint, err = reEncryption.ValidateKeys(secret)
+		}
+		if err != nil {
+			backup.Status.Phase = polardbxv1.BackupFailed
+			backup.Status.Reason = "KeyringKeyUnavailable"
+			backup.Status.FailureReason = polardbxv1polardbx.FailureReasonInvalidSpec
+			backup.Status.Message = "keys of keyring re-encryption are unavailable: " + err.Error()
+			rc.RecordEvent(backup, corev1.EventTypeWarning, backup.Status.Reason, backup.Status.Message)
+			return flow.Retry("Keys of keyring re-encryption unavailable, backup failed.")
+		}
+		backup.Status.KeyringKeyFingerprint = fingerprint
+		return flow.Continue("Keys of keyring re-encryption validated.", "fingerprint", fingerprint)
+	})
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
+)
+
+// backupSink identifies where backup sets are stored.
+type backupSink struct {
+	storage polardbxv1polardbx.BackupStorage
+	sink    string
+}
+
+// lintBackup returns the conditions of risky configurations found in backup. polardbx is nil if the cluster is
+// unavailable, and otherSinks are sinks of other backup sets of the cluster.
+func lintBackup(backup *polardbxv1.PolarDBXBackup, polardbx *polardbxv1.PolarDBXCluster,
+	otherSinks []backupSink) []polardbxv1polardbx.Condition {
+	conditions := make([]polardbxv1polardbx.Condition, 0)
+	if backup.Spec.PreferredBackupRole == xstoremeta.RoleLeader && !backup.Spec.ForbidBackupOnLeader {
+		conditions = append(conditions, polardbxv1polardbx.Condition{
+			Type:    polardbxv1.PolarDBXBackupLintBackupOnLeader,
+			Reason:  "BackupOnLeader",
+			Message: "backup targets the leader without forbidBackupOnLeader, which competes with online traffic",
+		})
+	}
+	if backup.Spec.RetentionTime.Duration == 0 {
+		conditions = append(conditions, polardbxv1polardbx.Condition{
+			Type:    polardbxv1.PolarDBXBackupLintNoRetention,
+			Reason:  "NoRetention",
+			Message: "retention time is zero, backup set is never purged",
+		})
+	}
+	sink := backupSink{storage: backup.Spec.StorageProvider.StorageName, sink: backup.Spec.StorageProvider.Sink}
+	replicated := false
+	for _, other := range otherSinks {
+		if other != sink {
+			replicated = true
+			break
+		}
+	}
+	if !replicated {
+		conditions = append(conditions, polardbxv1polardbx.Condition{
+			Type:   polardbxv1.PolarDBXBackupLintSingleSink,
+			Reason: "SingleSink",
+			Message: "no backup set of the cluster is stored in sink other than " + string(sink.storage) + "/" +
+				sink.sink + ", all of them are lost along with the sink",
+		})
+	}
+	if polardbx != nil && (polardbx.Spec.TDE.Enable || polardbx.Status.TdeStatus) &&
+		backup.Spec.StorageProvider.ServerSideEncryption == nil {
+		conditions = append(conditions, polardbxv1polardbx.Condition{
+			Type:    polardbxv1.PolarDBXBackupLintUnencryptedTDE,
+			Reason:  "UnencryptedTDE",
+			Message: "cluster enables tde while server-side encryption is not requested, keyring is stored in plain",
+		})
+	}
+	for i := range conditions {
+		conditions[i].Status = corev1.ConditionTrue
+	}
+	return conditions
+}
+
+// setBackupCondition adds or replaces the condition of same type, keeping the transition time if status unchanged.
+func setBackupCondition(backup *polardbxv1.PolarDBXBackup, cond polardbxv1polardbx.Condition) {
+	cond.LastTransitionTime = metav1.Now()
+	for i := range backup.Status.Conditions {
+		c := &backup.Status.Conditions[i]
+		if c.Type == cond.Type {
+			if c.Status == cond.Status {
+				cond.LastTransitionTime = c.LastTransitionTime
+			}
+			*c = cond
+			return
+		}
+	}
+	backup.Status.Conditions = append(backup.Status.Conditions, cond)
+}
+
+// LintBackup records risky configurations of backup into status conditions and events before backup starts,
+// it never blocks the backup even if the lint itself fails.
+var LintBackup = polardbxv1reconcile.NewStepBinder("LintBackup",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetPolarDBXBackup()
+
+		polardbx, err := rc.GetPolarDBX()
+		if err != nil {
+			flow.Logger().Info("Unable to get polardbx, skip linting tde.", "error", err.Error())
+			polardbx = nil
+		}
+		var backupList polardbxv1.PolarDBXBackupList
+		err = rc.Client().List(rc.Context(), &backupList, client.InNamespace(rc.Namespace()),
+			client.MatchingLabels{polardbxmeta.LabelName: backup.Spec.Cluster.Name})
+		if err != nil {
+			return flow.Continue("Unable to list backups, skip lint.", "error", err.Error())
+		}
+		otherSinks := make([]backupSink, 0, len(backupList.Items))
+		for _, other := range backupList.Items {
+			if other.Name == backup.Name || other.Status.Phase == polardbxv1.BackupFailed {
+				continue
+			}
+			otherSinks = append(otherSinks, backupSink{
+				storage: other.Spec.StorageProvider.StorageName,
+				sink:    other.Spec.StorageProvider.Sink,
+			})
+		}
+
+		conditions := lintBackup(backup, polardbx, otherSinks)
+		for _, cond := range conditions {
+			if !hasBackupCondition(backup, cond.Type) {
+				rc.RecordEvent(backup, corev1.EventTypeWarning, cond.Reason, cond.Message)
+			}
+			setBackupCondition(backup, cond)
+		}
+		if len(conditions) == 0 {
+			return flow.Continue("No risky configuration found.")
+		}
+		return flow.Continue("Risky configurations found.", "count", len(conditions))
+	})
+
+func hasBackupCondition(backup *polardbxv1.PolarDBXBackup, condType polardbxv1polardbx.ConditionType) bool {
+	for _, cond := range backup.Status.Conditions {
+		if cond.Type == condType {
+			return true
+		}
+	}
+	return false
+}
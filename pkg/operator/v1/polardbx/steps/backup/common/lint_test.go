@@ -0,0 +1,73 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+)
+
+func lintConditionTypes(conditions []polardbxv1polardbx.Condition) []polardbxv1polardbx.ConditionType {
+	types := make([]polardbxv1polardbx.ConditionType, 0, len(conditions))
+	for _, cond := range conditions {
+		types = append(types, cond.Type)
+	}
+	return types
+}
+
+func TestLintBackup(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	backup := &polardbxv1.PolarDBXBackup{}
+	backup.Spec.PreferredBackupRole = "follower"
+	backup.Spec.RetentionTime = metav1.Duration{Duration: 24 * time.Hour}
+	backup.Spec.StorageProvider = polardbxv1polardbx.BackupStorageProvider{StorageName: polardbxv1polardbx.OSS, Sink: "default"}
+	polardbx := &polardbxv1.PolarDBXCluster{}
+	otherSinks := []backupSink{
+		{storage: polardbxv1polardbx.OSS, sink: "default"},
+		{storage: polardbxv1polardbx.MINIO, sink: "default"},
+	}
+	g.Expect(lintBackup(backup, polardbx, otherSinks)).To(gomega.BeEmpty())
+
+	backup.Spec.PreferredBackupRole = "leader"
+	backup.Spec.RetentionTime = metav1.Duration{}
+	polardbx.Spec.TDE.Enable = true
+	conditions := lintBackup(backup, polardbx, otherSinks[:1])
+	g.Expect(lintConditionTypes(conditions)).To(gomega.Equal([]polardbxv1polardbx.ConditionType{
+		polardbxv1.PolarDBXBackupLintBackupOnLeader,
+		polardbxv1.PolarDBXBackupLintNoRetention,
+		polardbxv1.PolarDBXBackupLintSingleSink,
+		polardbxv1.PolarDBXBackupLintUnencryptedTDE,
+	}))
+	g.Expect(string(conditions[0].Status)).To(gomega.Equal("True"))
+
+	// forbidding backup on leader, encrypted, or cluster unavailable
+	backup.Spec.ForbidBackupOnLeader = true
+	backup.Spec.StorageProvider.ServerSideEncryption = &polardbxv1polardbx.ServerSideEncryption{
+		Algorithm: polardbxv1polardbx.SSEAlgorithmAES256,
+	}
+	g.Expect(lintConditionTypes(lintBackup(backup, polardbx, otherSinks))).To(gomega.Equal(
+		[]polardbxv1polardbx.ConditionType{polardbxv1.PolarDBXBackupLintNoRetention}))
+	backup.Spec.StorageProvider.ServerSideEncryption = nil
+	g.Expect(lintConditionTypes(lintBackup(backup, nil, otherSinks))).To(gomega.Equal(
+		[]polardbxv1polardbx.ConditionType{polardbxv1.PolarDBXBackupLintNoRetention}))
+}
@@ -25,6 +25,7 @@ import (
 	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
 	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
 	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+	hpfs "github.com/alibaba/polardbx-operator/pkg/hpfs/proto"
 	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
 	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/convention"
@@ -35,10 +36,12 @@ import (
 	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
 	"github.com/alibaba/polardbx-operator/pkg/util/path"
 	"github.com/alibaba/polardbx-operator/pkg/util/slice"
+	"github.com/go-logr/logr"
 	"github.com/google/uuid"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"math"
 	"modernc.org/mathutil"
@@ -56,6 +59,25 @@ type SeekCpJobContext struct {
 	DnNameList   string `json:"dnNameList,omitempty"`
 	StorageName  string `json:"storageName,omitempty"`
 	Sink         string `json:"sink,omitempty"`
+	Tags         string `json:"tags,omitempty"`
+	SSEAlgorithm string `json:"sseAlgorithm,omitempty"`
+	SSEKMSKeyId  string `json:"sseKMSKeyId,omitempty"`
+	EndpointType string `json:"endpointType,omitempty"`
+}
+
+// xstoreFailureReason returns the failure reason of failed xstore backup, which is unknown if not categorized.
+func xstoreFailureReason(xstoreBackup *polardbxv1.XStoreBackup) polardbxv1polardbx.BackupFailureReason {
+	if xstoreBackup.Status.FailureReason == "" {
+		return polardbxv1polardbx.FailureReasonUnknown
+	}
+	return xstoreBackup.Status.FailureReason
+}
+
+// clusterObjectTags returns the encoded tags attached to uploaded files of cluster backup, which are the
+// propagated labels of cluster.
+func clusterObjectTags(rc *polardbxv1reconcile.Context, polardbx *polardbxv1.PolarDBXCluster) string {
+	return filestream.EncodeObjectTags(
+		factory.PropagatedLabels(polardbx.Labels, rc.Config().Backup().GetPropagatedLabels()))
 }
 
 var PersistentPolarDBXBackup = polardbxv1reconcile.NewStepBinder("PersistentPolarDBXBackup",
@@ -83,6 +105,7 @@ var PersistentStatusChanges = polardbxv1reconcile.NewStepBinder("PersistentStatu
 var UpdateBackupStartInfo = polardbxv1reconcile.NewStepBinder("UpdateBackupStartInfo",
 	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		backup := rc.MustGetPolarDBXBackup()
+		previousRootPath := backup.Status.BackupRootPath
 
 		nowTime := metav1.Now()
 		backup.Status.StartTime = &nowTime
@@ -104,12 +127,150 @@ var UpdateBackupStartInfo = polardbxv1reconcile.NewStepBinder("UpdateBackupStart
 		backup.Spec.Cluster.UID = polardbx.UID
 		backup.Status.ClusterSpecSnapshot = polardbx.Spec.DeepCopy()
 
+		// record version and images of original polardbx, restore warns if they mismatch the target
+		backup.Status.PolarDBXVersion = polardbx.Status.StatusForPrint.DetailedVersion
+		backup.Status.Images = factory.ClusterImages(&polardbx.Spec, rc.Config().Images())
+
+		// record GMS schema versions, restore refuses backup set of versions newer than supported
+		if gmsManager, err := rc.GetPolarDBXGMSManager(); err != nil {
+			flow.Logger().Error(err, "Unable to get gms manager, skip recording gms schema versions")
+		} else if gmsManager == nil {
+			flow.Logger().Info("GMS not found, skip recording gms schema versions")
+		} else if versions, err := gmsManager.GetSchemaVersions(); err != nil {
+			flow.Logger().Error(err, "Unable to get gms schema versions, skip recording")
+		} else {
+			backup.Status.GMSSchemaVersions = versions
+		}
+
+		// fill storage provider and retention time by backup class if not specified, the class labeled on cluster
+		// applies if backup doesn't refer to one
+		if backup.Spec.BackupClass == "" {
+			backup.Spec.BackupClass = polardbx.Labels[polardbxmeta.LabelBackupClass]
+		}
+		if backup.Spec.BackupClass != "" {
+			err := fmt.Errorf("backup class %s not found", backup.Spec.BackupClass)
+			if class := rc.Config().Backup().GetBackupClass(backup.Spec.BackupClass); class != nil {
+				err = class.FillBackup(&backup.Spec.StorageProvider, &backup.Spec.RetentionTime)
+			}
+			if err != nil {
+				backup.Status.Phase = polardbxv1.BackupFailed
+				backup.Status.Reason = "InvalidBackupClass"
+				backup.Status.FailureReason = polardbxv1polardbx.FailureReasonInvalidSpec
+				backup.Status.Message = err.Error()
+				rc.RecordEvent(backup, corev1.EventTypeWarning, backup.Status.Reason, backup.Status.Message)
+				return flow.Retry("Invalid backup class, backup failed.", "class", backup.Spec.BackupClass)
+			}
+		}
+
+		// fill storage provider by sink policy of operator if not specified
+		if backup.Spec.StorageProvider.StorageName == "" && backup.Spec.StorageProvider.Sink == "" {
+			if storageProvider := rc.Config().Backup().DefaultStorageProvider(polardbx.Namespace, polardbx.Labels); storageProvider != nil {
+				backup.Spec.StorageProvider = *storageProvider
+			}
+		}
+
 		// mark to update spec
 		rc.MarkPolarDBXChanged()
 
+		// root path is derived from start time in seconds, fail fast rather than overwrite another backup set
+		if backup.Status.BackupRootPath != previousRootPath {
+			collision, err := detectBackupRootPathCollision(rc, backup, flow.Logger())
+			if err != nil {
+				return flow.Error(err, "Unable to check backup root path.")
+			}
+			if collision != "" {
+				backup.Status.Phase = polardbxv1.BackupFailed
+				backup.Status.Reason = "BackupRootPathCollision"
+				backup.Status.FailureReason = polardbxv1polardbx.FailureReasonInvalidSpec
+				backup.Status.Message = fmt.Sprintf("backup root path %s is already used, %s, please recreate the backup",
+					backup.Status.BackupRootPath, collision)
+				// drop the root path, so that the colliding backup set is never cleaned along with this backup
+				backup.Status.BackupRootPath = ""
+				rc.RecordEvent(backup, corev1.EventTypeWarning, backup.Status.Reason, backup.Status.Message)
+				return flow.Retry("Backup root path collision detected, backup failed.", "collision", collision)
+			}
+		}
+
 		return flow.Continue("Update backup start info")
 	})
 
+// findBackupByRootPath returns the name of backup in others which stores its backup set in the same root path and
+// sink as backup, or empty if none.
+func findBackupByRootPath(backup *polardbxv1.PolarDBXBackup, others []polardbxv1.PolarDBXBackup) string {
+	for _, other := range others {
+		if other.UID == backup.UID || other.Status.BackupRootPath != backup.Status.BackupRootPath {
+			continue
+		}
+		if other.Spec.StorageProvider.StorageName == backup.Spec.StorageProvider.StorageName &&
+			other.Spec.StorageProvider.Sink == backup.Spec.StorageProvider.Sink {
+			return other.Name
+		}
+	}
+	return ""
+}
+
+// detectBackupRootPathCollision checks whether the root path of backup is used by other backups of the cluster or
+// already exists in the storage, and describes the collision if so. The storage is not checked if unreachable,
+// since backup jobs fail anyway in that case.
+func detectBackupRootPathCollision(rc *polardbxv1reconcile.Context, backup *polardbxv1.PolarDBXBackup,
+	logger logr.Logger) (string, error) {
+	var backupList polardbxv1.PolarDBXBackupList
+	err := rc.Client().List(rc.Context(), &backupList, client.InNamespace(rc.Namespace()),
+		client.MatchingLabels{polardbxmeta.LabelName: backup.Spec.Cluster.Name})
+	if err != nil {
+		return "", err
+	}
+	if name := findBackupByRootPath(backup, backupList.Items); name != "" {
+		return "used by backup " + name, nil
+	}
+
+	filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(backup.Spec.StorageProvider.StorageName)
+	if err != nil {
+		return "", nil
+	}
+	filestreamClient, err := rc.GetFilestreamClient()
+	if err != nil {
+		logger.Info("Unable to get filestream client, skip checking root path in storage.", "error", err.Error())
+		return "", nil
+	}
+	exists, err := filestreamClient.Exists(filestream.ActionMetadata{
+		Action:    filestreamAction.List,
+		Sink:      backup.Spec.StorageProvider.Sink,
+		RequestId: uuid.New().String(),
+		Filepath:  backup.Status.BackupRootPath,
+	})
+	if err != nil {
+		logger.Info("Unable to check root path in storage, skip.", "error", err.Error())
+		return "", nil
+	}
+	if exists {
+		return "objects found in " + string(backup.Spec.StorageProvider.StorageName) + "/" +
+			backup.Spec.StorageProvider.Sink, nil
+	}
+	return "", nil
+}
+
+// overriddenStorageProviders returns the storage providers overridden for backups of xstores, keyed by xstore,
+// nil if none is overridden.
+func overriddenStorageProviders(backup *polardbxv1.PolarDBXBackup,
+	xstores []polardbxv1.XStore) map[string]polardbxv1polardbx.BackupStorageProvider {
+	var providers map[string]polardbxv1polardbx.BackupStorageProvider
+	for _, xstore := range xstores {
+		if _, ok := backup.Status.Backups[xstore.Name]; !ok {
+			continue
+		}
+		provider, overridden := backup.Spec.ResolveXStoreStorageProvider(xstore.Name, xstore.Labels[polardbxmeta.LabelRole])
+		if !overridden {
+			continue
+		}
+		if providers == nil {
+			providers = make(map[string]polardbxv1polardbx.BackupStorageProvider)
+		}
+		providers[xstore.Name] = *provider.DeepCopy()
+	}
+	return providers
+}
+
 var CreateBackupJobsForXStore = polardbxv1reconcile.NewStepBinder("CreateBackupsForDNAndGMS",
 	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		backup := rc.MustGetPolarDBXBackup()
@@ -139,25 +300,51 @@ var CreateBackupJobsForXStore = polardbxv1reconcile.NewStepBinder("CreateBackups
 			return flow.Error(err, "Unable to list xstore List")
 		}
 
-		// For each DN and GMS not having a backup, create a backup.
+		// Only xstores matching the selector are backed up, all of them by default
+		xstoreSelector := labels.Everything()
+		if backup.Spec.XStoreSelector != nil {
+			xstoreSelector, err = metav1.LabelSelectorAsSelector(backup.Spec.XStoreSelector)
+			if err != nil {
+				backup.Status.Phase = polardbxv1.BackupFailed
+				backup.Status.Reason = "invalid xstore selector: " + err.Error()
+				backup.Status.FailureReason = polardbxv1polardbx.FailureReasonInvalidSpec
+				return flow.Continue("Invalid xstore selector.")
+			}
+		}
+		selectedCount := 0
 		for _, xstore := range xstoreList.Items {
+			if xstoreSelector.Matches(labels.Set(xstore.Labels)) {
+				selectedCount++
+			}
+		}
+		if selectedCount == 0 {
+			backup.Status.Phase = polardbxv1.BackupFailed
+			backup.Status.Reason = "no xstore matches the xstore selector"
+			backup.Status.FailureReason = polardbxv1polardbx.FailureReasonInvalidSpec
+			return flow.Continue("No xstore selected.")
+		}
+		backup.Status.Partial = selectedCount < len(xstoreList.Items)
+
+		// For each selected DN and GMS not having a backup, create a backup.
+		for _, xstore := range xstoreList.Items {
+			if !xstoreSelector.Matches(labels.Set(xstore.Labels)) {
+				continue
+			}
 			if _, ok := backup.Status.Backups[xstore.Name]; ok {
 				continue
 			}
 
 			objectFactory := factory.NewObjectFactory(rc)
-			xstoreBackup, err := objectFactory.NewXStoreBackup(&xstore)
-			if err != nil {
-				return flow.Error(err, "Unable to build new physical backup for xstore", "xstore", xstore.Name)
-			}
-
-			err = rc.SetControllerRefAndCreateToBackup(xstoreBackup)
+			xstoreBackup, err := ensureXStoreBackup(rc, func() (*polardbxv1.XStoreBackup, error) {
+				return objectFactory.NewXStoreBackup(&xstore)
+			})
 			if err != nil {
 				return flow.Error(err, "Unable to create physical backup for xstore", "xstore", xstore.Name)
 			}
 			backup.Status.XStores = append(backup.Status.XStores, xstoreBackup.Spec.XStore.Name)
 			backup.Status.Backups[xstore.Name] = xstoreBackup.Name
 		}
+		backup.Status.XStoreStorageProviders = overriddenStorageProviders(backup, xstoreList.Items)
 		return flow.Continue("Create backups for dn and gms")
 	})
 
@@ -178,10 +365,18 @@ var WaitAllBackupJobsFinished = polardbxv1reconcile.NewStepBinder("WaitAllBackup
 			flow.Logger().Info("Backup Failed", "expect-size:", len(backup.Status.Backups), "actual-size", len(xstoreBackups.Items))
 			backup.Status.Phase = polardbxv1.BackupFailed
 			backup.Status.Phase = "Backup broken detected"
+			backup.Status.FailureReason = polardbxv1polardbx.FailureReasonUnknown
 			return flow.Continue("Backup Failed")
 		}
 
 		for _, xstoreBackup := range xstoreBackups.Items {
+			if xstoreBackup.Status.Phase == xstorev1.XstoreBackupFailed {
+				backup.Status.Phase = polardbxv1.BackupFailed
+				backup.Status.Reason = xstoreBackup.Status.Reason
+				backup.Status.FailureReason = xstoreFailureReason(&xstoreBackup)
+				backup.Status.Message = fmt.Sprintf("xstore backup %s failed: %s", xstoreBackup.Name, xstoreBackup.Status.Message)
+				return flow.Retry("Xstore backup failed when full backup", "xstoreBackupName", xstoreBackup.Name)
+			}
 			if xstoreBackup.Status.Phase != polardbxv1.XStoreBackupCollecting {
 				return flow.Wait("XStore backup is still collecting!", "xstore-name", xstoreBackup.Name)
 			}
@@ -319,6 +514,39 @@ var SendHeartBeat = polardbxv1reconcile.NewStepBinder("SendHeartBeat",
 		return flow.Continue("HeartBeat send!")
 	})
 
+// CollectCdcState captures the position and topology of cdc right after heartbeat sent, which are recorded in
+// metadata of backup set as the point consistent with it. Restore never reseeds cdc from the position, since cdc
+// reads no such start position from metadb. It is skipped if cdc is not enabled.
+var CollectCdcState = polardbxv1reconcile.NewStepBinder("CollectCdcState",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetPolarDBXBackup()
+		mgr, err := rc.GetPolarDBXGMSManager()
+		if err != nil {
+			return flow.Error(err, "Unable to get GMS manager.")
+		}
+		if mgr == nil {
+			return flow.Continue("GMS not found, skip collecting cdc state.")
+		}
+
+		cdcState, err := mgr.GetCdcState()
+		if err != nil {
+			return flow.Error(err, "Unable to get cdc state.")
+		}
+		if cdcState == nil {
+			return flow.Continue("Cdc not enabled, skip collecting cdc state.")
+		}
+
+		nodes := make([]string, 0, len(cdcState.Nodes))
+		for _, node := range cdcState.Nodes {
+			nodes = append(nodes, fmt.Sprintf("%s@%s:%d", node.ContainerId, node.Host, node.DaemonPort))
+		}
+		backup.Status.CdcState = &polardbxv1.CdcBackupState{
+			Tso:   cdcState.Tso,
+			Nodes: nodes,
+		}
+		return flow.Continue("Cdc state collected.", "tso", cdcState.Tso)
+	})
+
 var WaitHeartbeatSentToFollower = polardbxv1reconcile.NewStepBinder("WaitHeartbeatSentToFollower",
 	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		backupPodList, err := rc.GetXStoreBackupPods()
@@ -463,6 +691,12 @@ var WaitAllCollectBinlogJobFinished = polardbxv1reconcile.NewStepBinder("WaitAll
 			return flow.Error(err, "Unable to get  xstoreList!")
 		}
 		for _, xstoreBackup := range xstoreBackupList.Items {
+			if xstoreBackup.Status.Phase == xstorev1.XstoreBackupFailed {
+				backup.Status.Phase = polardbxv1.BackupFailed
+				backup.Status.FailureReason = xstoreFailureReason(&xstoreBackup)
+				backup.Status.Message = fmt.Sprintf("xstore backup %s failed: %s", xstoreBackup.Name, xstoreBackup.Status.Message)
+				return flow.Retry("Xstore backup failed when collecting binlog", "xstoreBackupName", xstoreBackup.Name)
+			}
 			if xstoreBackup.Status.Phase != xstorev1.XStoreBinlogBackuping {
 				return flow.Wait("xstorebackup is still collecting binlog", "xstoreBackupName", xstoreBackup.Name)
 			}
@@ -489,12 +723,16 @@ var PrepareSeekCpJobContext = polardbxv1reconcile.NewStepBinder("PrepareSeekCpJo
 			return flow.Pass()
 		}
 		polardbxBackup := rc.MustGetPolarDBXBackup()
+		polardbx, err := rc.GetPolarDBX()
+		if err != nil {
+			return flow.Error(err, "Unable to get original polardbx")
+		}
 
 		backupRootPath := polardbxBackup.Status.BackupRootPath
-		remoteCpPath := fmt.Sprintf("%s/%s/%s",
-			backupRootPath, polardbxmeta.BinlogOffsetPath, polardbxmeta.SeekCpName)
-		txEventsDir := fmt.Sprintf("%s/%s", backupRootPath, polardbxmeta.CollectBinlogPath)
-		indexesPath := fmt.Sprintf("%s/%s", backupRootPath, polardbxmeta.BinlogIndexesName)
+		remoteCpPath := path.JoinPath(backupRootPath, polardbxmeta.BinlogOffsetPath, polardbxmeta.SeekCpName)
+		txEventsDir := path.JoinPath(backupRootPath, polardbxmeta.CollectBinlogPath)
+		indexesPath := path.JoinPath(backupRootPath, polardbxmeta.BinlogIndexesName)
+		sseAlgorithm, sseKMSKeyId := polardbxBackup.Spec.StorageProvider.GetServerSideEncryption()
 
 		if err := rc.SaveTaskContext(string(xstoreconvention.BackupJobTypeSeekcp), &SeekCpJobContext{
 			RemoteCpPath: remoteCpPath,
@@ -503,6 +741,10 @@ var PrepareSeekCpJobContext = polardbxv1reconcile.NewStepBinder("PrepareSeekCpJo
 			DnNameList:   strings.Join(polardbxBackup.Status.XStores, ","),
 			StorageName:  string(polardbxBackup.Spec.StorageProvider.StorageName),
 			Sink:         polardbxBackup.Spec.StorageProvider.Sink,
+			Tags:         clusterObjectTags(rc, polardbx),
+			SSEAlgorithm: sseAlgorithm,
+			SSEKMSKeyId:  sseKMSKeyId,
+			EndpointType: string(polardbxBackup.Spec.StorageProvider.EndpointType),
 		}); err != nil {
 			return flow.Error(err, "Unable to save job context for seekcp!")
 		}
@@ -623,9 +865,15 @@ var RemoveSeekCpJob = polardbxv1reconcile.NewStepBinder("RemoveSeekCpJob",
 			return flow.Continue("SeekCp already job removed!")
 		}
 
-		err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
-		if client.IgnoreNotFound(err) != nil {
-			return flow.Error(err, "Unable to remove seekcp job", "job-name", job.Name)
+		propagation := rc.JobDeletePropagation()
+		if job.DeletionTimestamp.IsZero() {
+			err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(propagation))
+			if client.IgnoreNotFound(err) != nil {
+				return flow.Error(err, "Unable to remove seekcp job", "job-name", job.Name)
+			}
+		}
+		if propagation == metav1.DeletePropagationForeground {
+			return flow.RetryAfter(5*time.Second, "Wait until seekcp job removed.", "job-name", job.Name)
 		}
 
 		return flow.Continue("SeekCp job removed!", "job-name", job.Name)
@@ -634,6 +882,15 @@ var RemoveSeekCpJob = polardbxv1reconcile.NewStepBinder("RemoveSeekCpJob",
 var RemoveBackupOverRetention = polardbxv1reconcile.NewStepBinder("RemoveBackupOverRetention",
 	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		backup := rc.MustGetPolarDBXBackup()
+		// xstore backups are deleted along with pxc backup
+		protected, err := protectedXStoreBackups(rc)
+		if err != nil {
+			return flow.Error(err, "Unable to get xstore backups.")
+		}
+		if len(protected) > 0 {
+			flow.Logger().Info("Xstore backups are protected, skip retention deletion.", "xstore-backups", protected)
+			return flow.Continue("Protected backup retained!", "PolarDBXBackup-name", backup.Name)
+		}
 		if backup.Spec.RetentionTime.Duration.Seconds() > 0 {
 			toCleanTime := backup.Status.EndTime.Add(backup.Spec.RetentionTime.Duration)
 			now := time.Now()
@@ -686,112 +943,255 @@ var SavePXCSecrets = polardbxv1reconcile.NewStepBinder("SavePXCSecrets",
 		return flow.Continue("PolarDBX secret saved!")
 	})
 
-var UploadClusterMetadata = polardbxv1reconcile.NewStepBinder("UploadClusterMetadata",
-	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
-		// collect all the metadata and formatted them into a single json file
-		pxcBackup := rc.MustGetPolarDBXBackup()
-		polardbx, err := rc.GetPolarDBX()
-		if err != nil {
-			return flow.Error(err, "Unable to get original polardbx")
-		}
-		metadata := factory.MetadataBackup{
-			PolarDBXClusterMetadata: factory.PolarDBXClusterMetadata{
-				Name: polardbx.Name,
-				UID:  polardbx.UID,
-				Spec: pxcBackup.Status.ClusterSpecSnapshot.DeepCopy(),
-			},
-			XstoreMetadataList:         make([]factory.XstoreMetadata, 0, len(pxcBackup.Status.Backups)),
-			BackupSetName:              pxcBackup.Name,
-			BackupRootPath:             pxcBackup.Status.BackupRootPath,
-			StartTime:                  pxcBackup.Status.StartTime,
-			EndTime:                    pxcBackup.Status.EndTime,
-			LatestRecoverableTimestamp: pxcBackup.Status.LatestRecoverableTimestamp,
-		}
-
-		// check and record current serviceType according to service
-		service, err := rc.GetPolarDBXService(convention.ServiceTypeReadWrite)
+// buildClusterMetadata collects all the metadata of backup set, nil metadata is returned along with the result
+// to return from step if any of them is unavailable.
+func buildClusterMetadata(rc *polardbxv1reconcile.Context, flow control.Flow) (*factory.MetadataBackup, reconcile.Result, error) {
+	pxcBackup := rc.MustGetPolarDBXBackup()
+	polardbx, err := rc.GetPolarDBX()
+	if err != nil {
+		result, err := flow.Error(err, "Unable to get original polardbx")
+		return nil, result, err
+	}
+	metadata := factory.MetadataBackup{
+		PolarDBXClusterMetadata: factory.PolarDBXClusterMetadata{
+			Name: polardbx.Name,
+			UID:  polardbx.UID,
+			Spec: pxcBackup.Status.ClusterSpecSnapshot.DeepCopy(),
+		},
+		XstoreMetadataList:         make([]factory.XstoreMetadata, 0, len(pxcBackup.Status.Backups)),
+		BackupSetName:              pxcBackup.Name,
+		BackupRootPath:             pxcBackup.Status.BackupRootPath,
+		StartTime:                  pxcBackup.Status.StartTime,
+		EndTime:                    pxcBackup.Status.EndTime,
+		LatestRecoverableTimestamp: pxcBackup.Status.LatestRecoverableTimestamp,
+		CdcState:                   pxcBackup.Status.CdcState,
+		BackupMode:                 pxcBackup.Spec.BackupMode,
+		PolarDBXVersion:            pxcBackup.Status.PolarDBXVersion,
+		Images:                     pxcBackup.Status.Images,
+		GMSSchemaVersions:          pxcBackup.Status.GMSSchemaVersions,
+		ServerSideEncryption:       pxcBackup.Spec.StorageProvider.ServerSideEncryption.DeepCopy(),
+		UserMetadata:               pxcBackup.Spec.UserMetadata,
+		QuiescePoint:               pxcBackup.Status.QuiescePoint,
+		Archive:                    pxcBackup.Status.Archive.DeepCopy(),
+		IncludeDatabases:           pxcBackup.Spec.IncludeDatabases,
+		ExcludeDatabases:           pxcBackup.Spec.ExcludeDatabases,
+	}
+
+	// check and record current serviceType according to service
+	service, err := rc.GetPolarDBXService(convention.ServiceTypeReadWrite)
+	if err != nil {
+		result, err := flow.Error(err, "Unable to get polardbx service")
+		return nil, result, err
+	}
+	metadata.PolarDBXClusterMetadata.Spec.ServiceType = service.Spec.Type
+
+	// xstore metadata and secrets
+	pxcSecret, err := rc.GetSecret(pxcBackup.Name)
+	if err != nil || pxcSecret == nil {
+		result, err := flow.Error(err, "Unable to get secret for pxc", "pxc name", pxcBackup.Name)
+		return nil, result, err
+	}
+	metadata.PolarDBXClusterMetadata.Secrets = make([]polardbxv1polardbx.PrivilegeItem, 0, len(pxcSecret.Data))
+	for user, passwd := range pxcSecret.Data {
+		metadata.PolarDBXClusterMetadata.Secrets = append(
+			metadata.PolarDBXClusterMetadata.Secrets,
+			polardbxv1polardbx.PrivilegeItem{
+				Username: user,
+				Password: string(passwd),
+			})
+	}
+	for xstoreName, xstoreBackupName := range pxcBackup.Status.Backups {
+		var xstore xstorev1.XStore
+		err := rc.Client().Get(rc.Context(), types.NamespacedName{Namespace: rc.Namespace(), Name: xstoreName}, &xstore)
 		if err != nil {
-			return flow.Error(err, "Unable to get polardbx service")
+			result, err := flow.Error(err, "Unable to get xstore by name", "xstore name", xstoreName)
+			return nil, result, err
 		}
-		metadata.PolarDBXClusterMetadata.Spec.ServiceType = service.Spec.Type
-
-		// xstore metadata and secrets
-		pxcSecret, err := rc.GetSecret(pxcBackup.Name)
-		if err != nil || pxcSecret == nil {
-			return flow.Error(err, "Unable to get secret for pxc", "pxc name", pxcBackup.Name)
+		xstoreBackup, err := rc.GetXstoreBackupByName(xstoreBackupName)
+		if err != nil || xstoreBackup == nil {
+			result, err := flow.Error(err, "Unable to get backup for xstore", "xstore name", xstoreName)
+			return nil, result, err
 		}
-		metadata.PolarDBXClusterMetadata.Secrets = make([]polardbxv1polardbx.PrivilegeItem, 0, len(pxcSecret.Data))
-		for user, passwd := range pxcSecret.Data {
-			metadata.PolarDBXClusterMetadata.Secrets = append(
-				metadata.PolarDBXClusterMetadata.Secrets,
+		xstoreSecret, err := rc.GetSecret(xstoreBackup.GetSecretName())
+		if client.IgnoreNotFound(err) != nil {
+			result, err := flow.Error(err, "Unable to get secret for xstore", "xstore name", xstoreName)
+			return nil, result, err
+		} else if xstoreSecret == nil {
+			result, err := flow.RetryAfter(5*time.Second, "Wait for the creation of xstore secret bacup",
+				"xstore name", xstoreName)
+			return nil, result, err
+		}
+
+		xstoreMetadata := factory.XstoreMetadata{
+			Name:            xstoreName,
+			UID:             xstore.UID,
+			BackupName:      xstoreBackupName,
+			LastCommitIndex: xstoreBackup.Status.CommitIndex,
+			Secrets:         make([]polardbxv1polardbx.PrivilegeItem, 0, len(xstoreSecret.Data)),
+			TargetPod:       xstoreBackup.Status.TargetPod,
+		}
+		if provider, ok := pxcBackup.Status.XStoreStorageProviders[xstoreName]; ok {
+			xstoreMetadata.StorageProvider = provider.DeepCopy()
+		}
+		if xstoreBackup.Status.ChunkSize > 0 {
+			xstoreMetadata.ChunkSize = xstoreBackup.Status.ChunkSize
+			xstoreMetadata.ChunkManifestPath = path.JoinPath(xstoreBackup.Status.BackupRootPath,
+				polardbxmeta.FullBackupPath, xstoreName+".xbstream"+polardbxmeta.ChunkManifestSuffix)
+		}
+		if xstoreBackup.Status.KeyringKeyFingerprint != "" {
+			xstoreMetadata.KeyringReEncryption = xstoreBackup.Spec.KeyringReEncryption.DeepCopy()
+			xstoreMetadata.KeyringKeyFingerprint = xstoreBackup.Status.KeyringKeyFingerprint
+		}
+		xstoreMetadata.Encryption = xstoreBackup.Spec.Encryption.DeepCopy()
+		xstoreMetadata.FullBackupCodec = xstoreBackup.Status.FullBackupCodec.DeepCopy()
+		xstoreMetadata.BinlogBackupCodec = xstoreBackup.Status.BinlogBackupCodec.DeepCopy()
+		for user, passwd := range xstoreSecret.Data {
+			xstoreMetadata.Secrets = append(
+				xstoreMetadata.Secrets,
 				polardbxv1polardbx.PrivilegeItem{
 					Username: user,
 					Password: string(passwd),
 				})
 		}
-		for xstoreName, xstoreBackupName := range pxcBackup.Status.Backups {
-			var xstore xstorev1.XStore
-			err := rc.Client().Get(rc.Context(), types.NamespacedName{Namespace: rc.Namespace(), Name: xstoreName}, &xstore)
-			if err != nil {
-				return flow.Error(err, "Unable to get xstore by name", "xstore name", xstoreName)
-			}
-			xstoreSecret, err := rc.GetSecret(xstoreBackupName)
-			if client.IgnoreNotFound(err) != nil {
-				return flow.Error(err, "Unable to get secret for xstore", "xstore name", xstoreName)
-			} else if xstoreSecret == nil {
-				return flow.RetryAfter(5*time.Second, "Wait for the creation of xstore secret bacup",
-					"xstore name", xstoreName)
-			}
-			xstoreBackup, err := rc.GetXstoreBackupByName(xstoreBackupName)
-			if err != nil || xstoreBackup == nil {
-				return flow.Error(err, "Unable to get backup for xstore", "xstore name", xstoreName)
-			}
+		metadata.XstoreMetadataList = append(metadata.XstoreMetadataList, xstoreMetadata)
+	}
 
-			xstoreMetadata := factory.XstoreMetadata{
-				Name:            xstoreName,
-				UID:             xstore.UID,
-				BackupName:      xstoreBackupName,
-				LastCommitIndex: xstoreBackup.Status.CommitIndex,
-				Secrets:         make([]polardbxv1polardbx.PrivilegeItem, 0, len(xstoreSecret.Data)),
-				TargetPod:       xstoreBackup.Status.TargetPod,
-			}
-			for user, passwd := range xstoreSecret.Data {
-				xstoreMetadata.Secrets = append(
-					xstoreMetadata.Secrets,
-					polardbxv1polardbx.PrivilegeItem{
-						Username: user,
-						Password: string(passwd),
-					})
-			}
-			metadata.XstoreMetadataList = append(metadata.XstoreMetadataList, xstoreMetadata)
+	metadata.Sort()
+	return &metadata, reconcile.Result{}, nil
+}
+
+// uploadClusterMetadata uploads the metadata formatted into a single json file named by name under root path,
+// which overwrites the one uploaded before if any.
+func uploadClusterMetadata(rc *polardbxv1reconcile.Context, flow control.Flow,
+	metadata *factory.MetadataBackup, name string) (string, reconcile.Result, error) {
+	pxcBackup := rc.MustGetPolarDBXBackup()
+	polardbx, err := rc.GetPolarDBX()
+	if err != nil {
+		result, err := flow.Error(err, "Unable to get original polardbx")
+		return "", result, err
+	}
+
+	// parse metadata to json slice
+	jsonString, err := json.Marshal(metadata)
+	if err != nil {
+		result, err := flow.RetryErr(err, "Failed to marshal metadata, retry to upload metadata")
+		return "", result, err
+	}
+
+	// init filestream client and upload formatted metadata
+	filestreamClient, err := rc.GetFilestreamClient()
+	metadataBackupPath := path.JoinPath(metadata.BackupRootPath, name)
+	if err != nil {
+		result, err := flow.RetryAfter(10*time.Second, "Failed to get filestream client, error: "+err.Error())
+		return "", result, err
+	}
+	filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(pxcBackup.Spec.StorageProvider.StorageName)
+	if err != nil {
+		result, err := flow.RetryAfter(10*time.Second, "Unsupported storage provided")
+		return "", result, err
+	}
+	actionMetadata := filestream.ActionMetadata{
+		Action:    filestreamAction.Upload,
+		Sink:      pxcBackup.Spec.StorageProvider.Sink,
+		RequestId: uuid.New().String(),
+		Filename:  metadataBackupPath,
+		Tags:      clusterObjectTags(rc, polardbx),
+	}
+	actionMetadata.SSEAlgorithm, actionMetadata.SSEKMSKeyId = pxcBackup.Spec.StorageProvider.GetServerSideEncryption()
+	actionMetadata.EndpointType = string(pxcBackup.Spec.StorageProvider.EndpointType)
+	sendBytes, err := filestreamClient.Upload(bytes.NewReader(jsonString), actionMetadata)
+	if err != nil {
+		result, err := flow.RetryAfter(10*time.Second, "Upload metadata failed, error: "+err.Error())
+		return "", result, err
+	}
+	flow.Logger().Info("Uploading metadata finished", "sent bytes", sendBytes)
+	return metadataBackupPath, reconcile.Result{}, nil
+}
+
+// removePartialClusterMetadata removes the metadata uploaded early. Failure is only logged, since restore never
+// reads it and it's cleaned along with the backup set anyway.
+func removePartialClusterMetadata(rc *polardbxv1reconcile.Context, flow control.Flow) {
+	pxcBackup := rc.MustGetPolarDBXBackup()
+	hpfsClient, err := rc.GetHpfsClient()
+	if err != nil {
+		flow.Logger().Info("Unable to remove partial metadata.", "error", err)
+		return
+	}
+	response, err := hpfsClient.DeleteRemoteFile(rc.Context(), &hpfs.DeleteRemoteFileRequest{
+		SinkType: string(pxcBackup.Spec.StorageProvider.StorageName),
+		SinkName: pxcBackup.Spec.StorageProvider.Sink,
+		Target: &hpfs.RemoteFsEndpoint{
+			Path: path.JoinPath(pxcBackup.Status.BackupRootPath, polardbxmeta.PartialMetadataName),
+			Other: map[string]string{
+				"recursive": "false",
+			},
+		},
+	})
+	if err != nil || response.GetStatus().Code != hpfs.Status_OK {
+		flow.Logger().Info("Unable to remove partial metadata.", "error", err,
+			"status", response.GetStatus().String())
+	}
+}
+
+// UploadEarlyClusterMetadata uploads metadata of backup set while binlog backup is still running if enabled, so
+// that only the recoverable timestamp fields are left to UploadClusterMetadata. It's uploaded as partial metadata
+// rather than the one read by restore, since the backup set is not recoverable until finalized. Fields not known
+// yet are left empty, and the digest is recorded to tell whether the metadata changed when finalized.
+var UploadEarlyClusterMetadata = polardbxv1reconcile.NewStepBinder("UploadEarlyClusterMetadata",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		pxcBackup := rc.MustGetPolarDBXBackup()
+		if !rc.Config().Backup().IsEarlyMetadataUploadEnabled() || pxcBackup.Status.EarlyMetadataDigest != "" {
+			return flow.Pass()
 		}
 
-		// parse metadata to json slice
-		jsonString, err := json.Marshal(metadata)
+		metadata, result, err := buildClusterMetadata(rc, flow)
+		if metadata == nil {
+			return result, err
+		}
+		early := metadata.WithoutRecoverableFields()
+		digest, err := early.Digest()
 		if err != nil {
-			return flow.RetryErr(err, "Failed to marshal metadata, retry to upload metadata")
+			return flow.RetryErr(err, "Failed to digest metadata")
+		}
+		_, result, err = uploadClusterMetadata(rc, flow, early, polardbxmeta.PartialMetadataName)
+		if err != nil || !result.IsZero() {
+			return result, err
 		}
+		pxcBackup.Status.EarlyMetadataDigest = digest
+		return flow.Continue("Early metadata uploaded.", "digest", digest)
+	})
 
-		// init filestream client and upload formatted metadata
-		filestreamClient, err := rc.GetFilestreamClient()
-		metadataBackupPath := fmt.Sprintf("%s/metadata", metadata.BackupRootPath)
-		if err != nil {
-			return flow.RetryAfter(10*time.Second, "Failed to get filestream client, error: "+err.Error())
+// UploadClusterMetadata uploads the final metadata of backup set. The whole metadata is always uploaded in a single
+// object since restore reads it as a single file, so that it appears complete or not at all. It supersedes the
+// partial one uploaded early if any, which is removed afterwards. If nothing but recoverable timestamp fields changed
+// since early upload, it's just the early one with those fields finalized.
+var UploadClusterMetadata = polardbxv1reconcile.NewStepBinder("UploadClusterMetadata",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		pxcBackup := rc.MustGetPolarDBXBackup()
+		metadata, result, err := buildClusterMetadata(rc, flow)
+		if metadata == nil {
+			return result, err
 		}
-		filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(pxcBackup.Spec.StorageProvider.StorageName)
-		if err != nil {
-			return flow.RetryAfter(10*time.Second, "Unsupported storage provided")
+		if earlyDigest := pxcBackup.Status.EarlyMetadataDigest; earlyDigest != "" {
+			digest, err := metadata.WithoutRecoverableFields().Digest()
+			if err != nil {
+				return flow.RetryErr(err, "Failed to digest metadata")
+			}
+			if digest == earlyDigest {
+				flow.Logger().Info("Finalizing recoverable timestamp fields of early uploaded metadata")
+			} else {
+				rc.RecordEvent(pxcBackup, corev1.EventTypeNormal, "EarlyMetadataSuperseded",
+					"metadata changed since early upload, superseded by the final one")
+			}
 		}
-		actionMetadata := filestream.ActionMetadata{
-			Action:    filestreamAction.Upload,
-			Sink:      pxcBackup.Spec.StorageProvider.Sink,
-			RequestId: uuid.New().String(),
-			Filename:  metadataBackupPath,
+
+		metadataBackupPath, result, err := uploadClusterMetadata(rc, flow, metadata, polardbxmeta.MetadataName)
+		if err != nil || !result.IsZero() {
+			return result, err
 		}
-		sendBytes, err := filestreamClient.Upload(bytes.NewReader(jsonString), actionMetadata)
-		if err != nil {
-			return flow.RetryAfter(10*time.Second, "Upload metadata failed, error: "+err.Error())
+		if pxcBackup.Status.EarlyMetadataDigest != "" {
+			removePartialClusterMetadata(rc, flow)
 		}
-		flow.Logger().Info("Uploading metadata finished", "sent bytes", sendBytes)
+		pxcBackup.Status.Metadata = metadata.Summary(metadataBackupPath)
 		return flow.Continue("Metadata uploaded.")
 	})
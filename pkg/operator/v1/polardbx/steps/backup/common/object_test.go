@@ -0,0 +1,102 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+)
+
+func TestFindBackupByRootPath(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	newBackup := func(name, rootPath, sink string) polardbxv1.PolarDBXBackup {
+		backup := polardbxv1.PolarDBXBackup{}
+		backup.Name = name
+		backup.UID = types.UID("uid-" + name)
+		backup.Status.BackupRootPath = rootPath
+		backup.Spec.StorageProvider = polardbxv1polardbx.BackupStorageProvider{StorageName: polardbxv1polardbx.OSS, Sink: sink}
+		return backup
+	}
+	backup := newBackup("b1", "polardbx-backup/pxc/b1-20230101000000", "default")
+	others := []polardbxv1.PolarDBXBackup{
+		backup,
+		newBackup("b2", "polardbx-backup/pxc/b2-20230101000000", "default"),
+		newBackup("b3", "polardbx-backup/pxc/b1-20230101000000", "other"),
+	}
+	g.Expect(findBackupByRootPath(&backup, others)).To(gomega.BeEmpty())
+
+	others = append(others, newBackup("b4", "polardbx-backup/pxc/b1-20230101000000", "default"))
+	g.Expect(findBackupByRootPath(&backup, others)).To(gomega.Equal("b4"))
+}
+
+func TestXStoreFailureReason(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	xstoreBackup := &polardbxv1.XStoreBackup{}
+	xstoreBackup.Status.Reason = "full backup job failed"
+	g.Expect(xstoreFailureReason(xstoreBackup)).To(gomega.Equal(polardbxv1polardbx.FailureReasonUnknown))
+
+	xstoreBackup.Status.FailureReason = polardbxv1polardbx.FailureReasonJobFailed
+	g.Expect(xstoreFailureReason(xstoreBackup)).To(gomega.Equal(polardbxv1polardbx.FailureReasonJobFailed))
+}
+
+func TestOverriddenStorageProviders(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	newXStore := func(name, role string) polardbxv1.XStore {
+		xstore := polardbxv1.XStore{}
+		xstore.Name = name
+		xstore.Labels = map[string]string{polardbxmeta.LabelRole: role}
+		return xstore
+	}
+	xstores := []polardbxv1.XStore{
+		newXStore("pxc-gms", polardbxmeta.RoleGMS),
+		newXStore("pxc-dn-0", polardbxmeta.RoleDN),
+		newXStore("pxc-dn-1", polardbxmeta.RoleDN),
+	}
+	backup := &polardbxv1.PolarDBXBackup{}
+	backup.Spec.StorageProvider = polardbxv1polardbx.BackupStorageProvider{StorageName: polardbxv1polardbx.OSS, Sink: "default"}
+	backup.Status.Backups = map[string]string{"pxc-gms": "b-gms", "pxc-dn-0": "b-dn-0", "pxc-dn-1": "b-dn-1"}
+	g.Expect(overriddenStorageProviders(backup, xstores)).To(gomega.BeNil())
+
+	gmsSink := polardbxv1polardbx.BackupStorageProvider{StorageName: polardbxv1polardbx.MINIO, Sink: "gms"}
+	dnSink := polardbxv1polardbx.BackupStorageProvider{StorageName: polardbxv1polardbx.OSS, Sink: "dn"}
+	dn1Sink := polardbxv1polardbx.BackupStorageProvider{StorageName: polardbxv1polardbx.OSS, Sink: "dn-1"}
+	backup.Spec.StorageProviderOverrides = []polardbxv1polardbx.XStoreStorageProviderOverride{
+		{Role: polardbxmeta.RoleDN, StorageProvider: dnSink},
+		{XStore: "pxc-dn-1", StorageProvider: dn1Sink},
+		{Role: polardbxmeta.RoleGMS, StorageProvider: gmsSink},
+	}
+	g.Expect(overriddenStorageProviders(backup, xstores)).To(gomega.Equal(map[string]polardbxv1polardbx.BackupStorageProvider{
+		"pxc-gms":  gmsSink,
+		"pxc-dn-0": dnSink,
+		"pxc-dn-1": dn1Sink,
+	}))
+
+	// xstores not backed up are not recorded, and the others fall back to the default
+	backup.Spec.StorageProviderOverrides = backup.Spec.StorageProviderOverrides[1:2]
+	delete(backup.Status.Backups, "pxc-dn-1")
+	g.Expect(overriddenStorageProviders(backup, xstores)).To(gomega.BeNil())
+	backup.Status.Backups["pxc-dn-1"] = "b-dn-1"
+	backup.Status.XStoreStorageProviders = overriddenStorageProviders(backup, xstores)
+	g.Expect(backup.XStoreStorageProvider("pxc-dn-1")).To(gomega.Equal(dn1Sink))
+	g.Expect(backup.XStoreStorageProvider("pxc-dn-0")).To(gomega.Equal(backup.Spec.StorageProvider))
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	"github.com/alibaba/polardbx-operator/pkg/meta/core/group"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+)
+
+// quiesceTarget is the leader of xstore to be locked during quiesce.
+type quiesceTarget struct {
+	xstore  string
+	pod     string
+	manager group.GroupManager
+}
+
+// lockQuiesceTargets acquires the global read lock on all the targets concurrently, and releases all of them as
+// soon as they are acquired or failed, so that no xstore is locked longer than the timeout plus the time of
+// capturing binlog position. Positions are returned only if all the targets are locked.
+func lockQuiesceTargets(targets []quiesceTarget, timeout time.Duration) (map[string]polardbxv1.QuiescePosition, time.Duration, error) {
+	start := time.Now()
+	locks := make([]*group.ReadLock, len(targets))
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			locks[i], errs[i] = targets[i].manager.FlushTablesWithReadLock(timeout)
+		}(i)
+	}
+	wg.Wait()
+	for _, lock := range locks {
+		if lock != nil {
+			_ = lock.Release()
+		}
+	}
+	lockDuration := time.Since(start)
+
+	positions := make(map[string]polardbxv1.QuiescePosition, len(targets))
+	for i, target := range targets {
+		if errs[i] != nil {
+			return nil, lockDuration, errs[i]
+		}
+		positions[target.xstore] = polardbxv1.QuiescePosition{
+			Pod:          target.pod,
+			BinlogOffset: locks[i].BinlogOffset,
+			GtidExecuted: locks[i].GtidExecuted,
+		}
+	}
+	return positions, lockDuration, nil
+}
+
+// QuiesceXStores flushes tables with global read lock on leaders of the xstores to be backed up at once, records
+// their binlog positions as the quiesce point and releases the lock, before full backups begin. Quiesce is abandoned
+// and retried if any xstore fails to lock or the lock is held longer than allowed. It's skipped unless enabled.
+var QuiesceXStores = polardbxv1reconcile.NewStepBinder("QuiesceXStores",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetPolarDBXBackup()
+		if backup.Spec.Quiesce == nil {
+			return flow.Continue("Quiesce not enabled.")
+		}
+		if backup.Status.QuiescePoint != nil {
+			return flow.Continue("XStores already quiesced.")
+		}
+
+		var xstoreList polardbxv1.XStoreList
+		err := rc.Client().List(rc.Context(), &xstoreList, client.InNamespace(rc.Namespace()), client.MatchingLabels{
+			polardbxmeta.LabelName: backup.Spec.Cluster.Name,
+		})
+		if err != nil {
+			return flow.Error(err, "Unable to list xstore List")
+		}
+		xstoreSelector := labels.Everything()
+		if backup.Spec.XStoreSelector != nil {
+			xstoreSelector, err = metav1.LabelSelectorAsSelector(backup.Spec.XStoreSelector)
+			if err != nil {
+				// Backup fails on creating backups for xstores
+				return flow.Continue("Invalid xstore selector, skip quiesce.")
+			}
+		}
+
+		// Connections are prepared before locking, so that the lock window is not spent on connecting.
+		targets := make([]quiesceTarget, 0, len(xstoreList.Items))
+		for i := range xstoreList.Items {
+			xstore := &xstoreList.Items[i]
+			if !xstoreSelector.Matches(labels.Set(xstore.Labels)) {
+				continue
+			}
+			leaderPod, err := rc.GetLeaderOfDN(xstore)
+			if err != nil {
+				return flow.Error(err, "Unable to get leader of xstore", "xstore", xstore.Name)
+			}
+			manager, err := rc.GetXstoreGroupManagerByPod(leaderPod)
+			if err != nil {
+				return flow.Error(err, "Unable to get group manager", "pod", leaderPod.Name)
+			}
+			targets = append(targets, quiesceTarget{xstore: xstore.Name, pod: leaderPod.Name, manager: manager})
+		}
+		if len(targets) == 0 {
+			return flow.Continue("No xstore to quiesce.")
+		}
+
+		maxLockDuration := backup.Spec.Quiesce.GetMaxLockDuration()
+		lockedAt := metav1.Now()
+		positions, lockDuration, err := lockQuiesceTargets(targets, maxLockDuration)
+		if err != nil {
+			return flow.RetryAfter(10*time.Second, "Failed to lock xstores, quiesce abandoned.", "error", err.Error())
+		}
+		if lockDuration > maxLockDuration {
+			return flow.RetryAfter(10*time.Second, "Lock held longer than allowed, quiesce abandoned.",
+				"lock duration", lockDuration, "max lock duration", maxLockDuration)
+		}
+
+		backup.Status.QuiescePoint = &polardbxv1.QuiescePoint{
+			Timestamp:    &lockedAt,
+			LockDuration: metav1.Duration{Duration: lockDuration},
+			Positions:    positions,
+		}
+		return flow.Continue("XStores quiesced.", "lock duration", lockDuration)
+	})
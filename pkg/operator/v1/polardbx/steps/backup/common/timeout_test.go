@@ -0,0 +1,44 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+)
+
+func TestOverallTimeoutMessage(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	startTime := metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	backup := &polardbxv1.PolarDBXBackup{}
+	backup.Status.StartTime = &startTime
+	backup.Status.Phase = polardbxv1.FullBackuping
+	g.Expect(overallTimeoutMessage(backup, startTime.Add(24*time.Hour))).To(gomega.BeEmpty())
+
+	backup.Spec.OverallTimeout = &metav1.Duration{Duration: time.Hour}
+	g.Expect(overallTimeoutMessage(backup, startTime.Add(time.Minute))).To(gomega.BeEmpty())
+	g.Expect(overallTimeoutMessage(backup, startTime.Add(2*time.Hour))).To(gomega.Equal(
+		"backup not finished within overall timeout 1h0m0s, timed out in phase FullBackuping"))
+
+	backup.Status.Phase = polardbxv1.BackupFinished
+	g.Expect(overallTimeoutMessage(backup, startTime.Add(2*time.Hour))).To(gomega.BeEmpty())
+}
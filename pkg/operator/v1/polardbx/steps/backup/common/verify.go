@@ -0,0 +1,153 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+	"github.com/alibaba/polardbx-operator/pkg/util/path"
+)
+
+// expectedBackupObject is an object or a directory expected in backup set, path of which is relative to root path.
+// Objects of xstore are stored by storage provider of xstore backup, which may be overridden.
+type expectedBackupObject struct {
+	path   string
+	dir    bool
+	xstore string
+}
+
+// expectedBackupObjects lists the objects expected in backup set of finished backup, which are full backups,
+// binlog backups, keyrings and metadata. Binlog backups and keyrings are expected as directories, since their
+// objects are named by the engine.
+func expectedBackupObjects(backup *polardbxv1.PolarDBXBackup) []expectedBackupObject {
+	xstoreNames := make([]string, 0, len(backup.Status.Backups))
+	for xstoreName := range backup.Status.Backups {
+		xstoreNames = append(xstoreNames, xstoreName)
+	}
+	sort.Strings(xstoreNames)
+
+	objects := make([]expectedBackupObject, 0)
+	if backup.Status.Archive != nil {
+		objects = append(objects, expectedBackupObject{path: backup.Status.Archive.Path})
+	}
+	for _, xstoreName := range xstoreNames {
+		if backup.Status.Archive.GetEntry(xstoreName) == nil {
+			objects = append(objects, expectedBackupObject{
+				path:   path.JoinPath(polardbxmeta.FullBackupPath, xstoreName+".xbstream"),
+				xstore: xstoreName,
+			})
+		}
+		if !backup.IsSnapshotOnly() {
+			objects = append(objects, expectedBackupObject{
+				path:   path.JoinPath(polardbxmeta.BinlogBackupPath, xstoreName),
+				dir:    true,
+				xstore: xstoreName,
+			})
+		}
+	}
+	if snapshot := backup.Status.ClusterSpecSnapshot; snapshot != nil && snapshot.TDE.Enable && len(xstoreNames) > 0 {
+		// keyrings are uploaded by each xstore backup, so that the directory is in every sink of xstore backups
+		objects = append(objects, expectedBackupObject{path: polardbxmeta.KeyringPath, dir: true, xstore: xstoreNames[0]})
+	}
+	return append(objects, expectedBackupObject{path: polardbxmeta.MetadataName})
+}
+
+// QuickVerifyBackupObjects checks that the expected objects of finished backup exist in the sink with non-zero size,
+// which is much cheaper than a test restore and catches missing uploads immediately. Result is recorded as condition
+// Verified once, and it's skipped if required by spec.
+var QuickVerifyBackupObjects = polardbxv1reconcile.NewStepBinder("QuickVerifyBackupObjects",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetPolarDBXBackup()
+		if backup.Spec.SkipQuickVerify || hasBackupCondition(backup, polardbxv1.PolarDBXBackupVerified) {
+			return flow.Pass()
+		}
+
+		filestreamClient, err := rc.GetFilestreamClient()
+		if err != nil {
+			return flow.RetryAfter(10*time.Second, "Failed to get filestream client, error: "+err.Error())
+		}
+
+		objects := expectedBackupObjects(backup)
+		problems := make([]string, 0)
+		for _, object := range objects {
+			storageProvider := backup.Spec.StorageProvider
+			if object.xstore != "" {
+				storageProvider = backup.XStoreStorageProvider(object.xstore)
+			}
+			filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(storageProvider.StorageName)
+			if err != nil {
+				return flow.Error(err, "Unsupported storage provided.", "object", object.path)
+			}
+			objectPath := path.JoinPath(backup.Status.BackupRootPath, object.path)
+			exists, err := filestreamClient.Exists(filestream.ActionMetadata{
+				Action:    filestreamAction.List,
+				Sink:      storageProvider.Sink,
+				RequestId: uuid.New().String(),
+				Filepath:  objectPath,
+			})
+			if err != nil {
+				return flow.RetryAfter(10*time.Second, "Failed to list backup objects, error: "+err.Error(),
+					"object", object.path)
+			}
+			if !exists {
+				problems = append(problems, object.path+" not found")
+				continue
+			}
+			if object.dir {
+				continue
+			}
+			// reading the first byte tells the object is non-empty without downloading it
+			_, err = filestream.DownloadRange(filestreamClient, io.Discard, filestream.ActionMetadata{
+				Action:    filestreamAction.Download,
+				Sink:      storageProvider.Sink,
+				RequestId: uuid.New().String(),
+				Filename:  objectPath,
+			}, 0, 1)
+			if err != nil {
+				problems = append(problems, object.path+" is empty or unreadable")
+			}
+		}
+
+		cond := polardbxv1polardbx.Condition{
+			Type:    polardbxv1.PolarDBXBackupVerified,
+			Status:  corev1.ConditionTrue,
+			Reason:  "ObjectsVerified",
+			Message: fmt.Sprintf("%d objects of backup set verified", len(objects)),
+		}
+		if len(problems) > 0 {
+			cond.Status = corev1.ConditionFalse
+			cond.Reason = "ObjectsMissing"
+			cond.Message = "quick verification failed: " + strings.Join(problems, ", ")
+			rc.RecordEvent(backup, corev1.EventTypeWarning, cond.Reason, cond.Message)
+		}
+		setBackupCondition(backup, cond)
+		return flow.Continue("Backup objects verified.", "verified", cond.Status, "problems", len(problems))
+	})
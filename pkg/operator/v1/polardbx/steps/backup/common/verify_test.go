@@ -0,0 +1,56 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+)
+
+func TestExpectedBackupObjects(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	backup := &polardbxv1.PolarDBXBackup{}
+	backup.Status.Backups = map[string]string{"pxc-dn-0": "b-dn-0", "pxc-gms": "b-gms"}
+	g.Expect(expectedBackupObjects(backup)).To(gomega.Equal([]expectedBackupObject{
+		{path: "fullbackup/pxc-dn-0.xbstream", xstore: "pxc-dn-0"},
+		{path: "binlogbackup/pxc-dn-0", dir: true, xstore: "pxc-dn-0"},
+		{path: "fullbackup/pxc-gms.xbstream", xstore: "pxc-gms"},
+		{path: "binlogbackup/pxc-gms", dir: true, xstore: "pxc-gms"},
+		{path: "metadata"},
+	}))
+
+	// consolidated and snapshot-only backup of tde cluster
+	backup.Spec.BackupMode = polardbxv1polardbx.BackupModeSnapshot
+	backup.Status.ClusterSpecSnapshot = &polardbxv1.PolarDBXClusterSpec{}
+	backup.Status.ClusterSpecSnapshot.TDE.Enable = true
+	backup.Status.Archive = &polardbxv1polardbx.BackupArchive{
+		Path: "fullbackup/archive",
+		Entries: []polardbxv1polardbx.BackupArchiveEntry{
+			{XStore: "pxc-dn-0", Offset: 0, Size: 1024},
+			{XStore: "pxc-gms", Offset: 1024, Size: 1024},
+		},
+	}
+	g.Expect(expectedBackupObjects(backup)).To(gomega.Equal([]expectedBackupObject{
+		{path: "fullbackup/archive"},
+		{path: "keyring", dir: true, xstore: "pxc-dn-0"},
+		{path: "metadata"},
+	}))
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"sort"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/steps/backup/catalog"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
+)
+
+// backupSet is the view of a backup set considered when purging backup sets of schedule.
+type backupSet struct {
+	Backup    *polardbxv1.PolarDBXBackup
+	Size      int64
+	Protected bool
+}
+
+// pruneDecision records whether a backup set is evicted and why, along with the count and the estimated
+// storage usage of backup sets retained after the decision.
+type pruneDecision struct {
+	BackupSet     backupSet
+	Evict         bool
+	Reason        string
+	RetainedCount int
+	Usage         int64
+}
+
+// newBackupSets builds backup sets sorted from the eldest, size is summed from the xstore backups. Backup sets
+// being deleted are left out. Backup set is protected if either itself or any of its xstore backups is protected.
+func newBackupSets(backups []polardbxv1.PolarDBXBackup, xstoreBackups []polardbxv1.XStoreBackup) []backupSet {
+	xstoreBackupMap := make(map[string]*polardbxv1.XStoreBackup, len(xstoreBackups))
+	for i := range xstoreBackups {
+		xstoreBackupMap[xstoreBackups[i].Name] = &xstoreBackups[i]
+	}
+
+	backupSets := make([]backupSet, 0, len(backups))
+	for i := range backups {
+		backup := &backups[i]
+		if !backup.DeletionTimestamp.IsZero() {
+			continue
+		}
+		protected := backup.Annotations[polardbxmeta.AnnotationProtectedBackup] == "true"
+		for _, xstoreBackupName := range backup.Status.Backups {
+			if xstoreBackup, ok := xstoreBackupMap[xstoreBackupName]; ok &&
+				xstoreBackup.Annotations[xstoremeta.AnnotationProtectedBackup] == "true" {
+				protected = true
+			}
+		}
+		backupSets = append(backupSets, backupSet{
+			Backup:    backup,
+			Size:      catalog.NewBackupSetSummary(backup, xstoreBackupMap).Size,
+			Protected: protected,
+		})
+	}
+	sort.SliceStable(backupSets, func(i, j int) bool {
+		a, b := backupSets[i].Backup, backupSets[j].Backup
+		if a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.Name < b.Name
+		}
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	})
+	return backupSets
+}
+
+// planPrune decides which backup sets, sorted from the eldest, to evict. The eldest unprotected backup sets are
+// evicted while the count exceeds maxCount or the usage exceeds maxUsage, zero means no limit, but the count never
+// goes below minRetained. Decisions are returned for every backup set looked at, including the retained ones.
+func planPrune(backupSets []backupSet, maxCount, minRetained int, maxUsage int64) []pruneDecision {
+	count, usage := len(backupSets), int64(0)
+	for _, bs := range backupSets {
+		usage += bs.Size
+	}
+
+	var decisions []pruneDecision
+	for _, bs := range backupSets {
+		overCount := maxCount > 0 && count > maxCount
+		overUsage := maxUsage > 0 && usage > maxUsage
+		if !overCount && !overUsage {
+			break
+		}
+		decision := pruneDecision{BackupSet: bs}
+		if count <= minRetained {
+			decision.Reason = "min retained backups reached"
+			decision.RetainedCount, decision.Usage = count, usage
+			decisions = append(decisions, decision)
+			break
+		}
+		if bs.Protected {
+			decision.Reason = "backup set protected"
+		} else {
+			decision.Evict = true
+			if overCount {
+				decision.Reason = "backup count exceeds limit"
+			} else {
+				decision.Reason = "storage usage exceeds limit"
+			}
+			count, usage = count-1, usage-bs.Size
+		}
+		decision.RetainedCount, decision.Usage = count, usage
+		decisions = append(decisions, decision)
+	}
+	return decisions
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
+)
+
+func newBackup(name string, minute int) polardbxv1.PolarDBXBackup {
+	return polardbxv1.PolarDBXBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Date(2023, 1, 1, 0, minute, 0, 0, time.UTC)),
+		},
+		Status: polardbxv1.PolarDBXBackupStatus{
+			Phase:   polardbxv1.BackupFinished,
+			Backups: map[string]string{"dn-0": name + "-dn-0"},
+		},
+	}
+}
+
+func newXStoreBackup(name string, size int64) polardbxv1.XStoreBackup {
+	return polardbxv1.XStoreBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     polardbxv1.XStoreBackupStatus{FullBackupSize: size},
+	}
+}
+
+func evicted(decisions []pruneDecision) []string {
+	var names []string
+	for _, decision := range decisions {
+		if decision.Evict {
+			names = append(names, decision.BackupSet.Backup.Name)
+		}
+	}
+	return names
+}
+
+func TestNewBackupSets(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	protected := newBackup("b-protected", 2)
+	protected.Annotations = map[string]string{polardbxmeta.AnnotationProtectedBackup: "true"}
+	deleting := newBackup("b-deleting", 0)
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	xstoreProtected := newXStoreBackup("b-1-dn-0", 10)
+	xstoreProtected.Annotations = map[string]string{xstoremeta.AnnotationProtectedBackup: "true"}
+
+	backupSets := newBackupSets(
+		[]polardbxv1.PolarDBXBackup{newBackup("b-3", 3), protected, deleting, newBackup("b-1", 1)},
+		[]polardbxv1.XStoreBackup{xstoreProtected, newXStoreBackup("b-3-dn-0", 30)},
+	)
+	g.Expect(backupSets).To(gomega.HaveLen(3))
+	g.Expect(backupSets[0].Backup.Name).To(gomega.Equal("b-1"))
+	g.Expect(backupSets[0].Protected).To(gomega.BeTrue())
+	g.Expect(backupSets[0].Size).To(gomega.BeEquivalentTo(10))
+	g.Expect(backupSets[1].Backup.Name).To(gomega.Equal("b-protected"))
+	g.Expect(backupSets[1].Protected).To(gomega.BeTrue())
+	g.Expect(backupSets[2].Backup.Name).To(gomega.Equal("b-3"))
+	g.Expect(backupSets[2].Protected).To(gomega.BeFalse())
+	g.Expect(backupSets[2].Size).To(gomega.BeEquivalentTo(30))
+}
+
+func TestPlanPrune(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	newBackupSets := func(protected ...bool) []backupSet {
+		var backupSets []backupSet
+		for i, p := range protected {
+			backup := newBackup("b-"+string(rune('0'+i)), i)
+			backupSets = append(backupSets, backupSet{Backup: &backup, Size: 100, Protected: p})
+		}
+		return backupSets
+	}
+
+	// no limit
+	g.Expect(planPrune(newBackupSets(false, false, false), 0, 0, 0)).To(gomega.BeEmpty())
+
+	// over count
+	g.Expect(evicted(planPrune(newBackupSets(false, false, false), 2, 0, 0))).To(gomega.Equal([]string{"b-0"}))
+
+	// over usage, evicted until usage under limit
+	decisions := planPrune(newBackupSets(false, false, false, false), 0, 0, 250)
+	g.Expect(evicted(decisions)).To(gomega.Equal([]string{"b-0", "b-1"}))
+	g.Expect(decisions[1].Reason).To(gomega.Equal("storage usage exceeds limit"))
+	g.Expect(decisions[1].Usage).To(gomega.BeEquivalentTo(200))
+
+	// protected backup sets skipped
+	decisions = planPrune(newBackupSets(true, false, false), 0, 0, 150)
+	g.Expect(evicted(decisions)).To(gomega.Equal([]string{"b-1", "b-2"}))
+	g.Expect(decisions[0].Reason).To(gomega.Equal("backup set protected"))
+
+	// never below min retained even if usage still over limit
+	decisions = planPrune(newBackupSets(false, false, false, false), 1, 3, 50)
+	g.Expect(evicted(decisions)).To(gomega.Equal([]string{"b-0"}))
+	g.Expect(decisions).To(gomega.HaveLen(2))
+	g.Expect(decisions[1].Evict).To(gomega.BeFalse())
+	g.Expect(decisions[1].Reason).To(gomega.Equal("min retained backups reached"))
+	g.Expect(decisions[1].RetainedCount).To(gomega.Equal(3))
+}
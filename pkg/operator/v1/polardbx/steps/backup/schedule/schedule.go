@@ -1,16 +1,20 @@
 package schedule
 
 import (
+	"fmt"
 	"github.com/alibaba/polardbx-operator/api/v1"
 	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/factory"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
 	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
 	"github.com/alibaba/polardbx-operator/pkg/util/slice"
 	"github.com/robfig/cron"
+	"hash/fnv"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
-	"sort"
 	"time"
 )
 
@@ -29,35 +33,84 @@ var PersistPolarDBXBackupScheduleStatus = polardbxv1reconcile.NewStepBinder("Per
 var CleanOutdatedBackupSet = polardbxv1reconcile.NewStepBinder("CleanOutdatedBackupSet",
 	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		backupSchedule := rc.MustGetPolarDBXBackupSchedule()
-		if backupSchedule.Spec.MaxBackupCount == 0 {
-			return flow.Continue("No limit on the count of backup set.")
+		var maxStorageUsage int64
+		if backupSchedule.Spec.MaxStorageUsage != nil {
+			maxStorageUsage = backupSchedule.Spec.MaxStorageUsage.Value()
+		}
+		if backupSchedule.Spec.MaxBackupCount == 0 && maxStorageUsage == 0 {
+			return flow.Continue("No limit on the count or storage usage of backup set.")
 		}
 
 		backupList, err := rc.GetPolarDBXBackupListByScheduleName(backupSchedule.Name)
 		if err != nil {
 			return flow.Error(err, "Failed to get backup list.", "schedule name", backupSchedule.Name)
 		}
-		if backupSchedule.Spec.MaxBackupCount > len(backupList.Items) {
-			return flow.Continue("No outdated backup set needs to be cleaned.")
+		polardbxName := backupSchedule.Spec.BackupSpec.Cluster.Name
+		xstoreBackupList, err := rc.GetXStoreBackupListByPolarDBXName(polardbxName)
+		if err != nil {
+			return flow.Error(err, "Failed to get xstore backup list.", "PolarDBX name", polardbxName)
 		}
 
-		backupItems := backupList.Items
-		sort.Slice(backupItems, func(i, j int) bool {
-			a, b := backupItems[i], backupItems[j]
-			return a.CreationTimestamp.Before(&b.CreationTimestamp)
-		})
+		decisions := planPrune(newBackupSets(backupList.Items, xstoreBackupList.Items),
+			backupSchedule.Spec.MaxBackupCount, backupSchedule.Spec.MinRetainedBackups, maxStorageUsage)
+		if len(decisions) == 0 {
+			return flow.Continue("No outdated backup set needs to be cleaned.")
+		}
 
-		for i := 0; i < len(backupItems)-backupSchedule.Spec.MaxBackupCount; i++ {
-			flow.Logger().Info("Delete outdated backup", "backup name", backupItems[i].Name)
-			err := rc.Client().Delete(rc.Context(), &backupItems[i])
+		for _, decision := range decisions {
+			backup := decision.BackupSet.Backup
+			if !decision.Evict {
+				flow.Logger().Info("Retain backup", "backup name", backup.Name, "reason", decision.Reason,
+					"size", decision.BackupSet.Size, "retained count", decision.RetainedCount, "usage", decision.Usage)
+				continue
+			}
+			flow.Logger().Info("Delete outdated backup", "backup name", backup.Name, "reason", decision.Reason,
+				"size", decision.BackupSet.Size, "retained count", decision.RetainedCount, "usage", decision.Usage)
+			err := rc.Client().Delete(rc.Context(), backup)
 			if client.IgnoreNotFound(err) != nil {
-				return flow.Error(err, "Failed to delete backup.", "backup name", backupItems[i].Name)
+				return flow.Error(err, "Failed to delete backup.", "backup name", backup.Name)
 			}
 		}
 
 		return flow.Continue("Outdated backup set cleaned.")
 	})
 
+// scheduleJitter returns a delay in [0, maxJitter) derived from hash of the cluster, so that scheduled backups
+// of clusters sharing the same schedule are spread out and stay stable across operator restarts.
+func scheduleJitter(namespace, clusterName string, maxJitter time.Duration) time.Duration {
+	if maxJitter <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(namespace + "/" + clusterName))
+	return time.Duration(h.Sum64() % uint64(maxJitter))
+}
+
+// scheduleOf returns the cron expression of schedule, which falls back to the schedule of backup class referred by
+// backup spec or labeled on cluster if not specified, so that changes of class apply to the next backup.
+func scheduleOf(rc *polardbxv1reconcile.Context, backupSchedule *v1.PolarDBXBackupSchedule) (string, error) {
+	if backupSchedule.Spec.Schedule != "" {
+		return backupSchedule.Spec.Schedule, nil
+	}
+	className := backupSchedule.Spec.BackupSpec.BackupClass
+	if className == "" {
+		var polardbx v1.PolarDBXCluster
+		err := rc.Client().Get(rc.Context(), types.NamespacedName{
+			Namespace: backupSchedule.Namespace,
+			Name:      backupSchedule.Spec.BackupSpec.Cluster.Name,
+		}, &polardbx)
+		if err != nil {
+			return "", err
+		}
+		className = polardbx.Labels[polardbxmeta.LabelBackupClass]
+	}
+	class := rc.Config().Backup().GetBackupClass(className)
+	if class == nil || class.Schedule == "" {
+		return "", fmt.Errorf("schedule is neither specified nor defined by backup class %q", className)
+	}
+	return class.Schedule, nil
+}
+
 var CheckNextScheduleTime = polardbxv1reconcile.NewStepBinder("CheckNextScheduleTime",
 	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		backupSchedule := rc.MustGetPolarDBXBackupSchedule()
@@ -65,11 +118,21 @@ var CheckNextScheduleTime = polardbxv1reconcile.NewStepBinder("CheckNextSchedule
 		currentTime, nextTime, lastTime := time.Now(), backupSchedule.Status.NextBackupTime, backupSchedule.Status.LastBackupTime
 
 		// Parse schedule
-		schedule, err := cron.ParseStandard(backupSchedule.Spec.Schedule)
-		newNextTime := &metav1.Time{Time: schedule.Next(currentTime)}
+		scheduleSpec, err := scheduleOf(rc, backupSchedule)
+		if err != nil {
+			return flow.Error(err, "Get schedule failed.")
+		}
+		schedule, err := cron.ParseStandard(scheduleSpec)
 		if err != nil {
 			return flow.Error(err, "Parse schedule string failed.")
 		}
+		maxJitter, err := rc.Config().Backup().GetScheduleMaxJitter()
+		if err != nil {
+			return flow.Error(err, "Parse schedule max jitter failed.")
+		}
+		jitter := scheduleJitter(backupSchedule.Namespace, backupSchedule.Spec.BackupSpec.Cluster.Name, maxJitter)
+		// Shift the schedule by jitter, the planned time stays unchanged until it passes
+		newNextTime := &metav1.Time{Time: schedule.Next(currentTime.Add(-jitter)).Add(jitter)}
 
 		if nextTime == nil {
 			// Init next time if no planned backup found
@@ -89,6 +152,7 @@ var CheckNextScheduleTime = polardbxv1reconcile.NewStepBinder("CheckNextSchedule
 			}
 		}
 
+		nextTime = backupSchedule.Status.NextBackupTime
 		return flow.RetryAfter(nextTime.Sub(currentTime), "It is not the time for backup.",
 			"next backup time", nextTime)
 	})
@@ -120,10 +184,15 @@ var DispatchBackupTask = polardbxv1reconcile.NewStepBinder("DispatchBackupTask",
 			return flow.RetryErr(err, "Failed to new backup.")
 		}
 		err = rc.Client().Create(rc.Context(), polardbxBackup)
-		if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			// Backup of this round has been created, e.g. by the previous leader before recording it
+			// in schedule status, just record it rather than creating a new one.
+			flow.Logger().Info("Backup already created", "backup", polardbxBackup.Name)
+		} else if err != nil {
 			return flow.RetryErr(err, "Failed to create backup.")
+		} else {
+			flow.Logger().Info("New backup created", "backup", polardbxBackup.Name)
 		}
-		flow.Logger().Info("New backup created", "backup", polardbxBackup.Name)
 
 		// Record backup info
 		backupSchedule.Status.LastBackupTime = &metav1.Time{Time: time.Now()}
@@ -2,14 +2,47 @@ package backupbinlog
 
 import (
 	"errors"
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
 	hpfs "github.com/alibaba/polardbx-operator/pkg/hpfs/proto"
 	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
 	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
 	"math"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"time"
 )
 
+// latestFullBackupStartTime returns the start time in unix seconds of the latest finished full backup of the
+// cluster, 0 if there's none. Binlog after it is required to recover from the backup to any point in time.
+func latestFullBackupStartTime(backupBinlog *polardbxv1.PolarDBXBackupBinlog, backups []polardbxv1.PolarDBXBackup) int64 {
+	var latest int64
+	for _, backup := range backups {
+		if backup.Spec.Cluster.Name != backupBinlog.Spec.PxcName ||
+			(backupBinlog.Spec.PxcUid != "" && backup.Spec.Cluster.UID != "" &&
+				string(backup.Spec.Cluster.UID) != backupBinlog.Spec.PxcUid) {
+			continue
+		}
+		if backup.Status.Phase != polardbxv1.BackupFinished || !backup.DeletionTimestamp.IsZero() ||
+			backup.Status.StartTime == nil {
+			continue
+		}
+		if startTime := backup.Status.StartTime.Unix(); startTime > latest {
+			latest = startTime
+		}
+	}
+	return latest
+}
+
+// binlogExpireDeadline returns the deadline before which remote binlog files are deleted. Binlog retention is
+// independent of full backups, but binlog after the latest full backup is always kept, so that the cluster is
+// still recoverable to any point in time since then.
+func binlogExpireDeadline(retentionDeadline, latestFullBackupStart int64) int64 {
+	if latestFullBackupStart > 0 && latestFullBackupStart < retentionDeadline {
+		return latestFullBackupStart
+	}
+	return retentionDeadline
+}
+
 var TryDeleteExpiredFiles = polardbxv1reconcile.NewStepBinder("TryDeleteExpiredFiles", func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 	hpfsClient, err := rc.GetHpfsClient()
 	if err != nil {
@@ -20,6 +53,14 @@ var TryDeleteExpiredFiles = polardbxv1reconcile.NewStepBinder("TryDeleteExpiredF
 	deadline := now.Unix() - int64(backupBinlog.Spec.RemoteExpireLogHours.IntValue()*3600)
 	if !backupBinlog.DeletionTimestamp.IsZero() {
 		deadline = math.MaxInt64
+	} else {
+		var backupList polardbxv1.PolarDBXBackupList
+		if err := rc.Client().List(rc.Context(), &backupList, client.InNamespace(backupBinlog.Namespace)); err != nil {
+			return flow.RetryErr(err, "failed to list backups")
+		}
+		latestFullBackupStart := latestFullBackupStartTime(backupBinlog, backupList.Items)
+		deadline = binlogExpireDeadline(deadline, latestFullBackupStart)
+		flow.Logger().Info("binlog expire deadline", "deadline", deadline, "latestFullBackupStart", latestFullBackupStart)
 	}
 	rep, err := hpfsClient.DeleteBinlogFilesBefore(rc.Context(), &hpfs.DeleteBinlogFilesBeforeRequest{
 		Namespace: backupBinlog.Namespace,
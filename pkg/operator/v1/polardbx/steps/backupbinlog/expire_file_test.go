@@ -0,0 +1,37 @@
+package backupbinlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+)
+
+func TestBinlogExpireDeadline(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	newBackup := func(cluster string, phase polardbxv1.PolarDBXBackupPhase, hours int) polardbxv1.PolarDBXBackup {
+		startTime := metav1.NewTime(start.Add(time.Duration(hours) * time.Hour))
+		return polardbxv1.PolarDBXBackup{
+			Spec:   polardbxv1.PolarDBXBackupSpec{Cluster: polardbxv1.PolarDBXClusterReference{Name: cluster}},
+			Status: polardbxv1.PolarDBXBackupStatus{Phase: phase, StartTime: &startTime},
+		}
+	}
+	backupBinlog := &polardbxv1.PolarDBXBackupBinlog{Spec: polardbxv1.PolarDBXBackupBinlogSpec{PxcName: "pxc"}}
+	backups := []polardbxv1.PolarDBXBackup{
+		newBackup("pxc", polardbxv1.BackupFinished, 0),
+		newBackup("pxc", polardbxv1.BackupFinished, 24),
+		newBackup("pxc", polardbxv1.BackupFailed, 48),
+		newBackup("other", polardbxv1.BackupFinished, 72),
+	}
+	latest := latestFullBackupStartTime(backupBinlog, backups)
+	g.Expect(latest).To(gomega.Equal(start.Add(24 * time.Hour).Unix()))
+
+	// binlog after the latest full backup is kept
+	g.Expect(binlogExpireDeadline(start.Add(36*time.Hour).Unix(), latest)).To(gomega.Equal(latest))
+	g.Expect(binlogExpireDeadline(start.Add(12*time.Hour).Unix(), latest)).To(gomega.Equal(start.Add(12 * time.Hour).Unix()))
+	g.Expect(binlogExpireDeadline(start.Unix(), 0)).To(gomega.Equal(start.Unix()))
+}
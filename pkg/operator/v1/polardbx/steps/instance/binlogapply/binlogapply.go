@@ -0,0 +1,263 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package binlogapply
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/helper"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
+	"github.com/alibaba/polardbx-operator/pkg/util/path"
+)
+
+// binlogApplyTarget is the binlog applied onto a DN, covering commit index range (StartIndex, EndIndex]
+// of the original DN.
+type binlogApplyTarget struct {
+	XStore     *polardbxv1.XStore
+	BinlogDir  string
+	StartIndex int64
+	EndIndex   int64
+	// Codec is how binlogs are processed by backup, and Encryption selects the key if they're encrypted
+	Codec      *polardbxv1polardbx.BackupCodec
+	Encryption *polardbxv1polardbx.BackupEncryption
+}
+
+// binlogApplyRange returns the commit index range of binlog applied onto the DN. Binlog must start from
+// the index which data of DN has been applied or restored to, otherwise the data is not continuous.
+func binlogApplyRange(appliedIndexes map[string]int64, xstore *polardbxv1.XStore, xstoreBackup *polardbxv1.XStoreBackup) (int64, int64, error) {
+	startIndex, ok := appliedIndexes[xstore.Name]
+	if !ok && xstore.Status.RestoreStatus != nil {
+		startIndex = xstore.Status.RestoreStatus.ExpectedCommitIndex
+	}
+	if startIndex <= 0 {
+		return 0, 0, fmt.Errorf("commit index restored of xstore %s is unknown", xstore.Name)
+	}
+	endIndex := xstoreBackup.Status.CommitIndex
+	if endIndex <= startIndex {
+		return 0, 0, fmt.Errorf("commit index %d of backup %s is not after index %d applied of xstore %s",
+			endIndex, xstoreBackup.Name, startIndex, xstore.Name)
+	}
+	return startIndex, endIndex, nil
+}
+
+// planBinlogApply validates that the backup set is compatible with the data restored, and returns the
+// binlog applied onto each DN.
+func planBinlogApply(rc *polardbxv1reconcile.Context) (*polardbxv1.PolarDBXBackup, []*binlogApplyTarget, error) {
+	polardbx := rc.MustGetPolarDBX()
+	restore := polardbx.Spec.Restore
+	if restore == nil {
+		return nil, nil, errors.New("cluster is not restored from backup")
+	}
+	var appliedIndexes map[string]int64
+	if polardbx.Status.BinlogApplyStatus != nil {
+		appliedIndexes = polardbx.Status.BinlogApplyStatus.AppliedIndexes
+	}
+	if restore.Time != "" && len(appliedIndexes) == 0 {
+		return nil, nil, errors.New("commit index restored to a point in time is unknown")
+	}
+
+	backupSet := polardbx.Spec.BinlogApply.BackupSet
+	backup, err := rc.GetPXCBackupByName(backupSet)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get backup set %s: %w", backupSet, err)
+	}
+	if backup.Status.Phase != polardbxv1.BackupFinished {
+		return nil, nil, fmt.Errorf("backup set %s is not finished", backupSet)
+	}
+	if backup.IsSnapshotOnly() {
+		return nil, nil, fmt.Errorf("backup set %s includes no binlog", backupSet)
+	}
+	if restore.From.PolarBDXName != "" && backup.Spec.Cluster.Name != restore.From.PolarBDXName {
+		return nil, nil, fmt.Errorf("backup set %s is not of cluster %s", backupSet, restore.From.PolarBDXName)
+	}
+
+	dnList, err := rc.GetOrderedDNList()
+	if err != nil {
+		return nil, nil, err
+	}
+	targets := make([]*binlogApplyTarget, 0, len(dnList))
+	for _, xstore := range dnList {
+		if xstore.Spec.Restore == nil || xstore.Spec.Restore.From.XStoreName == "" {
+			return nil, nil, fmt.Errorf("xstore %s is not restored from backup", xstore.Name)
+		}
+		source := xstore.Spec.Restore.From.XStoreName
+		xstoreBackupName, ok := backup.Status.Backups[source]
+		if !ok {
+			return nil, nil, fmt.Errorf("backup of xstore %s not found in backup set %s", source, backupSet)
+		}
+		xstoreBackup, err := rc.GetXstoreBackupByName(xstoreBackupName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get xstore backup %s: %w", xstoreBackupName, err)
+		}
+		startIndex, endIndex, err := binlogApplyRange(appliedIndexes, xstore, xstoreBackup)
+		if err != nil {
+			return nil, nil, err
+		}
+		targets = append(targets, &binlogApplyTarget{
+			XStore:     xstore,
+			BinlogDir:  path.JoinPath(backup.Status.BackupRootPath, polardbxmeta.BinlogBackupPath, source),
+			StartIndex: startIndex,
+			EndIndex:   endIndex,
+			Codec:      xstoreBackup.Status.BinlogBackupCodec.OrDefault(polardbxv1polardbx.BackupArtifactBinlog),
+			Encryption: xstoreBackup.Spec.Encryption,
+		})
+	}
+	return backup, targets, nil
+}
+
+// failBinlogApply records the failure and turns the cluster back to running, data applied partially is kept.
+func failBinlogApply(rc *polardbxv1reconcile.Context, flow control.Flow, message string) (reconcile.Result, error) {
+	polardbx := rc.MustGetPolarDBX()
+	if polardbx.Status.BinlogApplyStatus == nil {
+		polardbx.Status.BinlogApplyStatus = &polardbxv1polardbx.BinlogApplyStatus{}
+	}
+	polardbx.Status.BinlogApplyStatus.Phase = polardbxv1polardbx.BinlogApplyFailed
+	polardbx.Status.BinlogApplyStatus.Message = message
+	if err := deleteBinlogApplyJobs(rc); err != nil {
+		return flow.Error(err, "Unable to delete binlog apply jobs.")
+	}
+	helper.TransferPhase(polardbx, polardbxv1polardbx.PhaseRunning)
+	return flow.Retry("Binlog apply failed, transfer back to running.", "message", message)
+}
+
+func deleteBinlogApplyJobs(rc *polardbxv1reconcile.Context) error {
+	polardbx := rc.MustGetPolarDBX()
+	return rc.Client().DeleteAllOf(rc.Context(), &batchv1.Job{},
+		client.InNamespace(rc.Namespace()),
+		client.MatchingLabels(newJobLabels(polardbx)),
+		client.PropagationPolicy(metav1.DeletePropagationBackground))
+}
+
+var CheckBinlogApplySpec = polardbxv1reconcile.NewStepBinder("CheckBinlogApplySpec",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		polardbx := rc.MustGetPolarDBX()
+		backupSet := polardbx.Spec.BinlogApply.BackupSet
+		status := polardbx.Status.BinlogApplyStatus
+		if status == nil {
+			status = &polardbxv1polardbx.BinlogApplyStatus{}
+			polardbx.Status.BinlogApplyStatus = status
+		}
+		if status.BackupSet == backupSet && status.Phase == polardbxv1polardbx.BinlogApplyApplying {
+			return flow.Continue("Binlog apply already checked.")
+		}
+		status.BackupSet = backupSet
+		status.Phase = polardbxv1polardbx.BinlogApplyApplying
+		status.Message = ""
+
+		if _, _, err := planBinlogApply(rc); err != nil {
+			return failBinlogApply(rc, flow, err.Error())
+		}
+		return flow.Continue("Binlog apply checked.", "backup-set", backupSet)
+	},
+)
+
+var StartBinlogApplyJobs = polardbxv1reconcile.NewStepBinder("StartBinlogApplyJobs",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		polardbx := rc.MustGetPolarDBX()
+		backup, targets, err := planBinlogApply(rc)
+		if err != nil {
+			return failBinlogApply(rc, flow, err.Error())
+		}
+		for _, target := range targets {
+			var job batchv1.Job
+			jobName := NewJobName(target.XStore.Name)
+			err := rc.Client().Get(rc.Context(), types.NamespacedName{Namespace: rc.Namespace(), Name: jobName}, &job)
+			if err == nil {
+				continue
+			}
+			if !apierrors.IsNotFound(err) {
+				return flow.Error(err, "Unable to get binlog apply job.", "job", jobName)
+			}
+
+			leaderPod, err := rc.GetLeaderOfDN(target.XStore)
+			if err != nil {
+				return flow.RetryAfter(10*time.Second, "Unable to get leader of xstore, retry.",
+					"xstore", target.XStore.Name, "error", err.Error())
+			}
+			password, err := rc.GetXStoreAccountPassword(convention.SuperAccount, target.XStore)
+			if err != nil {
+				return flow.Error(err, "Unable to get password of xstore.", "xstore", target.XStore.Name)
+			}
+			applyJob := newBinlogApplyJob(polardbx, target, leaderPod, string(backup.Spec.StorageProvider.StorageName),
+				backup.Spec.StorageProvider.Sink, password)
+			if err := rc.SetControllerRefAndCreate(applyJob); err != nil {
+				return flow.Error(err, "Unable to create binlog apply job.", "job", jobName)
+			}
+			flow.Logger().Info("Binlog apply job created.", "job", jobName, "start-index", target.StartIndex,
+				"end-index", target.EndIndex)
+		}
+		return flow.Continue("Binlog apply jobs started.")
+	},
+)
+
+var WaitBinlogApplyJobsFinished = polardbxv1reconcile.NewStepBinder("WaitBinlogApplyJobsFinished",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		polardbx := rc.MustGetPolarDBX()
+		backup, targets, err := planBinlogApply(rc)
+		if err != nil {
+			return failBinlogApply(rc, flow, err.Error())
+		}
+		for _, target := range targets {
+			var job batchv1.Job
+			jobName := NewJobName(target.XStore.Name)
+			err := rc.Client().Get(rc.Context(), types.NamespacedName{Namespace: rc.Namespace(), Name: jobName}, &job)
+			if err != nil {
+				return flow.Error(err, "Unable to get binlog apply job.", "job", jobName)
+			}
+			if k8shelper.IsJobFailed(&job) {
+				return failBinlogApply(rc, flow, fmt.Sprintf("binlog apply job %s failed", jobName))
+			}
+			if !k8shelper.IsJobCompleted(&job) {
+				return flow.RetryAfter(10*time.Second, "Binlog apply job is running, wait.", "job", jobName)
+			}
+		}
+
+		status := polardbx.Status.BinlogApplyStatus
+		appliedIndexes := make(map[string]int64, len(targets))
+		for _, target := range targets {
+			appliedIndexes[target.XStore.Name] = target.EndIndex
+		}
+		status.AppliedIndexes = appliedIndexes
+		status.AppliedTimestamp = backup.Status.LatestRecoverableTimestamp.DeepCopy()
+		status.Phase = polardbxv1polardbx.BinlogApplyFinished
+		return flow.Continue("Binlog apply jobs finished.")
+	},
+)
+
+var RemoveBinlogApplyJobs = polardbxv1reconcile.NewStepBinder("RemoveBinlogApplyJobs",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		if err := deleteBinlogApplyJobs(rc); err != nil {
+			return flow.Error(err, "Unable to delete binlog apply jobs.")
+		}
+		return flow.Continue("Binlog apply jobs removed.")
+	},
+)
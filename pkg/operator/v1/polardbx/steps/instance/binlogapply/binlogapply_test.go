@@ -0,0 +1,61 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package binlogapply
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/xstore"
+)
+
+func TestBinlogApplyRange(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dn := &polardbxv1.XStore{
+		ObjectMeta: metav1.ObjectMeta{Name: "dn-0"},
+		Status: polardbxv1.XStoreStatus{
+			RestoreStatus: &xstore.RestoreStatus{ExpectedCommitIndex: 100},
+		},
+	}
+	xstoreBackup := &polardbxv1.XStoreBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup-dn-0"},
+		Status:     polardbxv1.XStoreBackupStatus{CommitIndex: 200},
+	}
+
+	// continue from the index restored
+	start, end, err := binlogApplyRange(nil, dn, xstoreBackup)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(start).To(gomega.BeEquivalentTo(100))
+	g.Expect(end).To(gomega.BeEquivalentTo(200))
+
+	// continue from the index applied before
+	start, _, err = binlogApplyRange(map[string]int64{"dn-0": 150}, dn, xstoreBackup)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(start).To(gomega.BeEquivalentTo(150))
+
+	// backup not after the data
+	_, _, err = binlogApplyRange(map[string]int64{"dn-0": 200}, dn, xstoreBackup)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// index restored unknown
+	dn.Status.RestoreStatus = nil
+	_, _, err = binlogApplyRange(nil, dn, xstoreBackup)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package binlogapply
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
+	"github.com/alibaba/polardbx-operator/pkg/meta/core/gms/security"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/command"
+	xstoreconvention "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
+)
+
+const ContainerName = "binlogapply"
+
+func NewJobName(xstoreName string) string {
+	result := fmt.Sprintf("%s-binlog-apply", xstoreName)
+	if len(result) > 63 {
+		result = security.MustSha1Hash(result)
+	}
+	return result
+}
+
+func newJobLabels(polardbx *polardbxv1.PolarDBXCluster) map[string]string {
+	return map[string]string{
+		polardbxmeta.LabelName:    polardbx.Name,
+		polardbxmeta.LabelJobType: string(polardbxmeta.BinlogApplyJobType),
+	}
+}
+
+// newBinlogApplyJob creates the job applying binlog of the target onto the leader of DN. The job shares
+// the pod spec of the leader, so that it runs with the same tools and volumes.
+func newBinlogApplyJob(polardbx *polardbxv1.PolarDBXCluster, target *binlogApplyTarget, leaderPod *corev1.Pod,
+	storageName, sink, password string) *batchv1.Job {
+	podSpec := leaderPod.Spec.DeepCopy()
+	podSpec.InitContainers = nil
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+	podSpec.HostNetwork = false
+
+	// Remove containers except engine
+	podSpec.Containers = []corev1.Container{
+		*k8shelper.GetContainerFromPodSpec(podSpec, "engine"),
+	}
+	podSpec.Containers[0].Name = ContainerName
+
+	podSpec.Containers[0].Command = command.NewCanonicalCommandBuilder().Restore().
+		ApplyBinlog(target.BinlogDir, storageName, sink, target.StartIndex, target.EndIndex,
+			leaderPod.Status.PodIP, password, string(target.Codec.Compression), string(target.Codec.Encryption)).Build()
+	podSpec.Containers[0].Resources.Limits = nil
+	podSpec.Containers[0].Resources.Requests = nil
+	podSpec.Containers[0].Ports = nil
+
+	podSpec.Containers[0].LivenessProbe = nil
+	podSpec.Containers[0].ReadinessProbe = nil
+	podSpec.Containers[0].StartupProbe = nil
+
+	// Key of encrypted binlogs is passed by env referring the secret
+	if target.Codec.IsEncrypted() && target.Encryption != nil {
+		podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, corev1.EnvVar{
+			Name:      xstoreconvention.EnvBackupEncryptionKey,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: target.Encryption.KeySelector()},
+		})
+	}
+
+	labels := newJobLabels(polardbx)
+	labels[xstoremeta.JobLabelTargetPod] = leaderPod.Name
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      NewJobName(target.XStore.Name),
+			Namespace: polardbx.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: pointer.Int32(0),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: newJobLabels(polardbx),
+				},
+				Spec: *podSpec,
+			},
+		},
+	}
+}
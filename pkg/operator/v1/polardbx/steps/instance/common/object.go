@@ -24,14 +24,18 @@ import (
 	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
 	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
 	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
+	"github.com/alibaba/polardbx-operator/pkg/meta/core/gms"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/factory"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/helper"
 	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
 	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
 	polarxPath "github.com/alibaba/polardbx-operator/pkg/util/path"
 	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sort"
+	"strings"
 )
 
 var PersistentStatus = polardbxv1reconcile.NewStepBinder("PersistentStatus",
@@ -135,11 +139,16 @@ func downloadMetadataBackup(rc *polardbxv1reconcile.Context) (*factory.MetadataB
 	filestreamClient.InitWaitChan()
 	filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(polardbx.Spec.Restore.StorageProvider.StorageName)
 
+	// metadata is uploaded with the server-side encryption of backup, which is requested on download as well
+	sseAlgorithm, sseKMSKeyId := polardbx.Spec.Restore.StorageProvider.GetServerSideEncryption()
 	downloadActionMetadata := filestream.ActionMetadata{
-		Action:    filestreamAction.Download,
-		Sink:      polardbx.Spec.Restore.StorageProvider.Sink,
-		RequestId: uuid.New().String(),
-		Filename:  polarxPath.NewPathFromStringSequence(polardbx.Spec.Restore.From.BackupSetPath, "metadata"),
+		Action:       filestreamAction.Download,
+		Sink:         polardbx.Spec.Restore.StorageProvider.Sink,
+		RequestId:    uuid.New().String(),
+		Filename:     polarxPath.NewPathFromStringSequence(polardbx.Spec.Restore.From.BackupSetPath, "metadata"),
+		EndpointType: string(polardbx.Spec.Restore.StorageProvider.EndpointType),
+		SSEAlgorithm: sseAlgorithm,
+		SSEKMSKeyId:  sseKMSKeyId,
 	}
 	var downloadBuffer bytes.Buffer
 	recvBytes, err := filestreamClient.Download(&downloadBuffer, downloadActionMetadata)
@@ -260,6 +269,43 @@ var SyncSpecFromBackupSet = polardbxv1reconcile.NewStepBinder("SyncSpecFromBacku
 				"pxb", polardbx.Spec.Restore.BackupSet, "error", err)
 		}
 
+		// refuse to restore the whole cluster from backup set of part of xstores
+		if pxcBackup.Status.Partial {
+			helper.TransferPhase(polardbx, polardbxv1polardbx.PhaseFailed)
+			polardbx.Status.Message = "backup set " + pxcBackup.Name + " is partial, only xstores " +
+				strings.Join(pxcBackup.Status.XStores, ",") + " are backed up"
+			return flow.Error(errors.New("partial backup set"), "Unable to restore from partial backup set",
+				"pxb", pxcBackup.Name)
+		}
+
+		// refuse to restore the whole cluster from backup set of part of databases
+		if pxcBackup.IsDatabaseFiltered() {
+			helper.TransferPhase(polardbx, polardbxv1polardbx.PhaseFailed)
+			polardbx.Status.Message = "backup set " + pxcBackup.Name + " is partial, databases included: [" +
+				strings.Join(pxcBackup.Spec.IncludeDatabases, ",") + "], excluded: [" +
+				strings.Join(pxcBackup.Spec.ExcludeDatabases, ",") + "]"
+			return flow.Error(errors.New("partial backup set"), "Unable to restore from backup set of filtered databases",
+				"pxb", pxcBackup.Name)
+		}
+
+		// snapshot-only backup set has no binlog to recover to a point in time
+		if pxcBackup.IsSnapshotOnly() && polardbx.Spec.Restore.Time != "" {
+			helper.TransferPhase(polardbx, polardbxv1polardbx.PhaseFailed)
+			polardbx.Status.Message = "backup set " + pxcBackup.Name + " is snapshot-only, restore time " +
+				polardbx.Spec.Restore.Time + " is not supported"
+			return flow.Error(errors.New("snapshot-only backup set"), "Unable to restore to a point in time from snapshot-only backup set",
+				"pxb", pxcBackup.Name, "time", polardbx.Spec.Restore.Time)
+		}
+
+		// refuse to restore GMS of schema versions newer than supported, which fails in cryptic ways later
+		if unsupported := gms.UnsupportedSchemaVersions(pxcBackup.Status.GMSSchemaVersions); len(unsupported) > 0 &&
+			polardbx.Annotations[polardbxmeta.AnnotationSkipGMSSchemaCheck] != "true" {
+			helper.TransferPhase(polardbx, polardbxv1polardbx.PhaseFailed)
+			polardbx.Status.Message = GMSSchemaIncompatibleMessage(pxcBackup.Name, unsupported)
+			return flow.Error(errors.New("incompatible gms schema"), "Unable to restore from backup set of newer gms schema",
+				"pxb", pxcBackup.Name, "tables", unsupported)
+		}
+
 		if polardbx.Spec.Restore.SyncSpecWithOriginalCluster {
 			restoreSpec := polardbx.Spec.Restore.DeepCopy()
 			serviceName := polardbx.Spec.ServiceName
@@ -271,6 +317,25 @@ var SyncSpecFromBackupSet = polardbxv1reconcile.NewStepBinder("SyncSpecFromBacku
 		} else {
 			// ensure that restored cluster have the same dn replicas with original cluster
 			polardbx.Spec.Topology.Nodes.DN.Replicas = pxcBackup.Status.ClusterSpecSnapshot.Topology.Nodes.DN.Replicas
+
+			// warn if the restored cluster runs on images different from the original cluster
+			if mismatched := mismatchedImages(pxcBackup.Status.Images,
+				factory.ClusterImages(&polardbx.Spec, rc.Config().Images())); len(mismatched) > 0 {
+				message := "restore from backup set " + pxcBackup.Name + " of version " + pxcBackup.Status.PolarDBXVersion +
+					" with different images of " + strings.Join(mismatched, ",")
+				rc.RecordEvent(polardbx, corev1.EventTypeWarning, "CrossVersionRestore", message)
+				flow.Logger().Info("Cross version restore detected", "pxb", pxcBackup.Name,
+					"version", pxcBackup.Status.PolarDBXVersion, "components", mismatched)
+			}
+		}
+
+		// refuse to restore with name mapping of xstores not matching the backup set, or restored xstores conflict
+		if len(polardbx.Spec.Restore.XStoreNameMapping) > 0 {
+			if _, err := factory.ResolveXStoreNameMapping(polardbx, pxcBackup.Status.XStores); err != nil {
+				helper.TransferPhase(polardbx, polardbxv1polardbx.PhaseFailed)
+				polardbx.Status.Message = "invalid xstore name mapping: " + err.Error()
+				return flow.Error(err, "Invalid xstore name mapping", "pxb", pxcBackup.Name)
+			}
 		}
 
 		rc.MarkPolarDBXChanged()
@@ -278,6 +343,26 @@ var SyncSpecFromBackupSet = polardbxv1reconcile.NewStepBinder("SyncSpecFromBacku
 	},
 )
 
+// mismatchedImages returns sorted components whose image recorded in backup differs from the current one,
+// components absent from either side are ignored.
+func mismatchedImages(backupImages, currentImages map[string]string) []string {
+	mismatched := make([]string, 0)
+	for component, image := range backupImages {
+		if current, ok := currentImages[component]; ok && current != image {
+			mismatched = append(mismatched, component)
+		}
+	}
+	sort.Strings(mismatched)
+	return mismatched
+}
+
+// GMSSchemaIncompatibleMessage returns the guidance when GMS of backup set is newer than supported.
+func GMSSchemaIncompatibleMessage(backupSet string, unsupported []string) string {
+	return "gms schema of backup set " + backupSet + " is newer than supported (" + strings.Join(unsupported, ", ") +
+		"), upgrade the operator before restore, or annotate the cluster with " +
+		polardbxmeta.AnnotationSkipGMSSchemaCheck + "=true to restore anyway"
+}
+
 var CleanDummyBackupObject = polardbxv1reconcile.NewStepBinder("CleanDummyBackupObject",
 	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		polardbx := rc.MustGetPolarDBX()
@@ -0,0 +1,271 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	hpfs "github.com/alibaba/polardbx-operator/pkg/hpfs/proto"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	"github.com/alibaba/polardbx-operator/pkg/meta/core/gms"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/factory"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	polardbxv1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+	"github.com/alibaba/polardbx-operator/pkg/pitr/exportbackupset"
+)
+
+// resolveRestoreSource locates the backup set to restore from without touching spec. The pxc backup is nil if
+// restored from backup set path.
+func resolveRestoreSource(rc *polardbxv1reconcile.Context) (*polardbxv1.PolarDBXBackup,
+	*polardbxv1polardbx.BackupStorageProvider, string, error) {
+	restore := rc.MustGetPolarDBX().Spec.Restore
+	var pxcBackup *polardbxv1.PolarDBXBackup
+	var err error
+	switch {
+	case restore.BackupSet != "":
+		pxcBackup, err = rc.GetPXCBackupByName(restore.BackupSet)
+	case restore.From.BackupSetPath != "":
+		if restore.StorageProvider == nil {
+			return nil, nil, "", errors.New("storage provider is required to restore from backup set path")
+		}
+		return nil, restore.StorageProvider, restore.From.BackupSetPath, nil
+	default:
+		restoreTime, parseErr := rc.ParseRestoreTime()
+		if parseErr != nil {
+			return nil, nil, "", parseErr
+		}
+		pxcBackup, err = rc.GetLastCompletedPXCBackup(
+			map[string]string{polardbxmeta.LabelName: restore.From.PolarBDXName}, restoreTime)
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if pxcBackup == nil {
+		return nil, nil, "", errors.New("backup set not found")
+	}
+	return pxcBackup, &pxcBackup.Spec.StorageProvider, pxcBackup.Status.BackupRootPath, nil
+}
+
+// checkRestoreCompatibility returns problems which fail the restore from backup set described by metadata, and
+// notes which don't. pxcBackup is nil if restored from backup set path.
+func checkRestoreCompatibility(polardbx *polardbxv1.PolarDBXCluster, pxcBackup *polardbxv1.PolarDBXBackup,
+	metadata *factory.MetadataBackup, currentImages map[string]string) (problems []string, notes []string) {
+	restore := polardbx.Spec.Restore
+	if pxcBackup != nil && pxcBackup.Status.Partial {
+		problems = append(problems, "backup set is partial, only xstores "+
+			strings.Join(pxcBackup.Status.XStores, ",")+" are backed up")
+	}
+	if metadata.IsDatabaseFiltered() {
+		problems = append(problems, "backup set is partial, only part of databases are backed up")
+	}
+	if metadata.BackupMode == polardbxv1polardbx.BackupModeSnapshot && restore.Time != "" {
+		problems = append(problems, "backup set is snapshot-only, restore time "+restore.Time+" is not supported")
+	}
+	if unsupported := gms.UnsupportedSchemaVersions(metadata.GMSSchemaVersions); len(unsupported) > 0 &&
+		polardbx.Annotations[polardbxmeta.AnnotationSkipGMSSchemaCheck] != "true" {
+		problems = append(problems, GMSSchemaIncompatibleMessage(metadata.BackupSetName, unsupported))
+	}
+	if len(restore.XStoreNameMapping) > 0 {
+		if _, err := factory.ResolveXStoreNameMapping(polardbx, metadata.GetXstoreNameList()); err != nil {
+			problems = append(problems, "invalid xstore name mapping: "+err.Error())
+		}
+	}
+	if providers := metadata.GetXStoreStorageProviders(); len(providers) > 0 {
+		xstores := make([]string, 0, len(providers))
+		for xstore := range providers {
+			xstores = append(xstores, xstore)
+		}
+		sort.Strings(xstores)
+		notes = append(notes, "objects of xstores "+strings.Join(xstores, ",")+" in overridden sinks are unchecked")
+	}
+	if !restore.SyncSpecWithOriginalCluster {
+		if mismatched := mismatchedImages(metadata.Images, currentImages); len(mismatched) > 0 {
+			notes = append(notes, "different images of "+strings.Join(mismatched, ",")+" from version "+
+				metadata.PolarDBXVersion)
+		}
+	}
+	return problems, notes
+}
+
+// checkRestoreFreeSpace returns the problem if no node has free space for the largest full backup of xstores,
+// nothing is returned if sizes of full backups are unknown.
+func checkRestoreFreeSpace(freeSpaces map[string]uint64, fullBackupSizes map[string]int64) string {
+	largestXStore, largestSize := "", int64(0)
+	for xstore, size := range fullBackupSizes {
+		if size > largestSize || (size == largestSize && xstore < largestXStore) {
+			largestXStore, largestSize = xstore, size
+		}
+	}
+	if largestSize == 0 {
+		return ""
+	}
+	for _, free := range freeSpaces {
+		if free >= uint64(largestSize) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("no node has free space for full backup of %s of %d bytes", largestXStore, largestSize)
+}
+
+// getNodeFreeSpaces returns free space of data volume root of schedulable nodes. Nodes unreachable by hpfs
+// are skipped.
+func getNodeFreeSpaces(rc *polardbxv1reconcile.Context) (map[string]uint64, error) {
+	var nodeList corev1.NodeList
+	if err := rc.Client().List(rc.Context(), &nodeList); err != nil {
+		return nil, err
+	}
+	hpfsClient, err := rc.GetHpfsClient()
+	if err != nil {
+		return nil, err
+	}
+	freeSpaces := make(map[string]uint64)
+	for _, node := range nodeList.Items {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		resp, err := hpfsClient.ShowDiskInfo(rc.Context(), &hpfs.ShowDiskInfoRequest{
+			Host: &hpfs.Host{NodeName: node.Name},
+			Path: rc.Config().Store().HostPathDataVolumeRoot(),
+		})
+		if err != nil || resp.Status.Code != hpfs.Status_OK {
+			continue
+		}
+		freeSpaces[node.Name] = resp.Info.Free
+	}
+	return freeSpaces, nil
+}
+
+// getFullBackupSizes returns sizes of full backups of xstores in backup set, keyed by xstore.
+func getFullBackupSizes(rc *polardbxv1reconcile.Context, pxcBackup *polardbxv1.PolarDBXBackup) (map[string]int64, error) {
+	var xstoreBackupList polardbxv1.XStoreBackupList
+	err := rc.Client().List(rc.Context(), &xstoreBackupList, client.InNamespace(rc.Namespace()),
+		client.MatchingLabels{polardbxmeta.LabelTopBackup: pxcBackup.Name})
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64, len(xstoreBackupList.Items))
+	for _, xstoreBackup := range xstoreBackupList.Items {
+		sizes[xstoreBackup.Spec.XStore.Name] = xstoreBackup.Status.FullBackupSize
+	}
+	return sizes, nil
+}
+
+// setRestoreReadyCondition adds or replaces the condition of restore readiness, keeping the transition time if
+// status unchanged. It tells whether status is changed.
+func setRestoreReadyCondition(polardbx *polardbxv1.PolarDBXCluster, cond polardbxv1polardbx.Condition) bool {
+	cond.Type = polardbxv1polardbx.RestoreReady
+	cond.LastTransitionTime = metav1.Now()
+	for i := range polardbx.Status.Conditions {
+		c := &polardbx.Status.Conditions[i]
+		if c.Type == cond.Type {
+			changed := c.Status != cond.Status
+			if !changed {
+				cond.LastTransitionTime = c.LastTransitionTime
+			}
+			*c = cond
+			return changed
+		}
+	}
+	polardbx.Status.Conditions = append(polardbx.Status.Conditions, cond)
+	return true
+}
+
+// DryRunRestore validates the restore without applying it, readiness is reported in condition RestoreReady and
+// the cluster stays pending without anything created.
+var DryRunRestore = polardbxv1reconcile.NewStepBinder("DryRunRestore",
+	func(rc *polardbxv1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		polardbx := rc.MustGetPolarDBX()
+
+		var problems, notes []string
+		pxcBackup, storageProvider, rootPath, err := resolveRestoreSource(rc)
+		if err != nil {
+			problems = append(problems, "unable to locate backup set: "+err.Error())
+		} else {
+			filestreamClient, err := rc.GetFilestreamClient()
+			if err != nil {
+				return flow.RetryAfter(10*time.Second, "Failed to get filestream client, error: "+err.Error())
+			}
+			storage, err := exportbackupset.NewBackupSetStorage(filestreamClient, storageProvider.StorageName,
+				storageProvider.Sink)
+			if err != nil {
+				return flow.Error(err, "Unsupported storage provided.")
+			}
+			metadata, err := storage.DownloadMetadata(rootPath)
+			if err != nil {
+				problems = append(problems, err.Error())
+			} else {
+				objectProblems, err := exportbackupset.CheckObjects(storage, rootPath, metadata)
+				if err != nil {
+					return flow.RetryAfter(10*time.Second, "Failed to check objects of backup set, error: "+err.Error())
+				}
+				problems = append(problems, objectProblems...)
+				compatProblems, compatNotes := checkRestoreCompatibility(polardbx, pxcBackup, metadata,
+					factory.ClusterImages(&polardbx.Spec, rc.Config().Images()))
+				problems = append(problems, compatProblems...)
+				notes = append(notes, compatNotes...)
+			}
+		}
+		if pxcBackup != nil {
+			fullBackupSizes, err := getFullBackupSizes(rc, pxcBackup)
+			if err != nil {
+				return flow.Error(err, "Unable to list xstore backups.", "pxb", pxcBackup.Name)
+			}
+			freeSpaces, err := getNodeFreeSpaces(rc)
+			if err != nil {
+				return flow.RetryAfter(10*time.Second, "Failed to get free space of nodes, error: "+err.Error())
+			}
+			if problem := checkRestoreFreeSpace(freeSpaces, fullBackupSizes); problem != "" {
+				problems = append(problems, problem)
+			}
+		} else {
+			notes = append(notes, "free space unchecked as sizes of full backups are unknown")
+		}
+
+		cond := polardbxv1polardbx.Condition{
+			Status:  corev1.ConditionTrue,
+			Reason:  "BackupSetValid",
+			Message: "backup set is ready to restore",
+		}
+		eventType := corev1.EventTypeNormal
+		if len(problems) > 0 {
+			cond.Status = corev1.ConditionFalse
+			cond.Reason = "BackupSetInvalid"
+			cond.Message = strings.Join(problems, "; ")
+			eventType = corev1.EventTypeWarning
+		}
+		if len(notes) > 0 {
+			cond.Message += ", note: " + strings.Join(notes, "; ")
+		}
+		polardbx.Status.Message = "restore dry-run: " + cond.Message
+		if setRestoreReadyCondition(polardbx, cond) {
+			rc.RecordEvent(polardbx, eventType, "RestoreDryRun", cond.Message)
+		}
+		return flow.Wait("Restore dry-run finished, turn off dry-run to restore.", "ready", cond.Status,
+			"problems", len(problems))
+	},
+)
@@ -0,0 +1,52 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/factory"
+)
+
+func TestCheckRestoreFreeSpace(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	sizes := map[string]int64{"gms": 10, "dn-0": 100, "dn-1": 100}
+	g.Expect(checkRestoreFreeSpace(map[string]uint64{"node-1": 50, "node-2": 100}, sizes)).To(gomega.BeEmpty())
+	g.Expect(checkRestoreFreeSpace(map[string]uint64{"node-1": 50}, sizes)).To(
+		gomega.Equal("no node has free space for full backup of dn-0 of 100 bytes"))
+	g.Expect(checkRestoreFreeSpace(nil, map[string]int64{"dn-0": 0})).To(gomega.BeEmpty())
+}
+
+func TestCheckRestoreCompatibility(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	polardbx := &polardbxv1.PolarDBXCluster{}
+	polardbx.Spec.Restore = &polardbxv1polardbx.RestoreSpec{Time: "2026-01-01T00:00:00Z"}
+	metadata := &factory.MetadataBackup{
+		BackupSetName:   "pxc-backup",
+		BackupMode:      polardbxv1polardbx.BackupModeSnapshot,
+		PolarDBXVersion: "5.4.19",
+		Images:          map[string]string{"cn": "cn:v1", "dn": "dn:v1"},
+	}
+	problems, notes := checkRestoreCompatibility(polardbx, nil, metadata, map[string]string{"cn": "cn:v2", "dn": "dn:v1"})
+	g.Expect(problems).To(gomega.Equal([]string{
+		"backup set is snapshot-only, restore time 2026-01-01T00:00:00Z is not supported"}))
+	g.Expect(notes).To(gomega.Equal([]string{"different images of cn from version 5.4.19"}))
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package gms
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/alibaba/polardbx-operator/pkg/util/network"
@@ -25,6 +26,7 @@ import (
 	"strings"
 	"time"
 
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -34,8 +36,10 @@ import (
 	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
 	"github.com/alibaba/polardbx-operator/pkg/meta/core/gms"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/convention"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/helper"
 	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
 	polardbxreconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/reconcile"
+	commonsteps "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/steps/instance/common"
 	xstoreconvention "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
 	dictutil "github.com/alibaba/polardbx-operator/pkg/util/dict"
 )
@@ -99,20 +103,17 @@ func getHashFromXstoreName(xstoreNames interface{}) (string, error) {
 	return "", errors.New("failed to get hash from name of xstore")
 }
 
-// getOriginalPxcInfo is a helper function to extract hash and name of original pxc during restore
-func getOriginalPxcInfo(rc *polardbxreconcile.Context) (string, string, map[string]string, error) {
+// getRestoreBackup is a helper function to get the backup which current pxc restores from
+func getRestoreBackup(rc *polardbxreconcile.Context) (*polardbxv1.PolarDBXBackup, error) {
 	polardbx := rc.MustGetPolarDBX()
-	backup := &polardbxv1.PolarDBXBackup{}
-	var err error
 	if polardbx.Spec.Restore.BackupSet == "" && len(polardbx.Spec.Restore.BackupSet) == 0 {
-		backup, err = rc.GetCompletedPXCBackup(map[string]string{polardbxmeta.LabelName: polardbx.Spec.Restore.From.PolarBDXName})
-	} else {
-		backup, err = rc.GetPXCBackupByName(polardbx.Spec.Restore.BackupSet)
-	}
-	if err != nil {
-		return "", "", nil, err
+		return rc.GetCompletedPXCBackup(map[string]string{polardbxmeta.LabelName: polardbx.Spec.Restore.From.PolarBDXName})
 	}
+	return rc.GetPXCBackupByName(polardbx.Spec.Restore.BackupSet)
+}
 
+// getOriginalPxcInfo is a helper function to extract hash and name of original pxc during restore
+func getOriginalPxcInfo(rc *polardbxreconcile.Context, backup *polardbxv1.PolarDBXBackup) (string, string, map[string]string, error) {
 	var pxcHash, pxcName string
 	if backup != nil {
 		pxcName = backup.Spec.Cluster.Name
@@ -143,7 +144,11 @@ func getOriginalPxcInfo(rc *polardbxreconcile.Context) (string, string, map[stri
 var RestoreSchemas = polardbxreconcile.NewStepBinder("RestoreSchemas",
 	func(rc *polardbxreconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		polarDBX := rc.MustGetPolarDBX()
-		originalPXCHash, originalPXCName, originalDnNameMap, err := getOriginalPxcInfo(rc)
+		backup, err := getRestoreBackup(rc)
+		if err != nil {
+			return flow.Error(err, "Unable to get backup to restore from")
+		}
+		originalPXCHash, originalPXCName, originalDnNameMap, err := getOriginalPxcInfo(rc, backup)
 		if err != nil {
 			return flow.Error(err, "Get oldXStoreName Failed")
 		}
@@ -158,6 +163,20 @@ var RestoreSchemas = polardbxreconcile.NewStepBinder("RestoreSchemas",
 		}
 
 		if !restored {
+			// check versions of restored GMS as well, which are not recorded in backup sets of previous versions
+			if polarDBX.Annotations[polardbxmeta.AnnotationSkipGMSSchemaCheck] != "true" {
+				versions, err := mgr.GetSchemaVersions()
+				if err != nil {
+					return flow.Error(err, "Unable to get GMS schema versions.")
+				}
+				if unsupported := gms.UnsupportedSchemaVersions(versions); len(unsupported) > 0 {
+					helper.TransferPhase(polarDBX, polardbxv1polardbx.PhaseFailed)
+					polarDBX.Status.Message = commonsteps.GMSSchemaIncompatibleMessage(backup.Name, unsupported)
+					return flow.Error(errors.New("incompatible gms schema"), "Unable to restore GMS schemas of newer version.",
+						"tables", unsupported)
+				}
+			}
+
 			err = mgr.RestoreSchemas(originalPXCName, originalPXCHash, polarDBX.Status.Rand, originalDnNameMap)
 			if err != nil {
 				return flow.Error(err, "Unable to restore GMS schemas.")
@@ -231,6 +250,45 @@ var CreateAccounts = polardbxreconcile.NewStepBinder("CreateAccounts",
 	},
 )
 
+// SyncRestoredAccounts makes accounts in GMS consistent with the account secret after restore. Passwords of accounts
+// not restored from backup, i.e. differing from the backup secret, are synced into GMS. Root account is mandatory
+// whatever the account restore policy is.
+var SyncRestoredAccounts = polardbxreconcile.NewStepBinder("SyncRestoredAccounts",
+	func(rc *polardbxreconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		polardbx := rc.MustGetPolarDBX()
+		if polardbx.Spec.Restore == nil {
+			return flow.Pass()
+		}
+
+		accountSecret, err := rc.GetPolarDBXSecret(convention.SecretTypeAccount)
+		if err != nil {
+			return flow.Error(err, "Unable to get account secret.")
+		}
+		if _, ok := accountSecret.Data[convention.RootAccount]; !ok {
+			return flow.Error(errors.New("root account not found"), "Mandatory account missing after restore.",
+				"username", convention.RootAccount)
+		}
+		backupSecret, err := rc.GetPolarDBXSecretForRestore()
+		if client.IgnoreNotFound(err) != nil {
+			return flow.Error(err, "Unable to get account secret of backup.")
+		}
+
+		mgr, err := rc.GetPolarDBXGMSManager()
+		if err != nil {
+			return flow.Error(err, "Unable to get GMS manager.")
+		}
+		for user, passwd := range accountSecret.Data {
+			if backupSecret != nil && bytes.Equal(backupSecret.Data[user], passwd) {
+				continue
+			}
+			if err := mgr.SyncAccountPasswd(user, string(passwd)); err != nil {
+				return flow.Error(err, "Unable to sync account password.", "username", user)
+			}
+		}
+		return flow.Continue("Restored accounts synced.")
+	},
+)
+
 func SyncDynamicConfigs(force bool) control.BindFunc {
 	stepName := "SyncDynamicConfigs"
 	if force {
@@ -339,6 +339,35 @@ func (b *commandEngineBuilder) Version() *CommandBuilder {
 	return b.end()
 }
 
+// Checksum prints the checksum of each table, in the form of "schema.table", per line as "schema.table\tchecksum".
+// Checksum of missing table is printed as NULL.
+func (b *commandEngineBuilder) Checksum(tables ...string) *CommandBuilder {
+	b.args = append(b.args, "checksum")
+	for _, table := range tables {
+		b.args = append(b.args, "--table", table)
+	}
+	return b.end()
+}
+
+// ChecksumSample prints the checksum of rows sampled by the hash of primary key seeded by seed of each table, in
+// the form of "schema.table", per line as "schema.table\tchecksum\tsampledRows". Checksum of missing table is
+// printed as NULL.
+func (b *commandEngineBuilder) ChecksumSample(percent int32, seed int64, tables ...string) *CommandBuilder {
+	b.args = append(b.args, "checksum", "--sample-percent", strconv.Itoa(int(percent)),
+		"--sample-seed", strconv.FormatInt(seed, 10))
+	for _, table := range tables {
+		b.args = append(b.args, "--table", table)
+	}
+	return b.end()
+}
+
+// Health prints the health of engine as "healthy\tuptime", or "unhealthy\tuptime\treason" if the engine is
+// recovering from crash.
+func (b *commandEngineBuilder) Health() *CommandBuilder {
+	b.args = append(b.args, "health")
+	return b.end()
+}
+
 func (b *commandEngineBuilder) Shutdown() *CommandBuilder {
 	b.args = append(b.args, "shutdown")
 	return b.end()
@@ -378,8 +407,14 @@ func (b *nullCommandBuilder) Backup() *commandBackupBuilder {
 	}
 }
 
-func (b *commandBackupBuilder) StartBackup(backupContext, jobName string) *CommandBuilder {
+func (b *commandBackupBuilder) StartBackup(backupContext, jobName string, includeDatabases, excludeDatabases []string) *CommandBuilder {
 	b.args = append(b.args, "start", "--backup_context", backupContext, "-j", jobName)
+	for _, db := range includeDatabases {
+		b.args = append(b.args, "--include_database", db)
+	}
+	for _, db := range excludeDatabases {
+		b.args = append(b.args, "--exclude_database", db)
+	}
 	return b.end()
 }
 
@@ -400,6 +435,17 @@ func (b *commandRestoreBuilder) StartRestore(restoreContext string) *CommandBuil
 	return b.end()
 }
 
+func (b *commandRestoreBuilder) ApplyBinlog(binlogDir, storageName, sink string, startIndex, endIndex int64, targetPod, pwd string,
+	compression, encryption string) *CommandBuilder {
+	b.args = append(b.args, "apply_binlog", "--binlog_dir", binlogDir, "--storage_name", storageName, "--sink", sink,
+		"--start_index", strconv.FormatInt(startIndex, 10), "--end_index", strconv.FormatInt(endIndex, 10),
+		"-tp", targetPod, "-p", pwd, "--compression", compression)
+	if encryption != "" {
+		b.args = append(b.args, "--encryption", encryption)
+	}
+	return b.end()
+}
+
 type commandRecoverBuilder struct {
 	*commandBuilder
 }
@@ -19,6 +19,7 @@ package command
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/go-logr/logr"
@@ -105,3 +106,72 @@ func ParseCommandResultGenerally(commandResult string) ([]map[string]interface{}
 	}
 	return result, nil
 }
+
+// ParseTableChecksums parses the output of checksum command into checksums keyed by table, missing tables
+// are reported as error.
+func ParseTableChecksums(output string) (map[string]string, error) {
+	checksums := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		table, checksum, ok := strings.Cut(line, "\t")
+		if !ok {
+			return nil, fmt.Errorf("invalid checksum output: %s", line)
+		}
+		if checksum == "NULL" {
+			return nil, fmt.Errorf("table not found: %s", table)
+		}
+		checksums[table] = checksum
+	}
+	return checksums, nil
+}
+
+// EngineHealth is the health of engine reported by health command.
+type EngineHealth struct {
+	Healthy bool
+	Uptime  int64
+	Reason  string
+}
+
+// ParseEngineHealth parses the output of health command.
+func ParseEngineHealth(output string) (*EngineHealth, error) {
+	fields := strings.Split(strings.TrimSpace(output), "\t")
+	if len(fields) < 2 || (fields[0] != "healthy" && fields[0] != "unhealthy") {
+		return nil, fmt.Errorf("invalid health output: %s", output)
+	}
+	uptime, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health output: %s", output)
+	}
+	health := &EngineHealth{Healthy: fields[0] == "healthy", Uptime: uptime}
+	if len(fields) > 2 {
+		health.Reason = fields[2]
+	}
+	return health, nil
+}
+
+// ParseSampledTableChecksums parses the output of sampled checksum command into checksums and counts of rows
+// sampled keyed by table, missing tables are reported as error.
+func ParseSampledTableChecksums(output string) (map[string]string, map[string]int64, error) {
+	checksums, sampledRows := make(map[string]string), make(map[string]int64)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, nil, fmt.Errorf("invalid checksum output: %s", line)
+		}
+		if fields[1] == "NULL" {
+			return nil, nil, fmt.Errorf("table not found: %s", fields[0])
+		}
+		rows, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid checksum output: %s", line)
+		}
+		checksums[fields[0]] = fields[1]
+		sampledRows[fields[0]] = rows
+	}
+	return checksums, sampledRows, nil
+}
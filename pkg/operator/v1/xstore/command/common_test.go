@@ -0,0 +1,62 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestParseTableChecksums(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	checksums, err := ParseTableChecksums("db.t1\t123\ndb.t2\t0\n")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(checksums).To(gomega.Equal(map[string]string{"db.t1": "123", "db.t2": "0"}))
+
+	_, err = ParseTableChecksums("db.t1\t123\ndb.t3\tNULL\n")
+	g.Expect(err).To(gomega.MatchError("table not found: db.t3"))
+	_, err = ParseTableChecksums("Traceback (most recent call last):\n")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestParseSampledTableChecksums(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	checksums, sampledRows, err := ParseSampledTableChecksums("db.t1\t10:123\t10\ndb.t2\t0:0\t0\n")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(checksums).To(gomega.Equal(map[string]string{"db.t1": "10:123", "db.t2": "0:0"}))
+	g.Expect(sampledRows).To(gomega.Equal(map[string]int64{"db.t1": 10, "db.t2": 0}))
+
+	_, _, err = ParseSampledTableChecksums("db.t1\t10:123\t10\ndb.t3\tNULL\t0\n")
+	g.Expect(err).To(gomega.MatchError("table not found: db.t3"))
+	_, _, err = ParseSampledTableChecksums("db.t1\t123\n")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestParseEngineHealth(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	health, err := ParseEngineHealth("healthy\t3600\n")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(health).To(gomega.Equal(&EngineHealth{Healthy: true, Uptime: 3600}))
+
+	health, err = ParseEngineHealth("unhealthy\t10\tcrash recovery in progress\n")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(health).To(gomega.Equal(&EngineHealth{Uptime: 10, Reason: "crash recovery in progress"}))
+
+	_, err = ParseEngineHealth("Traceback (most recent call last):\n")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
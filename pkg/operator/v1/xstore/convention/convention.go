@@ -17,11 +17,14 @@ limitations under the License.
 package convention
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/alibaba/polardbx-operator/pkg/util/name"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -215,6 +218,16 @@ const (
 	KeyringFile        = "keyring_file_data"
 )
 
+// Envs of backup and restore jobs carrying the keys of keyring re-encryption.
+const (
+	EnvKeyringSourceKey = "KEYRING_SOURCE_KEY"
+	EnvKeyringTargetKey = "KEYRING_TARGET_KEY"
+)
+
+// EnvBackupEncryptionKey is the env of backup and restore jobs carrying the key which files of backup set are
+// encrypted with.
+const EnvBackupEncryptionKey = "BACKUP_ENCRYPTION_KEY"
+
 func NewConfigMapName(xstore *polardbxv1.XStore, cmType ConfigMapType) string {
 	if xstore.Status.Rand != "" {
 		return fmt.Sprintf("%s-%s-%s", xstore.Name, xstore.Status.Rand, cmType)
@@ -297,6 +310,30 @@ func NewBackupJobName(targetPod *corev1.Pod, jobType BackupJobType) string {
 	)
 }
 
+// IsBackupJobOfType checks whether the job name follows the naming convention of backup job of given type,
+// which holds for both the spliced name and the abbreviated one.
+func IsBackupJobOfType(jobName string, jobType BackupJobType) bool {
+	return strings.HasPrefix(jobName, fmt.Sprintf("%s-job-", jobType))
+}
+
+// NewSharedBackupSecretName returns name of the backup secret shared by backups of xstore, which is derived from
+// the accounts so that backups share the secret only if accounts are the same.
+func NewSharedBackupSecretName(xstoreName string, accounts map[string][]byte) string {
+	users := make([]string, 0, len(accounts))
+	for user := range accounts {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	hash := sha256.New()
+	for _, user := range users {
+		hash.Write([]byte(user))
+		hash.Write([]byte{0})
+		hash.Write(accounts[user])
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf("%s-backup-secret-%s", xstoreName, hex.EncodeToString(hash.Sum(nil))[:10])
+}
+
 type BackupConfigMapKeyType string
 
 const (
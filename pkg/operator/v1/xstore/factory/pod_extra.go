@@ -193,11 +193,12 @@ func (f *DefaultExtraPodFactory) newLogVolumeName(i int, skipSequence bool) stri
 	return "data-log" + strconv.Itoa(i)
 }
 
-func (f *DefaultExtraPodFactory) newDataVolumeMountPath(i int, skipSequence bool) string {
+func (f *DefaultExtraPodFactory) newDataVolumeMountPath(ctx *PodFactoryContext, i int, skipSequence bool) string {
+	dataDir := ctx.rc.Config().Store().EngineDataDir()
 	if skipSequence {
-		return "/data/mysql"
+		return dataDir
 	}
-	return path.Join("/data/mysql", strconv.Itoa(i))
+	return path.Join(dataDir, strconv.Itoa(i))
 }
 
 func (f *DefaultExtraPodFactory) newLogVolumeMountPath(i int, skipSequence bool) string {
@@ -275,7 +276,7 @@ func (f *DefaultExtraPodFactory) NewVolumeMounts(ctx *PodFactoryContext) (map[st
 	for i := range ctx.volumes {
 		mounts = append(mounts, corev1.VolumeMount{
 			Name:             f.newDataVolumeName(i, skipSequence),
-			MountPath:        f.newDataVolumeMountPath(i, skipSequence),
+			MountPath:        f.newDataVolumeMountPath(ctx, i, skipSequence),
 			MountPropagation: k8shelper.MountPropagationModePtr(corev1.MountPropagationHostToContainer),
 		})
 		mounts = append(mounts, corev1.VolumeMount{
@@ -79,9 +79,13 @@ func NewSecretForRestore(rc *reconcile.Context, xstore *polardbxv1.XStore) (*cor
 		if err != nil {
 			return nil, err
 		}
-		secretName = backup.Name
+		secretName = backup.GetSecretName()
 	} else {
 		secretName = xstore.Spec.Restore.BackupSet
+		backup, err := rc.GetXStoreBackupByName(xstore.Spec.Restore.BackupSet)
+		if err == nil && backup != nil {
+			secretName = backup.GetSecretName()
+		}
 	}
 	xsbSecret, err := rc.GetSecretByName(secretName)
 	if err != nil || xsbSecret == nil {
@@ -43,10 +43,31 @@ const (
 	AnnotationRebuildFromPod = "xstore/rebuild_from_pod"
 )
 
+// AnnotationTriggerBackup triggers an ad-hoc backup of standard xstore, value is "<storageName>:<sink>"
+// or empty to use sink policy of operator.
+const (
+	AnnotationTriggerBackup = "xstore/trigger-backup"
+)
+
 const (
 	AnnotationAdapting = "xstore/adapting"
 )
 
+// AnnotationLatestBackup records on xstore the name of its latest finished backup, which is resolved by restore
+// from backup set "latest".
+const (
+	AnnotationLatestBackup = "xstore/latest-backup"
+
+	// LatestBackupSet denotes the latest finished backup of source xstore as the backup set to restore from.
+	LatestBackupSet = "latest"
+)
+
+// AnnotationJobDeletePropagation overrides the propagation policy to delete jobs of xstore, either "Background"
+// or "Foreground". Operator config is used if absent.
+const (
+	AnnotationJobDeletePropagation = "xstore/job-delete-propagation"
+)
+
 func IsAdaptingTrue(val string) bool {
 	val = strings.ToLower(val)
 	return val == "1" || val == "on" || val == "true"
@@ -56,6 +77,25 @@ func IsAdaptingTrue(val string) bool {
 const (
 	// AnnotationCollectJobProbeLimit denotes retry limit of getting collect job when waiting collect job finished
 	AnnotationCollectJobProbeLimit = "xstore-backup/collect-job-probe-limit"
+
+	// AnnotationRerunBinlogBackup denotes to restart binlog backup phase, reusing the existing full backup. It
+	// applies to backup in binlog backup phase, or failed after full backup completed.
+	AnnotationRerunBinlogBackup = "xstore-backup/rerun-binlog-backup"
+
+	// AnnotationAbortFullBackup denotes to abort the full backup job, e.g. hung, and re-attempt full backup with
+	// a new job from scratch
+	AnnotationAbortFullBackup = "xstore-backup/abort-full-backup"
+
+	// AnnotationRefreshMetadata denotes to upload metadata of finished backup again from its current status,
+	// e.g. after status corrected manually
+	AnnotationRefreshMetadata = "xstore-backup/refresh-metadata"
+
+	// AnnotationProtectedBackup protects backup from retention deletion and its remote files from cleanup if "true"
+	AnnotationProtectedBackup = "xstore-backup/protected"
+
+	// AnnotationObjectTagLabels denotes comma separated keys of backup labels, which are attached to uploaded
+	// backup files as object tags
+	AnnotationObjectTagLabels = "xstore-backup/object-tag-labels"
 )
 
 const (
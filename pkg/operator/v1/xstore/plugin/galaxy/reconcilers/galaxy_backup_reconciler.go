@@ -19,6 +19,7 @@ package reconcilers
 import (
 	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
 	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
 	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
 	backupsteps "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/steps/backup"
 	"github.com/go-logr/logr"
@@ -60,20 +61,48 @@ func (r *GalaxyBackupReconciler) newReconcileTask(rc *xstorev1reconcile.BackupCo
 		backupsteps.UpdatePhaseTemplate(xstorev1.XStoreBackupDeleting, true),
 	)(task)
 
+	if xstoreBackup.Annotations[xstoremeta.AnnotationRerunBinlogBackup] == "true" &&
+		xstoreBackup.GetDeletionTimestamp().IsZero() {
+		backupsteps.PrepareBinlogBackupRerun(task)
+		return task, nil
+	}
+
+	if xstoreBackup.Annotations[xstoremeta.AnnotationAbortFullBackup] == "true" &&
+		xstoreBackup.GetDeletionTimestamp().IsZero() {
+		backupsteps.AbortFullBackupJob(task)
+		return task, nil
+	}
+
+	if xstoreBackup.Annotations[xstoremeta.AnnotationRefreshMetadata] == "true" &&
+		xstoreBackup.GetDeletionTimestamp().IsZero() {
+		backupsteps.RefreshXStoreMetadata(task)
+		return task, nil
+	}
+
+	backupsteps.FailBackupIfOverallTimeout(task)
+
 	switch xstoreBackup.Status.Phase {
 	case xstorev1.XStoreBackupNew:
 		backupsteps.AddFinalizer(task)
+		backupsteps.RepointBackupSink(task)
 		backupsteps.UpdateBackupStartInfo(task)
+		backupsteps.WaitXStoreStable(task)
 		backupsteps.CreateBackupConfigMap(task)
 		backupsteps.StartXStoreFullBackupJob(task)
 		backupsteps.UpdatePhaseTemplate(xstorev1.XStoreFullBackuping)(task)
 	case xstorev1.XStoreFullBackuping:
 		backupsteps.WaitFullBackupJobFinished(task)
-		control.Branch(isStandard,
+		backupsteps.RecordFullBackupSize(task)
+		backupsteps.CaptureTableChecksums(task)
+		// snapshot-only backup skips binlog collecting and backup
+		control.When(xstoreBackup.IsSnapshotOnly(), backupsteps.RecordSnapshotTimestamp)(task)
+		control.Branch(isStandard || xstoreBackup.IsSnapshotOnly(),
 			backupsteps.UpdatePhaseTemplate(xstorev1.XStoreBinlogWaiting),
 			backupsteps.UpdatePhaseTemplate(xstorev1.XStoreBackupCollecting))(task)
 	case xstorev1.XStoreBackupCollecting:
 		backupsteps.WaitBinlogOffsetCollected(task)
+		backupsteps.CheckBinlogNotPurged(task)
+		backupsteps.CheckBinlogContinuity(task)
 		backupsteps.StartCollectBinlogJob(task)
 		backupsteps.WaitCollectBinlogJobFinished(task)
 		backupsteps.UpdatePhaseTemplate(xstorev1.XStoreBinlogBackuping)(task)
@@ -81,27 +110,47 @@ func (r *GalaxyBackupReconciler) newReconcileTask(rc *xstorev1reconcile.BackupCo
 		backupsteps.WaitPXCSeekCpJobFinished(task)
 		backupsteps.StartBinlogBackupJob(task)
 		backupsteps.WaitBinlogBackupJobFinished(task)
+		backupsteps.RecordExcludedBinlogs(task)
 		backupsteps.UpdateBackupStatus(task)
 		backupsteps.ExtractLastEventTimestamp(task)
 		backupsteps.UpdatePhaseTemplate(xstorev1.XStoreBinlogWaiting)(task)
 	case xstorev1.XStoreBinlogWaiting:
 		control.When(!isStandard, backupsteps.WaitPXCBinlogBackupFinished)(task)
 		backupsteps.SaveXStoreSecrets(task)
+		backupsteps.RefreshXStoreSecrets(task)
 		control.Branch(isStandard,
 			backupsteps.UpdatePhaseTemplate(xstorev1.XStoreMetadataBackuping),
 			backupsteps.UpdatePhaseTemplate(xstorev1.XStoreBackupFinished),
 		)(task)
 	case xstorev1.XStoreMetadataBackuping:
 		defer control.ScheduleAfter(10*time.Second)(task, true)
+		backupsteps.RefreshXStoreSecrets(task)
 		backupsteps.UploadXStoreMetadata(task)
 		backupsteps.UpdateBackupStatus(task)
+		control.Branch(xstoreBackup.GetFinalizeGracePeriod() > 0,
+			backupsteps.UpdatePhaseTemplate(xstorev1.XStoreBackupFinalizing),
+			backupsteps.UpdatePhaseTemplate(xstorev1.XStoreBackupFinished),
+		)(task)
+	case xstorev1.XStoreBackupFinalizing:
+		backupsteps.RefreshXStoreSecrets(task)
+		backupsteps.UploadStaleXStoreMetadata(task)
+		backupsteps.WaitFinalizeGracePeriod(task)
 		backupsteps.UpdatePhaseTemplate(xstorev1.XStoreBackupFinished)(task)
 	case xstorev1.XStoreBackupFinished:
+		backupsteps.RecordLastSuccessfulBackup(task)
+		backupsteps.MarkLatestBackupSet(task)
 		backupsteps.RemoveFullBackupJob(task)
 		backupsteps.RemoveCollectBinlogJob(task)
 		backupsteps.RemoveBinlogBackupJob(task)
 		backupsteps.RemoveXSBackupOverRetention(task)
 		log.Info("Finished phase.")
+	case xstorev1.XstoreBackupFailed:
+		control.When(xstoreBackup.Status.Reason == backupsteps.ReasonOverallTimeout,
+			backupsteps.RemoveFullBackupJob,
+			backupsteps.RemoveCollectBinlogJob,
+			backupsteps.RemoveBinlogBackupJob,
+		)(task)
+		log.Info("Failed phase.")
 	case xstorev1.XStoreBackupDeleting:
 		control.When(isStandard, backupsteps.CleanRemoteBackupFiles)(task)
 		backupsteps.RemoveFinalizer(task)
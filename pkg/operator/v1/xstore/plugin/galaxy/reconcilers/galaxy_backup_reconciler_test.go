@@ -0,0 +1,1023 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcilers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	utilexec "k8s.io/utils/exec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	polardbxv1xstore "github.com/alibaba/polardbx-operator/api/v1/xstore"
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/config"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/factory"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	xstoreconvention "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
+	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
+	backupsteps "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/steps/backup"
+	instancesteps "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/steps/instance"
+	"github.com/alibaba/polardbx-operator/pkg/util/path"
+)
+
+const (
+	testNamespace     = "default"
+	testXStore        = "xstore"
+	testBackup        = "xstore-backup"
+	testSink          = "default"
+	testCommitIndex   = 12345
+	testDataSize      = 1 << 30
+	testChecksumTable = "db.t1"
+	testChecksum      = "1234567890"
+)
+
+// newTestClient returns a client of the api server started by envtest if KUBEBUILDER_ASSETS is set, otherwise
+// a fake client which keeps objects in memory.
+func newTestClient(t *testing.T, scheme *runtime.Scheme) client.Client {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		return fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithStatusSubresource(&xstorev1.XStore{}, &xstorev1.XStoreBackup{}, &xstorev1.PolarDBXBackup{}, &batchv1.Job{},
+				&corev1.Pod{}).
+			Build()
+	}
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "..", "..", "..", "..", "..", "charts", "polardbx-operator", "crds")},
+		ErrorIfCRDPathMissing: true,
+	}
+	restConfig, err := env.Start()
+	if err != nil {
+		t.Fatalf("unable to start test environment: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = env.Stop()
+	})
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+	return c
+}
+
+// newTestConfigLoader writes the operator config into a temporary directory and loads it.
+func newTestConfigLoader(t *testing.T, ctx context.Context, configYaml string) func() config.Config {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(configYaml), 0644); err != nil {
+		t.Fatalf("unable to write config: %v", err)
+	}
+	loaderFactory, err := config.NewConfigLoaderAndStartBackgroundRefresh(ctx,
+		config.LoadFromPath(dir), config.WithLogger(logr.Discard()))
+	if err != nil {
+		t.Fatalf("unable to load config: %v", err)
+	}
+	return loaderFactory()
+}
+
+// fakeEngine answers the commands executed on engine container by backup steps, as if the full backup job
+// succeeded on target pod. Unexpected commands fail.
+type fakeEngine struct {
+	commands []string
+	checksum string
+	// unhealthy is the number of health checks answered as recovering from crash before healthy
+	unhealthy int
+	// resultReadErrs is the number of reads of full backup result failed before succeeded
+	resultReadErrs int
+	// noResult tells the full backup job never writes result, as jobs of legacy images
+	noResult bool
+}
+
+func (e *fakeEngine) exec(_ *corev1.Pod, _ string, command []string, opts control.ExecOptions) error {
+	line := strings.Join(command, " ")
+	e.commands = append(e.commands, line)
+
+	var output string
+	switch {
+	case strings.Contains(line, "df -PB1"):
+		output = fmt.Sprintf("%d\n%d\n", 1<<20, 1<<40)
+	case strings.HasPrefix(line, "sh -c du -sb /data/mysql/data"):
+		output = fmt.Sprintf("%d\n", testDataSize)
+	case strings.HasPrefix(line, "cat ") && strings.HasSuffix(line, ".result"):
+		if e.resultReadErrs > 0 {
+			e.resultReadErrs--
+			return errors.New("unable to upgrade connection")
+		}
+		if e.noResult {
+			return utilexec.CodeExitError{Err: errors.New("no such file or directory"), Code: 1}
+		}
+		output = `{"success": true}`
+	case strings.HasPrefix(line, "cat ") && strings.HasSuffix(line, ".idx"):
+		output = fmt.Sprintf("%d", testCommitIndex)
+	case strings.Contains(line, " engine checksum --table "+testChecksumTable):
+		output = testChecksumTable + "\t" + e.checksum + "\n"
+	case strings.Contains(line, " engine checksum --sample-percent "):
+		output = testChecksumTable + "\t3:" + e.checksum + "\t3\n"
+	case strings.HasSuffix(line, " engine health"):
+		output = "healthy\t3600\n"
+		if e.unhealthy > 0 {
+			e.unhealthy--
+			output = "unhealthy\t10\tcrash recovery in progress\n"
+		}
+	default:
+		return errors.New("unexpected command: " + line)
+	}
+	_, err := io.WriteString(opts.Stdout, output)
+	return err
+}
+
+// backupHarness drives the xstore backup through phases with fake jobs, fake engine and fake filestream client.
+type backupHarness struct {
+	t            *testing.T
+	ctx          context.Context
+	scheme       *runtime.Scheme
+	client       client.Client
+	configLoader func() config.Config
+	engine       *fakeEngine
+	filestream   *filestream.FakeFilestreamClient
+}
+
+func newBackupHarness(t *testing.T, configYaml string) *backupHarness {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unable to build scheme: %v", err)
+	}
+	if err := xstorev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unable to build scheme: %v", err)
+	}
+
+	return &backupHarness{
+		t:            t,
+		ctx:          ctx,
+		scheme:       scheme,
+		client:       newTestClient(t, scheme),
+		configLoader: newTestConfigLoader(t, ctx, configYaml),
+		engine:       &fakeEngine{checksum: testChecksum},
+		filestream:   filestream.NewFakeFilestreamClient(),
+	}
+}
+
+func (h *backupHarness) mustCreate(obj client.Object) {
+	if err := h.client.Create(h.ctx, obj); err != nil {
+		h.t.Fatalf("unable to create %s: %v", obj.GetName(), err)
+	}
+}
+
+func (h *backupHarness) mustGet(name string, obj client.Object) {
+	if err := h.client.Get(h.ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, obj); err != nil {
+		h.t.Fatalf("unable to get %s: %v", name, err)
+	}
+}
+
+// setupXStore creates a standard xstore with its account secret and a leader pod to backup on.
+func (h *backupHarness) setupXStore() {
+	xstore := &xstorev1.XStore{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testXStore, UID: "xstore-uid"},
+		Spec: xstorev1.XStoreSpec{
+			Topology: polardbxv1xstore.Topology{
+				Template: polardbxv1xstore.NodeTemplate{
+					Spec: polardbxv1xstore.NodeSpec{Image: "polardbx-engine:test"},
+				},
+			},
+		},
+	}
+	h.mustCreate(xstore)
+	h.mustGet(testXStore, xstore)
+	xstore.Status.EngineVersion = "8.0.32"
+	xstore.Status.Phase = polardbxv1xstore.PhaseRunning
+	xstore.Status.ObservedGeneration = xstore.Generation
+	if err := h.client.Status().Update(h.ctx, xstore); err != nil {
+		h.t.Fatalf("unable to update status of xstore: %v", err)
+	}
+
+	h.mustCreate(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testXStore},
+		Data:       map[string][]byte{"admin": []byte("password")},
+	})
+	h.createLeaderPod(xstore)
+}
+
+// createLeaderPod creates the leader pod "<xstore>-cand-0" owned by the xstore.
+func (h *backupHarness) createLeaderPod(xstore *xstorev1.XStore) {
+	controller := true
+	h.mustCreate(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      xstore.Name + "-cand-0",
+			Labels: map[string]string{
+				xstoremeta.LabelName: xstore.Name,
+				xstoremeta.LabelRole: xstoremeta.RoleLeader,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: xstorev1.GroupVersion.String(),
+					Kind:       "XStore",
+					Name:       xstore.Name,
+					UID:        xstore.UID,
+					Controller: &controller,
+				},
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "engine", Image: "polardbx-engine:test"},
+			},
+		},
+	})
+}
+
+func (h *backupHarness) newXStoreBackup() *xstorev1.XStoreBackup {
+	backup := &xstorev1.XStoreBackup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testBackup, UID: "backup-uid"},
+		Spec: xstorev1.XStoreBackupSpec{
+			Engine:              "galaxy",
+			XStore:              xstorev1.XStoreReference{Name: testXStore},
+			PreferredBackupRole: xstoremeta.RoleLeader,
+			RetentionTime:       metav1.Duration{Duration: time.Hour},
+			UserMetadata:        map[string]string{"ticket": "T-1024"},
+			ChecksumTables:      []string{testChecksumTable},
+			StorageProvider: polardbx.BackupStorageProvider{
+				StorageName: polardbx.OSS,
+				Sink:        testSink,
+			},
+		},
+	}
+	h.mustCreate(backup)
+	return backup
+}
+
+// reconcile runs one round of reconciliation of the xstore backup, with contexts built as the controller does.
+func (h *backupHarness) reconcile() (reconcile.Result, error) {
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: testBackup}}
+	base := control.NewBaseReconcileContext(h.client, nil, nil, h.scheme, h.ctx, request)
+	base.SetCommandExecutor(h.engine.exec)
+	rc := xstorev1reconcile.NewBackupContext(base)
+
+	xstoreRequest := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: testXStore}}
+	xstoreRc := xstorev1reconcile.NewContext(
+		control.NewBaseReconcileContext(h.client, nil, nil, h.scheme, h.ctx, xstoreRequest), h.configLoader)
+	xstoreRc.SetXStoreKey(xstoreRequest.NamespacedName)
+	xstoreRc.SetFilestreamClient(h.filestream)
+	rc.SetXStoreContext(xstoreRc)
+
+	return (&GalaxyBackupReconciler{}).Reconcile(rc, logr.Discard(), request)
+}
+
+// completeJobs marks the jobs of xstore backup completed, since no job controller runs.
+func (h *backupHarness) completeJobs() {
+	var jobList batchv1.JobList
+	if err := h.client.List(h.ctx, &jobList, client.InNamespace(testNamespace)); err != nil {
+		h.t.Fatalf("unable to list jobs: %v", err)
+	}
+	for i := range jobList.Items {
+		job := &jobList.Items[i]
+		if job.Labels[xstoremeta.LabelXStoreBackupName] != testBackup || job.Status.CompletionTime != nil {
+			continue
+		}
+		now := metav1.Now()
+		job.Status.StartTime = &now
+		job.Status.CompletionTime = &now
+		job.Status.Succeeded = 1
+		job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{
+			Type:               batchv1.JobComplete,
+			Status:             corev1.ConditionTrue,
+			LastProbeTime:      now,
+			LastTransitionTime: now,
+		})
+		if err := h.client.Status().Update(h.ctx, job); err != nil {
+			h.t.Fatalf("unable to complete job %s: %v", job.Name, err)
+		}
+	}
+}
+
+// driveUntil reconciles the xstore backup until it reaches one of phases, phases transitioned are returned.
+func (h *backupHarness) driveUntil(phases ...xstorev1.XStoreBackupPhase) []xstorev1.XStoreBackupPhase {
+	transitions := make([]xstorev1.XStoreBackupPhase, 0)
+	for i := 0; i < 20; i++ {
+		if _, err := h.reconcile(); err != nil {
+			h.t.Fatalf("reconcile failed: %v", err)
+		}
+
+		var backup xstorev1.XStoreBackup
+		h.mustGet(testBackup, &backup)
+		if len(transitions) == 0 || transitions[len(transitions)-1] != backup.Status.Phase {
+			transitions = append(transitions, backup.Status.Phase)
+		}
+		for _, phase := range phases {
+			if backup.Status.Phase == phase {
+				return transitions
+			}
+		}
+		if backup.Status.Phase == xstorev1.XStoreFullBackuping {
+			h.completeJobs()
+		}
+	}
+	h.t.Fatalf("backup not reached phases %v, transitions: %v", phases, transitions)
+	return nil
+}
+
+func findBackupCondition(backup *xstorev1.XStoreBackup, condType polardbxv1xstore.ConditionType) *polardbxv1xstore.Condition {
+	for i := range backup.Status.Conditions {
+		if backup.Status.Conditions[i].Type == condType {
+			return &backup.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func TestGalaxyBackupAndRestore(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := newBackupHarness(t, "version: v1\n")
+	h.setupXStore()
+	h.newXStoreBackup()
+	// full backup job isn't started until the engine recovered from crash
+	h.engine.unhealthy = 1
+
+	transitions := h.driveUntil(xstorev1.XStoreBackupFinished, xstorev1.XstoreBackupFailed)
+	g.Expect(transitions).To(gomega.Equal([]xstorev1.XStoreBackupPhase{
+		xstorev1.XStoreBackupNew,
+		xstorev1.XStoreFullBackuping,
+		xstorev1.XStoreBinlogWaiting,
+		xstorev1.XStoreMetadataBackuping,
+		xstorev1.XStoreBackupFinished,
+	}))
+
+	var backup xstorev1.XStoreBackup
+	h.mustGet(testBackup, &backup)
+	g.Expect(backup.Finalizers).To(gomega.ContainElement(xstoremeta.Finalizer))
+	g.Expect(backup.Status.TargetPod).To(gomega.Equal(testXStore + "-cand-0"))
+	g.Expect(backup.Status.TargetPodHealthCheck).NotTo(gomega.BeNil())
+	g.Expect(backup.Status.TargetPodHealthCheck.Pod).To(gomega.Equal(backup.Status.TargetPod))
+	g.Expect(backup.Status.TargetPodHealthCheck.Healthy).To(gomega.BeTrue())
+	g.Expect(h.engine.unhealthy).To(gomega.BeZero())
+	g.Expect(backup.Status.CommitIndex).To(gomega.BeEquivalentTo(testCommitIndex))
+	g.Expect(backup.Status.FullBackupSize).To(gomega.BeEquivalentTo(testDataSize))
+	g.Expect(backup.Status.SecretName).To(gomega.Equal(testBackup))
+	g.Expect(backup.Status.EndTime).NotTo(gomega.BeNil())
+	g.Expect(backup.Status.Metadata).NotTo(gomega.BeNil())
+	g.Expect(backup.Status.Metadata.UserMetadata).To(gomega.HaveKeyWithValue("ticket", "T-1024"))
+	g.Expect(backup.Status.TableChecksums).To(gomega.Equal(map[string]string{testChecksumTable: testChecksum}))
+	for _, condType := range []polardbxv1xstore.ConditionType{
+		xstorev1.XStoreBackupFullBackupComplete,
+		xstorev1.XStoreBackupVerified,
+		xstorev1.XStoreBackupFilestreamConnected,
+		xstorev1.XStoreBackupMetadataUploaded,
+	} {
+		cond := findBackupCondition(&backup, condType)
+		g.Expect(cond).NotTo(gomega.BeNil(), "condition %s", condType)
+		g.Expect(cond.Status).To(gomega.Equal(corev1.ConditionTrue), "condition %s", condType)
+	}
+
+	// every command on engine is answered
+	g.Expect(h.engine.commands).NotTo(gomega.BeEmpty())
+
+	// marked as the latest backup set of xstore once finished
+	_, err := h.reconcile()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	var xstore xstorev1.XStore
+	h.mustGet(testXStore, &xstore)
+	g.Expect(xstore.Annotations).To(gomega.HaveKeyWithValue(xstoremeta.AnnotationLatestBackup, testBackup))
+
+	// metadata uploaded to sink
+	metadataPath := path.JoinPath(backup.Status.BackupRootPath, "metadata")
+	data, ok := h.filestream.GetFile(testSink, metadataPath)
+	g.Expect(ok).To(gomega.BeTrue(), "metadata not uploaded to %s", metadataPath)
+	metadata := &factory.MetadataBackup{}
+	g.Expect(json.Unmarshal(data, metadata)).To(gomega.Succeed())
+	g.Expect(metadata.BackupSetName).To(gomega.Equal(testBackup))
+	g.Expect(metadata.BackupRootPath).To(gomega.Equal(backup.Status.BackupRootPath))
+	g.Expect(metadata.PolarDBXVersion).To(gomega.Equal("8.0.32"))
+	g.Expect(metadata.Images).To(gomega.HaveKeyWithValue("dn", "polardbx-engine:test"))
+	g.Expect(metadata.UserMetadata).To(gomega.HaveKeyWithValue("ticket", "T-1024"))
+	g.Expect(metadata.XstoreMetadataList).To(gomega.HaveLen(1))
+	xstoreMetadata := metadata.XstoreMetadataList[0]
+	g.Expect(xstoreMetadata.Name).To(gomega.Equal(testXStore))
+	g.Expect(xstoreMetadata.BackupName).To(gomega.Equal(testBackup))
+	g.Expect(xstoreMetadata.LastCommitIndex).To(gomega.BeEquivalentTo(testCommitIndex))
+	g.Expect(xstoreMetadata.TargetPod).To(gomega.Equal(backup.Status.TargetPod))
+	g.Expect(xstoreMetadata.ChunkSize).To(gomega.Equal(backup.Status.ChunkSize))
+	g.Expect(xstoreMetadata.Secrets).To(gomega.ConsistOf(polardbx.PrivilegeItem{Username: "admin", Password: "password"}))
+	g.Expect(xstoreMetadata.TableChecksums).To(gomega.Equal(backup.Status.TableChecksums))
+
+	// restore from the backup set path, dummy backup and secret are built from the uploaded metadata
+	restored := &xstorev1.XStore{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "xstore-restored", UID: "xstore-restored-uid"},
+		Spec: xstorev1.XStoreSpec{
+			Restore: &xstorev1.XStoreRestoreSpec{
+				From:            xstorev1.XStoreRestoreFrom{BackupSetPath: backup.Status.BackupRootPath},
+				StorageProvider: backup.Spec.StorageProvider.DeepCopy(),
+			},
+		},
+	}
+	h.mustCreate(restored)
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: restored.Name}}
+	rc := xstorev1reconcile.NewContext(
+		control.NewBaseReconcileContext(h.client, nil, nil, h.scheme, h.ctx, request), h.configLoader)
+	rc.SetXStoreKey(request.NamespacedName)
+	rc.SetFilestreamClient(h.filestream)
+	task := control.NewTask()
+	instancesteps.CreateDummyBackupObject(task)
+	_, err = control.NewExecutor(logr.Discard()).Execute(rc, task)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	restoreSpec := rc.MustGetXStore().Spec.Restore
+	g.Expect(restoreSpec.BackupSet).NotTo(gomega.BeEmpty())
+	g.Expect(restoreSpec.From.XStoreName).To(gomega.Equal(testXStore))
+
+	var dummyBackup xstorev1.XStoreBackup
+	h.mustGet(restoreSpec.BackupSet, &dummyBackup)
+	g.Expect(dummyBackup.Status.Phase).To(gomega.Equal(xstorev1.XStoreBackupDummy))
+	g.Expect(dummyBackup.Status.CommitIndex).To(gomega.BeEquivalentTo(testCommitIndex))
+	g.Expect(dummyBackup.Status.BackupRootPath).To(gomega.Equal(backup.Status.BackupRootPath))
+	g.Expect(dummyBackup.Status.TargetPod).To(gomega.Equal(backup.Status.TargetPod))
+	g.Expect(dummyBackup.Spec.UserMetadata).To(gomega.HaveKeyWithValue("ticket", "T-1024"))
+	g.Expect(dummyBackup.Status.TableChecksums).To(gomega.Equal(backup.Status.TableChecksums))
+
+	// table checksums verified on leader of the restored xstore
+	h.createLeaderPod(restored)
+	restored = rc.MustGetXStore()
+	restored.Status.LeaderPod = restored.Name + "-cand-0"
+	g.Expect(rc.SaveTaskContext("restore", &instancesteps.RestoreJobContext{
+		TableChecksums: dummyBackup.Status.TableChecksums,
+	})).To(gomega.Succeed())
+	rc.SetCommandExecutor(h.engine.exec)
+	verify := func() {
+		task := control.NewTask()
+		instancesteps.VerifyRestoredTableChecksums(task)
+		_, err := control.NewExecutor(logr.Discard()).Execute(rc, task)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+	verify()
+	g.Expect(restored.Status.Phase).NotTo(gomega.Equal(polardbxv1xstore.PhaseFailed))
+
+	// only the rows sampled by backup are verified, and the coverage is recorded
+	g.Expect(rc.SaveTaskContext("restore", &instancesteps.RestoreJobContext{
+		TableChecksums:      map[string]string{testChecksumTable: "3:" + testChecksum},
+		TableChecksumSample: &polardbx.TableChecksumSample{Percent: 10, Seed: 42},
+	})).To(gomega.Succeed())
+	verify()
+	g.Expect(restored.Status.Phase).NotTo(gomega.Equal(polardbxv1xstore.PhaseFailed))
+	g.Expect(restored.Status.RestoreStatus.ChecksumCoverage).To(gomega.BeEquivalentTo(10))
+	g.Expect(h.engine.commands).To(gomega.ContainElement(gomega.ContainSubstring("--sample-percent 10 --sample-seed 42")))
+
+	h.engine.checksum = "0"
+	verify()
+	g.Expect(restored.Status.Phase).To(gomega.Equal(polardbxv1xstore.PhaseFailed))
+	g.Expect(restored.Status.RestoreStatus.Phase).To(gomega.Equal(polardbxv1xstore.RestorePhaseFailed))
+	g.Expect(restored.Status.RestoreStatus.Message).To(gomega.ContainSubstring(testChecksumTable))
+}
+
+func TestGalaxyBackupFailsAfterMetadataUploadAttempts(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := newBackupHarness(t, "version: v1\nbackup:\n  metadata_upload_max_attempts: 2\n")
+	h.filestream.UploadErr = errors.New("sink unavailable")
+	h.setupXStore()
+	h.newXStoreBackup()
+
+	transitions := h.driveUntil(xstorev1.XStoreBackupFinished, xstorev1.XstoreBackupFailed)
+	g.Expect(transitions).To(gomega.Equal([]xstorev1.XStoreBackupPhase{
+		xstorev1.XStoreFullBackuping,
+		xstorev1.XStoreBinlogWaiting,
+		xstorev1.XStoreMetadataBackuping,
+		xstorev1.XstoreBackupFailed,
+	}))
+	g.Expect(h.filestream.Uploads).To(gomega.HaveLen(2))
+
+	var backup xstorev1.XStoreBackup
+	h.mustGet(testBackup, &backup)
+	g.Expect(backup.Status.MetadataUploadAttempts).To(gomega.BeEquivalentTo(2))
+	g.Expect(backup.Status.Message).To(gomega.ContainSubstring("sink unavailable"))
+	cond := findBackupCondition(&backup, xstorev1.XStoreBackupMetadataUploaded)
+	g.Expect(cond).NotTo(gomega.BeNil())
+	g.Expect(cond.Status).To(gomega.Equal(corev1.ConditionFalse))
+	g.Expect(cond.Reason).To(gomega.Equal("UploadFailed"))
+}
+
+func TestGalaxyBackupRecordsPhaseTimestamps(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := newBackupHarness(t, "version: v1\n")
+	h.setupXStore()
+	h.newXStoreBackup()
+
+	var backup xstorev1.XStoreBackup
+	h.driveUntil(xstorev1.XStoreFullBackuping)
+	h.mustGet(testBackup, &backup)
+	g.Expect(backup.Status.PhaseTimestamps).To(gomega.HaveLen(1))
+	fullBackupEntry, ok := backup.Status.PhaseTimestamps[xstorev1.XStoreFullBackuping]
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(fullBackupEntry.IsZero()).To(gomega.BeFalse())
+
+	h.driveUntil(xstorev1.XStoreBackupFinished, xstorev1.XstoreBackupFailed)
+	h.mustGet(testBackup, &backup)
+	g.Expect(backup.Status.Phase).To(gomega.Equal(xstorev1.XStoreBackupFinished))
+	g.Expect(backup.Status.PhaseTimestamps[xstorev1.XStoreFullBackuping]).To(gomega.Equal(fullBackupEntry))
+	previous := fullBackupEntry
+	for _, phase := range []xstorev1.XStoreBackupPhase{
+		xstorev1.XStoreBinlogWaiting,
+		xstorev1.XStoreMetadataBackuping,
+		xstorev1.XStoreBackupFinished,
+	} {
+		entry, ok := backup.Status.PhaseTimestamps[phase]
+		g.Expect(ok).To(gomega.BeTrue(), "no timestamp of phase %s", phase)
+		g.Expect(entry.Before(&previous)).To(gomega.BeFalse(), "phase %s entered before the previous one", phase)
+		previous = entry
+	}
+}
+
+func TestGalaxyBackupRetriesReadingFullBackupResult(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := newBackupHarness(t, "version: v1\n")
+	h.engine.resultReadErrs = 2
+	h.setupXStore()
+	h.newXStoreBackup()
+
+	h.driveUntil(xstorev1.XStoreBackupFinished, xstorev1.XstoreBackupFailed)
+	var backup xstorev1.XStoreBackup
+	h.mustGet(testBackup, &backup)
+	g.Expect(backup.Status.Phase).To(gomega.Equal(xstorev1.XStoreBackupFinished))
+	g.Expect(h.engine.resultReadErrs).To(gomega.BeZero())
+	g.Expect(h.backupJobContext().JobResultDir).To(gomega.Equal("/data/mysql/tmp"))
+	cond := findBackupCondition(&backup, xstorev1.XStoreBackupVerified)
+	g.Expect(cond).NotTo(gomega.BeNil())
+	g.Expect(cond.Status).To(gomega.Equal(corev1.ConditionTrue))
+}
+
+func TestGalaxyBackupOfLegacyJobWithoutResult(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := newBackupHarness(t, "version: v1\n")
+	h.engine.noResult = true
+	h.setupXStore()
+	h.newXStoreBackup()
+
+	h.driveUntil(xstorev1.XStoreBackupFinished, xstorev1.XstoreBackupFailed)
+	var backup xstorev1.XStoreBackup
+	h.mustGet(testBackup, &backup)
+	g.Expect(backup.Status.Phase).To(gomega.Equal(xstorev1.XStoreBackupFinished))
+	g.Expect(backup.Status.CommitIndex).To(gomega.BeEquivalentTo(testCommitIndex))
+	g.Expect(findBackupCondition(&backup, xstorev1.XStoreBackupVerified)).To(gomega.BeNil())
+}
+
+func TestGalaxyBackupWaitsForStableXStore(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := newBackupHarness(t, "version: v1\n")
+	h.setupXStore()
+	h.newXStoreBackup()
+
+	var xstore xstorev1.XStore
+	h.mustGet(testXStore, &xstore)
+	xstore.Status.Phase = polardbxv1xstore.PhaseUpgrading
+	g.Expect(h.client.Status().Update(h.ctx, &xstore)).To(gomega.Succeed())
+
+	result, err := h.reconcile()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(result.RequeueAfter).To(gomega.BeNumerically(">", 0))
+
+	var backup xstorev1.XStoreBackup
+	h.mustGet(testBackup, &backup)
+	g.Expect(backup.Status.Phase).To(gomega.Equal(xstorev1.XStoreBackupNew))
+	g.Expect(backup.Status.Message).To(gomega.ContainSubstring("Upgrading"))
+	cond := findBackupCondition(&backup, xstorev1.XStoreBackupXStoreStable)
+	g.Expect(cond).NotTo(gomega.BeNil())
+	g.Expect(cond.Status).To(gomega.Equal(corev1.ConditionFalse))
+
+	var jobs batchv1.JobList
+	g.Expect(h.client.List(h.ctx, &jobs, client.InNamespace(testNamespace))).To(gomega.Succeed())
+	g.Expect(jobs.Items).To(gomega.BeEmpty())
+
+	h.mustGet(testXStore, &xstore)
+	xstore.Status.Phase = polardbxv1xstore.PhaseRunning
+	g.Expect(h.client.Status().Update(h.ctx, &xstore)).To(gomega.Succeed())
+
+	h.driveUntil(xstorev1.XStoreFullBackuping)
+	h.mustGet(testBackup, &backup)
+	cond = findBackupCondition(&backup, xstorev1.XStoreBackupXStoreStable)
+	g.Expect(cond).NotTo(gomega.BeNil())
+	g.Expect(cond.Status).To(gomega.Equal(corev1.ConditionTrue))
+}
+
+func TestGalaxyBackupRefusedWithoutEncryptionKey(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := newBackupHarness(t, "version: v1\n")
+	h.setupXStore()
+	backup := h.newXStoreBackup()
+	backup.Spec.Encryption = &polardbx.BackupEncryption{SecretName: "missing-key"}
+	g.Expect(h.client.Update(h.ctx, backup)).To(gomega.Succeed())
+
+	transitions := h.driveUntil(xstorev1.XStoreFullBackuping, xstorev1.XstoreBackupFailed)
+	g.Expect(transitions).To(gomega.Equal([]xstorev1.XStoreBackupPhase{xstorev1.XstoreBackupFailed}))
+	h.mustGet(testBackup, backup)
+	g.Expect(backup.Status.Reason).To(gomega.Equal("EncryptionKeyUnavailable"))
+	g.Expect(backup.Status.FailureReason).To(gomega.Equal(polardbx.FailureReasonInvalidSpec))
+
+	var jobs batchv1.JobList
+	g.Expect(h.client.List(h.ctx, &jobs, client.InNamespace(testNamespace))).To(gomega.Succeed())
+	g.Expect(jobs.Items).To(gomega.BeEmpty())
+}
+
+// requestBinlogBackupRerun annotates the xstore backup to rerun binlog backup phase.
+func (h *backupHarness) requestBinlogBackupRerun() {
+	var backup xstorev1.XStoreBackup
+	h.mustGet(testBackup, &backup)
+	if backup.Annotations == nil {
+		backup.Annotations = make(map[string]string)
+	}
+	backup.Annotations[xstoremeta.AnnotationRerunBinlogBackup] = "true"
+	if err := h.client.Update(h.ctx, &backup); err != nil {
+		h.t.Fatalf("unable to annotate backup: %v", err)
+	}
+}
+
+func TestGalaxyBinlogBackupRerunOfFailedBackup(t *testing.T) {
+	testCases := map[string]struct {
+		fullBackupComplete bool
+		rerun              bool
+	}{
+		"failed after full backup completed": {fullBackupComplete: true, rerun: true},
+		"failed during full backup":          {fullBackupComplete: false, rerun: false},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			h := newBackupHarness(t, "version: v1\n")
+			h.setupXStore()
+			h.newXStoreBackup()
+			if tc.fullBackupComplete {
+				h.driveUntil(xstorev1.XStoreBinlogWaiting)
+			} else {
+				h.driveUntil(xstorev1.XStoreFullBackuping)
+			}
+			h.filestream.PutFile(testSink, h.backupJobContext().FullBackupPath, []byte("full backup"))
+
+			var backup xstorev1.XStoreBackup
+			h.mustGet(testBackup, &backup)
+			backup.Status.Phase = xstorev1.XstoreBackupFailed
+			backup.Status.Reason = "binlogbackup job failed"
+			backup.Status.FailureReason = polardbx.FailureReasonJobFailed
+			backup.Status.Message = "binlogbackup failed"
+			g.Expect(h.client.Status().Update(h.ctx, &backup)).To(gomega.Succeed())
+
+			h.requestBinlogBackupRerun()
+			_, err := h.reconcile()
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+
+			h.mustGet(testBackup, &backup)
+			g.Expect(backup.Annotations).NotTo(gomega.HaveKey(xstoremeta.AnnotationRerunBinlogBackup))
+			if tc.rerun {
+				g.Expect(backup.Status.Phase).To(gomega.Equal(xstorev1.XStoreBinlogBackuping))
+				g.Expect(backup.Status.Reason).To(gomega.BeEmpty())
+				g.Expect(backup.Status.FailureReason).To(gomega.BeEmpty())
+				g.Expect(backup.Status.Message).To(gomega.BeEmpty())
+			} else {
+				g.Expect(backup.Status.Phase).To(gomega.Equal(xstorev1.XstoreBackupFailed))
+				g.Expect(backup.Status.Message).To(gomega.ContainSubstring("rerun rejected"))
+			}
+		})
+	}
+}
+
+// binlogBackupJobs returns the binlog backup jobs of xstore backup.
+func (h *backupHarness) binlogBackupJobs() []batchv1.Job {
+	var jobList batchv1.JobList
+	if err := h.client.List(h.ctx, &jobList, client.InNamespace(testNamespace),
+		client.MatchingLabels{xstoremeta.LabelXStoreBinlogBackupName: testBackup}); err != nil {
+		h.t.Fatalf("unable to list jobs: %v", err)
+	}
+	return jobList.Items
+}
+
+func TestGalaxyBinlogBackupRerunAfterJobFailed(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := newBackupHarness(t, "version: v1\n")
+	h.setupXStore()
+	h.newXStoreBackup()
+	h.driveUntil(xstorev1.XStoreBinlogWaiting)
+	h.filestream.PutFile(testSink, h.backupJobContext().FullBackupPath, []byte("full backup"))
+
+	// turn into binlog backup phase of polardbx backup
+	h.mustCreate(&xstorev1.PolarDBXBackup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "pxc-backup"},
+	})
+	var pxcBackup xstorev1.PolarDBXBackup
+	h.mustGet("pxc-backup", &pxcBackup)
+	pxcBackup.Status.Phase = xstorev1.BinlogBackuping
+	g.Expect(h.client.Status().Update(h.ctx, &pxcBackup)).To(gomega.Succeed())
+	var backup xstorev1.XStoreBackup
+	h.mustGet(testBackup, &backup)
+	backup.Labels = map[string]string{polardbxmeta.LabelTopBackup: "pxc-backup"}
+	g.Expect(h.client.Update(h.ctx, &backup)).To(gomega.Succeed())
+	backup.Status.Phase = xstorev1.XStoreBinlogBackuping
+	g.Expect(h.client.Status().Update(h.ctx, &backup)).To(gomega.Succeed())
+
+	_, err := h.reconcile()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	jobs := h.binlogBackupJobs()
+	g.Expect(jobs).To(gomega.HaveLen(1))
+	failedJob := jobs[0]
+	failedJob.Status.Failed = 1
+	failedJob.Status.Conditions = append(failedJob.Status.Conditions, batchv1.JobCondition{
+		Type:   batchv1.JobFailed,
+		Status: corev1.ConditionTrue,
+	})
+	g.Expect(h.client.Status().Update(h.ctx, &failedJob)).To(gomega.Succeed())
+
+	_, err = h.reconcile()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	h.mustGet(testBackup, &backup)
+	g.Expect(backup.Status.Phase).To(gomega.Equal(xstorev1.XstoreBackupFailed))
+	g.Expect(backup.Status.Message).To(gomega.ContainSubstring(xstoremeta.AnnotationRerunBinlogBackup))
+
+	// failed job is removed on rerun, and binlog backup is started again
+	h.requestBinlogBackupRerun()
+	_, err = h.reconcile()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	h.mustGet(testBackup, &backup)
+	g.Expect(backup.Status.Phase).To(gomega.Equal(xstorev1.XStoreBinlogBackuping))
+	g.Expect(backup.Status.Reason).To(gomega.BeEmpty())
+
+	_, err = h.reconcile()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	jobs = h.binlogBackupJobs()
+	g.Expect(jobs).To(gomega.HaveLen(1))
+	g.Expect(jobs[0].Status.Failed).To(gomega.BeZero())
+	g.Expect(jobs[0].Status.Conditions).To(gomega.BeEmpty())
+}
+
+// backupJobContext returns the task context for backup saved in config map.
+func (h *backupHarness) backupJobContext() *backupsteps.BackupJobContext {
+	var cmList corev1.ConfigMapList
+	if err := h.client.List(h.ctx, &cmList, client.InNamespace(testNamespace)); err != nil {
+		h.t.Fatalf("unable to list config maps: %v", err)
+	}
+	for _, cm := range cmList.Items {
+		if data, ok := cm.Data[xstoreconvention.BackupConfigMapKey]; ok {
+			var backupJobContext backupsteps.BackupJobContext
+			if err := json.Unmarshal([]byte(data), &backupJobContext); err != nil {
+				h.t.Fatalf("unable to parse task context for backup: %v", err)
+			}
+			return &backupJobContext
+		}
+	}
+	return nil
+}
+
+func TestGalaxyBackupRepointsSinkBeforeUpload(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := newBackupHarness(t, "version: v1\n")
+	h.setupXStore()
+	h.newXStoreBackup()
+	// hold the full backup job until the engine gets healthy
+	h.engine.unhealthy = 1
+
+	_, err := h.reconcile()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	var backup xstorev1.XStoreBackup
+	h.mustGet(testBackup, &backup)
+	g.Expect(backup.Status.Phase).To(gomega.Equal(xstorev1.XStoreBackupNew))
+	g.Expect(h.backupJobContext()).NotTo(gomega.BeNil())
+	g.Expect(h.backupJobContext().Sink).To(gomega.Equal(testSink))
+
+	backup.Spec.StorageProvider.Sink = "backup-repointed"
+	g.Expect(h.client.Update(h.ctx, &backup)).To(gomega.Succeed())
+
+	h.driveUntil(xstorev1.XStoreFullBackuping)
+	g.Expect(h.backupJobContext().Sink).To(gomega.Equal("backup-repointed"))
+	h.mustGet(testBackup, &backup)
+	g.Expect(backup.Spec.StorageProvider.Sink).To(gomega.Equal("backup-repointed"))
+	g.Expect(backup.Status.BackupRootPath).NotTo(gomega.BeEmpty())
+
+	var events corev1.EventList
+	g.Expect(h.client.List(h.ctx, &events, client.InNamespace(testNamespace))).To(gomega.Succeed())
+	reasons := make([]string, 0, len(events.Items))
+	for _, event := range events.Items {
+		reasons = append(reasons, event.Reason)
+	}
+	g.Expect(reasons).To(gomega.ContainElement("SinkRepointed"))
+}
+
+// downloadRecorder records the bytes read back by downloads through the fake filestream client.
+type downloadRecorder struct {
+	*filestream.FakeFilestreamClient
+	downloaded [][]byte
+}
+
+func (r *downloadRecorder) Download(writer io.Writer, actionMetadata filestream.ActionMetadata) (int64, error) {
+	var buf bytes.Buffer
+	n, err := r.FakeFilestreamClient.Download(io.MultiWriter(writer, &buf), actionMetadata)
+	r.downloaded = append(r.downloaded, buf.Bytes())
+	return n, err
+}
+
+// Backup artifacts produced with each server-side encryption and codec option must be restorable.
+func TestGalaxyBackupRestoreRoundTrip(t *testing.T) {
+	testCases := map[string]struct {
+		sse         *polardbx.ServerSideEncryption
+		compression polardbx.BackupCompression
+		encrypted   bool
+	}{
+		"no encryption": {},
+		"gzip": {
+			compression: polardbx.BackupCompressionGzip,
+		},
+		"gzip and encrypted": {
+			compression: polardbx.BackupCompressionGzip,
+			encrypted:   true,
+		},
+		"encrypted with AES256": {
+			sse:       &polardbx.ServerSideEncryption{Algorithm: polardbx.SSEAlgorithmAES256},
+			encrypted: true,
+		},
+		"AES256": {
+			sse: &polardbx.ServerSideEncryption{Algorithm: polardbx.SSEAlgorithmAES256},
+		},
+		"KMS with default key": {
+			sse: &polardbx.ServerSideEncryption{Algorithm: polardbx.SSEAlgorithmKMS},
+		},
+		"KMS with key": {
+			sse: &polardbx.ServerSideEncryption{
+				Algorithm: polardbx.SSEAlgorithmKMS,
+				KMSKeyId:  "0e7bb2d4-5ad4-4e3b-9c5f-6e9f3c0a7b21",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			h := newBackupHarness(t, "version: v1\n")
+			h.setupXStore()
+			backup := h.newXStoreBackup()
+			backup.Spec.StorageProvider.ServerSideEncryption = tc.sse
+			backup.Spec.Compression = tc.compression
+			keyFingerprint := ""
+			if tc.encrypted {
+				key := []byte("backup-encryption-key")
+				h.mustCreate(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "backup-key"},
+					Data:       map[string][]byte{polardbx.DefaultBackupEncryptionKey: key},
+				})
+				backup.Spec.Encryption = &polardbx.BackupEncryption{SecretName: "backup-key"}
+				keyFingerprint = polardbx.KeyFingerprint(key)
+			}
+			g.Expect(h.client.Update(h.ctx, backup)).To(gomega.Succeed())
+			sseAlgorithm, sseKMSKeyId := backup.Spec.StorageProvider.GetServerSideEncryption()
+
+			h.driveUntil(xstorev1.XStoreBackupFinished, xstorev1.XstoreBackupFailed)
+			h.mustGet(testBackup, backup)
+			g.Expect(backup.Status.Phase).To(gomega.Equal(xstorev1.XStoreBackupFinished))
+
+			// backup jobs upload with the encryption
+			var taskConfigMap corev1.ConfigMap
+			h.mustGet(testBackup+"-backup", &taskConfigMap)
+			backupJobContext := map[string]interface{}{}
+			g.Expect(json.Unmarshal([]byte(taskConfigMap.Data["backup"]), &backupJobContext)).To(gomega.Succeed())
+			if sseAlgorithm == "" {
+				g.Expect(backupJobContext).NotTo(gomega.HaveKey("sseAlgorithm"))
+			} else {
+				g.Expect(backupJobContext).To(gomega.HaveKeyWithValue("sseAlgorithm", sseAlgorithm))
+			}
+
+			// codec of full backup is recorded, standard backup never backs up binlogs by itself
+			fullBackupCodec := polardbx.NewBackupCodec(polardbx.BackupArtifactFullBackup, tc.compression,
+				backup.Spec.Encryption, keyFingerprint)
+			g.Expect(backup.Status.FullBackupCodec).To(gomega.Equal(&fullBackupCodec))
+			g.Expect(backup.Status.BinlogBackupCodec).To(gomega.BeNil())
+			if tc.compression != "" {
+				g.Expect(backupJobContext).To(gomega.HaveKeyWithValue("compression", string(tc.compression)))
+			}
+			if tc.encrypted {
+				g.Expect(backupJobContext).To(gomega.HaveKeyWithValue("encryption", string(polardbx.BackupEncryptionAES256)))
+			} else {
+				g.Expect(backupJobContext).NotTo(gomega.HaveKey("encryption"))
+			}
+
+			// metadata uploaded with the encryption
+			metadataPath := path.JoinPath(backup.Status.BackupRootPath, "metadata")
+			g.Expect(h.filestream.Uploads).To(gomega.HaveLen(1))
+			g.Expect(h.filestream.Uploads[0].Filename).To(gomega.Equal(metadataPath))
+			g.Expect(h.filestream.Uploads[0].SSEAlgorithm).To(gomega.Equal(sseAlgorithm))
+			g.Expect(h.filestream.Uploads[0].SSEKMSKeyId).To(gomega.Equal(sseKMSKeyId))
+			uploaded, ok := h.filestream.GetFile(testSink, metadataPath)
+			g.Expect(ok).To(gomega.BeTrue())
+			storedSSEAlgorithm, storedSSEKMSKeyId := h.filestream.GetFileServerSideEncryption(testSink, metadataPath)
+			g.Expect(storedSSEAlgorithm).To(gomega.Equal(sseAlgorithm))
+			g.Expect(storedSSEKMSKeyId).To(gomega.Equal(sseKMSKeyId))
+
+			// restore with the same storage provider reads back identical bytes
+			restored := &xstorev1.XStore{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "xstore-restored", UID: "xstore-restored-uid"},
+				Spec: xstorev1.XStoreSpec{
+					Restore: &xstorev1.XStoreRestoreSpec{
+						From:            xstorev1.XStoreRestoreFrom{BackupSetPath: backup.Status.BackupRootPath},
+						StorageProvider: backup.Spec.StorageProvider.DeepCopy(),
+					},
+				},
+			}
+			h.mustCreate(restored)
+			recorder := &downloadRecorder{FakeFilestreamClient: h.filestream}
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: restored.Name}}
+			rc := xstorev1reconcile.NewContext(
+				control.NewBaseReconcileContext(h.client, nil, nil, h.scheme, h.ctx, request), h.configLoader)
+			rc.SetXStoreKey(request.NamespacedName)
+			rc.SetFilestreamClient(recorder)
+			task := control.NewTask()
+			instancesteps.CreateDummyBackupObject(task)
+			_, err := control.NewExecutor(logr.Discard()).Execute(rc, task)
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(recorder.downloaded).To(gomega.HaveLen(1))
+			g.Expect(recorder.downloaded[0]).To(gomega.Equal(uploaded))
+			restoreDownload := h.filestream.Downloads[len(h.filestream.Downloads)-1]
+			g.Expect(restoreDownload.Filename).To(gomega.Equal(metadataPath))
+			g.Expect(restoreDownload.SSEAlgorithm).To(gomega.Equal(sseAlgorithm))
+			g.Expect(restoreDownload.SSEKMSKeyId).To(gomega.Equal(sseKMSKeyId))
+
+			var dummyBackup xstorev1.XStoreBackup
+			h.mustGet(rc.MustGetXStore().Spec.Restore.BackupSet, &dummyBackup)
+			g.Expect(dummyBackup.Status.CommitIndex).To(gomega.Equal(backup.Status.CommitIndex))
+			g.Expect(dummyBackup.Status.BackupRootPath).To(gomega.Equal(backup.Status.BackupRootPath))
+			g.Expect(dummyBackup.Status.TableChecksums).To(gomega.Equal(backup.Status.TableChecksums))
+			g.Expect(dummyBackup.Status.FullBackupCodec).To(gomega.Equal(backup.Status.FullBackupCodec))
+			g.Expect(dummyBackup.Status.BinlogBackupCodec).To(gomega.Equal(backup.Status.BinlogBackupCodec))
+			g.Expect(dummyBackup.Spec.Encryption).To(gomega.Equal(backup.Spec.Encryption))
+		})
+	}
+}
+
+func TestGalaxyRestoreFromLatestBackupSet(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := newBackupHarness(t, "version: v1\n")
+	h.setupXStore()
+
+	restored := &xstorev1.XStore{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "xstore-restored", UID: "xstore-restored-uid"},
+		Spec: xstorev1.XStoreSpec{
+			Restore: &xstorev1.XStoreRestoreSpec{
+				BackupSet: xstoremeta.LatestBackupSet,
+				From:      xstorev1.XStoreRestoreFrom{XStoreName: testXStore},
+			},
+		},
+	}
+	h.mustCreate(restored)
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: restored.Name}}
+	resolve := func() *xstorev1.XStore {
+		rc := xstorev1reconcile.NewContext(
+			control.NewBaseReconcileContext(h.client, nil, nil, h.scheme, h.ctx, request), h.configLoader)
+		rc.SetXStoreKey(request.NamespacedName)
+		task := control.NewTask()
+		instancesteps.ResolveLatestBackupSet(task)
+		_, err := control.NewExecutor(logr.Discard()).Execute(rc, task)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		return rc.MustGetXStore()
+	}
+
+	// wait until any backup finished
+	g.Expect(resolve().Spec.Restore.BackupSet).To(gomega.Equal(xstoremeta.LatestBackupSet))
+
+	h.newXStoreBackup()
+	h.driveUntil(xstorev1.XStoreBackupFinished, xstorev1.XstoreBackupFailed)
+	_, err := h.reconcile()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(resolve().Spec.Restore.BackupSet).To(gomega.Equal(testBackup))
+}
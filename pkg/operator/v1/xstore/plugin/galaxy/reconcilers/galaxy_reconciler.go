@@ -94,6 +94,7 @@ func (r *GalaxyReconciler) newReconcileTask(rc *xstorev1reconcile.Context, xstor
 		//   * ConfigMap for sharing information and templates among nodes
 		if xstore.Spec.Restore != nil {
 			instancesteps.CreateDummyBackupObject(task)
+			instancesteps.ResolveLatestBackupSet(task)
 		}
 		instancesteps.CreateSecret(task)
 		galaxyinstancesteps.CreateServices(task)
@@ -167,6 +168,8 @@ func (r *GalaxyReconciler) newReconcileTask(rc *xstorev1reconcile.Context, xstor
 
 			xstoreplugincommonsteps.SyncNodesInfoAndKeepBlock(task)
 			instancesteps.PrepareRestoreJobContext(task)
+			control.When(xstore.Status.RestoreStatus == nil,
+				instancesteps.UpdateRestorePhaseTemplate(polardbxv1xstore.RestorePhaseDownloading))(task)
 			instancesteps.StartRestoreJob(task)
 			instancesteps.WaitUntilRestoreJobFinished(task)
 			// Unblock bootstrap.
@@ -179,9 +182,13 @@ func (r *GalaxyReconciler) newReconcileTask(rc *xstorev1reconcile.Context, xstor
 			instancesteps.WaitUntilLeaderElected(task)
 			instancesteps.CreateAccounts(task)
 			if !isStandard {
+				instancesteps.UpdateRestorePhaseTemplate(polardbxv1xstore.RestorePhaseRecoveringBinlog)(task)
 				instancesteps.StartRecoverJob(task)
 				instancesteps.WaitUntilRecoverJobFinished(task)
+				instancesteps.UpdateRestorePhaseTemplate(polardbxv1xstore.RestorePhaseVerifying)(task)
+				instancesteps.VerifyRestoredCommitIndex(task)
 			}
+			instancesteps.VerifyRestoredTableChecksums(task)
 			// Check connectivity and set engine version into status.
 			control.Branch(debug.IsDebugEnabled(),
 				instancesteps.QueryAndUpdateEngineVersion,          // Query the engine version via command. (DEBUG)
@@ -197,6 +204,7 @@ func (r *GalaxyReconciler) newReconcileTask(rc *xstorev1reconcile.Context, xstor
 			control.When(rc.IsPitrRestore(xstore),
 				instancesteps.CleanPreparePitrBinlogJob,
 			)(task)
+			instancesteps.UpdateRestorePhaseTemplate(polardbxv1xstore.RestorePhaseFinished)(task)
 			// Go to phase "Running".
 			instancesteps.UpdateStageTemplate(polardbxv1xstore.StageEmpty)(task)
 			instancesteps.UpdatePhaseTemplate(polardbxv1xstore.PhaseRunning)(task)
@@ -291,6 +299,12 @@ func (r *GalaxyReconciler) newReconcileTask(rc *xstorev1reconcile.Context, xstor
 			)(task)
 
 			instancesteps.UpdateTdeConfig(task)
+
+			// Create backup if triggered by annotation.
+			instancesteps.WhenTriggerBackupAnnotated(
+				instancesteps.CreateBackupByTriggerAnnotation,
+			)(task)
+
 			// Update the observed generation at the end of running phase.
 			instancesteps.UpdateObservedGeneration(task)
 			instancesteps.UpdateObservedTopologyAndConfig(task)
@@ -19,10 +19,13 @@ package reconcile
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1xstore "github.com/alibaba/polardbx-operator/api/v1/xstore"
 	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
 	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
 	"github.com/alibaba/polardbx-operator/pkg/meta/core/group"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/config"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
 	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
@@ -39,8 +42,12 @@ import (
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
 )
 
+// ErrTaskContextCorrupted indicates that the task context saved in config map is unparsable.
+var ErrTaskContextCorrupted = errors.New("task context corrupted")
+
 type BackupContext struct {
 	*control.BaseReconcileContext
 	xStoreContext              *Context
@@ -88,6 +95,36 @@ func (rc *BackupContext) MustGetXStoreBackup() *polardbxv1.XStoreBackup {
 	return xstoreBackup
 }
 
+func (rc *BackupContext) UpdateXStoreBackupCondition(cond *polardbxv1xstore.Condition) {
+	if cond == nil {
+		return
+	}
+
+	// Set condition's time
+	now := metav1.Now()
+	cond.LastProbeTime = nil
+	cond.LastTransitionTime = now
+
+	xstoreBackup := rc.MustGetXStoreBackup()
+	for i := range xstoreBackup.Status.Conditions {
+		c := &xstoreBackup.Status.Conditions[i]
+		// Branch same type found
+		if c.Type == cond.Type {
+			transition := c.Status != cond.Status
+			if !transition {
+				cond.LastTransitionTime = c.LastTransitionTime
+				cond.Reason = c.Reason
+				cond.Message = c.Message
+			}
+			cond.DeepCopyInto(c)
+			return
+		}
+	}
+
+	// Handle condition type not found
+	xstoreBackup.Status.Conditions = append(xstoreBackup.Status.Conditions, *cond)
+}
+
 func (rc *BackupContext) GetXStoreBackup() (*polardbxv1.XStoreBackup, error) {
 	if rc.xstoreBackup == nil {
 		var xstoreBackup polardbxv1.XStoreBackup
@@ -123,40 +160,99 @@ func (rc *BackupContext) GetPolarDBXBackup() (*polardbxv1.PolarDBXBackup, error)
 
 func (rc *BackupContext) GetXStoreBackupJob() (*batchv1.Job, error) {
 	if rc.xstoreBackupJob == nil {
-		xstoreBackup := rc.MustGetXStoreBackup()
-
-		var jobList batchv1.JobList
-		err := rc.Client().List(rc.Context(), &jobList, client.InNamespace(rc.Request().Namespace),
-			client.MatchingLabels{
-				xstoremeta.LabelXStoreBackupName: xstoreBackup.Name,
-			})
-		if err != nil {
+		job, err := rc.getOwnedBackupJob(xstoreconvention.BackupJobTypeFullBackup, xstoremeta.LabelXStoreBackupName)
+		if err != nil || job == nil {
 			return nil, err
 		}
+		rc.xstoreBackupJob = job
+	}
+	return rc.xstoreBackupJob, nil
+}
 
-		if len(jobList.Items) == 0 {
-			return nil, nil
-		}
+// getOwnedBackupJob finds the job of given type owned by current xstore backup. Jobs are discovered by owner
+// reference and type label rather than exact name, and jobs created by previous versions of operator without
+// type label are recognized by naming convention, so that in-flight backup continues with its existing job
+// after operator upgraded. Jobs without type label are only looked up once backup has started on target pod.
+func (rc *BackupContext) getOwnedBackupJob(jobType xstoreconvention.BackupJobType, typeLabel string) (*batchv1.Job, error) {
+	xstoreBackup := rc.MustGetXStoreBackup()
+
+	var jobList batchv1.JobList
+	err := rc.Client().List(rc.Context(), &jobList, client.InNamespace(rc.Request().Namespace),
+		client.MatchingLabels{
+			typeLabel: xstoreBackup.Name,
+		})
+	if err != nil {
+		return nil, err
+	}
+	job, err := findOwnedBackupJob(jobList.Items, xstoreBackup, jobType, typeLabel)
+	if err != nil || job != nil {
+		return job, err
+	}
+
+	// fall back to jobs of legacy naming schemes
+	legacySelector := legacyBackupJobSelector(xstoreBackup)
+	if legacySelector == nil {
+		return nil, nil
+	}
+	err = rc.Client().List(rc.Context(), &jobList, client.InNamespace(rc.Request().Namespace), legacySelector)
+	if err != nil {
+		return nil, err
+	}
+	return findOwnedBackupJob(jobList.Items, xstoreBackup, jobType, typeLabel)
+}
+
+// legacyBackupJobSelector returns the selector of jobs that may be created by previous versions of operator for
+// xstore backup, nil if no job can have been created yet. Target pod is recorded in status before any backup job
+// created, and backup jobs of all versions are labeled with it.
+func legacyBackupJobSelector(xstoreBackup *polardbxv1.XStoreBackup) client.MatchingLabels {
+	if xstoreBackup.Status.StartTime == nil || xstoreBackup.Status.TargetPod == "" {
+		return nil
+	}
+	return client.MatchingLabels{
+		xstoremeta.JobLabelTargetPod: xstoreBackup.Status.TargetPod,
+	}
+}
 
-		ownedJobs := make([]*batchv1.Job, 0)
-		for i := range jobList.Items {
-			job := &jobList.Items[i]
-			if err = k8shelper.CheckControllerReference(job, xstoreBackup); err == nil {
+// findOwnedBackupJob returns the job of given type controlled by xstore backup among jobs, nil if not found. A job
+// is of given type if it is labeled with typeLabel, or it carries no type label but its name follows the naming
+// convention of given type.
+func findOwnedBackupJob(jobs []batchv1.Job, xstoreBackup *polardbxv1.XStoreBackup,
+	jobType xstoreconvention.BackupJobType, typeLabel string) (*batchv1.Job, error) {
+	ownedJobs := make([]*batchv1.Job, 0)
+	for i := range jobs {
+		job := &jobs[i]
+		if k8shelper.CheckControllerReference(job, xstoreBackup) != nil {
+			continue
+		}
+		if backupName, ok := job.Labels[typeLabel]; ok {
+			if backupName == xstoreBackup.Name {
 				ownedJobs = append(ownedJobs, job)
 			}
+		} else if !hasBackupJobTypeLabel(job) && xstoreconvention.IsBackupJobOfType(job.Name, jobType) {
+			ownedJobs = append(ownedJobs, job)
 		}
+	}
 
-		if len(ownedJobs) == 0 {
-			return nil, nil
-		}
+	if len(ownedJobs) == 0 {
+		return nil, nil
+	}
+	if len(ownedJobs) > 1 {
+		return nil, fmt.Errorf("multiple owned %s jobs found: %s, %s", jobType, ownedJobs[0].Name, ownedJobs[1].Name)
+	}
+	return ownedJobs[0], nil
+}
 
-		if len(ownedJobs) > 1 {
-			panic("multiple owned jobs found, must not happen")
+func hasBackupJobTypeLabel(job *batchv1.Job) bool {
+	for _, label := range []string{
+		xstoremeta.LabelXStoreBackupName,
+		xstoremeta.LabelXStoreCollectName,
+		xstoremeta.LabelXStoreBinlogBackupName,
+	} {
+		if _, ok := job.Labels[label]; ok {
+			return true
 		}
-
-		rc.xstoreBackupJob = ownedJobs[0]
 	}
-	return rc.xstoreBackupJob, nil
+	return false
 }
 
 func (rc *BackupContext) GetXStore() (*polardbxv1.XStore, error) {
@@ -173,6 +269,20 @@ func (rc *BackupContext) GetXStore() (*polardbxv1.XStore, error) {
 	return rc.xstore, nil
 }
 
+// JobDeletePropagation returns the propagation policy to delete jobs of backup, annotation of xstore overrides
+// the operator config.
+func (rc *BackupContext) JobDeletePropagation() metav1.DeletionPropagation {
+	defaultPolicy := metav1.DeletePropagationBackground
+	if rc.xStoreContext != nil {
+		defaultPolicy = rc.xStoreContext.Config().Cluster().JobDeletePropagation()
+	}
+	xstore, err := rc.GetXStore()
+	if err != nil {
+		return defaultPolicy
+	}
+	return k8shelper.JobDeletePropagationOrDefault(xstore.Annotations[xstoremeta.AnnotationJobDeletePropagation], defaultPolicy)
+}
+
 func (rc *BackupContext) MarkXstoreBackupChanged() {
 	rc.xstoreBackupChanged = true
 }
@@ -281,7 +391,6 @@ func (rc *BackupContext) GetXStoreTargetPod() (*corev1.Pod, error) {
 			return rc.xstoreTargetPod, nil
 		}
 
-		// TODO: Take health info and delay into consideration
 		// set target pod for XStoreBackup on which backup will be performed
 		// priority of the target pod: choice made by user > follower > leader
 		pods, err := rc.GetXStorePods()
@@ -304,105 +413,147 @@ func (rc *BackupContext) GetXStoreTargetPod() (*corev1.Pod, error) {
 			return pod, nil
 		}
 
-		// if `PreferredBackupRole` has not been set or set to something other than leader, then we pick follower as backup pod
-		pod, ok := rolePodMap[xstoremeta.RoleFollower]
-		if !ok {
+		// if `PreferredBackupRole` has not been set or set to something other than leader, then we pick a standby
+		// as backup pod, i.e. follower, or learner of read-only xstore, which has a consistent data view
+		standbyPods := StandbyBackupPods(pods)
+		if len(standbyPods) == 0 {
 			return nil, errors.New("target pod is follower, but follower not found")
 		}
-		manager, err := rc.GetXstoreGroupManagerByPod(pod)
-		if err != nil {
-			return nil, err
-		}
-		if manager == nil {
-			return nil, errors.New("fail to connect to follower")
-		}
-		defer manager.Close()
-
-		status, err := manager.ShowSlaveStatus()
-		if err != nil {
-			return nil, err
+		if check := xstoreBackup.Status.TargetPodHealthCheck; check != nil && !check.Healthy {
+			standbyPods = DeprioritizePod(standbyPods, check.Pod)
+		}
+		// with policy LeastLag, all the standby pods are checked to pick the most up-to-date one, otherwise the
+		// first one with a consistent data view is picked
+		leastLag := rc.xStoreContext != nil &&
+			rc.xStoreContext.Config().Backup().GetTargetPodPolicy() == config.TargetPodPolicyLeastLag
+		var candidates []StandbyCandidate
+		var abnormal []string
+		for _, standbyPod := range standbyPods {
+			status, err := rc.ShowStandbyReplication(standbyPod)
+			if err != nil {
+				abnormal = append(abnormal, standbyPod.Name+": "+err.Error())
+				continue
+			}
+			candidates = append(candidates, StandbyCandidate{Pod: standbyPod, Lag: status.SecondsBehindMaster})
+			if !leastLag {
+				break
+			}
 		}
-		if status.SlaveSQLRunning == "No" || status.LastError != "" {
-			return nil, errors.New("follower status abnormal, SlaveSQLRunning: " + status.SlaveSQLRunning +
-				", LastError: " + status.LastError)
+		candidate := PickLeastLagCandidate(candidates)
+		if candidate == nil {
+			return nil, errors.New("standby status abnormal, " + strings.Join(abnormal, "; "))
 		}
-		rc.xstoreTargetPod = pod
+		rc.xstoreTargetPod = candidate.Pod
 	}
 	return rc.xstoreTargetPod, nil
 }
 
-func (rc *BackupContext) GetCollectBinlogJob() (*batchv1.Job, error) {
-	if rc.xstoreCollectJob == nil {
-		xstoreBackup := rc.MustGetXStoreBackup()
-
-		var jobList batchv1.JobList
-		err := rc.Client().List(rc.Context(), &jobList, client.InNamespace(rc.Request().Namespace),
-			client.MatchingLabels{
-				xstoremeta.LabelXStoreCollectName: xstoreBackup.Name,
-			})
-		if err != nil {
-			return nil, err
-		}
+// StandbyCandidate is a standby pod with a consistent data view to perform backup on, along with its replication
+// lag in seconds, nil if unknown.
+type StandbyCandidate struct {
+	Pod *corev1.Pod
+	Lag *int64
+}
 
-		if len(jobList.Items) == 0 {
-			return nil, nil
+// PickLeastLagCandidate picks the candidate with the least replication lag, candidates with unknown lag rank last
+// and the former one is picked among candidates with the same lag. Nil is returned if no candidate.
+func PickLeastLagCandidate(candidates []StandbyCandidate) *StandbyCandidate {
+	var picked *StandbyCandidate
+	for i := range candidates {
+		candidate := &candidates[i]
+		if picked == nil || (candidate.Lag != nil && (picked.Lag == nil || *candidate.Lag < *picked.Lag)) {
+			picked = candidate
 		}
+	}
+	return picked
+}
 
-		ownedJobs := make([]*batchv1.Job, 0)
-		for i := range jobList.Items {
-			job := &jobList.Items[i]
-			if err = k8shelper.CheckControllerReference(job, xstoreBackup); err == nil {
-				ownedJobs = append(ownedJobs, job)
+// StandbyBackupPods returns the pods able to perform backup without promoting, i.e. followers and learners,
+// followers come first. Loggers are excluded since they hold no data.
+func StandbyBackupPods(pods []corev1.Pod) []*corev1.Pod {
+	standbyPods := make([]*corev1.Pod, 0, len(pods))
+	for _, role := range []string{xstoremeta.RoleFollower, xstoremeta.RoleLearner} {
+		for i := range pods {
+			if pods[i].Labels[xstoremeta.LabelRole] == role {
+				standbyPods = append(standbyPods, &pods[i])
 			}
 		}
+	}
+	return standbyPods
+}
 
-		if len(ownedJobs) == 0 {
-			return nil, nil
+// DeprioritizePod moves the named pod to the end of pods, e.g. the pod found unhealthy last time, so that other pods
+// are tried first while it's still a fallback.
+func DeprioritizePod(pods []*corev1.Pod, name string) []*corev1.Pod {
+	sorted := make([]*corev1.Pod, 0, len(pods))
+	var deprioritized *corev1.Pod
+	for _, pod := range pods {
+		if pod.Name == name {
+			deprioritized = pod
+			continue
 		}
+		sorted = append(sorted, pod)
+	}
+	if deprioritized != nil {
+		sorted = append(sorted, deprioritized)
+	}
+	return sorted
+}
 
-		if len(ownedJobs) > 1 {
-			panic("multiple owned jobs found, must not happen")
-		}
+// CheckStandbyDataView checks that the standby pod has a consistent data view to perform backup on, which
+// requires the pod ready and its replication applying without error.
+func (rc *BackupContext) CheckStandbyDataView(pod *corev1.Pod) error {
+	_, err := rc.ShowStandbyReplication(pod)
+	return err
+}
 
-		rc.xstoreCollectJob = ownedJobs[0]
+// ShowStandbyReplication returns the replication status of the standby pod, error returned if the pod has no
+// consistent data view to perform backup on.
+func (rc *BackupContext) ShowStandbyReplication(pod *corev1.Pod) (*group.SlaveStatus, error) {
+	if !k8shelper.IsPodReady(pod) {
+		return nil, errors.New("pod not ready")
+	}
+	manager, err := rc.GetXstoreGroupManagerByPod(pod)
+	if err != nil {
+		return nil, err
+	}
+	if manager == nil {
+		return nil, errors.New("fail to connect to standby")
+	}
+	defer manager.Close()
+
+	status, err := manager.ShowSlaveStatus()
+	if err != nil {
+		return nil, err
+	}
+	if status == nil {
+		return nil, errors.New("replication not found")
+	}
+	if status.SlaveSQLRunning == "No" || status.LastError != "" {
+		return nil, errors.New("replication abnormal, SlaveSQLRunning: " + status.SlaveSQLRunning +
+			", LastError: " + status.LastError)
+	}
+	return status, nil
+}
+
+func (rc *BackupContext) GetCollectBinlogJob() (*batchv1.Job, error) {
+	if rc.xstoreCollectJob == nil {
+		job, err := rc.getOwnedBackupJob(xstoreconvention.BackupJobTypeCollect, xstoremeta.LabelXStoreCollectName)
+		if err != nil || job == nil {
+			return nil, err
+		}
+		rc.xstoreCollectJob = job
 	}
 	return rc.xstoreCollectJob, nil
 }
 
 func (rc *BackupContext) GetBackupBinlogJob() (*batchv1.Job, error) {
 	if rc.xstoreBinlogBackupJob == nil {
-		xstoreBackup := rc.MustGetXStoreBackup()
-
-		var jobList batchv1.JobList
-		err := rc.Client().List(rc.Context(), &jobList, client.InNamespace(rc.Request().Namespace),
-			client.MatchingLabels{
-				xstoremeta.LabelXStoreBinlogBackupName: xstoreBackup.Name,
-			})
-		if err != nil {
+		job, err := rc.getOwnedBackupJob(xstoreconvention.BackupJobTypeBinlogBackup, xstoremeta.LabelXStoreBinlogBackupName)
+		if err != nil || job == nil {
 			return nil, err
 		}
-
-		if len(jobList.Items) == 0 {
-			return nil, nil
-		}
-
-		ownedJobs := make([]*batchv1.Job, 0)
-		for i := range jobList.Items {
-			job := &jobList.Items[i]
-			if err = k8shelper.CheckControllerReference(job, xstoreBackup); err == nil {
-				ownedJobs = append(ownedJobs, job)
-			}
-		}
-
-		if len(ownedJobs) == 0 {
-			return nil, nil
-		}
-
-		if len(ownedJobs) > 1 {
-			panic("multiple owned jobs found, must not happen")
-		}
-
-		rc.xstoreBinlogBackupJob = ownedJobs[0]
+		rc.xstoreBinlogBackupJob = job
 	}
 	return rc.xstoreBinlogBackupJob, nil
 }
@@ -421,11 +572,14 @@ func (rc *BackupContext) GetOrCreateXStoreBackupTaskConfigMap() (*corev1.ConfigM
 		err := rc.Client().Get(rc.Context(), types.NamespacedName{Namespace: rc.Namespace(), Name: name.XStoreBackupStableName(xstorebackup, "backup")}, &cm)
 		if err != nil {
 			if apierrors.IsNotFound(err) {
-				rc.taskConfigMap = NewBackupTaskConfigMap(xstorebackup)
-				err = rc.SetControllerRefAndCreate(rc.taskConfigMap)
+				// Only cache the config map once created, it may have been created by the previous leader
+				// while not synced to cache yet, in which case the creation fails and should be retried.
+				taskConfigMap := NewBackupTaskConfigMap(xstorebackup)
+				err = rc.SetControllerRefAndCreate(taskConfigMap)
 				if err != nil {
 					return nil, err
 				}
+				rc.taskConfigMap = taskConfigMap
 				return rc.taskConfigMap, nil
 			}
 			return nil, err
@@ -480,13 +634,29 @@ func (rc *BackupContext) IsTaskContextExists(key string) (bool, error) {
 	return ok, nil
 }
 
+// DeleteTaskContext removes the task context of key, so that it is built again when required.
+func (rc *BackupContext) DeleteTaskContext(key string) error {
+	cm, err := rc.GetOrCreateXStoreBackupTaskConfigMap()
+	if err != nil {
+		return err
+	}
+	if _, ok := cm.Data[key]; !ok {
+		return nil
+	}
+	delete(cm.Data, key)
+	return rc.Client().Update(rc.Context(), cm)
+}
+
 func (rc *BackupContext) GetTaskContext(key string, t interface{}) error {
 	cm, err := rc.GetOrCreateXStoreBackupTaskConfigMap()
 	if err != nil {
 		return err
 	}
 
-	return json.Unmarshal([]byte(cm.Data[key]), t)
+	if err := json.Unmarshal([]byte(cm.Data[key]), t); err != nil {
+		return fmt.Errorf("%w: key %s, %s", ErrTaskContextCorrupted, key, err.Error())
+	}
+	return nil
 }
 
 func (rc *BackupContext) GetSecret(name string) (*corev1.Secret, error) {
@@ -0,0 +1,255 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	xstoreconvention "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
+)
+
+func newOwnedJob(name string, owner types.UID, labels map[string]string) batchv1.Job {
+	controller := true
+	return batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+			OwnerReferences: []metav1.OwnerReference{
+				{Name: "backup", UID: owner, Controller: &controller},
+			},
+		},
+	}
+}
+
+func TestFindOwnedBackupJob(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	backup := &polardbxv1.XStoreBackup{ObjectMeta: metav1.ObjectMeta{Name: "backup", UID: "uid"}}
+
+	jobs := []batchv1.Job{
+		newOwnedJob("backup-job-pod-0-abcd", "other-uid", map[string]string{xstoremeta.LabelXStoreBackupName: "backup"}),
+		newOwnedJob("binlog-job-pod-0-abcd", "uid", map[string]string{xstoremeta.LabelXStoreBinlogBackupName: "backup"}),
+		newOwnedJob("backup-job-pod-0-efgh", "uid", map[string]string{xstoremeta.LabelXStoreBackupName: "backup"}),
+	}
+	job, err := findOwnedBackupJob(jobs, backup, xstoreconvention.BackupJobTypeFullBackup, xstoremeta.LabelXStoreBackupName)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(job.Name).To(gomega.Equal("backup-job-pod-0-efgh"))
+
+	// job created by previous version of operator, without type label
+	legacyJobs := []batchv1.Job{
+		newOwnedJob("collect-job-pod-0", "uid", nil),
+		newOwnedJob("backup-job-pod-0", "uid", map[string]string{xstoremeta.JobLabelTargetPod: "pod-0"}),
+	}
+	job, err = findOwnedBackupJob(legacyJobs, backup, xstoreconvention.BackupJobTypeFullBackup, xstoremeta.LabelXStoreBackupName)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(job.Name).To(gomega.Equal("backup-job-pod-0"))
+
+	job, err = findOwnedBackupJob(legacyJobs, backup, xstoreconvention.BackupJobTypeBinlogBackup, xstoremeta.LabelXStoreBinlogBackupName)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(job).To(gomega.BeNil())
+
+	_, err = findOwnedBackupJob(append(jobs, legacyJobs...), backup, xstoreconvention.BackupJobTypeFullBackup, xstoremeta.LabelXStoreBackupName)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestLegacyBackupJobSelector(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	backup := &polardbxv1.XStoreBackup{ObjectMeta: metav1.ObjectMeta{Name: "backup", UID: "uid"}}
+
+	// no job can exist before backup started on target pod
+	g.Expect(legacyBackupJobSelector(backup)).To(gomega.BeNil())
+	now := metav1.Now()
+	backup.Status.StartTime = &now
+	g.Expect(legacyBackupJobSelector(backup)).To(gomega.BeNil())
+
+	backup.Status.TargetPod = "pod-0"
+	g.Expect(legacyBackupJobSelector(backup)).To(gomega.Equal(client.MatchingLabels{
+		xstoremeta.JobLabelTargetPod: "pod-0",
+	}))
+}
+
+func TestStandbyBackupPods(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	newPod := func(name, role string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{xstoremeta.LabelRole: role}}}
+	}
+
+	pods := []corev1.Pod{
+		newPod("learner", xstoremeta.RoleLearner),
+		newPod("leader", xstoremeta.RoleLeader),
+		newPod("logger", xstoremeta.RoleLogger),
+		newPod("follower", xstoremeta.RoleFollower),
+	}
+	names := make([]string, 0)
+	for _, pod := range StandbyBackupPods(pods) {
+		names = append(names, pod.Name)
+	}
+	g.Expect(names).To(gomega.Equal([]string{"follower", "learner"}))
+
+	// only leader and logger available, no pod is able to backup without promoting
+	g.Expect(StandbyBackupPods(pods[1:3])).To(gomega.BeEmpty())
+
+	// pod found unhealthy is tried last
+	names = names[:0]
+	for _, pod := range DeprioritizePod(StandbyBackupPods(pods), "follower") {
+		names = append(names, pod.Name)
+	}
+	g.Expect(names).To(gomega.Equal([]string{"learner", "follower"}))
+}
+
+func TestPickLeastLagCandidate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	newCandidate := func(name string, lag *int64) StandbyCandidate {
+		return StandbyCandidate{Pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}, Lag: lag}
+	}
+	lagOf := func(seconds int64) *int64 {
+		return &seconds
+	}
+
+	g.Expect(PickLeastLagCandidate(nil)).To(gomega.BeNil())
+
+	candidates := []StandbyCandidate{
+		newCandidate("unknown", nil),
+		newCandidate("follower-0", lagOf(5)),
+		newCandidate("follower-1", lagOf(0)),
+		newCandidate("follower-2", lagOf(0)),
+	}
+	g.Expect(PickLeastLagCandidate(candidates).Pod.Name).To(gomega.Equal("follower-1"))
+
+	// pods with unknown lag are picked only if no lag is known
+	g.Expect(PickLeastLagCandidate(candidates[:1]).Pod.Name).To(gomega.Equal("unknown"))
+	g.Expect(PickLeastLagCandidate(candidates[:2]).Pod.Name).To(gomega.Equal("follower-0"))
+}
+
+// handoffClient keeps the objects shared by operator replicas. Reads of config maps and jobs can be stale to
+// simulate cache of a new leader not synced yet, other methods are not implemented.
+type handoffClient struct {
+	client.Client
+	backup     *polardbxv1.XStoreBackup
+	configMaps map[string]*corev1.ConfigMap
+	jobs       []batchv1.Job
+	stale      bool
+}
+
+func (c *handoffClient) Get(_ context.Context, key types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+	switch o := obj.(type) {
+	case *polardbxv1.XStoreBackup:
+		c.backup.DeepCopyInto(o)
+	case *corev1.ConfigMap:
+		cm, ok := c.configMaps[key.Name]
+		if !ok || c.stale {
+			return apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, key.Name)
+		}
+		cm.DeepCopyInto(o)
+	}
+	return nil
+}
+
+func (c *handoffClient) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	if jobList, ok := list.(*batchv1.JobList); ok && !c.stale {
+		jobList.Items = append([]batchv1.Job(nil), c.jobs...)
+	}
+	return nil
+}
+
+func (c *handoffClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	cm := obj.(*corev1.ConfigMap)
+	if _, ok := c.configMaps[cm.Name]; ok {
+		return apierrors.NewAlreadyExists(schema.GroupResource{Resource: "configmaps"}, cm.Name)
+	}
+	c.configMaps[cm.Name] = cm.DeepCopy()
+	return nil
+}
+
+func (c *handoffClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	cm := obj.(*corev1.ConfigMap)
+	c.configMaps[cm.Name] = cm.DeepCopy()
+	return nil
+}
+
+// newLeaderBackupContext returns the context of a reconcile on the replica currently holding the leadership.
+func newLeaderBackupContext(c client.Client, scheme *runtime.Scheme) *BackupContext {
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "backup"}}
+	return NewBackupContext(control.NewBaseReconcileContext(c, nil, nil, scheme, context.Background(), request))
+}
+
+func TestBackupResumedAfterLeaderHandoff(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(polardbxv1.AddToScheme(scheme)).To(gomega.Succeed())
+
+	c := &handoffClient{
+		backup: &polardbxv1.XStoreBackup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup", UID: "uid"},
+			Status:     polardbxv1.XStoreBackupStatus{Phase: polardbxv1.XStoreBackupNew},
+		},
+		configMaps: make(map[string]*corev1.ConfigMap),
+	}
+	taskContext := map[string]string{"fullBackupPath": "polardbx-backup/pxc/backup/fullbackup/xstore.xbstream"}
+
+	// The first leader prepares task context and starts the full backup job, then loses leadership before
+	// the phase is persisted.
+	rc := newLeaderBackupContext(c, scheme)
+	g.Expect(rc.SaveTaskContext(xstoreconvention.BackupConfigMapKey, taskContext)).To(gomega.Succeed())
+	c.jobs = append(c.jobs, newOwnedJob("backup-job-pod-0-abcd", "uid",
+		map[string]string{xstoremeta.LabelXStoreBackupName: "backup"}))
+
+	// The new leader starts with its cache not synced, creating the task config map fails without
+	// touching the one created before.
+	c.stale = true
+	rc = newLeaderBackupContext(c, scheme)
+	_, err := rc.IsTaskContextExists(xstoreconvention.BackupConfigMapKey)
+	g.Expect(apierrors.IsAlreadyExists(err)).To(gomega.BeTrue())
+	_, err = rc.IsTaskContextExists(xstoreconvention.BackupConfigMapKey)
+	g.Expect(apierrors.IsAlreadyExists(err)).To(gomega.BeTrue())
+
+	// Once synced, the new leader resumes the New phase with the task context and job of the first leader.
+	c.stale = false
+	rc = newLeaderBackupContext(c, scheme)
+	exists, err := rc.IsTaskContextExists(xstoreconvention.BackupConfigMapKey)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(exists).To(gomega.BeTrue())
+	job, err := rc.GetXStoreBackupJob()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(job.Name).To(gomega.Equal("backup-job-pod-0-abcd"))
+
+	// Leadership changes again after the phase is persisted, the next phase continues with the same state.
+	c.backup.Status.Phase = polardbxv1.XStoreFullBackuping
+	rc = newLeaderBackupContext(c, scheme)
+	g.Expect(rc.MustGetXStoreBackup().Status.Phase).To(gomega.Equal(polardbxv1.XStoreFullBackuping))
+	resumed := make(map[string]string)
+	g.Expect(rc.GetTaskContext(xstoreconvention.BackupConfigMapKey, &resumed)).To(gomega.Succeed())
+	g.Expect(resumed).To(gomega.Equal(taskContext))
+	job, err = rc.GetXStoreBackupJob()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(job.Name).To(gomega.Equal("backup-job-pod-0-abcd"))
+	g.Expect(c.configMaps).To(gomega.HaveLen(1))
+}
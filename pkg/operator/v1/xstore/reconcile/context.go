@@ -75,7 +75,7 @@ type Context struct {
 	hpfsClient hpfs.HpfsServiceClient
 
 	// Filestream client
-	filestreamClient *filestream.FileClient
+	filestreamClient filestream.Client
 
 	// Config
 	configLoader func() config.Config
@@ -790,6 +790,17 @@ func (rc *Context) Config() config.Config {
 	return rc.configLoader()
 }
 
+// JobDeletePropagation returns the propagation policy to delete jobs of xstore, annotation of xstore overrides
+// the operator config.
+func (rc *Context) JobDeletePropagation() metav1.DeletionPropagation {
+	defaultPolicy := rc.Config().Cluster().JobDeletePropagation()
+	xstore, err := rc.GetXStore()
+	if err != nil {
+		return defaultPolicy
+	}
+	return k8shelper.JobDeletePropagationOrDefault(xstore.Annotations[xstoremeta.AnnotationJobDeletePropagation], defaultPolicy)
+}
+
 func (rc *Context) Close() error {
 	errs := make([]error, 0)
 	if rc.hpfsConn != nil {
@@ -1096,7 +1107,12 @@ func (rc *Context) IsTdeEnable() bool {
 	return false
 }
 
-func (rc *Context) GetFilestreamClient() (*filestream.FileClient, error) {
+// SetFilestreamClient overrides the filestream client, e.g. with a fake one in tests.
+func (rc *Context) SetFilestreamClient(filestreamClient filestream.Client) {
+	rc.filestreamClient = filestreamClient
+}
+
+func (rc *Context) GetFilestreamClient() (filestream.Client, error) {
 	if rc.filestreamClient == nil {
 		hostPort := strings.SplitN(rc.Config().Store().FilestreamServiceEndpoint(), ":", 2)
 		if len(hostPort) < 2 {
@@ -1106,7 +1122,18 @@ func (rc *Context) GetFilestreamClient() (*filestream.FileClient, error) {
 		if err != nil {
 			return nil, errors.New("invalid filestream port: " + hostPort[1])
 		}
-		rc.filestreamClient = filestream.NewFileClient(hostPort[0], port, nil)
+		backoff, err := rc.Config().Store().FilestreamConnectBackoff()
+		if err != nil {
+			return nil, errors.New("invalid filestream connect backoff: " + err.Error())
+		}
+
+		// Retry on transient unavailability of filestream server, e.g. hpfs restarting.
+		filestreamClient := filestream.NewFileClient(hostPort[0], port, nil)
+		err = filestream.PingWithRetry(filestreamClient, rc.Config().Store().FilestreamConnectAttempts(), backoff)
+		if err != nil {
+			return nil, err
+		}
+		rc.filestreamClient = filestreamClient
 	}
 	return rc.filestreamClient, nil
 }
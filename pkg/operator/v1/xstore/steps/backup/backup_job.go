@@ -17,17 +17,77 @@ limitations under the License.
 package backup
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
 	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
 	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/command"
+	xstoreconvention "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
 	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
 	"github.com/alibaba/polardbx-operator/pkg/util/name"
+	"golang.org/x/exp/slices"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 )
 
+// setJobActiveDeadline makes the job killed by Kubernetes once it has been active longer than timeout,
+// no deadline is set if timeout is 0.
+func setJobActiveDeadline(job *batchv1.Job, timeout time.Duration) {
+	if timeout > 0 {
+		job.Spec.ActiveDeadlineSeconds = pointer.Int64(int64(timeout.Seconds()))
+	}
+}
+
+// backupJobCommandValues are the values to render command override of backup jobs.
+type backupJobCommandValues struct {
+	*BackupJobContext
+	BackupContext string
+	JobName       string
+	Command       string
+	HeartbeatName string
+	CommitIndex   string
+	XStoreName    string
+	IsGMS         string
+}
+
+// overrideJobContainer overrides name and command of the job container if configured. Each argument of command
+// is rendered as a template with values, in which the default command is available as {{ .Command }}.
+func overrideJobContainer(container *corev1.Container, override *polardbx.BackupJobCommandOverride,
+	command []string, values backupJobCommandValues) error {
+	if override == nil {
+		return nil
+	}
+	if override.ContainerName != "" {
+		container.Name = override.ContainerName
+	}
+	if len(command) == 0 {
+		return nil
+	}
+
+	values.Command = strings.Join(container.Command, " ")
+	rendered := make([]string, 0, len(command))
+	for _, arg := range command {
+		tmpl, err := template.New("command").Parse(arg)
+		if err != nil {
+			return fmt.Errorf("invalid command override %q: %w", arg, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, values); err != nil {
+			return fmt.Errorf("unable to render command override %q: %w", arg, err)
+		}
+		rendered = append(rendered, buf.String())
+	}
+	container.Command = rendered
+	return nil
+}
+
 func replaceSystemEnvs(podSpec *corev1.PodSpec, targetPod *corev1.Pod) {
 	for i := range podSpec.Containers {
 		c := &podSpec.Containers[i]
@@ -78,7 +138,42 @@ func patchTaskConfigMapVolumeAndVolumeMounts(xstoreBackup *xstorev1.XStoreBackup
 	}
 }
 
-func newBackupJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.Pod, jobName string) (*batchv1.Job, error) {
+// backupIncludeDatabases returns databases included by full backup, system databases are always included for
+// the backup set to be able to start up.
+// patchKeyringKeyEnvs passes both keys of keyring re-encryption to the backup job by envs referring the secret, so
+// that keys are never written to the task config map.
+func patchKeyringKeyEnvs(xstoreBackup *xstorev1.XStoreBackup, podSpec *corev1.PodSpec) {
+	reEncryption := xstoreBackup.Spec.KeyringReEncryption
+	if reEncryption == nil {
+		return
+	}
+	container := &podSpec.Containers[0]
+	container.Env = append(container.Env,
+		corev1.EnvVar{
+			Name:      xstoreconvention.EnvKeyringSourceKey,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: reEncryption.SourceKeySelector()},
+		},
+		corev1.EnvVar{
+			Name:      xstoreconvention.EnvKeyringTargetKey,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: reEncryption.TargetKeySelector()},
+		},
+	)
+}
+
+func backupIncludeDatabases(xstoreBackup *xstorev1.XStoreBackup) []string {
+	if len(xstoreBackup.Spec.IncludeDatabases) == 0 {
+		return nil
+	}
+	databases := append([]string(nil), polardbx.SystemDatabases...)
+	for _, db := range xstoreBackup.Spec.IncludeDatabases {
+		if !slices.Contains(databases, db) {
+			databases = append(databases, db)
+		}
+	}
+	return databases
+}
+
+func newBackupJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.Pod, jobName string, backupJobContext *BackupJobContext) (*batchv1.Job, error) {
 	podSpec := targetPod.Spec.DeepCopy()
 	podSpec.InitContainers = nil
 	podSpec.RestartPolicy = corev1.RestartPolicyNever
@@ -89,8 +184,10 @@ func newBackupJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.Pod, jo
 	}
 	podSpec.Containers[0].Name = "backupjob"
 
+	includeDatabases := backupIncludeDatabases(xstoreBackup)
+
 	podSpec.Containers[0].Command = command.NewCanonicalCommandBuilder().Backup().
-		StartBackup("/backup/backup", jobName).Build()
+		StartBackup("/backup/backup", jobName, includeDatabases, xstoreBackup.Spec.ExcludeDatabases).Build()
 	podSpec.Containers[0].Resources.Limits = nil
 	podSpec.Containers[0].Resources.Requests = nil
 	podSpec.Containers[0].Ports = nil
@@ -102,9 +199,23 @@ func newBackupJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.Pod, jo
 		podSpec.Containers[0].Lifecycle.PreStop = nil
 	}
 
+	override := xstoreBackup.Spec.BackupJobCommandOverride
+	if override != nil {
+		err := overrideJobContainer(&podSpec.Containers[0], override, override.FullBackup, backupJobCommandValues{
+			BackupJobContext: backupJobContext,
+			BackupContext:    "/backup/backup",
+			JobName:          jobName,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Replace system envs
 	replaceSystemEnvs(podSpec, targetPod)
 	patchTaskConfigMapVolumeAndVolumeMounts(xstoreBackup, podSpec)
+	patchKeyringKeyEnvs(xstoreBackup, podSpec)
+	patchEncryptionKeyEnv(xstoreBackup, podSpec)
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
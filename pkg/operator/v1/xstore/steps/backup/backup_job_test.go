@@ -0,0 +1,110 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+)
+
+func newJobTargetPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-0"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "engine", Command: []string{"/bin/engine"}}},
+		},
+	}
+}
+
+func TestNewBackupJobWithCommandOverride(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	xstoreBackup := &xstorev1.XStoreBackup{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup"}}
+	backupJobContext := &BackupJobContext{FullBackupPath: "polardbx-backup/pxc/backup/fullbackup/xstore.xbstream"}
+
+	job, err := newBackupJob(xstoreBackup, newJobTargetPod(), "backup-job", backupJobContext)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defaultCommand := job.Spec.Template.Spec.Containers[0].Command
+	g.Expect(job.Spec.Template.Spec.Containers[0].Name).To(gomega.Equal("backupjob"))
+
+	xstoreBackup.Spec.BackupJobCommandOverride = &polardbx.BackupJobCommandOverride{
+		ContainerName: "custom",
+		FullBackup:    []string{"/opt/wrapper.sh", "{{ .FullBackupPath }}", "{{ .JobName }}", "{{ .Command }}"},
+	}
+	job, err = newBackupJob(xstoreBackup, newJobTargetPod(), "backup-job", backupJobContext)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	container := job.Spec.Template.Spec.Containers[0]
+	g.Expect(container.Name).To(gomega.Equal("custom"))
+	g.Expect(container.Command).To(gomega.Equal([]string{"/opt/wrapper.sh", backupJobContext.FullBackupPath,
+		"backup-job", strings.Join(defaultCommand, " ")}))
+
+	// only container name overridden, the default command is kept
+	xstoreBackup.Spec.BackupJobCommandOverride.FullBackup = nil
+	job, err = newBackupJob(xstoreBackup, newJobTargetPod(), "backup-job", backupJobContext)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(job.Spec.Template.Spec.Containers[0].Command).To(gomega.Equal(defaultCommand))
+
+	xstoreBackup.Spec.BackupJobCommandOverride.FullBackup = []string{"{{ .Unknown }}"}
+	_, err = newBackupJob(xstoreBackup, newJobTargetPod(), "backup-job", backupJobContext)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestNewBackupJobWithDatabaseFilter(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	xstoreBackup := &xstorev1.XStoreBackup{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup"}}
+	g.Expect(backupIncludeDatabases(xstoreBackup)).To(gomega.BeNil())
+
+	xstoreBackup.Spec.IncludeDatabases = []string{"d1", "mysql"}
+	xstoreBackup.Spec.ExcludeDatabases = []string{"d2"}
+	g.Expect(backupIncludeDatabases(xstoreBackup)).To(gomega.Equal([]string{"mysql", "sys", "d1"}))
+
+	job, err := newBackupJob(xstoreBackup, newJobTargetPod(), "backup-job", &BackupJobContext{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(strings.Join(job.Spec.Template.Spec.Containers[0].Command, " ")).To(gomega.ContainSubstring(
+		"--include_database mysql --include_database sys --include_database d1 --exclude_database d2"))
+}
+
+func TestNewBackupJobWithKeyringReEncryption(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	xstoreBackup := &xstorev1.XStoreBackup{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup"}}
+	xstoreBackup.Spec.KeyringReEncryption = &polardbx.KeyringReEncryption{SecretName: "keys", TargetKey: "new"}
+
+	job, err := newBackupJob(xstoreBackup, newJobTargetPod(), "backup-job", &BackupJobContext{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	env := job.Spec.Template.Spec.Containers[0].Env
+	g.Expect(env).To(gomega.HaveLen(2))
+	g.Expect(env[0].ValueFrom.SecretKeyRef.Name).To(gomega.Equal("keys"))
+	g.Expect(env[0].ValueFrom.SecretKeyRef.Key).To(gomega.Equal(polardbx.DefaultKeyringSourceKey))
+	g.Expect(env[1].ValueFrom.SecretKeyRef.Key).To(gomega.Equal("new"))
+
+	secret := &corev1.Secret{Data: map[string][]byte{polardbx.DefaultKeyringSourceKey: []byte("old")}}
+	_, err = xstoreBackup.Spec.KeyringReEncryption.ValidateKeys(secret)
+	g.Expect(err).To(gomega.HaveOccurred())
+	secret.Data["new"] = []byte("old")
+	_, err = xstoreBackup.Spec.KeyringReEncryption.ValidateKeys(secret)
+	g.Expect(err).To(gomega.HaveOccurred())
+	secret.Data["new"] = []byte("new")
+	fingerprint, err := xstoreBackup.Spec.KeyringReEncryption.ValidateKeys(secret)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(fingerprint).To(gomega.Equal(polardbx.KeyFingerprint([]byte("new"))))
+}
@@ -28,7 +28,7 @@ import (
 	"strconv"
 )
 
-func newBinlogBackupJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.Pod, jobName string, isGMS bool) (*batchv1.Job, error) {
+func newBinlogBackupJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.Pod, jobName string, isGMS bool, backupJobContext *BackupJobContext) (*batchv1.Job, error) {
 	podSpec := targetPod.Spec.DeepCopy()
 	podSpec.InitContainers = nil
 	podSpec.RestartPolicy = corev1.RestartPolicyNever
@@ -45,8 +45,9 @@ func newBinlogBackupJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.P
 	if isGMS {
 		gmsLabel = "true"
 	}
+	commitIndex := strconv.FormatInt(CommitIndex, 10)
 	podSpec.Containers[0].Command = command.NewCanonicalCommandBuilder().BinlogBackup().
-		StartBinlogBackup("/backup/backup", strconv.FormatInt(CommitIndex, 10), xstoreName, gmsLabel).Build()
+		StartBinlogBackup("/backup/backup", commitIndex, xstoreName, gmsLabel).Build()
 	podSpec.Containers[0].Resources.Limits = nil
 	podSpec.Containers[0].Resources.Requests = nil
 	podSpec.Containers[0].Ports = nil
@@ -54,9 +55,25 @@ func newBinlogBackupJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.P
 		podSpec.Containers[0].Lifecycle.PreStop = nil
 	}
 
+	override := xstoreBackup.Spec.BackupJobCommandOverride
+	if override != nil {
+		err := overrideJobContainer(&podSpec.Containers[0], override, override.BinlogBackup, backupJobCommandValues{
+			BackupJobContext: backupJobContext,
+			BackupContext:    "/backup/backup",
+			JobName:          jobName,
+			CommitIndex:      commitIndex,
+			XStoreName:       xstoreName,
+			IsGMS:            gmsLabel,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Replace system envs
 	replaceSystemEnvs(podSpec, targetPod)
 	patchTaskConfigMapVolumeAndVolumeMounts(xstoreBackup, podSpec)
+	patchEncryptionKeyEnv(xstoreBackup, podSpec)
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -7,6 +7,7 @@ import (
 	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
 	hpfs "github.com/alibaba/polardbx-operator/pkg/hpfs/proto"
 	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
 	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -14,10 +15,17 @@ import (
 var CleanRemoteBackupFiles = NewStepBinder("CleanRemoteBackupFiles",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		backup := rc.MustGetXStoreBackup()
+		if IsBackupProtected(backup) {
+			return flow.Continue("Backup is protected, remote backup files retained.")
+		}
 		if backup.Spec.CleanPolicy == polardbx.CleanPolicyRetain ||
 			(backup.Spec.CleanPolicy == polardbx.CleanPolicyOnFailure && backup.Status.Phase != v1.XstoreBackupFailed) {
 			return flow.Continue("No need to clean remote backup files.")
 		}
+		if backup.Status.BackupRootPath == "" {
+			// root path is not assigned, or dropped due to collision with another backup set
+			return flow.Continue("No remote backup files to clean.")
+		}
 
 		client, err := rc.XStoreContext().GetHpfsClient()
 		if err != nil {
@@ -42,3 +50,9 @@ var CleanRemoteBackupFiles = NewStepBinder("CleanRemoteBackupFiles",
 
 		return flow.Continue("Remote backup files cleaned.")
 	})
+
+// IsBackupProtected checks whether backup is pinned by annotation, which exempts it from retention deletion and
+// its remote files from cleanup.
+func IsBackupProtected(backup *v1.XStoreBackup) bool {
+	return backup.Annotations[xstoremeta.AnnotationProtectedBackup] == "true"
+}
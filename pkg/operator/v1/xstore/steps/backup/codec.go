@@ -0,0 +1,128 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	xstoreconvention "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
+	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
+)
+
+// Codec returns the codec applied on files of artifact. Both full backup job and binlog backup job read the same
+// job context, so that the backup set is uniformly processed.
+func (c *BackupJobContext) Codec(artifact polardbx.BackupArtifact, keyFingerprint string) *polardbx.BackupCodec {
+	var encryption *polardbx.BackupEncryption
+	if c.Encryption != "" {
+		encryption = &polardbx.BackupEncryption{Algorithm: polardbx.BackupEncryptionAlgorithm(c.Encryption)}
+	}
+	codec := polardbx.NewBackupCodec(artifact, polardbx.BackupCompression(c.Compression), encryption, keyFingerprint)
+	return &codec
+}
+
+// patchEncryptionKeyEnv passes the encryption key to backup jobs by env referring the secret, so that the key is
+// never written to the task config map.
+func patchEncryptionKeyEnv(xstoreBackup *xstorev1.XStoreBackup, podSpec *corev1.PodSpec) {
+	encryption := xstoreBackup.Spec.Encryption
+	if encryption == nil {
+		return
+	}
+	container := &podSpec.Containers[0]
+	container.Env = append(container.Env, corev1.EnvVar{
+		Name:      xstoreconvention.EnvBackupEncryptionKey,
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: encryption.KeySelector()},
+	})
+}
+
+// encryptionKeyFingerprint returns the fingerprint of encryption key if files are encrypted by job context, the
+// backup is failed with reason if the key is unavailable. done is false if the backup should go on.
+func encryptionKeyFingerprint(rc *xstorev1reconcile.BackupContext, flow control.Flow,
+	backupJobContext *BackupJobContext) (fingerprint string, result reconcile.Result, done bool) {
+	if backupJobContext.Encryption == "" {
+		return "", reconcile.Result{}, false
+	}
+	xstoreBackup := rc.MustGetXStoreBackup()
+	encryption := xstoreBackup.Spec.Encryption
+	var err error
+	if encryption == nil {
+		err = errors.New("encryption removed from spec since backup started")
+	} else {
+		var secret *corev1.Secret
+		secret, err = rc.GetSecret(encryption.SecretName)
+		if err != nil && !apierrors.IsNotFound(err) {
+			result, _ = flow.RetryAfter(5*time.Second, "Unable to get secret of encryption key, error: "+err.Error(),
+				"secret", encryption.SecretName)
+			return "", result, true
+		}
+		if err == nil {
+			fingerprint, err = encryption.ValidateKey(secret)
+		}
+	}
+	if err != nil {
+		xstoreBackup.Status.Phase = xstorev1.XstoreBackupFailed
+		xstoreBackup.Status.Reason = "EncryptionKeyUnavailable"
+		xstoreBackup.Status.FailureReason = polardbx.FailureReasonInvalidSpec
+		xstoreBackup.Status.Message = "encryption key is unavailable: " + err.Error()
+		result, _ = flow.Retry("Encryption key unavailable, backup failed.")
+		return "", result, true
+	}
+	return fingerprint, reconcile.Result{}, false
+}
+
+// refuseBackupIfEncryptionKeyUnavailable fails the backup requesting encryption if the key is unavailable,
+// otherwise the codec of full backup is recorded. done is false if the backup should go on.
+func refuseBackupIfEncryptionKeyUnavailable(rc *xstorev1reconcile.BackupContext, flow control.Flow,
+	backupJobContext *BackupJobContext) (result reconcile.Result, done bool) {
+	fingerprint, result, done := encryptionKeyFingerprint(rc, flow, backupJobContext)
+	if done {
+		return result, true
+	}
+	xstoreBackup := rc.MustGetXStoreBackup()
+	xstoreBackup.Status.FullBackupCodec = backupJobContext.Codec(polardbx.BackupArtifactFullBackup, fingerprint)
+	return reconcile.Result{}, false
+}
+
+// failBackupIfEncryptionKeyChanged fails the backup if the encryption key changed since full backup, since binlogs
+// encrypted by another key can not be restored along with the full backup. Otherwise the codec of binlogs is
+// recorded. done is false if the backup should go on.
+func failBackupIfEncryptionKeyChanged(rc *xstorev1reconcile.BackupContext, flow control.Flow,
+	backupJobContext *BackupJobContext) (result reconcile.Result, done bool) {
+	fingerprint, result, done := encryptionKeyFingerprint(rc, flow, backupJobContext)
+	if done {
+		return result, true
+	}
+	xstoreBackup := rc.MustGetXStoreBackup()
+	if fullBackupCodec := xstoreBackup.Status.FullBackupCodec; fullBackupCodec != nil &&
+		fullBackupCodec.KeyFingerprint != fingerprint {
+		xstoreBackup.Status.Phase = xstorev1.XstoreBackupFailed
+		xstoreBackup.Status.Reason = "EncryptionKeyChanged"
+		xstoreBackup.Status.FailureReason = polardbx.FailureReasonInvalidSpec
+		xstoreBackup.Status.Message = "encryption key changed since full backup, fingerprint: " + fingerprint
+		result, _ = flow.Retry("Encryption key changed since full backup, backup failed.")
+		return result, true
+	}
+	xstoreBackup.Status.BinlogBackupCodec = backupJobContext.Codec(polardbx.BackupArtifactBinlog, fingerprint)
+	return reconcile.Result{}, false
+}
@@ -27,7 +27,9 @@ import (
 	"k8s.io/utils/pointer"
 )
 
-func newCollectJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.Pod, polarDBXBackup xstorev1.PolarDBXBackup, jobName string) (*batchv1.Job, error) {
+// newCollectJob creates the job to collect binlog events of the target pod. With collect parallelism greater than 1,
+// the job runs in indexed completion mode, each pod collects the sub-range identified by its completion index.
+func newCollectJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.Pod, polarDBXBackup xstorev1.PolarDBXBackup, jobName string, backupJobContext *BackupJobContext) (*batchv1.Job, error) {
 	podSpec := targetPod.Spec.DeepCopy()
 	podSpec.InitContainers = nil
 	podSpec.RestartPolicy = corev1.RestartPolicyNever
@@ -48,6 +50,19 @@ func newCollectJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.Pod, p
 		podSpec.Containers[0].Lifecycle.PreStop = nil
 	}
 
+	override := xstoreBackup.Spec.BackupJobCommandOverride
+	if override != nil {
+		err := overrideJobContainer(&podSpec.Containers[0], override, override.Collect, backupJobCommandValues{
+			BackupJobContext: backupJobContext,
+			BackupContext:    "/backup/backup",
+			JobName:          jobName,
+			HeartbeatName:    heartBeatName,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Replace system envs
 	replaceSystemEnvs(podSpec, targetPod)
 	patchTaskConfigMapVolumeAndVolumeMounts(xstoreBackup, podSpec)
@@ -76,5 +91,11 @@ func newCollectJob(xstoreBackup *xstorev1.XStoreBackup, targetPod *corev1.Pod, p
 			},
 		},
 	}
+	if parallelism := backupJobContext.CollectParallelism; parallelism > 1 {
+		completionMode := batchv1.IndexedCompletion
+		job.Spec.CompletionMode = &completionMode
+		job.Spec.Completions = pointer.Int32(parallelism)
+		job.Spec.Parallelism = pointer.Int32(parallelism)
+	}
 	return job, nil
 }
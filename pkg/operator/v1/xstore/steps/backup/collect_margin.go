@@ -0,0 +1,128 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
+	"github.com/alibaba/polardbx-operator/pkg/util/path"
+)
+
+// ReasonBinlogCollectMarginPurged is the failure reason of backup whose collect margin reaches purged binlog.
+const ReasonBinlogCollectMarginPurged = "BinlogCollectMarginPurged"
+
+// binlogFileOffset is where binlog events begin in each binlog file, right after the magic number.
+const binlogFileOffset = "4"
+
+// indexOfBinlogFile returns the position of binlog file of index "<binlog file>:<offset>" among binlog files, -1
+// if not found.
+func indexOfBinlogFile(binlogIndex string, binlogFiles []string) int {
+	binlogFile := strings.SplitN(binlogIndex, ":", 2)[0]
+	for i, f := range binlogFiles {
+		if f == binlogFile {
+			return i
+		}
+	}
+	return -1
+}
+
+// extendCollectStartIndex moves the start index back by lookback binlog files, binlog files available are listed
+// from the oldest to the latest. Error is returned if the margin reaches binlog files purged.
+func extendCollectStartIndex(startIndex string, lookback int32, binlogFiles []string) (string, error) {
+	if lookback <= 0 {
+		return startIndex, nil
+	}
+	i := indexOfBinlogFile(startIndex, binlogFiles)
+	if i < 0 {
+		return "", fmt.Errorf("binlog of start index %s has been purged", startIndex)
+	}
+	if i < int(lookback) {
+		return "", fmt.Errorf("lookback of %d binlog files from start index %s reaches purged binlog, "+
+			"oldest available binlog is %s", lookback, startIndex, binlogFiles[0])
+	}
+	return binlogFiles[i-int(lookback)] + ":" + binlogFileOffset, nil
+}
+
+// extendCollectEndFile returns the binlog file where collecting ends after moving forward by lookahead binlog
+// files, which is capped at the latest one. ok is false if the end is not moved.
+func extendCollectEndFile(endIndex string, lookahead int32, binlogFiles []string) (binlogFile string, ok bool) {
+	i := indexOfBinlogFile(endIndex, binlogFiles)
+	if lookahead <= 0 || i < 0 || i == len(binlogFiles)-1 {
+		return "", false
+	}
+	target := i + int(lookahead)
+	if target > len(binlogFiles)-1 {
+		target = len(binlogFiles) - 1
+	}
+	return binlogFiles[target], true
+}
+
+// newBinlogFileSizeCommand returns the command printing size of binlog file under log directory of data dir.
+func newBinlogFileSizeCommand(dataDir, binlogFile string) []string {
+	return []string{"stat", "-c", "%s", path.JoinPath(dataDir, "log", binlogFile)}
+}
+
+// applyBinlogCollectMargin extends the collect range on target pod by margin of backup, the effective range is
+// returned. Error of margin reaching purged binlog is told apart by purged.
+func applyBinlogCollectMargin(rc *xstorev1reconcile.BackupContext, targetPod *corev1.Pod,
+	margin *polardbxv1polardbx.BinlogCollectMargin, startIndex, endIndex string) (start, end string, purged bool, err error) {
+	if margin == nil || (margin.LookbackFiles <= 0 && margin.LookaheadFiles <= 0) {
+		return startIndex, endIndex, false, nil
+	}
+	groupManager, err := rc.GetXstoreGroupManagerByPod(targetPod)
+	if err != nil {
+		return "", "", false, err
+	}
+	if groupManager == nil {
+		return "", "", false, fmt.Errorf("group manager of pod %s not found", targetPod.Name)
+	}
+	defer groupManager.Close()
+	binlogFiles, err := groupManager.ShowBinaryLogs()
+	if err != nil {
+		return "", "", false, err
+	}
+	start, err = extendCollectStartIndex(startIndex, margin.LookbackFiles, binlogFiles)
+	if err != nil {
+		return "", "", true, err
+	}
+	endFile, ok := extendCollectEndFile(endIndex, margin.LookaheadFiles, binlogFiles)
+	if !ok {
+		return start, endIndex, false, nil
+	}
+	// the latest binlog file is still being written, ends at the current offset instead
+	if endFile == binlogFiles[len(binlogFiles)-1] {
+		end, err = groupManager.GetBinlogOffset()
+		return start, end, false, err
+	}
+	stdout := &bytes.Buffer{}
+	err = rc.ExecuteCommandOn(targetPod, "engine", newBinlogFileSizeCommand(engineDataDir(rc), endFile),
+		control.ExecOptions{
+			Stdout: stdout,
+			Stderr: &bytes.Buffer{},
+		})
+	if err != nil {
+		return "", "", false, err
+	}
+	return start, endFile + ":" + strings.TrimSpace(stdout.String()), false, nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
+)
+
+// lastSuccessfulBackupTimestamp exports the end time of the last successful backup of each xstore, which allows
+// to alert on stale backups, e.g. time() - polardbx_xstore_last_successful_backup_timestamp > 86400.
+var lastSuccessfulBackupTimestamp = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "polardbx_xstore_last_successful_backup_timestamp",
+		Help: "Unix timestamp in seconds of the end time of the last successful backup of xstore.",
+	},
+	[]string{"namespace", "xstore"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(lastSuccessfulBackupTimestamp)
+}
+
+// lastSuccessfulBackups keeps the timestamps exported, so that finished backups reconciled out of order, e.g.
+// after operator restarted, never move the timestamp backwards.
+var lastSuccessfulBackups = struct {
+	sync.Mutex
+	timestamps map[string]time.Time
+}{timestamps: make(map[string]time.Time)}
+
+// observeSuccessfulBackup exports the end time of successful backup of xstore if it's later than the exported
+// one, and returns the timestamp exported.
+func observeSuccessfulBackup(namespace, xstoreName string, endTime time.Time) time.Time {
+	lastSuccessfulBackups.Lock()
+	defer lastSuccessfulBackups.Unlock()
+
+	key := namespace + "/" + xstoreName
+	if last, ok := lastSuccessfulBackups.timestamps[key]; ok && !endTime.After(last) {
+		return last
+	}
+	lastSuccessfulBackups.timestamps[key] = endTime
+	lastSuccessfulBackupTimestamp.WithLabelValues(namespace, xstoreName).Set(float64(endTime.Unix()))
+	return endTime
+}
+
+// RecordLastSuccessfulBackup exports the end time of finished backup as the last successful backup of xstore.
+// It's executed on every reconciliation of finished backups, so that the metric is restored after operator
+// restarted.
+var RecordLastSuccessfulBackup = NewStepBinder("RecordLastSuccessfulBackup",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		if !backup.DeletionTimestamp.IsZero() {
+			return flow.Pass()
+		}
+		endTime := backup.CreationTimestamp.Time
+		if backup.Status.EndTime != nil {
+			endTime = backup.Status.EndTime.Time
+		}
+		observeSuccessfulBackup(backup.Namespace, backup.Spec.XStore.Name, endTime)
+		return flow.Pass()
+	})
@@ -0,0 +1,50 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestObserveSuccessfulBackup(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	exported := func(namespace, xstoreName string) float64 {
+		var m dto.Metric
+		g.Expect(lastSuccessfulBackupTimestamp.WithLabelValues(namespace, xstoreName).Write(&m)).To(gomega.Succeed())
+		return m.GetGauge().GetValue()
+	}
+
+	t1 := time.Unix(1700000000, 0)
+	t2 := t1.Add(24 * time.Hour)
+	g.Expect(observeSuccessfulBackup("default", "pxc-dn-0", t1)).To(gomega.Equal(t1))
+	g.Expect(exported("default", "pxc-dn-0")).To(gomega.Equal(float64(t1.Unix())))
+
+	g.Expect(observeSuccessfulBackup("default", "pxc-dn-0", t2)).To(gomega.Equal(t2))
+	g.Expect(exported("default", "pxc-dn-0")).To(gomega.Equal(float64(t2.Unix())))
+
+	// earlier backup reconciled later never moves the timestamp backwards
+	g.Expect(observeSuccessfulBackup("default", "pxc-dn-0", t1)).To(gomega.Equal(t2))
+	g.Expect(exported("default", "pxc-dn-0")).To(gomega.Equal(float64(t2.Unix())))
+
+	// xstores are tracked separately
+	g.Expect(observeSuccessfulBackup("other", "pxc-dn-0", t1)).To(gomega.Equal(t1))
+	g.Expect(exported("other", "pxc-dn-0")).To(gomega.Equal(float64(t1.Unix())))
+}
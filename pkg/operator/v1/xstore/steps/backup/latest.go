@@ -0,0 +1,76 @@
+package backup
+
+import (
+	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
+	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// isLaterBackup tells whether the finished backup is later than the current latest backup, which is nil if not
+// found. Backups are ordered by start time as restore by time does, and by name if started at the same time.
+func isLaterBackup(backup, current *xstorev1.XStoreBackup) bool {
+	if current == nil || current.Status.Phase != xstorev1.XStoreBackupFinished || current.Status.StartTime == nil {
+		return true
+	}
+	if backup.Status.StartTime == nil {
+		return false
+	}
+	if backup.Status.StartTime.Equal(current.Status.StartTime) {
+		return backup.Name > current.Name
+	}
+	return current.Status.StartTime.Before(backup.Status.StartTime)
+}
+
+// MarkLatestBackupSet points the latest backup annotation of xstore to the finished backup if it's later than
+// the current one. The annotation is replaced with optimistic lock, so that concurrently finished backups
+// never overwrite a later one.
+var MarkLatestBackupSet = NewStepBinder("MarkLatestBackupSet",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		if !backup.DeletionTimestamp.IsZero() {
+			return flow.Pass()
+		}
+		xstore, err := rc.GetXStore()
+		if apierrors.IsNotFound(err) {
+			return flow.Continue("XStore not found, skip marking latest backup set.")
+		}
+		if err != nil {
+			return flow.Error(err, "Unable to get xstore.")
+		}
+
+		currentName := xstore.Annotations[xstoremeta.AnnotationLatestBackup]
+		if currentName == backup.Name {
+			return flow.Pass()
+		}
+		var current *xstorev1.XStoreBackup
+		if currentName != "" {
+			current = &xstorev1.XStoreBackup{}
+			err := rc.Client().Get(rc.Context(), types.NamespacedName{Namespace: backup.Namespace, Name: currentName}, current)
+			if apierrors.IsNotFound(err) {
+				current = nil
+			} else if err != nil {
+				return flow.Error(err, "Unable to get current latest backup.", "backup", currentName)
+			}
+		}
+		if !isLaterBackup(backup, current) {
+			return flow.Continue("Latest backup set is later.", "latest", currentName)
+		}
+
+		patch := client.MergeFromWithOptions(xstore.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		if xstore.Annotations == nil {
+			xstore.Annotations = make(map[string]string)
+		}
+		xstore.Annotations[xstoremeta.AnnotationLatestBackup] = backup.Name
+		if err := rc.Client().Patch(rc.Context(), xstore, patch); err != nil {
+			if apierrors.IsConflict(err) {
+				return flow.Retry("XStore changed, retry marking latest backup set.")
+			}
+			return flow.Error(err, "Unable to mark latest backup set.")
+		}
+		return flow.Continue("Latest backup set marked.", "previous", currentName)
+	})
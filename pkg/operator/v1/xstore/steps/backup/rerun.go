@@ -0,0 +1,274 @@
+package backup
+
+import (
+	"fmt"
+	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	xstoreconvention "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
+	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
+	"github.com/alibaba/polardbx-operator/pkg/util/path"
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
+)
+
+// isRemoteFileExisted lists the parent directory of filePath in the storage of backup and checks whether the file exists
+func isRemoteFileExisted(rc *xstorev1reconcile.BackupContext, backup *xstorev1.XStoreBackup, filePath string) (bool, error) {
+	filestreamClient, err := rc.XStoreContext().GetFilestreamClient()
+	if err != nil {
+		return false, err
+	}
+	filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(backup.Spec.StorageProvider.StorageName)
+	if err != nil {
+		return false, err
+	}
+	return filestreamClient.Exists(filestream.ActionMetadata{
+		Action:    filestreamAction.List,
+		Sink:      backup.Spec.StorageProvider.Sink,
+		RequestId: uuid.New().String(),
+		Filepath:  filePath,
+	})
+}
+
+func consumeRerunBinlogBackupAnnotation(rc *xstorev1reconcile.BackupContext, backup *xstorev1.XStoreBackup, message string) {
+	delete(backup.Annotations, xstoremeta.AnnotationRerunBinlogBackup)
+	rc.MarkXstoreBackupChanged()
+	backup.Status.Message = message
+}
+
+// isBinlogBackupRerunnable tells whether binlog backup phase of backup can be rerun, i.e. the backup is either in
+// binlog backup phase, or failed after the full backup completed.
+func isBinlogBackupRerunnable(backup *xstorev1.XStoreBackup) bool {
+	switch backup.Status.Phase {
+	case xstorev1.XStoreBinlogBackuping:
+		return true
+	case xstorev1.XstoreBackupFailed:
+		return hasBackupCondition(backup, xstorev1.XStoreBackupFullBackupComplete, corev1.ConditionTrue)
+	default:
+		return false
+	}
+}
+
+// resetBinlogBackupRerun turns the backup back to binlog backup phase with failure cleared.
+func resetBinlogBackupRerun(backup *xstorev1.XStoreBackup) {
+	backup.Status.Phase = xstorev1.XStoreBinlogBackuping
+	backup.Status.Reason = ""
+	backup.Status.FailureReason = ""
+	backup.Status.Message = ""
+}
+
+// PrepareBinlogBackupRerun removes the binlog backup job so that binlog backup phase can be restarted, full backup
+// artifact and task config map are reused. Backup failed after full backup completed is turned back to binlog
+// backup phase. The rerun is rejected if the full backup artifact no longer exists.
+var PrepareBinlogBackupRerun = NewStepBinder("PrepareBinlogBackupRerun",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		if !isBinlogBackupRerunnable(backup) {
+			consumeRerunBinlogBackupAnnotation(rc, backup,
+				"binlog backup rerun rejected, backup is neither in phase "+string(xstorev1.XStoreBinlogBackuping)+
+					" nor failed after full backup completed")
+			return flow.Continue("Binlog backup rerun rejected.", "phase", backup.Status.Phase)
+		}
+
+		backupJobContext, err := getBackupJobContext(rc, flow.Logger())
+		if err != nil {
+			return flow.Error(err, "Unable to get task context for backup")
+		}
+		fullBackupPath := backupJobContext.FullBackupPath
+		if backup.Status.Archive != nil {
+			// full backup is packed in archive
+			fullBackupPath = path.JoinPath(backup.Status.BackupRootPath, backup.Status.Archive.Path)
+		}
+		exists, err := isRemoteFileExisted(rc, backup, fullBackupPath)
+		if err != nil {
+			return flow.RetryAfter(10*time.Second, "Failed to check full backup artifact, error: "+err.Error())
+		}
+		if !exists {
+			consumeRerunBinlogBackupAnnotation(rc, backup,
+				"binlog backup rerun rejected, full backup artifact not found: "+fullBackupPath)
+			return flow.Continue("Binlog backup rerun rejected.", "full-backup-path", fullBackupPath)
+		}
+
+		job, err := rc.GetBackupBinlogJob()
+		if client.IgnoreNotFound(err) != nil {
+			return flow.Error(err, "Unable to get binlog backup job!")
+		}
+		if job != nil {
+			propagation := rc.JobDeletePropagation()
+			if job.DeletionTimestamp.IsZero() {
+				err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(propagation))
+				if client.IgnoreNotFound(err) != nil {
+					return flow.Error(err, "Unable to remove binlog backup job", "job-name", job.Name)
+				}
+			}
+			if propagation == metav1.DeletePropagationForeground {
+				return flow.RetryAfter(5*time.Second, "Wait until binlog backup job removed.", "job-name", job.Name)
+			}
+		}
+
+		consumeRerunBinlogBackupAnnotation(rc, backup, "")
+		resetBinlogBackupRerun(backup)
+		return flow.Continue("Binlog backup rerun prepared.")
+	})
+
+func consumeAbortFullBackupAnnotation(rc *xstorev1reconcile.BackupContext, backup *xstorev1.XStoreBackup, message string) {
+	delete(backup.Annotations, xstoremeta.AnnotationAbortFullBackup)
+	rc.MarkXstoreBackupChanged()
+	backup.Status.Message = message
+}
+
+// recordFullBackupAbort resets status of backup to re-attempt full backup from scratch, so that the target pod
+// is selected again, and records the manual abort.
+func recordFullBackupAbort(backup *xstorev1.XStoreBackup, now metav1.Time) {
+	backup.Status.Phase = xstorev1.XStoreBackupNew
+	backup.Status.TargetPod = ""
+	backup.Status.TargetPodLagSeconds = nil
+	backup.Status.FullBackupAborts++
+	backup.Status.LastFullBackupAbortTime = &now
+	backup.Status.Message = fmt.Sprintf("full backup aborted manually for %d time(s), re-attempting full backup",
+		backup.Status.FullBackupAborts)
+}
+
+// AbortFullBackupJob removes the full backup job, e.g. hung, along with the task context for backup, and turns
+// the backup back to phase new to re-attempt full backup with a new job. The abort is rejected unless the backup
+// is in full backup phase.
+var AbortFullBackupJob = NewStepBinder("AbortFullBackupJob",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		if backup.Status.Phase != xstorev1.XStoreFullBackuping {
+			consumeAbortFullBackupAnnotation(rc, backup,
+				"full backup abort rejected, backup is not in phase "+string(xstorev1.XStoreFullBackuping))
+			return flow.Continue("Full backup abort rejected.", "phase", backup.Status.Phase)
+		}
+
+		job, err := rc.GetXStoreBackupJob()
+		if client.IgnoreNotFound(err) != nil {
+			return flow.Error(err, "Unable to get full backup job!")
+		}
+		// wait until the job is gone, otherwise it's taken as the job of the new attempt
+		if job != nil {
+			if job.DeletionTimestamp.IsZero() {
+				err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(rc.JobDeletePropagation()))
+				if client.IgnoreNotFound(err) != nil {
+					return flow.Error(err, "Unable to remove full backup job", "job-name", job.Name)
+				}
+			}
+			return flow.RetryAfter(5*time.Second, "Wait until full backup job removed.", "job-name", job.Name)
+		}
+
+		// task context for backup is built again with the target pod selected
+		if err := rc.DeleteTaskContext(xstoreconvention.BackupConfigMapKey); err != nil {
+			return flow.Error(err, "Unable to reset task context for backup")
+		}
+
+		consumeAbortFullBackupAnnotation(rc, backup, "")
+		recordFullBackupAbort(backup, metav1.Now())
+		rc.RecordEvent(backup, corev1.EventTypeWarning, "FullBackupAborted", backup.Status.Message)
+		return flow.Continue("Full backup job aborted.")
+	})
+
+// RepointBackupSink rebuilds the task context for backup once the sink of backup is changed before full backup
+// job started, and derives the backup root path again for standard backups, so that it's checked against the new
+// sink for collision. The change is reverted if the full backup job has started, since files are being uploaded
+// to the previous sink.
+var RepointBackupSink = NewStepBinder("RepointBackupSink",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		exists, err := rc.IsTaskContextExists(xstoreconvention.BackupConfigMapKey)
+		if err != nil {
+			return flow.Error(err, "Unable to get task context for backup")
+		}
+		if !exists {
+			return flow.Pass()
+		}
+		backupJobContext, err := getBackupJobContext(rc, flow.Logger())
+		if err != nil {
+			return flow.Error(err, "Unable to get task context for backup")
+		}
+		previousSink, sink := backupJobContext.Sink, backup.Spec.StorageProvider.Sink
+		if previousSink == sink {
+			return flow.Pass()
+		}
+
+		job, err := rc.GetXStoreBackupJob()
+		if client.IgnoreNotFound(err) != nil {
+			return flow.Error(err, "Unable to get full backup job!")
+		}
+		if job != nil {
+			backup.Spec.StorageProvider.Sink = previousSink
+			rc.MarkXstoreBackupChanged()
+			rc.RecordEvent(backup, corev1.EventTypeWarning, "SinkRepointRejected",
+				fmt.Sprintf("sink can not be re-pointed to %s, full backup job %s already started uploading to %s",
+					sink, job.Name, previousSink))
+			return flow.Continue("Sink re-point rejected.", "job-name", job.Name)
+		}
+
+		if err := rc.DeleteTaskContext(xstoreconvention.BackupConfigMapKey); err != nil {
+			return flow.Error(err, "Unable to reset task context for backup")
+		}
+		isStandard, err := rc.GetXStoreIsStandard()
+		if err != nil {
+			return flow.Error(err, "Unable to get corresponding xstore.")
+		}
+		if isStandard {
+			// root path is derived again from start time by UpdateBackupStartInfo
+			backup.Status.StartTime = nil
+			backup.Status.BackupRootPath = ""
+		}
+		rc.RecordEvent(backup, corev1.EventTypeNormal, "SinkRepointed",
+			fmt.Sprintf("sink re-pointed from %s to %s before upload", previousSink, sink))
+		return flow.Continue("Sink re-pointed.", "from", previousSink, "to", sink)
+	})
+
+func consumeRefreshMetadataAnnotation(rc *xstorev1reconcile.BackupContext, backup *xstorev1.XStoreBackup, message string) {
+	delete(backup.Annotations, xstoremeta.AnnotationRefreshMetadata)
+	rc.MarkXstoreBackupChanged()
+	backup.Status.Message = message
+}
+
+// RefreshXStoreMetadata uploads metadata of finished backup again from its current status, overwriting the
+// existing metadata under backup root path. Phase of backup is never changed, and the refresh is rejected
+// unless the backup is finished with metadata uploaded by itself, i.e. not a part of polardbx backup.
+var RefreshXStoreMetadata = NewStepBinder("RefreshXStoreMetadata",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		if backup.Status.Phase != xstorev1.XStoreBackupFinished {
+			consumeRefreshMetadataAnnotation(rc, backup,
+				"metadata refresh rejected, backup is not in phase "+string(xstorev1.XStoreBackupFinished))
+			return flow.Continue("Metadata refresh rejected.", "phase", backup.Status.Phase)
+		}
+		if backup.Status.Metadata == nil || backup.Status.BackupRootPath == "" {
+			consumeRefreshMetadataAnnotation(rc, backup, "metadata refresh rejected, metadata never uploaded")
+			return flow.Continue("Metadata refresh rejected.")
+		}
+		isStandard, err := rc.GetXStoreIsStandard()
+		if err != nil {
+			return flow.Error(err, "Unable to get corresponding xstore.")
+		}
+		if !isStandard {
+			consumeRefreshMetadataAnnotation(rc, backup,
+				"metadata refresh rejected, metadata is uploaded by polardbx backup")
+			return flow.Continue("Metadata refresh rejected.")
+		}
+
+		// failure of refresh neither counts against upload attempts nor fails the finished backup
+		failed := false
+		result, err := uploadXStoreMetadataOrElse(rc, flow,
+			func(rc *xstorev1reconcile.BackupContext, flow control.Flow, message string) (reconcile.Result, error) {
+				failed = true
+				consumeRefreshMetadataAnnotation(rc, backup, "metadata refresh failed: "+message)
+				return flow.Continue("Metadata refresh failed.", "error", message)
+			})
+		// retry later if filestream server is unreachable
+		if failed || err != nil || !result.IsZero() {
+			return result, err
+		}
+		consumeRefreshMetadataAnnotation(rc, backup, "")
+		return flow.Continue("Metadata refreshed.")
+	})
@@ -24,23 +24,36 @@ import (
 	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
 	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
 	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	polardbxv1xstore "github.com/alibaba/polardbx-operator/api/v1/xstore"
+	binlogmeta "github.com/alibaba/polardbx-operator/pkg/binlogtool/binlog/meta"
 	"github.com/alibaba/polardbx-operator/pkg/debug"
 	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
 	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
 	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
+	"github.com/alibaba/polardbx-operator/pkg/meta/core/group"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/factory"
 	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/command"
 	xstoreconvention "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
 	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
 	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
 	xstorectrlerrors "github.com/alibaba/polardbx-operator/pkg/util/error"
 	"github.com/alibaba/polardbx-operator/pkg/util/path"
+	"github.com/alibaba/polardbx-operator/pkg/util/unit"
+	"github.com/go-logr/logr"
 	"github.com/google/uuid"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"math/rand"
+	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -52,11 +65,150 @@ type BackupJobContext struct {
 	CollectFilePath     string `json:"collectFilePath,omitempty"`
 	CollectStartIndex   string `json:"collectStartIndex,omitempty"`
 	CollectEndIndex     string `json:"collectEndIndex,omitempty"`
+	CollectParallelism  int32  `json:"collectParallelism,omitempty"`
 	OffsetFileName      string `json:"offsetFileName,omitempty"`
 	StorageName         string `json:"storageName,omitempty"`
 	Sink                string `json:"sink,omitempty"`
 	KeyringPath         string `json:"keyringPath,omitempty"`
 	KeyringFilePath     string `json:"keyringFilePath,omitempty"`
+	ChunkSize           int64  `json:"chunkSize,omitempty"`
+	ChunkManifestPath   string `json:"chunkManifestPath,omitempty"`
+	KeyringChecksumPath string `json:"keyringChecksumPath,omitempty"`
+	UploadConcurrency   int32  `json:"uploadConcurrency,omitempty"`
+	UploadPartSize      int64  `json:"uploadPartSize,omitempty"`
+	MaxObjectSize       int64  `json:"maxObjectSize,omitempty"`
+	Tags                string `json:"tags,omitempty"`
+	SSEAlgorithm        string `json:"sseAlgorithm,omitempty"`
+	SSEKMSKeyId         string `json:"sseKMSKeyId,omitempty"`
+	EndpointType        string `json:"endpointType,omitempty"`
+	Compression         string `json:"compression,omitempty"`
+	Encryption          string `json:"encryption,omitempty"`
+
+	BinlogExcludePatterns []string `json:"binlogExcludePatterns,omitempty"`
+	// JobResultDir is where the full backup job writes its result and index files, on the data volume shared with
+	// engine container
+	JobResultDir string `json:"jobResultDir,omitempty"`
+}
+
+// Validate checks that the paths required by backup jobs are present.
+func (c *BackupJobContext) Validate() error {
+	required := []struct {
+		name, value string
+	}{
+		{"binlogBackupDir", c.BinlogBackupDir},
+		{"indexesPath", c.IndexesPath},
+		{"fullBackupPath", c.FullBackupPath},
+		{"collectFilePath", c.CollectFilePath},
+		{"offsetFileName", c.OffsetFileName},
+		{"storageName", c.StorageName},
+		{"keyringPath", c.KeyringPath},
+	}
+	for _, field := range required {
+		if field.value == "" {
+			return errors.New("missing " + field.name + " in backup job context")
+		}
+	}
+	return nil
+}
+
+// backupObjectTags returns the encoded tags attached to uploaded files of backup, which are the labels listed by
+// annotation of object tag labels.
+func backupObjectTags(backup *xstorev1.XStoreBackup) string {
+	tags := make(map[string]string)
+	for _, k := range strings.Split(backup.Annotations[xstoremeta.AnnotationObjectTagLabels], ",") {
+		if v, ok := backup.Labels[k]; ok && k != "" {
+			tags[k] = v
+		}
+	}
+	return filestream.EncodeObjectTags(tags)
+}
+
+// newBackupJobContext builds the job context of backup, with paths derived from backup root path.
+func newBackupJobContext(backup *xstorev1.XStoreBackup, chunkSize int64) (*BackupJobContext, error) {
+	backupRootPath := backup.Status.BackupRootPath
+	if backupRootPath == "" {
+		return nil, errors.New("backup root path not set")
+	}
+	fullBackupPath := path.JoinPath(backupRootPath, polardbxmeta.FullBackupPath,
+		backup.Spec.XStore.Name+".xbstream")
+	binlogEndOffsetPath := path.JoinPath(backupRootPath, polardbxmeta.BinlogOffsetPath,
+		backup.Spec.XStore.Name+"-end")
+	indexesPath := path.JoinPath(backupRootPath, polardbxmeta.BinlogIndexesName)
+	binlogBackupDir := path.JoinPath(backupRootPath, polardbxmeta.BinlogBackupPath, backup.Spec.XStore.Name)
+	collectFilePath := path.JoinPath(backupRootPath, polardbxmeta.CollectBinlogPath,
+		backup.Spec.XStore.Name+".evs")
+	offsetFileName := path.JoinPath(backupRootPath, polardbxmeta.BinlogOffsetPath, backup.Spec.XStore.Name)
+	keyringPath := path.JoinPath(backupRootPath, polardbxmeta.KeyringPath, backup.Spec.XStore.Name)
+	keyringFilePath := path.JoinPath(backupRootPath, polardbxmeta.KeyringPath,
+		backup.Spec.XStore.Name+"-file")
+	uploadPartSize, err := backup.Spec.StorageProvider.GetUploadPartSize()
+	if err != nil {
+		return nil, err
+	}
+	maxObjectSize, err := backup.Spec.StorageProvider.GetMaxObjectSize()
+	if err != nil {
+		return nil, err
+	}
+	sseAlgorithm, sseKMSKeyId := backup.Spec.StorageProvider.GetServerSideEncryption()
+	encryptionAlgorithm := ""
+	if backup.Spec.Encryption != nil {
+		encryptionAlgorithm = string(backup.Spec.Encryption.GetAlgorithm())
+	}
+
+	return &BackupJobContext{
+		BinlogBackupDir:     binlogBackupDir,
+		IndexesPath:         indexesPath,
+		BinlogEndOffsetPath: binlogEndOffsetPath,
+		FullBackupPath:      fullBackupPath,
+		CollectFilePath:     collectFilePath,
+		OffsetFileName:      offsetFileName,
+		StorageName:         string(backup.Spec.StorageProvider.StorageName),
+		Sink:                backup.Spec.StorageProvider.Sink,
+		KeyringPath:         keyringPath,
+		KeyringFilePath:     keyringFilePath,
+		ChunkSize:           chunkSize,
+		ChunkManifestPath:   fullBackupPath + polardbxmeta.ChunkManifestSuffix,
+		KeyringChecksumPath: keyringPath + polardbxmeta.KeyringChecksumSuffix,
+		UploadConcurrency:   backup.Spec.StorageProvider.UploadConcurrency,
+		UploadPartSize:      uploadPartSize,
+		MaxObjectSize:       maxObjectSize,
+		Tags:                backupObjectTags(backup),
+		SSEAlgorithm:        sseAlgorithm,
+		SSEKMSKeyId:         sseKMSKeyId,
+		EndpointType:        string(backup.Spec.StorageProvider.EndpointType),
+		Compression:         string(backup.Spec.Compression),
+		Encryption:          encryptionAlgorithm,
+
+		BinlogExcludePatterns: backup.Spec.BinlogExcludePatterns,
+	}, nil
+}
+
+// getBackupJobContext loads the job context of backup from task config map. If the saved context is unparsable
+// or incomplete, e.g. edited manually or partially written, it is rebuilt from backup root path and saved back,
+// offsets of binlog collecting are lost and collected again by later steps.
+func getBackupJobContext(rc *xstorev1reconcile.BackupContext, logger logr.Logger) (*BackupJobContext, error) {
+	backupJobContext := &BackupJobContext{}
+	err := rc.GetTaskContext(xstoreconvention.BackupConfigMapKey, backupJobContext)
+	if err == nil {
+		if validateErr := backupJobContext.Validate(); validateErr != nil {
+			err = fmt.Errorf("%w: %s", xstorev1reconcile.ErrTaskContextCorrupted, validateErr.Error())
+		}
+	}
+	if err == nil || !errors.Is(err, xstorev1reconcile.ErrTaskContextCorrupted) {
+		return backupJobContext, err
+	}
+
+	backup := rc.MustGetXStoreBackup()
+	backupJobContext, buildErr := newBackupJobContext(backup, backup.Status.ChunkSize)
+	if buildErr != nil {
+		return nil, fmt.Errorf("%s, unable to rebuild: %s", err.Error(), buildErr.Error())
+	}
+	logger.Info("Job context of backup corrupted, rebuilt from backup root path", "error", err.Error(),
+		"backup-root-path", backup.Status.BackupRootPath)
+	if err := rc.SaveTaskContext(xstoreconvention.BackupConfigMapKey, backupJobContext); err != nil {
+		return nil, err
+	}
+	return backupJobContext, nil
 }
 
 func UpdatePhaseTemplate(phase xstorev1.XStoreBackupPhase, requeue ...bool) control.BindFunc {
@@ -65,11 +217,15 @@ func UpdatePhaseTemplate(phase xstorev1.XStoreBackupPhase, requeue ...bool) cont
 			xstoreBackup := rc.MustGetXStoreBackup()
 
 			xstoreBackup.Status.Phase = phase
+			if xstoreBackup.Status.PhaseTimestamps == nil {
+				xstoreBackup.Status.PhaseTimestamps = make(map[xstorev1.XStoreBackupPhase]metav1.Time)
+			}
+			xstoreBackup.Status.PhaseTimestamps[phase] = metav1.Now()
 			return flow.Continue(" Phase xstore backup updated!", "phase-new", phase)
 		})
 }
 
-var PersistentXstoreBackup = NewStepBinder("PersistentXstoreBackup",
+var PersistentXstoreBackup = newStepBinderWithoutTiming("PersistentXstoreBackup",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		if rc.IsXstoreBackupChanged() {
 			if err := rc.UpdateXStoreBackup(); err != nil {
@@ -80,7 +236,7 @@ var PersistentXstoreBackup = NewStepBinder("PersistentXstoreBackup",
 		return flow.Continue("Xstore backup spec did not change.")
 	})
 
-var PersistentStatusChanges = NewStepBinder("PersistentStatusChanges",
+var PersistentStatusChanges = newStepBinderWithoutTiming("PersistentStatusChanges",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		if debug.IsDebugEnabled() {
 			xstoreBackup := rc.MustGetXStoreBackup()
@@ -108,6 +264,7 @@ var UpdateBackupStartInfo = NewStepBinder("UpdateBackupStartInfo",
 
 		}
 		xstoreBackup.Spec.XStore.UID = xstore.UID
+		previousRootPath := xstoreBackup.Status.BackupRootPath
 
 		if xstoreBackup.Status.StartTime == nil {
 			nowTime := metav1.Now()
@@ -142,15 +299,91 @@ var UpdateBackupStartInfo = NewStepBinder("UpdateBackupStartInfo",
 				fmt.Sprintf("%s-%s", xstoreBackup.Name, xstoreBackup.Status.StartTime.Format("20060102150405")),
 			)
 			xstoreBackup.Status.XStoreSpecSnapshot = xstore.Spec.DeepCopy()
+
+			// fill storage provider and retention time by backup class if not specified
+			if xstoreBackup.Spec.BackupClass != "" {
+				err := fmt.Errorf("backup class %s not found", xstoreBackup.Spec.BackupClass)
+				if class := rc.XStoreContext().Config().Backup().GetBackupClass(xstoreBackup.Spec.BackupClass); class != nil {
+					err = class.FillBackup(&xstoreBackup.Spec.StorageProvider, &xstoreBackup.Spec.RetentionTime)
+				}
+				if err != nil {
+					xstoreBackup.Status.Phase = xstorev1.XstoreBackupFailed
+					xstoreBackup.Status.Reason = "InvalidBackupClass"
+					xstoreBackup.Status.FailureReason = polardbxv1polardbx.FailureReasonInvalidSpec
+					xstoreBackup.Status.Message = err.Error()
+					rc.RecordEvent(xstoreBackup, corev1.EventTypeWarning, xstoreBackup.Status.Reason, xstoreBackup.Status.Message)
+					return flow.Retry("Invalid backup class, backup failed.", "class", xstoreBackup.Spec.BackupClass)
+				}
+			}
+
+			// fill storage provider by sink policy of operator if not specified
+			if xstoreBackup.Spec.StorageProvider.StorageName == "" && xstoreBackup.Spec.StorageProvider.Sink == "" {
+				if storageProvider := rc.XStoreContext().Config().Backup().DefaultStorageProvider(xstore.Namespace, xstore.Labels); storageProvider != nil {
+					xstoreBackup.Spec.StorageProvider = *storageProvider
+				}
+			}
 		}
 
 		// mark to update spec
 		rc.MarkXstoreBackupChanged()
 
+		// root path is derived from start time in seconds, fail fast rather than overwrite another backup set
+		if isStandard && xstoreBackup.Status.BackupRootPath != previousRootPath {
+			collision, err := detectBackupRootPathCollision(rc, xstoreBackup, flow.Logger())
+			if err != nil {
+				return flow.Error(err, "Unable to check backup root path.")
+			}
+			if collision != "" {
+				xstoreBackup.Status.Phase = xstorev1.XstoreBackupFailed
+				xstoreBackup.Status.Reason = "BackupRootPathCollision"
+				xstoreBackup.Status.FailureReason = polardbxv1polardbx.FailureReasonInvalidSpec
+				xstoreBackup.Status.Message = fmt.Sprintf("backup root path %s is already used, %s, please recreate the backup",
+					xstoreBackup.Status.BackupRootPath, collision)
+				// drop the root path, so that the colliding backup set is never cleaned along with this backup
+				xstoreBackup.Status.BackupRootPath = ""
+				rc.RecordEvent(xstoreBackup, corev1.EventTypeWarning, xstoreBackup.Status.Reason, xstoreBackup.Status.Message)
+				return flow.Retry("Backup root path collision detected, backup failed.", "collision", collision)
+			}
+		}
+
 		return flow.Continue("Update backup start info!")
 
 	})
 
+// detectBackupRootPathCollision checks whether the root path of standard xstore backup is used by other backups of
+// the xstore or already exists in the storage, and describes the collision if so. The storage is not checked if
+// unreachable, since the backup job fails anyway in that case.
+func detectBackupRootPathCollision(rc *xstorev1reconcile.BackupContext, backup *xstorev1.XStoreBackup,
+	logger logr.Logger) (string, error) {
+	var backupList xstorev1.XStoreBackupList
+	err := rc.Client().List(rc.Context(), &backupList, client.InNamespace(rc.Namespace()),
+		client.MatchingLabels{xstoremeta.LabelName: backup.Spec.XStore.Name})
+	if err != nil {
+		return "", err
+	}
+	for _, other := range backupList.Items {
+		if other.UID != backup.UID && other.Status.BackupRootPath == backup.Status.BackupRootPath &&
+			other.Spec.StorageProvider.StorageName == backup.Spec.StorageProvider.StorageName &&
+			other.Spec.StorageProvider.Sink == backup.Spec.StorageProvider.Sink {
+			return "used by backup " + other.Name, nil
+		}
+	}
+
+	if _, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(backup.Spec.StorageProvider.StorageName); err != nil {
+		return "", nil
+	}
+	exists, err := isRemoteFileExisted(rc, backup, backup.Status.BackupRootPath)
+	if err != nil {
+		logger.Info("Unable to check root path in storage, skip.", "error", err.Error())
+		return "", nil
+	}
+	if exists {
+		return "objects found in " + string(backup.Spec.StorageProvider.StorageName) + "/" +
+			backup.Spec.StorageProvider.Sink, nil
+	}
+	return "", nil
+}
+
 var CreateBackupConfigMap = NewStepBinder("CreateBackupConfigMap",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		exists, err := rc.IsTaskContextExists(xstoreconvention.BackupConfigMapKey)
@@ -162,35 +395,18 @@ var CreateBackupConfigMap = NewStepBinder("CreateBackupConfigMap",
 		}
 
 		backup := rc.MustGetXStoreBackup()
-		backupRootPath := backup.Status.BackupRootPath
-		fullBackupPath := fmt.Sprintf("%s/%s/%s.xbstream",
-			backupRootPath, polardbxmeta.FullBackupPath, backup.Spec.XStore.Name)
-		binlogEndOffsetPath := fmt.Sprintf("%s/%s/%s-end",
-			backupRootPath, polardbxmeta.BinlogOffsetPath, backup.Spec.XStore.Name)
-		indexesPath := fmt.Sprintf("%s/%s", backupRootPath, polardbxmeta.BinlogIndexesName)
-		binlogBackupDir := fmt.Sprintf("%s/%s/%s",
-			backupRootPath, polardbxmeta.BinlogBackupPath, backup.Spec.XStore.Name)
-		collectFilePath := fmt.Sprintf("%s/%s/%s.evs",
-			backupRootPath, polardbxmeta.CollectBinlogPath, backup.Spec.XStore.Name)
-		offsetFileName := fmt.Sprintf("%s/%s/%s",
-			backupRootPath, polardbxmeta.BinlogOffsetPath, backup.Spec.XStore.Name)
-		keyringPath := fmt.Sprintf("%s/%s/%s",
-			backupRootPath, polardbxmeta.KeyringPath, backup.Spec.XStore.Name)
-		keyringFilePath := fmt.Sprintf("%s/%s/%s-file",
-			backupRootPath, polardbxmeta.KeyringPath, backup.Spec.XStore.Name)
-
-		if err := rc.SaveTaskContext(xstoreconvention.BackupConfigMapKey, &BackupJobContext{
-			BinlogBackupDir:     binlogBackupDir,
-			IndexesPath:         indexesPath,
-			BinlogEndOffsetPath: binlogEndOffsetPath,
-			FullBackupPath:      fullBackupPath,
-			CollectFilePath:     collectFilePath,
-			OffsetFileName:      offsetFileName,
-			StorageName:         string(backup.Spec.StorageProvider.StorageName),
-			Sink:                backup.Spec.StorageProvider.Sink,
-			KeyringPath:         keyringPath,
-			KeyringFilePath:     keyringFilePath,
-		}); err != nil {
+		chunkSize, err := rc.XStoreContext().Config().Backup().GetUploadChunkSize()
+		if err != nil {
+			return flow.Error(err, "Unable to parse upload chunk size")
+		}
+		backup.Status.ChunkSize = chunkSize
+		backupJobContext, err := newBackupJobContext(backup, chunkSize)
+		if err != nil {
+			return flow.Error(err, "Unable to build job context for backup")
+		}
+		backupJobContext.JobResultDir = path.JoinPath(engineDataDir(rc), "tmp")
+
+		if err := rc.SaveTaskContext(xstoreconvention.BackupConfigMapKey, backupJobContext); err != nil {
 			return flow.Error(err, "Unable to save job context for backup!")
 		}
 		return flow.Continue("Job context for backup prepared!")
@@ -198,16 +414,21 @@ var CreateBackupConfigMap = NewStepBinder("CreateBackupConfigMap",
 
 var StartXStoreFullBackupJob = NewStepBinder("StartXStoreFullBackupJob",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
-		backupJobContext := &BackupJobContext{}
-		err := rc.GetTaskContext(xstoreconvention.BackupConfigMapKey, &backupJobContext)
+		backupJobContext, err := getBackupJobContext(rc, flow.Logger())
 		if err != nil {
 			return flow.Error(err, "Unable to get task context for backup")
 		}
 
 		// retry until target pod found, ops allowed here
 		xstoreBackup := rc.MustGetXStoreBackup()
+		forbidLeader := xstoreBackup.Spec.ForbidBackupOnLeader
 		targetPod, err := rc.GetXStoreTargetPod()
 		if err != nil {
+			if forbidLeader {
+				if result, done := failBackupIfOnlyLeaderAvailable(rc, flow); done {
+					return result, nil
+				}
+			}
 			xstoreBackup.Status.Message = err.Error()
 			return flow.RetryAfter(5*time.Second, "Unable to find target pod, error: "+err.Error())
 		}
@@ -216,10 +437,6 @@ var StartXStoreFullBackupJob = NewStepBinder("StartXStoreFullBackupJob",
 		}
 		xstoreBackup.Status.Message = ""
 
-		if targetPod.Labels[xstoremeta.LabelRole] == xstoremeta.RoleLeader { // warning when backup on leader pod
-			flow.Logger().Info("Warning: performing backup on leader", "leader pod", targetPod.Name)
-		}
-
 		job, err := rc.GetXStoreBackupJob()
 		if client.IgnoreNotFound(err) != nil {
 			return flow.Error(err, "Unable to get full backup job!")
@@ -228,13 +445,59 @@ var StartXStoreFullBackupJob = NewStepBinder("StartXStoreFullBackupJob",
 			return flow.Continue("Full Backup job already started!", "job-name", job.Name)
 		}
 
+		if targetPod.Labels[xstoremeta.LabelRole] == xstoremeta.RoleLeader {
+			if !forbidLeader { // warning when backup on leader pod
+				flow.Logger().Info("Warning: performing backup on leader", "leader pod", targetPod.Name)
+			} else if xstoreBackup.Spec.PreferredBackupRole == xstoremeta.RoleLeader {
+				xstoreBackup.Status.Phase = xstorev1.XstoreBackupFailed
+				xstoreBackup.Status.Reason = "LeaderBackupForbidden"
+				xstoreBackup.Status.FailureReason = polardbxv1polardbx.FailureReasonTargetPodNotFound
+				xstoreBackup.Status.Message = "backup on leader is forbidden, while leader is the preferred backup role"
+				return flow.Retry("Backup on leader forbidden, backup failed.")
+			} else {
+				// role changed since selected, retry to select a follower
+				if result, done := failBackupIfOnlyLeaderAvailable(rc, flow); done {
+					return result, nil
+				}
+				xstoreBackup.Status.Message = "target pod " + targetPod.Name + " is leader, retry to select a follower"
+				return flow.RetryAfter(5*time.Second, "Target pod is leader, retry to select a follower", "pod", targetPod.Name)
+			}
+		} else {
+			// backup on standby without promoting, which requires a consistent data view
+			replicationStatus, err := rc.ShowStandbyReplication(targetPod)
+			if err != nil {
+				xstoreBackup.Status.Message = "target pod " + targetPod.Name + " has no consistent data view, " + err.Error()
+				return flow.RetryAfter(5*time.Second, "Target pod has no consistent data view", "pod", targetPod.Name,
+					"error", err.Error())
+			}
+			xstoreBackup.Status.TargetPodLagSeconds = replicationStatus.SecondsBehindMaster
+		}
+
+		if result, done := refuseBackupIfDiskInsufficient(rc, flow, targetPod); done {
+			return result, nil
+		}
+		if result, done := refuseBackupIfKeyringKeyUnavailable(rc, flow); done {
+			return result, nil
+		}
+		if result, done := refuseBackupIfEncryptionKeyUnavailable(rc, flow, backupJobContext); done {
+			return result, nil
+		}
+		if result, done := retryBackupIfTargetPodUnhealthy(rc, flow, targetPod); done {
+			return result, nil
+		}
+
 		jobName := xstoreconvention.NewBackupJobName(targetPod, xstoreconvention.BackupJobTypeFullBackup)
 		xstoreBackup.Status.TargetPod = targetPod.Name
 
-		job, e := newBackupJob(xstoreBackup, targetPod, jobName)
-		if e != nil {
+		job, err = newBackupJob(xstoreBackup, targetPod, jobName, backupJobContext)
+		if err != nil {
 			return flow.Error(err, "Unable to newFullBackupJob")
 		}
+		timeout, err := rc.XStoreContext().Config().Backup().GetFullBackupJobTimeout()
+		if err != nil {
+			return flow.Error(err, "Unable to get timeout of full backup job")
+		}
+		setJobActiveDeadline(job, timeout)
 
 		if err := rc.SetControllerRefAndCreate(job); err != nil {
 			return flow.Error(err, "Unable to create job to initialize data")
@@ -243,6 +506,380 @@ var StartXStoreFullBackupJob = NewStepBinder("StartXStoreFullBackupJob",
 		return flow.Continue("Full Backup job started!", "job-name", jobName)
 	})
 
+// failBackupIfOnlyLeaderAvailable fails the backup which forbids backup on leader if no follower is available,
+// done is false if the backup should go on retrying target pod selection.
+func failBackupIfOnlyLeaderAvailable(rc *xstorev1reconcile.BackupContext, flow control.Flow) (result reconcile.Result, done bool) {
+	pods, err := rc.GetXStorePods()
+	if err != nil {
+		flow.Logger().Error(err, "Unable to get pods of xstore")
+		return reconcile.Result{}, false
+	}
+	if len(xstorev1reconcile.StandbyBackupPods(pods)) > 0 {
+		return reconcile.Result{}, false
+	}
+	xstoreBackup := rc.MustGetXStoreBackup()
+	xstoreBackup.Status.Phase = xstorev1.XstoreBackupFailed
+	xstoreBackup.Status.Reason = "OnlyLeaderAvailable"
+	xstoreBackup.Status.FailureReason = polardbxv1polardbx.FailureReasonTargetPodNotFound
+	xstoreBackup.Status.Message = "backup on leader is forbidden, while no follower is available"
+	result, _ = flow.Retry("No follower available, backup failed.")
+	return result, true
+}
+
+// xstoreUnstableReason tells why the xstore is not stable to start full backup on, e.g. being reconfigured or
+// restarted, during which the target pod may be replaced. Empty string is returned if the xstore is stable.
+func xstoreUnstableReason(xstore *xstorev1.XStore) string {
+	if xstore.Status.Phase != polardbxv1xstore.PhaseRunning {
+		return fmt.Sprintf("xstore is in phase %q", xstore.Status.Phase)
+	}
+	if xstore.Generation > xstore.Status.ObservedGeneration {
+		return fmt.Sprintf("spec of xstore is not observed yet, generation %d, observed %d",
+			xstore.Generation, xstore.Status.ObservedGeneration)
+	}
+	if xstore.Status.Restarting {
+		return "pods of xstore are restarting"
+	}
+	return ""
+}
+
+// WaitXStoreStable waits for the xstore to be stable before full backup starts, so that the target pod selected
+// is not replaced during backup. Backup fails if the xstore is not stable within the configured timeout.
+var WaitXStoreStable = NewStepBinder("WaitXStoreStable",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		job, err := rc.GetXStoreBackupJob()
+		if client.IgnoreNotFound(err) != nil {
+			return flow.Error(err, "Unable to get full backup job!")
+		}
+		if job != nil {
+			return flow.Pass()
+		}
+		xstore, err := rc.GetXStore()
+		if err != nil {
+			return flow.Error(err, "Unable to get xstore.")
+		}
+
+		backup := rc.MustGetXStoreBackup()
+		reason := xstoreUnstableReason(xstore)
+		if reason == "" {
+			rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+				Type:    xstorev1.XStoreBackupXStoreStable,
+				Status:  corev1.ConditionTrue,
+				Reason:  "Stable",
+				Message: "XStore is stable to start full backup on",
+			})
+			return flow.Continue("XStore is stable.")
+		}
+		rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+			Type:    xstorev1.XStoreBackupXStoreStable,
+			Status:  corev1.ConditionFalse,
+			Reason:  "Unstable",
+			Message: reason,
+		})
+
+		timeout, err := rc.XStoreContext().Config().Backup().GetStableWaitTimeout()
+		if err != nil {
+			return flow.Error(err, "Unable to get timeout of waiting xstore stable")
+		}
+		if timeout > 0 && backup.Status.StartTime != nil && time.Since(backup.Status.StartTime.Time) > timeout {
+			backup.Status.Phase = xstorev1.XstoreBackupFailed
+			backup.Status.Reason = "XStoreUnstable"
+			backup.Status.FailureReason = polardbxv1polardbx.FailureReasonTimeout
+			backup.Status.Message = fmt.Sprintf("xstore is not stable within %s, %s", timeout, reason)
+			return flow.Retry("XStore is not stable within timeout, backup failed.", "reason", reason)
+		}
+		backup.Status.Message = "waiting for xstore to be stable, " + reason
+		return flow.RetryAfter(10*time.Second, "Wait for xstore to be stable.", "reason", reason)
+	})
+
+// engineDataDir returns the data directory of engine on target pod, which is resolved from config so that engine
+// images mounting data elsewhere are supported.
+func engineDataDir(rc *xstorev1reconcile.BackupContext) string {
+	return rc.XStoreContext().Config().Store().EngineDataDir()
+}
+
+// newBackupDiskSpaceCommand returns the command printing the estimated local disk space of full backup and the
+// available space of data volume on target pod in bytes. Data files are streamed to remote directly, while redo logs
+// generated during backup are copied to local tmpdir, so the space is estimated by size of redo log files.
+func newBackupDiskSpaceCommand(dataDir string) []string {
+	return []string{"sh", "-c", fmt.Sprintf("du -scb %[1]s/data/ib_logfile* '%[1]s/data/#innodb_redo' "+
+		"%[1]s/log/ib_logfile* 2>/dev/null | tail -n 1 | cut -f 1; df -PB1 %[1]s | tail -n 1 | awk '{print $4}'",
+		strings.TrimRight(dataDir, "/"))}
+}
+
+// parseBackupDiskSpace parses output of backup disk space command into estimated and available disk space in bytes.
+func parseBackupDiskSpace(output string) (estimated int64, available int64, err error) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected output of disk space check: %q", output)
+	}
+	if estimated, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+		return 0, 0, err
+	}
+	if available, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return 0, 0, err
+	}
+	return estimated, available, nil
+}
+
+// requiredBackupDiskSpace returns the estimated disk space plus safety margin in percentage.
+func requiredBackupDiskSpace(estimated int64, margin int32) int64 {
+	return estimated + estimated*int64(margin)/100
+}
+
+// refuseBackupIfDiskInsufficient fails the backup if available disk space on target pod is less than the required
+// space of full backup, done is false if the backup should go on. Backup is not blocked if the check itself fails.
+func refuseBackupIfDiskInsufficient(rc *xstorev1reconcile.BackupContext, flow control.Flow, targetPod *corev1.Pod) (result reconcile.Result, done bool) {
+	stdout := &bytes.Buffer{}
+	err := rc.ExecuteCommandOn(targetPod, "engine", newBackupDiskSpaceCommand(engineDataDir(rc)), control.ExecOptions{
+		Stdout: stdout,
+		Stderr: &bytes.Buffer{},
+	})
+	var estimated, available int64
+	if err == nil {
+		estimated, available, err = parseBackupDiskSpace(stdout.String())
+	}
+	if err != nil {
+		flow.Logger().Error(err, "Unable to check disk space of target pod, skipped", "pod", targetPod.Name)
+		return reconcile.Result{}, false
+	}
+
+	margin := rc.XStoreContext().Config().Backup().GetDiskSpaceSafetyMargin()
+	required := requiredBackupDiskSpace(estimated, margin)
+	if available >= required {
+		return reconcile.Result{}, false
+	}
+	xstoreBackup := rc.MustGetXStoreBackup()
+	xstoreBackup.Status.Phase = xstorev1.XstoreBackupFailed
+	xstoreBackup.Status.Reason = "InsufficientDiskSpace"
+	xstoreBackup.Status.FailureReason = polardbxv1polardbx.FailureReasonInsufficientDiskSpace
+	xstoreBackup.Status.Message = fmt.Sprintf("insufficient disk space on target pod %s, required %s "+
+		"(estimated %s with %d%% safety margin), available %s", targetPod.Name, unit.ByteCountIEC(required),
+		unit.ByteCountIEC(estimated), margin, unit.ByteCountIEC(available))
+	result, _ = flow.Retry("Insufficient disk space on target pod, backup failed.", "pod", targetPod.Name,
+		"required", required, "available", available)
+	return result, true
+}
+
+// retryBackupIfTargetPodUnhealthy checks the health of engine on target pod and records the result, backup is
+// retried if the engine is unhealthy, e.g. recovering from crash, when another standby is preferred. done is false
+// if the backup should go on.
+func retryBackupIfTargetPodUnhealthy(rc *xstorev1reconcile.BackupContext, flow control.Flow, targetPod *corev1.Pod) (result reconcile.Result, done bool) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err := rc.ExecuteCommandOn(targetPod, "engine", command.NewCanonicalCommandBuilder().Engine().Health().Build(),
+		control.ExecOptions{
+			Stdout: stdout,
+			Stderr: stderr,
+		})
+	var health *command.EngineHealth
+	if err == nil {
+		health, err = command.ParseEngineHealth(stdout.String())
+	}
+	check := &xstorev1.TargetPodHealthCheck{Pod: targetPod.Name, CheckTime: metav1.Now()}
+	if err != nil {
+		check.Message = "health check failed: " + err.Error()
+	} else {
+		check.Healthy, check.Uptime, check.Message = health.Healthy, health.Uptime, health.Reason
+	}
+	xstoreBackup := rc.MustGetXStoreBackup()
+	xstoreBackup.Status.TargetPodHealthCheck = check
+	if check.Healthy {
+		return reconcile.Result{}, false
+	}
+	xstoreBackup.Status.Message = "engine on target pod " + targetPod.Name + " is unhealthy, " + check.Message
+	result, _ = flow.RetryAfter(10*time.Second, "Engine on target pod unhealthy, retry", "pod", targetPod.Name,
+		"message", check.Message, "stderr", stderr.String())
+	return result, true
+}
+
+// refuseBackupIfKeyringKeyUnavailable fails the backup requesting keyring re-encryption if any of the keys is
+// unavailable, otherwise the fingerprint of target key is recorded. done is false if the backup should go on.
+func refuseBackupIfKeyringKeyUnavailable(rc *xstorev1reconcile.BackupContext, flow control.Flow) (result reconcile.Result, done bool) {
+	xstoreBackup := rc.MustGetXStoreBackup()
+	reEncryption := xstoreBackup.Spec.KeyringReEncryption
+	if reEncryption == nil {
+		return reconcile.Result{}, false
+	}
+	secret, err := rc.GetSecret(reEncryption.SecretName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		result, _ = flow.RetryAfter(5*time.Second, "Unable to get secret of keyring keys, error: "+err.Error(),
+			"secret", reEncryption.SecretName)
+		return result, true
+	}
+	fingerprint := ""
+	if err == nil {
+		fingerprint, err = reEncryption.ValidateKeys(secret)
+	}
+	if err != nil {
+		xstoreBackup.Status.Phase = xstorev1.XstoreBackupFailed
+		xstoreBackup.Status.Reason = "KeyringKeyUnavailable"
+		xstoreBackup.Status.FailureReason = polardbxv1polardbx.FailureReasonInvalidSpec
+		xstoreBackup.Status.Message = "keys of keyring re-encryption are unavailable: " + err.Error()
+		result, _ = flow.Retry("Keys of keyring re-encryption unavailable, backup failed.")
+		return result, true
+	}
+	xstoreBackup.Status.KeyringKeyFingerprint = fingerprint
+	return reconcile.Result{}, false
+}
+
+// newFullBackupSizeCommand returns the command printing the size of data directory on target pod in bytes, which
+// approximates the size of full backup.
+func newFullBackupSizeCommand(dataDir string) []string {
+	return []string{"sh", "-c", "du -sb " + path.JoinPath(dataDir, "data") + " | cut -f 1"}
+}
+
+// parseFullBackupSize parses output of full backup size command into size in bytes.
+func parseFullBackupSize(output string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+}
+
+// RecordFullBackupSize records the estimated size of full backup, which is listed in the backup catalog of
+// cluster. Backup is not blocked if the size is unable to be measured.
+var RecordFullBackupSize = NewStepBinder("RecordFullBackupSize",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		if backup.Status.FullBackupSize > 0 {
+			return flow.Pass()
+		}
+		targetPod, err := rc.GetXStoreTargetPod()
+		if err != nil {
+			return flow.Error(err, "Unable to get targetPod")
+		}
+		stdout := &bytes.Buffer{}
+		err = rc.ExecuteCommandOn(targetPod, "engine", newFullBackupSizeCommand(engineDataDir(rc)), control.ExecOptions{
+			Logger: flow.Logger(),
+			Stdout: stdout,
+			Stderr: &bytes.Buffer{},
+		})
+		var size int64
+		if err == nil {
+			size, err = parseFullBackupSize(stdout.String())
+		}
+		if err != nil {
+			flow.Logger().Error(err, "Unable to measure size of full backup, skipped", "pod", targetPod.Name)
+			return flow.Pass()
+		}
+		backup.Status.FullBackupSize = size
+		return flow.Continue("Full backup size recorded.", "size", size)
+	})
+
+// CaptureTableChecksums captures checksums of the tables defined in spec on target pod after full backup
+// finished, which are verified after restore. Backup fails if any of the tables is missing.
+var CaptureTableChecksums = NewStepBinder("CaptureTableChecksums",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		if len(backup.Spec.ChecksumTables) == 0 || backup.Status.TableChecksums != nil {
+			return flow.Pass()
+		}
+		targetPod, err := rc.GetXStoreTargetPod()
+		if err != nil {
+			return flow.Error(err, "Unable to get targetPod")
+		}
+		// rows are sampled by a new seed, which is recorded to sample the same rows on restore
+		var sample *polardbxv1polardbx.TableChecksumSample
+		if percent := backup.Spec.ChecksumSamplePercent; percent > 0 && percent < 100 {
+			sample = &polardbxv1polardbx.TableChecksumSample{Percent: percent, Seed: rand.Int63()}
+		}
+		stdout := &bytes.Buffer{}
+		cmd := command.NewCanonicalCommandBuilder().Engine().Checksum(backup.Spec.ChecksumTables...).Build()
+		if sample != nil {
+			cmd = command.NewCanonicalCommandBuilder().Engine().
+				ChecksumSample(sample.Percent, sample.Seed, backup.Spec.ChecksumTables...).Build()
+		}
+		err = rc.ExecuteCommandOn(targetPod, "engine", cmd, control.ExecOptions{
+			Logger: flow.Logger(),
+			Stdout: stdout,
+			Stderr: &bytes.Buffer{},
+		})
+		if err != nil {
+			return flow.RetryAfter(10*time.Second, "Failed to checksum tables, error: "+err.Error(), "pod", targetPod.Name)
+		}
+		var checksums map[string]string
+		if sample != nil {
+			checksums, sample.SampledRows, err = command.ParseSampledTableChecksums(stdout.String())
+		} else {
+			checksums, err = command.ParseTableChecksums(stdout.String())
+		}
+		if err != nil {
+			backup.Status.Phase = xstorev1.XstoreBackupFailed
+			backup.Status.Reason = "ChecksumFailed"
+			backup.Status.FailureReason = polardbxv1polardbx.FailureReasonChecksumMismatch
+			backup.Status.Message = err.Error()
+			return flow.Retry("Unable to checksum tables, backup failed.", "error", err.Error())
+		}
+		backup.Status.TableChecksums = checksums
+		backup.Status.TableChecksumSample = sample
+		return flow.Continue("Table checksums captured.", "tables", len(checksums))
+	})
+
+// failBackupByJob marks backup failed by the failed job and sets the condition of the job false, failure caused
+// by deadline exceedance is reported with a distinct reason. The reason is returned.
+func failBackupByJob(rc *xstorev1reconcile.BackupContext, job *batchv1.Job, jobType xstoreconvention.BackupJobType,
+	condType polardbxv1xstore.ConditionType) string {
+	reason, condReason := string(jobType)+" job failed", "JobFailed"
+	failureReason := polardbxv1polardbx.FailureReasonJobFailed
+	if k8shelper.IsJobDeadlineExceeded(job) {
+		reason = fmt.Sprintf("%s job exceeded deadline of %ds", jobType, pointer.Int64Deref(job.Spec.ActiveDeadlineSeconds, 0))
+		condReason = "DeadlineExceeded"
+		failureReason = polardbxv1polardbx.FailureReasonTimeout
+	}
+	backup := rc.MustGetXStoreBackup()
+	backup.Status.Phase = xstorev1.XstoreBackupFailed
+	backup.Status.Reason = reason
+	backup.Status.FailureReason = failureReason
+	backup.Status.Message = string(jobType) + " failed, job: " + job.Name
+	if condType != "" {
+		rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+			Type:    condType,
+			Status:  corev1.ConditionFalse,
+			Reason:  condReason,
+			Message: reason,
+		})
+	}
+	return reason
+}
+
+// fullBackupResult is written by full backup job to tell whether the backup is valid
+type fullBackupResult struct {
+	Success bool   `json:"success"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// jobResultDir returns the directory where full backup job writes its result and index files. Task context
+// created before the directory is recorded falls back to the tmp directory under engine data dir.
+func jobResultDir(rc *xstorev1reconcile.BackupContext, backupJobContext *BackupJobContext) string {
+	if backupJobContext != nil && backupJobContext.JobResultDir != "" {
+		return backupJobContext.JobResultDir
+	}
+	return path.JoinPath(engineDataDir(rc), "tmp")
+}
+
+// readFullBackupResult reads result of full backup job on target pod, nil returned if result not found, e.g.
+// written by jobs of legacy images. Error is returned if the result is unable to read, which is worth a retry.
+func readFullBackupResult(rc *xstorev1reconcile.BackupContext, targetPod *corev1.Pod, resultDir, jobName string) (*fullBackupResult, error) {
+	command := []string{"cat", path.JoinPath(resultDir, jobName+".result")}
+	stdout := &bytes.Buffer{}
+	err := rc.ExecuteCommandOn(targetPod, "engine", command, control.ExecOptions{
+		Stdout: stdout,
+		Stderr: &bytes.Buffer{},
+	})
+	if err != nil {
+		if ee, ok := xstorectrlerrors.ExitError(err); ok && ee.ExitStatus() != 0 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseFullBackupResult(stdout.Bytes()), nil
+}
+
+// parseFullBackupResult parses result written by full backup job, malformed result is taken as failed.
+func parseFullBackupResult(data []byte) *fullBackupResult {
+	result := &fullBackupResult{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return &fullBackupResult{Success: false, Reason: "malformed full backup result: " + err.Error()}
+	}
+	return result
+}
+
 var WaitFullBackupJobFinished = NewStepBinder("WaitFullBackupJobFinished",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		xstoreBackup := rc.MustGetXStoreBackup()
@@ -255,20 +892,88 @@ var WaitFullBackupJobFinished = NewStepBinder("WaitFullBackupJobFinished",
 			return flow.RetryAfter(5*time.Second, "Full backup job may have not been created.")
 		}
 
-		if !k8shelper.IsJobCompleted(job) {
+		jobFailed := k8shelper.IsJobFailed(job)
+		if !k8shelper.IsJobCompleted(job) && !jobFailed {
 			return flow.Wait("Full Backup job is still running!", "job-name", job.Name)
 		}
-		flow.Logger().Info("Full Backup job completed!", "job-name", job.Name)
+
+		if k8shelper.IsJobDeadlineExceeded(job) {
+			reason := failBackupByJob(rc, job, xstoreconvention.BackupJobTypeFullBackup, xstorev1.XStoreBackupFullBackupComplete)
+			return flow.Retry("Full backup failed.", "job-name", job.Name, "reason", reason)
+		}
 
 		targetPod, err := rc.GetXStoreTargetPod()
 		if err != nil {
-			flow.Logger().Error(err, "Unable to get targetPod")
+			return flow.Error(err, "Unable to get target pod!")
+		}
+		if targetPod == nil {
+			return flow.Wait("Unable to find target pod!")
 		}
 		if xstoreBackup.Status.TargetPod == "" {
 			xstoreBackup.Status.TargetPod = targetPod.Name
 		}
 
-		command := []string{"cat", "/data/mysql/tmp/" + job.Name + ".idx"}
+		// A completed job may still produce an invalid backup, check the result written by the job
+		backupJobContext, err := getBackupJobContext(rc, flow.Logger())
+		if err != nil {
+			return flow.Error(err, "Unable to get task context for backup")
+		}
+		resultDir := jobResultDir(rc, backupJobContext)
+		result, err := readFullBackupResult(rc, targetPod, resultDir, job.Name)
+		if err != nil {
+			return flow.RetryAfter(5*time.Second, "Unable to read full backup result, error: "+err.Error(),
+				"job-name", job.Name)
+		}
+		// jobs of legacy images never write result, the completion along with index is checked as before
+		legacy := result == nil && !jobFailed
+		if legacy {
+			flow.Logger().Info("Full backup result not found, check completion and index only.", "job-name", job.Name)
+		}
+		if !legacy && (jobFailed || result == nil || !result.Success) {
+			reason := "full backup job failed"
+			if result != nil && result.Reason != "" {
+				reason = result.Reason
+			} else if !jobFailed {
+				reason = "full backup job completed without success result"
+			}
+			xstoreBackup.Status.Phase = xstorev1.XstoreBackupFailed
+			xstoreBackup.Status.Reason = reason
+			xstoreBackup.Status.FailureReason = polardbxv1polardbx.FailureReasonJobFailed
+			xstoreBackup.Status.Message = "full backup failed, job: " + job.Name
+			if jobFailed {
+				rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+					Type:    xstorev1.XStoreBackupFullBackupComplete,
+					Status:  corev1.ConditionFalse,
+					Reason:  "JobFailed",
+					Message: reason,
+				})
+			} else {
+				rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+					Type:    xstorev1.XStoreBackupVerified,
+					Status:  corev1.ConditionFalse,
+					Reason:  "VerificationFailed",
+					Message: reason,
+				})
+			}
+			return flow.Retry("Full backup failed.", "job-name", job.Name, "reason", reason)
+		}
+		flow.Logger().Info("Full Backup job completed!", "job-name", job.Name)
+		rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+			Type:    xstorev1.XStoreBackupFullBackupComplete,
+			Status:  corev1.ConditionTrue,
+			Reason:  "JobCompleted",
+			Message: "Full backup job completed: " + job.Name,
+		})
+		if !legacy {
+			rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+				Type:    xstorev1.XStoreBackupVerified,
+				Status:  corev1.ConditionTrue,
+				Reason:  "VerificationSucceeded",
+				Message: "Full backup verified by job: " + job.Name,
+			})
+		}
+
+		command := []string{"cat", path.JoinPath(resultDir, job.Name+".idx")}
 		stdout := &bytes.Buffer{}
 		stderr := &bytes.Buffer{}
 		err = rc.ExecuteCommandOn(targetPod, "engine", command, control.ExecOptions{
@@ -302,19 +1007,61 @@ var RemoveFullBackupJob = NewStepBinder("RemoveFullBackupJob",
 			return flow.Continue("Full backup job already removed!")
 		}
 
-		err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
-		if client.IgnoreNotFound(err) != nil {
-			return flow.Error(err, "Unable to remove full backup job", "job-name", job.Name)
+		propagation := rc.JobDeletePropagation()
+		if job.DeletionTimestamp.IsZero() {
+			err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(propagation))
+			if client.IgnoreNotFound(err) != nil {
+				return flow.Error(err, "Unable to remove full backup job", "job-name", job.Name)
+			}
+		}
+		if propagation == metav1.DeletePropagationForeground {
+			return flow.RetryAfter(5*time.Second, "Wait until full backup job removed.", "job-name", job.Name)
 		}
 
 		return flow.Continue("Full backup job removed!", "job-name", job.Name)
 	})
 
+// pxcBackupTerminatedMessage tells why the pxc backup will never advance, i.e. failed or being deleted, failure
+// reason of pxc backup included. Empty string is returned if the pxc backup is still in progress.
+func pxcBackupTerminatedMessage(polardbxBackup *polardbxv1.PolarDBXBackup) string {
+	switch polardbxBackup.Status.Phase {
+	case polardbxv1.BackupFailed, polardbxv1.BackupDeleting:
+	default:
+		return ""
+	}
+	message := fmt.Sprintf("polardbx backup %s is %s", polardbxBackup.Name, polardbxBackup.Status.Phase)
+	if polardbxBackup.Status.Reason != "" {
+		message += ", reason: " + polardbxBackup.Status.Reason
+	}
+	if polardbxBackup.Status.Message != "" {
+		message += ", message: " + polardbxBackup.Status.Message
+	}
+	return message
+}
+
+// failBackupIfPXCBackupTerminated fails the xstore backup if the pxc backup it waits for is terminated, otherwise
+// the xstore backup waits forever. It returns whether the xstore backup failed.
+func failBackupIfPXCBackupTerminated(rc *xstorev1reconcile.BackupContext, polardbxBackup *polardbxv1.PolarDBXBackup) bool {
+	message := pxcBackupTerminatedMessage(polardbxBackup)
+	if message == "" {
+		return false
+	}
+	backup := rc.MustGetXStoreBackup()
+	backup.Status.Phase = xstorev1.XstoreBackupFailed
+	backup.Status.Reason = "PolarDBXBackupTerminated"
+	backup.Status.FailureReason = polardbxv1polardbx.FailureReasonTerminated
+	backup.Status.Message = message
+	return true
+}
+
 var WaitBinlogOffsetCollected = NewStepBinder("WaitBinlogCollected",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		polardbxBackup, err := rc.GetPolarDBXBackup()
 		if err != nil {
-			flow.Error(err, "Unable to find polardbxBackup")
+			return flow.Error(err, "Unable to find polardbxBackup")
+		}
+		if failBackupIfPXCBackupTerminated(rc, polardbxBackup) {
+			return flow.Retry("PolarDBX backup terminated, backup failed.", "pxcBackup", polardbxBackup.Name)
 		}
 		if polardbxBackup.Status.Phase != polardbxv1.BackupCalculating {
 			return flow.RetryAfter(5*time.Second, "Wait polardbx backup Collected", "pxcBackup", polardbxBackup.Name)
@@ -322,16 +1069,43 @@ var WaitBinlogOffsetCollected = NewStepBinder("WaitBinlogCollected",
 
 		// get backup task config map
 		xstoreBackup := rc.MustGetXStoreBackup()
-		backupJobContext := &BackupJobContext{}
-		err = rc.GetTaskContext(xstoreconvention.BackupConfigMapKey, &backupJobContext)
+		backupJobContext, err := getBackupJobContext(rc, flow.Logger())
 		if err != nil {
 			return flow.Error(err, "Unable to get task context for backup")
 		}
 
+		// extend the collect range by margin, which must not reach purged binlog
+		startIndex := polardbxBackup.Status.CollectStartIndexMap[xstoreBackup.Status.TargetPod]
+		endIndex := polardbxBackup.Status.CollectEndIndexMap[xstoreBackup.Status.TargetPod]
+		if xstoreBackup.Spec.BinlogCollectMargin != nil {
+			targetPod, err := rc.GetXStoreTargetPod()
+			if err != nil {
+				return flow.Error(err, "Unable to find target pod!")
+			}
+			if targetPod == nil {
+				return flow.Wait("Unable to find target pod!")
+			}
+			var purged bool
+			startIndex, endIndex, purged, err = applyBinlogCollectMargin(rc, targetPod,
+				xstoreBackup.Spec.BinlogCollectMargin, startIndex, endIndex)
+			if purged {
+				xstoreBackup.Status.Phase = xstorev1.XstoreBackupFailed
+				xstoreBackup.Status.Reason = ReasonBinlogCollectMarginPurged
+				xstoreBackup.Status.FailureReason = polardbxv1polardbx.FailureReasonBinlogPurged
+				xstoreBackup.Status.Message = err.Error()
+				return flow.Retry("Binlog collect margin reaches purged binlog, backup failed.")
+			}
+			if err != nil {
+				return flow.RetryErr(err, "Unable to apply binlog collect margin", "pod", targetPod.Name)
+			}
+		}
+		xstoreBackup.Status.CollectRange = startIndex + "~" + endIndex
+
 		// persist binlog offset info into config map
-		backupJobContext.CollectStartIndex = polardbxBackup.Status.CollectStartIndexMap[xstoreBackup.Status.TargetPod]
-		backupJobContext.CollectEndIndex = polardbxBackup.Status.CollectEndIndexMap[xstoreBackup.Status.TargetPod]
-		err = rc.SaveTaskContext(xstoreconvention.BackupConfigMapKey, &backupJobContext)
+		backupJobContext.CollectStartIndex = startIndex
+		backupJobContext.CollectEndIndex = endIndex
+		backupJobContext.CollectParallelism = rc.XStoreContext().Config().Backup().GetCollectJobParallelism()
+		err = rc.SaveTaskContext(xstoreconvention.BackupConfigMapKey, backupJobContext)
 		if err != nil {
 			return flow.Error(err, "Unable to update task context for backup")
 		}
@@ -339,11 +1113,145 @@ var WaitBinlogOffsetCollected = NewStepBinder("WaitBinlogCollected",
 		return flow.Continue("Binlog Collected!")
 	})
 
+// ReasonBinlogPurged is the failure reason of backup whose binlog required to collect has been purged.
+const ReasonBinlogPurged = "BinlogPurged"
+
+// isBinlogFilePurged tells whether binlog file has been purged given the oldest binlog file available. Files are
+// compared by their numeric indexes, since width of indexes differs once they grow beyond the padding.
+func isBinlogFilePurged(binlogFile, oldest string) (bool, error) {
+	_, index, err := binlogmeta.ParseBinaryLogFileName(binlogFile)
+	if err != nil {
+		return false, fmt.Errorf("invalid binlog file %s: %w", binlogFile, err)
+	}
+	_, oldestIndex, err := binlogmeta.ParseBinaryLogFileName(oldest)
+	if err != nil {
+		return false, fmt.Errorf("invalid binlog file %s: %w", oldest, err)
+	}
+	return index < oldestIndex, nil
+}
+
+var CheckBinlogNotPurged = NewStepBinder("CheckBinlogNotPurged",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backupJobContext, err := getBackupJobContext(rc, flow.Logger())
+		if err != nil {
+			return flow.Error(err, "Unable to get task context for backup")
+		}
+		if backupJobContext.CollectStartIndex == "" {
+			return flow.Continue("No start offset to check.")
+		}
+
+		targetPod, err := rc.GetXStoreTargetPod()
+		if err != nil {
+			return flow.Error(err, "Unable to find target pod!")
+		}
+		if targetPod == nil {
+			return flow.Wait("Unable to find target pod!")
+		}
+		groupManager, err := rc.GetXstoreGroupManagerByPod(targetPod)
+		if err != nil || groupManager == nil {
+			return flow.RetryErr(err, "Unable to get group manager of target pod", "pod", targetPod.Name)
+		}
+		defer groupManager.Close()
+		binlogFiles, err := groupManager.ShowBinaryLogs()
+		if err != nil {
+			return flow.RetryErr(err, "Unable to show binary logs", "pod", targetPod.Name)
+		}
+		if len(binlogFiles) == 0 {
+			return flow.RetryAfter(5*time.Second, "No binlog found on target pod", "pod", targetPod.Name)
+		}
+
+		xstoreBackup := rc.MustGetXStoreBackup()
+		oldest, latest := binlogFiles[0], binlogFiles[len(binlogFiles)-1]
+		xstoreBackup.Status.AvailableBinlogRange = fmt.Sprintf("%s~%s", oldest, latest)
+
+		startFile := strings.SplitN(backupJobContext.CollectStartIndex, ":", 2)[0]
+		purged, err := isBinlogFilePurged(startFile, oldest)
+		if err != nil {
+			return flow.Error(err, "Unable to compare binlog files", "start-offset", backupJobContext.CollectStartIndex)
+		}
+		if purged {
+			xstoreBackup.Status.Phase = xstorev1.XstoreBackupFailed
+			xstoreBackup.Status.Reason = ReasonBinlogPurged
+			xstoreBackup.Status.FailureReason = polardbxv1polardbx.FailureReasonBinlogPurged
+			xstoreBackup.Status.Message = fmt.Sprintf("required binlog %s has been purged, available binlog range: %s",
+				startFile, xstoreBackup.Status.AvailableBinlogRange)
+			return flow.Retry("Required binlog purged, backup failed.", "start-offset", backupJobContext.CollectStartIndex)
+		}
+		return flow.Continue("Required binlog available.", "range", xstoreBackup.Status.AvailableBinlogRange)
+	})
+
+// findBinlogGap finds the range of consensus log indexes missing between the commit index of full backup and the
+// oldest binlog available, logs are listed from the oldest to the latest. ok is false if there is no gap.
+func findBinlogGap(commitIndex int64, logs []*group.ConsensusLog) (from, to int64, ok bool) {
+	if len(logs) == 0 || logs[0].StartLogIndex <= commitIndex+1 {
+		return 0, 0, false
+	}
+	return commitIndex + 1, logs[0].StartLogIndex - 1, true
+}
+
+func hasBackupCondition(backup *xstorev1.XStoreBackup, condType polardbxv1xstore.ConditionType,
+	status corev1.ConditionStatus) bool {
+	for _, cond := range backup.Status.Conditions {
+		if cond.Type == condType {
+			return cond.Status == status
+		}
+	}
+	return false
+}
+
+var CheckBinlogContinuity = NewStepBinder("CheckBinlogContinuity",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		xstoreBackup := rc.MustGetXStoreBackup()
+		if xstoreBackup.Status.CommitIndex <= 0 {
+			return flow.Continue("No commit index to check.")
+		}
+
+		// failure of check does not block backup, but leaves continuity of binlog unknown
+		targetPod, err := rc.GetXStoreTargetPod()
+		if err != nil || targetPod == nil {
+			return flow.Continue("Unable to find target pod, skip checking binlog continuity.")
+		}
+		groupManager, err := rc.GetXstoreGroupManagerByPod(targetPod)
+		if err != nil || groupManager == nil {
+			return flow.Continue("Unable to get group manager of target pod, skip checking binlog continuity.",
+				"pod", targetPod.Name)
+		}
+		defer groupManager.Close()
+		logs, err := groupManager.ShowConsensusLogs()
+		if err != nil {
+			return flow.Continue("Unable to show consensus logs, skip checking binlog continuity.",
+				"pod", targetPod.Name, "error", err.Error())
+		}
+
+		from, to, hasGap := findBinlogGap(xstoreBackup.Status.CommitIndex, logs)
+		if !hasGap {
+			rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+				Type:    xstorev1.XStoreBackupBinlogContinuous,
+				Status:  corev1.ConditionTrue,
+				Reason:  "BinlogContinuous",
+				Message: "binlog is continuous from commit index " + strconv.FormatInt(xstoreBackup.Status.CommitIndex, 10),
+			})
+			return flow.Continue("Binlog continuous from commit index.")
+		}
+
+		message := fmt.Sprintf("binlog of index %d~%d is unavailable on %s, point-in-time recovery is not continuous "+
+			"from the full backup at commit index %d", from, to, targetPod.Name, xstoreBackup.Status.CommitIndex)
+		if !hasBackupCondition(xstoreBackup, xstorev1.XStoreBackupBinlogContinuous, corev1.ConditionFalse) {
+			rc.RecordEvent(xstoreBackup, corev1.EventTypeWarning, "BinlogGap", message)
+		}
+		rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+			Type:    xstorev1.XStoreBackupBinlogContinuous,
+			Status:  corev1.ConditionFalse,
+			Reason:  "BinlogGap",
+			Message: message,
+		})
+		return flow.Continue("Binlog gap found after commit index.", "from", from, "to", to)
+	})
+
 var StartCollectBinlogJob = NewStepBinder("StartCollectBinlogJob",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		// check existence of backup job context
-		backupJobContext := &BackupJobContext{}
-		err := rc.GetTaskContext(xstoreconvention.BackupConfigMapKey, &backupJobContext)
+		backupJobContext, err := getBackupJobContext(rc, flow.Logger())
 		if err != nil {
 			return flow.Error(err, "Unable to get task context for backup")
 		}
@@ -377,10 +1285,15 @@ var StartCollectBinlogJob = NewStepBinder("StartCollectBinlogJob",
 		}
 		jobName := xstoreconvention.NewBackupJobName(targetPod, xstoreconvention.BackupJobTypeCollect)
 
-		job, err = newCollectJob(xstoreBackup, targetPod, *polardbxBackup, jobName)
+		job, err = newCollectJob(xstoreBackup, targetPod, *polardbxBackup, jobName, backupJobContext)
 		if err != nil {
 			return flow.Error(err, "Unable to create CollectJob")
 		}
+		timeout, err := rc.XStoreContext().Config().Backup().GetCollectJobTimeout()
+		if err != nil {
+			return flow.Error(err, "Unable to get timeout of collect job")
+		}
+		setJobActiveDeadline(job, timeout)
 
 		if err = rc.SetControllerRefAndCreate(job); err != nil {
 			return flow.Error(err, "Unable to create job to initialize data")
@@ -426,6 +1339,10 @@ var WaitCollectBinlogJobFinished = NewStepBinder("WaitCollectBinlogJobFinished",
 			return flow.Error(errors.New("collect binlog job abnormal"), "Collect binlog job not found, retry limits reached!")
 		}
 
+		if k8shelper.IsJobFailed(job) {
+			reason := failBackupByJob(rc, job, xstoreconvention.BackupJobTypeCollect, "")
+			return flow.Retry("Collect binlog failed.", "job-name", job.Name, "reason", reason)
+		}
 		if !k8shelper.IsJobCompleted(job) {
 			return flow.Wait("Collect binlog is still running!", "job-name", job.Name)
 		}
@@ -444,9 +1361,15 @@ var RemoveCollectBinlogJob = NewStepBinder("RemoveCollectBinlogJob",
 			return flow.Continue("Collect binlog job already removed!")
 		}
 
-		err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
-		if client.IgnoreNotFound(err) != nil {
-			return flow.Error(err, "Unable to remove collect binlog job", "job-name", job.Name)
+		propagation := rc.JobDeletePropagation()
+		if job.DeletionTimestamp.IsZero() {
+			err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(propagation))
+			if client.IgnoreNotFound(err) != nil {
+				return flow.Error(err, "Unable to remove collect binlog job", "job-name", job.Name)
+			}
+		}
+		if propagation == metav1.DeletePropagationForeground {
+			return flow.RetryAfter(5*time.Second, "Wait until collect binlog job removed.", "job-name", job.Name)
 		}
 
 		return flow.Continue("Collect binlog job removed!", "job-name", job.Name)
@@ -456,21 +1379,20 @@ var WaitPXCSeekCpJobFinished = NewStepBinder("WaitPXCSeekCpJobFinished",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		polardbxBackup, err := rc.GetPolarDBXBackup()
 		if err != nil {
-			flow.Error(err, "Unable to find polardbxBackup")
+			return flow.Error(err, "Unable to find polardbxBackup")
+		}
+		if failBackupIfPXCBackupTerminated(rc, polardbxBackup) {
+			return flow.Retry("PolarDBX backup terminated, backup failed.", "polardbxbackup", polardbxBackup.Name)
 		}
 		if polardbxBackup.Status.Phase != polardbxv1.BinlogBackuping {
 			return flow.RetryAfter(5*time.Second, "Wait polardbx backup Calculating", "polardbxbackup", polardbxBackup.Name)
 		}
-		if err != nil {
-			flow.Error(err, "Unable to get binlogOffset!")
-		}
 		return flow.Continue("Binlog Collected!")
 	})
 
 var StartBinlogBackupJob = NewStepBinder("StartBinlogBackupJob",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
-		backupJobContext := &BackupJobContext{}
-		err := rc.GetTaskContext(xstoreconvention.BackupConfigMapKey, &backupJobContext)
+		backupJobContext, err := getBackupJobContext(rc, flow.Logger())
 		if err != nil {
 			return flow.Error(err, "Unable to get task context for backup")
 		}
@@ -492,16 +1414,25 @@ var StartBinlogBackupJob = NewStepBinder("StartBinlogBackupJob",
 			return flow.Continue("Collect job already started!", "job-name", job.Name)
 		}
 
+		if result, done := failBackupIfEncryptionKeyChanged(rc, flow, backupJobContext); done {
+			return result, nil
+		}
+
 		jobName := xstoreconvention.NewBackupJobName(targetPod, xstoreconvention.BackupJobTypeBinlogBackup)
 
 		if targetPod.Labels[polardbxmeta.LabelRole] == polardbxmeta.RoleGMS {
-			job, err = newBinlogBackupJob(xstoreBackup, targetPod, jobName, true)
+			job, err = newBinlogBackupJob(xstoreBackup, targetPod, jobName, true, backupJobContext)
 		} else {
-			job, err = newBinlogBackupJob(xstoreBackup, targetPod, jobName, false)
+			job, err = newBinlogBackupJob(xstoreBackup, targetPod, jobName, false, backupJobContext)
 		}
 		if err != nil {
 			return flow.Error(err, "Unable to create CollectJob")
 		}
+		timeout, err := rc.XStoreContext().Config().Backup().GetBinlogBackupJobTimeout()
+		if err != nil {
+			return flow.Error(err, "Unable to get timeout of binlog backup job")
+		}
+		setJobActiveDeadline(job, timeout)
 
 		if err = rc.SetControllerRefAndCreate(job); err != nil {
 			return flow.Error(err, "Unable to create job to initialize data")
@@ -520,9 +1451,22 @@ var WaitBinlogBackupJobFinished = NewStepBinder("WaitBinlogBackupJobFinished",
 			flow.Logger().Info("Binlog backup job nil!", "err", err)
 			return flow.Continue("Binlog backup job removed!")
 		}
+		if k8shelper.IsJobFailed(job) {
+			reason := failBackupByJob(rc, job, xstoreconvention.BackupJobTypeBinlogBackup, xstorev1.XStoreBackupBinlogBackupComplete)
+			// full backup is kept, so that binlog backup can be rerun from the failure
+			backup := rc.MustGetXStoreBackup()
+			backup.Status.Message += ", rerun binlog backup by annotation " + xstoremeta.AnnotationRerunBinlogBackup
+			return flow.Retry("Binlog backup failed.", "job-name", job.Name, "reason", reason)
+		}
 		if !k8shelper.IsJobCompleted(job) {
 			return flow.Wait("Binlog backup job is still running!", "job-name", job.Name)
 		}
+		rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+			Type:    xstorev1.XStoreBackupBinlogBackupComplete,
+			Status:  corev1.ConditionTrue,
+			Reason:  "JobCompleted",
+			Message: "Binlog backup job completed: " + job.Name,
+		})
 		return flow.Continue("Binlog backup job wait finished!", "job-name", job.Name)
 	})
 
@@ -534,6 +1478,106 @@ var UpdateBackupStatus = NewStepBinder("UpdateBackupStatus",
 		return flow.Continue("Backup status update!")
 	})
 
+// finalizeGraceRemaining returns the time left of finalize grace period, which starts from the end of backup.
+func finalizeGraceRemaining(backup *xstorev1.XStoreBackup, now time.Time) time.Duration {
+	gracePeriod := backup.GetFinalizeGracePeriod()
+	if gracePeriod <= 0 || backup.Status.EndTime == nil {
+		return 0
+	}
+	return backup.Status.EndTime.Add(gracePeriod).Sub(now)
+}
+
+var WaitFinalizeGracePeriod = NewStepBinder("WaitFinalizeGracePeriod",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		now := time.Now()
+		if remaining := finalizeGraceRemaining(backup, now); remaining > 0 {
+			backup.Status.Message = "finalize grace period not elapsed, backup finishes at " +
+				now.Add(remaining).Format(time.RFC3339)
+			return flow.RetryAfter(remaining, "Wait for finalize grace period.", "remaining", remaining)
+		}
+		backup.Status.Message = ""
+		return flow.Continue("Finalize grace period elapsed.")
+	})
+
+// RecordSnapshotTimestamp takes the completion of full backup job as backup set timestamp of snapshot-only backup,
+// which has no binlog to recover beyond that point.
+var RecordSnapshotTimestamp = NewStepBinder("RecordSnapshotTimestamp",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		nowTime := metav1.Now()
+		backup.Status.EndTime = &nowTime
+		if backup.Status.BackupSetTimestamp != nil {
+			return flow.Pass()
+		}
+
+		timestamp := nowTime
+		job, err := rc.GetXStoreBackupJob()
+		if client.IgnoreNotFound(err) != nil {
+			return flow.Error(err, "Unable to get full backup job!")
+		}
+		if job != nil && job.Status.CompletionTime != nil {
+			timestamp = *job.Status.CompletionTime
+		}
+		backup.Status.BackupSetTimestamp = &timestamp
+		return flow.Continue("Snapshot timestamp recorded!", "timestamp", timestamp)
+	})
+
+// parseExcludedBinlogs parses the binlog files skipped by binlog backup job, one file per line.
+func parseExcludedBinlogs(output string) []string {
+	excluded := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			excluded = append(excluded, line)
+		}
+	}
+	return excluded
+}
+
+// RecordExcludedBinlogs records the binlog files skipped by binlog backup job, and warns that they leave gaps
+// in the recoverable range, since binlog backup covers only files required from the full backup.
+var RecordExcludedBinlogs = NewStepBinder("RecordExcludedBinlogs",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		if len(backup.Spec.BinlogExcludePatterns) == 0 {
+			return flow.Pass()
+		}
+		targetPod, err := rc.GetXStoreTargetPod()
+		if err != nil {
+			return flow.Error(err, "Unable to get targetPod")
+		}
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+		excludedBinlogsPath := path.JoinPath(engineDataDir(rc), "backup", "binlogbackup", "excluded_binlogs")
+		err = rc.ExecuteCommandOn(targetPod, "engine", []string{"cat", excludedBinlogsPath},
+			control.ExecOptions{
+				Logger: flow.Logger(),
+				Stdout: stdout,
+				Stderr: stderr,
+			})
+		if err != nil {
+			return flow.RetryErr(err, "Failed to cat excluded binlogs", "pod", targetPod.Name, "stderr", stderr.String())
+		}
+		excluded := parseExcludedBinlogs(stdout.String())
+		backup.Status.ExcludedBinlogs = excluded
+		if len(excluded) == 0 {
+			return flow.Continue("No binlog excluded.")
+		}
+
+		message := fmt.Sprintf("binlog %s excluded by patterns, point-in-time recovery is not continuous "+
+			"across them", strings.Join(excluded, ", "))
+		if !hasBackupCondition(backup, xstorev1.XStoreBackupBinlogContinuous, corev1.ConditionFalse) {
+			rc.RecordEvent(backup, corev1.EventTypeWarning, "BinlogExcluded", message)
+		}
+		rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+			Type:    xstorev1.XStoreBackupBinlogContinuous,
+			Status:  corev1.ConditionFalse,
+			Reason:  "BinlogExcluded",
+			Message: message,
+		})
+		return flow.Continue("Binlog excluded.", "excluded", excluded)
+	})
+
 var ExtractLastEventTimestamp = NewStepBinder("ExtractLastEventTimestamp",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		backup := rc.MustGetXStoreBackup()
@@ -544,7 +1588,7 @@ var ExtractLastEventTimestamp = NewStepBinder("ExtractLastEventTimestamp",
 		if err != nil {
 			flow.Error(err, "Unable to get targetPod")
 		}
-		Command := []string{"cat", "/data/mysql/backup/binlogbackup/last_event_timestamp"}
+		Command := []string{"cat", path.JoinPath(engineDataDir(rc), "backup", "binlogbackup", "last_event_timestamp")}
 		stdout := &bytes.Buffer{}
 		stderr := &bytes.Buffer{}
 		err = rc.ExecuteCommandOn(targetPod, "engine", Command, control.ExecOptions{
@@ -567,10 +1611,53 @@ var ExtractLastEventTimestamp = NewStepBinder("ExtractLastEventTimestamp",
 			return flow.Error(err, "Invalid last event timestamp", "pod", targetPod.Name, "error", err)
 		}
 		timestamp := metav1.Unix(timestampNum, 0)
+
+		// the timestamp comes from clock of target pod, while start and end time come from clock of operator
+		skew, err := measureClockSkew(rc, targetPod, flow.Logger())
+		if err != nil {
+			return flow.RetryErr(err, "Failed to measure clock skew", "pod", targetPod.Name)
+		}
+		backup.Status.ClockSkewSeconds = int64(skew.Round(time.Second).Seconds())
+		threshold, err := rc.XStoreContext().Config().Backup().GetClockSkewThreshold()
+		if err != nil {
+			return flow.Error(err, "Unable to get clock skew threshold")
+		}
+		if skew > threshold || skew < -threshold {
+			message := fmt.Sprintf("clock of pod %s differs from operator by %s", targetPod.Name, skew.Round(time.Second))
+			if rc.XStoreContext().Config().Backup().IsClockSkewCorrectionEnabled() {
+				timestamp = metav1.NewTime(timestamp.Add(-skew))
+				message += ", backup set timestamp corrected"
+			}
+			flow.Logger().Info("Warning: clock skew detected", "pod", targetPod.Name, "skew", skew)
+			rc.RecordEvent(backup, corev1.EventTypeWarning, "ClockSkewDetected", message)
+		}
 		backup.Status.BackupSetTimestamp = &timestamp
 		return flow.Continue("Extract binlog last event timestamp finished!", "pod", targetPod.Name)
 	})
 
+// measureClockSkew returns the clock of pod minus the clock of operator. Clock of pod is compared with the middle
+// of the command execution to cancel out the round trip, the result is accurate to about a second.
+func measureClockSkew(rc *xstorev1reconcile.BackupContext, pod *corev1.Pod, logger logr.Logger) (time.Duration, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	before := time.Now()
+	err := rc.ExecuteCommandOn(pod, "engine", []string{"date", "+%s"}, control.ExecOptions{
+		Logger: logger,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	after := time.Now()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get date of pod, stderr: %s, error: %w", stderr.String(), err)
+	}
+	podUnix, err := strconv.ParseInt(strings.TrimSpace(stdout.String()), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid date of pod: %w", err)
+	}
+	middle := before.Add(after.Sub(before) / 2)
+	return time.Unix(podUnix, 0).Sub(middle), nil
+}
+
 var RemoveBinlogBackupJob = NewStepBinder("RemoveBinlogBackupJob",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		job, err := rc.GetBackupBinlogJob()
@@ -581,17 +1668,38 @@ var RemoveBinlogBackupJob = NewStepBinder("RemoveBinlogBackupJob",
 			return flow.Continue("Binlog backup job already removed!")
 		}
 
-		err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
-		if client.IgnoreNotFound(err) != nil {
-			return flow.Error(err, "Unable to remove binlog backup job", "job-name", job.Name)
+		propagation := rc.JobDeletePropagation()
+		if job.DeletionTimestamp.IsZero() {
+			err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(propagation))
+			if client.IgnoreNotFound(err) != nil {
+				return flow.Error(err, "Unable to remove binlog backup job", "job-name", job.Name)
+			}
+		}
+		if propagation == metav1.DeletePropagationForeground {
+			return flow.RetryAfter(5*time.Second, "Wait until binlog backup job removed.", "job-name", job.Name)
 		}
 
 		return flow.Continue("Binlog backup job removed!", "job-name", job.Name)
 	})
 
+// scheduledDeletionTime returns when the backup is to be deleted by retention, i.e. end time plus retention time.
+// Nil is returned for protected backups, which are retained forever, or if backup not ended.
+func scheduledDeletionTime(backup *xstorev1.XStoreBackup) *metav1.Time {
+	if IsBackupProtected(backup) || backup.Status.EndTime == nil {
+		return nil
+	}
+	toCleanTime := metav1.NewTime(backup.Status.EndTime.Add(backup.Spec.RetentionTime.Duration))
+	return &toCleanTime
+}
+
 var RemoveXSBackupOverRetention = NewStepBinder("RemoveXSBackupOverRetention",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		backup := rc.MustGetXStoreBackup()
+		backup.Status.ScheduledDeletionTime = scheduledDeletionTime(backup)
+		if IsBackupProtected(backup) {
+			flow.Logger().Info("Backup is protected, skip retention deletion.", "XSBackup-name", backup.Name)
+			return flow.Continue("Protected backup retained!", "XSBackup-name", backup.Name)
+		}
 		if backup.Spec.RetentionTime.Duration.Seconds() > 0 {
 			toCleanTime := backup.Status.EndTime.Add(backup.Spec.RetentionTime.Duration)
 			now := time.Now()
@@ -625,7 +1733,10 @@ var WaitPXCBinlogBackupFinished = NewStepBinder("WaitPXCBinlogBackupFinished",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		polardbxBackup, err := rc.GetPolarDBXBackup()
 		if err != nil {
-			flow.Error(err, "Unable to find get PolarDBX backup")
+			return flow.Error(err, "Unable to find get PolarDBX backup")
+		}
+		if failBackupIfPXCBackupTerminated(rc, polardbxBackup) {
+			return flow.Retry("PolarDBX backup terminated, backup failed.", "pxc backup", polardbxBackup.Name)
 		}
 		if polardbxBackup.Status.Phase != polardbxv1.MetadataBackuping {
 			return flow.RetryAfter(5*time.Second, "Wait until PolarDBX binlog backup finished", "pxc backup", polardbxBackup.Name)
@@ -633,97 +1744,329 @@ var WaitPXCBinlogBackupFinished = NewStepBinder("WaitPXCBinlogBackupFinished",
 		return flow.Continue("PolarDBX binlog backup finished.")
 	})
 
+// saveSharedBackupSecret saves accounts of xstore into the backup secret shared by backups with the same accounts,
+// each of which owns the secret, so that the secret is garbage collected after all of them are deleted. Name of the
+// shared secret is returned.
+func saveSharedBackupSecret(rc *xstorev1reconcile.BackupContext, secret *corev1.Secret) (string, error) {
+	backup := rc.MustGetXStoreBackup()
+	secretName := xstoreconvention.NewSharedBackupSecretName(backup.Spec.XStore.Name, secret.Data)
+	backupSecret, err := rc.GetSecret(secretName)
+	if client.IgnoreNotFound(err) != nil {
+		return "", fmt.Errorf("unable to get shared backup secret %s: %w", secretName, err)
+	}
+	if backupSecret == nil {
+		backupSecret, err = rc.NewSecretFromXStore(secret)
+		if err != nil {
+			return "", err
+		}
+		backupSecret.Name = secretName
+		if err := controllerutil.SetOwnerReference(backup, backupSecret, rc.Scheme()); err != nil {
+			return "", err
+		}
+		if err := rc.Client().Create(rc.Context(), backupSecret); err != nil {
+			return "", fmt.Errorf("unable to create shared backup secret %s: %w", secretName, err)
+		}
+		return secretName, nil
+	}
+
+	if !reflect.DeepEqual(backupSecret.Data, secret.Data) {
+		return "", fmt.Errorf("shared backup secret %s conflicts with accounts of xstore", secretName)
+	}
+	if err := controllerutil.SetOwnerReference(backup, backupSecret, rc.Scheme()); err != nil {
+		return "", err
+	}
+	if err := rc.Client().Update(rc.Context(), backupSecret); err != nil {
+		return "", fmt.Errorf("unable to update owners of shared backup secret %s: %w", secretName, err)
+	}
+	return secretName, nil
+}
+
 var SaveXStoreSecrets = NewStepBinder("SaveXStoreSecrets",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
 		backup := rc.MustGetXStoreBackup()
-		backupSecret, err := rc.GetSecret(backup.Name)
-		if backupSecret != nil {
+		if backup.Status.SecretName != "" {
 			return flow.Continue("Already have backup secret")
 		}
+		if !rc.XStoreContext().Config().Backup().IsXStoreSecretSharingEnabled() {
+			backupSecret, err := rc.GetSecret(backup.Name)
+			if backupSecret != nil {
+				backup.Status.SecretName = backupSecret.Name
+				if backup.Status.SecretSnapshotTime == nil {
+					backup.Status.SecretSnapshotTime = backupSecret.CreationTimestamp.DeepCopy()
+				}
+				return flow.Continue("Already have backup secret")
+			}
+
+			secret, err := rc.GetSecret(backup.Spec.XStore.Name)
+			if err != nil {
+				return flow.Error(err, "Unable to get secret for xstore", "xstore_name", backup.Spec.XStore.Name)
+			}
+			backupSecret, err = rc.NewSecretFromXStore(secret)
+			if err != nil {
+				return flow.Error(err, "Unable to new account secret while backuping")
+			}
+			err = rc.SetControllerRefAndCreate(backupSecret)
+			if err != nil {
+				return flow.Error(err, "Unable to create account secret while backuping")
+			}
+			now := metav1.Now()
+			backup.Status.SecretName = backupSecret.Name
+			backup.Status.SecretSnapshotTime = &now
+			return flow.Continue("XStore Secret Saved!")
+		}
 
 		secret, err := rc.GetSecret(backup.Spec.XStore.Name)
 		if err != nil {
 			return flow.Error(err, "Unable to get secret for xstore", "xstore_name", backup.Spec.XStore.Name)
 		}
-		backupSecret, err = rc.NewSecretFromXStore(secret)
-		if err != nil {
-			return flow.Error(err, "Unable to new account secret while backuping")
-		}
-		err = rc.SetControllerRefAndCreate(backupSecret)
+		secretName, err := saveSharedBackupSecret(rc, secret)
 		if err != nil {
-			return flow.Error(err, "Unable to create account secret while backuping")
+			return flow.Error(err, "Unable to save shared backup secret")
 		}
-		return flow.Continue("XStore Secret Saved!")
+		now := metav1.Now()
+		backup.Status.SecretName = secretName
+		backup.Status.SecretSnapshotTime = &now
+		return flow.Continue("XStore Secret Saved!", "secret", secretName)
 	})
 
-var UploadXStoreMetadata = NewStepBinder("UploadXStoreMetadata",
+// RefreshXStoreSecrets refreshes the backup secret from the live secret of xstore if required by spec, so that
+// passwords rotated during backup are restored. Metadata already uploaded is marked stale to be uploaded again.
+var RefreshXStoreSecrets = NewStepBinder("RefreshXStoreSecrets",
 	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
-		xstore, err := rc.GetXStore()
-		if err != nil {
-			return flow.Error(err, "Unable to find xstore.")
-		}
 		backup := rc.MustGetXStoreBackup()
-		backupSecret, err := rc.GetSecret(backup.Name)
-		if client.IgnoreNotFound(err) != nil {
-			return flow.Error(err, "Unable to get secret for xstore", "xstore name", xstore.Name)
-		}
-
-		metadata := factory.MetadataBackup{
-			XstoreMetadataList:         make([]factory.XstoreMetadata, 0, 1),
-			BackupSetName:              backup.Name,
-			BackupRootPath:             backup.Status.BackupRootPath,
-			StartTime:                  backup.Status.StartTime,
-			EndTime:                    backup.Status.EndTime,
-			LatestRecoverableTimestamp: backup.Status.BackupSetTimestamp,
-		}
-
-		xstoreMetadata := factory.XstoreMetadata{
-			Name:            xstore.Name,
-			UID:             xstore.UID,
-			BackupName:      backup.Name,
-			LastCommitIndex: backup.Status.CommitIndex,
-			Secrets:         make([]polardbxv1polardbx.PrivilegeItem, 0, len(backupSecret.Data)),
-			TargetPod:       backup.Status.TargetPod,
-			Spec:            backup.Status.XStoreSpecSnapshot.DeepCopy(),
-		}
-
-		for user, passwd := range backupSecret.Data {
-			xstoreMetadata.Secrets = append(
-				xstoreMetadata.Secrets,
-				polardbxv1polardbx.PrivilegeItem{
-					Username: user,
-					Password: string(passwd),
-				})
-		}
-		metadata.XstoreMetadataList = append(metadata.XstoreMetadataList, xstoreMetadata)
-
-		// parse metadata to json string
-		jsonString, err := json.Marshal(metadata)
-		if err != nil {
-			return flow.RetryErr(err, "Failed to marshal metadata, retry to upload metadata")
+		if !backup.Spec.RefreshSecret || backup.Status.SecretName == "" {
+			return flow.Pass()
 		}
-
-		// init filestream client and upload formatted metadata
-		filestreamClient, err := rc.XStoreContext().GetFilestreamClient()
-		metadataBackupPath := fmt.Sprintf("%s/metadata", metadata.BackupRootPath)
+		secret, err := rc.GetSecret(backup.Spec.XStore.Name)
 		if err != nil {
-			return flow.RetryAfter(10*time.Second, "Failed to get filestream client, error: "+err.Error())
+			return flow.Error(err, "Unable to get secret for xstore", "xstore_name", backup.Spec.XStore.Name)
 		}
-		filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(backup.Spec.StorageProvider.StorageName)
-		if err != nil {
-			return flow.RetryAfter(10*time.Second, "Unsupported storage provided")
+		backupSecret, err := rc.GetSecret(backup.Status.SecretName)
+		if client.IgnoreNotFound(err) != nil {
+			return flow.Error(err, "Unable to get backup secret", "secret", backup.Status.SecretName)
 		}
-		actionMetadata := filestream.ActionMetadata{
-			Action:    filestreamAction.Upload,
-			Sink:      backup.Spec.StorageProvider.Sink,
-			RequestId: uuid.New().String(),
-			Filename:  metadataBackupPath,
+		if backupSecret != nil && reflect.DeepEqual(backupSecret.Data, secret.Data) {
+			return flow.Pass()
 		}
-		sendBytes, err := filestreamClient.Upload(bytes.NewReader(jsonString), actionMetadata)
-		if err != nil {
-			return flow.RetryAfter(10*time.Second, "Upload metadata failed, error: "+err.Error())
+
+		if !rc.XStoreContext().Config().Backup().IsXStoreSecretSharingEnabled() {
+			if backupSecret == nil {
+				// let SaveXStoreSecrets create it again
+				backup.Status.SecretName = ""
+				return flow.Retry("Backup secret not found, save again.")
+			}
+			newSecret, err := rc.NewSecretFromXStore(secret)
+			if err != nil {
+				return flow.Error(err, "Unable to new account secret while backuping")
+			}
+			backupSecret.Data = newSecret.Data
+			if err := rc.Client().Update(rc.Context(), backupSecret); err != nil {
+				return flow.Error(err, "Unable to update backup secret", "secret", backupSecret.Name)
+			}
+		} else {
+			// accounts changed, switch to the shared secret of the new accounts and release the old one
+			secretName, err := saveSharedBackupSecret(rc, secret)
+			if err != nil {
+				return flow.Error(err, "Unable to save shared backup secret")
+			}
+			if backupSecret != nil {
+				if err := controllerutil.RemoveOwnerReference(backup, backupSecret, rc.Scheme()); err == nil {
+					if err := rc.Client().Update(rc.Context(), backupSecret); err != nil {
+						return flow.Error(err, "Unable to update owners of shared backup secret", "secret", backupSecret.Name)
+					}
+				}
+			}
+			backup.Status.SecretName = secretName
+		}
+
+		now := metav1.Now()
+		backup.Status.SecretSnapshotTime = &now
+		message := "accounts of xstore changed, backup secret refreshed at " + now.Format(time.RFC3339)
+		rc.RecordEvent(backup, corev1.EventTypeNormal, "SecretRefreshed", message)
+		if hasBackupCondition(backup, xstorev1.XStoreBackupMetadataUploaded, corev1.ConditionTrue) {
+			rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+				Type:    xstorev1.XStoreBackupMetadataUploaded,
+				Status:  corev1.ConditionFalse,
+				Reason:  "SecretRefreshed",
+				Message: message,
+			})
+		}
+		return flow.Continue("Backup secret refreshed.", "secret", backup.Status.SecretName)
+	})
+
+// retryUploadMetadataOrFail counts the failed attempt of uploading metadata and marks the backup failed
+// with the last error once the attempts reach the limit, so that a broken sink won't be retried forever.
+func retryUploadMetadataOrFail(rc *xstorev1reconcile.BackupContext, flow control.Flow, message string) (reconcile.Result, error) {
+	backup := rc.MustGetXStoreBackup()
+	backup.Status.MetadataUploadAttempts++
+	maxAttempts := rc.XStoreContext().Config().Backup().GetMetadataUploadMaxAttempts()
+	if backup.Status.MetadataUploadAttempts >= maxAttempts {
+		backup.Status.Phase = xstorev1.XstoreBackupFailed
+		backup.Status.FailureReason = polardbxv1polardbx.FailureReasonSinkUnreachable
+		backup.Status.Message = fmt.Sprintf("upload metadata failed after %d attempts, last error: %s",
+			backup.Status.MetadataUploadAttempts, message)
+		rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+			Type:    xstorev1.XStoreBackupMetadataUploaded,
+			Status:  corev1.ConditionFalse,
+			Reason:  "UploadFailed",
+			Message: backup.Status.Message,
+		})
+		return flow.Retry("Upload metadata failed too many times, backup failed.", "attempts",
+			backup.Status.MetadataUploadAttempts)
+	}
+	return flow.RetryAfter(10*time.Second, message, "attempts", backup.Status.MetadataUploadAttempts,
+		"max-attempts", maxAttempts)
+}
+
+func uploadXStoreMetadata(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+	return uploadXStoreMetadataOrElse(rc, flow, retryUploadMetadataOrFail)
+}
+
+// uploadXStoreMetadataOrElse builds metadata from current status of backup and uploads it to the backup root path,
+// overwriting the existing one. Failures other than unreachable filestream server are handled by onFailure.
+func uploadXStoreMetadataOrElse(rc *xstorev1reconcile.BackupContext, flow control.Flow,
+	onFailure func(*xstorev1reconcile.BackupContext, control.Flow, string) (reconcile.Result, error)) (reconcile.Result, error) {
+	xstore, err := rc.GetXStore()
+	if err != nil {
+		return flow.Error(err, "Unable to find xstore.")
+	}
+	backup := rc.MustGetXStoreBackup()
+	backupSecret, err := rc.GetSecret(backup.GetSecretName())
+	if client.IgnoreNotFound(err) != nil {
+		return flow.Error(err, "Unable to get secret for xstore", "xstore name", xstore.Name)
+	}
+
+	metadata := factory.MetadataBackup{
+		XstoreMetadataList:         make([]factory.XstoreMetadata, 0, 1),
+		BackupSetName:              backup.Name,
+		BackupRootPath:             backup.Status.BackupRootPath,
+		StartTime:                  backup.Status.StartTime,
+		EndTime:                    backup.Status.EndTime,
+		LatestRecoverableTimestamp: backup.Status.BackupSetTimestamp,
+		BackupMode:                 backup.Spec.BackupMode,
+		// standard xstore has no pxc, record engine version of xstore instead
+		PolarDBXVersion:      xstore.Status.EngineVersion,
+		ServerSideEncryption: backup.Spec.StorageProvider.ServerSideEncryption.DeepCopy(),
+		UserMetadata:         backup.Spec.UserMetadata,
+		IncludeDatabases:     backup.Spec.IncludeDatabases,
+		ExcludeDatabases:     backup.Spec.ExcludeDatabases,
+	}
+
+	// record image of xstore engine, fall back to the image running on target pod if not specified
+	engineImage := xstore.Spec.Topology.Template.Spec.Image
+	if engineImage == "" {
+		if targetPod, err := rc.GetXStoreTargetPod(); err == nil && targetPod != nil {
+			if container := k8shelper.GetContainerFromPod(targetPod, xstoreconvention.ContainerEngine); container != nil {
+				engineImage = container.Image
+			}
 		}
-		flow.Logger().Info("Uploading metadata finished", "sent bytes", sendBytes)
-		return flow.Continue("Metadata uploaded.")
+	}
+	if engineImage != "" {
+		metadata.Images = map[string]string{polardbxmeta.RoleDN: engineImage}
+	}
+
+	xstoreMetadata := factory.XstoreMetadata{
+		Name:            xstore.Name,
+		UID:             xstore.UID,
+		BackupName:      backup.Name,
+		LastCommitIndex: backup.Status.CommitIndex,
+		Secrets:         make([]polardbxv1polardbx.PrivilegeItem, 0, len(backupSecret.Data)),
+		TargetPod:       backup.Status.TargetPod,
+		Spec:            backup.Status.XStoreSpecSnapshot.DeepCopy(),
+		TableChecksums:  backup.Status.TableChecksums,
+		// the same rows are sampled on restore from metadata
+		TableChecksumSample: backup.Status.TableChecksumSample,
+		// files are decoded on restore from metadata
+		Encryption:        backup.Spec.Encryption.DeepCopy(),
+		FullBackupCodec:   backup.Status.FullBackupCodec.DeepCopy(),
+		BinlogBackupCodec: backup.Status.BinlogBackupCodec.DeepCopy(),
+	}
+	if backup.Status.ChunkSize > 0 {
+		xstoreMetadata.ChunkSize = backup.Status.ChunkSize
+		xstoreMetadata.ChunkManifestPath = path.JoinPath(backup.Status.BackupRootPath, polardbxmeta.FullBackupPath,
+			backup.Spec.XStore.Name+".xbstream"+polardbxmeta.ChunkManifestSuffix)
+	}
+
+	for user, passwd := range backupSecret.Data {
+		xstoreMetadata.Secrets = append(
+			xstoreMetadata.Secrets,
+			polardbxv1polardbx.PrivilegeItem{
+				Username: user,
+				Password: string(passwd),
+			})
+	}
+	metadata.XstoreMetadataList = append(metadata.XstoreMetadataList, xstoreMetadata)
+
+	metadata.Sort()
+
+	// parse metadata to json string
+	jsonString, err := json.Marshal(metadata)
+	if err != nil {
+		return onFailure(rc, flow, "Failed to marshal metadata, error: "+err.Error())
+	}
+
+	// init filestream client and upload formatted metadata
+	filestreamClient, err := rc.XStoreContext().GetFilestreamClient()
+	metadataBackupPath := path.JoinPath(metadata.BackupRootPath, "metadata")
+	if errors.Is(err, filestream.ErrServerUnreachable) {
+		// transient unavailability of filestream server doesn't count against the upload attempts
+		rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+			Type:    xstorev1.XStoreBackupFilestreamConnected,
+			Status:  corev1.ConditionFalse,
+			Reason:  "Unreachable",
+			Message: err.Error(),
+		})
+		return flow.RetryAfter(10*time.Second, "Filestream server unreachable.", "error", err.Error())
+	}
+	if err != nil {
+		return onFailure(rc, flow, "Failed to get filestream client, error: "+err.Error())
+	}
+	rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+		Type:    xstorev1.XStoreBackupFilestreamConnected,
+		Status:  corev1.ConditionTrue,
+		Reason:  "Connected",
+		Message: "Filestream server is reachable",
+	})
+	filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(backup.Spec.StorageProvider.StorageName)
+	if err != nil {
+		return onFailure(rc, flow, "Unsupported storage provided")
+	}
+	actionMetadata := filestream.ActionMetadata{
+		Action:    filestreamAction.Upload,
+		Sink:      backup.Spec.StorageProvider.Sink,
+		RequestId: uuid.New().String(),
+		Filename:  metadataBackupPath,
+		Tags:      backupObjectTags(backup),
+	}
+	actionMetadata.SSEAlgorithm, actionMetadata.SSEKMSKeyId = backup.Spec.StorageProvider.GetServerSideEncryption()
+	actionMetadata.EndpointType = string(backup.Spec.StorageProvider.EndpointType)
+	provider := string(backup.Spec.StorageProvider.StorageName)
+	meter := newUploadThroughputMeter(bytes.NewReader(jsonString), provider, backup.Name, metadataBackupPath, flow.Logger())
+	sendBytes, err := filestreamClient.Upload(meter, actionMetadata)
+	if err != nil {
+		return onFailure(rc, flow, "Upload metadata failed, error: "+err.Error())
+	}
+	recordUploadThroughput(meter, provider, backup.Name, metadataBackupPath, flow.Logger())
+	flow.Logger().Info("Uploading metadata finished", "sent bytes", sendBytes)
+	backup.Status.Metadata = metadata.Summary(metadataBackupPath)
+	rc.UpdateXStoreBackupCondition(&polardbxv1xstore.Condition{
+		Type:    xstorev1.XStoreBackupMetadataUploaded,
+		Status:  corev1.ConditionTrue,
+		Reason:  "MetadataUploaded",
+		Message: "Metadata uploaded to " + metadataBackupPath,
+	})
+	return flow.Continue("Metadata uploaded.")
+}
 
+var UploadXStoreMetadata = NewStepBinder("UploadXStoreMetadata", uploadXStoreMetadata)
+
+// UploadStaleXStoreMetadata uploads metadata again if it turns stale after uploaded, e.g. accounts refreshed.
+var UploadStaleXStoreMetadata = NewStepBinder("UploadStaleXStoreMetadata",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		if backup.Status.Metadata == nil ||
+			!hasBackupCondition(backup, xstorev1.XStoreBackupMetadataUploaded, corev1.ConditionFalse) {
+			return flow.Pass()
+		}
+		return uploadXStoreMetadata(rc, flow)
 	})
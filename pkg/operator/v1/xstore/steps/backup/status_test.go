@@ -0,0 +1,502 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1xstore "github.com/alibaba/polardbx-operator/api/v1/xstore"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	"github.com/alibaba/polardbx-operator/pkg/meta/core/group"
+	xstoreconvention "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
+	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
+)
+
+// taskContextClient serves the xstore backup and its task config map, other methods are not implemented.
+type taskContextClient struct {
+	client.Client
+	backup    *xstorev1.XStoreBackup
+	configMap *corev1.ConfigMap
+	updated   int
+}
+
+func (c *taskContextClient) Get(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+	switch o := obj.(type) {
+	case *xstorev1.XStoreBackup:
+		c.backup.DeepCopyInto(o)
+	case *corev1.ConfigMap:
+		c.configMap.DeepCopyInto(o)
+	}
+	return nil
+}
+
+func (c *taskContextClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	c.configMap = obj.(*corev1.ConfigMap).DeepCopy()
+	c.updated++
+	return nil
+}
+
+func newTaskContextClient(value string) *taskContextClient {
+	backup := &xstorev1.XStoreBackup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup"},
+		Spec: xstorev1.XStoreBackupSpec{
+			XStore: xstorev1.XStoreReference{Name: "xstore"},
+		},
+		Status: xstorev1.XStoreBackupStatus{
+			BackupRootPath: "polardbx-backup/pxc/backup-20230101000000",
+			ChunkSize:      1024,
+		},
+	}
+	backup.Spec.StorageProvider.StorageName = "oss"
+	return &taskContextClient{
+		backup: backup,
+		configMap: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup-backup"},
+			Data:       map[string]string{xstoreconvention.BackupConfigMapKey: value},
+		},
+	}
+}
+
+func newTaskContextBackupContext(c client.Client) *xstorev1reconcile.BackupContext {
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "backup"}}
+	return xstorev1reconcile.NewBackupContext(
+		control.NewBaseReconcileContext(c, nil, nil, nil, context.Background(), request))
+}
+
+func TestGetBackupJobContextRebuildsCorruptedContext(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	for _, value := range []string{`{"fullBackupPath": "polardbx-backup/pxc/`, `{"sink": "default"}`} {
+		c := newTaskContextClient(value)
+		backupJobContext, err := getBackupJobContext(newTaskContextBackupContext(c), logr.Discard())
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(backupJobContext.FullBackupPath).To(gomega.Equal("polardbx-backup/pxc/backup-20230101000000/fullbackup/xstore.xbstream"))
+		g.Expect(backupJobContext.ChunkSize).To(gomega.BeEquivalentTo(1024))
+		g.Expect(backupJobContext.Validate()).To(gomega.Succeed())
+
+		// rebuilt context is saved back
+		g.Expect(c.updated).To(gomega.Equal(1))
+		saved := &BackupJobContext{}
+		g.Expect(json.Unmarshal([]byte(c.configMap.Data[xstoreconvention.BackupConfigMapKey]), saved)).To(gomega.Succeed())
+		g.Expect(saved).To(gomega.Equal(backupJobContext))
+	}
+}
+
+func TestGetBackupJobContextKeepsValidContext(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	expected, err := newBackupJobContext(newTaskContextClient("").backup, 1024)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	expected.CollectStartIndex = "100"
+	value, _ := json.Marshal(expected)
+
+	c := newTaskContextClient(string(value))
+	backupJobContext, err := getBackupJobContext(newTaskContextBackupContext(c), logr.Discard())
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(backupJobContext).To(gomega.Equal(expected))
+	g.Expect(c.updated).To(gomega.Equal(0))
+}
+
+func TestGetBackupJobContextCorruptedWithoutRootPath(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	c := newTaskContextClient("{")
+	c.backup.Status.BackupRootPath = ""
+	_, err := getBackupJobContext(newTaskContextBackupContext(c), logr.Discard())
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("task context corrupted")))
+	g.Expect(c.updated).To(gomega.Equal(0))
+}
+
+func TestFindBinlogGap(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	logs := []*group.ConsensusLog{
+		{LogName: "mysql-bin.000003", StartLogIndex: 201},
+		{LogName: "mysql-bin.000004", StartLogIndex: 301},
+	}
+
+	_, _, ok := findBinlogGap(250, logs)
+	g.Expect(ok).To(gomega.BeFalse())
+	_, _, ok = findBinlogGap(200, logs)
+	g.Expect(ok).To(gomega.BeFalse())
+	_, _, ok = findBinlogGap(150, nil)
+	g.Expect(ok).To(gomega.BeFalse())
+
+	from, to, ok := findBinlogGap(150, logs)
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(from).To(gomega.BeEquivalentTo(151))
+	g.Expect(to).To(gomega.BeEquivalentTo(200))
+}
+
+func TestIsBinlogFilePurged(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	purged, err := isBinlogFilePurged("mysql-bin.000003", "mysql-bin.000004")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(purged).To(gomega.BeTrue())
+	purged, err = isBinlogFilePurged("mysql-bin.000004", "mysql-bin.000004")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(purged).To(gomega.BeFalse())
+
+	// indexes grown beyond the padding are wider, but not later as strings
+	purged, err = isBinlogFilePurged("mysql-bin.1000000", "mysql-bin.999999")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(purged).To(gomega.BeFalse())
+	purged, err = isBinlogFilePurged("mysql-bin.999999", "mysql-bin.1000000")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(purged).To(gomega.BeTrue())
+
+	_, err = isBinlogFilePurged("mysql-bin", "mysql-bin.000004")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestParseFullBackupResult(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(parseFullBackupResult([]byte(`{"success": true}`))).To(gomega.Equal(&fullBackupResult{Success: true}))
+	g.Expect(parseFullBackupResult([]byte(`{"success": false, "reason": "xtrabackup not completed OK"}`))).
+		To(gomega.Equal(&fullBackupResult{Success: false, Reason: "xtrabackup not completed OK"}))
+
+	result := parseFullBackupResult([]byte(`{"success": tr`))
+	g.Expect(result.Success).To(gomega.BeFalse())
+	g.Expect(result.Reason).To(gomega.HavePrefix("malformed full backup result"))
+}
+
+func TestExtendCollectRange(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	binlogFiles := []string{"mysql-bin.000003", "mysql-bin.000004", "mysql-bin.000005", "mysql-bin.000006"}
+
+	start, err := extendCollectStartIndex("mysql-bin.000005:1024", 0, binlogFiles)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(start).To(gomega.Equal("mysql-bin.000005:1024"))
+	start, err = extendCollectStartIndex("mysql-bin.000005:1024", 2, binlogFiles)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(start).To(gomega.Equal("mysql-bin.000003:4"))
+	_, err = extendCollectStartIndex("mysql-bin.000005:1024", 3, binlogFiles)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("oldest available binlog is mysql-bin.000003")))
+	_, err = extendCollectStartIndex("mysql-bin.000002:1024", 1, binlogFiles)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	end, ok := extendCollectEndFile("mysql-bin.000004:2048", 1, binlogFiles)
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(end).To(gomega.Equal("mysql-bin.000005"))
+	end, ok = extendCollectEndFile("mysql-bin.000004:2048", 5, binlogFiles)
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(end).To(gomega.Equal("mysql-bin.000006"))
+	_, ok = extendCollectEndFile("mysql-bin.000006:2048", 1, binlogFiles)
+	g.Expect(ok).To(gomega.BeFalse())
+}
+
+func TestBackupObjectTags(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	backup := &xstorev1.XStoreBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"team": "db", "cost-center": "cc01", "polardbx/name": "pxc"},
+			Annotations: map[string]string{
+				xstoremeta.AnnotationObjectTagLabels: "team,cost-center,owner",
+			},
+		},
+	}
+	g.Expect(backupObjectTags(backup)).To(gomega.Equal("cost-center=cc01&team=db"))
+
+	delete(backup.Annotations, xstoremeta.AnnotationObjectTagLabels)
+	g.Expect(backupObjectTags(backup)).To(gomega.BeEmpty())
+}
+
+func TestBackupDiskSpace(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	estimated, available, err := parseBackupDiskSpace("1073741824\n536870912\n")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(estimated).To(gomega.BeEquivalentTo(1 << 30))
+	g.Expect(available).To(gomega.BeEquivalentTo(1 << 29))
+	g.Expect(requiredBackupDiskSpace(estimated, 20)).To(gomega.BeEquivalentTo(1288490188))
+	g.Expect(requiredBackupDiskSpace(estimated, 0)).To(gomega.Equal(estimated))
+
+	_, _, err = parseBackupDiskSpace("536870912\n")
+	g.Expect(err).To(gomega.HaveOccurred())
+	_, _, err = parseBackupDiskSpace("du: cannot access\n536870912\n")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestFinalizeGraceRemaining(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	endTime := metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	backup := &xstorev1.XStoreBackup{Status: xstorev1.XStoreBackupStatus{EndTime: &endTime}}
+
+	g.Expect(finalizeGraceRemaining(backup, endTime.Add(time.Minute))).To(gomega.BeZero())
+
+	backup.Spec.FinalizeGracePeriod = &metav1.Duration{Duration: 5 * time.Minute}
+	g.Expect(finalizeGraceRemaining(backup, endTime.Add(time.Minute))).To(gomega.Equal(4 * time.Minute))
+	g.Expect(finalizeGraceRemaining(backup, endTime.Add(10*time.Minute))).To(gomega.BeNumerically("<", 0))
+}
+
+func TestParseExcludedBinlogs(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(parseExcludedBinlogs("mysql_bin.000012\nmysql_bin.000013\n")).To(
+		gomega.Equal([]string{"mysql_bin.000012", "mysql_bin.000013"}))
+	g.Expect(parseExcludedBinlogs("")).To(gomega.BeEmpty())
+}
+
+func TestBackupCommandsUseEngineDataDir(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(newFullBackupSizeCommand("/var/lib/mysql/")).To(gomega.Equal(
+		[]string{"sh", "-c", "du -sb /var/lib/mysql/data | cut -f 1"}))
+	command := newBackupDiskSpaceCommand("/var/lib/mysql/")
+	g.Expect(command[2]).To(gomega.ContainSubstring("'/var/lib/mysql/data/#innodb_redo'"))
+	g.Expect(command[2]).To(gomega.ContainSubstring("df -PB1 /var/lib/mysql |"))
+	g.Expect(command[2]).NotTo(gomega.ContainSubstring("/data/mysql"))
+}
+
+func TestParseFullBackupSize(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(parseFullBackupSize("1073741824\n")).To(gomega.BeEquivalentTo(1 << 30))
+	_, err := parseFullBackupSize("du: cannot access\n")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestOverallTimeoutMessage(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	startTime := metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	backup := &xstorev1.XStoreBackup{}
+	backup.Status.StartTime = &startTime
+	backup.Spec.OverallTimeout = &metav1.Duration{Duration: time.Hour}
+	g.Expect(overallTimeoutMessage(backup, startTime.Add(time.Minute))).To(gomega.BeEmpty())
+	g.Expect(overallTimeoutMessage(backup, startTime.Add(2*time.Hour))).To(gomega.Equal(
+		"backup not finished within overall timeout 1h0m0s, timed out in phase New"))
+
+	backup.Status.Phase = xstorev1.XstoreBackupFailed
+	g.Expect(overallTimeoutMessage(backup, startTime.Add(2*time.Hour))).To(gomega.BeEmpty())
+}
+
+func TestXStoreUnstableReason(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	xstore := &xstorev1.XStore{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Status: xstorev1.XStoreStatus{
+			Phase:              polardbxv1xstore.PhaseRunning,
+			ObservedGeneration: 2,
+		},
+	}
+	g.Expect(xstoreUnstableReason(xstore)).To(gomega.BeEmpty())
+
+	xstore.Status.Restarting = true
+	g.Expect(xstoreUnstableReason(xstore)).To(gomega.ContainSubstring("restarting"))
+
+	xstore.Generation = 3
+	g.Expect(xstoreUnstableReason(xstore)).To(gomega.ContainSubstring("not observed"))
+
+	xstore.Status.Phase = polardbxv1xstore.PhaseUpgrading
+	g.Expect(xstoreUnstableReason(xstore)).To(gomega.ContainSubstring("Upgrading"))
+}
+
+func TestPXCBackupTerminatedMessage(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	polardbxBackup := &xstorev1.PolarDBXBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "pxc-backup"},
+		Status:     xstorev1.PolarDBXBackupStatus{Phase: xstorev1.BackupCalculating},
+	}
+	g.Expect(pxcBackupTerminatedMessage(polardbxBackup)).To(gomega.BeEmpty())
+
+	polardbxBackup.Status.Phase = xstorev1.BackupFailed
+	polardbxBackup.Status.Reason = "CN unavailable"
+	g.Expect(pxcBackupTerminatedMessage(polardbxBackup)).To(gomega.Equal(
+		"polardbx backup pxc-backup is Failed, reason: CN unavailable"))
+
+	polardbxBackup.Status.Phase = xstorev1.BackupDeleting
+	polardbxBackup.Status.Reason = ""
+	g.Expect(pxcBackupTerminatedMessage(polardbxBackup)).To(gomega.Equal("polardbx backup pxc-backup is Deleting"))
+}
+
+func TestScheduledDeletionTime(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	endTime := metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	backup := &xstorev1.XStoreBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup"},
+		Spec:       xstorev1.XStoreBackupSpec{RetentionTime: metav1.Duration{Duration: 24 * time.Hour}},
+	}
+	g.Expect(scheduledDeletionTime(backup)).To(gomega.BeNil())
+
+	backup.Status.EndTime = &endTime
+	g.Expect(scheduledDeletionTime(backup).Time).To(gomega.Equal(endTime.Add(24 * time.Hour)))
+
+	// follows the retention time
+	backup.Spec.RetentionTime.Duration = 48 * time.Hour
+	g.Expect(scheduledDeletionTime(backup).Time).To(gomega.Equal(endTime.Add(48 * time.Hour)))
+
+	backup.Annotations = map[string]string{xstoremeta.AnnotationProtectedBackup: "true"}
+	g.Expect(scheduledDeletionTime(backup)).To(gomega.BeNil())
+}
+
+func TestIsLaterBackup(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	newBackup := func(name string, phase xstorev1.XStoreBackupPhase, minute int) *xstorev1.XStoreBackup {
+		startTime := metav1.NewTime(time.Date(2023, 1, 1, 0, minute, 0, 0, time.UTC))
+		return &xstorev1.XStoreBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     xstorev1.XStoreBackupStatus{Phase: phase, StartTime: &startTime},
+		}
+	}
+
+	backup := newBackup("backup-b", xstorev1.XStoreBackupFinished, 10)
+	g.Expect(isLaterBackup(backup, nil)).To(gomega.BeTrue())
+	g.Expect(isLaterBackup(backup, newBackup("backup-a", xstorev1.XStoreBackupFinished, 5))).To(gomega.BeTrue())
+	g.Expect(isLaterBackup(backup, newBackup("backup-c", xstorev1.XStoreBackupFinished, 15))).To(gomega.BeFalse())
+
+	// started at the same time, ordered by name
+	g.Expect(isLaterBackup(backup, newBackup("backup-a", xstorev1.XStoreBackupFinished, 10))).To(gomega.BeTrue())
+	g.Expect(isLaterBackup(backup, newBackup("backup-c", xstorev1.XStoreBackupFinished, 10))).To(gomega.BeFalse())
+
+	// current one no longer recoverable
+	g.Expect(isLaterBackup(backup, newBackup("backup-c", xstorev1.XStoreBackupDeleting, 15))).To(gomega.BeTrue())
+}
+
+func TestRemoveFullBackupJobPropagationPolicy(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(gomega.Succeed())
+	g.Expect(xstorev1.AddToScheme(scheme)).To(gomega.Succeed())
+
+	xstore := &xstorev1.XStore{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "xstore",
+			Annotations: map[string]string{xstoremeta.AnnotationJobDeletePropagation: "Foreground"},
+		},
+	}
+	backup := &xstorev1.XStoreBackup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup", UID: "backup-uid"},
+		Spec:       xstorev1.XStoreBackupSpec{XStore: xstorev1.XStoreReference{Name: "xstore"}},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "backup-job",
+			Labels:    map[string]string{xstoremeta.LabelXStoreBackupName: "backup"},
+		},
+	}
+	g.Expect(controllerutil.SetControllerReference(backup, job, scheme)).To(gomega.Succeed())
+
+	var propagations []metav1.DeletionPropagation
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(xstore, backup, job).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+				deleteOpts := &client.DeleteOptions{}
+				deleteOpts.ApplyOptions(opts)
+				if deleteOpts.PropagationPolicy != nil {
+					propagations = append(propagations, *deleteOpts.PropagationPolicy)
+				}
+				return c.Delete(ctx, obj, opts...)
+			},
+		}).Build()
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "backup"}}
+	rc := xstorev1reconcile.NewBackupContext(
+		control.NewBaseReconcileContext(c, nil, nil, scheme, context.Background(), request))
+	task := control.NewTask()
+	RemoveFullBackupJob(task)
+	result, err := control.NewExecutor(logr.Discard()).Execute(rc, task)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(propagations).To(gomega.Equal([]metav1.DeletionPropagation{metav1.DeletePropagationForeground}))
+
+	// wait until the job is gone with foreground deletion
+	g.Expect(result.RequeueAfter).To(gomega.BeNumerically(">", 0))
+}
+
+func TestAbortFullBackupJob(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(gomega.Succeed())
+	g.Expect(xstorev1.AddToScheme(scheme)).To(gomega.Succeed())
+
+	backup := &xstorev1.XStoreBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "backup",
+			UID:         "backup-uid",
+			Annotations: map[string]string{xstoremeta.AnnotationAbortFullBackup: "true"},
+		},
+		Spec:   xstorev1.XStoreBackupSpec{XStore: xstorev1.XStoreReference{Name: "xstore"}},
+		Status: xstorev1.XStoreBackupStatus{Phase: xstorev1.XStoreFullBackuping, TargetPod: "xstore-cand-0"},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "backup-job",
+			Labels:    map[string]string{xstoremeta.LabelXStoreBackupName: "backup"},
+		},
+	}
+	g.Expect(controllerutil.SetControllerReference(backup, job, scheme)).To(gomega.Succeed())
+	taskConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup-backup"},
+		Data:       map[string]string{xstoreconvention.BackupConfigMapKey: "{}"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(backup, job, taskConfigMap).Build()
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "backup"}}
+	abort := func() (*xstorev1reconcile.BackupContext, reconcile.Result) {
+		rc := xstorev1reconcile.NewBackupContext(
+			control.NewBaseReconcileContext(c, nil, nil, scheme, context.Background(), request))
+		task := control.NewTask()
+		AbortFullBackupJob(task)
+		result, err := control.NewExecutor(logr.Discard()).Execute(rc, task)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		return rc, result
+	}
+
+	// wait until the job is gone
+	rc, result := abort()
+	g.Expect(result.RequeueAfter).To(gomega.BeNumerically(">", 0))
+	g.Expect(rc.MustGetXStoreBackup().Status.Phase).To(gomega.Equal(xstorev1.XStoreFullBackuping))
+
+	rc, _ = abort()
+	status := rc.MustGetXStoreBackup().Status
+	g.Expect(status.Phase).To(gomega.Equal(xstorev1.XStoreBackupNew))
+	g.Expect(status.TargetPod).To(gomega.BeEmpty())
+	g.Expect(status.FullBackupAborts).To(gomega.Equal(int32(1)))
+	g.Expect(status.LastFullBackupAbortTime).NotTo(gomega.BeNil())
+	g.Expect(rc.MustGetXStoreBackup().Annotations).NotTo(gomega.HaveKey(xstoremeta.AnnotationAbortFullBackup))
+	g.Expect(c.Get(context.Background(), client.ObjectKeyFromObject(taskConfigMap), taskConfigMap)).To(gomega.Succeed())
+	g.Expect(taskConfigMap.Data).NotTo(gomega.HaveKey(xstoreconvention.BackupConfigMapKey))
+
+	// rejected unless in full backup phase
+	backup = rc.MustGetXStoreBackup()
+	backup.Annotations = map[string]string{xstoremeta.AnnotationAbortFullBackup: "true"}
+	g.Expect(c.Update(context.Background(), backup)).To(gomega.Succeed())
+	rc, _ = abort()
+	g.Expect(rc.MustGetXStoreBackup().Status.Message).To(gomega.ContainSubstring("rejected"))
+	g.Expect(rc.MustGetXStoreBackup().Status.FullBackupAborts).To(gomega.Equal(int32(1)))
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package backup
 
 import (
+	"time"
+
 	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
 	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
 	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
@@ -27,7 +29,21 @@ import (
 type ConditionFunc func(rc *xstorev1reconcile.BackupContext, log logr.Logger) (bool, error)
 type StepFunc func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error)
 
+// NewStepBinder binds the step and records its duration into status of xstore backup, see recordStepDuration.
 func NewStepBinder(name string, f StepFunc) control.BindFunc {
+	return newStepBinderWithoutTiming(name,
+		func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+			startTime := time.Now()
+			result, err := f(rc, flow)
+			recordStepDuration(rc, name, startTime, err == nil && !isFlowBroken(flow))
+			return result, err
+		},
+	)
+}
+
+// newStepBinderWithoutTiming binds the step without recording its duration, used by steps which persist
+// the xstore backup, otherwise the duration recorded after persisting is left unsaved.
+func newStepBinderWithoutTiming(name string, f StepFunc) control.BindFunc {
 	return control.NewStepBinder(
 		control.NewStep(
 			name, func(rc control.ReconcileContext, flow control.Flow) (reconcile.Result, error) {
@@ -0,0 +1,84 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
+)
+
+// stepDurationSeconds observes the duration of each step of xstore backup once the step is passed,
+// the same as the one recorded in status.
+var stepDurationSeconds = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "polardbx_xstore_backup_step_duration_seconds",
+		Help:    "Time spent on each step of xstore backup, from the first execution to the step passed.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 12), // 10ms ~ 11.6h
+	},
+	[]string{"step"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(stepDurationSeconds)
+}
+
+// isFlowBroken tells whether the step breaks the reconciliation, i.e. it waits, retries or fails.
+func isFlowBroken(flow control.Flow) bool {
+	if f, ok := flow.(interface{ BreakLoop() bool }); ok {
+		return f.BreakLoop()
+	}
+	return false
+}
+
+// recordStepDuration records the start time of step at its first execution, and the end time and duration
+// once the step is passed. Steps executed again after passed, e.g. the ones bound in multiple phases, keep
+// the duration of the first pass. Steps of deleting backups are not recorded since the backup may be gone
+// once the finalizer removed.
+func recordStepDuration(rc *xstorev1reconcile.BackupContext, name string, startTime time.Time, passed bool) {
+	xstoreBackup, err := rc.GetXStoreBackup()
+	if err != nil || xstoreBackup == nil || !xstoreBackup.DeletionTimestamp.IsZero() {
+		return
+	}
+	var stepDuration *polardbxv1.StepDuration
+	for i := range xstoreBackup.Status.StepDurations {
+		if xstoreBackup.Status.StepDurations[i].Name == name {
+			stepDuration = &xstoreBackup.Status.StepDurations[i]
+			break
+		}
+	}
+	if stepDuration == nil {
+		xstoreBackup.Status.StepDurations = append(xstoreBackup.Status.StepDurations, polardbxv1.StepDuration{
+			Name:      name,
+			StartTime: metav1.NewTime(startTime),
+		})
+		stepDuration = &xstoreBackup.Status.StepDurations[len(xstoreBackup.Status.StepDurations)-1]
+	}
+	if !passed || stepDuration.EndTime != nil {
+		return
+	}
+	endTime := metav1.Now()
+	stepDuration.EndTime = &endTime
+	stepDuration.Duration = metav1.Duration{Duration: endTime.Sub(stepDuration.StartTime.Time)}
+	stepDurationSeconds.WithLabelValues(name).Observe(stepDuration.Duration.Seconds())
+}
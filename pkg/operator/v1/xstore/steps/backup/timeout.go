@@ -0,0 +1,69 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
+	polardbxv1polardbx "github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
+)
+
+// ReasonOverallTimeout is the failure reason of backup which runs beyond its overall timeout.
+const ReasonOverallTimeout = "OverallTimeout"
+
+// overallTimeoutMessage tells in which phase the backup runs beyond its overall timeout at now. Empty string is
+// returned if the backup is terminated or still within the timeout.
+func overallTimeoutMessage(backup *xstorev1.XStoreBackup, now time.Time) string {
+	switch backup.Status.Phase {
+	case xstorev1.XStoreBackupFinished, xstorev1.XstoreBackupFailed, xstorev1.XStoreBackupDummy,
+		xstorev1.XStoreBackupDeleting:
+		return ""
+	}
+	if !polardbxv1polardbx.OverallTimeoutExceeded(backup.Status.StartTime, backup.Spec.OverallTimeout, now) {
+		return ""
+	}
+	phase := backup.Status.Phase
+	if phase == xstorev1.XStoreBackupNew {
+		phase = "New"
+	}
+	return fmt.Sprintf("backup not finished within overall timeout %s, timed out in phase %s",
+		backup.Spec.OverallTimeout.Duration, phase)
+}
+
+// FailBackupIfOverallTimeout fails the backup in progress if it runs beyond its overall timeout, jobs of which
+// are removed in the failed phase. It guards every reconcile, so its duration is not recorded.
+var FailBackupIfOverallTimeout = newStepBinderWithoutTiming("FailBackupIfOverallTimeout",
+	func(rc *xstorev1reconcile.BackupContext, flow control.Flow) (reconcile.Result, error) {
+		backup := rc.MustGetXStoreBackup()
+		message := overallTimeoutMessage(backup, time.Now())
+		if message == "" {
+			return flow.Pass()
+		}
+		backup.Status.Phase = xstorev1.XstoreBackupFailed
+		backup.Status.Reason = ReasonOverallTimeout
+		backup.Status.FailureReason = polardbxv1polardbx.FailureReasonTimeout
+		backup.Status.Message = message
+		rc.RecordEvent(backup, corev1.EventTypeWarning, ReasonOverallTimeout, message)
+		return flow.Retry("Overall timeout exceeded, backup failed.", "message", message)
+	})
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"io"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+)
+
+// uploadThroughputReportInterval is the interval of reporting throughput during uploads.
+const uploadThroughputReportInterval = 10 * time.Second
+
+var (
+	uploadThroughputBytesPerSecond = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "polardbx_xstore_backup_upload_throughput_bytes_per_second",
+			Help: "Throughput of the ongoing upload of xstore backup over the last report interval.",
+		},
+		[]string{"provider", "backup"},
+	)
+	uploadAvgThroughputBytesPerSecond = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "polardbx_xstore_backup_upload_avg_throughput_bytes_per_second",
+			Help: "Average throughput of the last finished upload of xstore backup.",
+		},
+		[]string{"provider", "backup"},
+	)
+	uploadPeakThroughputBytesPerSecond = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "polardbx_xstore_backup_upload_peak_throughput_bytes_per_second",
+			Help: "Peak throughput over report intervals of the last finished upload of xstore backup.",
+		},
+		[]string{"provider", "backup"},
+	)
+	uploadDurationSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "polardbx_xstore_backup_upload_duration_seconds",
+			Help: "Duration of the last finished upload of xstore backup.",
+		},
+		[]string{"provider", "backup"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		uploadThroughputBytesPerSecond,
+		uploadAvgThroughputBytesPerSecond,
+		uploadPeakThroughputBytesPerSecond,
+		uploadDurationSeconds,
+	)
+}
+
+// newUploadThroughputMeter wraps reader of upload to log and export the throughput periodically.
+func newUploadThroughputMeter(reader io.Reader, provider, backupName, filename string, logger logr.Logger) *filestream.ThroughputMeter {
+	return filestream.NewThroughputMeter(reader, uploadThroughputReportInterval, func(sample filestream.ThroughputSample) {
+		logger.Info("Uploading", "filename", filename, "sent bytes", sample.Bytes,
+			"throughput (bytes/s)", int64(sample.BytesPerSecond))
+		uploadThroughputBytesPerSecond.WithLabelValues(provider, backupName).Set(sample.BytesPerSecond)
+	})
+}
+
+// recordUploadThroughput logs and exports the summary of the finished upload.
+func recordUploadThroughput(meter *filestream.ThroughputMeter, provider, backupName, filename string, logger logr.Logger) {
+	summary := meter.Summary()
+	logger.Info("Upload finished", "filename", filename, "sent bytes", summary.Bytes, "duration", summary.Duration,
+		"avg throughput (bytes/s)", int64(summary.AvgBytesPerSecond),
+		"peak throughput (bytes/s)", int64(summary.PeakBytesPerSecond))
+	uploadThroughputBytesPerSecond.DeleteLabelValues(provider, backupName)
+	uploadAvgThroughputBytesPerSecond.WithLabelValues(provider, backupName).Set(summary.AvgBytesPerSecond)
+	uploadPeakThroughputBytesPerSecond.WithLabelValues(provider, backupName).Set(summary.PeakBytesPerSecond)
+	uploadDurationSeconds.WithLabelValues(provider, backupName).Set(summary.Duration.Seconds())
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/go-logr/logr"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"path/filepath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"time"
@@ -85,6 +86,27 @@ var RunningRoute = NewStepBinder("RunningRoute",
 		return flow.RetryAfter(5*time.Second, "RunningRoute.")
 	})
 
+// latestFullBackupStartTime returns the start time in unix seconds of the latest finished full backup of the
+// xstore, 0 if there's none. Binlog after it is required to recover from the backup to any point in time.
+func latestFullBackupStartTime(backupBinlog *xstorev1.XStoreBackupBinlog, backups []xstorev1.XStoreBackup) int64 {
+	var latest int64
+	for _, backup := range backups {
+		if backup.Spec.XStore.Name != backupBinlog.Spec.XStoreName ||
+			(backupBinlog.Spec.XStoreUid != "" && backup.Spec.XStore.UID != "" &&
+				string(backup.Spec.XStore.UID) != backupBinlog.Spec.XStoreUid) {
+			continue
+		}
+		if backup.Status.Phase != xstorev1.XStoreBackupFinished || !backup.DeletionTimestamp.IsZero() ||
+			backup.Status.StartTime == nil {
+			continue
+		}
+		if startTime := backup.Status.StartTime.Unix(); startTime > latest {
+			latest = startTime
+		}
+	}
+	return latest
+}
+
 var TryDeleteExpiredFiles = NewStepBinder("TryDeleteExpiredFiles",
 	func(rc *xstorev1reconcile.BackupBinlogContext, flow control.Flow) (reconcile.Result, error) {
 		hpfsClient, err := rc.XStoreContext().GetHpfsClient()
@@ -96,6 +118,17 @@ var TryDeleteExpiredFiles = NewStepBinder("TryDeleteExpiredFiles",
 		seconds := backupBinlog.Spec.RemoteExpireLogHours.IntValue() * 3600
 		if !backupBinlog.DeletionTimestamp.IsZero() {
 			seconds = -3600
+		} else {
+			// Binlog after the latest full backup is always kept, regardless of the binlog retention.
+			var backupList xstorev1.XStoreBackupList
+			if err := rc.Client().List(rc.Context(), &backupList, client.InNamespace(backupBinlog.Namespace)); err != nil {
+				return flow.RetryErr(err, "failed to list backups")
+			}
+			latestFullBackupStart := latestFullBackupStartTime(backupBinlog, backupList.Items)
+			if latestFullBackupStart > 0 && now.Unix()-int64(seconds) > latestFullBackupStart {
+				seconds = int(now.Unix() - latestFullBackupStart)
+			}
+			flow.Logger().Info("binlog expire deadline", "deadline", now.Unix()-int64(seconds), "latestFullBackupStart", latestFullBackupStart)
 		}
 		rep, err := hpfsClient.DeleteBinlogFilesBefore(rc.Context(), &hpfs.DeleteBinlogFilesBeforeRequest{
 			Namespace: backupBinlog.Namespace,
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
+	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
+	xstorev1reconcile "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/reconcile"
+	"github.com/alibaba/polardbx-operator/pkg/util/name"
+)
+
+// parseTriggerBackupStorageProvider parses value of trigger backup annotation in format of "<storageName>:<sink>".
+// Empty value leaves the storage provider to be filled by sink policy of operator.
+func parseTriggerBackupStorageProvider(val string) (polardbx.BackupStorageProvider, error) {
+	storageProvider := polardbx.BackupStorageProvider{}
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return storageProvider, nil
+	}
+	tokens := strings.SplitN(val, ":", 2)
+	if len(tokens) != 2 || tokens[0] == "" || tokens[1] == "" {
+		return storageProvider, errors.New("invalid storage provider, expect <storageName>:<sink>: " + val)
+	}
+	storageProvider.StorageName = polardbx.BackupStorage(tokens[0])
+	storageProvider.Sink = tokens[1]
+	if _, err := polardbx.NewBackupStorageFilestreamAction(storageProvider.StorageName); err != nil {
+		return storageProvider, err
+	}
+	return storageProvider, nil
+}
+
+func WhenTriggerBackupAnnotated(binders ...control.BindFunc) control.BindFunc {
+	return xstorev1reconcile.NewStepIfBinder("WhenTriggerBackupAnnotated",
+		func(rc *xstorev1reconcile.Context, log logr.Logger) (bool, error) {
+			xstore := rc.MustGetXStore()
+			_, ok := xstore.Annotations[xstoremeta.AnnotationTriggerBackup]
+			return ok, nil
+		},
+		binders...,
+	)
+}
+
+// CreateBackupByTriggerAnnotation materializes the trigger backup annotation into an XStoreBackup
+// and clears the annotation. Only standard xstores support the trigger, members of polardbx cluster
+// are backed up by PolarDBXBackup.
+var CreateBackupByTriggerAnnotation = xstorev1reconcile.NewStepBinder("CreateBackupByTriggerAnnotation",
+	func(rc *xstorev1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		xstore := rc.MustGetXStore()
+		val := xstore.Annotations[xstoremeta.AnnotationTriggerBackup]
+		consumeAnnotation := func() {
+			delete(xstore.Annotations, xstoremeta.AnnotationTriggerBackup)
+			rc.MarkXStoreChanged()
+		}
+
+		isStandard, err := rc.GetXStoreIsStandard()
+		if err != nil {
+			return flow.Error(err, "Unable to determine whether xstore is standard.")
+		}
+		if !isStandard {
+			consumeAnnotation()
+			rc.RecordEvent(xstore, corev1.EventTypeWarning, "TriggerBackupRejected",
+				"Trigger backup is not supported by xstore of polardbx cluster, please use PolarDBXBackup")
+			return flow.Continue("Trigger backup rejected, xstore is not standard.")
+		}
+
+		storageProvider, err := parseTriggerBackupStorageProvider(val)
+		if err != nil {
+			consumeAnnotation()
+			rc.RecordEvent(xstore, corev1.EventTypeWarning, "TriggerBackupRejected", err.Error())
+			return flow.Continue("Trigger backup rejected.", "error", err.Error())
+		}
+
+		backup := &polardbxv1.XStoreBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: xstore.Namespace,
+				Name: name.NewSplicedName(
+					name.WithTokens(xstore.Name, time.Now().Format("20060102150405")),
+					name.WithPrefix("xstore-backup"),
+				),
+				Labels: map[string]string{
+					xstoremeta.LabelName: xstore.Name,
+					xstoremeta.LabelUid:  string(xstore.UID),
+				},
+			},
+			Spec: polardbxv1.XStoreBackupSpec{
+				Engine: xstore.Spec.Engine,
+				XStore: polardbxv1.XStoreReference{
+					Name: xstore.Name,
+					UID:  xstore.UID,
+				},
+				StorageProvider: storageProvider,
+			},
+		}
+		if err := rc.Client().Create(rc.Context(), backup); err != nil && !apierrors.IsAlreadyExists(err) {
+			return flow.Error(err, "Unable to create triggered backup.")
+		}
+		consumeAnnotation()
+		rc.RecordEvent(xstore, corev1.EventTypeNormal, "TriggerBackupCreated", "Backup "+backup.Name+" created")
+		return flow.Continue("Triggered backup created.", "backup", backup.Name)
+	})
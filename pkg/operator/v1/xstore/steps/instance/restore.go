@@ -31,6 +31,7 @@ import (
 	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/factory"
 	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/command"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
 	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
@@ -45,11 +46,16 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type RestoreJobContext struct {
 	BackupFilePath      string                 `json:"backupFilePath,omitempty"`
+	BackupFileOffset    int64                  `json:"backupFileOffset,omitempty"`
+	BackupFileSize      *int64                 `json:"backupFileSize,omitempty"`
 	BackupCommitIndex   *int64                 `json:"backupCommitIndex,omitempty"`
 	BinlogDirPath       string                 `json:"binlogDirPath,omitempty"`
 	BinlogEndOffsetPath string                 `json:"binlogEndOffsetPath,omitempty"`
@@ -62,6 +68,91 @@ type RestoreJobContext struct {
 	PxcXStore           *bool                  `json:"pxcXStore,omitempty"`
 	KeyringPath         string                 `json:"keyringPath,omitempty"`
 	KeyringFilePath     string                 `json:"keyringFilePath,omitempty"`
+	KeyringChecksumPath string                 `json:"keyringChecksumPath,omitempty"`
+	ChunkManifestPath   string                 `json:"chunkManifestPath,omitempty"`
+	EndpointType        string                 `json:"endpointType,omitempty"`
+	TableChecksums      map[string]string      `json:"tableChecksums,omitempty"`
+	// TableChecksumSample makes the same rows sampled as backup when verifying table checksums
+	TableChecksumSample *polardbxv1polardbx.TableChecksumSample `json:"tableChecksumSample,omitempty"`
+	// KeyringKey selects the key to decrypt the keyring re-encrypted by backup, which is passed to job by env
+	KeyringKey *corev1.SecretKeySelector `json:"keyringKey,omitempty"`
+	// BackupCodec and BinlogCodec are how full backup and binlogs are processed by backup, reversed on restore
+	BackupCodec *polardbxv1polardbx.BackupCodec `json:"backupCodec,omitempty"`
+	BinlogCodec *polardbxv1polardbx.BackupCodec `json:"binlogCodec,omitempty"`
+	// EncryptionKey selects the key to decrypt files encrypted by backup, which is passed to job by env
+	EncryptionKey *corev1.SecretKeySelector `json:"encryptionKey,omitempty"`
+}
+
+// errKeyringKeyUnavailable means the key to decrypt the keyring re-encrypted by backup is unknown or mismatched.
+var errKeyringKeyUnavailable = errors.New("keyring key unavailable")
+
+// restoreKeyringKey returns the selector of the target key which the keyring is re-encrypted with by backup, the
+// key is checked against the fingerprint recorded by backup.
+func restoreKeyringKey(rc *xstorev1reconcile.Context, backup *polardbxv1.XStoreBackup) (*corev1.SecretKeySelector, error) {
+	reEncryption := backup.Spec.KeyringReEncryption
+	if reEncryption == nil {
+		return nil, fmt.Errorf("%w: secret of keys is unknown", errKeyringKeyUnavailable)
+	}
+	secret, err := rc.GetSecretByName(reEncryption.SecretName)
+	if err != nil {
+		return nil, err
+	}
+	key := secret.Data[reEncryption.GetTargetKey()]
+	if len(key) == 0 || polardbxv1polardbx.KeyFingerprint(key) != backup.Status.KeyringKeyFingerprint {
+		return nil, fmt.Errorf("%w: key %s in secret %s mismatches fingerprint %s", errKeyringKeyUnavailable,
+			reEncryption.GetTargetKey(), reEncryption.SecretName, backup.Status.KeyringKeyFingerprint)
+	}
+	return reEncryption.TargetKeySelector(), nil
+}
+
+// errEncryptionKeyUnavailable means the key to decrypt files encrypted by backup is unknown or mismatched.
+var errEncryptionKeyUnavailable = errors.New("encryption key unavailable")
+
+// restoreEncryptionKey returns the selector of the key which files are encrypted with by backup, the key is
+// checked against the fingerprint recorded in codec.
+func restoreEncryptionKey(rc *xstorev1reconcile.Context, backup *polardbxv1.XStoreBackup,
+	codec *polardbxv1polardbx.BackupCodec) (*corev1.SecretKeySelector, error) {
+	encryption := backup.Spec.Encryption
+	if encryption == nil {
+		return nil, fmt.Errorf("%w: secret of key is unknown", errEncryptionKeyUnavailable)
+	}
+	secret, err := rc.GetSecretByName(encryption.SecretName)
+	if err != nil {
+		return nil, err
+	}
+	key := secret.Data[encryption.GetKey()]
+	if len(key) == 0 || polardbxv1polardbx.KeyFingerprint(key) != codec.KeyFingerprint {
+		return nil, fmt.Errorf("%w: key %s in secret %s mismatches fingerprint %s", errEncryptionKeyUnavailable,
+			encryption.GetKey(), encryption.SecretName, codec.KeyFingerprint)
+	}
+	return encryption.KeySelector(), nil
+}
+
+// helper function to check whether keyring related file of backup exists in remote storage
+func isKeyringBackupExisted(rc *xstorev1reconcile.Context, backup *polardbxv1.XStoreBackup, filePath string) (bool, error) {
+	filestreamClient, err := rc.GetFilestreamClient()
+	if err != nil {
+		return false, err
+	}
+	filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(backup.Spec.StorageProvider.StorageName)
+	if err != nil {
+		return false, err
+	}
+	return filestreamClient.Exists(filestream.ActionMetadata{
+		Action:    filestreamAction.List,
+		Sink:      backup.Spec.StorageProvider.Sink,
+		RequestId: uuid.New().String(),
+		Filepath:  filePath,
+	})
+}
+
+// restoreEndpointType returns the endpoint type of sink selected by restore, or the one of backup if not selected,
+// as the restored xstore may be in a region other than the backed up one.
+func restoreEndpointType(xstore *polardbxv1.XStore, backup *polardbxv1.XStoreBackup) string {
+	if provider := xstore.Spec.Restore.StorageProvider; provider != nil && provider.EndpointType != "" {
+		return string(provider.EndpointType)
+	}
+	return string(backup.Spec.StorageProvider.EndpointType)
 }
 
 // helper function to download metadata backup from remote storage
@@ -74,11 +165,16 @@ func downloadMetadataBackup(rc *xstorev1reconcile.Context) (*factory.MetadataBac
 	}
 	filestreamAction, err := polardbxv1polardbx.NewBackupStorageFilestreamAction(xstore.Spec.Restore.StorageProvider.StorageName)
 
+	// metadata is uploaded with the server-side encryption of backup, which is requested on download as well
+	sseAlgorithm, sseKMSKeyId := xstore.Spec.Restore.StorageProvider.GetServerSideEncryption()
 	downloadActionMetadata := filestream.ActionMetadata{
-		Action:    filestreamAction.Download,
-		Sink:      xstore.Spec.Restore.StorageProvider.Sink,
-		RequestId: uuid.New().String(),
-		Filename:  polarxPath.NewPathFromStringSequence(xstore.Spec.Restore.From.BackupSetPath, "metadata"),
+		Action:       filestreamAction.Download,
+		Sink:         xstore.Spec.Restore.StorageProvider.Sink,
+		RequestId:    uuid.New().String(),
+		Filename:     polarxPath.NewPathFromStringSequence(xstore.Spec.Restore.From.BackupSetPath, "metadata"),
+		EndpointType: string(xstore.Spec.Restore.StorageProvider.EndpointType),
+		SSEAlgorithm: sseAlgorithm,
+		SSEKMSKeyId:  sseKMSKeyId,
 	}
 	var downloadBuffer bytes.Buffer
 	recvBytes, err := filestreamClient.Download(&downloadBuffer, downloadActionMetadata)
@@ -119,16 +215,29 @@ func NewDummyXstoreBackup(rc *xstorev1reconcile.Context, metadata *factory.Metad
 				Name: xstoreMetadata.Name,
 				UID:  xstoreMetadata.UID,
 			},
-			StorageProvider: *xstore.Spec.Restore.StorageProvider,
+			StorageProvider:     *xstore.Spec.Restore.StorageProvider,
+			UserMetadata:        metadata.UserMetadata,
+			KeyringReEncryption: xstoreMetadata.KeyringReEncryption,
+			Encryption:          xstoreMetadata.Encryption,
 		},
 		Status: polardbxv1.XStoreBackupStatus{
-			Phase:              polardbxv1.XStoreBackupDummy,
-			CommitIndex:        xstoreMetadata.LastCommitIndex,
-			BackupRootPath:     metadata.BackupRootPath,
-			TargetPod:          xstoreMetadata.TargetPod,
-			XStoreSpecSnapshot: xstoreMetadata.Spec,
+			Phase:                 polardbxv1.XStoreBackupDummy,
+			CommitIndex:           xstoreMetadata.LastCommitIndex,
+			BackupRootPath:        metadata.BackupRootPath,
+			TargetPod:             xstoreMetadata.TargetPod,
+			XStoreSpecSnapshot:    xstoreMetadata.Spec,
+			TableChecksums:        xstoreMetadata.TableChecksums,
+			TableChecksumSample:   xstoreMetadata.TableChecksumSample,
+			ChunkSize:             xstoreMetadata.ChunkSize,
+			KeyringKeyFingerprint: xstoreMetadata.KeyringKeyFingerprint,
+			FullBackupCodec:       xstoreMetadata.FullBackupCodec,
+			BinlogBackupCodec:     xstoreMetadata.BinlogBackupCodec,
 		},
 	}
+	// backup of xstore may be stored in sink other than the one of backup set
+	if xstoreMetadata.StorageProvider != nil {
+		xstoreBackup.Spec.StorageProvider = *xstoreMetadata.StorageProvider
+	}
 	return xstoreBackup, nil
 }
 
@@ -209,6 +318,36 @@ var CreateDummyBackupObject = xstorev1reconcile.NewStepBinder("CreateDummyBackup
 		return flow.Continue("Dummy backup object created!")
 	})
 
+// ResolveLatestBackupSet resolves backup set "latest" to the latest finished backup of source xstore, which is
+// recorded in annotation of source xstore.
+var ResolveLatestBackupSet = xstorev1reconcile.NewStepBinder("ResolveLatestBackupSet",
+	func(rc *xstorev1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		xstore := rc.MustGetXStore()
+		if xstore.Spec.Restore.BackupSet != xstoremeta.LatestBackupSet {
+			return flow.Pass()
+		}
+
+		var fromXStore polardbxv1.XStore
+		fromXStoreKey := types.NamespacedName{Namespace: rc.Namespace(), Name: xstore.Spec.Restore.From.XStoreName}
+		if err := rc.Client().Get(rc.Context(), fromXStoreKey, &fromXStore); err != nil {
+			return flow.Error(err, "Unable to get source xstore.", "xstore", fromXStoreKey.Name)
+		}
+		latestBackup := fromXStore.Annotations[xstoremeta.AnnotationLatestBackup]
+		if latestBackup == "" {
+			rc.UpdateXStoreCondition(&xstorev1.Condition{
+				Type:    xstorev1.Restorable,
+				Status:  corev1.ConditionFalse,
+				Reason:  "BackupNotFound",
+				Message: "No latest backup set found of xstore " + fromXStore.Name,
+			})
+			return flow.Wait("No latest backup set found.", "xstore", fromXStore.Name)
+		}
+
+		xstore.Spec.Restore.BackupSet = latestBackup
+		rc.MarkXStoreChanged()
+		return flow.Continue("Latest backup set resolved.", "backup-set", latestBackup)
+	})
+
 var CheckXStoreRestoreSpec = xstorev1reconcile.NewStepBinder("CheckXStoreRestoreSpec",
 	func(rc *xstorev1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		xstore := rc.MustGetXStore()
@@ -276,7 +415,7 @@ var StartRestoreJob = xstorev1reconcile.NewStepBinder("StartRestoreJob",
 
 			// If not found, create one.
 			if job == nil {
-				job = newRestoreDataJob(xstore, &pod)
+				job = newRestoreDataJob(xstore, &pod, restoreJobContext.KeyringKey, restoreJobContext.EncryptionKey)
 				if err := rc.SetControllerRefAndCreate(job); err != nil {
 					return flow.Error(err, "Unable to create job to restore data", "pod", pod.Name)
 				}
@@ -299,12 +438,34 @@ var WaitUntilRestoreJobFinished = xstorev1reconcile.NewStepBinder("WaitUntilRest
 		if err != nil {
 			return flow.Error(err, "Unable to get pods for xcluster.")
 		}
+		// restore phase reported by the slowest pod
+		var slowestPhase *restoreProgress
+		for _, pod := range pods {
+			if progress := readRestoreProgress(rc, &pod); progress != nil &&
+				(slowestPhase == nil || progress.Progress < slowestPhase.Progress) {
+				slowestPhase = progress
+			}
+		}
+		restoreFailed := xstore.Status.RestoreStatus != nil &&
+			xstore.Status.RestoreStatus.Phase == polardbxv1xstore.RestorePhaseFailed
+		if slowestPhase != nil && !restoreFailed {
+			setRestorePhase(rc, xstore, slowestPhase.Phase, "")
+			if slowestPhase.Progress > xstore.Status.RestoreStatus.Progress {
+				xstore.Status.RestoreStatus.Progress = slowestPhase.Progress
+			}
+		}
+
 		for _, pod := range pods {
 			job, err := rc.GetXStoreJob(name.GetStableNameSuffix(xstore, pod.Name) + "-restore")
 			if err != nil {
 				return flow.Error(err, "Unable to get xstore restore data job", "pod", pod.Name)
 			}
 
+			if k8shelper.IsJobFailed(job) {
+				setRestorePhase(rc, xstore, polardbxv1xstore.RestorePhaseFailed,
+					fmt.Sprintf("restore job %s failed on pod %s", job.Name, pod.Name))
+				return flow.Wait("Job's failed!", "job", job.Name, "pod", pod.Name)
+			}
 			if !k8shelper.IsJobCompleted(job) {
 				return flow.Wait("Job's not completed! Wait... ", "job", job.Name, "pod", pod.Name)
 			}
@@ -313,6 +474,30 @@ var WaitUntilRestoreJobFinished = xstorev1reconcile.NewStepBinder("WaitUntilRest
 		return flow.Continue("Restore Job completed!")
 	})
 
+// restoreProgress is reported by restore job into restore progress file under data directory of engine
+type restoreProgress struct {
+	Phase    polardbxv1xstore.RestorePhase `json:"phase,omitempty"`
+	Progress int32                         `json:"progress,omitempty"`
+}
+
+func readRestoreProgress(rc *xstorev1reconcile.Context, pod *corev1.Pod) *restoreProgress {
+	restoreProgressPath := polarxPath.JoinPath(rc.Config().Store().EngineDataDir(), "tmp", "restore.progress")
+	stdout := &bytes.Buffer{}
+	err := rc.ExecuteCommandOn(pod, convention.ContainerEngine, []string{"cat", restoreProgressPath}, control.ExecOptions{
+		Stdout:  stdout,
+		Stderr:  &bytes.Buffer{},
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil
+	}
+	progress := &restoreProgress{}
+	if err := json.Unmarshal(stdout.Bytes(), progress); err != nil {
+		return nil
+	}
+	return progress
+}
+
 var PrepareRestoreJobContext = xstorev1reconcile.NewStepBinder("PrepareRestoreJobContext",
 	func(rc *xstorev1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		const restoreJobKey = "restore"
@@ -385,18 +570,29 @@ var PrepareRestoreJobContext = xstorev1reconcile.NewStepBinder("PrepareRestoreJo
 			return flow.Error(err, "Unable to update shared config map.")
 		}
 
-		fullBackupPath := fmt.Sprintf("%s/%s/%s.xbstream",
-			backupRootPath, polardbxmeta.FullBackupPath, fromXStoreName)
-		binlogEndOffsetPath := fmt.Sprintf("%s/%s/%s-end",
-			backupRootPath, polardbxmeta.BinlogOffsetPath, fromXStoreName)
-		indexesPath := fmt.Sprintf("%s/%s", backupRootPath, polardbxmeta.BinlogIndexesName)
-		binlogBackupDir := fmt.Sprintf("%s/%s/%s",
-			backupRootPath, polardbxmeta.BinlogBackupPath, fromXStoreName)
-		cpFilePath := fmt.Sprintf("%s/%s/%s",
-			backupRootPath, polardbxmeta.BinlogOffsetPath, polardbxmeta.SeekCpName)
+		fullBackupPath := polarxPath.JoinPath(backupRootPath, polardbxmeta.FullBackupPath, fromXStoreName+".xbstream")
+		// full backup packed in archive is restored by its byte range
+		var fullBackupOffset int64
+		var fullBackupSize *int64
+		if entry := backup.Status.Archive.GetEntry(backup.Spec.XStore.Name); entry != nil {
+			fullBackupPath = polarxPath.JoinPath(backupRootPath, backup.Status.Archive.Path)
+			fullBackupOffset = entry.Offset
+			fullBackupSize = &entry.Size
+		}
+		// full backup chunked on upload is verified against its chunk manifest once downloaded
+		var chunkManifestPath string
+		if fullBackupSize == nil && backup.Status.ChunkSize > 0 {
+			chunkManifestPath = fullBackupPath + polardbxmeta.ChunkManifestSuffix
+		}
+		binlogEndOffsetPath := polarxPath.JoinPath(backupRootPath, polardbxmeta.BinlogOffsetPath, fromXStoreName+"-end")
+		indexesPath := polarxPath.JoinPath(backupRootPath, polardbxmeta.BinlogIndexesName)
+		binlogBackupDir := polarxPath.JoinPath(backupRootPath, polardbxmeta.BinlogBackupPath, fromXStoreName)
+		cpFilePath := polarxPath.JoinPath(backupRootPath, polardbxmeta.BinlogOffsetPath, polardbxmeta.SeekCpName)
 		_, pxcXStore := xstore.Labels[polardbxmeta.LabelName]
 		keyringPath := ""
 		keyringFilePath := ""
+		keyringChecksumPath := ""
+		var keyringKey *corev1.SecretKeySelector
 
 		//DN或标准版 且TDE开启恢复的时候下载keyring
 		if xstore.Status.TdeStatus == true && xstore.Labels[polardbxmeta.LabelRole] != polardbxmeta.RoleGMS {
@@ -404,13 +600,82 @@ var PrepareRestoreJobContext = xstorev1reconcile.NewStepBinder("PrepareRestoreJo
 			if err != nil {
 				return flow.Error(err, "Unable to get tde config map.")
 			}
-			keyringPath = fmt.Sprintf("%s/%s/%s",
-				backupRootPath, polardbxmeta.KeyringPath, backup.Spec.XStore.Name)
+			keyringPath = polarxPath.JoinPath(backupRootPath, polardbxmeta.KeyringPath, backup.Spec.XStore.Name)
 			keyringFilePath = tdeCm.Data[convention.KeyringPath]
+			// Keyring is required by prepare of TDE backup, fail the restore if it's missing
+			exists, err := isKeyringBackupExisted(rc, backup, keyringPath)
+			if err != nil {
+				return flow.RetryAfter(10*time.Second, "Failed to check keyring backup, error: "+err.Error())
+			}
+			if !exists {
+				rc.UpdateXStoreCondition(&xstorev1.Condition{
+					Type:    xstorev1.Restorable,
+					Status:  corev1.ConditionFalse,
+					Reason:  "KeyringNotFound",
+					Message: "Keyring of TDE backup isn't found: " + keyringPath,
+				})
+				setRestorePhase(rc, xstore, polardbxv1xstore.RestorePhaseFailed, "keyring not found: "+keyringPath)
+				xstore.Status.Phase = xstorev1.PhaseFailed
+				return flow.Wait("Keyring of TDE backup isn't found!", "keyring-path", keyringPath)
+			}
+			// Backups taken by earlier versions have no checksum of keyring
+			checksumExists, err := isKeyringBackupExisted(rc, backup, keyringPath+polardbxmeta.KeyringChecksumSuffix)
+			if err != nil {
+				return flow.RetryAfter(10*time.Second, "Failed to check keyring checksum, error: "+err.Error())
+			}
+			if checksumExists {
+				keyringChecksumPath = keyringPath + polardbxmeta.KeyringChecksumSuffix
+			}
+			// Keyring re-encrypted by backup can only be decrypted by the key it's rotated to
+			if backup.Status.KeyringKeyFingerprint != "" {
+				keyringKey, err = restoreKeyringKey(rc, backup)
+				if err != nil {
+					if !apierrors.IsNotFound(err) && !errors.Is(err, errKeyringKeyUnavailable) {
+						return flow.RetryAfter(10*time.Second, "Failed to get keyring key, error: "+err.Error())
+					}
+					rc.UpdateXStoreCondition(&xstorev1.Condition{
+						Type:    xstorev1.Restorable,
+						Status:  corev1.ConditionFalse,
+						Reason:  "KeyringKeyUnavailable",
+						Message: "Key of re-encrypted keyring is unavailable: " + err.Error(),
+					})
+					setRestorePhase(rc, xstore, polardbxv1xstore.RestorePhaseFailed, "keyring key unavailable: "+err.Error())
+					xstore.Status.Phase = xstorev1.PhaseFailed
+					return flow.Wait("Key of re-encrypted keyring is unavailable!", "error", err.Error())
+				}
+			}
+		}
+		// Files encrypted by backup can only be decrypted by the key they're encrypted with, full backup and
+		// binlogs share the same key
+		backupCodec := backup.Status.FullBackupCodec.OrDefault(polardbxv1polardbx.BackupArtifactFullBackup)
+		binlogCodec := backup.Status.BinlogBackupCodec.OrDefault(polardbxv1polardbx.BackupArtifactBinlog)
+		encryptedCodec := backupCodec
+		if !encryptedCodec.IsEncrypted() {
+			encryptedCodec = binlogCodec
+		}
+		var encryptionKey *corev1.SecretKeySelector
+		if encryptedCodec.IsEncrypted() {
+			encryptionKey, err = restoreEncryptionKey(rc, backup, encryptedCodec)
+			if err != nil {
+				if !apierrors.IsNotFound(err) && !errors.Is(err, errEncryptionKeyUnavailable) {
+					return flow.RetryAfter(10*time.Second, "Failed to get encryption key, error: "+err.Error())
+				}
+				rc.UpdateXStoreCondition(&xstorev1.Condition{
+					Type:    xstorev1.Restorable,
+					Status:  corev1.ConditionFalse,
+					Reason:  "EncryptionKeyUnavailable",
+					Message: "Key of encrypted backup is unavailable: " + err.Error(),
+				})
+				setRestorePhase(rc, xstore, polardbxv1xstore.RestorePhaseFailed, "encryption key unavailable: "+err.Error())
+				xstore.Status.Phase = xstorev1.PhaseFailed
+				return flow.Wait("Key of encrypted backup is unavailable!", "error", err.Error())
+			}
 		}
 		// Save.
 		if err := rc.SaveTaskContext(restoreJobKey, &RestoreJobContext{
 			BackupFilePath:      fullBackupPath,
+			BackupFileOffset:    fullBackupOffset,
+			BackupFileSize:      fullBackupSize,
 			BackupCommitIndex:   &lastCommitIndex,
 			BinlogDirPath:       binlogBackupDir,
 			BinlogEndOffsetPath: binlogEndOffsetPath,
@@ -423,6 +688,15 @@ var PrepareRestoreJobContext = xstorev1reconcile.NewStepBinder("PrepareRestoreJo
 			PxcXStore:           &pxcXStore,
 			KeyringPath:         keyringPath,
 			KeyringFilePath:     keyringFilePath,
+			KeyringChecksumPath: keyringChecksumPath,
+			ChunkManifestPath:   chunkManifestPath,
+			EndpointType:        restoreEndpointType(xstore, backup),
+			TableChecksums:      backup.Status.TableChecksums,
+			TableChecksumSample: backup.Status.TableChecksumSample,
+			KeyringKey:          keyringKey,
+			BackupCodec:         backupCodec,
+			BinlogCodec:         binlogCodec,
+			EncryptionKey:       encryptionKey,
 		}); err != nil {
 			return flow.Error(err, "Unable to save job context for restore!")
 		}
@@ -437,18 +711,26 @@ var RemoveRestoreJob = xstorev1reconcile.NewStepBinder("RemoveRestoreJob",
 		if err != nil {
 			return flow.Error(err, "Unable to get pods for xcluster.")
 		}
+		propagation := rc.JobDeletePropagation()
+		jobRemaining := false
 		for _, pod := range pods {
 			job, err := rc.GetXStoreJob(name.GetStableNameSuffix(xstore, pod.Name) + "-restore")
 			if err != nil && !apierrors.IsNotFound(err) {
 				return flow.Error(err, "Unable to get xstore restore data job", "pod", pod.Name)
 			}
 			if job != nil {
-				err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
-				if client.IgnoreNotFound(err) != nil {
-					return flow.Error(err, "Unable to remove restore job", "job-name", job.Name)
+				jobRemaining = true
+				if job.DeletionTimestamp.IsZero() {
+					err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(propagation))
+					if client.IgnoreNotFound(err) != nil {
+						return flow.Error(err, "Unable to remove restore job", "job-name", job.Name)
+					}
 				}
 			}
 		}
+		if jobRemaining && propagation == metav1.DeletePropagationForeground {
+			return flow.RetryAfter(5*time.Second, "Wait until restore jobs removed.")
+		}
 		return flow.Continue("Restore job removed!")
 	})
 
@@ -536,6 +818,156 @@ var WaitUntilRecoverJobFinished = xstorev1reconcile.NewStepBinder("WaitUntilReco
 		return flow.Continue("Recover Job completed!")
 	})
 
+// restoreVerifyTimeout is the max time to wait for the leader to apply up to the commit index of backup
+const restoreVerifyTimeout = 5 * time.Minute
+
+// VerifyRestoredCommitIndex checks that the applied index of leader reaches the commit index recorded by backup,
+// which guarantees restored shards are consistent with each other. Restore fails if the leader still lags
+// behind after restoreVerifyTimeout.
+var VerifyRestoredCommitIndex = xstorev1reconcile.NewStepBinder("VerifyRestoredCommitIndex",
+	func(rc *xstorev1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		const restoreJobKey = "restore"
+		restoreJobContext := &RestoreJobContext{}
+		err := rc.GetTaskContext(restoreJobKey, &restoreJobContext)
+		if err != nil {
+			return flow.Error(err, "Unable to get task context for restore")
+		}
+		if restoreJobContext.BackupCommitIndex == nil || *restoreJobContext.BackupCommitIndex <= 0 {
+			return flow.Continue("No commit index recorded by backup, skip verification.")
+		}
+		expectedIndex := *restoreJobContext.BackupCommitIndex
+
+		xstore := rc.MustGetXStore()
+		leaderPod, err := rc.TryGetXStoreLeaderPod()
+		if err != nil {
+			return flow.Error(err, "Unable to get leader pod.")
+		}
+		if leaderPod == nil {
+			return flow.RetryAfter(5*time.Second, "Leader pod not found, retry.")
+		}
+		localInfo, err := ShowThis(rc, leaderPod, flow.Logger(), true)
+		if err != nil {
+			return flow.RetryAfter(5*time.Second, "Failed to show consensus info of leader, error: "+err.Error())
+		}
+		appliedIndex, err := strconv.ParseInt(localInfo.AppliedIndex, 10, 64)
+		if err != nil {
+			return flow.RetryAfter(5*time.Second, "Failed to parse applied index of leader: "+localInfo.AppliedIndex)
+		}
+
+		restoreStatus := xstore.Status.RestoreStatus
+		restoreStatus.ExpectedCommitIndex = expectedIndex
+		restoreStatus.AppliedIndex = appliedIndex
+		if appliedIndex >= expectedIndex {
+			return flow.Continue("Restored commit index verified.", "applied-index", appliedIndex,
+				"expected-index", expectedIndex)
+		}
+
+		if time.Since(restoreStatus.LastTransitionTime.Time) < restoreVerifyTimeout {
+			return flow.RetryAfter(10*time.Second, "Applied index of leader lags behind backup, wait.",
+				"applied-index", appliedIndex, "expected-index", expectedIndex)
+		}
+		message := fmt.Sprintf("applied index %d of leader %s lags behind commit index %d of backup",
+			appliedIndex, leaderPod.Name, expectedIndex)
+		rc.UpdateXStoreCondition(&xstorev1.Condition{
+			Type:    xstorev1.Restorable,
+			Status:  corev1.ConditionFalse,
+			Reason:  "CommitIndexNotReached",
+			Message: message,
+		})
+		setRestorePhase(rc, xstore, polardbxv1xstore.RestorePhaseFailed, message)
+		xstore.Status.Phase = xstorev1.PhaseFailed
+		return flow.Wait("Applied index of leader lags behind backup!", "applied-index", appliedIndex,
+			"expected-index", expectedIndex)
+	})
+
+// divergedTableChecksums returns the tables whose checksums differ from the expected ones, sorted.
+func divergedTableChecksums(expected, actual map[string]string) []string {
+	diverged := make([]string, 0)
+	for table, checksum := range expected {
+		if actual[table] != checksum {
+			diverged = append(diverged, table)
+		}
+	}
+	sort.Strings(diverged)
+	return diverged
+}
+
+// VerifyRestoredTableChecksums checks that checksums of tables on leader equal to the ones captured by backup,
+// which catches logical corruption that physical backup can't detect. Only the rows sampled by backup are
+// verified if checksums are captured by sampling, and the coverage is recorded in restore status. Verification
+// is skipped if no checksum is captured by backup, and restore fails if any of them diverges.
+var VerifyRestoredTableChecksums = xstorev1reconcile.NewStepBinder("VerifyRestoredTableChecksums",
+	func(rc *xstorev1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+		const restoreJobKey = "restore"
+		restoreJobContext := &RestoreJobContext{}
+		err := rc.GetTaskContext(restoreJobKey, &restoreJobContext)
+		if err != nil {
+			return flow.Error(err, "Unable to get task context for restore")
+		}
+		if len(restoreJobContext.TableChecksums) == 0 {
+			return flow.Continue("No table checksum captured by backup, skip verification.")
+		}
+
+		xstore := rc.MustGetXStore()
+		leaderPod, err := rc.TryGetXStoreLeaderPod()
+		if err != nil {
+			return flow.Error(err, "Unable to get leader pod.")
+		}
+		if leaderPod == nil {
+			return flow.RetryAfter(5*time.Second, "Leader pod not found, retry.")
+		}
+
+		tables := make([]string, 0, len(restoreJobContext.TableChecksums))
+		for table := range restoreJobContext.TableChecksums {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+		sample := restoreJobContext.TableChecksumSample
+		stdout := &bytes.Buffer{}
+		cmd := command.NewCanonicalCommandBuilder().Engine().Checksum(tables...).Build()
+		if sample != nil {
+			cmd = command.NewCanonicalCommandBuilder().Engine().ChecksumSample(sample.Percent, sample.Seed, tables...).Build()
+		}
+		err = rc.ExecuteCommandOn(leaderPod, convention.ContainerEngine, cmd, control.ExecOptions{
+			Logger: flow.Logger(),
+			Stdout: stdout,
+			Stderr: &bytes.Buffer{},
+		})
+		if err != nil {
+			return flow.RetryAfter(10*time.Second, "Failed to checksum tables, error: "+err.Error())
+		}
+
+		var message string
+		var checksums map[string]string
+		coverage := int32(100)
+		if sample != nil {
+			checksums, _, err = command.ParseSampledTableChecksums(stdout.String())
+			coverage = sample.Percent
+		} else {
+			checksums, err = command.ParseTableChecksums(stdout.String())
+		}
+		if err != nil {
+			message = "unable to checksum restored tables: " + err.Error()
+		} else if diverged := divergedTableChecksums(restoreJobContext.TableChecksums, checksums); len(diverged) > 0 {
+			message = "checksums of restored tables diverge from backup: " + strings.Join(diverged, ", ")
+		} else {
+			if xstore.Status.RestoreStatus == nil {
+				xstore.Status.RestoreStatus = &polardbxv1xstore.RestoreStatus{}
+			}
+			xstore.Status.RestoreStatus.ChecksumCoverage = coverage
+			return flow.Continue("Restored table checksums verified.", "tables", len(tables), "coverage", coverage)
+		}
+		rc.UpdateXStoreCondition(&xstorev1.Condition{
+			Type:    xstorev1.Restorable,
+			Status:  corev1.ConditionFalse,
+			Reason:  "ChecksumMismatch",
+			Message: message,
+		})
+		setRestorePhase(rc, xstore, polardbxv1xstore.RestorePhaseFailed, message)
+		xstore.Status.Phase = xstorev1.PhaseFailed
+		return flow.Wait("Table checksums of restored xstore diverge from backup!", "message", message)
+	})
+
 var RemoveRecoverJob = xstorev1reconcile.NewStepBinder("RemoveRecoverJob",
 	func(rc *xstorev1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		xstore := rc.MustGetXStore()
@@ -551,9 +983,15 @@ var RemoveRecoverJob = xstorev1reconcile.NewStepBinder("RemoveRecoverJob",
 			return flow.Error(err, "Unable to get xstore recover data job", "pod", leaderPod.Name)
 		}
 		if job != nil {
-			err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
-			if client.IgnoreNotFound(err) != nil {
-				return flow.Error(err, "Unable to remove recover job", "job-name", job.Name)
+			propagation := rc.JobDeletePropagation()
+			if job.DeletionTimestamp.IsZero() {
+				err = rc.Client().Delete(rc.Context(), job, client.PropagationPolicy(propagation))
+				if client.IgnoreNotFound(err) != nil {
+					return flow.Error(err, "Unable to remove recover job", "job-name", job.Name)
+				}
+			}
+			if propagation == metav1.DeletePropagationForeground {
+				return flow.RetryAfter(5*time.Second, "Wait until recover job removed.", "job-name", job.Name)
 			}
 		}
 		return flow.Continue("Recover job removed!")
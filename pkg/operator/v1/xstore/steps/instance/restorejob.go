@@ -20,6 +20,7 @@ import (
 	xstorev1 "github.com/alibaba/polardbx-operator/api/v1"
 	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/command"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/convention"
 	xstoremeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/xstore/meta"
 	"github.com/alibaba/polardbx-operator/pkg/util/name"
 	batchv1 "k8s.io/api/batch/v1"
@@ -78,7 +79,8 @@ func patchTaskConfigMapVolumeAndVolumeMounts(xstore *xstorev1.XStore, podSpec *c
 	}
 }
 
-func newRestoreDataJob(xstore *xstorev1.XStore, targetPod *corev1.Pod) *batchv1.Job {
+func newRestoreDataJob(xstore *xstorev1.XStore, targetPod *corev1.Pod, keyringKey,
+	encryptionKey *corev1.SecretKeySelector) *batchv1.Job {
 	podSpec := targetPod.Spec.DeepCopy()
 	podSpec.InitContainers = nil
 	podSpec.RestartPolicy = corev1.RestartPolicyNever
@@ -103,6 +105,21 @@ func newRestoreDataJob(xstore *xstorev1.XStore, targetPod *corev1.Pod) *batchv1.
 	replaceSystemEnvs(podSpec, targetPod)
 	patchTaskConfigMapVolumeAndVolumeMounts(xstore, podSpec)
 
+	// Key of re-encrypted keyring is passed by env referring the secret, never written to the task config map
+	if keyringKey != nil {
+		podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, corev1.EnvVar{
+			Name:      convention.EnvKeyringTargetKey,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: keyringKey},
+		})
+	}
+	// So is the key of files encrypted by backup
+	if encryptionKey != nil {
+		podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, corev1.EnvVar{
+			Name:      convention.EnvBackupEncryptionKey,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: encryptionKey},
+		})
+	}
+
 	return &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name.StableName(xstore, name.GetStableNameSuffix(xstore, targetPod.Name)+"-restore"),
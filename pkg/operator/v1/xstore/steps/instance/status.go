@@ -19,9 +19,12 @@ package instance
 import (
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	polardbxv1 "github.com/alibaba/polardbx-operator/api/v1"
 	polardbxv1xstore "github.com/alibaba/polardbx-operator/api/v1/xstore"
 	"github.com/alibaba/polardbx-operator/pkg/k8s/control"
 	k8shelper "github.com/alibaba/polardbx-operator/pkg/k8s/helper"
@@ -86,6 +89,60 @@ func UpdateStageTemplate(stage polardbxv1xstore.Stage, requeue ...bool) control.
 		})
 }
 
+// restorePhaseProgress is the estimated progress when restore enters the phase
+var restorePhaseProgress = map[polardbxv1xstore.RestorePhase]int32{
+	polardbxv1xstore.RestorePhaseDownloading:      0,
+	polardbxv1xstore.RestorePhasePreparing:        30,
+	polardbxv1xstore.RestorePhaseApplying:         50,
+	polardbxv1xstore.RestorePhaseRecoveringBinlog: 70,
+	polardbxv1xstore.RestorePhaseVerifying:        90,
+	polardbxv1xstore.RestorePhaseFinished:         100,
+}
+
+func setRestorePhase(rc *xstorev1reconcile.Context, xstore *polardbxv1.XStore, phase polardbxv1xstore.RestorePhase, message string) {
+	if xstore.Status.RestoreStatus == nil {
+		xstore.Status.RestoreStatus = &polardbxv1xstore.RestoreStatus{}
+	}
+	restoreStatus := xstore.Status.RestoreStatus
+	// Restore phase never moves backward, since steps of earlier phases are revisited on every reconcile.
+	if current, ok := restorePhaseProgress[restoreStatus.Phase]; ok {
+		if next, ok := restorePhaseProgress[phase]; ok && next < current {
+			return
+		}
+	}
+	restoreStatus.Message = message
+	if restoreStatus.Phase == phase {
+		return
+	}
+	restoreStatus.Phase = phase
+	restoreStatus.LastTransitionTime = metav1.Now()
+	if progress, ok := restorePhaseProgress[phase]; ok {
+		restoreStatus.Progress = progress
+	}
+
+	eventType := corev1.EventTypeNormal
+	if phase == polardbxv1xstore.RestorePhaseFailed {
+		eventType = corev1.EventTypeWarning
+	}
+	eventMessage := "Restore phase changed to " + string(phase)
+	if message != "" {
+		eventMessage += ": " + message
+	}
+	rc.RecordEvent(xstore, eventType, "Restore"+string(phase), eventMessage)
+}
+
+func UpdateRestorePhaseTemplate(phase polardbxv1xstore.RestorePhase) control.BindFunc {
+	return xstorev1reconcile.NewStepBinder("UpdateRestorePhaseTo"+string(phase),
+		func(rc *xstorev1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
+			xstore, err := rc.GetXStore()
+			if err != nil {
+				return flow.Error(err, "Unable to get xstore.")
+			}
+			setRestorePhase(rc, xstore, phase, "")
+			return flow.Continue("Restore phase updated!", "target-restore-phase", phase)
+		})
+}
+
 var MoveToPhaseDeletingIfDeleted = xstorev1reconcile.NewStepBinder("MoveToPhaseDeletingIfDeleted",
 	func(rc *xstorev1reconcile.Context, flow control.Flow) (reconcile.Result, error) {
 		xstore, err := rc.GetXStore()
@@ -0,0 +1,55 @@
+package exportbackupset
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/factory"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	"github.com/alibaba/polardbx-operator/pkg/util/path"
+)
+
+// CheckObjects checks in place that all the objects of backup set under rootPath exist, and that keyrings match
+// their checksums if recorded. Problems found in backup set are returned, while error is returned only if the
+// check itself fails.
+func CheckObjects(s *BackupSetStorage, rootPath string, metadata *factory.MetadataBackup) ([]string, error) {
+	objects, err := s.ListBackupSetObjects(rootPath, metadata)
+	if err != nil {
+		return nil, err
+	}
+	problems := make([]string, 0)
+	existing := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		exists, err := s.exists(path.JoinPath(rootPath, object))
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", object, err)
+		}
+		if !exists {
+			problems = append(problems, "object "+object+" not found")
+		}
+		existing[object] = exists
+	}
+	for _, object := range objects {
+		if !strings.HasSuffix(object, polardbxmeta.KeyringChecksumSuffix) || !existing[object] {
+			continue
+		}
+		keyring := strings.TrimSuffix(object, polardbxmeta.KeyringChecksumSuffix)
+		if !existing[keyring] {
+			problems = append(problems, "keyring "+keyring+" not found")
+			continue
+		}
+		var checksum, data bytes.Buffer
+		if _, err := s.download(&checksum, path.JoinPath(rootPath, object)); err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", object, err)
+		}
+		if _, err := s.download(&data, path.JoinPath(rootPath, keyring)); err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", keyring, err)
+		}
+		if expected, actual := strings.TrimSpace(checksum.String()), sha256Hex(data.Bytes()); expected != actual {
+			problems = append(problems, fmt.Sprintf("keyring %s checksum mismatch, expected %s, actual %s",
+				keyring, expected, actual))
+		}
+	}
+	return problems, nil
+}
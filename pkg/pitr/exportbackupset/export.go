@@ -0,0 +1,333 @@
+package exportbackupset
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/factory"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	"github.com/alibaba/polardbx-operator/pkg/util/path"
+)
+
+const metadataObjectPath = "metadata"
+
+// BackupSetStorage accesses objects of backup sets in a sink through filestream.
+type BackupSetStorage struct {
+	client filestream.Client
+	action *polardbx.BackupStorageFilestreamAction
+	sink   string
+}
+
+func NewBackupSetStorage(client filestream.Client, storageName polardbx.BackupStorage, sink string) (*BackupSetStorage, error) {
+	action, err := polardbx.NewBackupStorageFilestreamAction(storageName)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupSetStorage{
+		client: client,
+		action: action,
+		sink:   sink,
+	}, nil
+}
+
+func (s *BackupSetStorage) download(writer io.Writer, filename string) (int64, error) {
+	s.client.InitWaitChan()
+	return s.client.Download(writer, filestream.ActionMetadata{
+		Action:    s.action.Download,
+		Sink:      s.sink,
+		RequestId: uuid.New().String(),
+		Filename:  filename,
+	})
+}
+
+func (s *BackupSetStorage) upload(reader io.Reader, filename string) (int64, error) {
+	actionMetadata := filestream.ActionMetadata{
+		Action:    s.action.Upload,
+		Sink:      s.sink,
+		RequestId: uuid.New().String(),
+		Filename:  filename,
+	}
+	sentBytes, err := s.client.Upload(reader, actionMetadata)
+	if err != nil {
+		return sentBytes, err
+	}
+	return sentBytes, s.client.Check(actionMetadata)
+}
+
+func (s *BackupSetStorage) list(dir string) ([]string, error) {
+	var buf bytes.Buffer
+	if _, err := s.client.List(&buf, filestream.ActionMetadata{
+		Action:    s.action.List,
+		Sink:      s.sink,
+		RequestId: uuid.New().String(),
+		Filepath:  dir,
+	}); err != nil {
+		return nil, err
+	}
+	var entryNames []string
+	if err := json.Unmarshal(buf.Bytes(), &entryNames); err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(entryNames))
+	for _, entryName := range entryNames {
+		if entryName != "" {
+			result = append(result, entryName)
+		}
+	}
+	return result, nil
+}
+
+func (s *BackupSetStorage) exists(filePath string) (bool, error) {
+	return s.client.Exists(filestream.ActionMetadata{
+		Action:    s.action.List,
+		Sink:      s.sink,
+		RequestId: uuid.New().String(),
+		Filepath:  filePath,
+	})
+}
+
+// DownloadMetadata downloads and parses metadata of the backup set under rootPath.
+func (s *BackupSetStorage) DownloadMetadata(rootPath string) (*factory.MetadataBackup, error) {
+	var buf bytes.Buffer
+	if _, err := s.download(&buf, path.JoinPath(rootPath, metadataObjectPath)); err != nil {
+		return nil, fmt.Errorf("failed to download metadata: %w", err)
+	}
+	metadata := &factory.MetadataBackup{}
+	if err := json.Unmarshal(buf.Bytes(), metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// listDirObjects lists objects directly under dir relative to rootPath, nothing returned if dir doesn't exist.
+func (s *BackupSetStorage) listDirObjects(rootPath, dir string) ([]string, error) {
+	exists, err := s.exists(path.JoinPath(rootPath, dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s: %w", dir, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	entryNames, err := s.list(path.JoinPath(rootPath, dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	objects := make([]string, 0, len(entryNames))
+	for _, entryName := range entryNames {
+		objects = append(objects, path.JoinPath(dir, entryName))
+	}
+	return objects, nil
+}
+
+// ListBackupSetObjects lists paths relative to rootPath of all the objects belonging to the backup set, including
+// full backups, binlogs, binlog offsets, keyrings and metadata. Metadata is always the last one. Full backups and
+// binlogs of xstores stored in overridden sinks are not listed.
+func (s *BackupSetStorage) ListBackupSetObjects(rootPath string, metadata *factory.MetadataBackup) ([]string, error) {
+	objects := make([]string, 0)
+	fullBackupDirObjects, err := s.listDirObjects(rootPath, polardbxmeta.FullBackupPath)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Archive != nil {
+		objects = append(objects, metadata.Archive.Path)
+	}
+	for _, xstoreMetadata := range metadata.XstoreMetadataList {
+		if xstoreMetadata.StorageProvider != nil {
+			continue
+		}
+		fullBackupObject := path.JoinPath(polardbxmeta.FullBackupPath, xstoreMetadata.Name+".xbstream")
+		if metadata.Archive.GetEntry(xstoreMetadata.Name) == nil {
+			objects = append(objects, fullBackupObject)
+		}
+		// part objects of full backup split on upload
+		for _, object := range fullBackupDirObjects {
+			if strings.HasPrefix(object, fullBackupObject+filestream.SplitPartPrefix) {
+				objects = append(objects, object)
+			}
+		}
+		if xstoreMetadata.ChunkSize > 0 {
+			objects = append(objects, fullBackupObject+polardbxmeta.ChunkManifestSuffix)
+		}
+		binlogObjects, err := s.listDirObjects(rootPath, path.JoinPath(polardbxmeta.BinlogBackupPath, xstoreMetadata.Name))
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, binlogObjects...)
+	}
+	for _, dir := range []string{polardbxmeta.BinlogOffsetPath, polardbxmeta.KeyringPath} {
+		dirObjects, err := s.listDirObjects(rootPath, dir)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, dirObjects...)
+	}
+	indexesExists, err := s.exists(path.JoinPath(rootPath, polardbxmeta.BinlogIndexesName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s: %w", polardbxmeta.BinlogIndexesName, err)
+	}
+	if indexesExists {
+		objects = append(objects, polardbxmeta.BinlogIndexesName)
+	}
+	return append(objects, metadataObjectPath), nil
+}
+
+// Export downloads all the objects of backup set under rootPath into dir, along with a manifest recording size
+// and sha256 of each object, so that the backup set can be transferred offline and imported later.
+func Export(logger logr.Logger, s *BackupSetStorage, rootPath, dir string) (*Manifest, error) {
+	metadata, err := s.DownloadMetadata(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata.GetXStoreStorageProviders()) > 0 {
+		return nil, errors.New("backup set spans multiple sinks as storage providers of xstores are overridden, " +
+			"which can not be exported")
+	}
+	objects, err := s.ListBackupSetObjects(rootPath, metadata)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("backup set objects listed", "backup-set", metadata.BackupSetName, "count", len(objects))
+
+	manifest := &Manifest{
+		BackupSetName:  metadata.BackupSetName,
+		BackupRootPath: rootPath,
+		ExportTime:     time.Now().UTC().Format(time.RFC3339),
+		Objects:        make([]ManifestObject, 0, len(objects)),
+	}
+	for _, object := range objects {
+		manifestObject, err := exportObject(s, rootPath, dir, object)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("object exported", "path", object, "size", manifestObject.Size)
+		manifest.Objects = append(manifest.Objects, *manifestObject)
+	}
+	if err := WriteManifest(dir, manifest); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func exportObject(s *BackupSetStorage, rootPath, dir, object string) (*ManifestObject, error) {
+	localPath, err := objectLocalPath(dir, object)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := s.download(io.MultiWriter(f, hash), path.JoinPath(rootPath, object))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download object %s: %w", object, err)
+	}
+	if err := f.Sync(); err != nil {
+		return nil, err
+	}
+	return &ManifestObject{
+		Path:   object,
+		Size:   size,
+		Sha256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// Import verifies the exported backup set in dir against its manifest and uploads all the objects under rootPath.
+// Metadata is uploaded last, so that a partially imported backup set is never taken as restorable.
+func Import(logger logr.Logger, s *BackupSetStorage, dir, rootPath string) error {
+	manifest, err := Verify(dir)
+	if err != nil {
+		return fmt.Errorf("failed to verify exported backup set: %w", err)
+	}
+	logger.Info("exported backup set verified", "backup-set", manifest.BackupSetName, "count", len(manifest.Objects))
+
+	var metadataObject *ManifestObject
+	for i, object := range manifest.Objects {
+		if object.Path == metadataObjectPath {
+			metadataObject = &manifest.Objects[i]
+			continue
+		}
+		if err := importObject(s, dir, rootPath, object); err != nil {
+			return err
+		}
+		logger.Info("object imported", "path", object.Path, "size", object.Size)
+	}
+	if metadataObject == nil {
+		return fmt.Errorf("metadata not found in manifest")
+	}
+
+	localPath, err := objectLocalPath(dir, metadataObject.Path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	metadata := &factory.MetadataBackup{}
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	relocateMetadata(metadata, rootPath)
+	data, err = json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	if _, err := s.upload(bytes.NewReader(data), path.JoinPath(rootPath, metadataObjectPath)); err != nil {
+		return fmt.Errorf("failed to upload metadata: %w", err)
+	}
+	logger.Info("metadata imported", "root-path", rootPath)
+	return nil
+}
+
+// relocateMetadata points paths recorded in metadata to the new root path.
+func relocateMetadata(metadata *factory.MetadataBackup, rootPath string) {
+	metadata.BackupRootPath = rootPath
+	for i := range metadata.XstoreMetadataList {
+		xstoreMetadata := &metadata.XstoreMetadataList[i]
+		if xstoreMetadata.ChunkManifestPath != "" {
+			xstoreMetadata.ChunkManifestPath = path.JoinPath(rootPath, polardbxmeta.FullBackupPath,
+				xstoreMetadata.Name+".xbstream"+polardbxmeta.ChunkManifestSuffix)
+		}
+	}
+}
+
+func importObject(s *BackupSetStorage, dir, rootPath string, object ManifestObject) error {
+	localPath, err := objectLocalPath(dir, object.Path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sentBytes, err := s.upload(f, path.JoinPath(rootPath, object.Path))
+	if err != nil {
+		return fmt.Errorf("failed to upload object %s: %w", object.Path, err)
+	}
+	if sentBytes != object.Size {
+		return fmt.Errorf("object %s not completely uploaded, expected %d bytes, sent %d bytes",
+			object.Path, object.Size, sentBytes)
+	}
+	return nil
+}
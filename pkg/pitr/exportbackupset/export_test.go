@@ -0,0 +1,132 @@
+package exportbackupset
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/onsi/gomega"
+
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/factory"
+	"github.com/alibaba/polardbx-operator/pkg/util/json"
+)
+
+func newFakeBackupSet(client *filestream.FakeFilestreamClient, sink, rootPath string) {
+	metadata := factory.MetadataBackup{
+		BackupSetName:  "pxc-backup",
+		BackupRootPath: rootPath,
+		XstoreMetadataList: []factory.XstoreMetadata{
+			{Name: "dn-0", ChunkSize: 1024, ChunkManifestPath: rootPath + "/fullbackup/dn-0.xbstream.chunks"},
+			{Name: "gms"},
+		},
+	}
+	client.PutFile(sink, rootPath+"/metadata", []byte(json.Convert2JsonString(metadata)))
+	client.PutFile(sink, rootPath+"/fullbackup/dn-0.xbstream", []byte("dn-0 full"))
+	client.PutFile(sink, rootPath+"/fullbackup/dn-0.xbstream.chunks", []byte("dn-0 chunks"))
+	client.PutFile(sink, rootPath+"/fullbackup/gms.xbstream", []byte(filestream.SplitManifestMagic+"{}"))
+	client.PutFile(sink, rootPath+"/fullbackup/gms.xbstream.part-00000", []byte("gms full"))
+	client.PutFile(sink, rootPath+"/binlogbackup/dn-0/mysql-bin.000001", []byte("dn-0 binlog"))
+	client.PutFile(sink, rootPath+"/binlogoffset/dn-0-end", []byte("dn-0 offset"))
+	client.PutFile(sink, rootPath+"/indexes", []byte("indexes"))
+}
+
+func TestExportAndImport(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	client := filestream.NewFakeFilestreamClient()
+	newFakeBackupSet(client, "src", "backup/pxc-backup")
+
+	dir := t.TempDir()
+	src, err := NewBackupSetStorage(client, polardbx.MINIO, "src")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	manifest, err := Export(logr.Discard(), src, "backup/pxc-backup", dir)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(manifest.Objects).To(gomega.HaveLen(8))
+	g.Expect(manifest.Objects[len(manifest.Objects)-1].Path).To(gomega.Equal("metadata"))
+
+	dst, err := NewBackupSetStorage(client, polardbx.MINIO, "dst")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(Import(logr.Discard(), dst, dir, "imported/pxc-backup")).To(gomega.Succeed())
+	data, ok := client.GetFile("dst", "imported/pxc-backup/binlogbackup/dn-0/mysql-bin.000001")
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(string(data)).To(gomega.Equal("dn-0 binlog"))
+	data, ok = client.GetFile("dst", "imported/pxc-backup/fullbackup/gms.xbstream.part-00000")
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(string(data)).To(gomega.Equal("gms full"))
+
+	metadata, err := dst.DownloadMetadata("imported/pxc-backup")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(metadata.BackupRootPath).To(gomega.Equal("imported/pxc-backup"))
+	g.Expect(metadata.XstoreMetadataList[0].ChunkManifestPath).To(gomega.Equal("imported/pxc-backup/fullbackup/dn-0.xbstream.chunks"))
+}
+
+func TestImportRejectsCorruptedObject(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	client := filestream.NewFakeFilestreamClient()
+	newFakeBackupSet(client, "src", "backup/pxc-backup")
+
+	dir := t.TempDir()
+	src, err := NewBackupSetStorage(client, polardbx.MINIO, "src")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = Export(logr.Discard(), src, "backup/pxc-backup", dir)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	g.Expect(os.WriteFile(filepath.Join(dir, ObjectsDirName, "indexes"), []byte("corrupted"), 0644)).To(gomega.Succeed())
+	dst, err := NewBackupSetStorage(client, polardbx.MINIO, "dst")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(Import(logr.Discard(), dst, dir, "imported/pxc-backup")).NotTo(gomega.Succeed())
+	_, ok := client.GetFile("dst", "imported/pxc-backup/metadata")
+	g.Expect(ok).To(gomega.BeFalse())
+}
+
+func TestListBackupSetObjectsConsolidated(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	client := filestream.NewFakeFilestreamClient()
+	rootPath := "backup/pxc-backup"
+	metadata := &factory.MetadataBackup{
+		BackupSetName:      "pxc-backup",
+		BackupRootPath:     rootPath,
+		XstoreMetadataList: []factory.XstoreMetadata{{Name: "dn-0"}, {Name: "gms"}},
+		Archive: &polardbx.BackupArchive{
+			Path: "fullbackup/archive",
+			Entries: []polardbx.BackupArchiveEntry{
+				{XStore: "dn-0", Offset: 0, Size: 9},
+				{XStore: "gms", Offset: 9, Size: 8},
+			},
+		},
+	}
+	client.PutFile("src", rootPath+"/fullbackup/archive", []byte("dn-0 fullgms full"))
+
+	src, err := NewBackupSetStorage(client, polardbx.MINIO, "src")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	objects, err := src.ListBackupSetObjects(rootPath, metadata)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(objects).To(gomega.Equal([]string{"fullbackup/archive", "metadata"}))
+}
+
+func TestCheckObjects(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	client := filestream.NewFakeFilestreamClient()
+	rootPath := "backup/pxc-backup"
+	newFakeBackupSet(client, "src", rootPath)
+	client.PutFile("src", rootPath+"/keyring/dn-0", []byte("dn-0 keyring"))
+	client.PutFile("src", rootPath+"/keyring/dn-0.sha256", []byte(sha256Hex([]byte("dn-0 keyring"))+"\n"))
+
+	src, err := NewBackupSetStorage(client, polardbx.MINIO, "src")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	metadata, err := src.DownloadMetadata(rootPath)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	problems, err := CheckObjects(src, rootPath, metadata)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(problems).To(gomega.BeEmpty())
+
+	client.PutFile("src", rootPath+"/keyring/dn-0", []byte("corrupted"))
+	metadata.XstoreMetadataList = append(metadata.XstoreMetadataList, factory.XstoreMetadata{Name: "dn-1"})
+	problems, err = CheckObjects(src, rootPath, metadata)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(problems).To(gomega.HaveLen(2))
+	g.Expect(problems[0]).To(gomega.Equal("object fullbackup/dn-1.xbstream not found"))
+	g.Expect(problems[1]).To(gomega.HavePrefix("keyring keyring/dn-0 checksum mismatch"))
+}
@@ -0,0 +1,120 @@
+package exportbackupset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ManifestFilename is the name of manifest in the export directory, which lists all the objects of backup set
+	ManifestFilename = "manifest.json"
+	// ManifestChecksumFilename is the name of file which records sha256 of manifest
+	ManifestChecksumFilename = "manifest.sha256"
+	// ObjectsDirName is the directory in the export directory where objects are placed by their relative paths
+	ObjectsDirName = "objects"
+)
+
+type ManifestObject struct {
+	// Path is the path of object relative to backup root path
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+type Manifest struct {
+	BackupSetName  string           `json:"backupSetName,omitempty"`
+	BackupRootPath string           `json:"backupRootPath,omitempty"`
+	ExportTime     string           `json:"exportTime,omitempty"`
+	Objects        []ManifestObject `json:"objects"`
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// objectLocalPath returns the local path of object in the export directory, relative paths escaping the
+// directory are rejected since manifest may come from elsewhere.
+func objectLocalPath(dir, objectPath string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(objectPath))
+	if cleaned == "." || filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", errors.New("invalid object path: " + objectPath)
+	}
+	return filepath.Join(dir, ObjectsDirName, cleaned), nil
+}
+
+// WriteManifest writes manifest and its checksum into dir.
+func WriteManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFilename), data, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestChecksumFilename), []byte(sha256Hex(data)), 0644)
+}
+
+// ReadManifest reads manifest from dir and checks it against its checksum.
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFilename))
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := os.ReadFile(filepath.Join(dir, ManifestChecksumFilename))
+	if err != nil {
+		return nil, err
+	}
+	if actual := sha256Hex(data); actual != strings.TrimSpace(string(checksum)) {
+		return nil, fmt.Errorf("manifest checksum mismatch, expected %s, actual %s", strings.TrimSpace(string(checksum)), actual)
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func hashFile(localPath string) (int64, string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Verify checks the manifest in dir and that every object listed exists with the recorded size and sha256.
+func Verify(dir string) (*Manifest, error) {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range manifest.Objects {
+		localPath, err := objectLocalPath(dir, object.Path)
+		if err != nil {
+			return nil, err
+		}
+		size, sum, err := hashFile(localPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object %s: %w", object.Path, err)
+		}
+		if size != object.Size || sum != object.Sha256 {
+			return nil, fmt.Errorf("object %s corrupted, expected size %d sha256 %s, actual size %d sha256 %s",
+				object.Path, object.Size, object.Sha256, size, sum)
+		}
+	}
+	return manifest, nil
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -39,11 +40,13 @@ const (
 	TypeXStore   = "xstore"
 	TypeSelf     = "server"
 	TypeCdc      = "cdc"
+	TypeColumnar = "columnar"
 )
 
 type Prober struct {
-	target string
-	extra  string
+	target   string
+	extra    string
+	httpPath string
 
 	user    string
 	host    string
@@ -57,7 +60,7 @@ type Prober struct {
 
 func (p *Prober) valid() bool {
 	for _, t := range []string{
-		TypePolarDBX, TypeXStore, TypeSelf, TypeCdc,
+		TypePolarDBX, TypeXStore, TypeSelf, TypeCdc, TypeColumnar,
 	} {
 		if p.target == t {
 			return true
@@ -70,7 +73,7 @@ func (p *Prober) defaultUser() string {
 	switch p.target {
 	case TypeXStore:
 		return "root"
-	case TypePolarDBX:
+	case TypePolarDBX, TypeColumnar:
 		return "polardbx_root"
 	default:
 		return ""
@@ -79,14 +82,19 @@ func (p *Prober) defaultUser() string {
 
 func NewProber(r *http.Request) (*Prober, error) {
 	p := &Prober{
-		target: r.Header.Get("Probe-Target"),
-		extra:  r.Header.Get("Probe-Extra"),
+		target:   r.Header.Get("Probe-Target"),
+		extra:    r.Header.Get("Probe-Extra"),
+		httpPath: r.Header.Get("Probe-Http-Path"),
 	}
 
 	if !p.valid() {
 		return nil, errors.New("invalid probe target: %s" + p.target)
 	}
 
+	if p.httpPath != "" && !strings.HasPrefix(p.httpPath, "/") {
+		p.httpPath = "/" + p.httpPath
+	}
+
 	// Extract parameters from http headers.
 	p.host = defaults.NonEmptyStrOrDefault(r.Header.Get("Probe-Host"), "127.0.0.1")
 	p.user = defaults.NonEmptyStrOrDefault(r.Header.Get("Probe-User"), p.defaultUser())
@@ -139,7 +147,7 @@ func (p *Prober) ping() error {
 
 func (p *Prober) cdcConnect() error {
 	httpClient := http.Client{Timeout: p.timeout}
-	url := fmt.Sprintf("http://%s:%d/status", p.host, p.port)
+	url := fmt.Sprintf("http://%s:%d%s", p.host, p.port, defaults.NonEmptyStrOrDefault(p.httpPath, "/status"))
 	resp, err := httpClient.Get(url)
 	if err != nil {
 		return err
@@ -156,9 +164,23 @@ func (p *Prober) cdcConnect() error {
 	return nil
 }
 
+// cdcReadiness checks the port of cdc is open, and then the http health endpoint if specified.
+func (p *Prober) cdcReadiness() error {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", p.host, p.port), p.timeout)
+	if err != nil {
+		return err
+	}
+	_ = conn.Close()
+
+	if p.httpPath == "" {
+		return nil
+	}
+	return p.cdcConnect()
+}
+
 func (p *Prober) Liveness() error {
 	switch p.target {
-	case TypeXStore, TypePolarDBX:
+	case TypeXStore, TypePolarDBX, TypeColumnar:
 		if err := p.connect(); err != nil {
 			return err
 		}
@@ -189,6 +211,10 @@ func (p *Prober) extraReadinessForXStore() error {
 }
 
 func (p *Prober) ProbeReadiness() error {
+	if p.target == TypeCdc {
+		return p.cdcReadiness()
+	}
+
 	err := p.Liveness()
 	if err != nil {
 		return err
@@ -18,14 +18,18 @@ package probe
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
+
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
 )
 
 func handleErr(w http.ResponseWriter, err error) {
@@ -73,6 +77,11 @@ type ReadinessHandler struct {
 }
 
 func (handler *ReadinessHandler) Handle(r *http.Request) error {
+	// Draining pod is never ready, even in debug mode.
+	if handler.server.IsDraining() {
+		return errors.New("pod is draining")
+	}
+
 	if handler.server.IsDebugModeEnabled() {
 		log.Println("Debug enabled, return alive!")
 		return nil
@@ -95,6 +104,7 @@ func (handler *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 
 type ProxyServer struct {
 	isDebugModeEnabled int32
+	isDraining         int32
 }
 
 func (server *ProxyServer) IsDebugModeEnabled() bool {
@@ -140,6 +150,59 @@ func (server *ProxyServer) reloadRunmode() {
 	}
 }
 
+func (server *ProxyServer) IsDraining() bool {
+	return atomic.LoadInt32(&server.isDraining) == 1
+}
+
+func (server *ProxyServer) setIsDraining(val int32, message string) {
+	curVal := atomic.LoadInt32(&server.isDraining)
+	if curVal != val {
+		atomic.StoreInt32(&server.isDraining, val)
+
+		if val > 0 {
+			fmt.Println("Draining started! Detail: " + message)
+		} else {
+			fmt.Println("Draining stopped! Detail: " + message)
+		}
+	}
+}
+
+// parsePodAnnotation returns the value of the annotation from the annotations file of downward api, in which
+// each line is formatted as key="escaped value".
+func parsePodAnnotation(content string, key string) (string, bool) {
+	for _, line := range strings.Split(content, "\n") {
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || k != key {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(v); err == nil {
+			return unquoted, true
+		}
+		return v, true
+	}
+	return "", false
+}
+
+func (server *ProxyServer) reloadDrain() {
+	content, err := os.ReadFile("/etc/podinfo/annotations")
+	if err != nil {
+		if os.IsNotExist(err) {
+			server.setIsDraining(0, "file for annotations not exists")
+			return
+		}
+		// Keep unchanged if error not determined
+		fmt.Println("Unknown error: " + err.Error())
+		return
+	}
+
+	annotationVal, _ := parsePodAnnotation(string(content), polardbxmeta.AnnotationDrain)
+	if annotationVal == "true" {
+		server.setIsDraining(1, fmt.Sprintf("value of annotation %s is '%s'", polardbxmeta.AnnotationDrain, annotationVal))
+	} else {
+		server.setIsDraining(0, fmt.Sprintf("value of annotation %s is '%s'", polardbxmeta.AnnotationDrain, annotationVal))
+	}
+}
+
 func (server *ProxyServer) loopReloadRunmode(ctx context.Context) {
 	for {
 		select {
@@ -147,6 +210,7 @@ func (server *ProxyServer) loopReloadRunmode(ctx context.Context) {
 			return
 		case <-time.After(2 * time.Second):
 			server.reloadRunmode()
+			server.reloadDrain()
 		}
 	}
 }
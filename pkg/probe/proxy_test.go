@@ -0,0 +1,49 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probe
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestParsePodAnnotation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	content := "polardbx/drain=\"true\"\nrunmode=\"debug\"\npolardbx/config=\"{\\\"a\\\":1}\""
+
+	val, ok := parsePodAnnotation(content, "polardbx/drain")
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(val).To(gomega.Equal("true"))
+	val, _ = parsePodAnnotation(content, "polardbx/config")
+	g.Expect(val).To(gomega.Equal(`{"a":1}`))
+	_, ok = parsePodAnnotation(content, "polardbx/lock")
+	g.Expect(ok).To(gomega.BeFalse())
+}
+
+func TestReadinessFailsWhenDraining(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	server := &ProxyServer{}
+	server.setIsDebugModeEnabled(1, "test")
+	handler := &ReadinessHandler{server: server}
+	req := httptest.NewRequest("GET", "/readiness", nil)
+	g.Expect(handler.Handle(req)).To(gomega.Succeed())
+
+	server.setIsDraining(1, "test")
+	g.Expect(handler.Handle(req)).To(gomega.MatchError("pod is draining"))
+}
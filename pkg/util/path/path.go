@@ -22,6 +22,22 @@ func NewPathFromStringSequence(sequence ...string) string {
 	return strings.Join(sequence, "/")
 }
 
+// JoinPath joins elements with exactly one slash between them, leading and trailing slashes of each
+// element are trimmed and empty elements are skipped. Leading slash of the first element is kept.
+func JoinPath(elements ...string) string {
+	tokens := make([]string, 0, len(elements))
+	for _, element := range elements {
+		if token := strings.Trim(element, "/"); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	joined := strings.Join(tokens, "/")
+	if len(elements) > 0 && strings.HasPrefix(elements[0], "/") {
+		return "/" + joined
+	}
+	return joined
+}
+
 // GetBaseNameFromPath gets last non-empty token in the path
 func GetBaseNameFromPath(path string) string {
 	sequence := strings.Split(path, "/")
@@ -32,3 +48,12 @@ func GetBaseNameFromPath(path string) string {
 	}
 	return ""
 }
+
+// GetParentPathFromPath gets the path without its last non-empty token
+func GetParentPathFromPath(path string) string {
+	trimmed := strings.TrimRight(path, "/")
+	if idx := strings.LastIndex(trimmed, "/"); idx >= 0 {
+		return trimmed[:idx]
+	}
+	return ""
+}
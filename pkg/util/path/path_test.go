@@ -0,0 +1,40 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package path
+
+import "testing"
+
+func TestJoinPath(t *testing.T) {
+	testCases := []struct {
+		elements []string
+		expected string
+	}{
+		{elements: []string{"backup/root", "fullbackup", "xs.xbstream"}, expected: "backup/root/fullbackup/xs.xbstream"},
+		{elements: []string{"backup/root/", "fullbackup", "xs.xbstream"}, expected: "backup/root/fullbackup/xs.xbstream"},
+		{elements: []string{"backup/root", "/fullbackup", "xs.xbstream"}, expected: "backup/root/fullbackup/xs.xbstream"},
+		{elements: []string{"backup/root//", "//fullbackup/", "/xs.xbstream"}, expected: "backup/root/fullbackup/xs.xbstream"},
+		{elements: []string{"/backup/root", "fullbackup"}, expected: "/backup/root/fullbackup"},
+		{elements: []string{"", "fullbackup", "xs"}, expected: "fullbackup/xs"},
+		{elements: []string{"backup", "", "/", "xs"}, expected: "backup/xs"},
+		{elements: []string{}, expected: ""},
+	}
+	for _, tc := range testCases {
+		if actual := JoinPath(tc.elements...); actual != tc.expected {
+			t.Errorf("JoinPath(%q) = %q, expected %q", tc.elements, actual, tc.expected)
+		}
+	}
+}
@@ -23,11 +23,15 @@ import (
 	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
 	"github.com/alibaba/polardbx-operator/pkg/hpfs/filestream"
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/config"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
 	"github.com/alibaba/polardbx-operator/pkg/webhook/extension"
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strconv"
@@ -63,6 +67,61 @@ func (v *Validator) ValidateCreate(ctx context.Context, obj runtime.Object) erro
 	var storageProvider polardbx.BackupStorageProvider
 	if pxcBackup, ok := obj.(*v1.PolarDBXBackup); ok {
 		storageProvider = pxcBackup.Spec.StorageProvider
+		if pxcBackup.Spec.XStoreSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(pxcBackup.Spec.XStoreSelector); err != nil {
+				return field.Invalid(field.NewPath("spec", "xstoreSelector"), pxcBackup.Spec.XStoreSelector, err.Error())
+			}
+		}
+		if err := polardbx.ValidateBinlogExcludePatterns(pxcBackup.Spec.BinlogExcludePatterns); err != nil {
+			return field.Invalid(field.NewPath("spec", "binlogExcludePatterns"), pxcBackup.Spec.BinlogExcludePatterns, err.Error())
+		}
+		if err := polardbx.ValidateDatabaseFilter(pxcBackup.Spec.IncludeDatabases, pxcBackup.Spec.ExcludeDatabases); err != nil {
+			return field.Invalid(field.NewPath("spec", "excludeDatabases"), pxcBackup.Spec.ExcludeDatabases, err.Error())
+		}
+		if err := polardbx.ValidateStorageProviderOverrides(pxcBackup.Spec.StorageProviderOverrides); err != nil {
+			return field.Invalid(field.NewPath("spec", "storageProviderOverrides"), pxcBackup.Spec.StorageProviderOverrides, err.Error())
+		}
+		for i, override := range pxcBackup.Spec.StorageProviderOverrides {
+			if _, err := polardbx.NewBackupStorageFilestreamAction(override.StorageProvider.StorageName); err != nil {
+				return field.Invalid(field.NewPath("spec", "storageProviderOverrides").Index(i).Child("storageProvider", "storageName"),
+					override.StorageProvider.StorageName, "unsupported storage")
+			}
+		}
+		// full backups in different sinks can not be packed into a single archive
+		if pxcBackup.Spec.ConsolidateFullBackups && len(pxcBackup.Spec.StorageProviderOverrides) > 0 {
+			return field.Invalid(field.NewPath("spec", "consolidateFullBackups"), pxcBackup.Spec.ConsolidateFullBackups,
+				"consolidating full backups is incompatible with overrides of storage provider")
+		}
+		cluster := &v1.PolarDBXCluster{}
+		clusterErr := v.Get(ctx, types.NamespacedName{Namespace: pxcBackup.Namespace, Name: pxcBackup.Spec.Cluster.Name}, cluster)
+		// storage provider and retention time will be filled by backup class, which falls back to the class
+		// labeled on cluster
+		className := pxcBackup.Spec.BackupClass
+		if className == "" && clusterErr == nil {
+			className = cluster.Labels[polardbxmeta.LabelBackupClass]
+		}
+		if className != "" {
+			class := v.configLoader().Backup().GetBackupClass(className)
+			if class == nil {
+				return field.NotFound(field.NewPath("spec", "backupClass"), className)
+			}
+			retentionTime := pxcBackup.Spec.RetentionTime
+			if err := class.FillBackup(&storageProvider, &retentionTime); err != nil {
+				return field.Invalid(field.NewPath("spec", "backupClass"), className,
+					"invalid retention time of backup class: "+err.Error())
+			}
+		}
+		if storageProvider.StorageName == "" && storageProvider.Sink == "" && clusterErr == nil {
+			// storage provider will be filled by sink policy of operator
+			if defaultProvider := v.configLoader().Backup().DefaultStorageProvider(cluster.Namespace, cluster.Labels); defaultProvider != nil {
+				storageProvider = *defaultProvider
+			}
+		}
+		// archive is extracted by byte range, which is not supported by objects split on upload
+		if pxcBackup.Spec.ConsolidateFullBackups && storageProvider.MaxObjectSize != "" {
+			return field.Invalid(field.NewPath("spec", "consolidateFullBackups"), pxcBackup.Spec.ConsolidateFullBackups,
+				"consolidating full backups is incompatible with max object size of storage provider")
+		}
 	}
 	if pxcBinlogBackup, ok := obj.(*v1.PolarDBXBackupBinlog); ok {
 		storageProvider = pxcBinlogBackup.Spec.StorageProvider
@@ -77,11 +136,23 @@ func (v *Validator) ValidateCreate(ctx context.Context, obj runtime.Object) erro
 		return field.Required(field.NewPath("spec", "storageProvider", "sink"),
 			"sink must be provided")
 	}
+	if _, err := storageProvider.GetUploadPartSize(); err != nil {
+		return field.Invalid(field.NewPath("spec", "storageProvider", "uploadPartSize"),
+			storageProvider.UploadPartSize, err.Error())
+	}
+	if err := storageProvider.ValidateServerSideEncryption(); err != nil {
+		return field.Invalid(field.NewPath("spec", "storageProvider", "serverSideEncryption"),
+			storageProvider.ServerSideEncryption, err.Error())
+	}
 	filestreamAction, err := polardbx.NewBackupStorageFilestreamAction(storageProvider.StorageName)
 	if err != nil {
 		return field.Invalid(field.NewPath("spec", "storageProvider", "storageName"),
 			storageProvider.StorageName, "unsupported storage")
 	}
+	if _, err := storageProvider.GetMaxObjectSize(); err != nil {
+		return field.Invalid(field.NewPath("spec", "storageProvider", "maxObjectSize"),
+			storageProvider.MaxObjectSize, err.Error())
+	}
 
 	// validate whether storage is available
 	fsClient, err := v.getFilestreamClient()
@@ -94,10 +165,13 @@ func (v *Validator) ValidateCreate(ctx context.Context, obj runtime.Object) erro
 		RequestId: uuid.New().String(),
 		Filename:  magicString,
 	}
+	// probe with server-side encryption as well, which fails if the kms key is unavailable
+	actionMetadata.SSEAlgorithm, actionMetadata.SSEKMSKeyId = storageProvider.GetServerSideEncryption()
+	actionMetadata.EndpointType = string(storageProvider.EndpointType)
 	sentBytes, err := fsClient.Upload(strings.NewReader(magicString), actionMetadata)
 	if err != nil || sentBytes == 0 {
 		return field.Invalid(field.NewPath("spec", "storageProvider"), storageProvider,
-			"invalid storage, please check configuration of both backup and hpfs")
+			"invalid storage, please check configuration of both backup and hpfs, including proxy of sink")
 	}
 
 	return nil
@@ -114,7 +188,12 @@ func (v *Validator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.O
 	if oldBackup.Spec.Cluster != newBackup.Spec.Cluster {
 		return field.Forbidden(field.NewPath("spec", "cluster"), "immutable field")
 	}
-	if oldBackup.Spec.StorageProvider != newBackup.Spec.StorageProvider {
+	if !equality.Semantic.DeepEqual(oldBackup.Spec.XStoreSelector, newBackup.Spec.XStoreSelector) {
+		return field.Forbidden(field.NewPath("spec", "xstoreSelector"), "immutable field")
+	}
+	// storage provider left unset is allowed to be filled once by sink policy of operator
+	oldProviderUnset := equality.Semantic.DeepEqual(oldBackup.Spec.StorageProvider, polardbx.BackupStorageProvider{})
+	if !oldProviderUnset && !equality.Semantic.DeepEqual(oldBackup.Spec.StorageProvider, newBackup.Spec.StorageProvider) {
 		return field.Forbidden(field.NewPath("spec", "storageProvider"), "immutable field")
 	}
 	return nil
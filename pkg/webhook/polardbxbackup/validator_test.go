@@ -0,0 +1,79 @@
+package polardbxbackup
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newPolarDBXBackup(storageProvider polardbx.BackupStorageProvider) *v1.PolarDBXBackup {
+	return &v1.PolarDBXBackup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup"},
+		Spec: v1.PolarDBXBackupSpec{
+			Cluster:         v1.PolarDBXClusterReference{Name: "pxc"},
+			StorageProvider: storageProvider,
+		},
+	}
+}
+
+// newEncryptedStorageProvider allocates server-side encryption on each call.
+func newEncryptedStorageProvider(sink string) polardbx.BackupStorageProvider {
+	return polardbx.BackupStorageProvider{
+		StorageName: polardbx.OSS,
+		Sink:        sink,
+		ServerSideEncryption: &polardbx.ServerSideEncryption{
+			Algorithm: polardbx.SSEAlgorithmKMS,
+			KMSKeyId:  "key",
+		},
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	v := NewPolarDBXBackupValidator(nil, logr.Discard(), nil)
+
+	testCases := map[string]struct {
+		oldBackup, newBackup *v1.PolarDBXBackup
+		errMsg               string
+	}{
+		"storage provider unchanged": {
+			oldBackup: newPolarDBXBackup(polardbx.BackupStorageProvider{StorageName: polardbx.OSS, Sink: "default"}),
+			newBackup: newPolarDBXBackup(polardbx.BackupStorageProvider{StorageName: polardbx.OSS, Sink: "default"}),
+		},
+		"storage provider with server-side encryption unchanged": {
+			oldBackup: newPolarDBXBackup(newEncryptedStorageProvider("default")),
+			newBackup: newPolarDBXBackup(newEncryptedStorageProvider("default")),
+		},
+		"storage provider filled by sink policy": {
+			oldBackup: newPolarDBXBackup(polardbx.BackupStorageProvider{}),
+			newBackup: newPolarDBXBackup(newEncryptedStorageProvider("default")),
+		},
+		"storage provider changed": {
+			oldBackup: newPolarDBXBackup(newEncryptedStorageProvider("default")),
+			newBackup: newPolarDBXBackup(newEncryptedStorageProvider("other")),
+			errMsg:    "spec.storageProvider: Forbidden",
+		},
+		"server-side encryption changed": {
+			oldBackup: newPolarDBXBackup(newEncryptedStorageProvider("default")),
+			newBackup: func() *v1.PolarDBXBackup {
+				backup := newPolarDBXBackup(newEncryptedStorageProvider("default"))
+				backup.Spec.StorageProvider.ServerSideEncryption.KMSKeyId = "other-key"
+				return backup
+			}(),
+			errMsg: "spec.storageProvider: Forbidden",
+		},
+	}
+	for name, tc := range testCases {
+		err := v.ValidateUpdate(context.Background(), tc.oldBackup, tc.newBackup)
+		if tc.errMsg == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+		if tc.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tc.errMsg)) {
+			t.Errorf("%s: expect error containing %q, actual %v", name, tc.errMsg, err)
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/alibaba/polardbx-operator/pkg/operator/v1/config"
 	"github.com/alibaba/polardbx-operator/pkg/webhook/polardbxbackup"
 	backupbinlog "github.com/alibaba/polardbx-operator/pkg/webhook/polardbxbackup/binlog"
+	"github.com/alibaba/polardbx-operator/pkg/webhook/xstorebackup"
 	"net/http"
 
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -62,5 +63,9 @@ func SetupWebhooks(ctx context.Context, mgr ctrl.Manager, configPath string, con
 		return err
 	}
 
+	if err := xstorebackup.SetupWebhooks(ctx, mgr, ApiPath, configLoader); err != nil {
+		return err
+	}
+
 	return nil
 }
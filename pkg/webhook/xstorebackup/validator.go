@@ -0,0 +1,178 @@
+/*
+Copyright 2021 Alibaba Group Holding Limited.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xstorebackup
+
+import (
+	"context"
+	v1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/alibaba/polardbx-operator/pkg/operator/v1/config"
+	polardbxmeta "github.com/alibaba/polardbx-operator/pkg/operator/v1/polardbx/meta"
+	"github.com/alibaba/polardbx-operator/pkg/webhook/extension"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+type Validator struct {
+	client.Reader
+	logr.Logger
+	configLoader func() config.Config
+}
+
+func supportedStorageNames() []string {
+	names := make([]string, 0, len(polardbx.SupportedBackupStorages))
+	for _, storage := range polardbx.SupportedBackupStorages {
+		names = append(names, string(storage))
+	}
+	return names
+}
+
+func (v *Validator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	xstoreBackup, ok := obj.(*v1.XStoreBackup)
+	if !ok {
+		return nil
+	}
+	if xstoreBackup.Spec.RetentionTime.Duration < 0 {
+		return field.Invalid(field.NewPath("spec", "retentionTime"), xstoreBackup.Spec.RetentionTime.Duration.String(),
+			"retention time must not be negative")
+	}
+	if gracePeriod := xstoreBackup.Spec.FinalizeGracePeriod; gracePeriod != nil && gracePeriod.Duration < 0 {
+		return field.Invalid(field.NewPath("spec", "finalizeGracePeriod"), gracePeriod.Duration.String(),
+			"finalize grace period must not be negative")
+	}
+	if err := polardbx.ValidateBinlogExcludePatterns(xstoreBackup.Spec.BinlogExcludePatterns); err != nil {
+		return field.Invalid(field.NewPath("spec", "binlogExcludePatterns"), xstoreBackup.Spec.BinlogExcludePatterns,
+			err.Error())
+	}
+	if err := polardbx.ValidateChecksumTables(xstoreBackup.Spec.ChecksumTables); err != nil {
+		return field.Invalid(field.NewPath("spec", "checksumTables"), xstoreBackup.Spec.ChecksumTables, err.Error())
+	}
+	if err := polardbx.ValidateDatabaseFilter(xstoreBackup.Spec.IncludeDatabases, xstoreBackup.Spec.ExcludeDatabases); err != nil {
+		return field.Invalid(field.NewPath("spec", "excludeDatabases"), xstoreBackup.Spec.ExcludeDatabases, err.Error())
+	}
+
+	storageProvider := xstoreBackup.Spec.StorageProvider
+	if className := xstoreBackup.Spec.BackupClass; className != "" {
+		// storage provider and retention time will be filled by backup class
+		class := v.configLoader().Backup().GetBackupClass(className)
+		if class == nil {
+			return field.NotFound(field.NewPath("spec", "backupClass"), className)
+		}
+		retentionTime := xstoreBackup.Spec.RetentionTime
+		if err := class.FillBackup(&storageProvider, &retentionTime); err != nil {
+			return field.Invalid(field.NewPath("spec", "backupClass"), className,
+				"invalid retention time of backup class: "+err.Error())
+		}
+	}
+	if storageProvider.StorageName == "" && storageProvider.Sink == "" {
+		// storage provider will be filled by sink policy of operator
+		xstore := &v1.XStore{}
+		err := v.Get(ctx, types.NamespacedName{Namespace: xstoreBackup.Namespace, Name: xstoreBackup.Spec.XStore.Name}, xstore)
+		if err == nil {
+			if defaultProvider := v.configLoader().Backup().DefaultStorageProvider(xstore.Namespace, xstore.Labels); defaultProvider != nil {
+				storageProvider = *defaultProvider
+			}
+		}
+	}
+
+	return validateStorageProvider(storageProvider)
+}
+
+// validateStorageProvider validates the storage provider which files of backup are uploaded by.
+func validateStorageProvider(storageProvider polardbx.BackupStorageProvider) error {
+	if storageProvider.StorageName == "" {
+		return field.Required(field.NewPath("spec", "storageProvider", "storageName"),
+			"storage name must be provided, supported storages: "+strings.Join(supportedStorageNames(), ", "))
+	}
+	if _, err := polardbx.NewBackupStorageFilestreamAction(storageProvider.StorageName); err != nil {
+		return field.NotSupported(field.NewPath("spec", "storageProvider", "storageName"),
+			storageProvider.StorageName, supportedStorageNames())
+	}
+	if storageProvider.Sink == "" {
+		return field.Required(field.NewPath("spec", "storageProvider", "sink"),
+			"sink must be provided")
+	}
+	if _, err := storageProvider.GetUploadPartSize(); err != nil {
+		return field.Invalid(field.NewPath("spec", "storageProvider", "uploadPartSize"),
+			storageProvider.UploadPartSize, err.Error())
+	}
+	if err := storageProvider.ValidateServerSideEncryption(); err != nil {
+		return field.Invalid(field.NewPath("spec", "storageProvider", "serverSideEncryption"),
+			storageProvider.ServerSideEncryption, err.Error())
+	}
+	if _, err := storageProvider.GetMaxObjectSize(); err != nil {
+		return field.Invalid(field.NewPath("spec", "storageProvider", "maxObjectSize"),
+			storageProvider.MaxObjectSize, err.Error())
+	}
+	return nil
+}
+
+func (v *Validator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	oldBackup, ok := oldObj.(*v1.XStoreBackup)
+	if !ok {
+		return nil
+	}
+	newBackup := newObj.(*v1.XStoreBackup)
+	// full backup and binlogs are processed alike by the job context saved once backup started
+	if oldBackup.Status.StartTime != nil {
+		if oldBackup.Spec.Compression != newBackup.Spec.Compression {
+			return field.Forbidden(field.NewPath("spec", "compression"),
+				"compression can not be changed once backup started")
+		}
+		if !reflect.DeepEqual(oldBackup.Spec.Encryption, newBackup.Spec.Encryption) {
+			return field.Forbidden(field.NewPath("spec", "encryption"),
+				"encryption can not be changed once backup started")
+		}
+	}
+	if equality.Semantic.DeepEqual(oldBackup.Spec.StorageProvider, newBackup.Spec.StorageProvider) {
+		return nil
+	}
+	// sink left unset is allowed to be filled once by sink policy of operator, and sink set is allowed to be
+	// re-pointed until uploads started
+	oldSink, newSink := oldBackup.Spec.StorageProvider.Sink, newBackup.Spec.StorageProvider.Sink
+	if oldSink != "" && oldSink != newSink && newSink != "" {
+		sinkPath := field.NewPath("spec", "storageProvider", "sink")
+		if oldBackup.Labels[polardbxmeta.LabelTopBackup] != "" {
+			return field.Forbidden(sinkPath, "sink of xstore backup follows the polardbx backup "+
+				oldBackup.Labels[polardbxmeta.LabelTopBackup])
+		}
+		if oldBackup.Status.Phase != v1.XStoreBackupNew {
+			return field.Forbidden(sinkPath, "sink can not be changed once uploads started, phase: "+
+				string(oldBackup.Status.Phase))
+		}
+	}
+	// storage provider changed is validated as on create, rather than failing the job
+	return validateStorageProvider(newBackup.Spec.StorageProvider)
+}
+
+func (v *Validator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func NewXStoreBackupValidator(r client.Reader, logger logr.Logger, configLoader func() config.Config) extension.CustomValidator {
+	return &Validator{
+		Reader:       r,
+		Logger:       logger,
+		configLoader: configLoader,
+	}
+}
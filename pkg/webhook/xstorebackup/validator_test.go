@@ -0,0 +1,230 @@
+package xstorebackup
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/alibaba/polardbx-operator/api/v1"
+	"github.com/alibaba/polardbx-operator/api/v1/polardbx"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// emptyReader finds no object.
+type emptyReader struct{}
+
+func (r emptyReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return errors.NewNotFound(schema.GroupResource{}, key.Name)
+}
+
+func (r emptyReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return nil
+}
+
+func newXStoreBackup(storageProvider polardbx.BackupStorageProvider, retention time.Duration) *v1.XStoreBackup {
+	return &v1.XStoreBackup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup"},
+		Spec: v1.XStoreBackupSpec{
+			XStore:          v1.XStoreReference{Name: "xstore"},
+			RetentionTime:   metav1.Duration{Duration: retention},
+			StorageProvider: storageProvider,
+		},
+	}
+}
+
+func TestValidateCreate(t *testing.T) {
+	v := NewXStoreBackupValidator(emptyReader{}, logr.Discard(), nil)
+
+	testCases := map[string]struct {
+		backup *v1.XStoreBackup
+		errMsg string
+	}{
+		"valid": {
+			backup: newXStoreBackup(polardbx.BackupStorageProvider{StorageName: polardbx.OSS, Sink: "default"}, time.Hour),
+		},
+		"unsupported storage": {
+			backup: newXStoreBackup(polardbx.BackupStorageProvider{StorageName: "nas", Sink: "default"}, 0),
+			errMsg: `supported values: "oss", "sftp", "s3", "azure", "gcs"`,
+		},
+		"empty storage provider": {
+			backup: newXStoreBackup(polardbx.BackupStorageProvider{}, 0),
+			errMsg: "storage name must be provided",
+		},
+		"empty sink": {
+			backup: newXStoreBackup(polardbx.BackupStorageProvider{StorageName: polardbx.SFTP}, 0),
+			errMsg: "sink must be provided",
+		},
+		"invalid upload part size": {
+			backup: newXStoreBackup(polardbx.BackupStorageProvider{StorageName: polardbx.OSS, Sink: "default",
+				UploadConcurrency: 4, UploadPartSize: "64M1"}, 0),
+			errMsg: "uploadPartSize",
+		},
+		"max object size exceeds storage limit": {
+			backup: newXStoreBackup(polardbx.BackupStorageProvider{StorageName: polardbx.MINIO, Sink: "default",
+				MaxObjectSize: "6Ti"}, 0),
+			errMsg: "maxObjectSize",
+		},
+		"valid max object size": {
+			backup: newXStoreBackup(polardbx.BackupStorageProvider{StorageName: polardbx.MINIO, Sink: "default",
+				MaxObjectSize: "5Gi"}, 0),
+		},
+		"negative retention": {
+			backup: newXStoreBackup(polardbx.BackupStorageProvider{StorageName: polardbx.OSS, Sink: "default"}, -time.Hour),
+			errMsg: "retention time must not be negative",
+		},
+		"negative finalize grace period": {
+			backup: func() *v1.XStoreBackup {
+				backup := newXStoreBackup(polardbx.BackupStorageProvider{StorageName: polardbx.OSS, Sink: "default"}, 0)
+				backup.Spec.FinalizeGracePeriod = &metav1.Duration{Duration: -time.Minute}
+				return backup
+			}(),
+			errMsg: "finalize grace period must not be negative",
+		},
+		"invalid binlog exclude pattern": {
+			backup: func() *v1.XStoreBackup {
+				backup := newXStoreBackup(polardbx.BackupStorageProvider{StorageName: polardbx.OSS, Sink: "default"}, 0)
+				backup.Spec.BinlogExcludePatterns = []string{"mysql_bin.0001*", "mysql_bin.[0-9"}
+				return backup
+			}(),
+			errMsg: "invalid binlog exclude pattern: mysql_bin.[0-9",
+		},
+		"invalid checksum table": {
+			backup: func() *v1.XStoreBackup {
+				backup := newXStoreBackup(polardbx.BackupStorageProvider{StorageName: polardbx.OSS, Sink: "default"}, 0)
+				backup.Spec.ChecksumTables = []string{"db.t1", "t2"}
+				return backup
+			}(),
+			errMsg: "invalid checksum table, expect schema.table: t2",
+		},
+	}
+	for name, tc := range testCases {
+		err := v.ValidateCreate(context.Background(), tc.backup)
+		if tc.errMsg == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+		if tc.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tc.errMsg)) {
+			t.Errorf("%s: expect error containing %q, actual %v", name, tc.errMsg, err)
+		}
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	v := NewXStoreBackupValidator(emptyReader{}, logr.Discard(), nil)
+	withSink := func(backup *v1.XStoreBackup, sink string) *v1.XStoreBackup {
+		backup = backup.DeepCopy()
+		backup.Spec.StorageProvider.Sink = sink
+		return backup
+	}
+	inPhase := func(phase v1.XStoreBackupPhase) *v1.XStoreBackup {
+		backup := newXStoreBackup(polardbx.BackupStorageProvider{StorageName: polardbx.OSS, Sink: "default"}, 0)
+		backup.Status.Phase = phase
+		return backup
+	}
+	started := func(phase v1.XStoreBackupPhase) *v1.XStoreBackup {
+		backup := inPhase(phase)
+		backup.Status.StartTime = &metav1.Time{Time: time.Unix(1700000000, 0)}
+		return backup
+	}
+	inPolarDBXBackup := inPhase(v1.XStoreBackupNew)
+	inPolarDBXBackup.Labels = map[string]string{"polardbx/top-backup": "pxc-backup"}
+	withCodec := func(backup *v1.XStoreBackup, compression polardbx.BackupCompression, secretName string) *v1.XStoreBackup {
+		backup = backup.DeepCopy()
+		backup.Spec.Compression = compression
+		if secretName != "" {
+			backup.Spec.Encryption = &polardbx.BackupEncryption{SecretName: secretName}
+		}
+		return backup
+	}
+
+	testCases := map[string]struct {
+		oldBackup, newBackup *v1.XStoreBackup
+		errMsg               string
+	}{
+		"sink unchanged": {
+			oldBackup: inPhase(v1.XStoreFullBackuping),
+			newBackup: inPhase(v1.XStoreFullBackuping),
+		},
+		"sink filled by sink policy": {
+			oldBackup: withSink(inPhase(v1.XStoreBackupNew), ""),
+			newBackup: inPhase(v1.XStoreBackupNew),
+		},
+		"sink re-pointed before upload": {
+			oldBackup: inPhase(v1.XStoreBackupNew),
+			newBackup: withSink(inPhase(v1.XStoreBackupNew), "other"),
+		},
+		"sink re-pointed after upload started": {
+			oldBackup: inPhase(v1.XStoreFullBackuping),
+			newBackup: withSink(inPhase(v1.XStoreFullBackuping), "other"),
+			errMsg:    "sink can not be changed once uploads started",
+		},
+		"sink removed": {
+			oldBackup: inPhase(v1.XStoreBackupNew),
+			newBackup: withSink(inPhase(v1.XStoreBackupNew), ""),
+			errMsg:    "sink must be provided",
+		},
+		"storage provider re-pointed to unsupported storage": {
+			oldBackup: inPhase(v1.XStoreBackupNew),
+			newBackup: func() *v1.XStoreBackup {
+				backup := withSink(inPhase(v1.XStoreBackupNew), "other")
+				backup.Spec.StorageProvider.StorageName = "nas"
+				return backup
+			}(),
+			errMsg: `supported values: "oss", "sftp", "s3", "azure", "gcs"`,
+		},
+		"storage provider re-pointed with invalid upload part size": {
+			oldBackup: inPhase(v1.XStoreBackupNew),
+			newBackup: func() *v1.XStoreBackup {
+				backup := withSink(inPhase(v1.XStoreBackupNew), "other")
+				backup.Spec.StorageProvider.UploadConcurrency = 4
+				backup.Spec.StorageProvider.UploadPartSize = "64M1"
+				return backup
+			}(),
+			errMsg: "uploadPartSize",
+		},
+		"storage provider filled with invalid server-side encryption": {
+			oldBackup: withSink(inPhase(v1.XStoreBackupNew), ""),
+			newBackup: func() *v1.XStoreBackup {
+				backup := inPhase(v1.XStoreBackupNew)
+				backup.Spec.StorageProvider.ServerSideEncryption = &polardbx.ServerSideEncryption{
+					Algorithm: polardbx.SSEAlgorithmAES256,
+					KMSKeyId:  "key",
+				}
+				return backup
+			}(),
+			errMsg: "serverSideEncryption",
+		},
+		"sink re-pointed in polardbx backup": {
+			oldBackup: inPolarDBXBackup,
+			newBackup: withSink(inPolarDBXBackup, "other"),
+			errMsg:    "sink of xstore backup follows the polardbx backup pxc-backup",
+		},
+		"codec set before backup started": {
+			oldBackup: inPhase(v1.XStoreBackupNew),
+			newBackup: withCodec(inPhase(v1.XStoreBackupNew), polardbx.BackupCompressionGzip, "key"),
+		},
+		"compression changed after backup started": {
+			oldBackup: started(v1.XStoreBackupNew),
+			newBackup: withCodec(started(v1.XStoreBackupNew), polardbx.BackupCompressionGzip, ""),
+			errMsg:    "compression can not be changed once backup started",
+		},
+		"encryption changed after backup started": {
+			oldBackup: withCodec(started(v1.XStoreFullBackuping), "", "key"),
+			newBackup: withCodec(started(v1.XStoreFullBackuping), "", "other-key"),
+			errMsg:    "encryption can not be changed once backup started",
+		},
+	}
+	for name, tc := range testCases {
+		err := v.ValidateUpdate(context.Background(), tc.oldBackup, tc.newBackup)
+		if tc.errMsg == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+		if tc.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tc.errMsg)) {
+			t.Errorf("%s: expect error containing %q, actual %v", name, tc.errMsg, err)
+		}
+	}
+}
@@ -28,6 +28,8 @@ from core.convention import *
 from core.engine import new_engine
 from core.log import LogFactory
 from core.backup_restore.storage.filestream_client import FileStreamClient, BackupStorage
+from core.backup_restore.utils import sha256_of_file, encrypt_keyring, ENV_KEYRING_SOURCE_KEY, \
+    ENV_KEYRING_TARGET_KEY, encode_commands, open_pipeline, wait_pipeline
 from .common import check_parameters_exist, get_parameter_value
 
 
@@ -39,7 +41,9 @@ def backup_group():
 @click.command(name='start')
 @click.option('--backup_context', required=True, type=str)
 @click.option('-j', '--job_name', required=True, type=str)
-def start_backup(backup_context, job_name):
+@click.option('--include_database', multiple=True, type=str)
+@click.option('--exclude_database', multiple=True, type=str)
+def start_backup(backup_context, job_name, include_database, exclude_database):
     context = Context()
     logger = LogFactory.get_logger("fullbackup.log")
     with open(backup_context, 'r') as f:
@@ -49,6 +53,21 @@ def start_backup(backup_context, job_name):
         sink = params["sink"]
         keyring_path = params["keyringPath"]
         keyring_file_path = params["keyringFilePath"]
+        keyring_checksum_path = params.get("keyringChecksumPath", "")
+        chunk_size = params.get("chunkSize", 0)
+        chunk_manifest_path = params.get("chunkManifestPath", "")
+        upload_concurrency = params.get("uploadConcurrency", 1)
+        upload_part_size = params.get("uploadPartSize", 0)
+        max_object_size = params.get("maxObjectSize", 0)
+        tags = params.get("tags", "")
+        sse_algorithm = params.get("sseAlgorithm", "")
+        sse_kms_key_id = params.get("sseKMSKeyId", "")
+        endpoint_type = params.get("endpointType", "")
+        # full backup is processed alike binlogs, or compressed by xtrabackup if compression is not specified
+        compression = params.get("compression", "")
+        encryption = params.get("encryption", "")
+        # result and index files are read by operator from the same directory of data volume
+        job_result_dir = params.get("jobResultDir", "") or context.volume_path(VOLUME_DATA, 'tmp')
     sock_file = context.volume_path(VOLUME_DATA, 'run', 'mysql.sock')
     backup_dir = context.volume_path(VOLUME_DATA, 'backup')
 
@@ -65,53 +84,123 @@ def start_backup(backup_context, job_name):
                           "--socket=" + sock_file,
                           "--slave-info",
                           "--backup", "--lock-ddl",
-                          "--compress",
                           "--xtrabackup-plugin-dir=" + context.xtrabackup_plugin]
         elif context.is_xcluster57():
             backup_cmd = [context.xtrabackup,
                           "--stream=xbstream",
                           "--socket=" + sock_file,
-                          "--compress",
                           backup_dir]
 
+        backup_cmd[1:1] = database_filter_args(include_database, exclude_database)
+        if len(compression) == 0:
+            backup_cmd[1:1] = ["--compress"]
+        encode_cmds = encode_commands(compression, encryption)
         logger.info("backup_cmd: %s " % backup_cmd)
 
         stderr_path = backup_dir + '/fullbackup-stderr.out'
         upload_stderr_path = backup_dir + '/upload.out'
         stderr_outfile = open(stderr_path, 'w+')
         upload_stderr_outfile = open(upload_stderr_path, 'w+')
-        filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink)
+        filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink,
+                                             upload_concurrency=upload_concurrency, upload_part_size=upload_part_size,
+                                             tags=tags, sse_algorithm=sse_algorithm,
+                                             sse_kms_key_id=sse_kms_key_id, max_object_size=max_object_size,
+                                             endpoint_type=endpoint_type)
 
+        chunks = None
         with subprocess.Popen(backup_cmd, bufsize=8192, stdout=subprocess.PIPE, stderr=stderr_outfile,
                               close_fds=True) as pipe:
-            filestream_client.upload_from_stdin(remote_path=fullbackup_path, stdin=pipe.stdout,
-                                                stderr=upload_stderr_outfile, logger=logger)
+            # stream of xtrabackup is compressed and encrypted by pipeline before uploaded
+            encoders = open_pipeline(encode_cmds, pipe.stdout)
+            stream = encoders[-1].stdout if encoders else pipe.stdout
+            if chunk_size > 0:
+                chunks = filestream_client.upload_from_stdin_in_chunks(remote_path=fullbackup_path, stdin=stream,
+                                                                       chunk_size=chunk_size,
+                                                                       stderr=upload_stderr_outfile, logger=logger)
+            else:
+                filestream_client.upload_from_stdin(remote_path=fullbackup_path, stdin=stream,
+                                                    stderr=upload_stderr_outfile, logger=logger)
             pipe.stdout.close()
+            wait_pipeline(encoders)
             backup_return_code = pipe.wait()
             if backup_return_code:
                 raise Exception("backup process exited normally, return code: %s" % backup_return_code)
 
-        get_binlog_commit_index(job_name, stderr_path, logger)
+        if chunks is not None:
+            chunk_manifest_local = backup_dir + '/chunks.json'
+            with open(chunk_manifest_local, 'w') as f:
+                json.dump({"chunkSize": chunk_size, "chunks": chunks}, f)
+            filestream_client.upload_from_file(remote=chunk_manifest_path, local=chunk_manifest_local, logger=logger)
+            logger.info("chunk manifest upload finished, chunk count: %d" % len(chunks))
+
+        check_xtrabackup_completed(stderr_path)
+        get_binlog_commit_index(job_result_dir, job_name, stderr_path, logger)
         logger.info("backup upload finished")
 
         section = "mysqld"
         params_to_tde = ['early_plugin_load', 'keyring_file_data']
         if check_parameters_exist(section, params_to_tde):
             keyring_path_local = get_parameter_value(section, "keyring_file_data")
-            filestream_client.upload_from_file(remote=keyring_path, local=keyring_path_local, logger=logger)
+            keyring_upload_local = re_encrypt_keyring(keyring_path_local, backup_dir, logger)
+            filestream_client.upload_from_file(remote=keyring_path, local=keyring_upload_local, logger=logger)
             filestream_client.upload_from_string(remote=keyring_file_path, string=keyring_path_local, logger=logger)
+            if len(keyring_checksum_path) != 0:
+                filestream_client.upload_from_string(remote=keyring_checksum_path,
+                                                     string=sha256_of_file(keyring_upload_local), logger=logger)
             logger.info("keyring upload finished")
 
+        write_backup_result(job_result_dir, job_name, True, "")
     except Exception as e:
         logger.info(e)
+        write_backup_result(job_result_dir, job_name, False, str(e))
 
-        # backup process may exit abnormally, try to check and restart replication on follower
+        # backup process may exit abnormally, try to check and restart replication on follower or learner
         check_and_restart_replication_thread(context, sock_file, logger)
 
         raise e
 
 
-def get_binlog_commit_index(job_name, stderr_path, logger):
+def database_filter_args(include_database, exclude_database):
+    # only part of databases are backed up if filtered, system databases are included by operator
+    args = []
+    if include_database:
+        args.append("--databases=" + " ".join(include_database))
+    if exclude_database:
+        args.append("--databases-exclude=" + " ".join(exclude_database))
+    return args
+
+
+def re_encrypt_keyring(keyring_path_local, backup_dir, logger):
+    """
+    Re-encrypt the keyring with the target key if keyring re-encryption is requested, in which case both keys are
+    passed by envs. Returns the local path of keyring to upload.
+    """
+    if ENV_KEYRING_TARGET_KEY not in os.environ:
+        return keyring_path_local
+    if len(os.environ.get(ENV_KEYRING_SOURCE_KEY, "")) == 0:
+        raise Exception("source key of keyring re-encryption not found in env %s" % ENV_KEYRING_SOURCE_KEY)
+    keyring_encrypted_local = os.path.join(backup_dir, "keyring.enc")
+    encrypt_keyring(keyring_path_local, keyring_encrypted_local, logger=logger)
+    logger.info("keyring re-encrypted with target key")
+    return keyring_encrypted_local
+
+
+def check_xtrabackup_completed(stderr_path):
+    # xtrabackup may exit normally with a partial backup, it prints "completed OK!" only on success
+    with open(stderr_path, 'rb') as file:
+        stderr_text = file.read().decode('utf-8', 'ignore')
+    if 'completed OK!' not in stderr_text:
+        lines = [line for line in stderr_text.splitlines() if line.strip()]
+        raise Exception("xtrabackup not completed OK, last output: %s" % (lines[-1] if lines else ""))
+
+
+def write_backup_result(job_result_dir, job_name, success, reason):
+    # result file is checked by operator along with job completion
+    with open(os.path.join(job_result_dir, job_name + ".result"), mode='w+', encoding='utf-8') as f:
+        json.dump({"success": success, "reason": reason}, f)
+
+
+def get_binlog_commit_index(job_result_dir, job_name, stderr_path, logger):
     # parse stderr to get commit_index
     with open(stderr_path, 'rb') as file:
         stderr_text = file.read().decode('utf-8', 'ignore')
@@ -137,7 +226,7 @@ def get_binlog_commit_index(job_name, stderr_path, logger):
     m = re.search(r"consensus_apply_index:'(\d+)'", binlog_line)
     if m:
         slave_status['CONSENSUS_APPLY_INDEX'] = m.group(1)
-    with open("/data/mysql/tmp/" + job_name + ".idx", mode='w+', encoding='utf-8') as f:
+    with open(os.path.join(job_result_dir, job_name + ".idx"), mode='w+', encoding='utf-8') as f:
         if slave_status['CONSENSUS_APPLY_INDEX'] == "0" or slave_status['CONSENSUS_APPLY_INDEX'] == "1":
             f.write(slave_status['COMMIT_INDEX'])
         else:
@@ -155,8 +244,8 @@ def check_and_restart_replication_thread(context: Context, sock_file, logger):
     connection = pymysql.connect(unix_socket=sock_file, user='root', connect_timeout=1, read_timeout=10)
     with mgr_class(connection, None) as mgr:
         current_node = mgr.current_node()
-        if current_node.role != ConsensusRole.FOLLOWER:
-            logger.info("Current node is not follower: %s, no need to restart replication." % current_node)
+        if current_node.role not in (ConsensusRole.FOLLOWER, ConsensusRole.LEARNER):
+            logger.info("Current node is not follower or learner: %s, no need to restart replication." % current_node)
             return
 
         slave_status = mgr.show_slave_status()
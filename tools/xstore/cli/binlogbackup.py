@@ -23,6 +23,7 @@ from core.log import LogFactory
 from core.convention import *
 from core.backup_restore.xstore_binlog import XStoreBinlog
 from core.backup_restore.storage.filestream_client import FileStreamClient, BackupStorage
+from core.backup_restore.utils import exclude_binlogs, encode_commands, open_pipeline, wait_pipeline
 
 
 @click.group(name="binlogbackup")
@@ -45,6 +46,13 @@ def start_binlogbackup(backup_context,  start_index, gms_label, xstore_name):
         remote_binlog_backup_dir = params["binlogBackupDir"]
         storage_name = params["storageName"]
         sink = params["sink"]
+        tags = params.get("tags", "")
+        sse_algorithm = params.get("sseAlgorithm", "")
+        sse_kms_key_id = params.get("sseKMSKeyId", "")
+        endpoint_type = params.get("endpointType", "")
+        exclude_patterns = params.get("binlogExcludePatterns", [])
+        # binlogs are processed alike full backup, which reads the same context
+        codec = (params.get("compression", ""), params.get("encryption", ""))
 
     logger.info("start binlog backup")
     context = Context()
@@ -54,7 +62,9 @@ def start_binlogbackup(backup_context,  start_index, gms_label, xstore_name):
     backup_dir = context.volume_path(VOLUME_DATA, 'backup')
     local_binlog_backup_dir = os.path.join(backup_dir, "binlogbackup")
 
-    filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink)
+    filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink, tags=tags,
+                                         sse_algorithm=sse_algorithm, sse_kms_key_id=sse_kms_key_id,
+                                         endpoint_type=endpoint_type)
 
     os.makedirs(local_binlog_backup_dir, exist_ok=True)
 
@@ -70,9 +80,15 @@ def start_binlogbackup(backup_context,  start_index, gms_label, xstore_name):
     # 将可上传的binlog上传
     binlog_list = binlog.get_local_binlog(min_binlog_name=min_log_name, max_binglog_name=max_log_name,
                                           left_contain=True, right_contain=False)
-    upload_binlog_info(binlog_list, log_dir, remote_binlog_backup_dir, filestream_client, logger)
+    # 跳过匹配排除规则的binlog，记录下来以便operator展示
+    binlog_list, excluded_binlog_list = exclude_binlogs(binlog_list, exclude_patterns, keep=(max_log_name,))
+    with open(os.path.join(local_binlog_backup_dir, "excluded_binlogs"), 'w') as f:
+        f.write('\n'.join(excluded_binlog_list))
+    if excluded_binlog_list:
+        logger.warning("binlog excluded: %s", excluded_binlog_list)
+    upload_binlog_info(binlog_list, log_dir, remote_binlog_backup_dir, filestream_client, codec, logger)
     truncate_and_upload_binlog_info(context, log_dir, local_binlog_backup_dir, remote_binlog_backup_dir,
-                                    filestream_client, max_log_name, max_log_index, logger)
+                                    filestream_client, max_log_name, max_log_index, codec, logger)
 
     # 记录所有上传的binlog_name_list，用于后续恢复时下载binlog
     uploaded_binlog_list = [log_name for i, (log_name, start_log_index) in enumerate(binlog_list)]
@@ -123,7 +139,7 @@ def get_max_log_from_cp(filestream_client, indexes_path, binlog_backup_dir, xsto
 
 
 def truncate_and_upload_binlog_info(context, log_dir, binlogbackup_dir, binlogbackupdir_path, filestream_client,
-                                    max_log_name, max_log_index, logger):
+                                    max_log_name, max_log_index, codec, logger):
     binlog_file_path = os.path.join(log_dir, max_log_name)
     truncated_log_name = "{}_trunc.{}".format(*max_log_name.split('.'))
     truncate_file_path = os.path.join(binlogbackup_dir, truncated_log_name)
@@ -142,16 +158,35 @@ def truncate_and_upload_binlog_info(context, log_dir, binlogbackup_dir, binlogba
                 f.write(last_event_timestamp)
             filestream_client.upload_from_file(remote=os.path.join(binlogbackupdir_path, "last_event_timestamp"),
                                                local=last_event_timestamp_path, logger=logger)
-    filestream_client.upload_from_file(remote=os.path.join(binlogbackupdir_path, max_log_name),
-                                       local=truncate_file_path, logger=logger)
+    upload_binlog_file(filestream_client, os.path.join(binlogbackupdir_path, max_log_name), truncate_file_path,
+                       codec, logger)
 
 
-def upload_binlog_info(binlog_list, log_dir, binlog_backup_dir_path, filestream_client, logger):
+def upload_binlog_info(binlog_list, log_dir, binlog_backup_dir_path, filestream_client, codec, logger):
     for i, (log_name, start_log_index) in enumerate(binlog_list):
         logger.info("log to upload:%s during binlog backup" % log_name)
         binlog_file_path = os.path.join(log_dir, log_name)
-        filestream_client.upload_from_file(remote=os.path.join(binlog_backup_dir_path, log_name),
-                                           local=binlog_file_path, logger=logger)
+        upload_binlog_file(filestream_client, os.path.join(binlog_backup_dir_path, log_name), binlog_file_path,
+                           codec, logger)
+
+
+def upload_binlog_file(filestream_client, remote, local, codec, logger):
+    # binlog is compressed and encrypted by pipeline while uploaded if required, alike full backup, so that no
+    # encoded copy is left on local disk
+    compression, encryption = codec
+    encode_cmds = encode_commands(compression, encryption)
+    if len(encode_cmds) == 0:
+        filestream_client.upload_from_file(remote=remote, local=local, logger=logger)
+        return
+    logger.info("encode %s by: %s" % (local, " | ".join(" ".join(cmd) for cmd in encode_cmds)))
+    with open(local, 'rb') as f:
+        encoders = open_pipeline(encode_cmds, f)
+        try:
+            filestream_client.upload_from_stdin(remote_path=remote, stdin=encoders[-1].stdout, logger=logger)
+        finally:
+            # encoders receive SIGPIPE if upload stops early
+            encoders[-1].stdout.close()
+        wait_pipeline(encoders)
 
 
 binbackup_group.add_command(start_binlogbackup)
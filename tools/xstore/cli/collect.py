@@ -22,7 +22,7 @@ from core.log import LogFactory
 from core.convention import *
 from core.backup_restore.xstore_binlog import XStoreBinlog
 from core.backup_restore.storage.filestream_client import BackupStorage, FileStreamClient
-from core.backup_restore.utils import check_run_process
+from core.backup_restore.utils import check_run_process, exclude_binlogs
 
 
 @click.group(name="collect")
@@ -30,12 +30,16 @@ def collect_group():
     pass
 
 
-def get_binlog_list(context, start_binlog_name, end_binlog_name):
+def get_binlog_list(context, start_binlog_name, end_binlog_name, exclude_patterns=None, logger=None):
     mysql_port = context.port_access()
     binlog = XStoreBinlog(mysql_port)
     log_dir = context.volume_path(VOLUME_DATA, "log")
     binlog_list = binlog.get_local_binlog(min_binlog_name=start_binlog_name, max_binglog_name=end_binlog_name,
                                           left_contain=True, right_contain=True)
+    # binlog where collect starts or ends is never excluded
+    binlog_list, excluded = exclude_binlogs(binlog_list, exclude_patterns, keep=(start_binlog_name, end_binlog_name))
+    if excluded and logger:
+        logger.warning("binlog excluded: %s", excluded)
     binlog_path_list = []
     for i, (logname, start_log_index) in enumerate(binlog_list):
         binlog_path_list.append(os.path.join(log_dir, logname))
@@ -64,10 +68,10 @@ def seekhb_and_upload(filstream_client, file_path, context, binlog_list, heartbe
 
 
 def collect_and_upload(context, start_binlog_name, start_offset, end_binlog_name, end_offset, binlog_path_list,
-                       file_path, filestream_client, backup_dir, logger):
+                       file_path, filestream_client, backup_dir, logger, local_file_name="collect.evs"):
     collect_local_file = os.path.join(backup_dir, "collect")
     os.makedirs(collect_local_file, exist_ok=True)
-    local_collect_file_path = os.path.join(collect_local_file, "collect.evs")
+    local_collect_file_path = os.path.join(collect_local_file, local_file_name)
     start_binlog = start_binlog_name + ":" + start_offset
     end_binlog = end_binlog_name + ":" + end_offset
     collect_cmd = [context.bb_home, 'txdump',
@@ -78,6 +82,67 @@ def collect_and_upload(context, start_binlog_name, start_offset, end_binlog_name
     check_run_process(collect_cmd, logger=logger)
     filestream_client.upload_from_file(remote=file_path, local=local_collect_file_path, logger=logger)
 
+
+def split_binlog_list(binlog_list, count):
+    """
+    Split binlog files into count contiguous groups in order, trailing groups are empty if there are fewer
+    files than groups. Transactions never span binlog files, so events collected from the groups and
+    concatenated in order are identical to those collected from the whole range.
+    """
+    size, remainder = divmod(len(binlog_list), count)
+    groups, begin = [], 0
+    for i in range(count):
+        end = begin + size + (1 if i < remainder else 0)
+        groups.append(binlog_list[begin:end])
+        begin = end
+    return groups
+
+
+def collect_part_path(file_path, part_index):
+    return "%s.part-%d" % (file_path, part_index)
+
+
+def collect_index_path(file_path):
+    return file_path + ".index"
+
+
+def collect_part_and_upload(context, start_binlog_name, start_offset, end_binlog_name, end_offset, binlog_list,
+                            parallelism, part_index, file_path, filestream_client, backup_dir, logger):
+    """
+    Collect the sub-range of binlog files assigned to part_index and upload it as a part. Part 0 also uploads
+    the index listing all the parts in order, by which the parts are stitched into the collected file.
+    """
+    if part_index == 0:
+        index = {"parts": [collect_part_path(file_path, i) for i in range(parallelism)]}
+        filestream_client.upload_from_string(remote=collect_index_path(file_path), string=json.dumps(index),
+                                             logger=logger)
+
+    group = split_binlog_list(binlog_list, parallelism)[part_index]
+    remote_part_path = collect_part_path(file_path, part_index)
+    if len(group) == 0:
+        # upload an empty part, upload_from_string is not used since it appends a newline
+        empty_local_path = os.path.join(backup_dir, "collect", "collect-%d.evs" % part_index)
+        os.makedirs(os.path.dirname(empty_local_path), exist_ok=True)
+        open(empty_local_path, 'w').close()
+        filestream_client.upload_from_file(remote=remote_part_path, local=empty_local_path, logger=logger)
+        return
+
+    log_dir = context.volume_path(VOLUME_DATA, "log")
+    group_path_list = [os.path.join(log_dir, log_name) for log_name, _ in group]
+    # sub-ranges other than the first and last one cover their binlog files entirely
+    part_start_name, part_start_offset = group[0][0], start_offset if group[0][0] == start_binlog_name else "4"
+    part_end_name = group[-1][0]
+    if part_end_name == end_binlog_name:
+        part_end_offset = end_offset
+    else:
+        part_end_offset = str(os.path.getsize(group_path_list[-1]))
+    logger.info("collect part %d: %s:%s ~ %s:%s", part_index, part_start_name, part_start_offset,
+                part_end_name, part_end_offset)
+    collect_and_upload(context, part_start_name, part_start_offset, part_end_name, part_end_offset, group_path_list,
+                       remote_part_path, filestream_client, backup_dir, logger,
+                       local_file_name="collect-%d.evs" % part_index)
+
+
 @click.command(name="start")
 @click.option('--backup_context', required=True, type=str)
 @click.option('-hb', '--heartbeat_name', required=True, type=str)
@@ -90,23 +155,42 @@ def collect_binlog_index(backup_context, heartbeat_name):
         storage_name = params["storageName"]
         collect_start_index = params["collectStartIndex"]
         collect_end_index = params["collectEndIndex"]
+        parallelism = params.get("collectParallelism", 1)
         sink = params["sink"]
+        tags = params.get("tags", "")
+        sse_algorithm = params.get("sseAlgorithm", "")
+        sse_kms_key_id = params.get("sseKMSKeyId", "")
+        endpoint_type = params.get("endpointType", "")
+        exclude_patterns = params.get("binlogExcludePatterns", [])
 
     backup_dir = context.volume_path(VOLUME_DATA, 'backup')
     if not os.path.exists(backup_dir):
         os.mkdir(backup_dir)
 
-    filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink)
+    filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink, tags=tags,
+                                         sse_algorithm=sse_algorithm, sse_kms_key_id=sse_kms_key_id,
+                                         endpoint_type=endpoint_type)
 
     # collect_*_index has the format like "mysql.bin:000001:"
     start_binlog_name, start_offset = collect_start_index.split(':')
     end_binlog_name, end_offset = collect_end_index.split(':')
 
     # binlog_list only records binlog name, while binlog_path_list contains absolute path for each binlog
-    binlog_list, binlog_path_list = get_binlog_list(context, start_binlog_name, end_binlog_name)
+    binlog_list, binlog_path_list = get_binlog_list(context, start_binlog_name, end_binlog_name, exclude_patterns,
+                                                    logger)
 
     logger.info("start_binlog_name:%s, start_offset:%s, end_binlog_name:%s, end_offset:%s",
                 start_binlog_name, start_offset, end_binlog_name, end_offset)
+    if parallelism > 1:
+        # set by kubernetes for pods of indexed job
+        part_index = int(os.environ["JOB_COMPLETION_INDEX"])
+        part_binlog_list = split_binlog_list(binlog_list, parallelism)[part_index]
+        seekhb_and_upload(filestream_client, collect_file, context, part_binlog_list, heartbeat_name, backup_dir,
+                          logger)
+        collect_part_and_upload(context, start_binlog_name, start_offset, end_binlog_name, end_offset, binlog_list,
+                                parallelism, part_index, collect_file, filestream_client, backup_dir, logger)
+        return
+
     seekhb_and_upload(filestream_client, collect_file, context, binlog_list, heartbeat_name, backup_dir, logger)
 
     collect_and_upload(context, start_binlog_name, start_offset, end_binlog_name, end_offset, binlog_path_list,
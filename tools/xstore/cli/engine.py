@@ -56,6 +56,77 @@ def set_global(key, value):
 engine_group.add_command(set_global)
 
 
+def _quote(identifier):
+    return '`%s`' % identifier.replace('`', '``')
+
+
+def _sample_checksum(cur, schema, name, percent, seed):
+    """
+    checksum the rows sampled by hash of primary key seeded by seed, all columns are hashed if no primary key
+
+    :return: checksum and count of sampled rows, checksum is None if table not found
+    """
+    cur.execute('SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = %s AND TABLE_NAME = %s '
+                'ORDER BY ORDINAL_POSITION', args=(schema, name))
+    columns = [row[0] for row in cur.fetchall()]
+    if not columns:
+        return None, 0
+    cur.execute("SELECT COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = %s "
+                "AND TABLE_NAME = %s AND CONSTRAINT_NAME = 'PRIMARY' ORDER BY ORDINAL_POSITION", args=(schema, name))
+    keys = [row[0] for row in cur.fetchall()] or columns
+    # nulls are told apart from empty strings
+    row_expr = "CONCAT_WS('#', %s)" % ', '.join('ISNULL(%s), %s' % (_quote(c), _quote(c)) for c in columns)
+    key_expr = "CONCAT_WS('#', %%s, %s)" % ', '.join(_quote(c) for c in keys)
+    cur.execute('SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(%s)), 0) FROM %s.%s WHERE CRC32(%s) %%%% 100 < %%s'
+                % (row_expr, _quote(schema), _quote(name), key_expr), args=(seed, percent))
+    count, crc = cur.fetchone()
+    return '%d:%d' % (count, crc), count
+
+
+@click.command(name='checksum')
+@click.option('-t', '--table', required=True, multiple=True)
+@click.option('--sample-percent', type=int, default=0)
+@click.option('--sample-seed', type=int, default=0)
+def checksum(table, sample_percent, sample_seed):
+    with global_mgr.new_connection() as conn:
+        with conn.cursor() as cur:
+            for t in table:
+                schema, name = t.split('.', 1)
+                # sampled checksum is printed along with the count of rows sampled
+                if 0 < sample_percent < 100:
+                    value, count = _sample_checksum(cur, schema, name, sample_percent, sample_seed)
+                    print('%s\t%s\t%d' % (t, 'NULL' if value is None else value, count))
+                    continue
+                cur.execute('CHECKSUM TABLE %s.%s' % (_quote(schema), _quote(name)))
+                row = cur.fetchone()
+                print('%s\t%s' % (t, 'NULL' if row is None or row[1] is None else row[1]))
+
+
+engine_group.add_command(checksum)
+
+
+@click.command(name='health')
+def health():
+    """
+    print health of engine as "healthy\\tuptime", or "unhealthy\\tuptime\\treason" if the engine is recovering from
+    crash, i.e. rolling back the transactions recovered
+    """
+    with global_mgr.new_connection() as conn:
+        with conn.cursor() as cur:
+            cur.execute("SHOW GLOBAL STATUS LIKE 'Uptime'")
+            row = cur.fetchone()
+            uptime = int(row[1]) if row else 0
+            cur.execute("SELECT COUNT(*) FROM information_schema.INNODB_TRX WHERE trx_state = 'ROLLING BACK'")
+            rolling_back = cur.fetchone()[0]
+            if rolling_back > 0:
+                print('unhealthy\t%d\tcrash recovery in progress, %d transactions rolling back' % (uptime, rolling_back))
+                return
+            print('healthy\t%d' % uptime)
+
+
+engine_group.add_command(health)
+
+
 @click.command(name='set_engine_enable')
 @click.option('--enable', is_flag=True)
 @click.option('--disable', is_flag=True)
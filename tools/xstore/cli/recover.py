@@ -46,10 +46,12 @@ def start(restore_context, target_pod, password):
         storage_name = params["storageName"]
         sink = params["sink"]
         pitr_endpoint = params["pitrEndpoint"] if "pitrEndpoint" in params else ""
+        endpoint_type = params.get("endpointType", "")
 
     local_cp_path = os.path.join(RESTORE_TEMP_DIR, "set.cp")
     if len(pitr_endpoint) == 0:
-        filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink)
+        filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink,
+                                             endpoint_type=endpoint_type)
         filestream_client.download_to_file(remote=remote_cp_path, local=local_cp_path, logger=logger)
     else:
         download_url = "/".join([pitr_endpoint, "download", "recovertxs"])
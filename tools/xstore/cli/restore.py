@@ -29,7 +29,8 @@ from core.log import LogFactory
 from core.convention import *
 from core.context.mycnf_renderer import MycnfRenderer
 from core.backup_restore.storage.filestream_client import FileStreamClient, BackupStorage
-from core.backup_restore.utils import check_run_process
+from core.backup_restore.utils import check_run_process, sha256_of_file, decrypt_keyring, decode_commands, \
+    decode_file, open_pipeline, wait_pipeline, COMPRESSION_QUICKLZ
 import wget
 import requests
 from .common import check_parameters_exist, get_parameter_value
@@ -54,6 +55,9 @@ def start(restore_context):
         params = json.load(f)
         commit_index = params["backupCommitIndex"]
         backup_file_path = params["backupFilePath"]
+        # full backup packed in archive is extracted by its byte range
+        backup_file_offset = params.get("backupFileOffset", 0)
+        backup_file_size = params.get("backupFileSize", -1)
         binlog_dir_path = params["binlogDirPath"]
         storage_name = params["storageName"]
         sink = params["sink"]
@@ -62,6 +66,14 @@ def start(restore_context):
         is_pxc_xstore = params["pxcXStore"]
         keyring_path = params["keyringPath"] if "keyringPath" in params else ""
         keyringfile_path = params["keyringFilePath"] if "keyringFilePath" in params else ""
+        keyring_checksum_path = params["keyringChecksumPath"] if "keyringChecksumPath" in params else ""
+        endpoint_type = params.get("endpointType", "")
+        chunk_manifest_path = params.get("chunkManifestPath", "")
+        # keyring re-encrypted by backup is decrypted by the key passed by env
+        keyring_encrypted = params.get("keyringKey") is not None
+        # codecs of full backup and binlogs, which are reversed after downloaded
+        backup_codec = params.get("backupCodec") or {}
+        binlog_codec = params.get("binlogCodec") or {}
 
     logger.info('start restore: commit_index=%s, backup_file_path=%s, isPXCXStore:%s, pitr_endpoint=%s,'
                 'pitr_xstore=%s,keyring_path=%s'
@@ -73,26 +85,38 @@ def start(restore_context):
         logger.info("pod role is %s, no need to download backup." % node_role)
         return
 
-    filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink)
+    filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink,
+                                         endpoint_type=endpoint_type)
 
-    keyring_path_local = download_keyring_file(keyringfile_path,keyring_path, filestream_client, logger)
+    keyring_path_local = download_keyring_file(keyringfile_path, keyring_path, keyring_checksum_path,
+                                               filestream_client, logger, keyring_encrypted=keyring_encrypted)
 
     mkdir_needed(context)
 
+    report_restore_progress("Downloading", 10, context)
+
     backup_file_name = backup_file_path.split("/")[-1]
 
-    download_backup_file(backup_file_path, backup_file_name, filestream_client, logger)
+    download_backup_file(backup_file_path, backup_file_name, filestream_client, logger, backup_file_offset,
+                         backup_file_size, chunk_manifest_path)
+
+    report_restore_progress("Preparing", 30, context)
 
-    decompress_backup_file(backup_file_name, context, logger)
+    decompress_backup_file(backup_file_name, context, logger, codec=backup_codec)
 
     initialize_local_mycnf(context, logger)
 
     create_init_file(context, logger)
 
+    report_restore_progress("Applying", 50, context)
+
     apply_backup_file(keyring_path_local, context, logger)
 
     if is_pxc_xstore or len(pitr_endpoint) != 0:
-        mysql_bin_list = download_binlogbackup_file(binlog_dir_path, filestream_client, logger) if len(
+        report_restore_progress("RecoveringBinlog", 70, context)
+
+        mysql_bin_list = download_binlogbackup_file(binlog_dir_path, filestream_client, logger,
+                                                    codec=binlog_codec) if len(
             pitr_endpoint) == 0 else download_pitr_binloglist(context, pitr_endpoint, pitr_xstore, logger)
 
         copy_binlog_to_new_path(mysql_bin_list, context, logger)
@@ -130,6 +154,82 @@ def start(restore_context):
     context.mark_node_initialized()
 
 
+@click.command(name='apply_binlog')
+@click.option('--binlog_dir', required=True, type=str)
+@click.option('--storage_name', required=True, type=str)
+@click.option('--sink', required=True, type=str)
+@click.option('--start_index', required=True, type=int)
+@click.option('--end_index', required=True, type=int)
+@click.option('-tp', '--target_pod', required=True, type=str)
+@click.option('-p', '--password', required=True, type=str)
+@click.option('--compression', default="", type=str)
+@click.option('--encryption', default="", type=str)
+def apply_binlog(binlog_dir, storage_name, sink, start_index, end_index, target_pod, password, compression,
+                 encryption):
+    """
+    Apply binlog of consensus log index range (start_index, end_index] onto the running target pod,
+    which rolls forward data restored or applied up to start_index.
+    """
+    logger = LogFactory.get_logger("restore.log")
+    logger.info('start apply binlog: binlog_dir=%s, start_index=%s, end_index=%s, target_pod=%s'
+                % (binlog_dir, start_index, end_index, target_pod))
+
+    context = Context()
+    os.makedirs(RESTORE_TEMP_DIR, exist_ok=True)
+    filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink)
+    mysql_binlog_list = download_binlogbackup_file(binlog_dir, filestream_client, logger,
+                                                   codec={"compression": compression, "encryption": encryption})
+
+    binlog_indexes = []
+    for binlog in mysql_binlog_list:
+        binlog_path = os.path.join(RESTORE_TEMP_DIR, binlog)
+        first_index, last_index = xdb_show_binlog_index_range(binlog_path, context, logger)
+        binlog_indexes.append((binlog_path, first_index, last_index))
+    check_binlog_index_continuity(binlog_indexes, start_index, end_index)
+
+    mysqlbinlog = os.path.join(context.engine_home, 'bin', 'mysqlbinlog')
+    mysql = os.path.join(context.engine_home, 'bin', 'mysql')
+    for binlog_path, first_index, last_index in binlog_indexes:
+        if last_index <= start_index:
+            continue
+        if first_index > end_index:
+            break
+        truncated_path = binlog_path + ".apply"
+        check_run_process([context.bb_home, 'truncate', binlog_path,
+                           '--start-index', str(start_index),
+                           '--end-index', str(end_index),
+                           '-o', truncated_path], logger=logger)
+        logger.info("apply binlog %s" % truncated_path)
+        with subprocess.Popen([mysqlbinlog, truncated_path], stdout=subprocess.PIPE) as decoder:
+            subprocess.check_call([mysql, '-h', target_pod, '-P', str(context.port_access()), '-u', 'admin',
+                                   '-p' + password], stdin=decoder.stdout, stdout=sys.stdout)
+        if decoder.returncode != 0:
+            raise Exception("failed to decode binlog %s" % truncated_path)
+        os.remove(truncated_path)
+    logger.info("binlog applied up to index %s" % end_index)
+
+
+def check_binlog_index_continuity(binlog_indexes, start_index, end_index):
+    if len(binlog_indexes) == 0:
+        raise Exception("no binlog found in backup")
+    if binlog_indexes[0][1] > start_index + 1:
+        raise Exception("binlog starts from index %s, not continuous with index %s"
+                        % (binlog_indexes[0][1], start_index))
+    if binlog_indexes[-1][2] < end_index:
+        raise Exception("binlog ends at index %s, not reaching index %s" % (binlog_indexes[-1][2], end_index))
+    for i in range(1, len(binlog_indexes)):
+        if binlog_indexes[i][1] > binlog_indexes[i - 1][2] + 1:
+            raise Exception("binlog gap found between %s and %s"
+                            % (binlog_indexes[i - 1][0], binlog_indexes[i][0]))
+
+
+def report_restore_progress(phase, progress, context):
+    # progress file is read by operator to show restore phase in xstore status
+    progress_file = context.volume_path(VOLUME_DATA, "tmp", "restore.progress")
+    with open(progress_file, 'w') as f:
+        json.dump({"phase": phase, "progress": progress}, f)
+
+
 def mkdir_needed(context):
     if not os.path.exists(RESTORE_TEMP_DIR):
         os.mkdir(RESTORE_TEMP_DIR)
@@ -148,7 +248,8 @@ def mkdir_needed(context):
     shutil.chown(context.volume_path(VOLUME_DATA), "mysql","mysql")
 
 
-def download_keyring_file(keyringfile_path,keyring_path, filestream_client, logger):
+def download_keyring_file(keyringfile_path, keyring_path, keyring_checksum_path, filestream_client, logger,
+                          keyring_encrypted=False):
     if len(keyring_path) != 0:
         keyring_file_path = os.path.dirname(keyringfile_path)
         logger.info("keyring_file_path:%s",keyring_file_path)
@@ -157,26 +258,66 @@ def download_keyring_file(keyringfile_path,keyring_path, filestream_client, logg
         shutil.chown(keyring_file_path,"mysql","mysql")
         keyring_path_local = os.path.join(keyring_file_path, "keyring")
         filestream_client.download_to_file(remote=keyring_path, local=keyring_path_local, logger=logger)
+        # prepare of TDE backup can not go without keyring
+        if not os.path.exists(keyring_path_local) or os.path.getsize(keyring_path_local) == 0:
+            raise Exception("keyring of TDE backup is missing: %s" % keyring_path)
+        verify_keyring_checksum(keyring_path_local, keyring_checksum_path, filestream_client, logger)
+        if keyring_encrypted:
+            keyring_encrypted_local = keyring_path_local + ".enc"
+            os.rename(keyring_path_local, keyring_encrypted_local)
+            decrypt_keyring(keyring_encrypted_local, keyring_path_local, logger=logger)
+            os.remove(keyring_encrypted_local)
+            logger.info("backup keyring decrypted")
+        shutil.chown(keyring_path_local, "mysql", "mysql")
         logger.info("backup keyring downloaded!")
         return keyring_path_local
     return ""
 
 
-def download_backup_file(backup_file_path, backup_file_name, filestream_client, logger):
+def verify_keyring_checksum(keyring_path_local, keyring_checksum_path, filestream_client, logger):
+    # backups taken before checksum was introduced have no checksum file
+    if len(keyring_checksum_path) == 0:
+        return
+    checksum_path_local = keyring_path_local + ".sha256"
+    filestream_client.download_to_file(remote=keyring_checksum_path, local=checksum_path_local, logger=logger)
+    with open(checksum_path_local, 'r') as f:
+        expected_checksum = f.read().strip()
+    os.remove(checksum_path_local)
+    actual_checksum = sha256_of_file(keyring_path_local)
+    if expected_checksum != actual_checksum:
+        raise Exception("keyring checksum mismatch, expected: %s, actual: %s" % (expected_checksum, actual_checksum))
+    logger.info("keyring checksum verified")
+
+
+def download_backup_file(backup_file_path, backup_file_name, filestream_client, logger, offset=0, size=-1,
+                         chunk_manifest_path=""):
     backup_stream_file = os.path.join(RESTORE_TEMP_DIR, backup_file_name)
-    filestream_client.download_to_file(remote=backup_file_path, local=backup_stream_file, logger=logger)
+    if size >= 0:
+        filestream_client.download_to_file(remote=backup_file_path, local=backup_stream_file, logger=logger,
+                                           range_offset=offset, range_size=size)
+    else:
+        # resumed from the progress persisted on the data volume if downloaded before, e.g. by a restarted job
+        filestream_client.download_to_file_resumable(remote=backup_file_path, local=backup_stream_file,
+                                                     logger=logger, chunk_manifest=chunk_manifest_path)
     logger.info("backup file downloaded!")
 
 
-def download_binlogbackup_file(binlog_dir_path, filestream_client, logger):
+def download_binlogbackup_file(binlog_dir_path, filestream_client, logger, codec=None):
+    compression, encryption = (codec or {}).get("compression", ""), (codec or {}).get("encryption", "")
+    encoded = len(decode_commands(compression, encryption)) > 0
     binlog_list_path = os.path.join(RESTORE_TEMP_DIR, "binlog_list")
     filestream_client.download_to_file(remote=os.path.join(binlog_dir_path, "binlog_list"), local=binlog_list_path,
                                        logger=logger)
     with open(binlog_list_path, 'r') as f:
         mysql_binlog_list = f.read().splitlines()
     for binlog in mysql_binlog_list:
+        binlog_path = os.path.join(RESTORE_TEMP_DIR, binlog)
+        download_path = binlog_path + ".encoded" if encoded else binlog_path
         filestream_client.download_to_file(remote=os.path.join(binlog_dir_path, binlog),
-                                           local=os.path.join(RESTORE_TEMP_DIR, binlog), logger=logger)
+                                           local=download_path, logger=logger)
+        if encoded:
+            decode_file(download_path, binlog_path, compression, encryption, logger=logger)
+            os.remove(download_path)
     logger.info("binlog backup file download")
     logger.info("mysql_binlog_list:%s" % mysql_binlog_list)
     return mysql_binlog_list
@@ -232,13 +373,28 @@ def copy_binlog_to_new_path(mysql_bin_list, context, logger):
     logger.info("copy binlog to log_path")
 
 
-def decompress_backup_file(backup_file_name, context, logger):
-    decompress_cmd = "%s/xbstream --decompress -x < %s -C %s" % (
-        context.xtrabackup_home, os.path.join(RESTORE_TEMP_DIR, backup_file_name),
-        context.volume_path(VOLUME_DATA, "data"))
-    logger.info("decompress_cmd:%s" % decompress_cmd)
-    with subprocess.Popen(decompress_cmd, shell=True, stdout=sys.stdout):
-        logger.info("decompress!")
+def decompress_backup_file(backup_file_name, context, logger, codec=None):
+    compression = (codec or {}).get("compression", COMPRESSION_QUICKLZ)
+    encryption = (codec or {}).get("encryption", "")
+    if compression == COMPRESSION_QUICKLZ and len(encryption) == 0:
+        decompress_cmd = "%s/xbstream --decompress -x < %s -C %s" % (
+            context.xtrabackup_home, os.path.join(RESTORE_TEMP_DIR, backup_file_name),
+            context.volume_path(VOLUME_DATA, "data"))
+        logger.info("decompress_cmd:%s" % decompress_cmd)
+        with subprocess.Popen(decompress_cmd, shell=True, stdout=sys.stdout):
+            logger.info("decompress!")
+        return
+
+    # stream is decrypted and decompressed by pipeline before extracted
+    extract_cmd = [os.path.join(context.xtrabackup_home, "xbstream"), "-x", "-C",
+                   context.volume_path(VOLUME_DATA, "data")]
+    if compression == COMPRESSION_QUICKLZ:
+        extract_cmd.insert(1, "--decompress")
+    cmds = decode_commands(compression, encryption) + [extract_cmd]
+    logger.info("decompress_cmd:%s" % " | ".join(" ".join(cmd) for cmd in cmds))
+    with open(os.path.join(RESTORE_TEMP_DIR, backup_file_name), 'rb') as f:
+        wait_pipeline(open_pipeline(cmds, f, stdout=None))
+    logger.info("decompress!")
 
 
 def sort_config(config: configparser.ConfigParser) -> configparser.ConfigParser:
@@ -518,4 +674,25 @@ def xdb_show_binlog_index(binlog_path, context, logger):
     return end_index, end_term
 
 
+def xdb_show_binlog_index_range(binlog_path, context, logger):
+    """
+    Same as xdb_show_binlog_index, but returns the first and last index of the binlog.
+    """
+    cmd = [context.mysqlbinlogtailor,
+           "--show-index-info",
+           binlog_path
+           ]
+
+    logger.info("show_binlog_cmd:%s" % cmd)
+    with subprocess.Popen(cmd, stdout=subprocess.PIPE) as proc:
+        index_info = proc.stdout.read().decode('utf-8')
+        logger.info("xdb_show_binlog_index out" + index_info)
+
+    temp = index_info.strip().strip('[[]]').replace(' ', '')
+    start_index = temp.split(',')[0].split(':')[0]
+    end_index = temp.split(',')[1].split(':')[0]
+    return int(start_index), int(end_index)
+
+
 restore_group.add_command(start)
+restore_group.add_command(apply_binlog)
@@ -20,7 +20,7 @@ import click
 
 from core.log import LogFactory
 from core.convention import *
-from core.backup_restore.storage.filestream_client import BackupStorage, FileStreamClient
+from core.backup_restore.storage.filestream_client import BackupStorage, FileStreamClient, FilestreamException
 
 
 @click.group(name="seekcp")
@@ -41,6 +41,10 @@ def start_seekcp(seekcp_context):
         dn_name_list = params["dnNameList"].split(',')
         storage_name = params["storageName"]
         sink = params["sink"]
+        tags = params.get("tags", "")
+        sse_algorithm = params.get("sseAlgorithm", "")
+        sse_kms_key_id = params.get("sseKMSKeyId", "")
+        endpoint_type = params.get("endpointType", "")
 
     context = Context()
 
@@ -48,7 +52,9 @@ def start_seekcp(seekcp_context):
     if not os.path.exists(backup_dir):
         os.mkdir(backup_dir)
 
-    filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink)
+    filestream_client = FileStreamClient(context, BackupStorage[str.upper(storage_name)], sink, tags=tags,
+                                         sse_algorithm=sse_algorithm, sse_kms_key_id=sse_kms_key_id,
+                                         endpoint_type=endpoint_type)
 
     local_tx_dir = os.path.join(backup_dir, "seekcp")
     os.makedirs(local_tx_dir, exist_ok=True)
@@ -98,12 +104,34 @@ def get_heartbeat_txid(context, filestream_client, tx_events_dir, logger):
     return hb_tx_id.split(':')[-1].strip()
 
 
+def download_evs_parts(filestream_client, remote_path, local_path, logger):
+    """
+    Download the parts collected in parallel and stitch them into local_path in the order recorded by the index
+    of remote_path. Returns False if there is no index, i.e. events are collected as a single file.
+    """
+    local_index_path = local_path + ".index"
+    try:
+        filestream_client.download_to_file(remote=remote_path + ".index", local=local_index_path, logger=logger)
+        with open(local_index_path, "r") as f:
+            parts = json.load(f)["parts"]
+    except (FilestreamException, ValueError, KeyError):
+        return False
+    with open(local_path, "wb") as f:
+        for part in parts:
+            filestream_client.download_to_stdout(remote_path=part, stdout=f, logger=logger)
+            f.flush()
+    logger.info("stitched %d parts into %s" % (len(parts), local_path))
+    return True
+
+
 def download_evs_file(filestream_client, local_tx_dir, remote_tx_dir, dn_name_list, logger):
     for dn in dn_name_list:
         if dn.endswith("gms"):  # no need to download evs for gms
             continue
         remote_path = os.path.join(remote_tx_dir, dn + ".evs")
         local_path = os.path.join(local_tx_dir, dn + ".evs")
+        if download_evs_parts(filestream_client, remote_path, local_path, logger):
+            continue
         filestream_client.download_to_file(remote=remote_path, local=local_path, logger=logger)
 
 
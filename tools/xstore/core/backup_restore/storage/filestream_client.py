@@ -11,6 +11,7 @@
 # WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 # See the License for the specific language governing permissions and
 # limitations under the License.
+import hashlib
 import os
 import subprocess
 import sys
@@ -27,6 +28,8 @@ class BackupStorage(Enum):
     OSS = "OSS"
     SFTP = "SFTP"
     S3 = "S3"
+    AZURE = "AZURE"
+    GCS = "GCS"
 
 
 class ClientAction(Enum):
@@ -39,6 +42,10 @@ class ClientAction(Enum):
     UploadSsh = "uploadSsh"
     DownloadMinio = "downloadMinio"
     UploadMinio = "uploadMinio"
+    DownloadAzure = "downloadAzure"
+    UploadAzure = "uploadAzure"
+    DownloadGcs = "downloadGcs"
+    UploadGcs = "uploadGcs"
 
 
 class FilestreamException(Exception):
@@ -53,16 +60,28 @@ class FileStreamClient:
     A client to perform stream transmission
     """
 
-    def __init__(self, context: Context, storage: BackupStorage, sink):
+    def __init__(self, context: Context, storage: BackupStorage, sink, upload_concurrency=1, upload_part_size=0,
+                 tags="", sse_algorithm="", sse_kms_key_id="", max_object_size=0, endpoint_type=""):
         self._client = context.filestream_client()
         self._host_info = context.host_info()
         self._storage = storage
         self._sink = sink
+        self._upload_concurrency = upload_concurrency
+        self._upload_part_size = upload_part_size
+        # tags attached to uploaded objects, encoded as url query
+        self._tags = tags
+        # server-side encryption requested on uploaded objects
+        self._sse_algorithm = sse_algorithm
+        self._sse_kms_key_id = sse_kms_key_id
+        # stream of unknown size is split into objects no larger than it, reassembled on download
+        self._max_object_size = max_object_size
+        # type of endpoint of sink to transfer via, e.g. Accelerate
+        self._endpoint_type = endpoint_type
         self._download_action = None
         self._upload_action = None
         self.init_action()
 
-    def upload_from_stdin(self, remote_path, stdin, stderr=sys.stderr, logger=None, is_string_input=False, file_size=""):
+    def _upload_cmd(self, remote_path, is_string_input=False, file_size=""):
         upload_cmd = [
             self._client,
             "--meta.action=" + self._upload_action.value,
@@ -80,6 +99,25 @@ class FileStreamClient:
         if file_size != "" and self._storage == BackupStorage.S3:
             upload_cmd.append(f"--meta.minioBufferSize={file_size}")
 
+        # upload stream of unknown size by parts concurrently
+        if not is_string_input and file_size == "" and self._upload_concurrency > 1:
+            upload_cmd.append(f"--meta.uploadConcurrency={self._upload_concurrency}")
+            if self._upload_part_size > 0:
+                upload_cmd.append(f"--meta.uploadPartSize={self._upload_part_size}")
+        if not is_string_input and file_size == "" and self._max_object_size > 0:
+            upload_cmd.append(f"--maxObjectSize={self._max_object_size}")
+        if self._tags:
+            upload_cmd.append("--meta.tags=" + self._tags)
+        if self._sse_algorithm:
+            upload_cmd.append("--meta.sseAlgorithm=" + self._sse_algorithm)
+            if self._sse_kms_key_id:
+                upload_cmd.append("--meta.sseKMSKeyId=" + self._sse_kms_key_id)
+        if self._endpoint_type:
+            upload_cmd.append("--meta.endpointType=" + self._endpoint_type)
+        return upload_cmd
+
+    def upload_from_stdin(self, remote_path, stdin, stderr=sys.stderr, logger=None, is_string_input=False, file_size=""):
+        upload_cmd = self._upload_cmd(remote_path, is_string_input, file_size)
         if logger:
             logger.info("Upload command: %s" % upload_cmd)
 
@@ -88,7 +126,47 @@ class FileStreamClient:
             if return_code:
                 raise FilestreamException("Failed to upload, return code: %s" % return_code)
 
-    def download_to_stdout(self, remote_path, stdout, stderr=sys.stderr, logger=None):
+    def upload_from_stdin_in_chunks(self, remote_path, stdin, chunk_size, stderr=sys.stderr, logger=None,
+                                    buffer_size=1 << 20):
+        """
+        upload from stdin as a single file, while splitting the stream into chunks of fixed size
+        and calculating their hashes, which can be used as dedup hints by storage. every chunk is
+        uploaded, chunks already stored are never skipped here, dedup is left to storage
+
+        :param remote_path: remote path to store uploaded file
+        :param stdin: stream to upload
+        :param chunk_size: size of each chunk in bytes
+        :param stderr: redirect stderr
+        :param logger: just a logger
+        :param buffer_size: size of each read from stdin
+        :return: list of chunks, each of which records offset, size and sha256
+        """
+        upload_cmd = self._upload_cmd(remote_path)
+        if logger:
+            logger.info("Upload command: %s, chunk size: %d" % (upload_cmd, chunk_size))
+
+        chunks = []
+        offset, chunk_len, chunk_hash = 0, 0, hashlib.sha256()
+        with subprocess.Popen(upload_cmd, stdin=subprocess.PIPE, stderr=stderr, close_fds=True) as up:
+            while True:
+                data = stdin.read(min(buffer_size, chunk_size - chunk_len))
+                if not data:
+                    break
+                up.stdin.write(data)
+                chunk_hash.update(data)
+                chunk_len += len(data)
+                if chunk_len == chunk_size:
+                    chunks.append({"offset": offset, "size": chunk_len, "sha256": chunk_hash.hexdigest()})
+                    offset, chunk_len, chunk_hash = offset + chunk_len, 0, hashlib.sha256()
+            if chunk_len > 0:
+                chunks.append({"offset": offset, "size": chunk_len, "sha256": chunk_hash.hexdigest()})
+            up.stdin.close()
+            return_code = up.wait()
+            if return_code:
+                raise FilestreamException("Failed to upload, return code: %s" % return_code)
+        return chunks
+
+    def download_to_stdout(self, remote_path, stdout, stderr=sys.stderr, logger=None, range_offset=0, range_size=-1):
         download_cmd = [
             self._client,
             "--meta.action=" + self._download_action.value,
@@ -96,6 +174,12 @@ class FileStreamClient:
             "--meta.filename=" + remote_path,
             "--hostInfoFilePath=" + self._host_info
         ]
+        # download only the byte range, e.g. an entry of archive
+        if range_size >= 0:
+            download_cmd.append(f"--rangeOffset={range_offset}")
+            download_cmd.append(f"--rangeSize={range_size}")
+        if self._endpoint_type:
+            download_cmd.append("--meta.endpointType=" + self._endpoint_type)
         if logger:
             logger.info("Download command: %s" % download_cmd)
         with subprocess.Popen(download_cmd, stdout=stdout, stderr=stderr, close_fds=True) as dp:
@@ -117,7 +201,7 @@ class FileStreamClient:
             self.upload_from_stdin(remote_path=remote, stdin=f, stderr=stderr, logger=logger, is_string_input=False,
                                    file_size=str(file_size))
 
-    def download_to_file(self, remote, local, stderr=sys.stderr, logger=None):
+    def download_to_file(self, remote, local, stderr=sys.stderr, logger=None, range_offset=0, range_size=-1):
         """
         download from src file to dest file
 
@@ -125,9 +209,46 @@ class FileStreamClient:
         :param local: local path to store downloaded file
         :param stderr: redirect stderr
         :param logger: just a logger
+        :param range_offset: offset of the byte range to download
+        :param range_size: size of the byte range to download, -1 means the whole file
         """
         with open(local, 'w') as f:
-            self.download_to_stdout(remote_path=remote, stdout=f, stderr=stderr, logger=logger)
+            self.download_to_stdout(remote_path=remote, stdout=f, stderr=stderr, logger=logger,
+                                    range_offset=range_offset, range_size=range_size)
+
+    def download_to_file_resumable(self, remote, local, stderr=sys.stderr, logger=None, chunk_manifest="",
+                                   attempts=5):
+        """
+        download from src file to dest file resumably, the progress is persisted next to the local file,
+        so that a broken download, even by a restarted process, resumes from it instead of the beginning
+
+        :param remote: remote path of file to download
+        :param local: local path to store downloaded file
+        :param stderr: redirect stderr
+        :param logger: just a logger
+        :param chunk_manifest: remote path of chunk manifest to verify the downloaded file against, empty means no
+        verification
+        :param attempts: max attempts of download
+        """
+        download_cmd = [
+            self._client,
+            "--meta.action=" + self._download_action.value,
+            "--meta.sink=" + self._sink,
+            "--meta.filename=" + remote,
+            "--hostInfoFilePath=" + self._host_info,
+            "--resumeFile=" + local,
+            "--resumeAttempts=%d" % attempts,
+        ]
+        if chunk_manifest:
+            download_cmd.append("--chunkManifest=" + chunk_manifest)
+        if self._endpoint_type:
+            download_cmd.append("--meta.endpointType=" + self._endpoint_type)
+        if logger:
+            logger.info("Download command: %s" % download_cmd)
+        with subprocess.Popen(download_cmd, stdout=stderr, stderr=stderr, close_fds=True) as dp:
+            return_code = dp.wait()
+            if return_code:
+                raise FilestreamException("Failed to download, return code: %s" % return_code)
 
     def upload_from_string(self, remote, string, stderr=sys.stderr, logger=None):
         """
@@ -151,5 +272,11 @@ class FileStreamClient:
         elif self._storage == BackupStorage.S3:
             self._download_action = ClientAction.DownloadMinio
             self._upload_action = ClientAction.UploadMinio
+        elif self._storage == BackupStorage.AZURE:
+            self._download_action = ClientAction.DownloadAzure
+            self._upload_action = ClientAction.UploadAzure
+        elif self._storage == BackupStorage.GCS:
+            self._download_action = ClientAction.DownloadGcs
+            self._upload_action = ClientAction.UploadGcs
         else:
             raise NotImplementedError
@@ -0,0 +1,105 @@
+# Copyright 2021 Alibaba Group Holding Limited.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Run under tools/xstore: python3 -m unittest core.backup_restore.test_utils
+
+import os
+import tempfile
+import unittest
+
+from core.backup_restore.utils import encode_commands, decode_commands, decode_file, open_pipeline, \
+    wait_pipeline, ENV_BACKUP_ENCRYPTION_KEY, COMPRESSION_NONE, COMPRESSION_GZIP, COMPRESSION_QUICKLZ, \
+    ENCRYPTION_AES256
+
+
+class CodecTest(unittest.TestCase):
+
+    def setUp(self):
+        self.tmp = tempfile.TemporaryDirectory()
+        self.addCleanup(self.tmp.cleanup)
+        self.origin_key = os.environ.get(ENV_BACKUP_ENCRYPTION_KEY)
+        os.environ[ENV_BACKUP_ENCRYPTION_KEY] = "backup-secret"
+        self.addCleanup(self._restore_key)
+
+    def _restore_key(self):
+        if self.origin_key is None:
+            os.environ.pop(ENV_BACKUP_ENCRYPTION_KEY, None)
+        else:
+            os.environ[ENV_BACKUP_ENCRYPTION_KEY] = self.origin_key
+
+    def _path(self, name):
+        return os.path.join(self.tmp.name, name)
+
+    def _run_pipeline(self, cmds, src, dst):
+        with open(src, 'rb') as fin, open(dst, 'wb') as fout:
+            wait_pipeline(open_pipeline(cmds, fin, stdout=fout))
+
+    def _write(self, name, data):
+        with open(self._path(name), 'wb') as f:
+            f.write(data)
+        return self._path(name)
+
+    def _read(self, name):
+        with open(self._path(name), 'rb') as f:
+            return f.read()
+
+    def test_round_trip(self):
+        data = os.urandom(64 << 10) + b"binlog event" * (16 << 10)
+        for compression, encryption in [(COMPRESSION_GZIP, ""), ("", ENCRYPTION_AES256),
+                                        (COMPRESSION_GZIP, ENCRYPTION_AES256)]:
+            with self.subTest(compression=compression, encryption=encryption):
+                src = self._write("binlog", data)
+                self._run_pipeline(encode_commands(compression, encryption), src, self._path("encoded"))
+                encoded = self._read("encoded")
+                self.assertNotEqual(data, encoded)
+                if encryption:
+                    self.assertNotIn(b"binlog event", encoded)
+
+                self._run_pipeline(decode_commands(compression, encryption), self._path("encoded"),
+                                   self._path("decoded"))
+                self.assertEqual(data, self._read("decoded"))
+
+                decoded = decode_file(self._path("encoded"), self._path("decoded_file"), compression, encryption)
+                self.assertEqual(self._path("decoded_file"), decoded)
+                self.assertEqual(data, self._read("decoded_file"))
+
+    def test_no_codec(self):
+        for compression in ["", COMPRESSION_NONE, COMPRESSION_QUICKLZ]:
+            with self.subTest(compression=compression):
+                self.assertEqual([], encode_commands(compression, ""))
+                self.assertEqual([], decode_commands(compression, ""))
+        src = self._write("binlog", b"binlog event")
+        self.assertEqual(src, decode_file(src, self._path("decoded"), "", ""))
+
+    def test_decrypt_with_wrong_key(self):
+        src = self._write("binlog", b"binlog event" * 1024)
+        self._run_pipeline(encode_commands("", ENCRYPTION_AES256), src, self._path("encoded"))
+        os.environ[ENV_BACKUP_ENCRYPTION_KEY] = "another-secret"
+        with self.assertRaises(Exception):
+            self._run_pipeline(decode_commands("", ENCRYPTION_AES256), self._path("encoded"), self._path("decoded"))
+
+    def test_invalid_codec(self):
+        with self.assertRaisesRegex(Exception, "unsupported compression"):
+            encode_commands("zstd", "")
+        with self.assertRaisesRegex(Exception, "unsupported compression"):
+            decode_commands("zstd", "")
+        with self.assertRaisesRegex(Exception, "unsupported encryption"):
+            encode_commands("", "SM4")
+        os.environ.pop(ENV_BACKUP_ENCRYPTION_KEY)
+        with self.assertRaisesRegex(Exception, "encryption key not found"):
+            decode_commands("", ENCRYPTION_AES256)
+
+
+if __name__ == '__main__':
+    unittest.main()
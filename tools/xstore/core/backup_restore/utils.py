@@ -11,6 +11,9 @@
 # WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 # See the License for the specific language governing permissions and
 # limitations under the License.
+import fnmatch
+import hashlib
+import os
 import subprocess
 import shlex
 from typing import Sequence, AnyStr
@@ -27,3 +30,144 @@ def check_run_process(cmd: Sequence[AnyStr] or AnyStr, *, cwd=None, stdout=None,
         logger.info('%s execute command: %s' % (
             prefix, ' '.join([shlex.quote(s) for s in cmd]) if not isinstance(cmd, str) else cmd))
     return subprocess.check_call(cmd, shell=isinstance(cmd, str), cwd=cwd, stdout=stdout, stderr=stderr)
+
+
+def sha256_of_file(path):
+    sha256 = hashlib.sha256()
+    with open(path, 'rb') as f:
+        for block in iter(lambda: f.read(1 << 20), b''):
+            sha256.update(block)
+    return sha256.hexdigest()
+
+
+# Envs carrying the keys of keyring re-encryption, referring the secret specified by backup.
+ENV_KEYRING_SOURCE_KEY = "KEYRING_SOURCE_KEY"
+ENV_KEYRING_TARGET_KEY = "KEYRING_TARGET_KEY"
+
+
+def encrypt_keyring(src, dst, key_env=ENV_KEYRING_TARGET_KEY, logger=None):
+    """
+    Encrypt keyring file src into dst with the key in env key_env, which is never passed by command line.
+    """
+    if len(os.environ.get(key_env, "")) == 0:
+        raise Exception("key of keyring re-encryption not found in env %s" % key_env)
+    check_run_process(["openssl", "enc", "-aes-256-cbc", "-pbkdf2", "-salt", "-in", src, "-out", dst,
+                       "-pass", "env:" + key_env], logger=logger)
+
+
+def decrypt_keyring(src, dst, key_env=ENV_KEYRING_TARGET_KEY, logger=None):
+    """
+    Decrypt keyring file src encrypted by encrypt_keyring into dst with the key in env key_env.
+    """
+    if len(os.environ.get(key_env, "")) == 0:
+        raise Exception("key of re-encrypted keyring not found in env %s" % key_env)
+    check_run_process(["openssl", "enc", "-d", "-aes-256-cbc", "-pbkdf2", "-in", src, "-out", dst,
+                       "-pass", "env:" + key_env], logger=logger)
+
+
+# Env carrying the key which files of backup set are encrypted with, referring the secret specified by backup.
+ENV_BACKUP_ENCRYPTION_KEY = "BACKUP_ENCRYPTION_KEY"
+
+COMPRESSION_NONE = "none"
+COMPRESSION_GZIP = "gzip"
+# Compression of xtrabackup, which is applied on full backup only if compression is not specified.
+COMPRESSION_QUICKLZ = "quicklz"
+
+ENCRYPTION_AES256 = "AES256"
+
+
+def _encryption_command(encryption, decrypt=False):
+    if encryption != ENCRYPTION_AES256:
+        raise Exception("unsupported encryption: %s" % encryption)
+    if len(os.environ.get(ENV_BACKUP_ENCRYPTION_KEY, "")) == 0:
+        raise Exception("encryption key not found in env %s" % ENV_BACKUP_ENCRYPTION_KEY)
+    if decrypt:
+        return ["openssl", "enc", "-d", "-aes-256-cbc", "-pbkdf2", "-pass", "env:" + ENV_BACKUP_ENCRYPTION_KEY]
+    return ["openssl", "enc", "-aes-256-cbc", "-pbkdf2", "-salt", "-pass", "env:" + ENV_BACKUP_ENCRYPTION_KEY]
+
+
+def encode_commands(compression, encryption):
+    """
+    Commands of pipeline processing files of backup set before uploaded, files are compressed before encrypted.
+    Compression of xtrabackup is applied by xtrabackup itself, so no command is needed for it. Empty if files are
+    uploaded as they are.
+    """
+    cmds = []
+    if compression == COMPRESSION_GZIP:
+        cmds.append(["gzip", "-c"])
+    elif compression not in ("", COMPRESSION_NONE, COMPRESSION_QUICKLZ):
+        raise Exception("unsupported compression: %s" % compression)
+    if encryption:
+        cmds.append(_encryption_command(encryption))
+    return cmds
+
+
+def decode_commands(compression, encryption):
+    """
+    Commands of pipeline reversing encode_commands of the same compression and encryption.
+    """
+    cmds = []
+    if encryption:
+        cmds.append(_encryption_command(encryption, decrypt=True))
+    if compression == COMPRESSION_GZIP:
+        cmds.append(["gzip", "-d", "-c"])
+    elif compression not in ("", COMPRESSION_NONE, COMPRESSION_QUICKLZ):
+        raise Exception("unsupported compression: %s" % compression)
+    return cmds
+
+
+def open_pipeline(cmds, stdin, stdout=subprocess.PIPE):
+    """
+    Start commands chained by pipes reading from stdin, the output is the stdout of the last process. Returns
+    the processes started, empty if no command.
+    """
+    procs = []
+    for i, cmd in enumerate(cmds):
+        p = subprocess.Popen(cmd, stdin=stdin, stdout=stdout if i == len(cmds) - 1 else subprocess.PIPE,
+                             close_fds=True)
+        if procs:
+            # let the previous process receive SIGPIPE if this one exits
+            procs[-1].stdout.close()
+        procs.append(p)
+        stdin = p.stdout
+    return procs
+
+
+def wait_pipeline(procs):
+    for p in procs:
+        return_code = p.wait()
+        if return_code:
+            raise Exception("%s exited abnormally, return code: %s" % (p.args[0], return_code))
+
+
+def _transform_file(cmds, src, dst, logger=None):
+    if len(cmds) == 0:
+        return src
+    if logger:
+        logger.info("process %s into %s by: %s" % (src, dst, " | ".join(" ".join(cmd) for cmd in cmds)))
+    with open(src, 'rb') as fin, open(dst, 'wb') as fout:
+        wait_pipeline(open_pipeline(cmds, fin, stdout=fout))
+    return dst
+
+
+def decode_file(src, dst, compression, encryption, logger=None):
+    """
+    Decode file src encoded by encode_commands of the same compression and encryption into dst, returns the path of
+    file decoded, which is src itself if it's uploaded as it is.
+    """
+    return _transform_file(decode_commands(compression, encryption), src, dst, logger=logger)
+
+
+def exclude_binlogs(binlog_list, patterns, keep=()):
+    """
+    Split binlog list of (log_name, start_log_index) into the ones kept and names of the ones excluded, as their
+    names match any of the shell patterns. Binlog named in keep is never excluded.
+    """
+    kept, excluded = [], []
+    for binlog in binlog_list:
+        log_name = binlog[0]
+        if log_name not in keep and any(fnmatch.fnmatchcase(log_name, p) for p in patterns or []):
+            excluded.append(log_name)
+        else:
+            kept.append(binlog)
+    return kept, excluded